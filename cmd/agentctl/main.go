@@ -4,15 +4,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	// Adds version information
 	_ "github.com/grafana/agent/pkg/build"
 	"github.com/grafana/agent/pkg/client/grafanacloud"
-	"github.com/grafana/agent/pkg/config"
 	"github.com/olekukonko/tablewriter"
 	"github.com/prometheus/common/version"
 
@@ -20,9 +21,14 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/grafana/agent/pkg/agentctl"
 	"github.com/grafana/agent/pkg/client"
+	"github.com/grafana/agent/pkg/tempo"
+	"github.com/grafana/agent/pkg/tempo/tracesbench"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
 
 	// Register Prometheus SD components
+	_ "github.com/grafana/agent/pkg/prom/discovery/http"
 	_ "github.com/prometheus/prometheus/discovery/install"
 
 	// Register integrations
@@ -40,7 +46,15 @@ func main() {
 	cmd.AddCommand(
 		configSyncCmd(),
 		configCheckCmd(),
+		configListCmd(),
+		targetsCmd(),
+		tracesStatusCmd(),
+		tracesOTelConfigCmd(),
+		tracesImportOTelCmd(),
+		tracesBenchCmd(),
 		walStatsCmd(),
+		walInspectCmd(),
+		walRepairCmd(),
 		targetStatsCmd(),
 		samplesCmd(),
 		cloudConfigCmd(),
@@ -98,25 +112,39 @@ func configCheckCmd() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "config-check [config file]",
-		Short: "Perform basic validation of the given Agent configuration file",
-		Long: `config-check performs basic syntactic validation of the given Agent configuration
-file. The file is checked to ensure the types match the expected configuration types. Optionally,
-${var} style substitutions can be expanded based on the values of the environmental variables.
+		Short: "Validate the given Agent configuration file",
+		Long: `config-check validates the given Agent configuration file the same way the
+Agent does on startup, and additionally builds the OpenTelemetry collector
+config of every tempo instance. Optionally, ${var} style substitutions can be
+expanded based on the values of the environmental variables.
 
-If the configuration file is valid the exit code will be 0. If the configuration file is invalid
-the exit code will be 1.`,
+Errors and warnings for deprecated fields are printed along with the line of
+the configuration file they refer to, when known.
+
+If the configuration file is valid the exit code will be 0, even if warnings
+were printed. If the configuration file is invalid the exit code will be 1.`,
 		Args: cobra.ExactArgs(1),
 		Run: func(_ *cobra.Command, args []string) {
 			file := args[0]
 
-			cfg := config.Config{}
-			err := config.LoadFile(file, expandEnv, &cfg)
+			buf, err := ioutil.ReadFile(file)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "failed to validate config: %s\n", err)
+				fmt.Fprintf(os.Stderr, "failed to read config: %s\n", err)
 				os.Exit(1)
-			} else {
-				fmt.Fprintln(os.Stdout, "config valid")
 			}
+
+			diags := agentctl.CheckConfig(buf, expandEnv)
+			for _, d := range diags {
+				if d.Line > 0 {
+					fmt.Fprintf(os.Stderr, "%s:%d: %s: %s\n", file, d.Line, d.Severity, d.Message)
+				} else {
+					fmt.Fprintf(os.Stderr, "%s: %s: %s\n", file, d.Severity, d.Message)
+				}
+			}
+			if agentctl.HasErrors(diags) {
+				os.Exit(1)
+			}
+			fmt.Fprintln(os.Stdout, "config valid")
 		},
 	}
 
@@ -124,6 +152,349 @@ the exit code will be 1.`,
 	return cmd
 }
 
+func configListCmd() *cobra.Command {
+	var (
+		agentAddr string
+		selector  string
+		pageSize  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "config-list",
+		Short: "List the names of configs in an Agent's config management API",
+		Long: `config-list prints the names of the configs stored in the config management
+API, one per line. A label selector (e.g., {team="infra"}) may be given to only
+list configs whose labels match it.`,
+		Args: cobra.NoArgs,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			if agentAddr == "" {
+				fmt.Fprintln(os.Stderr, "-addr must not be an empty string")
+				os.Exit(1)
+			}
+
+			cli := client.New(agentAddr)
+			names, err := agentctl.ListConfigs(context.Background(), cli.PrometheusClient, selector, pageSize)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to list configs: %s\n", err)
+				os.Exit(1)
+			}
+			for _, name := range names {
+				fmt.Fprintln(os.Stdout, name)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&agentAddr, "addr", "a", "http://localhost:12345", "address of the agent to connect to")
+	cmd.Flags().StringVarP(&selector, "selector", "s", "", "only list configs whose labels match the selector")
+	cmd.Flags().IntVar(&pageSize, "page-size", 100, "number of configs to request at a time. 0 requests all configs at once")
+	return cmd
+}
+
+func targetsCmd() *cobra.Command {
+	var agentAddr string
+
+	cmd := &cobra.Command{
+		Use:   "targets",
+		Short: "List the scrape targets of a running Agent",
+		Long: `targets prints the scrape targets of every Prometheus instance running in the
+Agent along with the health and error of their last scrape.`,
+		Args: cobra.NoArgs,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			if agentAddr == "" {
+				fmt.Fprintln(os.Stderr, "-addr must not be an empty string")
+				os.Exit(1)
+			}
+
+			cli := client.New(agentAddr)
+			targets, err := cli.Targets(context.Background())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to get targets: %s\n", err)
+				os.Exit(1)
+			}
+
+			sort.Slice(targets, func(i, j int) bool {
+				if targets[i].InstanceName != targets[j].InstanceName {
+					return targets[i].InstanceName < targets[j].InstanceName
+				}
+				if targets[i].TargetGroup != targets[j].TargetGroup {
+					return targets[i].TargetGroup < targets[j].TargetGroup
+				}
+				return targets[i].Endpoint < targets[j].Endpoint
+			})
+
+			table := tablewriter.NewWriter(os.Stdout)
+			defer table.Render()
+
+			table.SetHeader([]string{"Instance", "Job", "Endpoint", "State", "Last Scrape", "Duration", "Error"})
+			for _, t := range targets {
+				var lastScrape string
+				if !t.LastScrape.IsZero() {
+					lastScrape = time.Since(t.LastScrape).Round(time.Second).String() + " ago"
+				}
+				table.Append([]string{
+					t.InstanceName,
+					t.TargetGroup,
+					t.Endpoint,
+					t.State,
+					lastScrape,
+					(time.Duration(t.ScrapeDuration) * time.Millisecond).String(),
+					t.ScrapeError,
+				})
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&agentAddr, "addr", "a", "http://localhost:12345", "address of the agent to connect to")
+	return cmd
+}
+
+func tracesStatusCmd() *cobra.Command {
+	var agentAddr string
+
+	cmd := &cobra.Command{
+		Use:   "traces-status",
+		Short: "Show the receivers and exporters of a running Agent's traces pipelines",
+		Long: `traces-status prints the receivers and exporters of every traces instance
+running in the Agent, the number of spans that went through them, and the size
+of each exporter's sending queue.
+
+Span counts and queue sizes are collected per component name. Components with
+the same name in different instances report the same values.`,
+		Args: cobra.NoArgs,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			if agentAddr == "" {
+				fmt.Fprintln(os.Stderr, "-addr must not be an empty string")
+				os.Exit(1)
+			}
+
+			cli := client.New(agentAddr)
+			status, err := cli.TracesStatus(context.Background())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to get traces status: %s\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Println("Receivers:")
+			receivers := tablewriter.NewWriter(os.Stdout)
+			receivers.SetHeader([]string{"Instance", "Receiver", "Accepted Spans", "Refused Spans"})
+			for _, inst := range status {
+				for _, r := range inst.Receivers {
+					receivers.Append([]string{
+						inst.InstanceName,
+						r.Name,
+						fmt.Sprintf("%d", r.AcceptedSpans),
+						fmt.Sprintf("%d", r.RefusedSpans),
+					})
+				}
+			}
+			receivers.Render()
+
+			fmt.Println("\nExporters:")
+			exporters := tablewriter.NewWriter(os.Stdout)
+			exporters.SetHeader([]string{"Instance", "Exporter", "Sent Spans", "Failed Spans", "Enqueue Failed Spans", "Queue Size"})
+			for _, inst := range status {
+				for _, e := range inst.Exporters {
+					exporters.Append([]string{
+						inst.InstanceName,
+						e.Name,
+						fmt.Sprintf("%d", e.SentSpans),
+						fmt.Sprintf("%d", e.FailedSpans),
+						fmt.Sprintf("%d", e.EnqueueFailedSpans),
+						fmt.Sprintf("%d", e.QueueSize),
+					})
+				}
+			}
+			exporters.Render()
+		},
+	}
+
+	cmd.Flags().StringVarP(&agentAddr, "addr", "a", "http://localhost:12345", "address of the agent to connect to")
+	return cmd
+}
+
+func tracesOTelConfigCmd() *cobra.Command {
+	var agentAddr string
+
+	cmd := &cobra.Command{
+		Use:   "traces-otel-config [instance]",
+		Short: "Print the OpenTelemetry collector config of a running traces instance",
+		Long: `traces-otel-config prints the OpenTelemetry collector config which the Agent
+generated for a running traces instance. The authorization header of
+exporters, which holds the basic_auth credentials, is redacted.`,
+		Args: cobra.ExactArgs(1),
+
+		Run: func(_ *cobra.Command, args []string) {
+			if agentAddr == "" {
+				fmt.Fprintln(os.Stderr, "-addr must not be an empty string")
+				os.Exit(1)
+			}
+
+			cli := client.New(agentAddr)
+			cfg, err := cli.TracesOTelConfig(context.Background(), args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to get OTel config: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(cfg)
+		},
+	}
+
+	cmd.Flags().StringVarP(&agentAddr, "addr", "a", "http://localhost:12345", "address of the agent to connect to")
+	return cmd
+}
+
+func tracesImportOTelCmd() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "traces-import-otel [collector config file]",
+		Short: "Convert an OpenTelemetry collector config into a traces instance config",
+		Long: `traces-import-otel converts the traces pipeline of an OpenTelemetry collector
+config file into a traces instance config, which can be added to the configs
+of the tempo block of an Agent config file.
+
+The collector config must have exactly one traces pipeline. Its receivers are
+copied as-is, its otlp and otlphttp exporters become remote_write endpoints,
+and its batch, attributes, groupbytrace and tail_sampling processors are
+converted into the matching blocks. The Agent runs processors in its own
+order, which may differ from the order of the collector pipeline.`,
+		Args: cobra.ExactArgs(1),
+
+		Run: func(_ *cobra.Command, args []string) {
+			buf, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to read collector config: %s\n", err)
+				os.Exit(1)
+			}
+
+			cfg, err := tempo.InstanceConfigFromOTel(name, buf)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to convert collector config: %s\n", err)
+				os.Exit(1)
+			}
+
+			out, err := yaml.Marshal(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to marshal instance config: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(string(out))
+		},
+	}
+
+	cmd.Flags().StringVarP(&name, "name", "n", "default", "name of the traces instance config")
+	return cmd
+}
+
+func tracesBenchCmd() *cobra.Command {
+	var (
+		cfg       = tracesbench.DefaultConfig
+		agentAddr string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "traces-bench",
+		Short: "Send synthetic traces to a running traces instance and report throughput and latency",
+		Long: `traces-bench sends synthetic traces to an OTLP gRPC receiver of a running
+traces instance for a fixed duration, then prints the number of spans sent,
+the rate of spans accepted by the receiver, and latency percentiles of the
+requests. Requests which fail are counted and not retried.
+
+When --addr is set, the spans sent by every exporter of the Agent during the
+run are printed as well, which shows the throughput of the whole pipeline
+rather than only of the receiver. Exporters keep sending queued spans after
+the run ends, so the rates of exporters with large queues may be too low.
+
+Examples:
+
+Send 1000 traces per second to a local Agent for a minute:
+
+$ agentctl traces-bench --endpoint localhost:4317 --rate 1000 --duration 1m --addr http://localhost:12345
+`,
+		Args: cobra.NoArgs,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			var (
+				cli    *client.Client
+				before tempo.StatusResponse
+			)
+			if agentAddr != "" {
+				cli = client.New(agentAddr)
+
+				var err error
+				before, err = cli.TracesStatus(context.Background())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to get traces status: %s\n", err)
+					os.Exit(1)
+				}
+			}
+
+			res, err := tracesbench.Run(context.Background(), zap.NewNop(), cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to run benchmark: %s\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Duration:           %s\n", res.Duration.Round(time.Millisecond))
+			fmt.Printf("Requests:           %d (%d failed)\n", res.Requests, res.FailedRequests)
+			fmt.Printf("Sent Spans:         %d (%d failed)\n", res.SentSpans, res.FailedSpans)
+			fmt.Printf("Spans/s:            %.1f\n", res.SpansPerSecond())
+			fmt.Printf("Latency p50:        %s\n", res.LatencyP50)
+			fmt.Printf("Latency p90:        %s\n", res.LatencyP90)
+			fmt.Printf("Latency p99:        %s\n", res.LatencyP99)
+			fmt.Printf("Latency max:        %s\n", res.LatencyMax)
+
+			if cli == nil {
+				return
+			}
+			after, err := cli.TracesStatus(context.Background())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to get traces status: %s\n", err)
+				os.Exit(1)
+			}
+
+			sentBefore := make(map[string]int64)
+			for _, inst := range before {
+				for _, e := range inst.Exporters {
+					sentBefore[inst.InstanceName+"/"+e.Name] = e.SentSpans
+				}
+			}
+
+			fmt.Println("\nExporters:")
+			exporters := tablewriter.NewWriter(os.Stdout)
+			exporters.SetHeader([]string{"Instance", "Exporter", "Sent Spans", "Spans/s", "Queue Size"})
+			for _, inst := range after {
+				for _, e := range inst.Exporters {
+					sent := e.SentSpans - sentBefore[inst.InstanceName+"/"+e.Name]
+					exporters.Append([]string{
+						inst.InstanceName,
+						e.Name,
+						fmt.Sprintf("%d", sent),
+						fmt.Sprintf("%.1f", float64(sent)/res.Duration.Seconds()),
+						fmt.Sprintf("%d", e.QueueSize),
+					})
+				}
+			}
+			exporters.Render()
+		},
+	}
+
+	cmd.Flags().StringVarP(&cfg.Endpoint, "endpoint", "e", cfg.Endpoint, "host:port of the OTLP gRPC receiver to send traces to")
+	cmd.Flags().BoolVar(&cfg.Insecure, "insecure", cfg.Insecure, "disable TLS when connecting to the receiver")
+	cmd.Flags().StringToStringVar(&cfg.Headers, "header", nil, "header to add to every request, as key=value")
+	cmd.Flags().DurationVarP(&cfg.Duration, "duration", "d", cfg.Duration, "how long to send traces for")
+	cmd.Flags().IntVarP(&cfg.Rate, "rate", "r", cfg.Rate, "traces to send per second. 0 sends traces as fast as possible")
+	cmd.Flags().IntVarP(&cfg.Workers, "workers", "w", cfg.Workers, "number of concurrent requests")
+	cmd.Flags().IntVar(&cfg.TracesPerRequest, "traces-per-request", cfg.TracesPerRequest, "number of traces sent in each request")
+	cmd.Flags().IntVar(&cfg.SpansPerTrace, "spans-per-trace", cfg.SpansPerTrace, "number of spans of each trace")
+	cmd.Flags().DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "timeout of each request")
+	cmd.Flags().StringVarP(&agentAddr, "addr", "a", "", "address of the agent to read exporter statistics from")
+	return cmd
+}
+
 func samplesCmd() *cobra.Command {
 	var selector string
 
@@ -151,19 +522,7 @@ $ agentctl sample-stats -s '{job="a"}' /tmp/wal
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(_ *cobra.Command, args []string) {
-			directory := args[0]
-			if _, err := os.Stat(directory); os.IsNotExist(err) {
-				fmt.Printf("%s does not exist\n", directory)
-				os.Exit(1)
-			} else if err != nil {
-				fmt.Printf("error getting wal: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Check if ./wal is a subdirectory, use that instead.
-			if _, err := os.Stat(filepath.Join(directory, "wal")); err == nil {
-				directory = filepath.Join(directory, "wal")
-			}
+			directory := walDirectory(args[0])
 
 			stats, err := agentctl.FindSamples(directory, selector)
 			if err != nil {
@@ -204,19 +563,7 @@ high-cardinality series that you do not want to send.`,
 		Args: cobra.ExactArgs(1),
 
 		Run: func(_ *cobra.Command, args []string) {
-			directory := args[0]
-			if _, err := os.Stat(directory); os.IsNotExist(err) {
-				fmt.Printf("%s does not exist\n", directory)
-				os.Exit(1)
-			} else if err != nil {
-				fmt.Printf("error getting wal: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Check if ./wal is a subdirectory, use that instead.
-			if _, err := os.Stat(filepath.Join(directory, "wal")); err == nil {
-				directory = filepath.Join(directory, "wal")
-			}
+			directory := walDirectory(args[0])
 
 			cardinality, err := agentctl.FindCardinality(directory, jobLabel, instanceLabel)
 			if err != nil {
@@ -258,19 +605,7 @@ deletion but then comes back at some point).`,
 		Args: cobra.ExactArgs(1),
 
 		Run: func(_ *cobra.Command, args []string) {
-			directory := args[0]
-			if _, err := os.Stat(directory); os.IsNotExist(err) {
-				fmt.Printf("%s does not exist\n", directory)
-				os.Exit(1)
-			} else if err != nil {
-				fmt.Printf("error getting wal: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Check if ./wal is a subdirectory, use that instead.
-			if _, err := os.Stat(filepath.Join(directory, "wal")); err == nil {
-				directory = filepath.Join(directory, "wal")
-			}
+			directory := walDirectory(args[0])
 
 			stats, err := agentctl.CalculateStats(directory)
 			if err != nil {
@@ -306,6 +641,125 @@ deletion but then comes back at some point).`,
 	}
 }
 
+func walInspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "wal-inspect [WAL directory]",
+		Short: "Inspect the checkpoint and segments of a WAL for corruption",
+		Long: `wal-inspect reads every record of the latest checkpoint and the segments after
+it in a WAL directory, reporting the size, number of records, and any corruption
+found in each of them. If the WAL is not corrupted, the series count and the
+oldest and newest samples are also printed.
+
+The exit code is 1 if the WAL is corrupted. Use wal-repair to repair it.`,
+		Args: cobra.ExactArgs(1),
+
+		Run: func(_ *cobra.Command, args []string) {
+			directory := walDirectory(args[0])
+
+			inspection, err := agentctl.InspectWAL(directory)
+			if err != nil {
+				fmt.Printf("failed to inspect WAL: %v\n", err)
+				os.Exit(1)
+			}
+
+			if len(inspection.TempCheckpoints) > 0 {
+				fmt.Printf("Temporary checkpoints: %s\n\n", strings.Join(inspection.TempCheckpoints, ", "))
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"File", "Index", "Size", "Records", "Corruption"})
+			appendFile := func(name string, f agentctl.WALFileInfo) {
+				var corruption string
+				if f.Corruption != nil {
+					corruption = f.Corruption.Error()
+				}
+				table.Append([]string{
+					name,
+					fmt.Sprintf("%d", f.Index),
+					fmt.Sprintf("%d", f.Size),
+					fmt.Sprintf("%d", f.Records),
+					corruption,
+				})
+			}
+			if inspection.Checkpoint != nil {
+				appendFile(filepath.Base(inspection.Checkpoint.Path), *inspection.Checkpoint)
+			}
+			for _, s := range inspection.Segments {
+				appendFile(filepath.Base(s.Path), s)
+			}
+			table.Render()
+
+			if inspection.Corrupted() {
+				fmt.Println("\nWAL is corrupted. Run wal-repair to repair it.")
+				os.Exit(1)
+			}
+
+			stats, err := agentctl.CalculateStats(directory)
+			if err != nil {
+				fmt.Printf("failed to get WAL stats: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println()
+			fmt.Printf("Oldest Sample:      %s\n", stats.From)
+			fmt.Printf("Newest Sample:      %s\n", stats.To)
+			fmt.Printf("Total Series:       %d\n", stats.Series())
+			fmt.Printf("Total Samples:      %d\n", stats.Samples())
+		},
+	}
+}
+
+func walRepairCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "wal-repair [WAL directory]",
+		Short: "Repair a corrupted WAL",
+		Long: `wal-repair repairs a WAL directory which wal-inspect reports as corrupted,
+keeping as much data as possible instead of deleting the entire WAL:
+
+1. Temporary checkpoint directories left behind by an interrupted checkpoint
+   are deleted.
+2. A corrupted checkpoint is deleted. Samples in later segments for series
+   only defined in the checkpoint will be dropped.
+3. The first corrupted segment is truncated at the corrupted record, and all
+   segments after it are deleted.
+
+The Agent using the WAL must be stopped before running wal-repair.`,
+		Args: cobra.ExactArgs(1),
+
+		Run: func(_ *cobra.Command, args []string) {
+			logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
+			directory := walDirectory(args[0])
+
+			if err := agentctl.RepairWAL(logger, directory, dryRun); err != nil {
+				level.Error(logger).Log("msg", "failed to repair WAL", "err", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "log the repairs that would be made without changing the WAL")
+	return cmd
+}
+
+// walDirectory exits if directory doesn't exist. If directory contains a wal
+// subdirectory, it is returned instead.
+func walDirectory(directory string) string {
+	if _, err := os.Stat(directory); os.IsNotExist(err) {
+		fmt.Printf("%s does not exist\n", directory)
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Printf("error getting wal: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Check if ./wal is a subdirectory, use that instead.
+	if _, err := os.Stat(filepath.Join(directory, "wal")); err == nil {
+		directory = filepath.Join(directory, "wal")
+	}
+	return directory
+}
+
 func cloudConfigCmd() *cobra.Command {
 	var (
 		stackID string
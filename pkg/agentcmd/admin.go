@@ -0,0 +1,26 @@
+package agentcmd
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/grafana/agent/pkg/agentproto"
+	"github.com/grafana/agent/pkg/prom"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// adminServer implements agentproto.AdminServiceServer. Methods other than
+// Reload are implemented by the Prometheus subsystem.
+type adminServer struct {
+	*prom.Agent
+	ep *Entrypoint
+}
+
+// Reload implements agentproto.AdminServiceServer.
+func (s *adminServer) Reload(context.Context, *agentproto.ReloadRequest) (*empty.Empty, error) {
+	if err := s.ep.reload(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to reload config file: %s", err)
+	}
+	return &empty.Empty{}, nil
+}
@@ -0,0 +1,595 @@
+package agentcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/agentproto"
+	"github.com/grafana/agent/pkg/governor"
+	"github.com/grafana/agent/pkg/health"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/loki"
+	"github.com/grafana/agent/pkg/summary"
+	"github.com/grafana/agent/pkg/tempo"
+	"github.com/grafana/agent/pkg/usage"
+	"github.com/grafana/agent/pkg/util"
+	"github.com/grafana/agent/pkg/util/server"
+	"github.com/oklog/run"
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/agent/pkg/config"
+	"github.com/grafana/agent/pkg/prom"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/prom/monitors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/common/signals"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// Entrypoint is the entrypoint of the application that starts all subsystems.
+type Entrypoint struct {
+	mut sync.Mutex
+
+	reloader Reloader
+
+	log     *util.Logger
+	cfg     config.Config
+	applied bool // true once cfg has been applied to every subsystem
+
+	// mode is the set of subsystems which run. Subsystems which aren't part
+	// of mode are nil.
+	mode config.Mode
+
+	srv         *server.Server
+	auth        *server.Authenticator
+	promMetrics *prom.Agent
+	monitors    *monitors.Manager
+	lokiLogs    *loki.Loki
+	tempoTraces *tempo.Tempo
+	manager     *integrations.Manager
+	usage       *usage.Tracker
+	summary     *summary.Tracker
+	governor    *governor.Governor
+
+	reloadListener net.Listener
+	reloadServer   *http.Server
+}
+
+// Reloader is any function that returns a new config.
+type Reloader = func() (*config.Config, error)
+
+// NewEntrypoint creates a new Entrypoint.
+func NewEntrypoint(logger *util.Logger, cfg *config.Config, reloader Reloader) (*Entrypoint, error) {
+	var (
+		ep = &Entrypoint{
+			log:      logger,
+			reloader: reloader,
+			mode:     cfg.Mode,
+		}
+		err error
+
+		// im is the metrics instance manager used by the other subsystems,
+		// if metrics are collected.
+		im instance.Manager
+	)
+
+	ep.auth = server.NewAuthenticator()
+
+	if cfg.ReloadPort != 0 {
+		ep.reloadListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", cfg.ReloadAddress, cfg.ReloadPort))
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on address for secondary /-/reload server: %w", err)
+		}
+
+		reloadMux := mux.NewRouter()
+		reloadMux.HandleFunc("/-/reload", ep.reloadHandler)
+		ep.reloadServer = &http.Server{Handler: ep.auth.Wrap(reloadMux)}
+	}
+
+	ep.srv = server.New(prometheus.DefaultRegisterer, logger)
+
+	if ep.mode.Metrics() {
+		ep.promMetrics, err = prom.New(prometheus.DefaultRegisterer, cfg.Prometheus, logger)
+		if err != nil {
+			return nil, err
+		}
+		im = ep.promMetrics.InstanceManager()
+
+		ep.monitors = monitors.New(logger, im)
+	}
+
+	if ep.mode.Logs() {
+		ep.lokiLogs, err = loki.New(prometheus.DefaultRegisterer, cfg.Loki, im, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// queues is left as a nil interface when traces aren't collected.
+	var queues governor.QueueScaler
+	if ep.mode.Traces() {
+		ep.tempoTraces, err = tempo.New(ep.lokiLogs, im, prometheus.DefaultRegisterer, cfg.Tempo, cfg.Server.LogLevel.Logrus)
+		if err != nil {
+			return nil, err
+		}
+		queues = ep.tempoTraces
+	}
+
+	if ep.mode.Metrics() {
+		ep.manager, err = integrations.NewManager(cfg.Integrations, logger, im, ep.promMetrics.Validate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ep.usage, err = usage.New(logger, im, prometheus.DefaultGatherer, prometheus.DefaultRegisterer)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []string
+	if ep.mode.Metrics() {
+		running = append(running, "prometheus")
+	}
+	if ep.mode.Logs() {
+		running = append(running, "loki")
+	}
+	if ep.mode.Traces() {
+		running = append(running, "tempo")
+	}
+	ep.summary = summary.New(logger, prometheus.DefaultGatherer, running)
+
+	ep.governor, err = governor.New(logger, im, queues, prometheus.DefaultRegisterer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mostly everything should be up to date except for the server, which hasn't
+	// been created yet.
+	if err := ep.ApplyConfig(*cfg); err != nil {
+		return nil, err
+	}
+	return ep, nil
+}
+
+// ApplyConfigError is returned by ApplyConfig when the config could not be
+// applied. ApplyConfig is atomic: when an ApplyConfigError is returned, every
+// subsystem is left running with the config it had before the call.
+type ApplyConfigError struct {
+	// Errors holds the error for each subsystem that failed, keyed by the
+	// name of the subsystem.
+	Errors map[string]error
+}
+
+// Subsystems returns the sorted names of the subsystems that failed.
+func (e *ApplyConfigError) Subsystems() []string {
+	subsystems := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		subsystems = append(subsystems, name)
+	}
+	sort.Strings(subsystems)
+	return subsystems
+}
+
+// Error implements error.
+func (e *ApplyConfigError) Error() string {
+	subsystems := e.Subsystems()
+	msgs := make([]string, 0, len(subsystems))
+	for _, name := range subsystems {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", name, e.Errors[name]))
+	}
+	return fmt.Sprintf("changes did not apply successfully: %s", strings.Join(msgs, "; "))
+}
+
+// subsystem is a named step of ApplyConfig.
+type subsystem struct {
+	name  string
+	apply func(cfg config.Config) error
+}
+
+// subsystems returns the running subsystems of the Agent in the order
+// configs are applied to them.
+func (ep *Entrypoint) subsystems() []subsystem {
+	subsystems := []subsystem{
+		{"logger", func(cfg config.Config) error { return ep.log.ApplyConfig(&cfg.Server) }},
+		{"server", func(cfg config.Config) error {
+			if err := ep.auth.ApplyConfig(cfg.ServerAuth); err != nil {
+				return err
+			}
+			cfg.Server.HTTPMiddleware = append(cfg.Server.HTTPMiddleware, ep.auth)
+			return ep.srv.ApplyConfig(cfg.Server, ep.wire)
+		}},
+	}
+
+	if ep.mode.Metrics() {
+		subsystems = append(subsystems,
+			subsystem{"prometheus", func(cfg config.Config) error { return ep.promMetrics.ApplyConfig(cfg.Prometheus) }},
+			subsystem{"kubernetes_monitors", func(cfg config.Config) error {
+				return ep.monitors.ApplyConfig(cfg.Prometheus.KubernetesMonitors, cfg.Prometheus.Global)
+			}},
+		)
+	}
+	if ep.mode.Logs() {
+		subsystems = append(subsystems, subsystem{"loki", func(cfg config.Config) error { return ep.lokiLogs.ApplyConfig(cfg.Loki) }})
+	}
+	if ep.mode.Traces() {
+		subsystems = append(subsystems, subsystem{"tempo", func(cfg config.Config) error {
+			return ep.tempoTraces.ApplyConfig(ep.lokiLogs, ep.instanceManager(), cfg.Tempo, cfg.Server.LogLevel.Logrus)
+		}})
+	}
+	if ep.mode.Metrics() {
+		subsystems = append(subsystems, subsystem{"integrations", func(cfg config.Config) error { return ep.manager.ApplyConfig(cfg.Integrations) }})
+	}
+
+	return append(subsystems,
+		subsystem{"usage", func(cfg config.Config) error { return ep.usage.ApplyConfig(cfg.Usage, cfg.Loki, cfg.Tempo) }},
+		subsystem{"summary", func(cfg config.Config) error { return ep.summary.ApplyConfig(cfg.Summary) }},
+		subsystem{"governor", func(cfg config.Config) error { return ep.governor.ApplyConfig(cfg.Governor) }},
+	)
+}
+
+// instanceManager returns the metrics instance manager, or nil if metrics
+// aren't collected.
+func (ep *Entrypoint) instanceManager() instance.Manager {
+	if ep.promMetrics == nil {
+		return nil
+	}
+	return ep.promMetrics.InstanceManager()
+}
+
+// validateConfig checks the sections of cfg used by the subsystems which run
+// in mode, including errors that would otherwise only be found while a
+// subsystem is applying it.
+func validateConfig(cfg config.Config, mode config.Mode) error {
+	errs := make(map[string]error)
+
+	if mode.Metrics() {
+		if err := cfg.Prometheus.ApplyDefaults(); err != nil {
+			errs["prometheus"] = err
+		}
+		if err := cfg.Integrations.ApplyDefaults(&cfg.Prometheus); err != nil {
+			errs["integrations"] = err
+		}
+	}
+	if mode.Traces() {
+		// Tempo may forward logs to Loki, which isn't available when logs
+		// aren't collected.
+		lokiCfg := cfg.Loki
+		if !mode.Logs() {
+			lokiCfg = loki.Config{}
+		}
+		if err := cfg.Tempo.DryRun(&lokiCfg); err != nil {
+			errs["tempo"] = err
+		}
+	}
+	if err := cfg.Governor.Validate(); err != nil {
+		errs["governor"] = err
+	}
+
+	if len(errs) > 0 {
+		return &ApplyConfigError{Errors: errs}
+	}
+	return nil
+}
+
+// ApplyConfig applies changes to the subsystems of the Agent. cfg is
+// validated before any subsystem is changed. If a subsystem then fails to
+// apply cfg, every subsystem that was already changed is rolled back to the
+// previous config. Both cases return an *ApplyConfigError.
+func (ep *Entrypoint) ApplyConfig(cfg config.Config) error {
+	ep.mut.Lock()
+	defer ep.mut.Unlock()
+
+	if err := validateConfig(cfg, ep.mode); err != nil {
+		level.Error(ep.log).Log("msg", "rejecting invalid config", "err", err)
+		return err
+	}
+
+	subsystems := ep.subsystems()
+	for i, sub := range subsystems {
+		err := sub.apply(cfg)
+		if err == nil {
+			continue
+		}
+		level.Error(ep.log).Log("msg", "failed to update "+sub.name, "err", err)
+		errs := map[string]error{sub.name: err}
+
+		// There's nothing to roll back to when the Entrypoint is first created.
+		if !ep.applied {
+			return &ApplyConfigError{Errors: errs}
+		}
+
+		// Roll back in reverse order, including the subsystem that failed
+		// since it may have partially applied cfg.
+		for j := i; j >= 0; j-- {
+			prev := subsystems[j]
+			if err := prev.apply(ep.cfg); err != nil {
+				level.Error(ep.log).Log("msg", "failed to roll back "+prev.name, "err", err)
+				if j != i {
+					errs[prev.name] = fmt.Errorf("rolling back: %w", err)
+				}
+			}
+		}
+		return &ApplyConfigError{Errors: errs}
+	}
+
+	ep.cfg = cfg
+	ep.applied = true
+	return nil
+}
+
+// wire is used to hook up API endpoints to components, and is called every
+// time a new Weaveworks server is creatd.
+func (ep *Entrypoint) wire(mux *mux.Router, grpc *grpc.Server) {
+	if ep.mode.Metrics() {
+		ep.promMetrics.WireAPI(mux)
+		ep.promMetrics.WireGRPC(grpc)
+		agentproto.RegisterAdminServiceServer(grpc, &adminServer{Agent: ep.promMetrics, ep: ep})
+
+		ep.manager.WireAPI(mux)
+	}
+	if ep.mode.Traces() {
+		ep.tempoTraces.WireAPI(mux)
+	}
+	ep.usage.WireAPI(mux)
+	ep.summary.WireAPI(mux)
+
+	mux.HandleFunc("/-/healthy", func(w http.ResponseWriter, r *http.Request) {
+		report := ep.healthReport()
+		ep.writeHealthReport(w, report, report.Healthy)
+	})
+
+	mux.HandleFunc("/-/ready", func(w http.ResponseWriter, r *http.Request) {
+		report := ep.healthReport()
+		ep.writeHealthReport(w, report, report.Ready)
+	})
+
+	mux.HandleFunc("/-/config", func(rw http.ResponseWriter, r *http.Request) {
+		ep.mut.Lock()
+		bb, err := yaml.Marshal(ep.cfg)
+		ep.mut.Unlock()
+
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("failed to marshal config: %s", err), http.StatusInternalServerError)
+		} else {
+			_, _ = rw.Write(bb)
+		}
+	})
+
+	mux.HandleFunc("/-/reload", ep.reloadHandler)
+}
+
+// healthReport returns the health and readiness of every subsystem.
+func (ep *Entrypoint) healthReport() health.Report {
+	var subsystems []health.Subsystem
+	if ep.mode.Metrics() {
+		subsystems = append(subsystems, ep.promMetrics.Health())
+	}
+	if ep.mode.Logs() {
+		subsystems = append(subsystems, ep.lokiLogs.Health())
+	}
+	if ep.mode.Traces() {
+		subsystems = append(subsystems, ep.tempoTraces.Health())
+	}
+	if ep.mode.Metrics() {
+		subsystems = append(subsystems, ep.manager.Health())
+	}
+	return health.NewReport(subsystems...)
+}
+
+// writeHealthReport writes report as JSON with a status code of 200 if ok is
+// true and 503 otherwise.
+func (ep *Entrypoint) writeHealthReport(w http.ResponseWriter, report health.Report, ok bool) {
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		level.Error(ep.log).Log("msg", "failed to write health report", "err", err)
+	}
+}
+
+func (ep *Entrypoint) reloadHandler(rw http.ResponseWriter, r *http.Request) {
+	err := ep.reload()
+	if err == nil {
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	rw.WriteHeader(http.StatusBadRequest)
+
+	var applyErr *ApplyConfigError
+	if !errors.As(err, &applyErr) {
+		fmt.Fprintf(rw, "failed to reload config file: %s\n", err)
+		return
+	}
+
+	// Report each subsystem that failed on its own line.
+	subsystems := applyErr.Subsystems()
+	fmt.Fprintf(rw, "failed to apply config to %d subsystem(s):\n", len(subsystems))
+	for _, name := range subsystems {
+		fmt.Fprintf(rw, "%s: %s\n", name, applyErr.Errors[name])
+	}
+}
+
+// TriggerReload will cause the Entrypoint to re-request the config file and
+// apply the latest config. TriggerReload returns true if the reload was
+// successful.
+func (ep *Entrypoint) TriggerReload() bool {
+	return ep.reload() == nil
+}
+
+// reload re-requests the config file and applies it. Config files which fail
+// to load or validate are rejected before any subsystem is changed.
+func (ep *Entrypoint) reload() error {
+	level.Info(ep.log).Log("msg", "reload of config file requested")
+
+	cfg, err := ep.reloader()
+	if err != nil {
+		level.Error(ep.log).Log("msg", "failed to reload config file", "err", err)
+		return err
+	}
+
+	err = ep.ApplyConfig(*cfg)
+	if err != nil {
+		level.Error(ep.log).Log("msg", "failed to reload config file", "err", err)
+		return err
+	}
+	return nil
+}
+
+// pollConfig re-requests the config file and applies it. Subsystems
+// whose config did not change are left untouched.
+func (ep *Entrypoint) pollConfig() {
+	level.Debug(ep.log).Log("msg", "polling config file")
+
+	cfg, err := ep.reloader()
+	if err != nil {
+		level.Error(ep.log).Log("msg", "failed to load polled config file", "err", err)
+		return
+	}
+	if err := ep.ApplyConfig(*cfg); err != nil {
+		level.Error(ep.log).Log("msg", "failed to apply polled config file", "err", err)
+	}
+}
+
+// Stop stops the Entrypoint and all subsystems.
+func (ep *Entrypoint) Stop() {
+	ep.mut.Lock()
+	defer ep.mut.Unlock()
+
+	ep.governor.Stop()
+	ep.usage.Stop()
+	ep.summary.Stop()
+	if ep.mode.Metrics() {
+		ep.manager.Stop()
+		ep.monitors.Stop()
+	}
+	if ep.mode.Logs() {
+		ep.lokiLogs.Stop()
+	}
+	if ep.mode.Metrics() {
+		ep.promMetrics.Stop()
+	}
+	if ep.mode.Traces() {
+		ep.tempoTraces.Stop()
+	}
+	ep.srv.Close()
+
+	if ep.reloadServer != nil {
+		ep.reloadServer.Close()
+	}
+}
+
+// Start starts the server used by the Entrypoint, and will block until a
+// termination signal is sent to the process.
+func (ep *Entrypoint) Start() error {
+	var g run.Group
+
+	// Create a signal handler that will stop the Entrypoint once a termination
+	// signal is received.
+	signalHandler := signals.NewHandler(ep.cfg.Server.Log)
+
+	g.Add(func() error {
+		signalHandler.Loop()
+		return nil
+	}, func(e error) {
+		signalHandler.Stop()
+	})
+
+	// Reload the config file when SIGHUP is received.
+	{
+		reloadCh := make(chan os.Signal, 1)
+		signal.Notify(reloadCh, syscall.SIGHUP)
+		done := make(chan struct{})
+
+		g.Add(func() error {
+			for {
+				select {
+				case <-reloadCh:
+					level.Info(ep.log).Log("msg", "received SIGHUP")
+					ep.TriggerReload()
+				case <-done:
+					return nil
+				}
+			}
+		}, func(e error) {
+			signal.Stop(reloadCh)
+			close(done)
+		})
+	}
+
+	// Periodically fetch and apply the dynamic config.
+	if dc := ep.cfg.DynamicConfig; dc.Enabled() && dc.PollInterval > 0 {
+		done := make(chan struct{})
+
+		g.Add(func() error {
+			ticker := time.NewTicker(dc.PollInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					ep.pollConfig()
+				case <-done:
+					return nil
+				}
+			}
+		}, func(e error) {
+			close(done)
+		})
+	}
+
+	// Periodically load the config file again to renew expired secrets.
+	if sc := ep.cfg.Secrets; sc.Enabled && sc.RefreshInterval > 0 {
+		done := make(chan struct{})
+
+		g.Add(func() error {
+			ticker := time.NewTicker(sc.RefreshInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					ep.pollConfig()
+				case <-done:
+					return nil
+				}
+			}
+		}, func(e error) {
+			close(done)
+		})
+	}
+
+	if ep.reloadServer != nil && ep.reloadListener != nil {
+		g.Add(func() error {
+			return ep.reloadServer.Serve(ep.reloadListener)
+		}, func(e error) {
+			ep.reloadServer.Close()
+		})
+	}
+
+	g.Add(func() error {
+		return ep.srv.Run()
+	}, func(e error) {
+		ep.srv.Close()
+	})
+
+	return g.Run()
+}
@@ -1,6 +1,6 @@
 // +build !windows
 
-package main
+package agentcmd
 
 // IsWindowsService returns whether the current process is running as a Windows
 // Service. On non-Windows platforms, this always returns false.
@@ -0,0 +1,211 @@
+package agentctl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/agent/pkg/config"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is the severity of a ConfigDiagnostic.
+type Severity string
+
+// Supported severities.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// ConfigDiagnostic is a problem found in a config file by CheckConfig.
+type ConfigDiagnostic struct {
+	Severity Severity
+	// Line is the 1-indexed line of the config file the problem was found
+	// at. 0 if unknown.
+	Line    int
+	Message string
+}
+
+// deprecatedFields are paths of config fields which are deprecated along
+// with what should be used instead. A "*" index matches every element of a
+// list.
+var deprecatedFields = []struct {
+	path    string
+	message string
+}{
+	{"tempo.configs[*].push_config", "deprecated in favor of remote_write and batch"},
+	{"tempo.configs[*].remote_write[*].insecure_skip_verify", "deprecated in favor of tls_config.insecure_skip_verify"},
+	{"integrations.use_hostname_label", "deprecated in favor of replace_instance_label"},
+}
+
+// CheckConfig validates the Agent config file in buf the same way the Agent
+// does on startup, and additionally builds the OTel collector config of every
+// tempo instance. Errors and warnings for deprecated fields are returned with
+// the line of the config file they refer to, if known.
+func CheckConfig(buf []byte, expandEnvVars bool) []ConfigDiagnostic {
+	var cfg config.Config
+	if err := config.LoadBytes(buf, expandEnvVars, &cfg); err != nil {
+		return yamlDiagnostics(err)
+	}
+
+	// Errors after this point can be mapped to a line by finding the path
+	// they're prefixed with in the document.
+	var root yaml.Node
+	if err := yaml.Unmarshal(buf, &root); err != nil {
+		return []ConfigDiagnostic{{Severity: SeverityError, Message: err.Error()}}
+	}
+
+	var diags []ConfigDiagnostic
+	for _, field := range deprecatedFields {
+		for _, f := range findFields(&root, field.path) {
+			diags = append(diags, ConfigDiagnostic{
+				Severity: SeverityWarning,
+				Line:     f.key.Line,
+				Message:  fmt.Sprintf("%s: %s", f.path, field.message),
+			})
+		}
+	}
+
+	err := cfg.ApplyDefaults()
+	if err == nil {
+		err = cfg.Tempo.DryRun(&cfg.Loki)
+	}
+	if err != nil {
+		diags = append(diags, ConfigDiagnostic{
+			Severity: SeverityError,
+			Line:     errorLine(&root, err.Error()),
+			Message:  err.Error(),
+		})
+	}
+	return diags
+}
+
+// HasErrors returns true if any diagnostic in diags is an error.
+func HasErrors(diags []ConfigDiagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+var yamlLineRegex = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// yamlDiagnostics converts an error from unmarshaling YAML into diagnostics.
+func yamlDiagnostics(err error) []ConfigDiagnostic {
+	te, ok := err.(*yamlv2.TypeError)
+	if !ok {
+		line, msg := 0, err.Error()
+		if m := yamlLineRegex.FindStringSubmatch(strings.TrimPrefix(msg, "yaml: ")); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = m[2]
+		}
+		return []ConfigDiagnostic{{Severity: SeverityError, Line: line, Message: msg}}
+	}
+
+	diags := make([]ConfigDiagnostic, 0, len(te.Errors))
+	for _, e := range te.Errors {
+		d := ConfigDiagnostic{Severity: SeverityError, Message: e}
+		if m := yamlLineRegex.FindStringSubmatch(e); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Message = m[2]
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+var errorPathRegex = regexp.MustCompile(`^([a-z_]+(?:\[\d+\])?(?:\.[a-z_]+(?:\[\d+\])?)*): `)
+
+// errorLine returns the line of the field an error message is prefixed with,
+// such as "tempo.configs[0].spanmetrics: ...". If the field can't be found,
+// the line of the closest parent which can be found is used. 0 is returned if
+// the message isn't prefixed with a path.
+func errorLine(root *yaml.Node, msg string) int {
+	m := errorPathRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return 0
+	}
+
+	segments := strings.Split(m[1], ".")
+	for len(segments) > 0 {
+		if fields := findFields(root, strings.Join(segments, ".")); len(fields) > 0 {
+			return fields[0].key.Line
+		}
+		segments = segments[:len(segments)-1]
+	}
+	return 0
+}
+
+var pathSegmentRegex = regexp.MustCompile(`^([a-z_]+)(?:\[(\d+|\*)\])?$`)
+
+// field is a field found in a YAML document.
+type field struct {
+	// path of the field, with all list indexes resolved.
+	path       string
+	key, value *yaml.Node
+}
+
+// findFields returns all fields in the document root that match path, where
+// path is a dot-separated list of keys optionally followed by a list index,
+// e.g., tempo.configs[0].remote_write[*].endpoint. For list elements, key is
+// the element itself.
+func findFields(root *yaml.Node, path string) []field {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+
+	fields := []field{{value: root.Content[0]}}
+	for _, segment := range strings.Split(path, ".") {
+		m := pathSegmentRegex.FindStringSubmatch(segment)
+		if m == nil {
+			return nil
+		}
+		name, index := m[1], m[2]
+
+		var next []field
+		for _, f := range fields {
+			key, value := mappingValue(f.value, name)
+			if value == nil {
+				continue
+			}
+
+			fieldPath := name
+			if f.path != "" {
+				fieldPath = f.path + "." + name
+			}
+
+			if index == "" {
+				next = append(next, field{path: fieldPath, key: key, value: value})
+				continue
+			}
+			if value.Kind != yaml.SequenceNode {
+				continue
+			}
+			for i, elem := range value.Content {
+				if index == "*" || index == strconv.Itoa(i) {
+					next = append(next, field{path: fmt.Sprintf("%s[%d]", fieldPath, i), key: elem, value: elem})
+				}
+			}
+		}
+		fields = next
+	}
+	return fields
+}
+
+// mappingValue returns the key and value nodes of name in the mapping node n.
+func mappingValue(n *yaml.Node, name string) (key, value *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == name {
+			return n.Content[i], n.Content[i+1]
+		}
+	}
+	return nil, nil
+}
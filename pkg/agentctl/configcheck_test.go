@@ -0,0 +1,114 @@
+package agentctl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfig(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    string
+		expect []ConfigDiagnostic
+	}{
+		{
+			name: "valid",
+			cfg: `
+tempo:
+  configs:
+  - name: default
+    receivers:
+      jaeger:
+        protocols:
+          grpc:
+    remote_write:
+    - endpoint: example.com:12345
+`,
+		},
+		{
+			name: "unknown fields",
+			cfg: `
+prometheus:
+  wal_directory: /tmp/wal
+  foo: bar
+tempo:
+  bar: baz
+`,
+			expect: []ConfigDiagnostic{
+				{Severity: SeverityError, Line: 4, Message: "field foo not found in type prom.plain"},
+				{Severity: SeverityError, Line: 6, Message: "field bar not found in type tempo.plain"},
+			},
+		},
+		{
+			name: "validation error",
+			cfg: `
+tempo:
+  configs:
+  - name: default
+    remote_write:
+    - endpoint: example.com:12345
+      protocol: thrift
+`,
+			expect: []ConfigDiagnostic{{
+				Severity: SeverityError,
+				Line:     7,
+				Message:  "tempo.configs[0].remote_write[0].protocol: unsupported protocol 'thrift', expected 'grpc' or 'http'",
+			}},
+		},
+		{
+			name: "validation error for unset field uses parent line",
+			cfg: `
+tempo:
+  configs:
+  - name: default
+  - name: other
+    spanmetrics:
+      prom_instance: tempo
+      handler_endpoint: 0.0.0.0:8889
+`,
+			expect: []ConfigDiagnostic{{
+				Severity: SeverityError,
+				Line:     6,
+				Message:  "tempo.configs[1].spanmetrics: must not configure both prom_instance and handler_endpoint",
+			}},
+		},
+		{
+			name: "deprecated fields",
+			cfg: `
+integrations:
+  use_hostname_label: false
+tempo:
+  configs:
+  - name: default
+    receivers:
+      jaeger:
+        protocols:
+          grpc:
+    push_config:
+      endpoint: example.com:12345
+  - name: other
+    receivers:
+      jaeger:
+        protocols:
+          grpc:
+    remote_write:
+    - endpoint: example.com:12345
+    - endpoint: example.com:12346
+      insecure_skip_verify: true
+`,
+			expect: []ConfigDiagnostic{
+				{Severity: SeverityWarning, Line: 11, Message: "tempo.configs[0].push_config: deprecated in favor of remote_write and batch"},
+				{Severity: SeverityWarning, Line: 21, Message: "tempo.configs[1].remote_write[1].insecure_skip_verify: deprecated in favor of tls_config.insecure_skip_verify"},
+				{Severity: SeverityWarning, Line: 3, Message: "integrations.use_hostname_label: deprecated in favor of replace_instance_label"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := CheckConfig([]byte(tc.cfg), false)
+			require.Equal(t, tc.expect, diags)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package agentctl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/agent/pkg/client"
+)
+
+// ListConfigs returns the names of all configs in the provided
+// PrometheusClient API whose labels match selector. An empty selector
+// matches all configs.
+//
+// If pageSize is greater than zero, configs are requested pageSize at a
+// time, following the Next field of each response until all pages have
+// been read.
+func ListConfigs(ctx context.Context, cli client.PrometheusClient, selector string, pageSize int) ([]string, error) {
+	var (
+		names []string
+		opts  = client.ListConfigsOptions{Selector: selector, Limit: pageSize}
+	)
+
+	for {
+		resp, err := cli.ListConfigsWithOptions(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("could not list configs: %w", err)
+		}
+		names = append(names, resp.Configs...)
+
+		if resp.Next == "" {
+			return names, nil
+		} else if resp.Next == opts.After {
+			return nil, fmt.Errorf("could not list configs: API returned the same page twice after %q", opts.After)
+		}
+		opts.After = resp.Next
+	}
+}
@@ -0,0 +1,55 @@
+package agentctl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/agent/pkg/client"
+	"github.com/grafana/agent/pkg/prom/cluster/configapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListConfigs_Pages(t *testing.T) {
+	all := []string{"a", "b", "c", "d", "e"}
+
+	var requests []client.ListConfigsOptions
+	cli := &mockFuncPromClient{}
+	cli.ListConfigsOptionsFunc = func(_ context.Context, opts client.ListConfigsOptions) (*configapi.ListConfigurationsResponse, error) {
+		requests = append(requests, opts)
+
+		var page []string
+		for _, name := range all {
+			if name > opts.After {
+				page = append(page, name)
+			}
+		}
+
+		var resp configapi.ListConfigurationsResponse
+		if opts.Limit > 0 && len(page) > opts.Limit {
+			page = page[:opts.Limit]
+			resp.Next = page[len(page)-1]
+		}
+		resp.Configs = page
+		return &resp, nil
+	}
+
+	names, err := ListConfigs(context.Background(), cli, `{team="infra"}`, 2)
+	require.NoError(t, err)
+	require.Equal(t, all, names)
+
+	require.Equal(t, []client.ListConfigsOptions{
+		{Selector: `{team="infra"}`, Limit: 2},
+		{Selector: `{team="infra"}`, Limit: 2, After: "b"},
+		{Selector: `{team="infra"}`, Limit: 2, After: "d"},
+	}, requests)
+}
+
+func TestListConfigs_RepeatedPage(t *testing.T) {
+	cli := &mockFuncPromClient{}
+	cli.ListConfigsOptionsFunc = func(_ context.Context, opts client.ListConfigsOptions) (*configapi.ListConfigurationsResponse, error) {
+		return &configapi.ListConfigurationsResponse{Configs: []string{"a"}, Next: "a"}, nil
+	}
+
+	_, err := ListConfigs(context.Background(), cli, "", 1)
+	require.Error(t, err)
+}
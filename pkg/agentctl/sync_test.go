@@ -5,6 +5,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/grafana/agent/pkg/client"
 	"github.com/grafana/agent/pkg/prom/cluster/configapi"
 	"github.com/grafana/agent/pkg/prom/instance"
 	"github.com/stretchr/testify/require"
@@ -95,7 +96,9 @@ func TestConfigSync_DryRun(t *testing.T) {
 
 type mockFuncPromClient struct {
 	InstancesFunc           func(ctx context.Context) ([]string, error)
+	TargetsFunc             func(ctx context.Context) (configapi.ListTargetsResponse, error)
 	ListConfigsFunc         func(ctx context.Context) (*configapi.ListConfigurationsResponse, error)
+	ListConfigsOptionsFunc  func(ctx context.Context, opts client.ListConfigsOptions) (*configapi.ListConfigurationsResponse, error)
 	GetConfigurationFunc    func(ctx context.Context, name string) (*instance.Config, error)
 	PutConfigurationFunc    func(ctx context.Context, name string, cfg *instance.Config) error
 	DeleteConfigurationFunc func(ctx context.Context, name string) error
@@ -108,6 +111,13 @@ func (m mockFuncPromClient) Instances(ctx context.Context) ([]string, error) {
 	return nil, errors.New("not implemented")
 }
 
+func (m mockFuncPromClient) Targets(ctx context.Context) (configapi.ListTargetsResponse, error) {
+	if m.TargetsFunc != nil {
+		return m.TargetsFunc(ctx)
+	}
+	return nil, errors.New("not implemented")
+}
+
 func (m mockFuncPromClient) ListConfigs(ctx context.Context) (*configapi.ListConfigurationsResponse, error) {
 	if m.ListConfigsFunc != nil {
 		return m.ListConfigsFunc(ctx)
@@ -115,6 +125,13 @@ func (m mockFuncPromClient) ListConfigs(ctx context.Context) (*configapi.ListCon
 	return nil, errors.New("not implemented")
 }
 
+func (m mockFuncPromClient) ListConfigsWithOptions(ctx context.Context, opts client.ListConfigsOptions) (*configapi.ListConfigurationsResponse, error) {
+	if m.ListConfigsOptionsFunc != nil {
+		return m.ListConfigsOptionsFunc(ctx, opts)
+	}
+	return nil, errors.New("not implemented")
+}
+
 func (m mockFuncPromClient) GetConfiguration(ctx context.Context, name string) (*instance.Config, error) {
 	if m.GetConfigurationFunc != nil {
 		return m.GetConfigurationFunc(ctx, name)
@@ -0,0 +1,219 @@
+package agentctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wal"
+)
+
+// WALInspection describes the files within a WAL directory.
+type WALInspection struct {
+	// Checkpoint is the most recent checkpoint. Nil if the WAL has no
+	// checkpoint.
+	Checkpoint *WALFileInfo
+
+	// Segments are the segment files which are read after the checkpoint, in
+	// order.
+	Segments []WALFileInfo
+
+	// TempCheckpoints are the names of temporary checkpoint directories left
+	// behind by a checkpoint that never finished.
+	TempCheckpoints []string
+}
+
+// Corrupted returns true if the checkpoint or any segment is corrupt.
+func (i WALInspection) Corrupted() bool {
+	if i.Checkpoint != nil && i.Checkpoint.Corruption != nil {
+		return true
+	}
+	for _, s := range i.Segments {
+		if s.Corruption != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// WALFileInfo describes a segment or checkpoint within a WAL.
+type WALFileInfo struct {
+	// Index of the segment, or of the last segment included in a checkpoint.
+	Index int
+	// Path of the segment file or checkpoint directory.
+	Path string
+	// Size of the segment or checkpoint in bytes.
+	Size int64
+	// Records is the number of records that could be read before reaching
+	// the end of the file or a corruption.
+	Records int
+	// Corruption is set when reading stopped at a corrupted record.
+	Corruption *wal.CorruptionErr
+}
+
+// InspectWAL reads every record of the latest checkpoint and the segments
+// after it in walDir. Corrupted files are reported in the returned
+// WALInspection rather than as an error.
+func InspectWAL(walDir string) (WALInspection, error) {
+	var res WALInspection
+
+	tempCheckpoints, err := filepath.Glob(filepath.Join(walDir, "checkpoint.*.tmp"))
+	if err != nil {
+		return res, err
+	}
+	res.TempCheckpoints = tempCheckpoints
+
+	checkpoint, checkpointIdx, err := wal.LastCheckpoint(walDir)
+	if err != nil && err != record.ErrNotFound {
+		return res, err
+	}
+
+	startIdx, lastIdx, err := wal.Segments(walDir)
+	if err != nil {
+		return res, err
+	}
+
+	if checkpoint != "" {
+		info, err := inspectCheckpoint(checkpoint, checkpointIdx)
+		if err != nil {
+			return res, err
+		}
+		res.Checkpoint = &info
+		startIdx = checkpointIdx + 1
+	}
+
+	// An empty WAL directory has no segments and reports lastIdx as -1.
+	for i := startIdx; i <= lastIdx && lastIdx >= 0; i++ {
+		info, err := inspectSegment(walDir, i)
+		if err != nil {
+			return res, err
+		}
+		res.Segments = append(res.Segments, info)
+	}
+
+	return res, nil
+}
+
+func inspectCheckpoint(dir string, idx int) (WALFileInfo, error) {
+	info := WALFileInfo{Index: idx, Path: dir}
+
+	err := filepath.Walk(dir, func(_ string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			info.Size += fi.Size()
+		}
+		return err
+	})
+	if err != nil {
+		return info, err
+	}
+
+	sr, err := wal.NewSegmentsReader(dir)
+	if err != nil {
+		return info, err
+	}
+	defer sr.Close()
+
+	info.Records, info.Corruption = countRecords(wal.NewReader(sr))
+	return info, nil
+}
+
+func inspectSegment(walDir string, idx int) (WALFileInfo, error) {
+	info := WALFileInfo{Index: idx, Path: wal.SegmentName(walDir, idx)}
+
+	fi, err := os.Stat(info.Path)
+	if err != nil {
+		return info, err
+	}
+	info.Size = fi.Size()
+
+	s, err := wal.OpenReadSegment(info.Path)
+	if err != nil {
+		return info, err
+	}
+	sr := wal.NewSegmentBufReader(s)
+	defer sr.Close()
+
+	info.Records, info.Corruption = countRecords(wal.NewReader(sr))
+	return info, nil
+}
+
+// countRecords reads all records from r, returning the number of records read
+// and the corruption reading stopped at, if any.
+func countRecords(r *wal.Reader) (int, *wal.CorruptionErr) {
+	var records int
+	for r.Next() {
+		records++
+	}
+
+	var cerr *wal.CorruptionErr
+	if err := r.Err(); err != nil && !errors.As(err, &cerr) {
+		cerr = &wal.CorruptionErr{Err: err, Segment: -1}
+	}
+	return records, cerr
+}
+
+// RepairWAL repairs a WAL found to be corrupted by InspectWAL. The WAL must
+// not be in use by a running Agent. Repairing:
+//
+// 1. Deletes temporary checkpoint directories.
+// 2. Deletes the latest checkpoint if it is corrupt. Series defined only in
+//    the checkpoint are lost, and their samples in later segments are
+//    dropped when the WAL is replayed.
+// 3. Truncates the first corrupted segment at the corrupted record and
+//    deletes all segments after it.
+//
+// If dryRun is true, the actions are logged but nothing is changed.
+func RepairWAL(logger log.Logger, walDir string, dryRun bool) error {
+	inspection, err := InspectWAL(walDir)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range inspection.TempCheckpoints {
+		level.Info(logger).Log("msg", "deleting temporary checkpoint", "dir", dir)
+		if dryRun {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to delete temporary checkpoint: %w", err)
+		}
+	}
+
+	if cp := inspection.Checkpoint; cp != nil && cp.Corruption != nil {
+		level.Warn(logger).Log("msg", "deleting corrupted checkpoint", "dir", cp.Path, "err", cp.Corruption)
+		if !dryRun {
+			if err := os.RemoveAll(cp.Path); err != nil {
+				return fmt.Errorf("failed to delete checkpoint: %w", err)
+			}
+		}
+	}
+
+	for _, s := range inspection.Segments {
+		if s.Corruption == nil {
+			continue
+		}
+		if s.Corruption.Segment < 0 {
+			return fmt.Errorf("cannot repair segment %d: %w", s.Index, s.Corruption)
+		}
+
+		level.Warn(logger).Log("msg", "truncating corrupted segment and deleting all later segments", "segment", s.Index, "offset", s.Corruption.Offset, "err", s.Corruption.Err)
+		if dryRun {
+			return nil
+		}
+
+		// Open the WAL the same way the Agent does so repaired records are
+		// rewritten with the same compression.
+		w, err := wal.NewSize(logger, nil, walDir, wal.DefaultSegmentSize, true)
+		if err != nil {
+			return fmt.Errorf("failed to open WAL: %w", err)
+		}
+		defer w.Close()
+		return w.Repair(s.Corruption)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package agentctl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/prometheus/tsdb/wal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectWAL(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	inspection, err := InspectWAL(walDir)
+	require.NoError(t, err)
+	require.False(t, inspection.Corrupted())
+
+	require.NotNil(t, inspection.Checkpoint)
+	require.Equal(t, 1, inspection.Checkpoint.Index)
+	require.Equal(t, 1, inspection.Checkpoint.Records)
+
+	var indexes []int
+	for _, s := range inspection.Segments {
+		indexes = append(indexes, s.Index)
+	}
+	require.Equal(t, []int{2, 3}, indexes)
+	require.Equal(t, 1, inspection.Segments[0].Records)
+	require.Equal(t, 0, inspection.Segments[1].Records)
+}
+
+func TestRepairWAL(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	// Leave behind a temporary checkpoint and corrupt the segment holding the
+	// samples.
+	tmpCheckpoint := filepath.Join(walDir, "checkpoint.00000003.tmp")
+	require.NoError(t, os.Mkdir(tmpCheckpoint, 0755))
+	corruptSegment(t, wal.SegmentName(walDir, 2))
+
+	inspection, err := InspectWAL(walDir)
+	require.NoError(t, err)
+	require.True(t, inspection.Corrupted())
+	require.Equal(t, []string{tmpCheckpoint}, inspection.TempCheckpoints)
+	require.NotNil(t, inspection.Segments[0].Corruption)
+	require.Equal(t, 2, inspection.Segments[0].Corruption.Segment)
+
+	// A dry run must not change anything.
+	require.NoError(t, RepairWAL(log.NewNopLogger(), walDir, true))
+	dryRun, err := InspectWAL(walDir)
+	require.NoError(t, err)
+	require.True(t, dryRun.Corrupted())
+	require.Equal(t, inspection.TempCheckpoints, dryRun.TempCheckpoints)
+	require.Len(t, dryRun.Segments, len(inspection.Segments))
+
+	require.NoError(t, RepairWAL(log.NewNopLogger(), walDir, false))
+	repaired, err := InspectWAL(walDir)
+	require.NoError(t, err)
+	require.False(t, repaired.Corrupted())
+	require.Empty(t, repaired.TempCheckpoints)
+
+	// The checkpoint was healthy and must be kept.
+	require.NotNil(t, repaired.Checkpoint)
+	require.Equal(t, 1, repaired.Checkpoint.Records)
+
+	// The corrupted record is dropped from segment 2. Repairing replaces the
+	// segments after it with a new, empty segment.
+	require.Equal(t, 2, repaired.Segments[0].Index)
+	for _, s := range repaired.Segments {
+		require.Equal(t, 0, s.Records)
+	}
+}
+
+func TestRepairWAL_CorruptedCheckpoint(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	inspection, err := InspectWAL(walDir)
+	require.NoError(t, err)
+	corruptSegment(t, wal.SegmentName(inspection.Checkpoint.Path, 0))
+
+	require.NoError(t, RepairWAL(log.NewNopLogger(), walDir, false))
+	repaired, err := InspectWAL(walDir)
+	require.NoError(t, err)
+	require.False(t, repaired.Corrupted())
+	require.Nil(t, repaired.Checkpoint)
+}
+
+// corruptSegment overwrites the header of the first record in the segment at
+// path with an invalid record type.
+func corruptSegment(t *testing.T, path string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.WriteAt([]byte{0xff}, 0)
+	require.NoError(t, err)
+}
@@ -9,22 +9,27 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/grafana/agent/pkg/prom/cluster/configapi"
 	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/tempo"
 	"gopkg.in/yaml.v2"
 )
 
 // Client is a collection of all subsystem clients.
 type Client struct {
 	PrometheusClient
+	TracesClient
 }
 
 // New creates a new Client.
 func New(addr string) *Client {
 	return &Client{
 		PrometheusClient: &prometheusClient{addr: addr},
+		TracesClient:     &tracesClient{addr: addr},
 	}
 }
 
@@ -34,6 +39,9 @@ type PrometheusClient interface {
 	// Instances runs the list of currently running instances.
 	Instances(ctx context.Context) ([]string, error)
 
+	// Targets returns the scrape targets of every running instance.
+	Targets(ctx context.Context) (configapi.ListTargetsResponse, error)
+
 	// The following methods are for the scraping service mode
 	// only and will fail when not enabled on the Agent.
 
@@ -41,6 +49,10 @@ type PrometheusClient interface {
 	// management KV store.
 	ListConfigs(ctx context.Context) (*configapi.ListConfigurationsResponse, error)
 
+	// ListConfigsWithOptions is like ListConfigs, but filters and paginates
+	// the list of instance configs using opts.
+	ListConfigsWithOptions(ctx context.Context, opts ListConfigsOptions) (*configapi.ListConfigurationsResponse, error)
+
 	// GetConfiguration returns a named configuration from the config
 	// management KV store.
 	GetConfiguration(ctx context.Context, name string) (*instance.Config, error)
@@ -61,7 +73,7 @@ type prometheusClient struct {
 func (c *prometheusClient) Instances(ctx context.Context) ([]string, error) {
 	url := fmt.Sprintf("%s/agent/api/v1/instances", c.addr)
 
-	resp, err := c.doRequest(ctx, "GET", url, nil)
+	resp, err := doRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -71,10 +83,62 @@ func (c *prometheusClient) Instances(ctx context.Context) ([]string, error) {
 	return data, err
 }
 
+func (c *prometheusClient) Targets(ctx context.Context) (configapi.ListTargetsResponse, error) {
+	url := fmt.Sprintf("%s/agent/api/v1/metrics/targets", c.addr)
+
+	resp, err := doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data configapi.ListTargetsResponse
+	err = unmarshalPrometheusAPIResponse(resp.Body, &data)
+	return data, err
+}
+
 func (c *prometheusClient) ListConfigs(ctx context.Context) (*configapi.ListConfigurationsResponse, error) {
 	url := fmt.Sprintf("%s/agent/api/v1/configs", c.addr)
 
-	resp, err := c.doRequest(ctx, "GET", url, nil)
+	resp, err := doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data configapi.ListConfigurationsResponse
+	err = unmarshalPrometheusAPIResponse(resp.Body, &data)
+	return &data, err
+}
+
+// ListConfigsOptions filters and paginates the list of instance configs.
+type ListConfigsOptions struct {
+	// Selector only lists configs whose labels match the selector, e.g.,
+	// {team="infra"}.
+	Selector string
+
+	// Limit is the maximum number of configs to return. 0 returns all
+	// configs.
+	Limit int
+
+	// After only lists configs sorted after the given name. Set to the Next
+	// field of a previous response to get the next page.
+	After string
+}
+
+func (c *prometheusClient) ListConfigsWithOptions(ctx context.Context, opts ListConfigsOptions) (*configapi.ListConfigurationsResponse, error) {
+	params := url.Values{}
+	if opts.Selector != "" {
+		params.Set("selector", opts.Selector)
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.After != "" {
+		params.Set("after", opts.After)
+	}
+
+	url := fmt.Sprintf("%s/agent/api/v1/configs?%s", c.addr, params.Encode())
+
+	resp, err := doRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +151,7 @@ func (c *prometheusClient) ListConfigs(ctx context.Context) (*configapi.ListConf
 func (c *prometheusClient) GetConfiguration(ctx context.Context, name string) (*instance.Config, error) {
 	url := fmt.Sprintf("%s/agent/api/v1/configs/%s", c.addr, name)
 
-	resp, err := c.doRequest(ctx, "GET", url, nil)
+	resp, err := doRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +174,7 @@ func (c *prometheusClient) PutConfiguration(ctx context.Context, name string, cf
 		return err
 	}
 
-	resp, err := c.doRequest(ctx, "POST", url, bytes.NewReader(bb))
+	resp, err := doRequest(ctx, "POST", url, bytes.NewReader(bb))
 	if err != nil {
 		return err
 	}
@@ -121,7 +185,7 @@ func (c *prometheusClient) PutConfiguration(ctx context.Context, name string, cf
 func (c *prometheusClient) DeleteConfiguration(ctx context.Context, name string) error {
 	url := fmt.Sprintf("%s/agent/api/v1/config/%s", c.addr, name)
 
-	resp, err := c.doRequest(ctx, "DELETE", url, nil)
+	resp, err := doRequest(ctx, "DELETE", url, nil)
 	if err != nil {
 		return err
 	}
@@ -129,7 +193,49 @@ func (c *prometheusClient) DeleteConfiguration(ctx context.Context, name string)
 	return unmarshalPrometheusAPIResponse(resp.Body, nil)
 }
 
-func (c *prometheusClient) doRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Response, error) {
+// TracesClient is the client interface to the API exposed by the Tempo
+// subsystem of the Grafana Agent.
+type TracesClient interface {
+	// TracesStatus returns the receivers and exporters of every running
+	// instance.
+	TracesStatus(ctx context.Context) (tempo.StatusResponse, error)
+
+	// TracesOTelConfig returns the OpenTelemetry collector config generated
+	// for a running instance.
+	TracesOTelConfig(ctx context.Context, instance string) (string, error)
+}
+
+type tracesClient struct {
+	addr string
+}
+
+func (c *tracesClient) TracesStatus(ctx context.Context) (tempo.StatusResponse, error) {
+	url := fmt.Sprintf("%s/agent/api/v1/traces/status", c.addr)
+
+	resp, err := doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data tempo.StatusResponse
+	err = unmarshalPrometheusAPIResponse(resp.Body, &data)
+	return data, err
+}
+
+func (c *tracesClient) TracesOTelConfig(ctx context.Context, instance string) (string, error) {
+	url := fmt.Sprintf("%s/agent/api/v1/traces/otel_config/%s", c.addr, instance)
+
+	resp, err := doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var data tempo.OTelConfigResponse
+	err = unmarshalPrometheusAPIResponse(resp.Body, &data)
+	return data.Value, err
+}
+
+func doRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
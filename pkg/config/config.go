@@ -1,21 +1,25 @@
 package config
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 
-	"github.com/weaveworks/common/server"
-
 	"github.com/drone/envsubst"
+	"github.com/grafana/agent/pkg/governor"
 	"github.com/grafana/agent/pkg/integrations"
 	"github.com/grafana/agent/pkg/loki"
 	"github.com/grafana/agent/pkg/prom"
+	"github.com/grafana/agent/pkg/summary"
 	"github.com/grafana/agent/pkg/tempo"
+	"github.com/grafana/agent/pkg/usage"
 	"github.com/grafana/agent/pkg/util"
+	"github.com/grafana/agent/pkg/util/server"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/version"
+	node_https "github.com/prometheus/node_exporter/https"
 	"gopkg.in/yaml.v2"
 )
 
@@ -39,6 +43,25 @@ type Config struct {
 	// to restart.
 	ReloadAddress string `yaml:"-"`
 	ReloadPort    int    `yaml:"-"`
+
+	// ServerAuth configures authentication for the HTTP server and the
+	// secondary /-/reload server.
+	ServerAuth server.AuthConfig `yaml:"-"`
+
+	// DynamicConfig loads the config file from remote sources.
+	DynamicConfig DynamicConfig `yaml:"-"`
+	// Template renders the config file as a template.
+	Template TemplateConfig `yaml:"-"`
+	// Secrets resolves references to secrets in the config file.
+	Secrets SecretsConfig `yaml:"-"`
+	// Usage tracks the usage of every instance and tenant.
+	Usage usage.Config `yaml:"-"`
+	// Summary reports a compact snapshot of the health of the Agent.
+	Summary summary.Config `yaml:"-"`
+	// Governor applies backpressure when resource usage is too high.
+	Governor governor.Config `yaml:"-"`
+	// Mode selects which subsystems run.
+	Mode Mode `yaml:"-"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -54,6 +77,13 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // ApplyDefaults sets default values in the config
 func (c *Config) ApplyDefaults() error {
+	if err := applyServerTLSDefaults("http_tls_config", &c.Server.HTTPTLSConfig); err != nil {
+		return err
+	}
+	if err := applyServerTLSDefaults("grpc_tls_config", &c.Server.GRPCTLSConfig); err != nil {
+		return err
+	}
+
 	if err := c.Prometheus.ApplyDefaults(); err != nil {
 		return err
 	}
@@ -81,6 +111,28 @@ func (c *Config) ApplyDefaults() error {
 	return nil
 }
 
+// applyServerTLSDefaults validates the TLS config of a server listener and
+// sets its default minimum TLS version. The server only enables TLS when both
+// a certificate and a key are set, so an incomplete TLS config is rejected
+// instead of silently serving plaintext.
+func applyServerTLSDefaults(name string, c *node_https.TLSStruct) error {
+	enabled := c.TLSCertPath != "" && c.TLSKeyPath != ""
+	if !enabled {
+		if c.TLSCertPath != "" || c.TLSKeyPath != "" || c.ClientAuth != "" || c.ClientCAs != "" {
+			return fmt.Errorf("server.%s: cert_file and key_file must both be set to enable TLS", name)
+		}
+		return nil
+	}
+
+	if c.MinVersion == 0 {
+		c.MinVersion = tls.VersionTLS12
+	}
+	if c.MaxVersion != 0 && c.MaxVersion < c.MinVersion {
+		return fmt.Errorf("server.%s: max_version must not be less than min_version", name)
+	}
+	return nil
+}
+
 // RegisterFlags registers flags in underlying configs
 func (c *Config) RegisterFlags(f *flag.FlagSet) {
 	c.Server.MetricsNamespace = "agent"
@@ -90,6 +142,17 @@ func (c *Config) RegisterFlags(f *flag.FlagSet) {
 
 	f.StringVar(&c.ReloadAddress, "reload-addr", "127.0.0.1", "address to expose a secondary server for /-/reload on.")
 	f.IntVar(&c.ReloadPort, "reload-port", 0, "port to expose a secondary server for /-/reload on. 0 disables secondary server.")
+	c.ServerAuth.RegisterFlags(f)
+
+	c.DynamicConfig.RegisterFlags(f)
+	c.Template.RegisterFlags(f)
+	c.Secrets.RegisterFlags(f)
+	c.Usage.RegisterFlags(f)
+	c.Summary.RegisterFlags(f)
+	c.Governor.RegisterFlags(f)
+
+	c.Mode = ModeAll
+	f.Var(&c.Mode, "mode", "Subsystems to run: all, metrics-only, logs-only, or traces-only. Sections of the config file for subsystems which don't run are ignored.")
 }
 
 // LoadFile reads a file and passes the contents to Load
@@ -103,7 +166,8 @@ func LoadFile(filename string, expandEnvVars bool, c *Config) error {
 
 // LoadBytes unmarshals a config from a buffer. Defaults are not
 // applied to the file and must be done manually if LoadBytes
-// is called directly.
+// is called directly. References to secrets are resolved when
+// c.Secrets is enabled.
 func LoadBytes(buf []byte, expandEnvVars bool, c *Config) error {
 	// (Optionally) expand with environment variables
 	if expandEnvVars {
@@ -113,6 +177,14 @@ func LoadBytes(buf []byte, expandEnvVars bool, c *Config) error {
 		}
 		buf = []byte(s)
 	}
+	// (Optionally) expand with secrets
+	if c.Secrets.Enabled {
+		var err error
+		buf, err = ExpandSecrets(buf, c.Secrets)
+		if err != nil {
+			return fmt.Errorf("unable to substitute config with secrets: %w", err)
+		}
+	}
 	// Unmarshal yaml config
 	return yaml.UnmarshalStrict(buf, c)
 }
@@ -133,11 +205,13 @@ func load(fs *flag.FlagSet, args []string, loader func(string, bool, *Config) er
 		printVersion    bool
 		file            string
 		configExpandEnv bool
+		dryRun          bool
 	)
 
 	fs.StringVar(&file, "config.file", "", "configuration file to load")
 	fs.BoolVar(&printVersion, "version", false, "Print this build's version information")
 	fs.BoolVar(&configExpandEnv, "config.expand-env", false, "Expands ${var} in config according to the values of the environment variables.")
+	fs.BoolVar(&dryRun, "config.dry-run", false, "Validate the config file, including building the tempo pipelines, and exit without starting the agent.")
 	cfg.RegisterFlags(fs)
 
 	if err := fs.Parse(args); err != nil {
@@ -149,10 +223,25 @@ func load(fs *flag.FlagSet, args []string, loader func(string, bool, *Config) er
 		os.Exit(0)
 	}
 
-	if file == "" {
-		return nil, fmt.Errorf("-config.file flag required")
-	} else if err := loader(file, configExpandEnv, &cfg); err != nil {
-		return nil, fmt.Errorf("error loading config file %s: %w", file, err)
+	switch {
+	case file != "" && cfg.DynamicConfig.Enabled():
+		return nil, fmt.Errorf("-config.file and -dynamic-config.url must not both be set")
+	case cfg.DynamicConfig.Enabled():
+		if err := LoadDynamic(cfg.DynamicConfig, cfg.Template, configExpandEnv, &cfg); err != nil {
+			return nil, fmt.Errorf("error loading dynamic config %s: %w", cfg.DynamicConfig.URL, err)
+		}
+	case file == "":
+		return nil, fmt.Errorf("-config.file or -dynamic-config.url flag required")
+	case !cfg.Template.Enabled && (len(cfg.Template.Snippets) > 0 || len(cfg.Template.Vars) > 0):
+		return nil, fmt.Errorf("-config.snippet and -config.var require -config.template")
+	case cfg.Template.Enabled:
+		if err := LoadTemplateFile(file, cfg.Template, configExpandEnv, &cfg); err != nil {
+			return nil, fmt.Errorf("error loading config file %s: %w", file, err)
+		}
+	default:
+		if err := loader(file, configExpandEnv, &cfg); err != nil {
+			return nil, fmt.Errorf("error loading config file %s: %w", file, err)
+		}
 	}
 
 	// Parse the flags again to override any YAML values with command line flag
@@ -166,5 +255,13 @@ func load(fs *flag.FlagSet, args []string, loader func(string, bool, *Config) er
 		return nil, fmt.Errorf("error in config file: %w", err)
 	}
 
+	if dryRun {
+		if err := cfg.Tempo.DryRun(&cfg.Loki); err != nil {
+			return nil, fmt.Errorf("error in config file: %w", err)
+		}
+		fmt.Printf("config file %s is valid\n", file)
+		os.Exit(0)
+	}
+
 	return &cfg, nil
 }
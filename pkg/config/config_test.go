@@ -1,7 +1,9 @@
 package config
 
 import (
+	"crypto/tls"
 	"flag"
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
@@ -99,6 +101,32 @@ prometheus:
 	require.Equal(t, "/tmp/wal", c.Prometheus.WALDir)
 }
 
+func TestConfig_Mode(t *testing.T) {
+	loadMode := func(args ...string) (*Config, error) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		args = append([]string{"-config.file", "test"}, args...)
+		return load(fs, args, func(_ string, _ bool, c *Config) error {
+			return LoadBytes([]byte(`{}`), false, c)
+		})
+	}
+
+	c, err := loadMode()
+	require.NoError(t, err)
+	require.Equal(t, ModeAll, c.Mode)
+	require.True(t, c.Mode.Metrics() && c.Mode.Logs() && c.Mode.Traces())
+
+	c, err = loadMode("-mode", "logs-only")
+	require.NoError(t, err)
+	require.Equal(t, ModeLogsOnly, c.Mode)
+	require.False(t, c.Mode.Metrics())
+	require.True(t, c.Mode.Logs())
+	require.False(t, c.Mode.Traces())
+
+	_, err = loadMode("-mode", "profiles-only")
+	require.Error(t, err)
+}
+
 func TestConfig_StrictYamlParsing(t *testing.T) {
 	t.Run("duplicate key", func(t *testing.T) {
 		cfg := `
@@ -204,7 +232,7 @@ tempo:
       backend: loki
       loki_name: default
       spans: true`,
-			expectedError: "error in config file: specified loki config default not found",
+			expectedError: "error in config file: tempo.configs[0].automatic_logging.loki_name: specified loki config default not found",
 		},
 	}
 
@@ -217,3 +245,77 @@ tempo:
 		require.EqualError(t, err, tc.expectedError)
 	}
 }
+
+func TestConfig_ServerTLS(t *testing.T) {
+	tt := []struct {
+		name       string
+		cfg        string
+		expectErr  string
+		expectHTTP uint16
+		expectGRPC uint16
+	}{
+		{
+			name: "disabled",
+			cfg:  `{}`,
+		},
+		{
+			name: "default min version",
+			cfg: `
+server:
+  http_tls_config:
+    cert_file: /tmp/server.crt
+    key_file: /tmp/server.key
+  grpc_tls_config:
+    cert_file: /tmp/server.crt
+    key_file: /tmp/server.key
+    client_auth_type: RequireAndVerifyClientCert
+    client_ca_file: /tmp/ca.crt
+    min_version: TLS13`,
+			expectHTTP: tls.VersionTLS12,
+			expectGRPC: tls.VersionTLS13,
+		},
+		{
+			name: "missing key",
+			cfg: `
+server:
+  http_tls_config:
+    cert_file: /tmp/server.crt`,
+			expectErr: "server.http_tls_config: cert_file and key_file must both be set to enable TLS",
+		},
+		{
+			name: "client CA without certificate",
+			cfg: `
+server:
+  grpc_tls_config:
+    client_ca_file: /tmp/ca.crt`,
+			expectErr: "server.grpc_tls_config: cert_file and key_file must both be set to enable TLS",
+		},
+		{
+			name: "max version less than min version",
+			cfg: `
+server:
+  http_tls_config:
+    cert_file: /tmp/server.crt
+    key_file: /tmp/server.key
+    max_version: TLS11`,
+			expectErr: "server.http_tls_config: max_version must not be less than min_version",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ExitOnError)
+			c, err := load(fs, []string{"-config.file", "test"}, func(_ string, _ bool, c *Config) error {
+				return LoadBytes([]byte(tc.cfg), false, c)
+			})
+			if tc.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.EqualValues(t, tc.expectHTTP, c.Server.HTTPTLSConfig.MinVersion)
+			require.EqualValues(t, tc.expectGRPC, c.Server.GRPCTLSConfig.MinVersion)
+		})
+	}
+}
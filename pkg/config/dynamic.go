@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// fetchTimeout is the maximum amount of time to spend fetching a single
+// remote file.
+const fetchTimeout = time.Minute
+
+// DynamicConfig configures loading the config file from remote sources
+// instead of from disk.
+type DynamicConfig struct {
+	// URL of the template used as the config file. Supported schemes are
+	// http, https, s3, gs, and file.
+	URL string
+
+	// PollInterval is how often to fetch the config file again and apply it
+	// when it changed. 0 disables polling.
+	PollInterval time.Duration
+}
+
+// RegisterFlags registers flags for the DynamicConfig.
+func (c *DynamicConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&c.URL, "dynamic-config.url", "", "URL of a config file template to load instead of -config.file. Supports http, https, s3, gs, and file URLs.")
+	f.DurationVar(&c.PollInterval, "dynamic-config.poll-interval", 0, "how often to fetch the dynamic config again and apply it when it changed. 0 disables polling.")
+}
+
+// Enabled returns true when the config file should be loaded from URL.
+func (c *DynamicConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// LoadDynamic fetches the config file configured by dc, renders it as a
+// template with the snippets and variables from tc, and unmarshals the result
+// into c. Like LoadBytes, defaults are not applied.
+func LoadDynamic(dc DynamicConfig, tc TemplateConfig, expandEnvVars bool, c *Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	buf, err := fetchURL(ctx, dc.URL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", dc.URL, err)
+	}
+	buf, err = renderTemplate(dc.URL, buf, tc)
+	if err != nil {
+		return err
+	}
+	return LoadBytes(buf, expandEnvVars, c)
+}
+
+// fetchURL reads the contents of the file at rawURL.
+func fetchURL(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		return ioutil.ReadAll(resp.Body)
+
+	case "s3":
+		sess, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s3.New(sess).GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(u.Host),
+			Key:    aws.String(strings.TrimPrefix(u.Path, "/")),
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return ioutil.ReadAll(resp.Body)
+
+	case "gs":
+		cli, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer cli.Close()
+		r, err := cli.Bucket(u.Host).Object(strings.TrimPrefix(u.Path, "/")).NewReader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+
+	case "file":
+		return ioutil.ReadFile(u.Path)
+
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDynamic(t *testing.T) {
+	files := map[string]string{
+		"/agent.yml": `
+prometheus:
+  wal_directory: /tmp/wal
+  global:
+{{ template "global.yml" }}`,
+		"/snippets/global.yml": `    scrape_interval: {{ list "5" "s" | join "" }}`,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		f, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(rw, r)
+			return
+		}
+		_, _ = rw.Write([]byte(f))
+	}))
+	defer srv.Close()
+
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	c, err := load(fs, []string{
+		"-dynamic-config.url", srv.URL + "/agent.yml",
+		"-config.snippet", srv.URL + "/snippets/global.yml",
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, model.Duration(5*time.Second), c.Prometheus.Global.Prometheus.ScrapeInterval)
+	require.Equal(t, srv.URL+"/agent.yml", c.DynamicConfig.URL)
+
+	t.Run("missing snippet", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		_, err := load(fs, []string{"-dynamic-config.url", srv.URL + "/agent.yml"}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `template "global.yml" not defined`)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		_, err := load(fs, []string{"-dynamic-config.url", srv.URL + "/missing.yml"}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unexpected status code 404")
+	})
+
+	t.Run("both config.file and dynamic-config.url", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		_, err := load(fs, []string{"-config.file", "test", "-dynamic-config.url", srv.URL + "/agent.yml"}, nil)
+		require.EqualError(t, err, "-config.file and -dynamic-config.url must not both be set")
+	})
+}
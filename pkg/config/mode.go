@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode selects which subsystems of the Agent run. Subsystems which aren't
+// part of the mode are never created, so they don't use any memory or listen
+// on any ports, and their sections of the config file are ignored.
+type Mode string
+
+// Supported modes.
+const (
+	// ModeAll runs every subsystem.
+	ModeAll Mode = "all"
+	// ModeMetricsOnly runs metrics collection, including integrations and
+	// Kubernetes monitors.
+	ModeMetricsOnly Mode = "metrics-only"
+	// ModeLogsOnly runs logs collection.
+	ModeLogsOnly Mode = "logs-only"
+	// ModeTracesOnly runs traces collection.
+	ModeTracesOnly Mode = "traces-only"
+)
+
+var modes = []Mode{ModeAll, ModeMetricsOnly, ModeLogsOnly, ModeTracesOnly}
+
+// String implements flag.Value.
+func (m *Mode) String() string {
+	if *m == "" {
+		return string(ModeAll)
+	}
+	return string(*m)
+}
+
+// Set implements flag.Value.
+func (m *Mode) Set(s string) error {
+	for _, mode := range modes {
+		if Mode(s) == mode {
+			*m = mode
+			return nil
+		}
+	}
+
+	names := make([]string, 0, len(modes))
+	for _, mode := range modes {
+		names = append(names, string(mode))
+	}
+	return fmt.Errorf("unsupported mode %q, must be one of %s", s, strings.Join(names, ", "))
+}
+
+// Metrics returns true if the metrics subsystems run in m.
+func (m Mode) Metrics() bool { return m == "" || m == ModeAll || m == ModeMetricsOnly }
+
+// Logs returns true if the logs subsystem runs in m.
+func (m Mode) Logs() bool { return m == "" || m == ModeAll || m == ModeLogsOnly }
+
+// Traces returns true if the traces subsystem runs in m.
+func (m Mode) Traces() bool { return m == "" || m == ModeAll || m == ModeTracesOnly }
@@ -0,0 +1,422 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// inClusterNamespaceFile holds the namespace of the Pod the Agent runs in
+// when running in Kubernetes.
+const inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// secretRefRegexp matches references to secrets in the config file, e.g.,
+// $(secret:vault:secret/data/agent#password).
+var secretRefRegexp = regexp.MustCompile(`\$\(secret:([a-z]+):([^)]+)\)`)
+
+// SecretsConfig configures resolving references to secrets in the config
+// file.
+type SecretsConfig struct {
+	// Enabled resolves $(secret:<provider>:<reference>) in the config file.
+	Enabled bool
+
+	// CacheTTL is how long resolved secrets are cached before they're
+	// fetched again. Secrets with a shorter lease, such as leased Vault
+	// secrets, are cached for the duration of the lease instead.
+	CacheTTL time.Duration
+
+	// RefreshInterval is how often to load the config file again to renew
+	// expired secrets. 0 disables renewing secrets.
+	RefreshInterval time.Duration
+
+	Vault      VaultSecretsConfig
+	Kubernetes KubernetesSecretsConfig
+}
+
+// VaultSecretsConfig configures reading secrets from HashiCorp Vault.
+type VaultSecretsConfig struct {
+	// Address of the Vault server.
+	Address string
+	// TokenFile is read for the token to authenticate with. The VAULT_TOKEN
+	// environment variable is used when empty.
+	TokenFile string
+	// Namespace is the Vault Enterprise namespace to read secrets from.
+	Namespace string
+}
+
+// KubernetesSecretsConfig configures reading Kubernetes Secrets.
+type KubernetesSecretsConfig struct {
+	// KubeconfigFile is used to connect to Kubernetes. The in-cluster config
+	// is used when empty.
+	KubeconfigFile string
+	// Namespace of Secrets which are referenced without a namespace.
+	Namespace string
+}
+
+// RegisterFlags registers flags for the SecretsConfig.
+func (c *SecretsConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.Enabled, "config.expand-secrets", false, "Expands $(secret:<provider>:<reference>) in config with the values of secrets.")
+	f.DurationVar(&c.CacheTTL, "secrets.cache-ttl", 5*time.Minute, "how long to cache secrets before fetching them again.")
+	f.DurationVar(&c.RefreshInterval, "secrets.refresh-interval", 0, "how often to load the config file again to renew expired secrets. 0 disables renewing secrets.")
+
+	f.StringVar(&c.Vault.Address, "secrets.vault.address", os.Getenv("VAULT_ADDR"), "address of the Vault server to read secrets from. Defaults to $VAULT_ADDR.")
+	f.StringVar(&c.Vault.TokenFile, "secrets.vault.token-file", "", "file containing the Vault token. $VAULT_TOKEN is used when empty.")
+	f.StringVar(&c.Vault.Namespace, "secrets.vault.namespace", os.Getenv("VAULT_NAMESPACE"), "Vault namespace to read secrets from. Defaults to $VAULT_NAMESPACE.")
+
+	f.StringVar(&c.Kubernetes.KubeconfigFile, "secrets.kubernetes.kubeconfig-file", "", "kubeconfig used to read Kubernetes secrets. The in-cluster config is used when empty.")
+	f.StringVar(&c.Kubernetes.Namespace, "secrets.kubernetes.namespace", "", "namespace of Kubernetes secrets referenced without a namespace. Defaults to the namespace the Agent runs in.")
+}
+
+// secretProvider reads secrets from a source.
+type secretProvider interface {
+	// Get returns the value of the secret referenced by ref and how long it
+	// may be cached for. A ttl of 0 caches the value for the default TTL.
+	Get(ctx context.Context, ref string) (value string, ttl time.Duration, err error)
+}
+
+func newSecretProvider(name string, c SecretsConfig) (secretProvider, error) {
+	switch name {
+	case "env":
+		return envSecretProvider{}, nil
+	case "file":
+		return fileSecretProvider{}, nil
+	case "kubernetes":
+		return newKubernetesSecretProvider(c.Kubernetes)
+	case "vault":
+		return newVaultSecretProvider(c.Vault)
+	default:
+		return nil, fmt.Errorf("unknown secret provider %q", name)
+	}
+}
+
+// secretCache caches resolved secrets across config reloads.
+type secretCache struct {
+	mut     sync.Mutex
+	now     func() time.Time
+	entries map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+var secretsCache = &secretCache{
+	now:     time.Now,
+	entries: make(map[string]cachedSecret),
+}
+
+// get returns a cached secret for key if it hasn't expired yet.
+func (c *secretCache) get(key string) (string, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		return "", false
+	}
+	return e.value, true
+}
+
+func (c *secretCache) set(key, value string, ttl time.Duration) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.entries[key] = cachedSecret{value: value, expires: c.now().Add(ttl)}
+}
+
+// ExpandSecrets replaces references to secrets in the string values of the
+// YAML document buf with the values of the secrets. References have the form
+// $(secret:<provider>:<reference>).
+//
+// Secrets are cached for c.CacheTTL. buf is returned unmodified when it
+// doesn't contain any references.
+func ExpandSecrets(buf []byte, c SecretsConfig) ([]byte, error) {
+	if !secretRefRegexp.Match(buf) {
+		return buf, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	r := secretResolver{
+		ctx:       ctx,
+		cfg:       c,
+		cache:     secretsCache,
+		providers: make(map[string]secretProvider),
+	}
+
+	// Secrets are replaced within the parsed document rather than the raw
+	// text so values don't need to be escaped for YAML.
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, err
+	}
+	expanded, err := r.expand(doc)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(expanded)
+}
+
+type secretResolver struct {
+	ctx       context.Context
+	cfg       SecretsConfig
+	cache     *secretCache
+	providers map[string]secretProvider
+}
+
+func (r *secretResolver) expand(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return r.expandString(v)
+	case yaml.MapSlice:
+		for i, item := range v {
+			value, err := r.expand(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			v[i].Value = value
+		}
+		return v, nil
+	case []interface{}:
+		for i, item := range v {
+			value, err := r.expand(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = value
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+func (r *secretResolver) expandString(s string) (string, error) {
+	var firstErr error
+	res := secretRefRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		groups := secretRefRegexp.FindStringSubmatch(match)
+		value, err := r.resolve(groups[1], groups[2])
+		if err != nil {
+			firstErr = fmt.Errorf("failed to resolve secret %s: %w", match, err)
+			return match
+		}
+		return value
+	})
+	return res, firstErr
+}
+
+func (r *secretResolver) resolve(providerName, ref string) (string, error) {
+	key := providerName + ":" + ref
+	if value, ok := r.cache.get(key); ok {
+		return value, nil
+	}
+
+	p, ok := r.providers[providerName]
+	if !ok {
+		var err error
+		p, err = newSecretProvider(providerName, r.cfg)
+		if err != nil {
+			return "", err
+		}
+		r.providers[providerName] = p
+	}
+
+	value, ttl, err := p.Get(r.ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > r.cfg.CacheTTL {
+		ttl = r.cfg.CacheTTL
+	}
+	r.cache.set(key, value, ttl)
+	return value, nil
+}
+
+// envSecretProvider reads secrets from environment variables. References are
+// the name of the variable.
+type envSecretProvider struct{}
+
+func (envSecretProvider) Get(_ context.Context, ref string) (string, time.Duration, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", 0, fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, 0, nil
+}
+
+// fileSecretProvider reads secrets from files. References are the path of
+// the file. Trailing newlines are removed.
+type fileSecretProvider struct{}
+
+func (fileSecretProvider) Get(_ context.Context, ref string) (string, time.Duration, error) {
+	buf, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", 0, err
+	}
+	return strings.TrimRight(string(buf), "\r\n"), 0, nil
+}
+
+// kubernetesSecretProvider reads Kubernetes Secrets. References have the form
+// [<namespace>/]<name>#<key>.
+type kubernetesSecretProvider struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+func newKubernetesSecretProvider(c KubernetesSecretsConfig) (*kubernetesSecretProvider, error) {
+	var (
+		restConfig *rest.Config
+		err        error
+	)
+	if c.KubeconfigFile != "" {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", c.KubeconfigFile)
+	} else {
+		restConfig, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	namespace := c.Namespace
+	if namespace == "" {
+		namespace = "default"
+		if buf, err := ioutil.ReadFile(inClusterNamespaceFile); err == nil {
+			namespace = strings.TrimSpace(string(buf))
+		}
+	}
+	return &kubernetesSecretProvider{client: client, namespace: namespace}, nil
+}
+
+func (p *kubernetesSecretProvider) Get(ctx context.Context, ref string) (string, time.Duration, error) {
+	name, key, err := splitSecretKey(ref)
+	if err != nil {
+		return "", 0, err
+	}
+	namespace := p.namespace
+	if parts := strings.SplitN(name, "/", 2); len(parts) == 2 {
+		namespace, name = parts[0], parts[1]
+	}
+
+	secret, err := p.client.CoreV1().Secrets(namespace).Get(ctx, name, meta_v1.GetOptions{})
+	if err != nil {
+		return "", 0, err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", 0, fmt.Errorf("no key %s in Secret %s/%s", key, namespace, name)
+	}
+	return string(value), 0, nil
+}
+
+// vaultSecretProvider reads secrets from HashiCorp Vault. References have the
+// form <path>#<key>, where path is the API path of the secret, such as
+// secret/data/agent for a KV version 2 secrets engine mounted at secret/.
+type vaultSecretProvider struct {
+	cfg    VaultSecretsConfig
+	client *http.Client
+}
+
+func newVaultSecretProvider(c VaultSecretsConfig) (*vaultSecretProvider, error) {
+	if c.Address == "" {
+		return nil, fmt.Errorf("-secrets.vault.address must be set to read secrets from vault")
+	}
+	return &vaultSecretProvider{cfg: c, client: http.DefaultClient}, nil
+}
+
+func (p *vaultSecretProvider) Get(ctx context.Context, ref string) (string, time.Duration, error) {
+	path, key, err := splitSecretKey(ref)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// The token is read for every request so it can be rotated, e.g., by a
+	// Vault Agent sidecar.
+	token := os.Getenv("VAULT_TOKEN")
+	if p.cfg.TokenFile != "" {
+		buf, err := ioutil.ReadFile(p.cfg.TokenFile)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to read vault token: %w", err)
+		}
+		token = strings.TrimSpace(string(buf))
+	}
+
+	url := strings.TrimSuffix(p.cfg.Address, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if p.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cfg.Namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", 0, fmt.Errorf("unexpected status code %d reading %s", resp.StatusCode, path)
+	}
+
+	var secret struct {
+		LeaseDuration int                    `json:"lease_duration"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", 0, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	// KV version 2 secrets are nested within the data of the response.
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil {
+		data = nested
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", 0, fmt.Errorf("no key %s in vault secret %s", key, path)
+	}
+	ttl := time.Duration(secret.LeaseDuration) * time.Second
+
+	if s, ok := value.(string); ok {
+		return s, ttl, nil
+	}
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(buf), ttl, nil
+}
+
+// splitSecretKey splits a reference of the form <name>#<key>.
+func splitSecretKey(ref string) (name, key string, err error) {
+	idx := strings.LastIndex(ref, "#")
+	if idx <= 0 || idx == len(ref)-1 {
+		return "", "", fmt.Errorf("expected <name>#<key>, got %q", ref)
+	}
+	return ref[:idx], ref[idx+1:], nil
+}
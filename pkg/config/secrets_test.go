@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExpandSecrets(t *testing.T) {
+	resetSecretsCache(t)
+
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, ioutil.WriteFile(passwordFile, []byte("file: \"secret\"\n"), 0600))
+
+	require.NoError(t, os.Setenv("AGENT_TEST_SECRET", "env-secret"))
+	defer os.Unsetenv("AGENT_TEST_SECRET")
+
+	in := fmt.Sprintf(`
+prometheus:
+  global:
+    remote_write:
+    - url: http://localhost:9009/api/prom/push?token=$(secret:env:AGENT_TEST_SECRET)
+      basic_auth:
+        username: user
+        password: $(secret:file:%s)
+`, passwordFile)
+
+	out, err := ExpandSecrets([]byte(in), SecretsConfig{Enabled: true, CacheTTL: time.Minute})
+	require.NoError(t, err)
+
+	var actual struct {
+		Prometheus struct {
+			Global struct {
+				RemoteWrite []struct {
+					URL       string `yaml:"url"`
+					BasicAuth struct {
+						Username string `yaml:"username"`
+						Password string `yaml:"password"`
+					} `yaml:"basic_auth"`
+				} `yaml:"remote_write"`
+			} `yaml:"global"`
+		} `yaml:"prometheus"`
+	}
+	require.NoError(t, yaml.UnmarshalStrict(out, &actual))
+
+	rw := actual.Prometheus.Global.RemoteWrite
+	require.Len(t, rw, 1)
+	require.Equal(t, "http://localhost:9009/api/prom/push?token=env-secret", rw[0].URL)
+	require.Equal(t, "user", rw[0].BasicAuth.Username)
+	require.Equal(t, `file: "secret"`, rw[0].BasicAuth.Password)
+}
+
+func TestExpandSecrets_NoReferences(t *testing.T) {
+	in := []byte("prometheus:\n  wal_directory: /tmp/wal # comment\n")
+	out, err := ExpandSecrets(in, SecretsConfig{Enabled: true, CacheTTL: time.Minute})
+	require.NoError(t, err)
+	require.Equal(t, in, out)
+}
+
+func TestExpandSecrets_Errors(t *testing.T) {
+	tt := []struct {
+		name   string
+		in     string
+		expect string
+	}{
+		{
+			name:   "unknown provider",
+			in:     "password: $(secret:unknown:foo)",
+			expect: `unknown secret provider "unknown"`,
+		},
+		{
+			name:   "missing environment variable",
+			in:     "password: $(secret:env:AGENT_TEST_MISSING_SECRET)",
+			expect: "environment variable AGENT_TEST_MISSING_SECRET is not set",
+		},
+		{
+			name:   "vault without address",
+			in:     "password: $(secret:vault:secret/data/agent#password)",
+			expect: "-secrets.vault.address must be set",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			resetSecretsCache(t)
+
+			_, err := ExpandSecrets([]byte(tc.in), SecretsConfig{Enabled: true, CacheTTL: time.Minute})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expect)
+		})
+	}
+}
+
+func TestExpandSecrets_Vault(t *testing.T) {
+	resetSecretsCache(t)
+
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+		require.Equal(t, "team-a", r.Header.Get("X-Vault-Namespace"))
+
+		switch r.URL.Path {
+		case "/v1/secret/data/agent":
+			fmt.Fprint(w, `{"lease_duration": 0, "data": {"data": {"password": "kv-secret"}, "metadata": {"version": 1}}}`)
+		case "/v1/database/creds/agent":
+			fmt.Fprint(w, `{"lease_duration": 30, "data": {"password": "leased-secret"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("vault-token\n"), 0600))
+
+	cfg := SecretsConfig{
+		Enabled:  true,
+		CacheTTL: time.Minute,
+		Vault: VaultSecretsConfig{
+			Address:   srv.URL,
+			TokenFile: tokenFile,
+			Namespace: "team-a",
+		},
+	}
+
+	in := []byte(`
+kv: $(secret:vault:secret/data/agent#password)
+leased: $(secret:vault:database/creds/agent#password)
+`)
+	out, err := ExpandSecrets(in, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "kv: kv-secret\nleased: leased-secret\n", string(out))
+	require.Equal(t, 2, requests)
+
+	// Both secrets are cached.
+	_, err = ExpandSecrets(in, cfg)
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+
+	// The leased secret expires after its lease, the KV secret after the
+	// cache TTL.
+	advanceSecretsCache(t, 45*time.Second)
+	_, err = ExpandSecrets(in, cfg)
+	require.NoError(t, err)
+	require.Equal(t, 3, requests)
+
+	advanceSecretsCache(t, 30*time.Second)
+	_, err = ExpandSecrets(in, cfg)
+	require.NoError(t, err)
+	require.Equal(t, 5, requests)
+
+	_, err = ExpandSecrets([]byte("password: $(secret:vault:secret/data/missing#password)"), cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unexpected status code 404")
+}
+
+func TestLoadBytes_ExpandSecrets(t *testing.T) {
+	resetSecretsCache(t)
+
+	require.NoError(t, os.Setenv("AGENT_TEST_SECRET", "/tmp/wal"))
+	defer os.Unsetenv("AGENT_TEST_SECRET")
+
+	in := []byte(`
+prometheus:
+  wal_directory: $(secret:env:AGENT_TEST_SECRET)
+`)
+
+	var disabled Config
+	require.NoError(t, LoadBytes(in, false, &disabled))
+	require.Equal(t, "$(secret:env:AGENT_TEST_SECRET)", disabled.Prometheus.WALDir)
+
+	enabled := Config{Secrets: SecretsConfig{Enabled: true, CacheTTL: time.Minute}}
+	require.NoError(t, LoadBytes(in, false, &enabled))
+	require.Equal(t, "/tmp/wal", enabled.Prometheus.WALDir)
+}
+
+// resetSecretsCache clears the secrets cache and makes its clock
+// controllable by advanceSecretsCache.
+func resetSecretsCache(t *testing.T) {
+	t.Helper()
+
+	now := time.Now()
+	secretsCache.mut.Lock()
+	secretsCache.entries = make(map[string]cachedSecret)
+	secretsCache.now = func() time.Time { return now }
+	secretsCache.mut.Unlock()
+
+	t.Cleanup(func() {
+		secretsCache.mut.Lock()
+		secretsCache.entries = make(map[string]cachedSecret)
+		secretsCache.now = time.Now
+		secretsCache.mut.Unlock()
+	})
+}
+
+func advanceSecretsCache(t *testing.T, d time.Duration) {
+	t.Helper()
+
+	secretsCache.mut.Lock()
+	now := secretsCache.now().Add(d)
+	secretsCache.now = func() time.Time { return now }
+	secretsCache.mut.Unlock()
+}
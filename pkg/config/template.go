@@ -0,0 +1,174 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/cortexproject/cortex/pkg/util/flagext"
+)
+
+// TemplateConfig configures rendering the config file as a Go template.
+type TemplateConfig struct {
+	// Enabled renders -config.file as a template. Dynamic configs are always
+	// rendered as templates.
+	Enabled bool
+
+	// Snippets are paths or URLs of templates which may be included from the
+	// config file by their file name, e.g., {{ template "remote_write.yml" }}.
+	// Local paths may be glob patterns.
+	Snippets flagext.StringSlice
+
+	// Vars are made available to templates as {{ .Vars.name }}.
+	Vars TemplateVars
+}
+
+// RegisterFlags registers flags for the TemplateConfig.
+func (c *TemplateConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.Enabled, "config.template", false, "Render the config file as a Go template before parsing it.")
+	f.Var(&c.Snippets, "config.snippet", "Path or URL of a template snippet usable from the config file template. Local paths may be glob patterns. May be given multiple times.")
+	f.Var(&c.Vars, "config.var", "name=value variable usable from the config file template as {{ .Vars.name }}. May be given multiple times.")
+}
+
+// TemplateVars is a set of variables that can be set from flags.
+type TemplateVars map[string]string
+
+// String implements flag.Value.
+func (v TemplateVars) String() string {
+	pairs := make([]string, 0, len(v))
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value.
+func (v *TemplateVars) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	if *v == nil {
+		*v = make(TemplateVars)
+	}
+	(*v)[parts[0]] = parts[1]
+	return nil
+}
+
+// templateData is passed to the config file template when rendering it.
+type templateData struct {
+	// Hostname of the machine the Agent is running on.
+	Hostname string
+	Vars     TemplateVars
+}
+
+// LoadTemplateFile reads a file, renders it as a template and passes the
+// result to LoadBytes.
+func LoadTemplateFile(filename string, tc TemplateConfig, expandEnvVars bool, c *Config) error {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	buf, err = renderTemplate(filename, buf, tc)
+	if err != nil {
+		return err
+	}
+	return LoadBytes(buf, expandEnvVars, c)
+}
+
+// renderTemplate renders text as a template named name, which may include the
+// snippets from tc.
+func renderTemplate(name string, text []byte, tc TemplateConfig) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	tmpl, err := template.New(name).Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	snippets, err := expandSnippets(tc.Snippets)
+	if err != nil {
+		return nil, err
+	}
+	for _, snippet := range snippets {
+		snippetName, snippetText, err := readSnippet(ctx, snippet)
+		if err != nil {
+			return nil, err
+		}
+		if tmpl.Lookup(snippetName) != nil {
+			return nil, fmt.Errorf("found multiple snippets named %s", snippetName)
+		}
+		if _, err := tmpl.New(snippetName).Parse(string(snippetText)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", snippet, err)
+		}
+	}
+
+	data := templateData{Vars: tc.Vars}
+	if data.Hostname, err = os.Hostname(); err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// readSnippet reads the snippet at the given local path or URL, returning
+// its name and text.
+func readSnippet(ctx context.Context, snippet string) (name string, text []byte, err error) {
+	if isURL(snippet) {
+		u, err := url.Parse(snippet)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid snippet URL %s: %w", snippet, err)
+		}
+		text, err = fetchURL(ctx, snippet)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to fetch %s: %w", snippet, err)
+		}
+		return path.Base(u.Path), text, nil
+	}
+
+	text, err = ioutil.ReadFile(snippet)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read snippet: %w", err)
+	}
+	return filepath.Base(snippet), text, nil
+}
+
+func isURL(s string) bool {
+	return strings.Contains(s, "://")
+}
+
+// expandSnippets expands glob patterns of local snippet paths.
+func expandSnippets(snippets []string) ([]string, error) {
+	var res []string
+	for _, s := range snippets {
+		if isURL(s) {
+			res = append(res, s)
+			continue
+		}
+
+		matches, err := filepath.Glob(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snippet path %s: %w", s, err)
+		} else if len(matches) == 0 {
+			return nil, fmt.Errorf("no snippets found matching %s", s)
+		}
+		res = append(res, matches...)
+	}
+	return res, nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTemplateFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, text string) string {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, ioutil.WriteFile(p, []byte(text), 0644))
+		return p
+	}
+
+	file := writeFile("agent.yml", `
+prometheus:
+  wal_directory: /tmp/wal
+  global:
+{{ template "global.yml" . }}
+  configs:
+  - name: {{ .Hostname }}
+    remote_write:
+{{ template "remote_write.yml" . }}
+`)
+	writeFile("snippets/global.yml", `    scrape_interval: {{ .Vars.interval }}`)
+	writeFile("snippets/remote_write.yml", `    - url: http://{{ .Vars.cluster }}.example.com/api/prom/push`)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	c, err := load(fs, []string{
+		"-config.file", file,
+		"-config.template",
+		"-config.snippet", filepath.Join(dir, "snippets", "*.yml"),
+		"-config.var", "interval=5s",
+		"-config.var", "cluster=us-east",
+	}, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, model.Duration(5*time.Second), c.Prometheus.Global.Prometheus.ScrapeInterval)
+	require.Len(t, c.Prometheus.Configs, 1)
+	require.Equal(t, hostname, c.Prometheus.Configs[0].Name)
+	require.Equal(t, "http://us-east.example.com/api/prom/push", c.Prometheus.Configs[0].RemoteWrite[0].URL.String())
+
+	t.Run("missing variable", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		_, err := load(fs, []string{
+			"-config.file", file,
+			"-config.template",
+			"-config.snippet", filepath.Join(dir, "snippets", "*.yml"),
+			"-config.var", "interval=5s",
+		}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `map has no entry for key "cluster"`)
+	})
+
+	t.Run("snippets without templating", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		_, err := load(fs, []string{
+			"-config.file", file,
+			"-config.snippet", filepath.Join(dir, "snippets", "*.yml"),
+		}, nil)
+		require.EqualError(t, err, "-config.snippet and -config.var require -config.template")
+	})
+}
+
+func TestTemplateVars_Set(t *testing.T) {
+	var v TemplateVars
+	require.NoError(t, v.Set("a=b=c"))
+	require.NoError(t, v.Set("d="))
+	require.Equal(t, TemplateVars{"a": "b=c", "d": ""}, v)
+	require.Equal(t, "a=b=c,d=", v.String())
+
+	require.EqualError(t, v.Set("=b"), `expected name=value, got "=b"`)
+	require.EqualError(t, v.Set("ab"), `expected name=value, got "ab"`)
+}
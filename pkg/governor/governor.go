@@ -0,0 +1,369 @@
+// Package governor protects the Agent from being killed for using too much
+// memory or CPU. As resource usage crosses configured limits, the governor
+// applies increasing backpressure across subsystems: scraping of the lowest
+// priority metrics instances is paused and the sending queues of traces
+// exporters are shrunk.
+package governor
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Config holds the resource limits of the Agent. Limits set to 0 are
+// disabled.
+type Config struct {
+	// MemorySoftLimit and MemoryHardLimit are limits of the resident memory
+	// of the process in bytes.
+	MemorySoftLimit uint64
+	MemoryHardLimit uint64
+
+	// CPUSoftLimit and CPUHardLimit are limits of the number of CPU cores
+	// used by the process, averaged over CheckInterval.
+	CPUSoftLimit float64
+	CPUHardLimit float64
+
+	// CheckInterval is how often resource usage is checked.
+	CheckInterval time.Duration
+}
+
+// RegisterFlags registers flags for the Config.
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.Uint64Var(&c.MemorySoftLimit, "governor.memory-soft-limit-bytes", 0, "Resident memory at which lower priority scraping is paused one priority at a time and traces sending queues are halved. 0 disables the limit.")
+	f.Uint64Var(&c.MemoryHardLimit, "governor.memory-hard-limit-bytes", 0, "Resident memory at which all but the highest priority scraping is paused and traces sending queues are shrunk to a tenth. 0 disables the limit.")
+	f.Float64Var(&c.CPUSoftLimit, "governor.cpu-soft-limit", 0, "Number of CPU cores at which the soft limit backpressure is applied. 0 disables the limit.")
+	f.Float64Var(&c.CPUHardLimit, "governor.cpu-hard-limit", 0, "Number of CPU cores at which the hard limit backpressure is applied. 0 disables the limit.")
+	f.DurationVar(&c.CheckInterval, "governor.check-interval", 15*time.Second, "How often to check resource usage against the governor limits.")
+}
+
+// Enabled returns true if any limit is set.
+func (c *Config) Enabled() bool {
+	return c.MemorySoftLimit > 0 || c.MemoryHardLimit > 0 || c.CPUSoftLimit > 0 || c.CPUHardLimit > 0
+}
+
+// Validate returns an error if c is invalid.
+func (c *Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	switch {
+	case c.CheckInterval <= 0:
+		return errors.New("-governor.check-interval must be greater than 0s")
+	case c.CPUSoftLimit < 0 || c.CPUHardLimit < 0:
+		return errors.New("CPU limits must not be negative")
+	case c.MemorySoftLimit > 0 && c.MemoryHardLimit > 0 && c.MemoryHardLimit < c.MemorySoftLimit:
+		return errors.New("-governor.memory-hard-limit-bytes must not be less than -governor.memory-soft-limit-bytes")
+	case c.CPUSoftLimit > 0 && c.CPUHardLimit > 0 && c.CPUHardLimit < c.CPUSoftLimit:
+		return errors.New("-governor.cpu-hard-limit must not be less than -governor.cpu-soft-limit")
+	}
+	return nil
+}
+
+// Level is how much backpressure the governor applies.
+type Level int
+
+// Levels of backpressure.
+const (
+	// LevelNormal applies no backpressure. Paused scraping is resumed one
+	// priority at a time.
+	LevelNormal Level = iota
+	// LevelSoft pauses scraping of one more priority every check, up to all
+	// but the highest priority, and halves traces sending queues.
+	LevelSoft
+	// LevelHard pauses scraping of all but the highest priority, shrinks
+	// traces sending queues to a tenth, and returns free memory to the OS.
+	LevelHard
+)
+
+// String implements fmt.Stringer.
+func (l Level) String() string {
+	switch l {
+	case LevelNormal:
+		return "normal"
+	case LevelSoft:
+		return "soft"
+	case LevelHard:
+		return "hard"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// queueScales are the scales of traces sending queues per level.
+var queueScales = map[Level]float64{
+	LevelNormal: 1,
+	LevelSoft:   0.5,
+	LevelHard:   0.1,
+}
+
+// QueueScaler is implemented by subsystems whose queues can be shrunk.
+type QueueScaler interface {
+	// SetQueueScale scales the size of queues, where scale is in (0, 1].
+	SetQueueScale(scale float64) error
+}
+
+// scrapePauser is implemented by metrics instances which can pause scraping.
+type scrapePauser interface {
+	ScrapePriority() int
+	SetScrapePaused(paused bool)
+}
+
+// usage is the resource usage of the process.
+type usage struct {
+	// ResidentMemory in bytes.
+	ResidentMemory uint64
+	// CPUTime is the total number of CPU seconds used.
+	CPUTime float64
+}
+
+// Governor periodically checks the resource usage of the process and applies
+// backpressure when it crosses the limits of its Config.
+type Governor struct {
+	log    log.Logger
+	im     instance.Manager
+	queues QueueScaler
+	usage  func() (usage, error)
+
+	mut     sync.Mutex
+	cfg     Config
+	stop    chan struct{}
+	done    chan struct{}
+	stopped bool
+
+	level       Level
+	pausedTiers int
+	prevUsage   usage
+	prevTime    time.Time
+
+	levelGauge    prometheus.Gauge
+	pausedGauge   prometheus.Gauge
+	cpuCoresGauge prometheus.Gauge
+}
+
+// New creates a new Governor. Scraping of the instances of im is paused and
+// the queues of queues are scaled as backpressure. im and queues may be nil. The Governor does nothing
+// until ApplyConfig is called with limits.
+func New(l log.Logger, im instance.Manager, queues QueueScaler, reg prometheus.Registerer) (*Governor, error) {
+	g := &Governor{
+		log:    log.With(l, "component", "governor"),
+		im:     im,
+		queues: queues,
+		usage:  processUsage,
+
+		levelGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_governor_level",
+			Help: "Level of backpressure applied by the resource governor. 0 is normal, 1 is the soft limit, and 2 is the hard limit.",
+		}),
+		pausedGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_governor_paused_instances",
+			Help: "Number of metrics instances whose scraping is paused by the resource governor.",
+		}),
+		cpuCoresGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_governor_cpu_cores",
+			Help: "Number of CPU cores used by the process, averaged over the governor check interval.",
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{g.levelGauge, g.pausedGauge, g.cpuCoresGauge} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// ApplyConfig updates the limits of the Governor. All backpressure is
+// removed when no limits are set.
+func (g *Governor) ApplyConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.stopped {
+		return errors.New("governor stopped")
+	}
+	if g.cfg == cfg {
+		return nil
+	}
+	g.cfg = cfg
+
+	g.stopLoop()
+	if !cfg.Enabled() {
+		return g.apply(LevelNormal, 0)
+	}
+
+	g.stop, g.done = make(chan struct{}), make(chan struct{})
+	go g.run(cfg.CheckInterval, g.stop, g.done)
+	return nil
+}
+
+// stopLoop stops checking resource usage. mut must be held.
+func (g *Governor) stopLoop() {
+	if g.stop == nil {
+		return
+	}
+	close(g.stop)
+	<-g.done
+	g.stop, g.done = nil, nil
+}
+
+func (g *Governor) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := g.Check(time.Now()); err != nil {
+				level.Error(g.log).Log("msg", "failed to apply backpressure", "err", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Check measures resource usage and applies backpressure for it.
+func (g *Governor) Check(now time.Time) error {
+	u, err := g.usage()
+	if err != nil {
+		return fmt.Errorf("failed to measure resource usage: %w", err)
+	}
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	var cpuCores float64
+	if !g.prevTime.IsZero() {
+		if elapsed := now.Sub(g.prevTime).Seconds(); elapsed > 0 {
+			cpuCores = (u.CPUTime - g.prevUsage.CPUTime) / elapsed
+		}
+	}
+	g.prevUsage, g.prevTime = u, now
+	g.cpuCoresGauge.Set(cpuCores)
+
+	lvl := limitLevel(float64(u.ResidentMemory), float64(g.cfg.MemorySoftLimit), float64(g.cfg.MemoryHardLimit))
+	if cpuLevel := limitLevel(cpuCores, g.cfg.CPUSoftLimit, g.cfg.CPUHardLimit); cpuLevel > lvl {
+		lvl = cpuLevel
+	}
+
+	if lvl != g.level {
+		level.Warn(g.log).Log("msg", "resource usage changed backpressure level", "from", g.level, "to", lvl, "resident_memory_bytes", u.ResidentMemory, "cpu_cores", cpuCores)
+	}
+
+	// Pausing and resuming is done one priority at a time so the least
+	// amount of scraping needed to stay below the limits is paused.
+	pausedTiers := g.pausedTiers
+	switch lvl {
+	case LevelNormal:
+		if pausedTiers > 0 {
+			pausedTiers--
+		}
+	case LevelSoft:
+		pausedTiers++
+	case LevelHard:
+		// Clamped to all but the highest priority by apply.
+		pausedTiers = len(g.listInstances())
+		debug.FreeOSMemory()
+	}
+
+	return g.apply(lvl, pausedTiers)
+}
+
+// listInstances returns the running metrics instances, if any.
+func (g *Governor) listInstances() map[string]instance.ManagedInstance {
+	if g.im == nil {
+		return nil
+	}
+	return g.im.ListInstances()
+}
+
+// limitLevel returns the level of backpressure for value given its limits.
+func limitLevel(value, soft, hard float64) Level {
+	switch {
+	case hard > 0 && value >= hard:
+		return LevelHard
+	case soft > 0 && value >= soft:
+		return LevelSoft
+	default:
+		return LevelNormal
+	}
+}
+
+// apply pauses scraping of the pausedTiers lowest priorities of the running
+// instances and scales traces queues for lvl. The highest priority is never
+// paused. mut must be held.
+func (g *Governor) apply(lvl Level, pausedTiers int) error {
+	var (
+		instances  = make(map[string]scrapePauser)
+		priorities []int
+		seen       = make(map[int]struct{})
+	)
+	for name, inst := range g.listInstances() {
+		p, ok := inst.(scrapePauser)
+		if !ok {
+			continue
+		}
+		instances[name] = p
+
+		priority := p.ScrapePriority()
+		if _, ok := seen[priority]; !ok {
+			seen[priority] = struct{}{}
+			priorities = append(priorities, priority)
+		}
+	}
+	sort.Ints(priorities)
+
+	if max := len(priorities) - 1; pausedTiers > max {
+		pausedTiers = max
+	}
+	if pausedTiers < 0 {
+		pausedTiers = 0
+	}
+
+	var paused int
+	for name, p := range instances {
+		pause := pausedTiers > 0 && p.ScrapePriority() < priorities[pausedTiers]
+		if pause {
+			level.Debug(g.log).Log("msg", "pausing scraping", "instance", name)
+			paused++
+		}
+		p.SetScrapePaused(pause)
+	}
+
+	g.level, g.pausedTiers = lvl, pausedTiers
+	g.levelGauge.Set(float64(lvl))
+	g.pausedGauge.Set(float64(paused))
+
+	if g.queues != nil {
+		if err := g.queues.SetQueueScale(queueScales[lvl]); err != nil {
+			return fmt.Errorf("failed to scale traces queues: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stop stops the Governor. Backpressure that is currently applied is kept.
+func (g *Governor) Stop() {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	g.stopLoop()
+	g.stopped = true
+}
@@ -0,0 +1,146 @@
+package governor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInstance struct {
+	instance.NoOpInstance
+	priority int
+	paused   bool
+}
+
+func (i *fakeInstance) ScrapePriority() int         { return i.priority }
+func (i *fakeInstance) SetScrapePaused(paused bool) { i.paused = paused }
+
+type fakeQueues struct{ scale float64 }
+
+func (q *fakeQueues) SetQueueScale(scale float64) error {
+	q.scale = scale
+	return nil
+}
+
+func TestGovernor(t *testing.T) {
+	var (
+		low    = &fakeInstance{priority: -1}
+		medium = &fakeInstance{priority: 0}
+		high   = &fakeInstance{priority: 10}
+		queues = &fakeQueues{scale: 1}
+	)
+	im := instance.MockManager{
+		ListInstancesFunc: func() map[string]instance.ManagedInstance {
+			return map[string]instance.ManagedInstance{"low": low, "medium": medium, "high": high}
+		},
+	}
+
+	g, err := New(log.NewNopLogger(), im, queues, prometheus.NewRegistry())
+	require.NoError(t, err)
+	defer g.Stop()
+
+	var current usage
+	g.usage = func() (usage, error) { return current, nil }
+
+	// Use a long interval so usage is only checked manually.
+	require.NoError(t, g.ApplyConfig(Config{
+		MemorySoftLimit: 100,
+		MemoryHardLimit: 200,
+		CPUSoftLimit:    1,
+		CheckInterval:   time.Hour,
+	}))
+
+	now := time.Now()
+	check := func(memory uint64, cpuTime float64) {
+		t.Helper()
+		now = now.Add(10 * time.Second)
+		current = usage{ResidentMemory: memory, CPUTime: cpuTime}
+		require.NoError(t, g.Check(now))
+	}
+	requirePaused := func(expectLevel Level, expectScale float64, expectPaused ...bool) {
+		t.Helper()
+		require.Equal(t, expectLevel, g.level)
+		require.Equal(t, expectScale, queues.scale)
+		require.Equal(t, expectPaused, []bool{low.paused, medium.paused, high.paused})
+	}
+
+	check(50, 0)
+	requirePaused(LevelNormal, 1, false, false, false)
+
+	// The soft limit pauses one more priority every check, but never the
+	// highest priority.
+	check(150, 0)
+	requirePaused(LevelSoft, 0.5, true, false, false)
+	check(150, 0)
+	requirePaused(LevelSoft, 0.5, true, true, false)
+	check(150, 0)
+	requirePaused(LevelSoft, 0.5, true, true, false)
+
+	// Scraping is resumed one priority at a time.
+	check(50, 0)
+	requirePaused(LevelNormal, 1, true, false, false)
+
+	// The hard limit pauses all but the highest priority at once.
+	check(250, 0)
+	requirePaused(LevelHard, 0.1, true, true, false)
+
+	check(50, 0)
+	requirePaused(LevelNormal, 1, true, false, false)
+	check(50, 0)
+	requirePaused(LevelNormal, 1, false, false, false)
+
+	// Using 1.5 cores over the last 10 seconds crosses the CPU soft limit.
+	check(50, 15)
+	requirePaused(LevelSoft, 0.5, true, false, false)
+
+	// Removing the limits removes all backpressure.
+	require.NoError(t, g.ApplyConfig(Config{CheckInterval: time.Hour}))
+	requirePaused(LevelNormal, 1, false, false, false)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    Config
+		expect string
+	}{
+		{
+			name: "disabled",
+			cfg:  Config{},
+		},
+		{
+			name: "soft limit only",
+			cfg:  Config{MemorySoftLimit: 100, CheckInterval: time.Second},
+		},
+		{
+			name:   "hard limit below soft limit",
+			cfg:    Config{MemorySoftLimit: 100, MemoryHardLimit: 50, CheckInterval: time.Second},
+			expect: "-governor.memory-hard-limit-bytes must not be less than -governor.memory-soft-limit-bytes",
+		},
+		{
+			name:   "CPU hard limit below soft limit",
+			cfg:    Config{CPUSoftLimit: 2, CPUHardLimit: 1, CheckInterval: time.Second},
+			expect: "-governor.cpu-hard-limit must not be less than -governor.cpu-soft-limit",
+		},
+		{
+			name:   "no check interval",
+			cfg:    Config{MemorySoftLimit: 100},
+			expect: "-governor.check-interval must be greater than 0s",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.expect == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package governor
+
+import (
+	"runtime"
+
+	"github.com/prometheus/procfs"
+)
+
+// processUsage returns the resource usage of the process. When procfs isn't
+// available, the memory obtained by the Go runtime is used and CPU usage is
+// unknown.
+func processUsage() (usage, error) {
+	p, err := procfs.Self()
+	if err == nil {
+		stat, err := p.Stat()
+		if err == nil {
+			return usage{
+				ResidentMemory: uint64(stat.ResidentMemory()),
+				CPUTime:        stat.CPUTime(),
+			}, nil
+		}
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return usage{ResidentMemory: ms.Sys - ms.HeapReleased}, nil
+}
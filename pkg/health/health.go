@@ -0,0 +1,62 @@
+// Package health describes the health and readiness of the subsystems of the
+// Agent and the components they run.
+package health
+
+import "sort"
+
+// Component is a single unit of work run by a subsystem, such as a metrics
+// instance or an integration.
+type Component struct {
+	Name string `json:"name"`
+
+	// Healthy is false when the component is failing.
+	Healthy bool `json:"healthy"`
+
+	// Ready is false until the component has finished starting up.
+	Ready bool `json:"ready"`
+
+	// Message explains why the component is unhealthy or not ready.
+	Message string `json:"message,omitempty"`
+}
+
+// Subsystem is the status of a subsystem of the Agent. A subsystem is
+// healthy or ready when all of its components are.
+type Subsystem struct {
+	Name       string      `json:"name"`
+	Healthy    bool        `json:"healthy"`
+	Ready      bool        `json:"ready"`
+	Components []Component `json:"components"`
+}
+
+// NewSubsystem creates a Subsystem from its components, sorted by name.
+func NewSubsystem(name string, components []Component) Subsystem {
+	if components == nil {
+		components = []Component{}
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+
+	s := Subsystem{Name: name, Healthy: true, Ready: true, Components: components}
+	for _, c := range components {
+		s.Healthy = s.Healthy && c.Healthy
+		s.Ready = s.Ready && c.Ready
+	}
+	return s
+}
+
+// Report is the status of every subsystem of the Agent. The Agent is healthy
+// or ready when all of its subsystems are.
+type Report struct {
+	Healthy    bool        `json:"healthy"`
+	Ready      bool        `json:"ready"`
+	Subsystems []Subsystem `json:"subsystems"`
+}
+
+// NewReport creates a Report from a set of subsystems.
+func NewReport(subsystems ...Subsystem) Report {
+	r := Report{Healthy: true, Ready: true, Subsystems: subsystems}
+	for _, s := range subsystems {
+		r.Healthy = r.Healthy && s.Healthy
+		r.Ready = r.Ready && s.Ready
+	}
+	return r
+}
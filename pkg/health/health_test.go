@@ -0,0 +1,38 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSubsystem(t *testing.T) {
+	s := NewSubsystem("prometheus", []Component{
+		{Name: "b", Healthy: true, Ready: false, Message: "replaying WAL"},
+		{Name: "a", Healthy: true, Ready: true},
+	})
+	require.Equal(t, Subsystem{
+		Name:    "prometheus",
+		Healthy: true,
+		Ready:   false,
+		Components: []Component{
+			{Name: "a", Healthy: true, Ready: true},
+			{Name: "b", Healthy: true, Ready: false, Message: "replaying WAL"},
+		},
+	}, s)
+
+	empty := NewSubsystem("loki", nil)
+	require.True(t, empty.Healthy)
+	require.True(t, empty.Ready)
+	require.NotNil(t, empty.Components)
+}
+
+func TestNewReport(t *testing.T) {
+	r := NewReport(
+		NewSubsystem("prometheus", []Component{{Name: "a", Healthy: true, Ready: true}}),
+		NewSubsystem("integrations", []Component{{Name: "b", Healthy: false, Ready: true}}),
+	)
+	require.False(t, r.Healthy)
+	require.True(t, r.Ready)
+	require.Len(t, r.Subsystems, 2)
+}
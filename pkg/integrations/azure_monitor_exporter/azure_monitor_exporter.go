@@ -0,0 +1,211 @@
+// Package azure_monitor_exporter implements an integration which pulls
+// metrics of Azure resources from Azure Monitor.
+package azure_monitor_exporter //nolint:golint
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// Authentication methods.
+const (
+	AuthMethodOAuth           = "OAuth"
+	AuthMethodManagedIdentity = "ManagedIdentity"
+)
+
+// Aggregations supported by Azure Monitor.
+var aggregations = []string{"Average", "Minimum", "Maximum", "Total", "Count"}
+
+// timeGrains are the periods supported by Azure Monitor, along with their
+// ISO 8601 representation.
+var timeGrains = map[time.Duration]string{
+	time.Minute:      "PT1M",
+	5 * time.Minute:  "PT5M",
+	15 * time.Minute: "PT15M",
+	30 * time.Minute: "PT30M",
+	time.Hour:        "PT1H",
+	6 * time.Hour:    "PT6H",
+	12 * time.Hour:   "PT12H",
+	24 * time.Hour:   "P1D",
+}
+
+// DefaultConfig is the default config for azure_monitor_exporter.
+var DefaultConfig = Config{
+	Environment:          "AzurePublicCloud",
+	AuthenticationMethod: AuthMethodOAuth,
+	Period:               time.Minute,
+	Delay:                3 * time.Minute,
+	Timeout:              30 * time.Second,
+}
+
+// DefaultMetric holds default settings for a Metric.
+var DefaultMetric = Metric{
+	Aggregations: []string{"Average"},
+}
+
+// Config controls the azure_monitor_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// Environment is the Azure cloud, such as AzurePublicCloud or
+	// AzureChinaCloud.
+	Environment string `yaml:"environment,omitempty"`
+
+	// AuthenticationMethod is either OAuth, using a service principal, or
+	// ManagedIdentity.
+	AuthenticationMethod string `yaml:"authentication_method,omitempty"`
+
+	SubscriptionID string             `yaml:"subscription_id"`
+	TenantID       string             `yaml:"tenant_id,omitempty"`
+	ClientID       string             `yaml:"client_id,omitempty"`
+	ClientSecret   config_util.Secret `yaml:"client_secret,omitempty"`
+
+	// Period is the granularity of requested datapoints.
+	Period time.Duration `yaml:"period,omitempty"`
+
+	// Delay is how far in the past the requested period ends, since
+	// datapoints can take several minutes to become available.
+	Delay time.Duration `yaml:"delay,omitempty"`
+
+	// Timeout of requesting all metrics.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Resources selects resources and the metrics to request for them.
+	Resources []Resources `yaml:"resources,omitempty"`
+}
+
+// Resources selects resources of a type and the metrics to request for
+// them.
+type Resources struct {
+	// Type of the resources, such as Microsoft.Compute/virtualMachines.
+	Type string `yaml:"resource_type"`
+
+	// ResourceGroup limits the resources to a resource group.
+	ResourceGroup string `yaml:"resource_group,omitempty"`
+
+	// Tags limits the resources to those with all of the given tags.
+	Tags map[string]string `yaml:"tags,omitempty"`
+
+	// MetricNamespace of the metrics. Defaults to the resource type.
+	MetricNamespace string `yaml:"metric_namespace,omitempty"`
+
+	// Metrics to request.
+	Metrics []Metric `yaml:"metrics"`
+}
+
+// Metric is an Azure Monitor metric to request.
+type Metric struct {
+	// Name of the metric, such as Percentage CPU.
+	Name string `yaml:"name"`
+
+	// Aggregations to request: Average, Minimum, Maximum, Total, or Count.
+	Aggregations []string `yaml:"aggregations,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Metric.
+func (m *Metric) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*m = DefaultMetric
+
+	type plain Metric
+	if err := unmarshal((*plain)(m)); err != nil {
+		return err
+	}
+
+	if m.Name == "" {
+		return fmt.Errorf("metric must have a name")
+	}
+	if len(m.Aggregations) == 0 {
+		return fmt.Errorf("metric %s must have at least one aggregation", m.Name)
+	}
+	for _, a := range m.Aggregations {
+		if !contains(aggregations, a) {
+			return fmt.Errorf("metric %s has unsupported aggregation %q", m.Name, a)
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.SubscriptionID == "" {
+		return fmt.Errorf("azure_monitor_exporter requires a subscription_id")
+	}
+	switch c.AuthenticationMethod {
+	case AuthMethodOAuth:
+		if c.TenantID == "" || c.ClientID == "" || c.ClientSecret == "" {
+			return fmt.Errorf("azure_monitor_exporter requires tenant_id, client_id, and client_secret with authentication_method %s", AuthMethodOAuth)
+		}
+	case AuthMethodManagedIdentity:
+	default:
+		return fmt.Errorf("azure_monitor_exporter has unknown authentication_method %q. Supported methods are %q or %q", c.AuthenticationMethod, AuthMethodOAuth, AuthMethodManagedIdentity)
+	}
+	if _, ok := timeGrains[c.Period]; !ok {
+		return fmt.Errorf("azure_monitor_exporter period must be one of 1m, 5m, 15m, 30m, 1h, 6h, 12h, or 24h")
+	}
+	if c.Delay < 0 {
+		return fmt.Errorf("azure_monitor_exporter delay must not be negative")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("azure_monitor_exporter timeout must be greater than 0")
+	}
+
+	for idx, r := range c.Resources {
+		if r.Type == "" {
+			return fmt.Errorf("azure_monitor_exporter resources index %d must have a resource_type", idx)
+		}
+		if len(r.Metrics) == 0 {
+			return fmt.Errorf("azure_monitor_exporter resources %s must have at least one metric", r.Type)
+		}
+	}
+	return nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "azure_monitor_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new azure_monitor_exporter integration. Resources are listed
+// and their metrics requested each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
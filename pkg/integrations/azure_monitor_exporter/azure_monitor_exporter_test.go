@@ -0,0 +1,146 @@
+package azure_monitor_exporter //nolint:golint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+subscription_id: sub
+authentication_method: ManagedIdentity
+period: 5m
+resources:
+- resource_type: Microsoft.Compute/virtualMachines
+  tags: {env: prod}
+  metrics:
+  - name: Percentage CPU
+  - name: Network In Total
+    aggregations: [Total]
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Average"}, cfg.Resources[0].Metrics[0].Aggregations)
+
+	tt := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{
+			name: "missing credentials",
+			cfg:  `subscription_id: sub`,
+			err:  "azure_monitor_exporter requires tenant_id, client_id, and client_secret with authentication_method OAuth",
+		},
+		{
+			name: "unsupported period",
+			cfg:  `{subscription_id: sub, authentication_method: ManagedIdentity, period: 2m}`,
+			err:  "azure_monitor_exporter period must be one of 1m, 5m, 15m, 30m, 1h, 6h, 12h, or 24h",
+		},
+		{
+			name: "unsupported aggregation",
+			cfg:  `{subscription_id: sub, authentication_method: ManagedIdentity, resources: [{resource_type: a, metrics: [{name: m, aggregations: [p99]}]}]}`,
+			err:  `metric m has unsupported aggregation "p99"`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg), tc.err)
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	require.Equal(t, "percentage_cpu", sanitize("Percentage CPU"))
+	require.Equal(t, "disk_read_bytes_sec", sanitize("Disk Read Bytes/sec"))
+	require.Equal(t, "requests", sanitize(" Requests "))
+}
+
+const vmID = "/subscriptions/sub/resourceGroups/web/providers/Microsoft.Compute/virtualMachines/vm1"
+
+func TestCollector(t *testing.T) {
+	var metricsQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var resp interface{}
+		switch {
+		case r.URL.Path == "/subscriptions/sub/resources":
+			require.Equal(t, "resourceType eq 'Microsoft.Compute/virtualMachines'", r.URL.Query().Get("$filter"))
+			resp = map[string]interface{}{"value": []interface{}{
+				map[string]interface{}{"id": vmID, "name": "vm1", "type": "Microsoft.Compute/virtualMachines", "tags": map[string]string{"env": "prod"}},
+				map[string]interface{}{"id": vmID + "-dev", "name": "vm1-dev", "type": "Microsoft.Compute/virtualMachines", "tags": map[string]string{"env": "dev"}},
+			}}
+		case r.URL.Path == vmID+"/providers/microsoft.insights/metrics":
+			metricsQuery = r.URL.RawQuery
+			resp = map[string]interface{}{"value": []interface{}{
+				map[string]interface{}{
+					"name": map[string]string{"value": "Percentage CPU"},
+					"timeseries": []interface{}{map[string]interface{}{"data": []interface{}{
+						map[string]interface{}{"timeStamp": "2021-06-01T12:25:00Z", "average": 12.5, "maximum": 30},
+						map[string]interface{}{"timeStamp": "2021-06-01T12:26:00Z", "average": 20},
+					}}},
+				},
+				map[string]interface{}{
+					"name":       map[string]string{"value": "Network In Total"},
+					"timeseries": []interface{}{map[string]interface{}{"data": []interface{}{map[string]interface{}{"total": 1024}}}},
+				},
+			}}
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(resp)
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+subscription_id: sub
+authentication_method: ManagedIdentity
+resources:
+- resource_type: Microsoft.Compute/virtualMachines
+  tags: {env: prod}
+  metrics:
+  - name: Percentage CPU
+    aggregations: [Average, Maximum]
+  - name: Network In Total
+    aggregations: [Total]
+`), &cfg)
+	require.NoError(t, err)
+
+	col := newCollectorWithBaseURI(log.NewNopLogger(), &cfg, srv.URL, autorest.NullAuthorizer{})
+	col.now = func() time.Time { return time.Date(2021, 6, 1, 12, 30, 15, 0, time.UTC) }
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(col))
+
+	expect := `
+# HELP azure_monitor_exporter_scrape_error Non-zero if this scrape failed.
+# TYPE azure_monitor_exporter_scrape_error gauge
+azure_monitor_exporter_scrape_error 0
+# HELP azure_network_in_total_total Azure Monitor metric Network In Total, aggregated by Total.
+# TYPE azure_network_in_total_total gauge
+azure_network_in_total_total{resource_group="web",resource_name="vm1",resource_type="Microsoft.Compute/virtualMachines"} 1024
+# HELP azure_percentage_cpu_average Azure Monitor metric Percentage CPU, aggregated by Average.
+# TYPE azure_percentage_cpu_average gauge
+azure_percentage_cpu_average{resource_group="web",resource_name="vm1",resource_type="Microsoft.Compute/virtualMachines"} 20
+# HELP azure_percentage_cpu_maximum Azure Monitor metric Percentage CPU, aggregated by Maximum.
+# TYPE azure_percentage_cpu_maximum gauge
+azure_percentage_cpu_maximum{resource_group="web",resource_name="vm1",resource_type="Microsoft.Compute/virtualMachines"} 30
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect)))
+	require.Contains(t, metricsQuery, "timespan=2021-06-01T12%3A26%3A00Z%2F2021-06-01T12%3A27%3A00Z")
+	require.Contains(t, metricsQuery, "interval=PT1M")
+}
@@ -0,0 +1,302 @@
+package azure_monitor_exporter //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/preview/monitor/mgmt/2019-06-01/insights"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-05-01/resources"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxConcurrentRequests limits how many resources have their metrics
+// requested at the same time.
+const maxConcurrentRequests = 10
+
+func newAuthorizer(c *Config, env azure.Environment) (autorest.Authorizer, error) {
+	var spt *adal.ServicePrincipalToken
+	switch c.AuthenticationMethod {
+	case AuthMethodManagedIdentity:
+		msiEndpoint, err := adal.GetMSIVMEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		spt, err = adal.NewServicePrincipalTokenFromMSI(msiEndpoint, env.ResourceManagerEndpoint)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, c.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		spt, err = adal.NewServicePrincipalToken(*oauthConfig, c.ClientID, string(c.ClientSecret), env.ResourceManagerEndpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return autorest.NewBearerAuthorizer(spt), nil
+}
+
+type collector struct {
+	log       log.Logger
+	cfg       *Config
+	resources resources.Client
+	metrics   insights.MetricsClient
+	now       func() time.Time
+
+	scrapeError *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	env, err := azure.EnvironmentFromName(c.Environment)
+	if err != nil {
+		return nil, err
+	}
+	authorizer, err := newAuthorizer(c, env)
+	if err != nil {
+		return nil, err
+	}
+	return newCollectorWithBaseURI(l, c, env.ResourceManagerEndpoint, authorizer), nil
+}
+
+func newCollectorWithBaseURI(l log.Logger, c *Config, baseURI string, authorizer autorest.Authorizer) *collector {
+	col := &collector{
+		log:       l,
+		cfg:       c,
+		resources: resources.NewClientWithBaseURI(baseURI, c.SubscriptionID),
+		metrics:   insights.NewMetricsClientWithBaseURI(baseURI, c.SubscriptionID),
+		now:       time.Now,
+
+		scrapeError: prometheus.NewDesc("azure_monitor_exporter_scrape_error", "Non-zero if this scrape failed.", nil, nil),
+	}
+	col.resources.Authorizer = authorizer
+	col.metrics.Authorizer = authorizer
+	return col
+}
+
+// Describe implements prometheus.Collector. Nothing is sent since metrics
+// are only known once resources are listed, making collector an unchecked
+// collector.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	end := c.now().Add(-c.cfg.Delay).Truncate(time.Minute)
+	timespan := end.Add(-c.cfg.Period).UTC().Format(time.RFC3339) + "/" + end.UTC().Format(time.RFC3339)
+
+	var (
+		wg          sync.WaitGroup
+		sem         = make(chan struct{}, maxConcurrentRequests)
+		errMut      sync.Mutex
+		scrapeError float64
+	)
+	fail := func(msg string, err error, keyvals ...interface{}) {
+		level.Error(c.log).Log(append([]interface{}{"msg", msg, "err", err}, keyvals...)...)
+		errMut.Lock()
+		scrapeError = 1
+		errMut.Unlock()
+	}
+
+	for i := range c.cfg.Resources {
+		r := &c.cfg.Resources[i]
+		list, err := c.listResources(ctx, r)
+		if err != nil {
+			fail("failed to list resources", err, "resource_type", r.Type)
+			continue
+		}
+
+		for _, res := range list {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(res resources.GenericResource) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				if err := c.collectResource(ctx, r, res, timespan, ch); err != nil {
+					fail("failed to get metrics of resource", err, "resource", *res.ID)
+				}
+			}(res)
+		}
+	}
+	wg.Wait()
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError)
+}
+
+// listResources lists the resources selected by r.
+func (c *collector) listResources(ctx context.Context, r *Resources) ([]resources.GenericResource, error) {
+	var (
+		filter = fmt.Sprintf("resourceType eq '%s'", r.Type)
+		iter   resources.ListResultIterator
+		err    error
+	)
+	if r.ResourceGroup != "" {
+		iter, err = c.resources.ListByResourceGroupComplete(ctx, r.ResourceGroup, filter, "", nil)
+	} else {
+		iter, err = c.resources.ListComplete(ctx, filter, "", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var res []resources.GenericResource
+	for ; iter.NotDone(); err = iter.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		if v := iter.Value(); v.ID != nil && matchTags(r.Tags, v.Tags) {
+			res = append(res, v)
+		}
+	}
+	return res, err
+}
+
+func matchTags(want map[string]string, tags map[string]*string) bool {
+	for k, v := range want {
+		if tag, ok := tags[k]; !ok || tag == nil || *tag != v {
+			return false
+		}
+	}
+	return true
+}
+
+// collectResource requests the metrics of a single resource.
+func (c *collector) collectResource(ctx context.Context, r *Resources, res resources.GenericResource, timespan string, ch chan<- prometheus.Metric) error {
+	var (
+		names []string
+		aggs  []string
+	)
+	for _, m := range r.Metrics {
+		names = append(names, m.Name)
+		for _, a := range m.Aggregations {
+			if !contains(aggs, a) {
+				aggs = append(aggs, a)
+			}
+		}
+	}
+
+	// The resource URI is joined to the path of the request, so the leading
+	// slash of the ID must be removed.
+	var (
+		uri   = strings.TrimPrefix(*res.ID, "/")
+		grain = timeGrains[c.cfg.Period]
+	)
+	resp, err := c.metrics.List(ctx, uri, timespan, &grain, strings.Join(names, ","), strings.Join(aggs, ","), nil, "", "", insights.Data, r.MetricNamespace)
+	if err != nil {
+		return err
+	}
+	if resp.Value == nil {
+		return nil
+	}
+
+	labelValues := []string{resourceGroup(*res.ID), stringValue(res.Name), stringValue(res.Type)}
+	for _, m := range *resp.Value {
+		if m.Name == nil {
+			continue
+		}
+		cfg, ok := findMetric(r.Metrics, stringValue(m.Name.Value))
+		if !ok || m.Timeseries == nil || len(*m.Timeseries) == 0 {
+			continue
+		}
+		data := (*m.Timeseries)[0].Data
+		if data == nil {
+			continue
+		}
+
+		for _, agg := range cfg.Aggregations {
+			v, ok := latestValue(*data, agg)
+			if !ok {
+				continue
+			}
+			desc := prometheus.NewDesc(
+				"azure_"+sanitize(cfg.Name)+"_"+strings.ToLower(agg),
+				fmt.Sprintf("Azure Monitor metric %s, aggregated by %s.", cfg.Name, agg),
+				[]string{"resource_group", "resource_name", "resource_type"}, nil,
+			)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labelValues...)
+		}
+	}
+	return nil
+}
+
+func findMetric(metrics []Metric, name string) (Metric, bool) {
+	for _, m := range metrics {
+		if strings.EqualFold(m.Name, name) {
+			return m, true
+		}
+	}
+	return Metric{}, false
+}
+
+// latestValue returns the value of the most recent datapoint which has the
+// given aggregation.
+func latestValue(data []insights.MetricValue, agg string) (float64, bool) {
+	for i := len(data) - 1; i >= 0; i-- {
+		var v *float64
+		switch agg {
+		case "Average":
+			v = data[i].Average
+		case "Minimum":
+			v = data[i].Minimum
+		case "Maximum":
+			v = data[i].Maximum
+		case "Total":
+			v = data[i].Total
+		case "Count":
+			v = data[i].Count
+		}
+		if v != nil {
+			return *v, true
+		}
+	}
+	return 0, false
+}
+
+// resourceGroup returns the resource group from a resource ID of the form
+// /subscriptions/<id>/resourceGroups/<group>/providers/...
+func resourceGroup(id string) string {
+	parts := strings.Split(id, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// sanitize converts a metric name such as "Disk Read Bytes/sec" into
+// disk_read_bytes_sec.
+func sanitize(name string) string {
+	var sb strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			underscore = false
+		} else if !underscore && sb.Len() > 0 {
+			sb.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "_")
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
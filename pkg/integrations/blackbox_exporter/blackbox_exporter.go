@@ -0,0 +1,205 @@
+// Package blackbox_exporter implements probes similar to
+// https://github.com/prometheus/blackbox_exporter, checking the availability
+// of endpoints over HTTP, TCP, and ICMP.
+package blackbox_exporter //nolint:golint
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+// Probers which can be used by a Module.
+const (
+	ProberHTTP = "http"
+	ProberTCP  = "tcp"
+	ProberICMP = "icmp"
+)
+
+// DefaultConfig is the default config for blackbox_exporter.
+var DefaultConfig = Config{}
+
+// DefaultModule holds default settings for a Module.
+var DefaultModule = Module{
+	Timeout: 5 * time.Second,
+	HTTP: HTTPProbe{
+		Method: "GET",
+	},
+}
+
+// defaultModules returns the modules which are available without being
+// configured.
+func defaultModules() map[string]Module {
+	withProber := func(prober string) Module {
+		m := DefaultModule
+		m.Prober = prober
+		return m
+	}
+	return map[string]Module{
+		"http_2xx":    withProber(ProberHTTP),
+		"tcp_connect": withProber(ProberTCP),
+		"icmp":        withProber(ProberICMP),
+	}
+}
+
+// Config controls the blackbox_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// Modules define how to probe targets, keyed by name. The http_2xx,
+	// tcp_connect, and icmp modules are always available unless overridden.
+	Modules map[string]Module `yaml:"modules,omitempty"`
+
+	// Targets to probe every time the integration is scraped.
+	Targets []Target `yaml:"targets,omitempty"`
+}
+
+// Module configures how to probe a target.
+type Module struct {
+	// Prober to use: http, tcp, or icmp.
+	Prober string `yaml:"prober"`
+
+	// Timeout of a probe.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	HTTP HTTPProbe `yaml:"http,omitempty"`
+	TCP  TCPProbe  `yaml:"tcp,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Module.
+func (m *Module) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*m = DefaultModule
+
+	type plain Module
+	if err := unmarshal((*plain)(m)); err != nil {
+		return err
+	}
+
+	switch m.Prober {
+	case ProberHTTP, ProberTCP, ProberICMP:
+	default:
+		return fmt.Errorf("unsupported prober %q", m.Prober)
+	}
+	if m.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// HTTPProbe configures probes using the http prober. Targets are URLs.
+type HTTPProbe struct {
+	// Method of the request.
+	Method string `yaml:"method,omitempty"`
+
+	// Headers to send with the request.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// ValidStatusCodes are the status codes for which the probe succeeds.
+	// Defaults to any 2xx status code.
+	ValidStatusCodes []int `yaml:"valid_status_codes,omitempty"`
+
+	// NoFollowRedirects fails on redirects rather than following them.
+	NoFollowRedirects bool `yaml:"no_follow_redirects,omitempty"`
+
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+}
+
+// TCPProbe configures probes using the tcp prober. Targets are host:port
+// addresses.
+type TCPProbe struct {
+	// TLS performs a TLS handshake after connecting.
+	TLS       bool                  `yaml:"tls,omitempty"`
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+}
+
+// Target is an endpoint to probe.
+type Target struct {
+	// Name of the target, used as the value of the target label.
+	Name string `yaml:"name"`
+
+	// Address to probe. The format depends on the prober of the module.
+	Address string `yaml:"address"`
+
+	// Module to probe the target with.
+	Module string `yaml:"module"`
+
+	// Labels added to the metrics of the target.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	modules := defaultModules()
+	for name, m := range c.Modules {
+		modules[name] = m
+	}
+	c.Modules = modules
+
+	names := make(map[string]struct{}, len(c.Targets))
+	for idx, t := range c.Targets {
+		if t.Name == "" {
+			return fmt.Errorf("blackbox_exporter target index %d must have a name", idx)
+		}
+		if _, ok := names[t.Name]; ok {
+			return fmt.Errorf("blackbox_exporter has two targets named %s", t.Name)
+		}
+		names[t.Name] = struct{}{}
+
+		if t.Address == "" {
+			return fmt.Errorf("blackbox_exporter target %s must have an address", t.Name)
+		}
+		if _, ok := c.Modules[t.Module]; !ok {
+			return fmt.Errorf("blackbox_exporter target %s uses unknown module %q", t.Name, t.Module)
+		}
+		if err := t.Labels.Validate(); err != nil {
+			return fmt.Errorf("blackbox_exporter target %s: %w", t.Name, err)
+		}
+		for _, reserved := range []model.LabelName{labelTarget, labelModule} {
+			if _, ok := t.Labels[reserved]; ok {
+				return fmt.Errorf("blackbox_exporter target %s must not set the %s label", t.Name, reserved)
+			}
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "blackbox_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new blackbox_exporter integration. Every target is probed
+// each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
@@ -0,0 +1,125 @@
+package blackbox_exporter //nolint:golint
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+modules:
+  http_post:
+    prober: http
+    http:
+      method: POST
+targets:
+- name: api
+  address: https://example.com
+  module: http_post
+  labels:
+    team: a
+- name: ssh
+  address: example.com:22
+  module: tcp_connect
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, "POST", cfg.Modules["http_post"].HTTP.Method)
+	require.Equal(t, DefaultModule.Timeout, cfg.Modules["http_post"].Timeout)
+	require.Equal(t, ProberTCP, cfg.Modules["tcp_connect"].Prober)
+
+	tt := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{
+			name: "unknown module",
+			cfg:  `targets: [{name: a, address: example.com, module: dns}]`,
+			err:  `blackbox_exporter target a uses unknown module "dns"`,
+		},
+		{
+			name: "duplicate target",
+			cfg:  `targets: [{name: a, address: example.com, module: icmp}, {name: a, address: example.org, module: icmp}]`,
+			err:  "blackbox_exporter has two targets named a",
+		},
+		{
+			name: "reserved label",
+			cfg:  `targets: [{name: a, address: example.com, module: icmp, labels: {module: b}}]`,
+			err:  "blackbox_exporter target a must not set the module label",
+		},
+		{
+			name: "unknown prober",
+			cfg:  `modules: {dns: {prober: dns}}`,
+			err:  `unsupported prober "dns"`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg), tc.err)
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	// Find an address nothing is listening on by closing a listener.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	var cfg Config
+	err = yaml.UnmarshalStrict([]byte(`
+modules:
+  http_404:
+    prober: http
+    http:
+      valid_status_codes: [404]
+targets:
+- {name: ok, address: `+srv.URL+`, module: http_2xx, labels: {team: a}}
+- {name: missing, address: `+srv.URL+`/missing, module: http_2xx}
+- {name: expected_missing, address: `+srv.URL+`/missing, module: http_404}
+- {name: tcp_ok, address: `+srv.Listener.Addr().String()+`, module: tcp_connect}
+- {name: tcp_closed, address: `+closedAddr+`, module: tcp_connect}
+`), &cfg)
+	require.NoError(t, err)
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(col))
+
+	expect := `
+# HELP probe_http_status_code Status code of the HTTP response.
+# TYPE probe_http_status_code gauge
+probe_http_status_code{module="http_2xx",target="missing"} 404
+probe_http_status_code{module="http_2xx",target="ok",team="a"} 200
+probe_http_status_code{module="http_404",target="expected_missing"} 404
+# HELP probe_success Whether the probe succeeded.
+# TYPE probe_success gauge
+probe_success{module="http_2xx",target="missing"} 0
+probe_success{module="http_2xx",target="ok",team="a"} 1
+probe_success{module="http_404",target="expected_missing"} 1
+probe_success{module="tcp_connect",target="tcp_closed"} 0
+probe_success{module="tcp_connect",target="tcp_ok"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), "probe_success", "probe_http_status_code"))
+}
@@ -0,0 +1,167 @@
+package blackbox_exporter //nolint:golint
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/integrations/blackbox_exporter/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	labelTarget model.LabelName = "target"
+	labelModule model.LabelName = "module"
+)
+
+// collector probes every target when collected.
+type collector struct {
+	log     log.Logger
+	targets []*target
+}
+
+// target is a Target ready to be probed.
+type target struct {
+	name   string
+	module Module
+	probe  func(ctx context.Context) (prober.Result, error)
+
+	successDesc    *prometheus.Desc
+	durationDesc   *prometheus.Desc
+	statusCodeDesc *prometheus.Desc
+	certExpiryDesc *prometheus.Desc
+	labelValues    []string
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	col := &collector{log: l}
+
+	for _, t := range c.Targets {
+		var (
+			module = c.Modules[t.Module]
+			addr   = t.Address
+		)
+
+		tt := &target{name: t.Name, module: module}
+		switch module.Prober {
+		case ProberHTTP:
+			client, err := newHTTPClient(module.HTTP)
+			if err != nil {
+				return nil, err
+			}
+			opts := prober.HTTPOptions{
+				Method:           module.HTTP.Method,
+				Headers:          module.HTTP.Headers,
+				ValidStatusCodes: module.HTTP.ValidStatusCodes,
+			}
+			tt.probe = func(ctx context.Context) (prober.Result, error) { return prober.HTTP(ctx, client, opts, addr) }
+		case ProberTCP:
+			var tlsConfig *tls.Config
+			if module.TCP.TLS {
+				var err error
+				tlsConfig, err = config_util.NewTLSConfig(&module.TCP.TLSConfig)
+				if err != nil {
+					return nil, err
+				}
+			}
+			tt.probe = func(ctx context.Context) (prober.Result, error) { return prober.TCP(ctx, tlsConfig, addr) }
+		case ProberICMP:
+			tt.probe = func(ctx context.Context) (prober.Result, error) { return prober.ICMP(ctx, addr) }
+		}
+
+		names := []string{string(labelTarget), string(labelModule)}
+		tt.labelValues = []string{t.Name, t.Module}
+		for _, name := range sortedLabelNames(t.Labels) {
+			names = append(names, string(name))
+			tt.labelValues = append(tt.labelValues, string(t.Labels[name]))
+		}
+
+		tt.successDesc = prometheus.NewDesc("probe_success", "Whether the probe succeeded.", names, nil)
+		tt.durationDesc = prometheus.NewDesc("probe_duration_seconds", "How long the probe took in seconds.", names, nil)
+		tt.statusCodeDesc = prometheus.NewDesc("probe_http_status_code", "Status code of the HTTP response.", names, nil)
+		tt.certExpiryDesc = prometheus.NewDesc("probe_ssl_earliest_cert_expiry", "Earliest expiry of the certificates presented by the target as a Unix timestamp.", names, nil)
+
+		col.targets = append(col.targets, tt)
+	}
+
+	return col, nil
+}
+
+func sortedLabelNames(ls model.LabelSet) []model.LabelName {
+	names := make([]model.LabelName, 0, len(ls))
+	for name := range ls {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func newHTTPClient(cfg HTTPProbe) (*http.Client, error) {
+	tlsConfig, err := config_util.NewTLSConfig(&cfg.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+	}
+	if cfg.NoFollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client, nil
+}
+
+// Describe implements prometheus.Collector. Nothing is sent since the label
+// names of each target depend on its configured labels, making collector an
+// unchecked collector.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector. Targets are probed concurrently.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for _, t := range c.targets {
+		wg.Add(1)
+		go func(t *target) {
+			defer wg.Done()
+			c.collectTarget(t, ch)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (c *collector) collectTarget(t *target, ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.module.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	res, err := t.probe(ctx)
+	duration := time.Since(start)
+
+	success := 1.0
+	if err != nil {
+		level.Debug(c.log).Log("msg", "probe failed", "target", t.name, "err", err)
+		success = 0
+	}
+
+	ch <- prometheus.MustNewConstMetric(t.successDesc, prometheus.GaugeValue, success, t.labelValues...)
+	ch <- prometheus.MustNewConstMetric(t.durationDesc, prometheus.GaugeValue, duration.Seconds(), t.labelValues...)
+	if t.module.Prober == ProberHTTP {
+		ch <- prometheus.MustNewConstMetric(t.statusCodeDesc, prometheus.GaugeValue, float64(res.StatusCode), t.labelValues...)
+	}
+	if !res.CertExpiry.IsZero() {
+		ch <- prometheus.MustNewConstMetric(t.certExpiryDesc, prometheus.GaugeValue, float64(res.CertExpiry.Unix()), t.labelValues...)
+	}
+}
@@ -0,0 +1,237 @@
+// Package prober implements probes checking the availability of endpoints
+// over HTTP, TCP, and ICMP.
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Result holds the details of a probe. Fields which don't apply to a
+// prober are left empty.
+type Result struct {
+	// StatusCode is the status code of the HTTP response.
+	StatusCode int
+	// CertExpiry is the earliest expiry of the certificates presented by the
+	// target.
+	CertExpiry time.Time
+}
+
+// HTTPOptions configures HTTP probes.
+type HTTPOptions struct {
+	// Method of the request.
+	Method string
+
+	// Headers to send with the request.
+	Headers map[string]string
+
+	// ValidStatusCodes are the status codes for which the probe succeeds. An
+	// empty list allows any 2xx status code.
+	ValidStatusCodes []int
+}
+
+// HTTP sends a request to url. The probe succeeds if the response has a
+// valid status code.
+func HTTP(ctx context.Context, client *http.Client, opts HTTPOptions, url string) (Result, error) {
+	var res Result
+
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return res, err
+	}
+	for k, v := range opts.Headers {
+		// The Host header is ignored by http.Client unless set on the request.
+		if http.CanonicalHeaderKey(k) == "Host" {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	res.StatusCode = resp.StatusCode
+	if resp.TLS != nil {
+		res.CertExpiry = earliestExpiry(resp.TLS.PeerCertificates)
+	}
+
+	if !validStatusCode(opts.ValidStatusCodes, resp.StatusCode) {
+		return res, fmt.Errorf("invalid status code %d", resp.StatusCode)
+	}
+	return res, nil
+}
+
+func validStatusCode(valid []int, code int) bool {
+	if len(valid) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, v := range valid {
+		if v == code {
+			return true
+		}
+	}
+	return false
+}
+
+// TCP connects to addr, performing a TLS handshake if tlsConfig is non-nil.
+// The probe succeeds if the connection can be established.
+func TCP(ctx context.Context, tlsConfig *tls.Config, addr string) (Result, error) {
+	var (
+		res    Result
+		dialer net.Dialer
+	)
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return res, err
+	}
+	defer conn.Close()
+
+	if tlsConfig == nil {
+		return res, nil
+	}
+
+	cfg := tlsConfig.Clone()
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return res, err
+		}
+		cfg.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = tlsConn.SetDeadline(deadline)
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return res, err
+	}
+	res.CertExpiry = earliestExpiry(tlsConn.ConnectionState().PeerCertificates)
+	return res, nil
+}
+
+func earliestExpiry(certs []*x509.Certificate) time.Time {
+	var earliest time.Time
+	for _, cert := range certs {
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	return earliest
+}
+
+// ICMP sends an echo request to host. The probe succeeds if an echo reply is
+// received.
+//
+// Sending ICMP packets requires a raw socket, which needs the CAP_NET_RAW
+// capability on Linux. When a raw socket can't be opened, an unprivileged
+// datagram socket is used instead, which Linux only allows for groups in
+// the net.ipv4.ping_group_range sysctl.
+func ICMP(ctx context.Context, host string) (Result, error) {
+	var res Result
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return res, err
+	} else if len(ips) == 0 {
+		return res, fmt.Errorf("no addresses found for %s", host)
+	}
+	ip := ips[0].IP
+
+	var (
+		proto        int
+		listenAddr   string
+		networks     [2]string // privileged, unprivileged
+		requestType  icmp.Type
+		responseType icmp.Type
+	)
+	if ip.To4() != nil {
+		proto, listenAddr = 1, "0.0.0.0"
+		networks = [2]string{"ip4:icmp", "udp4"}
+		requestType, responseType = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	} else {
+		proto, listenAddr = 58, "::"
+		networks = [2]string{"ip6:ipv6-icmp", "udp6"}
+		requestType, responseType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	var (
+		dst          net.Addr = &net.IPAddr{IP: ip}
+		unprivileged bool
+	)
+	conn, err := icmp.ListenPacket(networks[0], listenAddr)
+	if err != nil {
+		conn, err = icmp.ListenPacket(networks[1], listenAddr)
+		if err != nil {
+			return res, fmt.Errorf("failed to open ICMP socket: %w", err)
+		}
+		dst, unprivileged = &net.UDPAddr{IP: ip}, true
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var (
+		id  = os.Getpid() & 0xffff
+		seq = rand.Intn(0xffff)
+	)
+	req, err := (&icmp.Message{
+		Type: requestType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("Grafana Agent")},
+	}).Marshal(nil)
+	if err != nil {
+		return res, err
+	}
+	if _, err := conn.WriteTo(req, dst); err != nil {
+		return res, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return res, err
+		}
+
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || msg.Type != responseType {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq {
+			continue
+		}
+		// The kernel replaces the ID of echo requests sent from unprivileged
+		// sockets, so it can only be checked for raw sockets.
+		if !unprivileged && echo.ID != id {
+			continue
+		}
+		return res, nil
+	}
+}
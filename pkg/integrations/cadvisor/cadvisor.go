@@ -0,0 +1,111 @@
+// Package cadvisor implements an integration which collects container
+// metrics from the Docker Engine API, using the metric names of
+// https://github.com/google/cadvisor so existing dashboards keep working.
+package cadvisor
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+)
+
+// DefaultConfig is the default config for cadvisor.
+var DefaultConfig = Config{
+	DockerHost:        "unix:///var/run/docker.sock",
+	IncludeContainers: ".+",
+	Timeout:           10 * time.Second,
+}
+
+// Config controls the cadvisor integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// DockerHost is the address of the Docker daemon.
+	DockerHost string `yaml:"docker_host,omitempty"`
+
+	// IncludeContainers and ExcludeContainers are regular expressions
+	// matched against container names. A container is only collected if its
+	// name matches IncludeContainers and doesn't match ExcludeContainers.
+	IncludeContainers string `yaml:"include_containers,omitempty"`
+	ExcludeContainers string `yaml:"exclude_containers,omitempty"`
+
+	// IncludeLabels only collects containers which have all of the given
+	// Docker labels, such as com.docker.compose.project.
+	IncludeLabels map[string]string `yaml:"include_labels,omitempty"`
+
+	// DockerLabels lists the Docker labels of containers to expose as
+	// container_label_<name> labels.
+	DockerLabels []string `yaml:"docker_labels,omitempty"`
+
+	// Timeout of requesting the stats of a container.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if _, err := regexp.Compile(c.IncludeContainers); err != nil {
+		return fmt.Errorf("invalid include_containers: %w", err)
+	}
+	if _, err := regexp.Compile(c.ExcludeContainers); err != nil {
+		return fmt.Errorf("invalid exclude_containers: %w", err)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+
+	labels := make(map[string]string, len(c.DockerLabels))
+	for _, l := range c.DockerLabels {
+		name := dockerLabelName(l)
+		if orig, ok := labels[name]; ok {
+			return fmt.Errorf("docker_labels %s and %s both map to the %s label", orig, l, name)
+		}
+		labels[name] = l
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "cadvisor"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new cadvisor integration. The stats of every running
+// container are requested each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	cli, err := client.NewClientWithOpts(client.WithHost(c.DockerHost), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+
+	col, err := newCollector(l, cli, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
@@ -0,0 +1,111 @@
+package cadvisor
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`docker_labels: [com.docker.compose.service]`), &cfg))
+	require.Equal(t, DefaultConfig.DockerHost, cfg.DockerHost)
+
+	err := yaml.UnmarshalStrict([]byte(`docker_labels: [a.b, a-b]`), &cfg)
+	require.EqualError(t, err, "docker_labels a.b and a-b both map to the container_label_a_b label")
+
+	err = yaml.UnmarshalStrict([]byte(`include_containers: "("`), &cfg)
+	require.Error(t, err)
+}
+
+type fakeClient struct {
+	containers []types.Container
+	stats      map[string]types.StatsJSON
+	filters    []string
+}
+
+func (c *fakeClient) ContainerList(_ context.Context, opts types.ContainerListOptions) ([]types.Container, error) {
+	c.filters = opts.Filters.Get("label")
+	return c.containers, nil
+}
+
+func (c *fakeClient) ContainerStatsOneShot(_ context.Context, id string) (types.ContainerStats, error) {
+	b, err := json.Marshal(c.stats[id])
+	if err != nil {
+		return types.ContainerStats{}, err
+	}
+	return types.ContainerStats{Body: ioutil.NopCloser(strings.NewReader(string(b)))}, nil
+}
+
+func TestCollector(t *testing.T) {
+	var stats types.StatsJSON
+	stats.CPUStats.CPUUsage.TotalUsage = 2500000000
+	stats.MemoryStats = types.MemoryStats{
+		Usage: 1000,
+		Limit: 4000,
+		Stats: map[string]uint64{"inactive_file": 300, "file": 400, "anon": 500},
+	}
+	stats.Networks = map[string]types.NetworkStats{"eth0": {RxBytes: 10, TxBytes: 20}}
+	stats.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{
+		{Major: 8, Minor: 0, Op: "read", Value: 100},
+		{Major: 8, Minor: 0, Op: "write", Value: 200},
+		{Major: 8, Minor: 16, Op: "Read", Value: 5},
+	}
+
+	cli := &fakeClient{
+		containers: []types.Container{
+			{ID: "abc", Names: []string{"/web"}, Image: "nginx", Labels: map[string]string{"com.docker.compose.service": "frontend"}},
+			{ID: "def", Names: []string{"/sidecar"}, Image: "envoy"},
+		},
+		stats: map[string]types.StatsJSON{"abc": stats, "def": stats},
+	}
+
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+exclude_containers: side.*
+include_labels:
+  env: prod
+docker_labels: [com.docker.compose.service]
+`), &cfg))
+
+	col, err := newCollector(log.NewNopLogger(), cli, &cfg)
+	require.NoError(t, err)
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(col))
+
+	expect := `
+# HELP container_cpu_usage_seconds_total Cumulative cpu time consumed in seconds.
+# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{container_label_com_docker_compose_service="frontend",id="/docker/abc",image="nginx",name="web"} 2.5
+# HELP container_fs_reads_bytes_total Cumulative count of bytes read.
+# TYPE container_fs_reads_bytes_total counter
+container_fs_reads_bytes_total{container_label_com_docker_compose_service="frontend",device="8:0",id="/docker/abc",image="nginx",name="web"} 100
+container_fs_reads_bytes_total{container_label_com_docker_compose_service="frontend",device="8:16",id="/docker/abc",image="nginx",name="web"} 5
+# HELP container_memory_cache Number of bytes of page cache memory.
+# TYPE container_memory_cache gauge
+container_memory_cache{container_label_com_docker_compose_service="frontend",id="/docker/abc",image="nginx",name="web"} 400
+# HELP container_memory_working_set_bytes Current working set in bytes.
+# TYPE container_memory_working_set_bytes gauge
+container_memory_working_set_bytes{container_label_com_docker_compose_service="frontend",id="/docker/abc",image="nginx",name="web"} 700
+# HELP container_network_transmit_bytes_total Cumulative count of bytes transmitted.
+# TYPE container_network_transmit_bytes_total counter
+container_network_transmit_bytes_total{container_label_com_docker_compose_service="frontend",id="/docker/abc",image="nginx",interface="eth0",name="web"} 20
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect),
+		"container_cpu_usage_seconds_total",
+		"container_fs_reads_bytes_total",
+		"container_memory_cache",
+		"container_memory_working_set_bytes",
+		"container_network_transmit_bytes_total",
+	))
+	require.Equal(t, []string{"env=prod"}, cli.filters)
+}
@@ -0,0 +1,269 @@
+package cadvisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dockerClient is the subset of the Docker client used by collector.
+type dockerClient interface {
+	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
+	ContainerStatsOneShot(ctx context.Context, containerID string) (types.ContainerStats, error)
+}
+
+// value is a single sample of a metric. labels are the values of the
+// metric's extra labels.
+type value struct {
+	v      float64
+	labels []string
+}
+
+// metric creates samples from the stats of a container.
+type metric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	values    func(s *types.StatsJSON) []value
+}
+
+// collector requests the stats of every container when collected.
+type collector struct {
+	log     log.Logger
+	client  dockerClient
+	cfg     *Config
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+	metrics []metric
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// dockerLabelName returns the name of the label exposing a Docker label.
+func dockerLabelName(l string) string {
+	return "container_label_" + invalidLabelChars.ReplaceAllString(l, "_")
+}
+
+func newCollector(l log.Logger, cli dockerClient, c *Config) (*collector, error) {
+	col := &collector{log: l, client: cli, cfg: c}
+
+	var err error
+	if col.include, err = regexp.Compile("^(?:" + c.IncludeContainers + ")$"); err != nil {
+		return nil, err
+	}
+	if c.ExcludeContainers != "" {
+		if col.exclude, err = regexp.Compile("^(?:" + c.ExcludeContainers + ")$"); err != nil {
+			return nil, err
+		}
+	}
+
+	labelNames := []string{"id", "name", "image"}
+	for _, l := range c.DockerLabels {
+		labelNames = append(labelNames, dockerLabelName(l))
+	}
+
+	newMetric := func(name, help string, valueType prometheus.ValueType, extraLabels []string, values func(s *types.StatsJSON) []value) {
+		names := append(append([]string{}, labelNames...), extraLabels...)
+		col.metrics = append(col.metrics, metric{
+			desc:      prometheus.NewDesc(name, help, names, nil),
+			valueType: valueType,
+			values:    values,
+		})
+	}
+	single := func(f func(s *types.StatsJSON) float64) func(s *types.StatsJSON) []value {
+		return func(s *types.StatsJSON) []value { return []value{{v: f(s)}} }
+	}
+
+	newMetric("container_cpu_usage_seconds_total", "Cumulative cpu time consumed in seconds.", prometheus.CounterValue, nil,
+		single(func(s *types.StatsJSON) float64 { return nanoseconds(s.CPUStats.CPUUsage.TotalUsage) }))
+	newMetric("container_cpu_user_seconds_total", "Cumulative user cpu time consumed in seconds.", prometheus.CounterValue, nil,
+		single(func(s *types.StatsJSON) float64 { return nanoseconds(s.CPUStats.CPUUsage.UsageInUsermode) }))
+	newMetric("container_cpu_system_seconds_total", "Cumulative system cpu time consumed in seconds.", prometheus.CounterValue, nil,
+		single(func(s *types.StatsJSON) float64 { return nanoseconds(s.CPUStats.CPUUsage.UsageInKernelmode) }))
+	newMetric("container_cpu_cfs_periods_total", "Number of elapsed enforcement period intervals.", prometheus.CounterValue, nil,
+		single(func(s *types.StatsJSON) float64 { return float64(s.CPUStats.ThrottlingData.Periods) }))
+	newMetric("container_cpu_cfs_throttled_periods_total", "Number of throttled period intervals.", prometheus.CounterValue, nil,
+		single(func(s *types.StatsJSON) float64 { return float64(s.CPUStats.ThrottlingData.ThrottledPeriods) }))
+	newMetric("container_cpu_cfs_throttled_seconds_total", "Total time duration the container has been throttled.", prometheus.CounterValue, nil,
+		single(func(s *types.StatsJSON) float64 { return nanoseconds(s.CPUStats.ThrottlingData.ThrottledTime) }))
+
+	newMetric("container_memory_usage_bytes", "Current memory usage in bytes, including all memory regardless of when it was accessed.", prometheus.GaugeValue, nil,
+		single(func(s *types.StatsJSON) float64 { return float64(s.MemoryStats.Usage) }))
+	newMetric("container_memory_working_set_bytes", "Current working set in bytes.", prometheus.GaugeValue, nil,
+		single(func(s *types.StatsJSON) float64 { return float64(workingSet(s.MemoryStats)) }))
+	newMetric("container_memory_cache", "Number of bytes of page cache memory.", prometheus.GaugeValue, nil,
+		single(func(s *types.StatsJSON) float64 { return float64(memoryStat(s.MemoryStats, "cache", "file")) }))
+	newMetric("container_memory_rss", "Size of RSS in bytes.", prometheus.GaugeValue, nil,
+		single(func(s *types.StatsJSON) float64 { return float64(memoryStat(s.MemoryStats, "rss", "anon")) }))
+	newMetric("container_memory_failcnt", "Number of memory usage hits limits.", prometheus.CounterValue, nil,
+		single(func(s *types.StatsJSON) float64 { return float64(s.MemoryStats.Failcnt) }))
+	newMetric("container_spec_memory_limit_bytes", "Memory limit for the container.", prometheus.GaugeValue, nil,
+		single(func(s *types.StatsJSON) float64 { return float64(s.MemoryStats.Limit) }))
+
+	network := func(f func(n types.NetworkStats) uint64) func(s *types.StatsJSON) []value {
+		return func(s *types.StatsJSON) []value {
+			res := make([]value, 0, len(s.Networks))
+			for iface, n := range s.Networks {
+				res = append(res, value{v: float64(f(n)), labels: []string{iface}})
+			}
+			return res
+		}
+	}
+	iface := []string{"interface"}
+	newMetric("container_network_receive_bytes_total", "Cumulative count of bytes received.", prometheus.CounterValue, iface,
+		network(func(n types.NetworkStats) uint64 { return n.RxBytes }))
+	newMetric("container_network_receive_packets_total", "Cumulative count of packets received.", prometheus.CounterValue, iface,
+		network(func(n types.NetworkStats) uint64 { return n.RxPackets }))
+	newMetric("container_network_receive_errors_total", "Cumulative count of errors encountered while receiving.", prometheus.CounterValue, iface,
+		network(func(n types.NetworkStats) uint64 { return n.RxErrors }))
+	newMetric("container_network_receive_packets_dropped_total", "Cumulative count of packets dropped while receiving.", prometheus.CounterValue, iface,
+		network(func(n types.NetworkStats) uint64 { return n.RxDropped }))
+	newMetric("container_network_transmit_bytes_total", "Cumulative count of bytes transmitted.", prometheus.CounterValue, iface,
+		network(func(n types.NetworkStats) uint64 { return n.TxBytes }))
+	newMetric("container_network_transmit_packets_total", "Cumulative count of packets transmitted.", prometheus.CounterValue, iface,
+		network(func(n types.NetworkStats) uint64 { return n.TxPackets }))
+	newMetric("container_network_transmit_errors_total", "Cumulative count of errors encountered while transmitting.", prometheus.CounterValue, iface,
+		network(func(n types.NetworkStats) uint64 { return n.TxErrors }))
+	newMetric("container_network_transmit_packets_dropped_total", "Cumulative count of packets dropped while transmitting.", prometheus.CounterValue, iface,
+		network(func(n types.NetworkStats) uint64 { return n.TxDropped }))
+
+	device := []string{"device"}
+	newMetric("container_fs_reads_bytes_total", "Cumulative count of bytes read.", prometheus.CounterValue, device,
+		func(s *types.StatsJSON) []value { return blkioBytes(s.BlkioStats, "read") })
+	newMetric("container_fs_writes_bytes_total", "Cumulative count of bytes written.", prometheus.CounterValue, device,
+		func(s *types.StatsJSON) []value { return blkioBytes(s.BlkioStats, "write") })
+
+	newMetric("container_last_seen", "Last time a container was seen by the exporter.", prometheus.GaugeValue, nil,
+		single(func(*types.StatsJSON) float64 { return float64(time.Now().Unix()) }))
+
+	return col, nil
+}
+
+func nanoseconds(ns uint64) float64 {
+	return float64(ns) / float64(time.Second)
+}
+
+// memoryStat returns the first of keys found in the memory stats. Keys
+// differ between cgroups v1 and v2.
+func memoryStat(m types.MemoryStats, keys ...string) uint64 {
+	for _, k := range keys {
+		if v, ok := m.Stats[k]; ok {
+			return v
+		}
+	}
+	return 0
+}
+
+// workingSet calculates the working set the same way as cAdvisor: memory
+// usage minus inactive file pages, which the kernel can reclaim.
+func workingSet(m types.MemoryStats) uint64 {
+	inactive := memoryStat(m, "total_inactive_file", "inactive_file")
+	if inactive > m.Usage {
+		return 0
+	}
+	return m.Usage - inactive
+}
+
+// blkioBytes sums the bytes of the given operation per device.
+func blkioBytes(s types.BlkioStats, op string) []value {
+	var (
+		res     []value
+		devices = make(map[string]int)
+	)
+	for _, e := range s.IoServiceBytesRecursive {
+		if !strings.EqualFold(e.Op, op) {
+			continue
+		}
+		dev := fmt.Sprintf("%d:%d", e.Major, e.Minor)
+		if idx, ok := devices[dev]; ok {
+			res[idx].v += float64(e.Value)
+			continue
+		}
+		devices[dev] = len(res)
+		res = append(res, value{v: float64(e.Value), labels: []string{dev}})
+	}
+	return res
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+// Collect implements prometheus.Collector. Stats of containers are requested
+// concurrently.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	opts := types.ContainerListOptions{Filters: filters.NewArgs()}
+	for k, v := range c.cfg.IncludeLabels {
+		opts.Filters.Add("label", k+"="+v)
+	}
+	containers, err := c.client.ContainerList(ctx, opts)
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to list containers", "err", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, ctr := range containers {
+		name := containerName(ctr)
+		if !c.include.MatchString(name) || (c.exclude != nil && c.exclude.MatchString(name)) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(ctr types.Container, name string) {
+			defer wg.Done()
+			if err := c.collectContainer(ctx, ctr, name, ch); err != nil {
+				level.Warn(c.log).Log("msg", "failed to get container stats", "container", name, "err", err)
+			}
+		}(ctr, name)
+	}
+	wg.Wait()
+}
+
+func containerName(ctr types.Container) string {
+	if len(ctr.Names) == 0 {
+		return ctr.ID
+	}
+	return strings.TrimPrefix(ctr.Names[0], "/")
+}
+
+func (c *collector) collectContainer(ctx context.Context, ctr types.Container, name string, ch chan<- prometheus.Metric) error {
+	resp, err := c.client.ContainerStatsOneShot(ctx, ctr.ID)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return err
+	}
+
+	labels := []string{"/docker/" + ctr.ID, name, ctr.Image}
+	for _, l := range c.cfg.DockerLabels {
+		labels = append(labels, ctr.Labels[l])
+	}
+
+	for _, m := range c.metrics {
+		for _, v := range m.values(&stats) {
+			values := append(append([]string{}, labels...), v.labels...)
+			ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, v.v, values...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Package cloudwatch_exporter implements an integration which pulls metrics
+// of AWS services from CloudWatch, using the metric names of
+// https://github.com/prometheus/cloudwatch_exporter.
+package cloudwatch_exporter //nolint:golint
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig is the default config for cloudwatch_exporter.
+var DefaultConfig = Config{
+	Period:  5 * time.Minute,
+	Delay:   5 * time.Minute,
+	Timeout: 30 * time.Second,
+}
+
+// DefaultMetric holds default settings for a Metric.
+var DefaultMetric = Metric{
+	Statistics: []string{"Average"},
+}
+
+// Config controls the cloudwatch_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// Region to request metrics from.
+	Region string `yaml:"region"`
+
+	// Endpoint overrides the CloudWatch API endpoint.
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// AccessKey and SecretKey are static credentials. When unset, the
+	// default credential chain is used, which includes environment
+	// variables, the shared credentials file, and instance roles.
+	AccessKey string             `yaml:"access_key,omitempty"`
+	SecretKey config_util.Secret `yaml:"secret_key,omitempty"`
+
+	// Profile of the shared credentials file to use.
+	Profile string `yaml:"profile,omitempty"`
+
+	// RoleARN is a role to assume before requesting metrics, optionally
+	// with an ExternalID required by the trust policy of the role.
+	RoleARN    string `yaml:"role_arn,omitempty"`
+	ExternalID string `yaml:"external_id,omitempty"`
+
+	// Period is the default granularity of requested datapoints.
+	Period time.Duration `yaml:"period,omitempty"`
+
+	// Delay is how far in the past the requested period ends, since
+	// CloudWatch datapoints can take several minutes to become available.
+	Delay time.Duration `yaml:"delay,omitempty"`
+
+	// Timeout of requesting all metrics.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Metrics to request.
+	Metrics []Metric `yaml:"metrics,omitempty"`
+}
+
+// Metric selects a CloudWatch metric to request.
+type Metric struct {
+	// Namespace of the metric, such as AWS/EC2.
+	Namespace string `yaml:"namespace"`
+
+	// Name of the metric, such as CPUUtilization.
+	Name string `yaml:"name"`
+
+	// Dimensions the metric must have, which become labels of the exposed
+	// metrics. Metrics with other dimensions are ignored.
+	Dimensions []string `yaml:"dimensions,omitempty"`
+
+	// DimensionSelections limits the values of dimensions, such as a list
+	// of instance IDs.
+	DimensionSelections map[string][]string `yaml:"dimension_selections,omitempty"`
+
+	// Statistics to request, such as Average, Sum, or p99.
+	Statistics []string `yaml:"statistics,omitempty"`
+
+	// Period overrides the Period of the Config.
+	Period time.Duration `yaml:"period,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Metric.
+func (m *Metric) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*m = DefaultMetric
+
+	type plain Metric
+	return unmarshal((*plain)(m))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.Region == "" {
+		return fmt.Errorf("cloudwatch_exporter region must be set")
+	}
+	if c.ExternalID != "" && c.RoleARN == "" {
+		return fmt.Errorf("cloudwatch_exporter external_id requires role_arn to be set")
+	}
+	if c.Period <= 0 || c.Period%time.Minute != 0 {
+		return fmt.Errorf("cloudwatch_exporter period must be a multiple of 1m")
+	}
+	if c.Delay < 0 {
+		return fmt.Errorf("cloudwatch_exporter delay must not be negative")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("cloudwatch_exporter timeout must be greater than 0")
+	}
+
+	seen := make(map[string]struct{}, len(c.Metrics))
+	for idx := range c.Metrics {
+		m := &c.Metrics[idx]
+		if m.Namespace == "" || m.Name == "" {
+			return fmt.Errorf("cloudwatch_exporter metric index %d must have a namespace and name", idx)
+		}
+
+		// Metrics are exposed with their dimensions as labels, which must be
+		// the same for every series of a metric name.
+		key := m.Namespace + "/" + m.Name
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("cloudwatch_exporter metric %s is configured twice", key)
+		}
+		seen[key] = struct{}{}
+
+		if len(m.Statistics) == 0 {
+			return fmt.Errorf("cloudwatch_exporter metric %s must have at least one statistic", key)
+		}
+		for dim := range m.DimensionSelections {
+			if !contains(m.Dimensions, dim) {
+				return fmt.Errorf("cloudwatch_exporter metric %s selects values of dimension %s which isn't in dimensions", key, dim)
+			}
+		}
+		if m.Period == 0 {
+			m.Period = c.Period
+		} else if m.Period < 0 || m.Period%time.Minute != 0 {
+			return fmt.Errorf("cloudwatch_exporter metric %s period must be a multiple of 1m", key)
+		}
+	}
+	return nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "cloudwatch_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new cloudwatch_exporter integration. Metrics are requested
+// from CloudWatch each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	client, err := newCloudWatchClient(c)
+	if err != nil {
+		return nil, err
+	}
+	col := newCollector(l, c, client)
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
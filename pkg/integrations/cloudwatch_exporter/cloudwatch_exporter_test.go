@@ -0,0 +1,181 @@
+package cloudwatch_exporter //nolint:golint
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+region: us-east-1
+role_arn: arn:aws:iam::123456789012:role/cloudwatch
+external_id: agent
+metrics:
+- namespace: AWS/EC2
+  name: CPUUtilization
+  dimensions: [InstanceId]
+- namespace: AWS/ELB
+  name: RequestCount
+  dimensions: [LoadBalancerName]
+  statistics: [Sum]
+  period: 1m
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Average"}, cfg.Metrics[0].Statistics)
+	require.Equal(t, DefaultConfig.Period, cfg.Metrics[0].Period)
+	require.Equal(t, time.Minute, cfg.Metrics[1].Period)
+
+	tt := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{
+			name: "no region",
+			cfg:  `metrics: []`,
+			err:  "cloudwatch_exporter region must be set",
+		},
+		{
+			name: "external id without role",
+			cfg:  `{region: us-east-1, external_id: agent}`,
+			err:  "cloudwatch_exporter external_id requires role_arn to be set",
+		},
+		{
+			name: "period not in minutes",
+			cfg:  `{region: us-east-1, period: 90s}`,
+			err:  "cloudwatch_exporter period must be a multiple of 1m",
+		},
+		{
+			name: "duplicate metric",
+			cfg:  `{region: us-east-1, metrics: [{namespace: AWS/EC2, name: CPUUtilization}, {namespace: AWS/EC2, name: CPUUtilization}]}`,
+			err:  "cloudwatch_exporter metric AWS/EC2/CPUUtilization is configured twice",
+		},
+		{
+			name: "selection of unknown dimension",
+			cfg:  `{region: us-east-1, metrics: [{namespace: AWS/EC2, name: CPUUtilization, dimension_selections: {InstanceId: [i-1]}}]}`,
+			err:  "cloudwatch_exporter metric AWS/EC2/CPUUtilization selects values of dimension InstanceId which isn't in dimensions",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg), tc.err)
+		})
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	for in, expect := range map[string]string{
+		"AWS/EC2":          "aws_ec2",
+		"CPUUtilization":   "cpuutilization",
+		"NetworkIn":        "network_in",
+		"InstanceId":       "instance_id",
+		"p99":              "p99",
+		"HTTPCode_ELB_5XX": "httpcode_elb_5xx",
+	} {
+		require.Equal(t, expect, toSnakeCase(in), in)
+	}
+}
+
+type fakeClient struct {
+	metrics []*cloudwatch.Metric
+	values  map[string]float64 // keyed by instance ID and statistic
+	input   *cloudwatch.GetMetricDataInput
+}
+
+func (c *fakeClient) ListMetricsPagesWithContext(_ aws.Context, _ *cloudwatch.ListMetricsInput, fn func(*cloudwatch.ListMetricsOutput, bool) bool, _ ...request.Option) error {
+	fn(&cloudwatch.ListMetricsOutput{Metrics: c.metrics}, true)
+	return nil
+}
+
+func (c *fakeClient) GetMetricDataPagesWithContext(_ aws.Context, in *cloudwatch.GetMetricDataInput, fn func(*cloudwatch.GetMetricDataOutput, bool) bool, _ ...request.Option) error {
+	c.input = in
+
+	var out cloudwatch.GetMetricDataOutput
+	for _, q := range in.MetricDataQueries {
+		key := aws.StringValue(q.MetricStat.Metric.Dimensions[0].Value) + "/" + aws.StringValue(q.MetricStat.Stat)
+		res := &cloudwatch.MetricDataResult{Id: q.Id}
+		if v, ok := c.values[key]; ok {
+			res.Values = []*float64{aws.Float64(v)}
+		}
+		out.MetricDataResults = append(out.MetricDataResults, res)
+	}
+	fn(&out, true)
+	return nil
+}
+
+func ec2Metric(dims ...string) *cloudwatch.Metric {
+	m := &cloudwatch.Metric{Namespace: aws.String("AWS/EC2"), MetricName: aws.String("CPUUtilization")}
+	for i := 0; i < len(dims); i += 2 {
+		m.Dimensions = append(m.Dimensions, &cloudwatch.Dimension{Name: aws.String(dims[i]), Value: aws.String(dims[i+1])})
+	}
+	return m
+}
+
+func TestCollector(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+region: us-east-1
+metrics:
+- namespace: AWS/EC2
+  name: CPUUtilization
+  dimensions: [InstanceId]
+  dimension_selections:
+    InstanceId: [i-1, i-2]
+  statistics: [Average, Maximum]
+`), &cfg)
+	require.NoError(t, err)
+
+	client := &fakeClient{
+		metrics: []*cloudwatch.Metric{
+			ec2Metric("InstanceId", "i-1"),
+			ec2Metric("InstanceId", "i-2"),
+			// Not selected.
+			ec2Metric("InstanceId", "i-3"),
+			// Has another dimension.
+			ec2Metric("InstanceId", "i-1", "AutoScalingGroupName", "asg"),
+		},
+		values: map[string]float64{
+			"i-1/Average": 12.5,
+			"i-1/Maximum": 40,
+			"i-2/Average": 3,
+			// i-2 has no Maximum datapoint in the period.
+		},
+	}
+
+	col := newCollector(log.NewNopLogger(), &cfg, client)
+	col.now = func() time.Time { return time.Date(2021, 6, 1, 12, 30, 15, 0, time.UTC) }
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(col))
+
+	expect := `
+# HELP aws_ec2_cpuutilization_average CloudWatch metric AWS/EC2 CPUUtilization Statistic: Average
+# TYPE aws_ec2_cpuutilization_average gauge
+aws_ec2_cpuutilization_average{instance_id="i-1"} 12.5
+aws_ec2_cpuutilization_average{instance_id="i-2"} 3
+# HELP aws_ec2_cpuutilization_maximum CloudWatch metric AWS/EC2 CPUUtilization Statistic: Maximum
+# TYPE aws_ec2_cpuutilization_maximum gauge
+aws_ec2_cpuutilization_maximum{instance_id="i-1"} 40
+# HELP cloudwatch_exporter_scrape_error Non-zero if this scrape failed.
+# TYPE cloudwatch_exporter_scrape_error gauge
+cloudwatch_exporter_scrape_error 0
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect)))
+
+	// The requested period ends delay before now, aligned to the minute.
+	require.Equal(t, time.Date(2021, 6, 1, 12, 25, 0, 0, time.UTC), aws.TimeValue(client.input.EndTime))
+	require.Equal(t, time.Date(2021, 6, 1, 12, 20, 0, 0, time.UTC), aws.TimeValue(client.input.StartTime))
+	require.Len(t, client.input.MetricDataQueries, 4)
+}
@@ -0,0 +1,260 @@
+package cloudwatch_exporter //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxQueriesPerRequest is the limit of queries in a GetMetricData request.
+const maxQueriesPerRequest = 500
+
+// cloudWatchClient is the subset of the CloudWatch API used by the
+// collector.
+type cloudWatchClient interface {
+	ListMetricsPagesWithContext(aws.Context, *cloudwatch.ListMetricsInput, func(*cloudwatch.ListMetricsOutput, bool) bool, ...request.Option) error
+	GetMetricDataPagesWithContext(aws.Context, *cloudwatch.GetMetricDataInput, func(*cloudwatch.GetMetricDataOutput, bool) bool, ...request.Option) error
+}
+
+func newCloudWatchClient(c *Config) (cloudWatchClient, error) {
+	creds := credentials.NewStaticCredentials(c.AccessKey, string(c.SecretKey), "")
+	if c.AccessKey == "" && c.SecretKey == "" {
+		creds = nil
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config: aws.Config{
+			Endpoint:    &c.Endpoint,
+			Region:      &c.Region,
+			Credentials: creds,
+		},
+		Profile: c.Profile,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create aws session: %w", err)
+	}
+
+	if c.RoleARN != "" {
+		creds := stscreds.NewCredentials(sess, c.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			if c.ExternalID != "" {
+				p.ExternalID = aws.String(c.ExternalID)
+			}
+		})
+		return cloudwatch.New(sess, &aws.Config{Credentials: creds}), nil
+	}
+	return cloudwatch.New(sess), nil
+}
+
+type collector struct {
+	log     log.Logger
+	client  cloudWatchClient
+	delay   time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	metrics     []*metric
+	scrapeError *prometheus.Desc
+}
+
+// metric is a configured Metric with the descriptors of its statistics.
+type metric struct {
+	cfg   Metric
+	descs map[string]*prometheus.Desc
+}
+
+// query is a statistic of a single CloudWatch metric.
+type query struct {
+	cwMetric    *cloudwatch.Metric
+	statistic   string
+	labelValues []string
+}
+
+func newCollector(l log.Logger, c *Config, client cloudWatchClient) *collector {
+	col := &collector{
+		log:     l,
+		client:  client,
+		delay:   c.Delay,
+		timeout: c.Timeout,
+		now:     time.Now,
+
+		scrapeError: prometheus.NewDesc("cloudwatch_exporter_scrape_error", "Non-zero if this scrape failed.", nil, nil),
+	}
+
+	for _, m := range c.Metrics {
+		labels := make([]string, 0, len(m.Dimensions))
+		for _, d := range m.Dimensions {
+			labels = append(labels, toSnakeCase(d))
+		}
+
+		mm := &metric{cfg: m, descs: make(map[string]*prometheus.Desc, len(m.Statistics))}
+		for _, stat := range m.Statistics {
+			name := strings.Join([]string{toSnakeCase(m.Namespace), toSnakeCase(m.Name), toSnakeCase(stat)}, "_")
+			help := fmt.Sprintf("CloudWatch metric %s %s Statistic: %s", m.Namespace, m.Name, stat)
+			mm.descs[stat] = prometheus.NewDesc(name, help, labels, nil)
+		}
+		col.metrics = append(col.metrics, mm)
+	}
+	return col
+}
+
+// toSnakeCase converts CloudWatch names, such as AWS/EC2 or NetworkIn, into
+// snake case usable in metric and label names.
+func toSnakeCase(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && unicode.IsLower(runes[i-1]) {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		case unicode.IsLower(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+// Describe implements prometheus.Collector. Nothing is sent since metrics
+// are only known once they are listed, making collector an unchecked
+// collector.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var scrapeError float64
+	for _, m := range c.metrics {
+		if err := c.collectMetric(ctx, m, ch); err != nil {
+			level.Error(c.log).Log("msg", "failed to get CloudWatch metric", "namespace", m.cfg.Namespace, "name", m.cfg.Name, "err", err)
+			scrapeError = 1
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError)
+}
+
+func (c *collector) collectMetric(ctx context.Context, m *metric, ch chan<- prometheus.Metric) error {
+	queries, err := c.listQueries(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	end := c.now().Add(-c.delay).Truncate(time.Minute)
+	start := end.Add(-m.cfg.Period)
+
+	for len(queries) > 0 {
+		batch := queries
+		if len(batch) > maxQueriesPerRequest {
+			batch = batch[:maxQueriesPerRequest]
+		}
+		queries = queries[len(batch):]
+
+		input := &cloudwatch.GetMetricDataInput{
+			StartTime: aws.Time(start),
+			EndTime:   aws.Time(end),
+			ScanBy:    aws.String(cloudwatch.ScanByTimestampDescending),
+		}
+		for i, q := range batch {
+			input.MetricDataQueries = append(input.MetricDataQueries, &cloudwatch.MetricDataQuery{
+				Id: aws.String(fmt.Sprintf("q%d", i)),
+				MetricStat: &cloudwatch.MetricStat{
+					Metric: q.cwMetric,
+					Period: aws.Int64(int64(m.cfg.Period / time.Second)),
+					Stat:   aws.String(q.statistic),
+				},
+			})
+		}
+
+		err := c.client.GetMetricDataPagesWithContext(ctx, input, func(out *cloudwatch.GetMetricDataOutput, _ bool) bool {
+			for _, res := range out.MetricDataResults {
+				var i int
+				if _, err := fmt.Sscanf(aws.StringValue(res.Id), "q%d", &i); err != nil || i >= len(batch) || len(res.Values) == 0 {
+					continue
+				}
+				q := batch[i]
+				ch <- prometheus.MustNewConstMetric(m.descs[q.statistic], prometheus.GaugeValue, aws.Float64Value(res.Values[0]), q.labelValues...)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listQueries lists the CloudWatch metrics matching m and returns a query for
+// each of their statistics.
+func (c *collector) listQueries(ctx context.Context, m *metric) ([]query, error) {
+	input := &cloudwatch.ListMetricsInput{
+		Namespace:  aws.String(m.cfg.Namespace),
+		MetricName: aws.String(m.cfg.Name),
+	}
+	for _, d := range m.cfg.Dimensions {
+		input.Dimensions = append(input.Dimensions, &cloudwatch.DimensionFilter{Name: aws.String(d)})
+	}
+
+	var queries []query
+	err := c.client.ListMetricsPagesWithContext(ctx, input, func(out *cloudwatch.ListMetricsOutput, _ bool) bool {
+		for _, cwMetric := range out.Metrics {
+			labelValues, ok := matchDimensions(m.cfg, cwMetric.Dimensions)
+			if !ok {
+				continue
+			}
+			for _, stat := range m.cfg.Statistics {
+				queries = append(queries, query{cwMetric: cwMetric, statistic: stat, labelValues: labelValues})
+			}
+		}
+		return true
+	})
+
+	// Sort for a stable order of queries across scrapes.
+	sort.SliceStable(queries, func(i, j int) bool {
+		return strings.Join(queries[i].labelValues, "\xff") < strings.Join(queries[j].labelValues, "\xff")
+	})
+	return queries, err
+}
+
+// matchDimensions returns the values of the configured dimensions. ok is
+// false if the metric has other dimensions or a value isn't selected.
+func matchDimensions(m Metric, dims []*cloudwatch.Dimension) (values []string, ok bool) {
+	if len(dims) != len(m.Dimensions) {
+		return nil, false
+	}
+
+	byName := make(map[string]string, len(dims))
+	for _, d := range dims {
+		byName[aws.StringValue(d.Name)] = aws.StringValue(d.Value)
+	}
+
+	values = make([]string, 0, len(m.Dimensions))
+	for _, name := range m.Dimensions {
+		v, found := byName[name]
+		if !found {
+			return nil, false
+		}
+		if sel, filtered := m.DimensionSelections[name]; filtered && !contains(sel, v) {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
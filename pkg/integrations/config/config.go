@@ -5,6 +5,7 @@ package config
 import (
 	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/relabel"
 )
 
@@ -15,13 +16,33 @@ import (
 //     Common config.Common `yaml:",inline"`
 //   }
 type Common struct {
-	Enabled              bool              `yaml:"enabled,omitempty"`
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Instance names this instance of the integration. It must be set when
+	// more than one instance of the same integration is configured and is used
+	// as the value of the instance label of its metrics.
+	Instance string `yaml:"instance,omitempty"`
+
 	ScrapeIntegration    *bool             `yaml:"scrape_integration,omitempty"`
 	ScrapeInterval       time.Duration     `yaml:"scrape_interval,omitempty"`
 	ScrapeTimeout        time.Duration     `yaml:"scrape_timeout,omitempty"`
 	RelabelConfigs       []*relabel.Config `yaml:"relabel_configs,omitempty"`
 	MetricRelabelConfigs []*relabel.Config `yaml:"metric_relabel_configs,omitempty"`
 	WALTruncateFrequency time.Duration     `yaml:"wal_truncate_frequency,omitempty"`
+
+	Autoscrape Autoscrape `yaml:"autoscrape,omitempty"`
+}
+
+// Autoscrape controls where the metrics of an automatically scraped
+// integration are sent.
+type Autoscrape struct {
+	// MetricsInstance is the name of a Prometheus instance config. When set,
+	// the integration's metrics use the remote_write and WAL settings of that
+	// instance instead of the integrations' prometheus_remote_write.
+	MetricsInstance string `yaml:"metrics_instance,omitempty"`
+
+	// ExtraLabels are added to every metric scraped from the integration.
+	ExtraLabels model.LabelSet `yaml:"extra_labels,omitempty"`
 }
 
 // ScrapeConfig is a subset of options used by integrations to inform how samples
@@ -36,6 +57,6 @@ type ScrapeConfig struct {
 	// MetricsPath is the path relative to the integration where metrics are exposed.
 	// It should match a route added to the router provided in Integration.RegisterRoutes.
 	// The path will be prepended by "/integrations/<integration name>" when read by
-	// the integrations manager.
+	// the integrations manager, followed by the instance name for named instances.
 	MetricsPath string
 }
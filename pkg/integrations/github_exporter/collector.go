@@ -0,0 +1,354 @@
+package github_exporter //nolint:golint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxConcurrentRequests limits how many repositories have their workflow
+// runs requested at once.
+const maxConcurrentRequests = 5
+
+// nextLink matches the URL of the next page in a Link header.
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// repository is a repository returned by the GitHub API.
+type repository struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Private  bool   `json:"private"`
+	Fork     bool   `json:"fork"`
+	Archived bool   `json:"archived"`
+	Language string `json:"language"`
+	License  *struct {
+		Key string `json:"key"`
+	} `json:"license"`
+
+	Stars      float64 `json:"stargazers_count"`
+	Forks      float64 `json:"forks_count"`
+	OpenIssues float64 `json:"open_issues_count"`
+	Watchers   float64 `json:"watchers_count"`
+	Size       float64 `json:"size"`
+}
+
+// workflowRun is an element of the response of
+// /repos/{owner}/{repo}/actions/runs.
+type workflowRun struct {
+	Name         string    `json:"name"`
+	WorkflowID   int64     `json:"workflow_id"`
+	Conclusion   string    `json:"conclusion"`
+	CreatedAt    time.Time `json:"created_at"`
+	RunStartedAt time.Time `json:"run_started_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// rateLimit is the response of /rate_limit.
+type rateLimit struct {
+	Resources struct {
+		Core struct {
+			Limit     float64 `json:"limit"`
+			Remaining float64 `json:"remaining"`
+			Reset     float64 `json:"reset"`
+		} `json:"core"`
+	} `json:"resources"`
+}
+
+type collector struct {
+	log    log.Logger
+	cfg    *Config
+	url    string
+	client *http.Client
+
+	scrapeError *prometheus.Desc
+	repoDescs   struct{ stars, forks, openIssues, watchers, size *prometheus.Desc }
+	runDescs    struct{ success, timestamp, duration *prometheus.Desc }
+	rateDescs   struct{ limit, remaining, reset *prometheus.Desc }
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	col := &collector{
+		log:    l,
+		cfg:    c,
+		url:    strings.TrimSuffix(c.APIURL, "/"),
+		client: &http.Client{},
+
+		scrapeError: prometheus.NewDesc("github_exporter_scrape_error", "Whether requesting the GitHub API failed.", nil, nil),
+	}
+
+	repoLabels := []string{"repo", "user", "private", "fork", "archived", "license", "language"}
+	col.repoDescs.stars = prometheus.NewDesc("github_repo_stars", "Number of stars of the repository.", repoLabels, nil)
+	col.repoDescs.forks = prometheus.NewDesc("github_repo_forks", "Number of forks of the repository.", repoLabels, nil)
+	col.repoDescs.openIssues = prometheus.NewDesc("github_repo_open_issues", "Number of open issues and pull requests of the repository.", repoLabels, nil)
+	col.repoDescs.watchers = prometheus.NewDesc("github_repo_watchers", "Number of watchers of the repository.", repoLabels, nil)
+	col.repoDescs.size = prometheus.NewDesc("github_repo_size_kb", "Size of the repository in KB.", repoLabels, nil)
+
+	runLabels := []string{"repo", "workflow"}
+	col.runDescs.success = prometheus.NewDesc("github_workflow_last_run_success", "Whether the latest completed run of the workflow succeeded.", runLabels, nil)
+	col.runDescs.timestamp = prometheus.NewDesc("github_workflow_last_run_timestamp_seconds", "When the latest completed run of the workflow finished.", runLabels, nil)
+	col.runDescs.duration = prometheus.NewDesc("github_workflow_last_run_duration_seconds", "How long the latest completed run of the workflow took.", runLabels, nil)
+
+	col.rateDescs.limit = prometheus.NewDesc("github_rate_limit", "Number of API requests allowed per hour.", nil, nil)
+	col.rateDescs.remaining = prometheus.NewDesc("github_rate_remaining", "Number of API requests remaining in the current window.", nil, nil)
+	col.rateDescs.reset = prometheus.NewDesc("github_rate_reset", "When the current rate limit window resets, in seconds since the epoch.", nil, nil)
+
+	return col, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.scrapeError,
+		c.repoDescs.stars, c.repoDescs.forks, c.repoDescs.openIssues, c.repoDescs.watchers, c.repoDescs.size,
+		c.runDescs.success, c.runDescs.timestamp, c.runDescs.duration,
+		c.rateDescs.limit, c.rateDescs.remaining, c.rateDescs.reset,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	var (
+		errMut      sync.Mutex
+		scrapeError float64
+	)
+	fail := func(msg string, err error, keyvals ...interface{}) {
+		level.Error(c.log).Log(append([]interface{}{"msg", msg, "err", err}, keyvals...)...)
+		errMut.Lock()
+		scrapeError = 1
+		errMut.Unlock()
+	}
+
+	token, err := c.token()
+	if err != nil {
+		fail("failed to read API token", err)
+		ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, 1)
+		return
+	}
+
+	var rate rateLimit
+	if err := c.get(ctx, token, c.url+"/rate_limit", &rate); err != nil {
+		fail("failed to get rate limit", err)
+	} else {
+		core := rate.Resources.Core
+		ch <- prometheus.MustNewConstMetric(c.rateDescs.limit, prometheus.GaugeValue, core.Limit)
+		ch <- prometheus.MustNewConstMetric(c.rateDescs.remaining, prometheus.GaugeValue, core.Remaining)
+		ch <- prometheus.MustNewConstMetric(c.rateDescs.reset, prometheus.GaugeValue, core.Reset)
+	}
+
+	repos := c.listRepositories(ctx, token, fail)
+	for _, r := range repos {
+		c.collectRepository(r, ch)
+	}
+
+	if c.cfg.Workflows {
+		var (
+			wg  sync.WaitGroup
+			sem = make(chan struct{}, maxConcurrentRequests)
+		)
+		for _, r := range repos {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(r *repository) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				if err := c.collectWorkflows(ctx, token, r, ch); err != nil {
+					fail("failed to get workflow runs", err, "repo", r.FullName)
+				}
+			}(r)
+		}
+		wg.Wait()
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError)
+}
+
+// listRepositories returns the configured repositories and the repositories
+// of the configured organizations and users. Repositories are only returned
+// once.
+func (c *collector) listRepositories(ctx context.Context, token string, fail func(string, error, ...interface{})) []*repository {
+	var (
+		res  []*repository
+		seen = make(map[string]struct{})
+	)
+	add := func(r *repository) {
+		if _, ok := seen[r.FullName]; ok {
+			return
+		}
+		seen[r.FullName] = struct{}{}
+		res = append(res, r)
+	}
+
+	for _, name := range c.cfg.Repositories {
+		var r repository
+		if err := c.get(ctx, token, c.url+"/repos/"+name, &r); err != nil {
+			fail("failed to get repository", err, "repo", name)
+			continue
+		}
+		add(&r)
+	}
+
+	var lists []string
+	for _, org := range c.cfg.Organizations {
+		lists = append(lists, c.url+"/orgs/"+org+"/repos?per_page=100")
+	}
+	for _, user := range c.cfg.Users {
+		lists = append(lists, c.url+"/users/"+user+"/repos?per_page=100")
+	}
+	for _, u := range lists {
+		err := c.getPages(ctx, token, u, func(dec *json.Decoder) error {
+			var page []*repository
+			if err := dec.Decode(&page); err != nil {
+				return err
+			}
+			for _, r := range page {
+				add(r)
+			}
+			return nil
+		})
+		if err != nil {
+			fail("failed to list repositories", err, "url", u)
+		}
+	}
+	return res
+}
+
+func (c *collector) collectRepository(r *repository, ch chan<- prometheus.Metric) {
+	license := ""
+	if r.License != nil {
+		license = r.License.Key
+	}
+	labels := []string{
+		r.Name, r.Owner.Login,
+		strconv.FormatBool(r.Private), strconv.FormatBool(r.Fork), strconv.FormatBool(r.Archived),
+		license, r.Language,
+	}
+
+	d := &c.repoDescs
+	ch <- prometheus.MustNewConstMetric(d.stars, prometheus.GaugeValue, r.Stars, labels...)
+	ch <- prometheus.MustNewConstMetric(d.forks, prometheus.GaugeValue, r.Forks, labels...)
+	ch <- prometheus.MustNewConstMetric(d.openIssues, prometheus.GaugeValue, r.OpenIssues, labels...)
+	ch <- prometheus.MustNewConstMetric(d.watchers, prometheus.GaugeValue, r.Watchers, labels...)
+	ch <- prometheus.MustNewConstMetric(d.size, prometheus.GaugeValue, r.Size, labels...)
+}
+
+// collectWorkflows sends metrics for the latest completed run of each
+// workflow of r. Runs are returned newest first, and only the first page is
+// requested, so workflows which haven't run recently may be missing.
+func (c *collector) collectWorkflows(ctx context.Context, token string, r *repository, ch chan<- prometheus.Metric) error {
+	var resp struct {
+		WorkflowRuns []workflowRun `json:"workflow_runs"`
+	}
+	if err := c.get(ctx, token, c.url+"/repos/"+r.FullName+"/actions/runs?status=completed&per_page=100", &resp); err != nil {
+		return err
+	}
+
+	seen := make(map[int64]struct{})
+	for _, run := range resp.WorkflowRuns {
+		if _, ok := seen[run.WorkflowID]; ok {
+			continue
+		}
+		seen[run.WorkflowID] = struct{}{}
+
+		started := run.RunStartedAt
+		if started.IsZero() {
+			started = run.CreatedAt
+		}
+		var success float64
+		if run.Conclusion == "success" {
+			success = 1
+		}
+
+		labels := []string{r.FullName, run.Name}
+		ch <- prometheus.MustNewConstMetric(c.runDescs.success, prometheus.GaugeValue, success, labels...)
+		ch <- prometheus.MustNewConstMetric(c.runDescs.timestamp, prometheus.GaugeValue, float64(run.UpdatedAt.Unix()), labels...)
+		ch <- prometheus.MustNewConstMetric(c.runDescs.duration, prometheus.GaugeValue, run.UpdatedAt.Sub(started).Seconds(), labels...)
+	}
+	return nil
+}
+
+// token returns the configured API token, reading it from api_token_file if
+// set so that rotated tokens are picked up.
+func (c *collector) token() (string, error) {
+	if c.cfg.APITokenFile == "" {
+		return string(c.cfg.APIToken), nil
+	}
+	bb, err := ioutil.ReadFile(c.cfg.APITokenFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read api token file %s: %w", c.cfg.APITokenFile, err)
+	}
+	return strings.TrimSpace(string(bb)), nil
+}
+
+// get requests url and decodes the JSON response into v.
+func (c *collector) get(ctx context.Context, token, url string, v interface{}) error {
+	_, err := c.do(ctx, token, url, func(dec *json.Decoder) error { return dec.Decode(v) })
+	return err
+}
+
+// getPages requests url and every following page, passing the decoder of
+// each response to decode.
+func (c *collector) getPages(ctx context.Context, token, url string, decode func(*json.Decoder) error) error {
+	for url != "" {
+		next, err := c.do(ctx, token, url, decode)
+		if err != nil {
+			return err
+		}
+		url = next
+	}
+	return nil
+}
+
+// do requests url and passes the decoder of the response to decode. Returns
+// the URL of the next page, if any.
+func (c *collector) do(ctx context.Context, token, url string, decode func(*json.Decoder) error) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return "", fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, req.URL.Path)
+	}
+	if err := decode(json.NewDecoder(resp.Body)); err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", req.URL.Path, err)
+	}
+
+	var next string
+	if m := nextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		next = m[1]
+	}
+	return next, nil
+}
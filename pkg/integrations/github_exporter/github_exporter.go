@@ -0,0 +1,107 @@
+// Package github_exporter implements an integration which collects
+// repository, workflow, and rate limit metrics from the GitHub API, using the
+// metric names of https://github.com/githubexporter/github-exporter.
+package github_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig is the default config for github_exporter.
+var DefaultConfig = Config{
+	APIURL:  "https://api.github.com",
+	Timeout: 30 * time.Second,
+}
+
+// Config controls the github_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// APIURL is the URL of the GitHub API. Set it to
+	// https://<host>/api/v3 for GitHub Enterprise Server.
+	APIURL string `yaml:"api_url,omitempty"`
+
+	// Repositories to collect, in the owner/name format.
+	Repositories []string `yaml:"repositories,omitempty"`
+
+	// Organizations and Users whose repositories are all collected.
+	Organizations []string `yaml:"organizations,omitempty"`
+	Users         []string `yaml:"users,omitempty"`
+
+	// Workflows enables collecting the latest run of each GitHub Actions
+	// workflow of the collected repositories.
+	Workflows bool `yaml:"workflows,omitempty"`
+
+	// APIToken authenticates requests. Unauthenticated requests have a
+	// much lower rate limit.
+	APIToken     config_util.Secret `yaml:"api_token,omitempty"`
+	APITokenFile string             `yaml:"api_token_file,omitempty"`
+
+	// Timeout of collecting all repositories.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.APIURL)
+	if err != nil {
+		return fmt.Errorf("invalid api_url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("api_url has unsupported scheme %q", u.Scheme)
+	}
+	if c.APIToken != "" && c.APITokenFile != "" {
+		return fmt.Errorf("at most one of api_token and api_token_file must be configured")
+	}
+	if len(c.Repositories)+len(c.Organizations)+len(c.Users) == 0 {
+		return fmt.Errorf("github_exporter requires at least one repository, organization, or user")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "github_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new github_exporter integration. The GitHub API is queried
+// each time the integration is scraped, so scrape_interval should be chosen
+// with the rate limit in mind.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
@@ -0,0 +1,117 @@
+package github_exporter //nolint:golint
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+repositories: [grafana/agent]
+api_token_file: /etc/agent/github-token
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, DefaultConfig.APIURL, cfg.APIURL)
+
+	err = yaml.UnmarshalStrict([]byte(`{}`), &cfg)
+	require.EqualError(t, err, "github_exporter requires at least one repository, organization, or user")
+
+	err = yaml.UnmarshalStrict([]byte(`
+repositories: [grafana/agent]
+api_token: secret
+api_token_file: /etc/agent/github-token
+`), &cfg)
+	require.Error(t, err)
+}
+
+func TestCollector(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/rate_limit":
+			_, _ = io.WriteString(rw, `{"resources": {"core": {"limit": 5000, "remaining": 4990, "reset": 1620000000}}}`)
+		case "/repos/grafana/agent":
+			_, _ = io.WriteString(rw, `{"name": "agent", "full_name": "grafana/agent", "owner": {"login": "grafana"},
+				"license": {"key": "apache-2.0"}, "language": "Go",
+				"stargazers_count": 500, "forks_count": 80, "open_issues_count": 60, "watchers_count": 500, "size": 20000}`)
+		case "/orgs/example/repos":
+			if r.URL.Query().Get("page") == "" {
+				rw.Header().Set("Link", `<`+srv.URL+`/orgs/example/repos?per_page=100&page=2>; rel="next"`)
+				_, _ = io.WriteString(rw, `[{"name": "agent", "full_name": "grafana/agent", "owner": {"login": "grafana"}}]`)
+				return
+			}
+			_, _ = io.WriteString(rw, `[{"name": "tools", "full_name": "example/tools", "owner": {"login": "example"}, "archived": true,
+				"stargazers_count": 1, "forks_count": 0, "open_issues_count": 0, "watchers_count": 1, "size": 10}]`)
+		case "/repos/grafana/agent/actions/runs":
+			_, _ = io.WriteString(rw, `{"workflow_runs": [
+				{"name": "CI", "workflow_id": 1, "conclusion": "failure", "run_started_at": "2021-05-01T10:00:00Z", "updated_at": "2021-05-01T10:05:00Z"},
+				{"name": "CI", "workflow_id": 1, "conclusion": "success", "run_started_at": "2021-05-01T09:00:00Z", "updated_at": "2021-05-01T09:04:00Z"},
+				{"name": "Release", "workflow_id": 2, "conclusion": "success", "created_at": "2021-04-30T00:00:00Z", "updated_at": "2021-04-30T00:10:00Z"}
+			]}`)
+		case "/repos/example/tools/actions/runs":
+			_, _ = io.WriteString(rw, `{"workflow_runs": []}`)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600))
+
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+api_url: `+srv.URL+`
+repositories: [grafana/agent]
+organizations: [example]
+workflows: true
+api_token_file: `+tokenFile+`
+`), &cfg)
+	require.NoError(t, err)
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP github_exporter_scrape_error Whether requesting the GitHub API failed.
+# TYPE github_exporter_scrape_error gauge
+github_exporter_scrape_error 0
+# HELP github_rate_limit Number of API requests allowed per hour.
+# TYPE github_rate_limit gauge
+github_rate_limit 5000
+# HELP github_rate_remaining Number of API requests remaining in the current window.
+# TYPE github_rate_remaining gauge
+github_rate_remaining 4990
+# HELP github_repo_stars Number of stars of the repository.
+# TYPE github_repo_stars gauge
+github_repo_stars{archived="false",fork="false",language="Go",license="apache-2.0",private="false",repo="agent",user="grafana"} 500
+github_repo_stars{archived="true",fork="false",language="",license="",private="false",repo="tools",user="example"} 1
+# HELP github_workflow_last_run_duration_seconds How long the latest completed run of the workflow took.
+# TYPE github_workflow_last_run_duration_seconds gauge
+github_workflow_last_run_duration_seconds{repo="grafana/agent",workflow="CI"} 300
+github_workflow_last_run_duration_seconds{repo="grafana/agent",workflow="Release"} 600
+# HELP github_workflow_last_run_success Whether the latest completed run of the workflow succeeded.
+# TYPE github_workflow_last_run_success gauge
+github_workflow_last_run_success{repo="grafana/agent",workflow="CI"} 0
+github_workflow_last_run_success{repo="grafana/agent",workflow="Release"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect),
+		"github_exporter_scrape_error", "github_rate_limit", "github_rate_remaining", "github_repo_stars",
+		"github_workflow_last_run_duration_seconds", "github_workflow_last_run_success"))
+}
@@ -0,0 +1,239 @@
+package gitlab_exporter //nolint:golint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pipelineStatuses are the statuses a pipeline can have. The status metric
+// has a series for each, set to 1 for the current status.
+var pipelineStatuses = []string{
+	"created", "waiting_for_resource", "preparing", "pending", "running",
+	"success", "failed", "canceled", "skipped", "manual", "scheduled",
+}
+
+// project is the response of /projects/:id.
+type project struct {
+	ID            int64   `json:"id"`
+	DefaultBranch string  `json:"default_branch"`
+	Stars         float64 `json:"star_count"`
+	Forks         float64 `json:"forks_count"`
+	OpenIssues    float64 `json:"open_issues_count"`
+}
+
+// pipeline is the response of /projects/:id/pipelines/:pipeline_id.
+type pipeline struct {
+	ID        float64   `json:"id"`
+	Status    string    `json:"status"`
+	Duration  float64   `json:"duration"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type collector struct {
+	log    log.Logger
+	cfg    *Config
+	url    string
+	client *http.Client
+
+	scrapeError  *prometheus.Desc
+	projectDescs struct{ stars, forks, openIssues *prometheus.Desc }
+	pipeDescs    struct{ id, status, duration, timestamp *prometheus.Desc }
+	rateDescs    struct{ limit, remaining *prometheus.Desc }
+
+	// mut protects rate, which is updated from the headers of every
+	// response.
+	mut  sync.Mutex
+	rate struct{ limit, remaining float64 }
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	col := &collector{
+		log:    l,
+		cfg:    c,
+		url:    strings.TrimSuffix(c.URL, "/") + "/api/v4",
+		client: &http.Client{},
+
+		scrapeError: prometheus.NewDesc("gitlab_exporter_scrape_error", "Whether requesting the GitLab API failed.", nil, nil),
+	}
+
+	projectLabels := []string{"project"}
+	col.projectDescs.stars = prometheus.NewDesc("gitlab_project_stars", "Number of stars of the project.", projectLabels, nil)
+	col.projectDescs.forks = prometheus.NewDesc("gitlab_project_forks", "Number of forks of the project.", projectLabels, nil)
+	col.projectDescs.openIssues = prometheus.NewDesc("gitlab_project_open_issues", "Number of open issues of the project.", projectLabels, nil)
+
+	pipeLabels := []string{"project", "ref"}
+	col.pipeDescs.id = prometheus.NewDesc("gitlab_ci_pipeline_id", "ID of the latest pipeline of the ref.", pipeLabels, nil)
+	col.pipeDescs.status = prometheus.NewDesc("gitlab_ci_pipeline_status", "Status of the latest pipeline of the ref.", append(pipeLabels, "status"), nil)
+	col.pipeDescs.duration = prometheus.NewDesc("gitlab_ci_pipeline_duration_seconds", "Duration of the latest pipeline of the ref.", pipeLabels, nil)
+	col.pipeDescs.timestamp = prometheus.NewDesc("gitlab_ci_pipeline_timestamp", "When the latest pipeline of the ref was last updated, in seconds since the epoch.", pipeLabels, nil)
+
+	col.rateDescs.limit = prometheus.NewDesc("gitlab_rate_limit", "Number of API requests allowed per minute.", nil, nil)
+	col.rateDescs.remaining = prometheus.NewDesc("gitlab_rate_remaining", "Number of API requests remaining in the current window.", nil, nil)
+
+	return col, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.scrapeError,
+		c.projectDescs.stars, c.projectDescs.forks, c.projectDescs.openIssues,
+		c.pipeDescs.id, c.pipeDescs.status, c.pipeDescs.duration, c.pipeDescs.timestamp,
+		c.rateDescs.limit, c.rateDescs.remaining,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	var scrapeError float64
+	fail := func(msg string, err error, keyvals ...interface{}) {
+		level.Error(c.log).Log(append([]interface{}{"msg", msg, "err", err}, keyvals...)...)
+		scrapeError = 1
+	}
+
+	token, err := c.token()
+	if err != nil {
+		fail("failed to read token", err)
+		ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, 1)
+		return
+	}
+
+	for _, p := range c.cfg.Projects {
+		if err := c.collectProject(ctx, token, p, ch); err != nil {
+			fail("failed to collect project", err, "project", p.Name)
+		}
+	}
+
+	// GitLab only sends rate limit headers when rate limiting is enabled.
+	c.mut.Lock()
+	if c.rate.limit > 0 {
+		ch <- prometheus.MustNewConstMetric(c.rateDescs.limit, prometheus.GaugeValue, c.rate.limit)
+		ch <- prometheus.MustNewConstMetric(c.rateDescs.remaining, prometheus.GaugeValue, c.rate.remaining)
+	}
+	c.mut.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError)
+}
+
+func (c *collector) collectProject(ctx context.Context, token string, p Project, ch chan<- prometheus.Metric) error {
+	var proj project
+	if err := c.get(ctx, token, "/projects/"+url.PathEscape(p.Name), &proj); err != nil {
+		return err
+	}
+
+	d := &c.projectDescs
+	ch <- prometheus.MustNewConstMetric(d.stars, prometheus.GaugeValue, proj.Stars, p.Name)
+	ch <- prometheus.MustNewConstMetric(d.forks, prometheus.GaugeValue, proj.Forks, p.Name)
+	ch <- prometheus.MustNewConstMetric(d.openIssues, prometheus.GaugeValue, proj.OpenIssues, p.Name)
+
+	refs := p.Refs
+	if len(refs) == 0 && proj.DefaultBranch != "" {
+		refs = []string{proj.DefaultBranch}
+	}
+	for _, ref := range refs {
+		// The list of pipelines doesn't include their duration, so the
+		// latest pipeline is requested separately.
+		var latest []struct {
+			ID int64 `json:"id"`
+		}
+		path := fmt.Sprintf("/projects/%d/pipelines?per_page=1&ref=%s", proj.ID, url.QueryEscape(ref))
+		if err := c.get(ctx, token, path, &latest); err != nil {
+			return err
+		}
+		if len(latest) == 0 {
+			continue
+		}
+
+		var pipe pipeline
+		if err := c.get(ctx, token, fmt.Sprintf("/projects/%d/pipelines/%d", proj.ID, latest[0].ID), &pipe); err != nil {
+			return err
+		}
+
+		d := &c.pipeDescs
+		ch <- prometheus.MustNewConstMetric(d.id, prometheus.GaugeValue, pipe.ID, p.Name, ref)
+		ch <- prometheus.MustNewConstMetric(d.duration, prometheus.GaugeValue, pipe.Duration, p.Name, ref)
+		ch <- prometheus.MustNewConstMetric(d.timestamp, prometheus.GaugeValue, float64(pipe.UpdatedAt.Unix()), p.Name, ref)
+		for _, status := range pipelineStatuses {
+			var v float64
+			if status == pipe.Status {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(d.status, prometheus.GaugeValue, v, p.Name, ref, status)
+		}
+	}
+	return nil
+}
+
+// token returns the configured token, reading it from token_file if set so
+// that rotated tokens are picked up.
+func (c *collector) token() (string, error) {
+	if c.cfg.TokenFile == "" {
+		return string(c.cfg.Token), nil
+	}
+	bb, err := ioutil.ReadFile(c.cfg.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token file %s: %w", c.cfg.TokenFile, err)
+	}
+	return strings.TrimSpace(string(bb)), nil
+}
+
+// get requests path from the GitLab API and decodes the JSON response into
+// v.
+func (c *collector) get(ctx context.Context, token, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("PRIVATE-TOKEN", token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.updateRateLimit(resp.Header)
+
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, req.URL.Path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+func (c *collector) updateRateLimit(h http.Header) {
+	limit, err := strconv.ParseFloat(h.Get("RateLimit-Limit"), 64)
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.ParseFloat(h.Get("RateLimit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.rate.limit, c.rate.remaining = limit, remaining
+}
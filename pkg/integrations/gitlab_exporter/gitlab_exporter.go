@@ -0,0 +1,112 @@
+// Package gitlab_exporter implements an integration which collects project
+// and CI pipeline metrics from the GitLab API, using the pipeline metric
+// names of https://github.com/mvisonneau/gitlab-ci-pipelines-exporter.
+package gitlab_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig is the default config for gitlab_exporter.
+var DefaultConfig = Config{
+	URL:     "https://gitlab.com",
+	Timeout: 30 * time.Second,
+}
+
+// Config controls the gitlab_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// URL of the GitLab instance.
+	URL string `yaml:"url,omitempty"`
+
+	// Token is a personal, group, or project access token with the
+	// read_api scope, sent as the PRIVATE-TOKEN header.
+	Token     config_util.Secret `yaml:"token,omitempty"`
+	TokenFile string             `yaml:"token_file,omitempty"`
+
+	// Projects to collect.
+	Projects []Project `yaml:"projects"`
+
+	// Timeout of collecting all projects.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Project is a GitLab project to collect.
+type Project struct {
+	// Name is the full path of the project, such as group/project.
+	Name string `yaml:"name"`
+
+	// Refs whose latest pipeline is collected. Defaults to the default
+	// branch of the project.
+	Refs []string `yaml:"refs,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url has unsupported scheme %q", u.Scheme)
+	}
+	if c.Token != "" && c.TokenFile != "" {
+		return fmt.Errorf("at most one of token and token_file must be configured")
+	}
+	if len(c.Projects) == 0 {
+		return fmt.Errorf("gitlab_exporter requires at least one project")
+	}
+	for _, p := range c.Projects {
+		if p.Name == "" {
+			return fmt.Errorf("gitlab_exporter project requires a name")
+		}
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "gitlab_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new gitlab_exporter integration. The GitLab API is queried
+// each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
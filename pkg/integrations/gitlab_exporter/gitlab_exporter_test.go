@@ -0,0 +1,103 @@
+package gitlab_exporter //nolint:golint
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+token_file: /etc/agent/gitlab-token
+projects:
+- name: group/project
+  refs: [main, release]
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, DefaultConfig.URL, cfg.URL)
+
+	err = yaml.UnmarshalStrict([]byte(`token: secret`), &cfg)
+	require.EqualError(t, err, "gitlab_exporter requires at least one project")
+}
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rw.Header().Set("RateLimit-Limit", "600")
+		rw.Header().Set("RateLimit-Remaining", "598")
+
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/group%2Fproject":
+			_, _ = io.WriteString(rw, `{"id": 42, "default_branch": "main", "star_count": 12, "forks_count": 3, "open_issues_count": 7}`)
+		case "/api/v4/projects/42/pipelines":
+			if r.URL.Query().Get("ref") != "main" {
+				_, _ = io.WriteString(rw, `[]`)
+				return
+			}
+			_, _ = io.WriteString(rw, `[{"id": 1001}]`)
+		case "/api/v4/projects/42/pipelines/1001":
+			_, _ = io.WriteString(rw, `{"id": 1001, "status": "failed", "duration": 310, "updated_at": "2021-05-01T10:00:00Z"}`)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+url: `+srv.URL+`
+token: secret
+projects:
+- name: group/project
+`), &cfg)
+	require.NoError(t, err)
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP gitlab_ci_pipeline_duration_seconds Duration of the latest pipeline of the ref.
+# TYPE gitlab_ci_pipeline_duration_seconds gauge
+gitlab_ci_pipeline_duration_seconds{project="group/project",ref="main"} 310
+# HELP gitlab_ci_pipeline_id ID of the latest pipeline of the ref.
+# TYPE gitlab_ci_pipeline_id gauge
+gitlab_ci_pipeline_id{project="group/project",ref="main"} 1001
+# HELP gitlab_exporter_scrape_error Whether requesting the GitLab API failed.
+# TYPE gitlab_exporter_scrape_error gauge
+gitlab_exporter_scrape_error 0
+# HELP gitlab_project_stars Number of stars of the project.
+# TYPE gitlab_project_stars gauge
+gitlab_project_stars{project="group/project"} 12
+# HELP gitlab_rate_remaining Number of API requests remaining in the current window.
+# TYPE gitlab_rate_remaining gauge
+gitlab_rate_remaining 598
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect),
+		"gitlab_ci_pipeline_duration_seconds", "gitlab_ci_pipeline_id", "gitlab_exporter_scrape_error",
+		"gitlab_project_stars", "gitlab_rate_remaining"))
+
+	// Every status has a series, set to 1 for the current status.
+	statuses := `
+# HELP gitlab_ci_pipeline_status Status of the latest pipeline of the ref.
+# TYPE gitlab_ci_pipeline_status gauge
+`
+	for _, status := range pipelineStatuses {
+		v := "0"
+		if status == "failed" {
+			v = "1"
+		}
+		statuses += `gitlab_ci_pipeline_status{project="group/project",ref="main",status="` + status + `"} ` + v + "\n"
+	}
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(statuses), "gitlab_ci_pipeline_status"))
+}
@@ -0,0 +1,308 @@
+package haproxy_exporter //nolint:golint
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+const namespace = "haproxy"
+
+// Values of the type field of a statistics row.
+const (
+	typeFrontend = "0"
+	typeBackend  = "1"
+	typeServer   = "2"
+)
+
+// field is a numeric field of the statistics which is exposed as a metric.
+type field struct {
+	name      string
+	metric    string
+	help      string
+	valueType prometheus.ValueType
+}
+
+var (
+	frontendFields = []field{
+		{"scur", "current_sessions", "Current number of active sessions.", prometheus.GaugeValue},
+		{"smax", "max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue},
+		{"slim", "limit_sessions", "Configured session limit.", prometheus.GaugeValue},
+		{"stot", "sessions_total", "Total number of sessions.", prometheus.CounterValue},
+		{"bin", "bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue},
+		{"bout", "bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue},
+		{"dreq", "requests_denied_total", "Total of requests denied for security.", prometheus.CounterValue},
+		{"ereq", "request_errors_total", "Total of request errors.", prometheus.CounterValue},
+		{"req_tot", "http_requests_total", "Total HTTP requests.", prometheus.CounterValue},
+	}
+	backendFields = []field{
+		{"qcur", "current_queue", "Current number of queued requests not assigned to any server.", prometheus.GaugeValue},
+		{"scur", "current_sessions", "Current number of active sessions.", prometheus.GaugeValue},
+		{"smax", "max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue},
+		{"slim", "limit_sessions", "Configured session limit.", prometheus.GaugeValue},
+		{"stot", "sessions_total", "Total number of sessions.", prometheus.CounterValue},
+		{"bin", "bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue},
+		{"bout", "bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue},
+		{"econ", "connection_errors_total", "Total of connection errors.", prometheus.CounterValue},
+		{"eresp", "response_errors_total", "Total of response errors.", prometheus.CounterValue},
+		{"weight", "weight", "Total weight of the servers in the backend.", prometheus.GaugeValue},
+	}
+	serverFields = []field{
+		{"qcur", "current_queue", "Current number of queued requests assigned to this server.", prometheus.GaugeValue},
+		{"scur", "current_sessions", "Current number of active sessions.", prometheus.GaugeValue},
+		{"smax", "max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue},
+		{"slim", "limit_sessions", "Configured session limit.", prometheus.GaugeValue},
+		{"stot", "sessions_total", "Total number of sessions.", prometheus.CounterValue},
+		{"bin", "bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue},
+		{"bout", "bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue},
+		{"econ", "connection_errors_total", "Total of connection errors.", prometheus.CounterValue},
+		{"eresp", "response_errors_total", "Total of response errors.", prometheus.CounterValue},
+		{"weight", "weight", "Current weight of the server.", prometheus.GaugeValue},
+	}
+
+	// responseCodes are the fields holding the number of HTTP responses by
+	// status code class.
+	responseCodes = map[string]string{
+		"hrsp_1xx":   "1xx",
+		"hrsp_2xx":   "2xx",
+		"hrsp_3xx":   "3xx",
+		"hrsp_4xx":   "4xx",
+		"hrsp_5xx":   "5xx",
+		"hrsp_other": "other",
+	}
+)
+
+// proxyType describes the metrics of one type of statistics row.
+type proxyType struct {
+	fields    []field
+	descs     []*prometheus.Desc
+	upDesc    *prometheus.Desc
+	respDesc  *prometheus.Desc
+	numLabels int
+}
+
+func newProxyType(subsystem string, labels []string, fields []field, withUp bool) *proxyType {
+	t := &proxyType{fields: fields, numLabels: len(labels)}
+	for _, f := range fields {
+		t.descs = append(t.descs, prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, f.metric), f.help, labels, nil))
+	}
+	if withUp {
+		t.upDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "up"), "Current health status of the "+subsystem+" (1 = UP, 0 = DOWN).", labels, nil)
+	}
+	t.respDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "http_responses_total"), "Total of HTTP responses.", append(labels, "code"), nil)
+	return t
+}
+
+type collector struct {
+	log     log.Logger
+	uri     *url.URL
+	client  *http.Client
+	timeout time.Duration
+
+	up        *prometheus.Desc
+	frontends *proxyType
+	backends  *proxyType
+	servers   *proxyType
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	u, err := url.Parse(c.ScrapeURI)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := config_util.NewTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &collector{
+		log:     l,
+		uri:     u,
+		timeout: c.Timeout,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+
+		up:        prometheus.NewDesc("haproxy_up", "Was the last scrape of HAProxy successful.", nil, nil),
+		frontends: newProxyType("frontend", []string{"frontend"}, frontendFields, false),
+		backends:  newProxyType("backend", []string{"backend"}, backendFields, true),
+		servers:   newProxyType("server", []string{"backend", "server"}, serverFields, true),
+	}, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	for _, t := range []*proxyType{c.frontends, c.backends, c.servers} {
+		for _, d := range t.descs {
+			ch <- d
+		}
+		if t.upDesc != nil {
+			ch <- t.upDesc
+		}
+		ch <- t.respDesc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	up := 1.0
+	if err := c.scrape(ctx, ch); err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape HAProxy", "err", err)
+		up = 0
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up)
+}
+
+func (c *collector) scrape(ctx context.Context, ch chan<- prometheus.Metric) error {
+	body, err := c.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	r := csv.NewReader(body)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimPrefix(name, "# ")] = i
+	}
+	for _, required := range []string{"pxname", "svname", "type"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("statistics are missing the %s column", required)
+		}
+	}
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		c.collectRow(ch, columns, row)
+	}
+}
+
+// fetch returns the CSV statistics, either from the stats socket or the
+// stats page.
+func (c *collector) fetch(ctx context.Context) (io.ReadCloser, error) {
+	if c.uri.Scheme == "unix" {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "unix", c.uri.Path)
+		if err != nil {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+		if _, err := io.WriteString(conn, "show stat\n"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+func (c *collector) collectRow(ch chan<- prometheus.Metric, columns map[string]int, row []string) {
+	get := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var (
+		t      *proxyType
+		labels []string
+	)
+	switch get("type") {
+	case typeFrontend:
+		t, labels = c.frontends, []string{get("pxname")}
+	case typeBackend:
+		t, labels = c.backends, []string{get("pxname")}
+	case typeServer:
+		t, labels = c.servers, []string{get("pxname"), get("svname")}
+	default:
+		return
+	}
+
+	for i, f := range t.fields {
+		if v, ok := parseValue(get(f.name)); ok {
+			ch <- prometheus.MustNewConstMetric(t.descs[i], f.valueType, v, labels...)
+		}
+	}
+	if t.upDesc != nil {
+		if status := get("status"); status != "" {
+			ch <- prometheus.MustNewConstMetric(t.upDesc, prometheus.GaugeValue, parseStatus(status), labels...)
+		}
+	}
+	for name, code := range responseCodes {
+		if v, ok := parseValue(get(name)); ok {
+			ch <- prometheus.MustNewConstMetric(t.respDesc, prometheus.CounterValue, v, append(labels, code)...)
+		}
+	}
+}
+
+// parseValue parses a numeric field. Fields which don't apply to a row are
+// left empty.
+func parseValue(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// parseStatus converts a status to 1 if the proxy is up. Servers in a
+// transition, such as "UP 1/3", count as up, as do servers without health
+// checks.
+func parseStatus(s string) float64 {
+	switch {
+	case s == "UP", strings.HasPrefix(s, "UP "), s == "OPEN", s == "no check":
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,136 @@
+package haproxy_exporter //nolint:golint
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const stats = `# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot
+http,FRONTEND,,,2,10,2000,120,4000,8000,1,0,3,,,,,OPEN,,,,,,,,,1,2,0,,,,0,1,0,5,,,,0,100,5,10,5,0,,1,5,120
+app,web1,0,1,1,4,,60,2000,4000,,0,,0,1,0,0,UP,1,1,0,0,0,100,0,,1,3,1,,60,,2,0,,3,L4OK,,1,0,55,2,3,0,0,0,,,,
+app,web2,0,0,0,0,,0,0,0,,0,,2,0,0,0,DOWN,1,1,0,1,1,5,5,,1,3,2,,0,,2,0,,0,L4CON,,0,0,0,0,0,0,0,0,,,,
+app,BACKEND,0,1,1,4,200,60,2000,4000,0,0,,2,1,0,0,UP,2,2,0,,0,100,0,,1,3,0,,60,,1,0,,3,,,,0,55,2,3,0,0,,,,,
+`
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`scrape_uri: unix:/run/haproxy/admin.sock`), &cfg))
+	require.Equal(t, DefaultConfig.Timeout, cfg.Timeout)
+
+	err := yaml.UnmarshalStrict([]byte(`scrape_uri: ftp://haproxy`), &cfg)
+	require.EqualError(t, err, `scrape_uri has unsupported scheme "ftp"`)
+}
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(rw, stats)
+	}))
+	defer srv.Close()
+
+	expect := `
+# HELP haproxy_backend_up Current health status of the backend (1 = UP, 0 = DOWN).
+# TYPE haproxy_backend_up gauge
+haproxy_backend_up{backend="app"} 1
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{frontend="http"} 2
+# HELP haproxy_frontend_http_responses_total Total of HTTP responses.
+# TYPE haproxy_frontend_http_responses_total counter
+haproxy_frontend_http_responses_total{code="1xx",frontend="http"} 0
+haproxy_frontend_http_responses_total{code="2xx",frontend="http"} 100
+haproxy_frontend_http_responses_total{code="3xx",frontend="http"} 5
+haproxy_frontend_http_responses_total{code="4xx",frontend="http"} 10
+haproxy_frontend_http_responses_total{code="5xx",frontend="http"} 5
+haproxy_frontend_http_responses_total{code="other",frontend="http"} 0
+# HELP haproxy_server_connection_errors_total Total of connection errors.
+# TYPE haproxy_server_connection_errors_total counter
+haproxy_server_connection_errors_total{backend="app",server="web1"} 0
+haproxy_server_connection_errors_total{backend="app",server="web2"} 2
+# HELP haproxy_server_up Current health status of the server (1 = UP, 0 = DOWN).
+# TYPE haproxy_server_up gauge
+haproxy_server_up{backend="app",server="web1"} 1
+haproxy_server_up{backend="app",server="web2"} 0
+# HELP haproxy_up Was the last scrape of HAProxy successful.
+# TYPE haproxy_up gauge
+haproxy_up 1
+`
+	names := []string{
+		"haproxy_up",
+		"haproxy_backend_up",
+		"haproxy_frontend_current_sessions",
+		"haproxy_frontend_http_responses_total",
+		"haproxy_server_connection_errors_total",
+		"haproxy_server_up",
+	}
+
+	t.Run("http", func(t *testing.T) {
+		reg := newRegistry(t, "scrape_uri: "+srv.URL+"/;csv")
+		require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), names...))
+	})
+
+	t.Run("socket", func(t *testing.T) {
+		dir, err := ioutil.TempDir(os.TempDir(), "haproxy")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "admin.sock")
+		lis, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		defer lis.Close()
+		go func() {
+			for {
+				conn, err := lis.Accept()
+				if err != nil {
+					return
+				}
+				buf := make([]byte, len("show stat\n"))
+				if _, err := io.ReadFull(conn, buf); err == nil && string(buf) == "show stat\n" {
+					_, _ = io.WriteString(conn, stats)
+				}
+				conn.Close()
+			}
+		}()
+
+		reg := newRegistry(t, "scrape_uri: unix:"+path)
+		require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), names...))
+	})
+
+	t.Run("down", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		defer srv.Close()
+
+		reg := newRegistry(t, "scrape_uri: "+srv.URL+"/;csv")
+		require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP haproxy_up Was the last scrape of HAProxy successful.
+# TYPE haproxy_up gauge
+haproxy_up 0
+`), "haproxy_up"))
+	})
+}
+
+func newRegistry(t *testing.T, cfgText string) *prometheus.Registry {
+	t.Helper()
+
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfgText), &cfg))
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(col))
+	return reg
+}
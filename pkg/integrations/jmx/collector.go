@@ -0,0 +1,158 @@
+package jmx
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+// targetLabel is added to every metric with the name of its target.
+const targetLabel = "jmx_target"
+
+type target struct {
+	name   string
+	url    string
+	mbeans []string
+	client *http.Client
+	rules  *rulesFile
+}
+
+type collector struct {
+	log     log.Logger
+	targets []*target
+	timeout time.Duration
+
+	scrapeError *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	col := &collector{
+		log:     l,
+		timeout: c.Timeout,
+
+		scrapeError: prometheus.NewDesc("jmx_scrape_error", "Was reading the MBeans of the target unsuccessful.", []string{targetLabel}, nil),
+	}
+
+	for _, t := range c.Targets {
+		client, err := config_util.NewClientFromConfig(t.HTTPClientConfig, "jmx")
+		if err != nil {
+			return nil, err
+		}
+		rules, err := loadRules(t.RulesFile)
+		if err != nil {
+			return nil, err
+		}
+		col.targets = append(col.targets, &target{
+			name:   t.Name,
+			url:    t.URL,
+			mbeans: t.MBeans,
+			client: client,
+			rules:  rules,
+		})
+	}
+	return col, nil
+}
+
+// Describe implements prometheus.Collector. The metrics depend on the MBeans
+// of the targets, so the collector is unchecked.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	// Targets are read concurrently, but their samples are processed in
+	// order so the same help text wins on every scrape.
+	results := make([][]sample, len(c.targets))
+	errs := make([]error, len(c.targets))
+
+	var wg sync.WaitGroup
+	for i, t := range c.targets {
+		wg.Add(1)
+		go func(i int, t *target) {
+			defer wg.Done()
+			results[i], errs[i] = t.read(ctx)
+		}(i, t)
+	}
+	wg.Wait()
+
+	var (
+		// Metrics with the same name must have the same help and type.
+		families = make(map[string]sample)
+		seen     = make(map[string]struct{})
+	)
+	for i, t := range c.targets {
+		if errs[i] != nil {
+			level.Error(c.log).Log("msg", "failed to read MBeans", "target", t.name, "err", errs[i])
+			ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, 1, t.name)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, 0, t.name)
+
+		for _, s := range results[i] {
+			s.labels[targetLabel] = t.name
+			if f, ok := families[s.name]; ok {
+				s.help, s.valueType = f.help, f.valueType
+			} else {
+				families[s.name] = s
+			}
+
+			names := make([]string, 0, len(s.labels))
+			for k := range s.labels {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			values := make([]string, 0, len(names))
+			for _, k := range names {
+				values = append(values, s.labels[k])
+			}
+
+			// Like the JMX exporter, only the first of several attributes
+			// converted to the same series is kept.
+			key := s.name + "\xff" + strings.Join(names, "\xff") + "\xff" + strings.Join(values, "\xff")
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			desc := prometheus.NewDesc(s.name, s.help, names, nil)
+			m, err := prometheus.NewConstMetric(desc, s.valueType, s.value, values...)
+			if err != nil {
+				level.Debug(c.log).Log("msg", "dropping invalid metric", "target", t.name, "name", s.name, "err", err)
+				continue
+			}
+			ch <- m
+		}
+	}
+}
+
+// read reads the MBeans of t and converts them to samples.
+func (t *target) read(ctx context.Context) ([]sample, error) {
+	attrs, err := readMBeans(ctx, t.client, t.url, t.mbeans)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort attributes so the first of several attributes converted to the
+	// same series is stable across scrapes.
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].path(attrs[i].name) < attrs[j].path(attrs[j].name)
+	})
+
+	samples := make([]sample, 0, len(attrs))
+	for i := range attrs {
+		if s, ok := t.rules.convert(&attrs[i]); ok {
+			samples = append(samples, s)
+		}
+	}
+	return samples, nil
+}
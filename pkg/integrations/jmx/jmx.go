@@ -0,0 +1,134 @@
+// Package jmx implements an integration which reads MBeans from Jolokia
+// agents and converts them to metrics with the rules of
+// https://github.com/prometheus/jmx_exporter.
+package jmx
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig is the default config for jmx.
+var DefaultConfig = Config{
+	Timeout: 10 * time.Second,
+}
+
+// DefaultTarget is the default config for a Target.
+var DefaultTarget = Target{
+	HTTPClientConfig: config_util.DefaultHTTPClientConfig,
+	MBeans:           []string{"*:*"},
+}
+
+// Config controls the jmx integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// Targets are the Jolokia agents to read MBeans from.
+	Targets []Target `yaml:"targets"`
+
+	// Timeout of reading MBeans from a target.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Target is a Jolokia agent to read MBeans from.
+type Target struct {
+	// Name of the target, added as the jmx_target label to its metrics.
+	Name string `yaml:"name"`
+
+	// URL of the Jolokia agent, such as http://localhost:8778/jolokia.
+	URL string `yaml:"url"`
+
+	// HTTPClientConfig holds the credentials and TLS settings used to
+	// request the Jolokia agent.
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:",inline"`
+
+	// MBeans are the object names to read, which may be patterns.
+	MBeans []string `yaml:"mbeans,omitempty"`
+
+	// RulesFile is a JMX exporter config file whose rules convert MBean
+	// attributes to metrics. When empty, every attribute is converted with
+	// the default format of the JMX exporter.
+	RulesFile string `yaml:"rules_file,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Target.
+func (t *Target) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*t = DefaultTarget
+
+	type plain Target
+	if err := unmarshal((*plain)(t)); err != nil {
+		return err
+	}
+
+	if t.Name == "" {
+		return fmt.Errorf("jmx target requires a name")
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url for jmx target %s: %w", t.Name, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url of jmx target %s has unsupported scheme %q", t.Name, u.Scheme)
+	}
+	if len(t.MBeans) == 0 {
+		return fmt.Errorf("jmx target %s requires at least one mbean", t.Name)
+	}
+	return t.HTTPClientConfig.Validate()
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	names := make(map[string]struct{}, len(c.Targets))
+	for _, t := range c.Targets {
+		if _, ok := names[t.Name]; ok {
+			return fmt.Errorf("found multiple jmx targets named %s", t.Name)
+		}
+		names[t.Name] = struct{}{}
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "jmx"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new jmx integration. Rules files are loaded once when the
+// integration is created; MBeans are read each time it's scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
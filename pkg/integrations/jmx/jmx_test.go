@@ -0,0 +1,169 @@
+package jmx
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+targets:
+- name: kafka
+  url: http://kafka:8778/jolokia
+  basic_auth:
+    username: monitoring
+    password: secret
+  rules_file: /etc/agent/kafka.yml
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"*:*"}, cfg.Targets[0].MBeans)
+
+	err = yaml.UnmarshalStrict([]byte(`
+targets:
+- {name: kafka, url: http://kafka-1:8778/jolokia}
+- {name: kafka, url: http://kafka-2:8778/jolokia}
+`), &cfg)
+	require.EqualError(t, err, "found multiple jmx targets named kafka")
+
+	err = yaml.UnmarshalStrict([]byte(`
+targets:
+- url: http://kafka:8778/jolokia
+`), &cfg)
+	require.EqualError(t, err, "jmx target requires a name")
+}
+
+func TestParseObjectName(t *testing.T) {
+	domain, props, err := parseObjectName(`kafka.server:type=BrokerTopicMetrics,name="Bytes,In",topic=orders`)
+	require.NoError(t, err)
+	require.Equal(t, "kafka.server", domain)
+	require.Equal(t, [][2]string{{"type", "BrokerTopicMetrics"}, {"name", `"Bytes,In"`}, {"topic", "orders"}}, props)
+
+	_, _, err = parseObjectName("kafka.server")
+	require.Error(t, err)
+}
+
+func TestRules(t *testing.T) {
+	rf := writeRules(t, `
+lowercaseOutputName: true
+rules:
+- pattern: 'kafka.server<type=(.+), name=(.+)PerSec, topic=(.+)><>Count'
+  name: kafka_server_$1_$2_total
+  type: COUNTER
+  labels:
+    topic: $3
+- pattern: 'java.lang<type=Memory><HeapMemoryUsage>(\w+)'
+  name: jvm_memory_heap_$1_bytes
+  help: Heap memory $1.
+  type: GAUGE
+- pattern: 'java.lang<type=Threading><>ThreadCount'
+`)
+
+	heap := attribute{domain: "java.lang", props: [][2]string{{"type", "Memory"}}, keys: []string{"HeapMemoryUsage"}, name: "used", value: 1024}
+	s, ok := rf.convert(&heap)
+	require.True(t, ok)
+	require.Equal(t, "jvm_memory_heap_used_bytes", s.name)
+	require.Equal(t, "Heap memory used.", s.help)
+
+	bytesIn := attribute{
+		domain: "kafka.server",
+		props:  [][2]string{{"type", "BrokerTopicMetrics"}, {"name", "BytesInPerSec"}, {"topic", "orders"}},
+		name:   "Count",
+		value:  42,
+	}
+	s, ok = rf.convert(&bytesIn)
+	require.True(t, ok)
+	require.Equal(t, "kafka_server_brokertopicmetrics_bytesin_total", s.name)
+	require.Equal(t, map[string]string{"topic": "orders"}, s.labels)
+
+	// Rules without a name use the default format.
+	threads := attribute{domain: "java.lang", props: [][2]string{{"type", "Threading"}}, name: "ThreadCount", value: 12}
+	s, ok = rf.convert(&threads)
+	require.True(t, ok)
+	require.Equal(t, "java_lang_threading_threadcount", s.name)
+
+	// Attributes without a matching rule are dropped.
+	_, ok = rf.convert(&attribute{domain: "java.lang", props: [][2]string{{"type", "OperatingSystem"}}, name: "ProcessCpuLoad"})
+	require.False(t, ok)
+}
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var reqs []readRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil || len(reqs) != 2 {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(rw, `[
+			{"status": 200, "value": {"HeapMemoryUsage": {"init": 256, "used": 1024, "committed": 2048, "max": 4096}, "ObjectPendingFinalizationCount": 0, "Verbose": false}},
+			{"status": 200, "value": {
+				"kafka.server:type=BrokerTopicMetrics,name=BytesInPerSec,topic=orders": {"Count": 42, "RateUnit": "SECONDS"},
+				"kafka.server:type=BrokerTopicMetrics,name=BytesInPerSec,topic=payments": {"Count": 7, "RateUnit": "SECONDS"}
+			}}
+		]`)
+	}))
+	defer srv.Close()
+
+	rulesFile := filepath.Join(t.TempDir(), "rules.yml")
+	require.NoError(t, ioutil.WriteFile(rulesFile, []byte(`
+rules:
+- pattern: 'kafka.server<type=(.+), name=(.+)PerSec, topic=(.+)><>Count'
+  name: kafka_server_$1_$2_total
+  help: Kafka $2 per topic.
+  type: COUNTER
+  labels:
+    topic: $3
+- pattern: 'java.lang<type=Memory><HeapMemoryUsage>used'
+`), 0600))
+
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+targets:
+- name: kafka
+  url: `+srv.URL+`
+  mbeans: [java.lang:type=Memory, 'kafka.server:type=BrokerTopicMetrics,*']
+  rules_file: `+rulesFile+`
+- name: down
+  url: http://127.0.0.1:0/jolokia
+`), &cfg)
+	require.NoError(t, err)
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP java_lang_Memory_HeapMemoryUsage_used Attribute exposed for management java.lang<type=Memory><HeapMemoryUsage>used
+# TYPE java_lang_Memory_HeapMemoryUsage_used untyped
+java_lang_Memory_HeapMemoryUsage_used{jmx_target="kafka"} 1024
+# HELP jmx_scrape_error Was reading the MBeans of the target unsuccessful.
+# TYPE jmx_scrape_error gauge
+jmx_scrape_error{jmx_target="down"} 1
+jmx_scrape_error{jmx_target="kafka"} 0
+# HELP kafka_server_BrokerTopicMetrics_BytesIn_total Kafka BytesIn per topic.
+# TYPE kafka_server_BrokerTopicMetrics_BytesIn_total counter
+kafka_server_BrokerTopicMetrics_BytesIn_total{jmx_target="kafka",topic="orders"} 42
+kafka_server_BrokerTopicMetrics_BytesIn_total{jmx_target="kafka",topic="payments"} 7
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func writeRules(t *testing.T, contents string) *rulesFile {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	rf, err := loadRules(path)
+	require.NoError(t, err)
+	return rf
+}
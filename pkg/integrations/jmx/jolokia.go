@@ -0,0 +1,170 @@
+package jmx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// readRequest is a Jolokia read request.
+type readRequest struct {
+	Type   string            `json:"type"`
+	MBean  string            `json:"mbean"`
+	Config map[string]string `json:"config"`
+}
+
+// readResponse is an element of the response to a bulk Jolokia request.
+type readResponse struct {
+	Status int             `json:"status"`
+	Error  string          `json:"error"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// readRequestConfig skips attributes which can't be read instead of failing
+// the whole request, and keeps properties of object names in the order they
+// were registered, which is the order the JMX exporter uses.
+var readRequestConfig = map[string]string{
+	"ignoreErrors":    "true",
+	"canonicalNaming": "false",
+}
+
+// readMBeans reads the attributes of mbeans from the Jolokia agent at url.
+// Numeric and boolean leaves of the attributes are returned.
+func readMBeans(ctx context.Context, client *http.Client, url string, mbeans []string) ([]attribute, error) {
+	reqs := make([]readRequest, 0, len(mbeans))
+	for _, mbean := range mbeans {
+		reqs = append(reqs, readRequest{Type: "read", MBean: mbean, Config: readRequestConfig})
+	}
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	var resps []readResponse
+	if err := json.NewDecoder(resp.Body).Decode(&resps); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(resps) != len(reqs) {
+		return nil, fmt.Errorf("expected %d responses, got %d", len(reqs), len(resps))
+	}
+
+	var attrs []attribute
+	for i, r := range resps {
+		mbean := reqs[i].MBean
+		if r.Status != http.StatusOK {
+			return nil, fmt.Errorf("failed to read %s: %s", mbean, r.Error)
+		}
+
+		// Patterns return the attributes of each matching MBean keyed by its
+		// name, while other requests return the attributes directly.
+		values := make(map[string]map[string]interface{})
+		if isPattern(mbean) {
+			err = json.Unmarshal(r.Value, &values)
+		} else {
+			var v map[string]interface{}
+			err = json.Unmarshal(r.Value, &v)
+			values[mbean] = v
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode value of %s: %w", mbean, err)
+		}
+
+		for name, mbeanAttrs := range values {
+			domain, props, err := parseObjectName(name)
+			if err != nil {
+				return nil, err
+			}
+			for attrName, v := range mbeanAttrs {
+				attrs = appendLeaves(attrs, attribute{domain: domain, props: props, name: attrName}, v)
+			}
+		}
+	}
+	return attrs, nil
+}
+
+// appendLeaves appends the numeric leaves of v to attrs. Composite values
+// add their keys to the path of the leaves.
+func appendLeaves(attrs []attribute, a attribute, v interface{}) []attribute {
+	switch v := v.(type) {
+	case float64:
+		a.value = v
+		return append(attrs, a)
+	case bool:
+		if v {
+			a.value = 1
+		}
+		return append(attrs, a)
+	case map[string]interface{}:
+		keys := append(a.keys[:len(a.keys):len(a.keys)], a.name)
+		for k, inner := range v {
+			attrs = appendLeaves(attrs, attribute{domain: a.domain, props: a.props, keys: keys, name: k}, inner)
+		}
+	}
+	return attrs
+}
+
+func isPattern(mbean string) bool {
+	return strings.ContainsAny(mbean, "*?")
+}
+
+// parseObjectName splits an object name into its domain and key properties.
+// Quoted values may contain commas.
+func parseObjectName(name string) (domain string, props [][2]string, err error) {
+	idx := strings.Index(name, ":")
+	if idx == -1 {
+		return "", nil, fmt.Errorf("invalid object name %q", name)
+	}
+	domain, rest := name[:idx], name[idx+1:]
+
+	var (
+		start  int
+		quoted bool
+	)
+	for i := 0; i <= len(rest); i++ {
+		if i < len(rest) {
+			switch rest[i] {
+			case '\\':
+				i++
+				continue
+			case '"':
+				quoted = !quoted
+				continue
+			case ',':
+				if quoted {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		kv := strings.SplitN(rest[start:i], "=", 2)
+		if len(kv) != 2 {
+			return "", nil, fmt.Errorf("invalid object name %q", name)
+		}
+		props = append(props, [2]string{kv[0], kv[1]})
+		start = i + 1
+	}
+	return domain, props, nil
+}
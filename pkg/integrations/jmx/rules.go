@@ -0,0 +1,234 @@
+package jmx
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+// rulesFile is the subset of the JMX exporter config file used by the
+// integration. Other fields, such as hostPort, are ignored.
+type rulesFile struct {
+	LowercaseOutputName       bool   `yaml:"lowercaseOutputName"`
+	LowercaseOutputLabelNames bool   `yaml:"lowercaseOutputLabelNames"`
+	Rules                     []rule `yaml:"rules"`
+}
+
+// rule converts matching MBean attributes to a metric. Patterns and
+// templates use the syntax of the JMX exporter.
+type rule struct {
+	Pattern           string            `yaml:"pattern"`
+	Name              string            `yaml:"name"`
+	Value             string            `yaml:"value"`
+	ValueFactor       float64           `yaml:"valueFactor"`
+	Help              string            `yaml:"help"`
+	Labels            map[string]string `yaml:"labels"`
+	Type              string            `yaml:"type"`
+	AttrNameSnakeCase bool              `yaml:"attrNameSnakeCase"`
+
+	re *regexp.Regexp
+}
+
+// defaultHelp prefixes the path of an attribute in the help of metrics
+// whose rule doesn't set a help.
+const defaultHelp = "Attribute exposed for management "
+
+var (
+	// javaGroupRef matches the $1 group references of Java regex
+	// replacements, which Go would read as ${1_...} when followed by
+	// a word character.
+	javaGroupRef = regexp.MustCompile(`\$(\d+)`)
+
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9:_]`)
+	repeatUnderscores = regexp.MustCompile(`__+`)
+	snakeCaseBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+)
+
+// loadRules reads a JMX exporter config file. An empty path returns a
+// rulesFile without rules, which exports every attribute in the default
+// format.
+func loadRules(path string) (*rulesFile, error) {
+	if path == "" {
+		return &rulesFile{}, nil
+	}
+
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rules file %s: %w", path, err)
+	}
+	var rf rulesFile
+	if err := yaml.Unmarshal(bb, &rf); err != nil {
+		return nil, fmt.Errorf("unable to parse rules file %s: %w", path, err)
+	}
+
+	for i := range rf.Rules {
+		r := &rf.Rules[i]
+		// Like the JMX exporter, patterns aren't anchored.
+		if r.re, err = regexp.Compile("^.*(?:" + r.Pattern + ").*$"); err != nil {
+			return nil, fmt.Errorf("invalid pattern in rules file %s: %w", path, err)
+		}
+		switch strings.ToUpper(r.Type) {
+		case "", "UNTYPED", "GAUGE", "COUNTER":
+		default:
+			return nil, fmt.Errorf("unsupported type %q in rules file %s", r.Type, path)
+		}
+	}
+	return &rf, nil
+}
+
+// attribute is a leaf value of an MBean attribute. Composite attributes
+// produce one attribute for each of their numeric leaves.
+type attribute struct {
+	domain string
+	// props are the key properties of the MBean in their original order.
+	props [][2]string
+	// keys are the names of the composite values leading to the leaf.
+	keys  []string
+	name  string
+	value float64
+}
+
+// path returns the location of the attribute in the format of the JMX
+// exporter: domain<prop=value, ...><key, ...>attrName.
+func (a *attribute) path(attrName string) string {
+	props := make([]string, 0, len(a.props))
+	for _, p := range a.props {
+		props = append(props, p[0]+"="+p[1])
+	}
+	return fmt.Sprintf("%s<%s><%s>%s", a.domain, strings.Join(props, ", "), strings.Join(a.keys, ", "), attrName)
+}
+
+// matchString returns the string rule patterns are matched against:
+// the path of the attribute followed by its value.
+func (a *attribute) matchString(attrName string) string {
+	return a.path(attrName) + ": " + strconv.FormatFloat(a.value, 'g', -1, 64)
+}
+
+// sample is a metric produced from an attribute.
+type sample struct {
+	name      string
+	help      string
+	valueType prometheus.ValueType
+	labels    map[string]string
+	value     float64
+}
+
+// convert converts a to a sample with the first matching rule. Returns false
+// if rules are configured and none matched.
+func (rf *rulesFile) convert(a *attribute) (sample, bool) {
+	if len(rf.Rules) == 0 {
+		return rf.finish(defaultSample(a, a.name)), true
+	}
+
+	for _, r := range rf.Rules {
+		attrName := a.name
+		if r.AttrNameSnakeCase {
+			attrName = strings.ToLower(snakeCaseBoundary.ReplaceAllString(attrName, "${1}_${2}"))
+		}
+		match := a.matchString(attrName)
+		groups := r.re.FindStringSubmatchIndex(match)
+		if groups == nil {
+			continue
+		}
+
+		// Rules without a name export the attribute in the default format.
+		if r.Name == "" {
+			return rf.finish(defaultSample(a, attrName)), true
+		}
+
+		s := sample{
+			name:      expand(r.re, r.Name, match, groups),
+			help:      r.Help,
+			valueType: prometheus.UntypedValue,
+			labels:    make(map[string]string, len(r.Labels)),
+			value:     a.value,
+		}
+		if s.help == "" {
+			s.help = defaultHelp + a.path(attrName)
+		} else {
+			s.help = expand(r.re, s.help, match, groups)
+		}
+		switch strings.ToUpper(r.Type) {
+		case "GAUGE":
+			s.valueType = prometheus.GaugeValue
+		case "COUNTER":
+			s.valueType = prometheus.CounterValue
+		}
+		for k, v := range r.Labels {
+			s.labels[expand(r.re, k, match, groups)] = expand(r.re, v, match, groups)
+		}
+		if r.Value != "" {
+			v, err := strconv.ParseFloat(expand(r.re, r.Value, match, groups), 64)
+			if err != nil {
+				return sample{}, false
+			}
+			s.value = v
+		}
+		if r.ValueFactor != 0 {
+			s.value *= r.ValueFactor
+		}
+		return rf.finish(s), true
+	}
+	return sample{}, false
+}
+
+// finish sanitizes the names of s.
+func (rf *rulesFile) finish(s sample) sample {
+	s.name = safeName(s.name)
+	if rf.LowercaseOutputName {
+		s.name = strings.ToLower(s.name)
+	}
+
+	labels := make(map[string]string, len(s.labels))
+	for k, v := range s.labels {
+		if k == "" || v == "" {
+			continue
+		}
+		k = safeName(k)
+		if rf.LowercaseOutputLabelNames {
+			k = strings.ToLower(k)
+		}
+		labels[k] = v
+	}
+	s.labels = labels
+	return s
+}
+
+// defaultSample converts a with the default format of the JMX exporter:
+// domain_firstPropValue_key_attrName, with the other properties as labels.
+func defaultSample(a *attribute, attrName string) sample {
+	parts := []string{a.domain}
+	labels := make(map[string]string, len(a.props))
+	for i, p := range a.props {
+		if i == 0 {
+			parts = append(parts, p[1])
+			continue
+		}
+		labels[p[0]] = p[1]
+	}
+	parts = append(parts, a.keys...)
+	parts = append(parts, attrName)
+
+	return sample{
+		name:      strings.Join(parts, "_"),
+		help:      defaultHelp + a.path(attrName),
+		valueType: prometheus.UntypedValue,
+		labels:    labels,
+		value:     a.value,
+	}
+}
+
+// expand expands the group references of a template.
+func expand(re *regexp.Regexp, template, src string, groups []int) string {
+	template = javaGroupRef.ReplaceAllString(template, "$${$1}")
+	return string(re.ExpandString(nil, template, src, groups))
+}
+
+func safeName(s string) string {
+	return repeatUnderscores.ReplaceAllString(invalidNameChars.ReplaceAllString(s, "_"), "_")
+}
@@ -0,0 +1,274 @@
+package kafka_exporter //nolint:golint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type collector struct {
+	log     log.Logger
+	brokers []string
+	cfg     *sarama.Config
+
+	topics *regexp.Regexp
+	groups *regexp.Regexp
+
+	// mut protects client and admin, which are created on the first scrape
+	// and recreated after failures.
+	mut    sync.Mutex
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+
+	up          *prometheus.Desc
+	brokerCount *prometheus.Desc
+	topicDescs  struct {
+		partitions, currentOffset, oldestOffset, leader, replicas, inSyncReplicas *prometheus.Desc
+	}
+	groupDescs struct {
+		members, currentOffset, currentOffsetSum, lag, lagSum *prometheus.Desc
+	}
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	cfg, err := c.saramaConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	col := &collector{
+		log:     l,
+		brokers: c.Brokers,
+		cfg:     cfg,
+
+		up:          prometheus.NewDesc("kafka_up", "Was the last query of the Kafka brokers successful.", nil, nil),
+		brokerCount: prometheus.NewDesc("kafka_brokers", "Number of brokers in the Kafka cluster.", nil, nil),
+	}
+
+	if col.topics, err = regexp.Compile("^(?:" + c.TopicsFilter + ")$"); err != nil {
+		return nil, err
+	}
+	if col.groups, err = regexp.Compile("^(?:" + c.GroupsFilter + ")$"); err != nil {
+		return nil, err
+	}
+
+	t := &col.topicDescs
+	t.partitions = prometheus.NewDesc("kafka_topic_partitions", "Number of partitions of the topic.", []string{"topic"}, nil)
+	partitionLabels := []string{"topic", "partition"}
+	t.currentOffset = prometheus.NewDesc("kafka_topic_partition_current_offset", "Newest offset of the partition.", partitionLabels, nil)
+	t.oldestOffset = prometheus.NewDesc("kafka_topic_partition_oldest_offset", "Oldest offset of the partition.", partitionLabels, nil)
+	t.leader = prometheus.NewDesc("kafka_topic_partition_leader", "ID of the leader broker of the partition.", partitionLabels, nil)
+	t.replicas = prometheus.NewDesc("kafka_topic_partition_replicas", "Number of replicas of the partition.", partitionLabels, nil)
+	t.inSyncReplicas = prometheus.NewDesc("kafka_topic_partition_in_sync_replica", "Number of in-sync replicas of the partition.", partitionLabels, nil)
+
+	g := &col.groupDescs
+	g.members = prometheus.NewDesc("kafka_consumergroup_members", "Number of members of the consumer group.", []string{"consumergroup"}, nil)
+	groupPartitionLabels := []string{"consumergroup", "topic", "partition"}
+	groupTopicLabels := []string{"consumergroup", "topic"}
+	g.currentOffset = prometheus.NewDesc("kafka_consumergroup_current_offset", "Committed offset of the consumer group in the partition.", groupPartitionLabels, nil)
+	g.currentOffsetSum = prometheus.NewDesc("kafka_consumergroup_current_offset_sum", "Sum of the committed offsets of the consumer group in the topic.", groupTopicLabels, nil)
+	g.lag = prometheus.NewDesc("kafka_consumergroup_lag", "Lag of the consumer group in the partition.", groupPartitionLabels, nil)
+	g.lagSum = prometheus.NewDesc("kafka_consumergroup_lag_sum", "Sum of the lag of the consumer group in the topic.", groupTopicLabels, nil)
+
+	return col, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	t, g := &c.topicDescs, &c.groupDescs
+	for _, d := range []*prometheus.Desc{
+		c.up, c.brokerCount,
+		t.partitions, t.currentOffset, t.oldestOffset, t.leader, t.replicas, t.inSyncReplicas,
+		g.members, g.currentOffset, g.currentOffsetSum, g.lag, g.lagSum,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if err := c.collect(ch); err != nil {
+		level.Error(c.log).Log("msg", "failed to query the Kafka brokers", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+
+		// Reconnect on the next scrape in case the connection is broken.
+		// Closing the admin also closes the client.
+		if c.admin != nil {
+			_ = c.admin.Close()
+			c.client, c.admin = nil, nil
+		}
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+}
+
+// collect sends the metrics of the cluster to ch. Metrics are sent only once
+// everything has been queried so a failed scrape only sends kafka_up.
+func (c *collector) collect(ch chan<- prometheus.Metric) error {
+	if c.admin == nil {
+		client, err := sarama.NewClient(c.brokers, c.cfg)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		admin, err := sarama.NewClusterAdminFromClient(client)
+		if err != nil {
+			_ = client.Close()
+			return err
+		}
+		c.client, c.admin = client, admin
+	} else if err := c.client.RefreshMetadata(); err != nil {
+		return fmt.Errorf("failed to refresh metadata: %w", err)
+	}
+	client := c.client
+
+	var metrics []prometheus.Metric
+	metrics = append(metrics, prometheus.MustNewConstMetric(c.brokerCount, prometheus.GaugeValue, float64(len(client.Brokers()))))
+
+	topics, err := client.Topics()
+	if err != nil {
+		return fmt.Errorf("failed to list topics: %w", err)
+	}
+	sort.Strings(topics)
+
+	// newest holds the newest offset of each partition of the collected
+	// topics, used to compute the lag of consumer groups.
+	newest := make(map[string]map[int32]int64)
+
+	t := &c.topicDescs
+	for _, topic := range topics {
+		if !c.topics.MatchString(topic) {
+			continue
+		}
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return fmt.Errorf("failed to list partitions of %s: %w", topic, err)
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(t.partitions, prometheus.GaugeValue, float64(len(partitions)), topic))
+
+		newest[topic] = make(map[int32]int64, len(partitions))
+		for _, p := range partitions {
+			labels := []string{topic, strconv.Itoa(int(p))}
+
+			current, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+			if err != nil {
+				return fmt.Errorf("failed to get newest offset of %s/%d: %w", topic, p, err)
+			}
+			oldest, err := client.GetOffset(topic, p, sarama.OffsetOldest)
+			if err != nil {
+				return fmt.Errorf("failed to get oldest offset of %s/%d: %w", topic, p, err)
+			}
+			newest[topic][p] = current
+			metrics = append(metrics,
+				prometheus.MustNewConstMetric(t.currentOffset, prometheus.GaugeValue, float64(current), labels...),
+				prometheus.MustNewConstMetric(t.oldestOffset, prometheus.GaugeValue, float64(oldest), labels...),
+			)
+
+			if leader, err := client.Leader(topic, p); err == nil {
+				metrics = append(metrics, prometheus.MustNewConstMetric(t.leader, prometheus.GaugeValue, float64(leader.ID()), labels...))
+			}
+			if replicas, err := client.Replicas(topic, p); err == nil {
+				metrics = append(metrics, prometheus.MustNewConstMetric(t.replicas, prometheus.GaugeValue, float64(len(replicas)), labels...))
+			}
+			if isr, err := client.InSyncReplicas(topic, p); err == nil {
+				metrics = append(metrics, prometheus.MustNewConstMetric(t.inSyncReplicas, prometheus.GaugeValue, float64(len(isr)), labels...))
+			}
+		}
+	}
+
+	groupMetrics, err := c.collectGroups(newest)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range append(metrics, groupMetrics...) {
+		ch <- m
+	}
+	return nil
+}
+
+// collectGroups returns the metrics of the collected consumer groups. Only
+// partitions of collected topics with a committed offset are included.
+func (c *collector) collectGroups(newest map[string]map[int32]int64) ([]prometheus.Metric, error) {
+	groups, err := c.admin.ListConsumerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumer groups: %w", err)
+	}
+	var names []string
+	for name := range groups {
+		if c.groups.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	descriptions, err := c.admin.DescribeConsumerGroups(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe consumer groups: %w", err)
+	}
+
+	partitions := make(map[string][]int32, len(newest))
+	for topic, offsets := range newest {
+		for p := range offsets {
+			partitions[topic] = append(partitions[topic], p)
+		}
+	}
+
+	var (
+		g       = &c.groupDescs
+		metrics []prometheus.Metric
+	)
+	for _, desc := range descriptions {
+		metrics = append(metrics, prometheus.MustNewConstMetric(g.members, prometheus.GaugeValue, float64(len(desc.Members)), desc.GroupId))
+		if len(partitions) == 0 {
+			continue
+		}
+
+		offsets, err := c.admin.ListConsumerGroupOffsets(desc.GroupId, partitions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get offsets of consumer group %s: %w", desc.GroupId, err)
+		}
+
+		for topic, blocks := range offsets.Blocks {
+			var (
+				offsetSum, lagSum float64
+				committed         bool
+			)
+			for p, block := range blocks {
+				// Partitions without a committed offset report -1.
+				if block.Err != sarama.ErrNoError || block.Offset < 0 {
+					continue
+				}
+				committed = true
+
+				labels := []string{desc.GroupId, topic, strconv.Itoa(int(p))}
+				lag := float64(newest[topic][p] - block.Offset)
+				offsetSum += float64(block.Offset)
+				lagSum += lag
+				metrics = append(metrics,
+					prometheus.MustNewConstMetric(g.currentOffset, prometheus.GaugeValue, float64(block.Offset), labels...),
+					prometheus.MustNewConstMetric(g.lag, prometheus.GaugeValue, lag, labels...),
+				)
+			}
+			if committed {
+				metrics = append(metrics,
+					prometheus.MustNewConstMetric(g.currentOffsetSum, prometheus.GaugeValue, offsetSum, desc.GroupId, topic),
+					prometheus.MustNewConstMetric(g.lagSum, prometheus.GaugeValue, lagSum, desc.GroupId, topic),
+				)
+			}
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,109 @@
+// Package kafka_exporter implements an integration which collects topic
+// offsets and consumer group lag from Kafka brokers, using the metric names
+// of https://github.com/danielqsj/kafka_exporter.
+package kafka_exporter //nolint:golint
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	kafkautil "github.com/grafana/agent/pkg/util/kafka"
+)
+
+// DefaultConfig is the default config for kafka_exporter.
+var DefaultConfig = Config{
+	Version:      "2.2.1",
+	TopicsFilter: ".*",
+	GroupsFilter: ".*",
+}
+
+// Config controls the kafka_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// Brokers to bootstrap the connection to the Kafka cluster from.
+	Brokers []string `yaml:"brokers"`
+
+	// Version of Kafka the brokers are running.
+	Version string `yaml:"version,omitempty"`
+
+	Authentication kafkautil.Authentication `yaml:"authentication,omitempty"`
+
+	// TopicsFilter and GroupsFilter are regular expressions selecting the
+	// topics and consumer groups to collect.
+	TopicsFilter string `yaml:"topics_filter,omitempty"`
+	GroupsFilter string `yaml:"groups_filter,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if len(c.Brokers) == 0 {
+		return fmt.Errorf("kafka_exporter requires at least one broker")
+	}
+	if _, err := regexp.Compile(c.TopicsFilter); err != nil {
+		return fmt.Errorf("invalid topics_filter: %w", err)
+	}
+	if _, err := regexp.Compile(c.GroupsFilter); err != nil {
+		return fmt.Errorf("invalid groups_filter: %w", err)
+	}
+	_, err := c.saramaConfig()
+	return err
+}
+
+// saramaConfig builds the configuration for the Kafka client.
+func (c *Config) saramaConfig() (*sarama.Config, error) {
+	cfg := sarama.NewConfig()
+	cfg.ClientID = "grafana-agent"
+
+	version, err := sarama.ParseKafkaVersion(c.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %w", c.Version, err)
+	}
+	cfg.Version = version
+
+	if err := c.Authentication.Apply(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "kafka_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new kafka_exporter integration. The connection to the
+// brokers is established on the first scrape, so the integration starts even
+// if the brokers are unavailable.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
@@ -0,0 +1,122 @@
+package kafka_exporter //nolint:golint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`brokers: [localhost:9092]`), &cfg))
+	require.Equal(t, "2.2.1", cfg.Version)
+	require.Equal(t, ".*", cfg.TopicsFilter)
+
+	tt := []struct {
+		name, in, err string
+	}{
+		{"no brokers", `{}`, "kafka_exporter requires at least one broker"},
+		{"bad version", `{brokers: [localhost:9092], version: latest}`, `invalid version "latest": invalid version ` + "`latest`"},
+		{"bad filter", `{brokers: [localhost:9092], groups_filter: "("}`, "invalid groups_filter: error parsing regexp: missing closing ): `(`"},
+		{"sasl without user", `{brokers: [localhost:9092], authentication: {sasl_config: {mechanism: PLAIN}}}`, "sasl_config must set user and password"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.in), &cfg), tc.err)
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()).
+			SetLeader("ignored", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset("orders", 0, sarama.OffsetNewest, 100).
+			SetOffset("orders", 0, sarama.OffsetOldest, 10).
+			SetOffset("orders", 1, sarama.OffsetNewest, 50).
+			SetOffset("orders", 1, sarama.OffsetOldest, 0),
+		"ListGroupsRequest": sarama.NewMockListGroupsResponse(t).
+			AddGroup("billing", "consumer"),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("billing", &sarama.GroupDescription{
+				GroupId: "billing",
+				State:   "Stable",
+				Members: map[string]*sarama.GroupMemberDescription{"member-1": {}},
+			}),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "billing", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("billing", "orders", 0, 90, "", sarama.ErrNoError).
+			SetOffset("billing", "orders", 1, -1, "", sarama.ErrNoError),
+	})
+
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+brokers: [`+broker.Addr()+`]
+version: 1.0.0
+topics_filter: orders
+`), &cfg))
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP kafka_brokers Number of brokers in the Kafka cluster.
+# TYPE kafka_brokers gauge
+kafka_brokers 1
+# HELP kafka_consumergroup_current_offset Committed offset of the consumer group in the partition.
+# TYPE kafka_consumergroup_current_offset gauge
+kafka_consumergroup_current_offset{consumergroup="billing",partition="0",topic="orders"} 90
+# HELP kafka_consumergroup_current_offset_sum Sum of the committed offsets of the consumer group in the topic.
+# TYPE kafka_consumergroup_current_offset_sum gauge
+kafka_consumergroup_current_offset_sum{consumergroup="billing",topic="orders"} 90
+# HELP kafka_consumergroup_lag Lag of the consumer group in the partition.
+# TYPE kafka_consumergroup_lag gauge
+kafka_consumergroup_lag{consumergroup="billing",partition="0",topic="orders"} 10
+# HELP kafka_consumergroup_lag_sum Sum of the lag of the consumer group in the topic.
+# TYPE kafka_consumergroup_lag_sum gauge
+kafka_consumergroup_lag_sum{consumergroup="billing",topic="orders"} 10
+# HELP kafka_consumergroup_members Number of members of the consumer group.
+# TYPE kafka_consumergroup_members gauge
+kafka_consumergroup_members{consumergroup="billing"} 1
+# HELP kafka_topic_partition_current_offset Newest offset of the partition.
+# TYPE kafka_topic_partition_current_offset gauge
+kafka_topic_partition_current_offset{partition="0",topic="orders"} 100
+kafka_topic_partition_current_offset{partition="1",topic="orders"} 50
+# HELP kafka_topic_partition_oldest_offset Oldest offset of the partition.
+# TYPE kafka_topic_partition_oldest_offset gauge
+kafka_topic_partition_oldest_offset{partition="0",topic="orders"} 10
+kafka_topic_partition_oldest_offset{partition="1",topic="orders"} 0
+# HELP kafka_topic_partitions Number of partitions of the topic.
+# TYPE kafka_topic_partitions gauge
+kafka_topic_partitions{topic="orders"} 2
+# HELP kafka_up Was the last query of the Kafka brokers successful.
+# TYPE kafka_up gauge
+kafka_up 1
+`
+	names := []string{
+		"kafka_brokers", "kafka_up", "kafka_topic_partitions",
+		"kafka_topic_partition_current_offset", "kafka_topic_partition_oldest_offset",
+		"kafka_consumergroup_members", "kafka_consumergroup_current_offset", "kafka_consumergroup_current_offset_sum",
+		"kafka_consumergroup_lag", "kafka_consumergroup_lag_sum",
+	}
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect), names...))
+
+	// The connection is reused by following scrapes.
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect), names...))
+}
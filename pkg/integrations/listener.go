@@ -0,0 +1,159 @@
+package integrations
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	config_util "github.com/prometheus/common/config"
+	node_https "github.com/prometheus/node_exporter/https"
+)
+
+// ListenerConfig controls how the HTTP endpoints of integrations are served.
+type ListenerConfig struct {
+	// ListenAddress serves the endpoints of integrations on a separate
+	// listener instead of the HTTP server of the Agent when set.
+	ListenAddress string `yaml:"listen_address,omitempty"`
+
+	// TLSConfig enables TLS for the separate listener.
+	TLSConfig node_https.TLSStruct `yaml:"tls_config,omitempty"`
+
+	// BasicAuth requires clients to authenticate when requesting the endpoints
+	// of integrations, whether they're served by the separate listener or
+	// not.
+	BasicAuth *BasicAuth `yaml:"basic_auth,omitempty"`
+}
+
+// BasicAuth holds the credentials clients must use for basic auth.
+type BasicAuth struct {
+	Username     string             `yaml:"username"`
+	Password     config_util.Secret `yaml:"password,omitempty"`
+	PasswordFile string             `yaml:"password_file,omitempty"`
+}
+
+// validate checks that c can be used.
+func (c *ListenerConfig) validate() error {
+	if c.ListenAddress != "" {
+		if _, _, err := net.SplitHostPort(c.ListenAddress); err != nil {
+			return fmt.Errorf("invalid listen_address %q: %w", c.ListenAddress, err)
+		}
+	}
+
+	tlsConfig := c.TLSConfig
+	switch {
+	case (tlsConfig.TLSCertPath == "") != (tlsConfig.TLSKeyPath == ""):
+		return fmt.Errorf("tls_config must set both cert_file and key_file")
+	case tlsConfig.TLSCertPath != "" && c.ListenAddress == "":
+		return fmt.Errorf("tls_config requires listen_address to be set")
+	}
+
+	if ba := c.BasicAuth; ba != nil {
+		switch {
+		case ba.Username == "":
+			return fmt.Errorf("basic_auth must set a username")
+		case ba.Password != "" && ba.PasswordFile != "":
+			return fmt.Errorf("basic_auth must set at most one of password and password_file")
+		}
+	}
+	return nil
+}
+
+// usingTLS returns true if the separate listener serves with TLS.
+func (c *ListenerConfig) usingTLS() bool {
+	return c.ListenAddress != "" && c.TLSConfig.TLSCertPath != ""
+}
+
+// scrapeAddress returns the address for scraping the separate listener.
+// Unspecified hosts are replaced by 127.0.0.1.
+func (c *ListenerConfig) scrapeAddress() string {
+	host, port, _ := net.SplitHostPort(c.ListenAddress)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// httpClientConfig returns the basic auth settings for scraping integrations.
+func (ba *BasicAuth) httpClientConfig() *config_util.BasicAuth {
+	return &config_util.BasicAuth{
+		Username:     ba.Username,
+		Password:     ba.Password,
+		PasswordFile: ba.PasswordFile,
+	}
+}
+
+// readPassword returns the password of ba, reading it from its file if
+// needed.
+func (ba *BasicAuth) readPassword() (string, error) {
+	if ba.PasswordFile == "" {
+		return string(ba.Password), nil
+	}
+	bb, err := ioutil.ReadFile(ba.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read basic_auth password_file: %w", err)
+	}
+	return strings.TrimSpace(string(bb)), nil
+}
+
+// basicAuthHandler wraps next to require the given credentials.
+func basicAuthHandler(username, password string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+
+			rw.Header().Set("WWW-Authenticate", `Basic realm="integrations"`)
+			http.Error(rw, "401 Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// listener is a separate HTTP server for the endpoints of integrations.
+type listener struct {
+	cfg ListenerConfig
+	lis net.Listener
+	srv *http.Server
+}
+
+// newListener starts serving handler on the address of cfg.
+func newListener(l log.Logger, cfg ListenerConfig, handler http.Handler) (*listener, error) {
+	lis, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddress, err)
+	}
+
+	srv := &http.Server{Handler: handler}
+	if cfg.usingTLS() {
+		tlsConfig, err := node_https.ConfigToTLSConfig(&cfg.TLSConfig)
+		if err != nil {
+			lis.Close()
+			return nil, fmt.Errorf("invalid tls_config: %w", err)
+		}
+		srv.TLSConfig = tlsConfig
+		lis = tls.NewListener(lis, tlsConfig)
+	}
+
+	go func() {
+		level.Info(l).Log("msg", "serving integrations", "addr", lis.Addr())
+		if err := srv.Serve(lis); err != nil && err != http.ErrServerClosed {
+			level.Error(l).Log("msg", "integrations listener stopped", "err", err)
+		}
+	}()
+
+	return &listener{cfg: cfg, lis: lis, srv: srv}, nil
+}
+
+// Addr returns the address the listener is serving on.
+func (l *listener) Addr() net.Addr { return l.lis.Addr() }
+
+// Close stops the listener.
+func (l *listener) Close() error { return l.srv.Close() }
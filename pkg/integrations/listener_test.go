@@ -0,0 +1,196 @@
+package integrations
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/prom/instance"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	node_https "github.com/prometheus/node_exporter/https"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerConfig_validate(t *testing.T) {
+	tt := []struct {
+		name      string
+		cfg       ListenerConfig
+		expectErr string
+	}{
+		{name: "defaults"},
+		{
+			name: "valid",
+			cfg: ListenerConfig{
+				ListenAddress: "0.0.0.0:12346",
+				TLSConfig:     node_https.TLSStruct{TLSCertPath: "cert.pem", TLSKeyPath: "key.pem"},
+				BasicAuth:     &BasicAuth{Username: "user", PasswordFile: "password"},
+			},
+		},
+		{
+			name:      "invalid address",
+			cfg:       ListenerConfig{ListenAddress: "12346"},
+			expectErr: `invalid listen_address "12346": address 12346: missing port in address`,
+		},
+		{
+			name: "missing key file",
+			cfg: ListenerConfig{
+				ListenAddress: "0.0.0.0:12346",
+				TLSConfig:     node_https.TLSStruct{TLSCertPath: "cert.pem"},
+			},
+			expectErr: "tls_config must set both cert_file and key_file",
+		},
+		{
+			name:      "tls without listener",
+			cfg:       ListenerConfig{TLSConfig: node_https.TLSStruct{TLSCertPath: "cert.pem", TLSKeyPath: "key.pem"}},
+			expectErr: "tls_config requires listen_address to be set",
+		},
+		{
+			name:      "missing username",
+			cfg:       ListenerConfig{BasicAuth: &BasicAuth{Password: "secret"}},
+			expectErr: "basic_auth must set a username",
+		},
+		{
+			name:      "password and password file",
+			cfg:       ListenerConfig{BasicAuth: &BasicAuth{Username: "user", Password: "secret", PasswordFile: "password"}},
+			expectErr: "basic_auth must set at most one of password and password_file",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.validate()
+			if tc.expectErr != "" {
+				require.EqualError(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestManager_Listener(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600))
+
+	mock := newMockIntegration()
+
+	cfg := mockManagerConfig()
+	cfg.Integrations = append(cfg.Integrations, mockConfig{integration: mock})
+	cfg.Listener = ListenerConfig{
+		ListenAddress: "127.0.0.1:0",
+		BasicAuth:     &BasicAuth{Username: "user", PasswordFile: passwordFile},
+	}
+
+	im := instance.NewBasicManager(instance.DefaultBasicManagerConfig, log.NewNopLogger(), mockInstanceFactory)
+	m, err := NewManager(cfg, log.NewNopLogger(), im, noOpValidator)
+	require.NoError(t, err)
+	defer m.Stop()
+
+	r := mux.NewRouter()
+	m.WireAPI(r)
+
+	get := func(url string, auth bool) int {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if auth {
+			req.SetBasicAuth("user", "secret")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	getMain := func(auth bool) int {
+		req := httptest.NewRequest(http.MethodGet, "/integrations/mock/metrics", nil)
+		if auth {
+			req.SetBasicAuth("user", "secret")
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	url := fmt.Sprintf("http://%s/integrations/mock/metrics", m.listener.Addr())
+	require.Equal(t, http.StatusUnauthorized, get(url, false))
+	require.Equal(t, http.StatusOK, get(url, true))
+	require.Equal(t, http.StatusNotFound, getMain(true), "main server should not serve integrations")
+
+	// Moving integrations back to the main server should stop the listener
+	// but keep requiring basic auth.
+	cfg.Listener.ListenAddress = ""
+	require.NoError(t, m.ApplyConfig(cfg))
+	require.Nil(t, m.listener)
+	require.Equal(t, http.StatusUnauthorized, getMain(false))
+	require.Equal(t, http.StatusOK, getMain(true))
+}
+
+func TestManager_instanceConfigForIntegration_Listener(t *testing.T) {
+	mock := newMockIntegration()
+	icfg := mockConfig{integration: mock}
+
+	mcfg := mockManagerConfig()
+	mcfg.ServerUsingTLS = true
+	mcfg.TLSConfig = config_util.TLSConfig{InsecureSkipVerify: true}
+
+	im := instance.NewBasicManager(instance.DefaultBasicManagerConfig, log.NewNopLogger(), mockInstanceFactory)
+	m, err := NewManager(mockManagerConfig(), log.NewNopLogger(), im, noOpValidator)
+	require.NoError(t, err)
+	defer m.Stop()
+
+	tt := []struct {
+		name         string
+		listener     ListenerConfig
+		expectScheme string
+		expectAddr   string
+		expectAuth   *config_util.BasicAuth
+	}{
+		{
+			name:         "main server",
+			expectScheme: "https",
+			expectAddr:   "127.0.0.1:0",
+		},
+		{
+			name: "separate listener",
+			listener: ListenerConfig{
+				ListenAddress: "0.0.0.0:12346",
+				BasicAuth:     &BasicAuth{Username: "user", Password: "secret"},
+			},
+			expectScheme: "http",
+			expectAddr:   "127.0.0.1:12346",
+			expectAuth:   &config_util.BasicAuth{Username: "user", Password: "secret"},
+		},
+		{
+			name: "separate listener with tls",
+			listener: ListenerConfig{
+				ListenAddress: "localhost:12346",
+				TLSConfig:     node_https.TLSStruct{TLSCertPath: "cert.pem", TLSKeyPath: "key.pem"},
+			},
+			expectScheme: "https",
+			expectAddr:   "localhost:12346",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mcfg.Listener = tc.listener
+
+			cfg, err := m.instanceConfigForIntegration(icfg, mock, mcfg)
+			require.NoError(t, err)
+			require.Len(t, cfg.ScrapeConfigs, 1)
+
+			sc := cfg.ScrapeConfigs[0]
+			require.Equal(t, tc.expectScheme, sc.Scheme)
+			require.Equal(t, tc.expectAuth, sc.HTTPClientConfig.BasicAuth)
+
+			sd := sc.ServiceDiscoveryConfigs[0].(discovery.StaticConfig)
+			require.Equal(t, model.LabelValue(tc.expectAddr), sd[0].Targets[0][model.AddressLabel])
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/health"
 	"github.com/grafana/agent/pkg/prom"
 	"github.com/grafana/agent/pkg/prom/instance"
 	"github.com/grafana/agent/pkg/prom/instance/configstore"
@@ -23,6 +24,7 @@ import (
 	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/pkg/relabel"
+	"go.uber.org/atomic"
 )
 
 var (
@@ -59,7 +61,12 @@ type ManagerConfig struct {
 	Labels model.LabelSet `yaml:"labels,omitempty"`
 
 	// Prometheus RW configs to use for all integrations.
-	PrometheusRemoteWrite []*config.RemoteWriteConfig `yaml:"prometheus_remote_write,omitempty"`
+	PrometheusRemoteWrite []*instance.RemoteWriteConfig `yaml:"prometheus_remote_write,omitempty"`
+
+	// PrometheusConfigs are the Prometheus instance configs which integrations
+	// may send their metrics through with autoscrape.metrics_instance. Set by
+	// ApplyDefaults.
+	PrometheusConfigs []instance.Config `yaml:"-"`
 
 	IntegrationRestartBackoff time.Duration `yaml:"integration_restart_backoff,omitempty"`
 
@@ -73,6 +80,9 @@ type ManagerConfig struct {
 
 	TLSConfig config_util.TLSConfig `yaml:"http_tls_config,omitempty"`
 
+	// Listener controls how the HTTP endpoints of integrations are served.
+	Listener ListenerConfig `yaml:"listener,omitempty"`
+
 	// This is set to true if the Server TLSConfig Cert and Key path are set
 	ServerUsingTLS bool `yaml:"-"`
 }
@@ -113,8 +123,15 @@ func (c *ManagerConfig) DefaultRelabelConfigs(hostname string) []*relabel.Config
 // that it can be used.
 //
 // If any integrations are enabled and are configured to be scraped, the
-// Prometheus configuration must have a WAL directory configured.
+// Prometheus configuration must have a WAL directory configured. Integrations
+// may only send metrics through instance configs defined in cfg.
 func (c *ManagerConfig) ApplyDefaults(cfg *prom.Config) error {
+	c.PrometheusConfigs = cfg.Configs
+
+	if err := c.Listener.validate(); err != nil {
+		return fmt.Errorf("invalid listener: %w", err)
+	}
+
 	for _, ic := range c.Integrations {
 		if !ic.CommonConfig().Enabled {
 			continue
@@ -129,11 +146,41 @@ func (c *ManagerConfig) ApplyDefaults(cfg *prom.Config) error {
 		if scrapeIntegration && cfg.WALDir == "" {
 			return fmt.Errorf("no wal_directory configured")
 		}
+
+		// Integrations may override the global scrape interval and timeout.
+		// Validate them here so a bad override is reported when the config is
+		// loaded rather than when the integration is scheduled for scraping.
+		common := ic.CommonConfig()
+		interval := common.ScrapeInterval
+		if interval == 0 {
+			interval = time.Duration(cfg.Global.Prometheus.ScrapeInterval)
+		}
+		if common.ScrapeTimeout > interval {
+			return fmt.Errorf("integration %s: scrape_timeout (%s) must not be greater than scrape_interval (%s)", integrationID(ic), common.ScrapeTimeout, interval)
+		}
+		if err := common.Autoscrape.ExtraLabels.Validate(); err != nil {
+			return fmt.Errorf("integration %s: invalid autoscrape.extra_labels: %w", integrationID(ic), err)
+		}
+		if name := common.Autoscrape.MetricsInstance; name != "" {
+			if _, ok := c.metricsInstance(name); !ok {
+				return fmt.Errorf("integration %s: autoscrape.metrics_instance %q does not match any prometheus instance config", integrationID(ic), name)
+			}
+		}
 	}
 
 	return nil
 }
 
+// metricsInstance returns the Prometheus instance config with the given name.
+func (c *ManagerConfig) metricsInstance(name string) (*instance.Config, bool) {
+	for i := range c.PrometheusConfigs {
+		if c.PrometheusConfigs[i].Name == name {
+			return &c.PrometheusConfigs[i], true
+		}
+	}
+	return nil, false
+}
+
 // Manager manages a set of integrations and runs them.
 type Manager struct {
 	logger log.Logger
@@ -152,6 +199,17 @@ type Manager struct {
 
 	integrationsMut sync.RWMutex
 	integrations    map[string]*integrationProcess
+
+	// integrationErrs holds the error of every integration which failed to
+	// start or be scheduled for scraping by the last call to ApplyConfig,
+	// keyed by integration name. Protected by integrationsMut.
+	integrationErrs map[string]error
+
+	// listener serves the endpoints of integrations when a separate listener
+	// is configured. authPassword is the password required by basic auth.
+	// Protected by cfgMut.
+	listener     *listener
+	authPassword string
 }
 
 // NewManager creates a new integrations manager. NewManager must be given an
@@ -169,7 +227,8 @@ func NewManager(c ManagerConfig, logger log.Logger, im instance.Manager, validat
 		im:        im,
 		validator: validate,
 
-		integrations: make(map[string]*integrationProcess, len(c.Integrations)),
+		integrations:    make(map[string]*integrationProcess, len(c.Integrations)),
+		integrationErrs: make(map[string]error),
 	}
 
 	var err error
@@ -194,7 +253,11 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 	m.integrationsMut.Lock()
 	defer m.integrationsMut.Unlock()
 
-	if util.CompareYAML(m.cfg, cfg) {
+	// PrometheusConfigs isn't marshaled, so it has to be compared on its own.
+	// The config is applied again if the separate listener previously failed
+	// to start.
+	listenerRunning := m.listener != nil || cfg.Listener.ListenAddress == ""
+	if util.CompareYAML(m.cfg, cfg) && util.CompareYAML(m.cfg.PrometheusConfigs, cfg.PrometheusConfigs) && listenerRunning {
 		return nil
 	}
 
@@ -205,12 +268,18 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 		// No-op
 	}
 
+	if err := m.applyListener(cfg.Listener); err != nil {
+		return err
+	}
+
+	m.integrationErrs = make(map[string]error)
+
 	// Iterate over our integrations. New or changed integrations will be
 	// started, with their existing counterparts being shut down.
 	for _, ic := range cfg.Integrations {
 		// Key is used to identify the instance of this integration within the
 		// instance manager and within our set of running integrations.
-		key := integrationKey(ic.Name())
+		key := integrationKey(ic.Name(), ic.CommonConfig().Instance)
 
 		// Look for an existing integration with the same key. If it exists and
 		// is unchanged, we have nothing to do. Otherwise, we're going to recreate
@@ -223,10 +292,11 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 			delete(m.integrations, key)
 		}
 
-		l := log.With(m.logger, "integration", ic.Name())
+		l := log.With(m.logger, "integration", integrationID(ic))
 		i, err := ic.NewIntegration(l)
 		if err != nil {
-			level.Error(m.logger).Log("msg", "failed to initialize integration. it will not run or be scraped", "integration", ic.Name(), "err", err)
+			level.Error(m.logger).Log("msg", "failed to initialize integration. it will not run or be scraped", "integration", integrationID(ic), "err", err)
+			m.integrationErrs[integrationID(ic)] = fmt.Errorf("failed to initialize: %w", err)
 			failed = true
 
 			// If this integration was running before, its instance won't be cleaned
@@ -247,6 +317,8 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 
 			wg:   &m.wg,
 			wait: m.instanceBackoff,
+
+			restartErr: atomic.NewError(nil),
 		}
 		go p.Run()
 		m.integrations[key] = p
@@ -257,7 +329,7 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 	for key, process := range m.integrations {
 		foundConfig := false
 		for _, ic := range cfg.Integrations {
-			if integrationKey(ic.Name()) == key {
+			if integrationKey(ic.Name(), ic.CommonConfig().Instance) == key {
 				foundConfig = true
 				break
 			}
@@ -282,15 +354,20 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 
 		switch shouldCollect {
 		case true:
-			instanceConfig := m.instanceConfigForIntegration(p.cfg, p.i, cfg)
-			if err := m.validator(&instanceConfig); err != nil {
-				level.Error(p.log).Log("msg", "failed to validate generated scrape config for integration. integration will not be scraped", "err", err, "integration", p.cfg.Name())
+			instanceConfig, err := m.instanceConfigForIntegration(p.cfg, p.i, cfg)
+			if err == nil {
+				err = m.validator(&instanceConfig)
+			}
+			if err != nil {
+				level.Error(p.log).Log("msg", "failed to validate generated scrape config for integration. integration will not be scraped", "err", err, "integration", integrationID(p.cfg))
+				m.integrationErrs[integrationID(p.cfg)] = fmt.Errorf("invalid scrape config: %w", err)
 				failed = true
 				break
 			}
 
 			if err := m.im.ApplyConfig(instanceConfig); err != nil {
-				level.Error(p.log).Log("msg", "failed to apply integration. integration will not be scraped", "err", err, "integration", p.cfg.Name())
+				level.Error(p.log).Log("msg", "failed to apply integration. integration will not be scraped", "err", err, "integration", integrationID(p.cfg))
+				m.integrationErrs[integrationID(p.cfg)] = fmt.Errorf("failed to schedule for scraping: %w", err)
 				failed = true
 			}
 		case false:
@@ -309,6 +386,44 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 	return nil
 }
 
+// applyListener starts, restarts, or stops the separate listener for the
+// endpoints of integrations. It must be called with cfgMut held.
+func (m *Manager) applyListener(cfg ListenerConfig) error {
+	var password string
+	if cfg.BasicAuth != nil {
+		var err error
+		if password, err = cfg.BasicAuth.readPassword(); err != nil {
+			return err
+		}
+	}
+	m.authPassword = password
+
+	if m.listener != nil {
+		unchanged := m.listener.cfg.ListenAddress == cfg.ListenAddress && util.CompareYAML(m.listener.cfg.TLSConfig, cfg.TLSConfig)
+		if unchanged {
+			return nil
+		}
+
+		// The old listener is closed first since the new one may use the same
+		// address.
+		_ = m.listener.Close()
+		m.listener = nil
+	}
+	if cfg.ListenAddress == "" {
+		return nil
+	}
+
+	r := mux.NewRouter()
+	m.wireRoutes(r, true)
+
+	lis, err := newListener(m.logger, cfg, r)
+	if err != nil {
+		return err
+	}
+	m.listener = lis
+	return nil
+}
+
 // integrationProcess is a running integration.
 type integrationProcess struct {
 	log  log.Logger
@@ -319,6 +434,10 @@ type integrationProcess struct {
 
 	wg   *sync.WaitGroup
 	wait func(cfg Config, err error)
+
+	// restartErr is set to the error the integration stopped with while
+	// waiting to restart it.
+	restartErr *atomic.Error
 }
 
 // Run runs the integration until the process is canceled.
@@ -326,7 +445,7 @@ func (p *integrationProcess) Run() {
 	defer func() {
 		if r := recover(); r != nil {
 			err := fmt.Errorf("%v", r)
-			level.Error(p.log).Log("msg", "integration has panicked. THIS IS A BUG!", "err", err, "integration", p.cfg.Name())
+			level.Error(p.log).Log("msg", "integration has panicked. THIS IS A BUG!", "err", err, "integration", integrationID(p.cfg))
 		}
 	}()
 
@@ -336,47 +455,108 @@ func (p *integrationProcess) Run() {
 	for {
 		err := p.i.Run(p.ctx)
 		if err != nil && err != context.Canceled {
+			p.restartErr.Store(err)
 			p.wait(p.cfg, err)
+			p.restartErr.Store(nil)
 		} else {
-			level.Info(p.log).Log("msg", "stopped integration", "integration", p.cfg.Name())
+			level.Info(p.log).Log("msg", "stopped integration", "integration", integrationID(p.cfg))
 			break
 		}
 	}
 }
 
+// Health returns the status of every configured integration. Integrations are
+// unhealthy when they failed to start or be scheduled for scraping, or while
+// waiting to be restarted after stopping abnormally.
+func (m *Manager) Health() health.Subsystem {
+	m.cfgMut.RLock()
+	defer m.cfgMut.RUnlock()
+
+	m.integrationsMut.RLock()
+	defer m.integrationsMut.RUnlock()
+
+	components := make([]health.Component, 0, len(m.cfg.Integrations))
+	for _, ic := range m.cfg.Integrations {
+		c := health.Component{Name: integrationID(ic), Healthy: true, Ready: true}
+
+		if err, ok := m.integrationErrs[integrationID(ic)]; ok {
+			c.Healthy = false
+			c.Message = err.Error()
+		} else if p, ok := m.integrations[integrationKey(ic.Name(), ic.CommonConfig().Instance)]; ok {
+			if err := p.restartErr.Load(); err != nil {
+				c.Healthy = false
+				c.Message = fmt.Sprintf("restarting after stopping abnormally: %s", err)
+			}
+		}
+
+		components = append(components, c)
+	}
+
+	return health.NewSubsystem("integrations", components)
+}
+
 func (m *Manager) instanceBackoff(cfg Config, err error) {
 	m.cfgMut.RLock()
 	defer m.cfgMut.RUnlock()
 
 	integrationAbnormalExits.WithLabelValues(cfg.Name()).Inc()
-	level.Error(m.logger).Log("msg", "integration stopped abnormally, restarting after backoff", "err", err, "integration", cfg.Name(), "backoff", m.cfg.IntegrationRestartBackoff)
+	level.Error(m.logger).Log("msg", "integration stopped abnormally, restarting after backoff", "err", err, "integration", integrationID(cfg), "backoff", m.cfg.IntegrationRestartBackoff)
 	time.Sleep(m.cfg.IntegrationRestartBackoff)
 }
 
-func (m *Manager) instanceConfigForIntegration(icfg Config, i Integration, cfg ManagerConfig) instance.Config {
+func (m *Manager) instanceConfigForIntegration(icfg Config, i Integration, cfg ManagerConfig) (instance.Config, error) {
 	common := icfg.CommonConfig()
-	relabelConfigs := append(cfg.DefaultRelabelConfigs(m.hostname), common.RelabelConfigs...)
+
+	// Named instances set their own instance label, so the default relabel
+	// rule replacing it must be skipped.
+	relabelCfg := cfg
+	if common.Instance != "" {
+		relabelCfg.ReplaceInstanceLabel = false
+	}
+	relabelConfigs := append(relabelCfg.DefaultRelabelConfigs(m.hostname), common.RelabelConfigs...)
 
 	schema := "http"
 	// Check for HTTPS support
 	var httpClientConfig config_util.HTTPClientConfig
-	if cfg.ServerUsingTLS {
+	usingTLS := cfg.ServerUsingTLS
+	if cfg.Listener.ListenAddress != "" {
+		usingTLS = cfg.Listener.usingTLS()
+	}
+	if usingTLS {
 		schema = "https"
 		httpClientConfig.TLSConfig = cfg.TLSConfig
 	}
+	if ba := cfg.Listener.BasicAuth; ba != nil {
+		httpClientConfig.BasicAuth = ba.httpClientConfig()
+	}
 
 	var scrapeConfigs []*config.ScrapeConfig
 
 	for _, isc := range i.ScrapeConfigs() {
+		var (
+			jobName = fmt.Sprintf("integrations/%s", isc.JobName)
+			labels  = common.Autoscrape.ExtraLabels.Clone()
+		)
+		if common.Instance != "" {
+			// Job names must be unique across instances, but every instance
+			// should still have the same job label.
+			if labels == nil {
+				labels = make(model.LabelSet, 2)
+			}
+			labels[model.JobLabel] = model.LabelValue(jobName)
+			labels[model.InstanceLabel] = model.LabelValue(common.Instance)
+			jobName = fmt.Sprintf("%s/%s", jobName, common.Instance)
+		}
+
 		sc := &config.ScrapeConfig{
-			JobName:                 fmt.Sprintf("integrations/%s", isc.JobName),
-			MetricsPath:             path.Join("/integrations", icfg.Name(), isc.MetricsPath),
+			JobName:                 jobName,
+			MetricsPath:             path.Join("/integrations", icfg.Name(), common.Instance, isc.MetricsPath),
 			Scheme:                  schema,
 			HonorLabels:             false,
 			HonorTimestamps:         true,
 			ScrapeInterval:          model.Duration(common.ScrapeInterval),
 			ScrapeTimeout:           model.Duration(common.ScrapeTimeout),
-			ServiceDiscoveryConfigs: m.scrapeServiceDiscovery(cfg),
+			ServiceDiscoveryConfigs: m.scrapeServiceDiscovery(cfg, labels),
 			RelabelConfigs:          relabelConfigs,
 			MetricRelabelConfigs:    common.MetricRelabelConfigs,
 			HTTPClientConfig:        httpClientConfig,
@@ -386,28 +566,72 @@ func (m *Manager) instanceConfigForIntegration(icfg Config, i Integration, cfg M
 	}
 
 	instanceCfg := instance.DefaultConfig
-	instanceCfg.Name = integrationKey(icfg.Name())
-	instanceCfg.ScrapeConfigs = scrapeConfigs
 	instanceCfg.RemoteWrite = cfg.PrometheusRemoteWrite
+
+	if name := common.Autoscrape.MetricsInstance; name != "" {
+		metricsInstance, ok := cfg.metricsInstance(name)
+		if !ok {
+			return instance.Config{}, fmt.Errorf("metrics instance %q not found", name)
+		}
+
+		// Use every setting of the metrics instance besides its scrape configs.
+		// With a shared instance mode, this groups the integration into the
+		// same instance, sharing its WAL.
+		instanceCfg = *metricsInstance
+		instanceCfg.Labels = nil
+		instanceCfg.RemoteWrite = make([]*instance.RemoteWriteConfig, 0, len(metricsInstance.RemoteWrite))
+		for _, rw := range metricsInstance.RemoteWrite {
+			// Remote write names must be unique across instances. Blank them out
+			// so they're generated again for the integration.
+			rwCopy := *rw
+			rwCopy.Name = ""
+			instanceCfg.RemoteWrite = append(instanceCfg.RemoteWrite, &rwCopy)
+		}
+	}
+
+	instanceCfg.Name = integrationKey(icfg.Name(), common.Instance)
+	instanceCfg.ScrapeConfigs = scrapeConfigs
 	if common.WALTruncateFrequency > 0 {
 		instanceCfg.WALTruncateFrequency = common.WALTruncateFrequency
 	}
-	return instanceCfg
+	return instanceCfg, nil
+}
+
+// integrationID returns the name of an integration Config followed by its
+// instance name, if it has one. It identifies the integration in logs and
+// health reports.
+func integrationID(cfg Config) string {
+	if instance := cfg.CommonConfig().Instance; instance != "" {
+		return fmt.Sprintf("%s/%s", cfg.Name(), instance)
+	}
+	return cfg.Name()
 }
 
-// integrationKey returns the key for an integration Config, used for its
-// instance name and name in the process cache.
-func integrationKey(name string) string {
+// integrationKey returns the key for an instance of an integration, used for
+// its instance name and name in the process cache. instance is empty for
+// integrations without an instance name.
+func integrationKey(name, instance string) string {
+	if instance != "" {
+		// Not using a slash keeps the WAL of every instance directly in the
+		// integration directory of the WAL.
+		return fmt.Sprintf("integration/%s-%s", name, instance)
+	}
 	return fmt.Sprintf("integration/%s", name)
 }
 
-func (m *Manager) scrapeServiceDiscovery(cfg ManagerConfig) discovery.Configs {
+// scrapeServiceDiscovery returns the service discovery config for scraping
+// integrations from the Agent. extraLabels are added to the target after the
+// labels of cfg.
+func (m *Manager) scrapeServiceDiscovery(cfg ManagerConfig, extraLabels model.LabelSet) discovery.Configs {
 	// A blank host somehow works, but it then requires a sever name to be set under tls.
 	newHost := cfg.ListenHost
 	if newHost == "" {
 		newHost = "127.0.0.1"
 	}
 	localAddr := fmt.Sprintf("%s:%d", newHost, cfg.ListenPort)
+	if cfg.Listener.ListenAddress != "" {
+		localAddr = cfg.Listener.scrapeAddress()
+	}
 	labels := model.LabelSet{}
 	if cfg.UseHostnameLabel {
 		labels[model.LabelName("agent_hostname")] = model.LabelValue(m.hostname)
@@ -415,6 +639,9 @@ func (m *Manager) scrapeServiceDiscovery(cfg ManagerConfig) discovery.Configs {
 	for k, v := range cfg.Labels {
 		labels[k] = v
 	}
+	for k, v := range extraLabels {
+		labels[k] = v
+	}
 
 	return discovery.Configs{
 		discovery.StaticConfig{{
@@ -424,8 +651,15 @@ func (m *Manager) scrapeServiceDiscovery(cfg ManagerConfig) discovery.Configs {
 	}
 }
 
-// WireAPI hooks up /metrics routes per-integration.
+// WireAPI hooks up /metrics routes per-integration. The routes return 404
+// when a separate listener is configured for integrations.
 func (m *Manager) WireAPI(r *mux.Router) {
+	m.wireRoutes(r, false)
+}
+
+// wireRoutes hooks up /metrics routes per-integration. separateListener is
+// true when r is the router of the separate listener.
+func (m *Manager) wireRoutes(r *mux.Router, separateListener bool) {
 	type handlerCacheEntry struct {
 		handler http.Handler
 		process *integrationProcess
@@ -459,7 +693,7 @@ func (m *Manager) WireAPI(r *mux.Router) {
 		// a handler for it and cache it.
 		handler, err := p.i.MetricsHandler()
 		if err != nil {
-			level.Error(m.logger).Log("msg", "could not create http handler for integration", "integration", p.cfg.Name(), "err", err)
+			level.Error(m.logger).Log("msg", "could not create http handler for integration", "integration", integrationID(p.cfg), "err", err)
 			return http.HandlerFunc(internalServiceError)
 		}
 
@@ -468,13 +702,40 @@ func (m *Manager) WireAPI(r *mux.Router) {
 		return cacheEntry.handler
 	}
 
-	r.HandleFunc("/integrations/{name}/metrics", func(rw http.ResponseWriter, r *http.Request) {
+	serveMetrics := func(rw http.ResponseWriter, r *http.Request) {
 		m.integrationsMut.RLock()
 		defer m.integrationsMut.RUnlock()
 
-		key := integrationKey(mux.Vars(r)["name"])
+		vars := mux.Vars(r)
+		key := integrationKey(vars["name"], vars["instance"])
 		handler := loadHandler(key)
 		handler.ServeHTTP(rw, r)
+	}
+	authorized := m.authorize(separateListener, http.HandlerFunc(serveMetrics))
+	r.Handle("/integrations/{name}/metrics", authorized)
+	r.Handle("/integrations/{name}/{instance}/metrics", authorized)
+}
+
+// authorize wraps the endpoints of integrations, only serving them from the
+// listener they're configured to use and requiring basic auth if configured.
+func (m *Manager) authorize(separateListener bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		m.cfgMut.RLock()
+		var (
+			useSeparate = m.cfg.Listener.ListenAddress != ""
+			auth        = m.cfg.Listener.BasicAuth
+			password    = m.authPassword
+		)
+		m.cfgMut.RUnlock()
+
+		switch {
+		case useSeparate != separateListener:
+			http.NotFound(rw, r)
+		case auth != nil:
+			basicAuthHandler(auth.Username, password, next).ServeHTTP(rw, r)
+		default:
+			next.ServeHTTP(rw, r)
+		}
 	})
 }
 
@@ -487,4 +748,11 @@ func internalServiceError(w http.ResponseWriter, r *http.Request) {
 func (m *Manager) Stop() {
 	m.cancel()
 	m.wg.Wait()
+
+	m.cfgMut.Lock()
+	defer m.cfgMut.Unlock()
+	if m.listener != nil {
+		_ = m.listener.Close()
+		m.listener = nil
+	}
 }
@@ -4,14 +4,21 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/cortexproject/cortex/pkg/util/test"
 	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/health"
 	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/grafana/agent/pkg/prom"
 	"github.com/grafana/agent/pkg/prom/instance"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
+	prom_config "github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/relabel"
 	"github.com/stretchr/testify/require"
@@ -79,6 +86,29 @@ test:
 	require.YAMLEq(t, cfgText, string(outBytes))
 }
 
+// Test that multiple instances of an integration are remarshaled as a list.
+func TestConfig_RemarshalInstances(t *testing.T) {
+	RegisterIntegration(&testIntegrationC{})
+	cfgText := `
+scrape_integrations: true
+replace_instance_label: true
+integration_restart_backoff: 5s
+use_hostname_label: true
+multi:
+- instance: a
+  text: first
+- instance: b
+  text: second
+`
+	var cfg ManagerConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfgText), &cfg))
+	require.Len(t, cfg.Integrations, 2)
+
+	outBytes, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	require.YAMLEq(t, cfgText, string(outBytes))
+}
+
 func TestConfig_AddressRelabels(t *testing.T) {
 	cfgText := `
 agent:
@@ -116,13 +146,271 @@ func TestManager_instanceConfigForIntegration(t *testing.T) {
 	require.NoError(t, err)
 	defer m.Stop()
 
-	cfg := m.instanceConfigForIntegration(icfg, mock, mockManagerConfig())
+	cfg, err := m.instanceConfigForIntegration(icfg, mock, mockManagerConfig())
+	require.NoError(t, err)
 
 	// Validate that the generated MetricsPath is a valid URL path
 	require.Len(t, cfg.ScrapeConfigs, 1)
 	require.Equal(t, "/integrations/mock/metrics", cfg.ScrapeConfigs[0].MetricsPath)
 }
 
+// TestManager_instanceConfigForIntegration_Overrides ensures that the scrape
+// settings of an integration override the defaults of its scrape config.
+func TestManager_instanceConfigForIntegration_Overrides(t *testing.T) {
+	mock := newMockIntegration()
+	mock.commonCfg.ScrapeInterval = 5 * time.Minute
+	mock.commonCfg.ScrapeTimeout = 30 * time.Second
+	mock.commonCfg.MetricRelabelConfigs = []*relabel.Config{{
+		SourceLabels: model.LabelNames{"__name__"},
+		Regex:        relabel.MustNewRegexp("go_.*"),
+		Action:       relabel.Drop,
+	}}
+	icfg := mockConfig{integration: mock}
+
+	im := instance.NewBasicManager(instance.DefaultBasicManagerConfig, log.NewNopLogger(), mockInstanceFactory)
+	m, err := NewManager(mockManagerConfig(), log.NewNopLogger(), im, noOpValidator)
+	require.NoError(t, err)
+	defer m.Stop()
+
+	cfg, err := m.instanceConfigForIntegration(icfg, mock, mockManagerConfig())
+	require.NoError(t, err)
+	require.Len(t, cfg.ScrapeConfigs, 1)
+
+	sc := cfg.ScrapeConfigs[0]
+	require.Equal(t, model.Duration(5*time.Minute), sc.ScrapeInterval)
+	require.Equal(t, model.Duration(30*time.Second), sc.ScrapeTimeout)
+	require.Equal(t, mock.commonCfg.MetricRelabelConfigs, sc.MetricRelabelConfigs)
+}
+
+// TestManager_instanceConfigForIntegration_Instance ensures that named
+// instances get unique job names while keeping the job label of the
+// integration.
+func TestManager_instanceConfigForIntegration_Instance(t *testing.T) {
+	mock := newMockIntegration()
+	mock.commonCfg.Instance = "primary"
+	mock.commonCfg.Autoscrape.ExtraLabels = model.LabelSet{"team": "a"}
+	icfg := mockConfig{integration: mock}
+
+	mcfg := mockManagerConfig()
+	mcfg.ReplaceInstanceLabel = true
+
+	im := instance.NewBasicManager(instance.DefaultBasicManagerConfig, log.NewNopLogger(), mockInstanceFactory)
+	m, err := NewManager(mcfg, log.NewNopLogger(), im, noOpValidator)
+	require.NoError(t, err)
+	defer m.Stop()
+
+	cfg, err := m.instanceConfigForIntegration(icfg, mock, mcfg)
+	require.NoError(t, err)
+	require.Equal(t, "integration/mock-primary", cfg.Name)
+	require.Len(t, cfg.ScrapeConfigs, 1)
+
+	sc := cfg.ScrapeConfigs[0]
+	require.Equal(t, "integrations/mock/primary", sc.JobName)
+	require.Equal(t, "/integrations/mock/primary/metrics", sc.MetricsPath)
+	require.Empty(t, sc.RelabelConfigs, "instance label should not be replaced")
+
+	sd := sc.ServiceDiscoveryConfigs[0].(discovery.StaticConfig)
+	require.Equal(t, model.LabelSet{
+		"job":      "integrations/mock",
+		"instance": "primary",
+		"team":     "a",
+	}, sd[0].Labels)
+}
+
+func TestManager_instanceConfigForIntegration_MetricsInstance(t *testing.T) {
+	mock := newMockIntegration()
+	mock.commonCfg.Autoscrape.MetricsInstance = "team-a"
+	mock.commonCfg.WALTruncateFrequency = 5 * time.Minute
+	icfg := mockConfig{integration: mock}
+
+	metricsInstance := instance.DefaultConfig
+	metricsInstance.Name = "team-a"
+	metricsInstance.WriteStaleOnShutdown = true
+	metricsInstance.RemoteWrite = []*instance.RemoteWriteConfig{{RemoteWriteConfig: prom_config.RemoteWriteConfig{Name: "team-a-123456"}}}
+
+	mcfg := mockManagerConfig()
+	mcfg.PrometheusRemoteWrite = []*instance.RemoteWriteConfig{{RemoteWriteConfig: prom_config.RemoteWriteConfig{Name: "integrations"}}}
+	mcfg.PrometheusConfigs = []instance.Config{metricsInstance}
+
+	im := instance.NewBasicManager(instance.DefaultBasicManagerConfig, log.NewNopLogger(), mockInstanceFactory)
+	m, err := NewManager(mcfg, log.NewNopLogger(), im, noOpValidator)
+	require.NoError(t, err)
+	defer m.Stop()
+
+	cfg, err := m.instanceConfigForIntegration(icfg, mock, mcfg)
+	require.NoError(t, err)
+	require.Equal(t, "integration/mock", cfg.Name)
+	require.True(t, cfg.WriteStaleOnShutdown)
+	require.Equal(t, 5*time.Minute, cfg.WALTruncateFrequency)
+	require.Len(t, cfg.ScrapeConfigs, 1)
+
+	// Remote write names should be generated again for the integration.
+	require.Equal(t, []*instance.RemoteWriteConfig{{}}, cfg.RemoteWrite)
+	require.Equal(t, "team-a-123456", metricsInstance.RemoteWrite[0].Name)
+
+	mock.commonCfg.Autoscrape.MetricsInstance = "team-b"
+	_, err = m.instanceConfigForIntegration(icfg, mock, mcfg)
+	require.EqualError(t, err, `metrics instance "team-b" not found`)
+}
+
+func TestManager_MultipleInstances(t *testing.T) {
+	var (
+		mockA = newMockIntegration()
+		mockB = newMockIntegration()
+	)
+	mockA.commonCfg.Instance = "a"
+	mockB.commonCfg.Instance = "b"
+
+	cfg := mockManagerConfig()
+	cfg.Integrations = append(cfg.Integrations, mockConfig{integration: mockA}, mockConfig{integration: mockB})
+
+	im := instance.NewBasicManager(instance.DefaultBasicManagerConfig, log.NewNopLogger(), mockInstanceFactory)
+	m, err := NewManager(cfg, log.NewNopLogger(), im, noOpValidator)
+	require.NoError(t, err)
+	defer m.Stop()
+
+	test.Poll(t, time.Second, 2, func() interface{} {
+		return len(im.ListConfigs())
+	})
+	require.Contains(t, im.ListConfigs(), "integration/mock-a")
+	require.Contains(t, im.ListConfigs(), "integration/mock-b")
+
+	test.Poll(t, time.Second, 2, func() interface{} {
+		return int(mockA.startedCount.Load() + mockB.startedCount.Load())
+	})
+	require.Equal(t, []health.Component{
+		{Name: "mock/a", Healthy: true, Ready: true},
+		{Name: "mock/b", Healthy: true, Ready: true},
+	}, m.Health().Components)
+
+	r := mux.NewRouter()
+	m.WireAPI(r)
+	for path, code := range map[string]int{
+		"/integrations/mock/a/metrics": http.StatusOK,
+		"/integrations/mock/b/metrics": http.StatusOK,
+		"/integrations/mock/c/metrics": http.StatusNotFound,
+		"/integrations/mock/metrics":   http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, code, rec.Code, path)
+	}
+
+	// Removing an instance should only stop that instance.
+	cfg.Integrations = cfg.Integrations[:1]
+	require.NoError(t, m.ApplyConfig(cfg))
+	require.Equal(t, []string{"integration/mock-a"}, keys(im.ListConfigs()))
+	test.Poll(t, time.Second, false, func() interface{} {
+		return mockB.running.Load()
+	})
+	require.True(t, mockA.running.Load())
+}
+
+func keys(cfgs map[string]instance.Config) []string {
+	out := make([]string, 0, len(cfgs))
+	for k := range cfgs {
+		out = append(out, k)
+	}
+	return out
+}
+
+func TestManagerConfig_ApplyDefaults_ScrapeTimeout(t *testing.T) {
+	promCfg := prom.DefaultConfig
+	promCfg.WALDir = "/tmp/wal"
+	promCfg.Global.Prometheus.ScrapeInterval = model.Duration(time.Minute)
+
+	tt := []struct {
+		name      string
+		interval  time.Duration
+		timeout   time.Duration
+		expectErr string
+	}{
+		{name: "defaults"},
+		{name: "timeout within global interval", timeout: 30 * time.Second},
+		{name: "timeout within interval", interval: 5 * time.Minute, timeout: 2 * time.Minute},
+		{
+			name:      "timeout greater than global interval",
+			timeout:   2 * time.Minute,
+			expectErr: "integration mock: scrape_timeout (2m0s) must not be greater than scrape_interval (1m0s)",
+		},
+		{
+			name:      "timeout greater than interval",
+			interval:  10 * time.Second,
+			timeout:   30 * time.Second,
+			expectErr: "integration mock: scrape_timeout (30s) must not be greater than scrape_interval (10s)",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := newMockIntegration()
+			mock.commonCfg.Enabled = true
+			mock.commonCfg.ScrapeInterval = tc.interval
+			mock.commonCfg.ScrapeTimeout = tc.timeout
+
+			cfg := mockManagerConfig()
+			cfg.Integrations = append(cfg.Integrations, mockConfig{integration: mock})
+
+			err := cfg.ApplyDefaults(&promCfg)
+			if tc.expectErr != "" {
+				require.EqualError(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestManagerConfig_ApplyDefaults_Autoscrape(t *testing.T) {
+	promCfg := prom.DefaultConfig
+	promCfg.WALDir = "/tmp/wal"
+	promCfg.Configs = []instance.Config{{Name: "team-a"}}
+
+	tt := []struct {
+		name       string
+		autoscrape config.Autoscrape
+		expectErr  string
+	}{
+		{name: "defaults"},
+		{
+			name: "valid",
+			autoscrape: config.Autoscrape{
+				MetricsInstance: "team-a",
+				ExtraLabels:     model.LabelSet{"team": "a"},
+			},
+		},
+		{
+			name:       "unknown metrics instance",
+			autoscrape: config.Autoscrape{MetricsInstance: "team-b"},
+			expectErr:  `integration mock: autoscrape.metrics_instance "team-b" does not match any prometheus instance config`,
+		},
+		{
+			name:       "invalid extra labels",
+			autoscrape: config.Autoscrape{ExtraLabels: model.LabelSet{"not-valid": "a"}},
+			expectErr:  `integration mock: invalid autoscrape.extra_labels: invalid name "not-valid"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := newMockIntegration()
+			mock.commonCfg.Enabled = true
+			mock.commonCfg.Autoscrape = tc.autoscrape
+
+			cfg := mockManagerConfig()
+			cfg.Integrations = append(cfg.Integrations, mockConfig{integration: mock})
+
+			err := cfg.ApplyDefaults(&promCfg)
+			if tc.expectErr != "" {
+				require.EqualError(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, promCfg.Configs, cfg.PrometheusConfigs)
+			}
+		})
+	}
+}
+
 // TestManager_NoIntegrationsScrape ensures that configs don't get generates
 // when the ScrapeIntegrations flag is disabled.
 func TestManager_NoIntegrationsScrape(t *testing.T) {
@@ -211,6 +499,41 @@ func TestManager_RestartsIntegrations(t *testing.T) {
 	})
 }
 
+func TestManager_Health(t *testing.T) {
+	mock := newMockIntegration()
+	icfg := mockConfig{integration: mock}
+
+	cfg := mockManagerConfig()
+	cfg.IntegrationRestartBackoff = 500 * time.Millisecond
+	cfg.Integrations = append(cfg.Integrations, icfg)
+
+	im := instance.NewBasicManager(instance.DefaultBasicManagerConfig, log.NewNopLogger(), mockInstanceFactory)
+	m, err := NewManager(cfg, log.NewNopLogger(), im, noOpValidator)
+	require.NoError(t, err)
+	defer m.Stop()
+
+	test.Poll(t, time.Second, 1, func() interface{} {
+		return int(mock.startedCount.Load())
+	})
+	require.Equal(t, health.Subsystem{
+		Name:       "integrations",
+		Healthy:    true,
+		Ready:      true,
+		Components: []health.Component{{Name: "mock", Healthy: true, Ready: true}},
+	}, m.Health())
+
+	mock.err <- fmt.Errorf("something went wrong")
+	test.Poll(t, time.Second, false, func() interface{} {
+		return m.Health().Healthy
+	})
+	require.Equal(t, "restarting after stopping abnormally: something went wrong", m.Health().Components[0].Message)
+
+	// The integration is healthy again once it restarts.
+	test.Poll(t, time.Second, true, func() interface{} {
+		return m.Health().Healthy
+	})
+}
+
 func TestManager_GracefulStop(t *testing.T) {
 	mock := newMockIntegration()
 	icfg := mockConfig{integration: mock}
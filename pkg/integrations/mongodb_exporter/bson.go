@@ -0,0 +1,266 @@
+package mongodb_exporter //nolint:golint
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// The subset of BSON (https://bsonspec.org) needed to run commands and read
+// their replies.
+
+// bsonD is an ordered document, used to encode commands since MongoDB
+// requires the name of the command to be the first key.
+type bsonD []bsonE
+
+// bsonE is an element of a bsonD.
+type bsonE struct {
+	Key   string
+	Value interface{}
+}
+
+// document is a decoded BSON document.
+type document map[string]interface{}
+
+func encodeDocument(d bsonD) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeDocument(&buf, d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeDocument(buf *bytes.Buffer, d bsonD) error {
+	start := buf.Len()
+	buf.Write([]byte{0, 0, 0, 0}) // Length, filled in below.
+
+	for _, e := range d {
+		if err := writeElement(buf, e.Key, e.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(0)
+
+	binary.LittleEndian.PutUint32(buf.Bytes()[start:], uint32(buf.Len()-start))
+	return nil
+}
+
+func writeElement(buf *bytes.Buffer, key string, v interface{}) error {
+	writeKey := func(typ byte) {
+		buf.WriteByte(typ)
+		buf.WriteString(key)
+		buf.WriteByte(0)
+	}
+
+	switch v := v.(type) {
+	case float64:
+		writeKey(0x01)
+		_ = binary.Write(buf, binary.LittleEndian, math.Float64bits(v))
+	case string:
+		writeKey(0x02)
+		_ = binary.Write(buf, binary.LittleEndian, int32(len(v)+1))
+		buf.WriteString(v)
+		buf.WriteByte(0)
+	case bsonD:
+		writeKey(0x03)
+		return writeDocument(buf, v)
+	case []interface{}:
+		writeKey(0x04)
+		arr := make(bsonD, len(v))
+		for i := range v {
+			arr[i] = bsonE{strconv.Itoa(i), v[i]}
+		}
+		return writeDocument(buf, arr)
+	case []byte:
+		writeKey(0x05)
+		_ = binary.Write(buf, binary.LittleEndian, int32(len(v)))
+		buf.WriteByte(0) // Generic binary subtype.
+		buf.Write(v)
+	case bool:
+		writeKey(0x08)
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case int32:
+		writeKey(0x10)
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	case int:
+		writeKey(0x12)
+		_ = binary.Write(buf, binary.LittleEndian, int64(v))
+	case int64:
+		writeKey(0x12)
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	default:
+		return fmt.Errorf("cannot encode %T as BSON", v)
+	}
+	return nil
+}
+
+var errTruncated = errors.New("truncated BSON document")
+
+// decodeDocument decodes a BSON document. Types with no use for metrics,
+// such as ObjectIds, regular expressions, and decimals, decode to nil.
+func decodeDocument(b []byte) (document, error) {
+	doc := make(document)
+	err := decodeElements(b, func(key string, v interface{}) { doc[key] = v })
+	return doc, err
+}
+
+func decodeArray(b []byte) ([]interface{}, error) {
+	var arr []interface{}
+	err := decodeElements(b, func(_ string, v interface{}) { arr = append(arr, v) })
+	return arr, err
+}
+
+func decodeElements(b []byte, add func(key string, v interface{})) error {
+	if len(b) < 5 {
+		return errTruncated
+	}
+	size := int(binary.LittleEndian.Uint32(b))
+	if size < 5 || size > len(b) {
+		return errTruncated
+	}
+	b = b[4 : size-1]
+
+	for len(b) > 0 {
+		typ := b[0]
+		end := bytes.IndexByte(b[1:], 0)
+		if end < 0 {
+			return errTruncated
+		}
+		key := string(b[1 : end+1])
+		b = b[end+2:]
+
+		v, n, err := decodeValue(typ, b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		add(key, v)
+		b = b[n:]
+	}
+	return nil
+}
+
+// decodeValue decodes a value of type typ at the start of b, returning the
+// value and how many bytes it used.
+func decodeValue(typ byte, b []byte) (v interface{}, n int, err error) {
+	fixed := func(size int) ([]byte, error) {
+		if len(b) < size {
+			return nil, errTruncated
+		}
+		return b[:size], nil
+	}
+	// sized returns the size of a value prefixed by its length.
+	sized := func(extra int) (int, error) {
+		if len(b) < 4 {
+			return 0, errTruncated
+		}
+		size := int(int32(binary.LittleEndian.Uint32(b))) + extra
+		if size < 4 || size > len(b) {
+			return 0, errTruncated
+		}
+		return size, nil
+	}
+
+	switch typ {
+	case 0x01: // Double
+		bb, err := fixed(8)
+		if err != nil {
+			return nil, 0, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(bb)), 8, nil
+	case 0x02, 0x0D, 0x0E: // String, JavaScript, Symbol
+		size, err := sized(4)
+		if err != nil || size < 5 {
+			return nil, 0, errTruncated
+		}
+		return string(b[4 : size-1]), size, nil
+	case 0x03: // Document
+		size, err := sized(0)
+		if err != nil {
+			return nil, 0, err
+		}
+		doc, err := decodeDocument(b[:size])
+		return doc, size, err
+	case 0x04: // Array
+		size, err := sized(0)
+		if err != nil {
+			return nil, 0, err
+		}
+		arr, err := decodeArray(b[:size])
+		return arr, size, err
+	case 0x05: // Binary
+		size, err := sized(5)
+		if err != nil {
+			return nil, 0, err
+		}
+		return append([]byte(nil), b[5:size]...), size, nil
+	case 0x06, 0x0A, 0x7F, 0xFF: // Undefined, Null, MaxKey, MinKey
+		return nil, 0, nil
+	case 0x07: // ObjectId
+		_, err := fixed(12)
+		return nil, 12, err
+	case 0x08: // Boolean
+		bb, err := fixed(1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return bb[0] != 0, 1, nil
+	case 0x09: // UTC datetime
+		bb, err := fixed(8)
+		if err != nil {
+			return nil, 0, err
+		}
+		ms := int64(binary.LittleEndian.Uint64(bb))
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC(), 8, nil
+	case 0x0B: // Regular expression, made of two cstrings.
+		n := 0
+		for i := 0; i < 2; i++ {
+			end := bytes.IndexByte(b[n:], 0)
+			if end < 0 {
+				return nil, 0, errTruncated
+			}
+			n += end + 1
+		}
+		return nil, n, nil
+	case 0x0C: // DBPointer
+		size, err := sized(4)
+		if err != nil {
+			return nil, 0, err
+		}
+		_, err = fixed(size + 12)
+		return nil, size + 12, err
+	case 0x0F: // JavaScript with scope
+		size, err := sized(0)
+		return nil, size, err
+	case 0x10: // Int32
+		bb, err := fixed(4)
+		if err != nil {
+			return nil, 0, err
+		}
+		return int32(binary.LittleEndian.Uint32(bb)), 4, nil
+	case 0x11: // Timestamp
+		bb, err := fixed(8)
+		if err != nil {
+			return nil, 0, err
+		}
+		return binary.LittleEndian.Uint64(bb), 8, nil
+	case 0x12: // Int64
+		bb, err := fixed(8)
+		if err != nil {
+			return nil, 0, err
+		}
+		return int64(binary.LittleEndian.Uint64(bb)), 8, nil
+	case 0x13: // Decimal128
+		_, err := fixed(16)
+		return nil, 16, err
+	default:
+		return nil, 0, fmt.Errorf("unknown BSON type 0x%02x", typ)
+	}
+}
@@ -0,0 +1,342 @@
+package mongodb_exporter //nolint:golint
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	promconfig "github.com/prometheus/common/config"
+)
+
+// systemDatabases aren't discovered by the collstats collector.
+var systemDatabases = map[string]bool{"admin": true, "config": true, "local": true}
+
+type collector struct {
+	log       log.Logger
+	cfg       *Config
+	target    *target
+	tlsConfig *tls.Config
+
+	up *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config, uri string) (*collector, error) {
+	t, err := parseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" {
+		t.username = c.Username
+	}
+	if c.Password != "" {
+		t.password = string(c.Password)
+	}
+
+	col := &collector{
+		log:    l,
+		cfg:    c,
+		target: t,
+
+		up: prometheus.NewDesc("mongodb_up", "Whether the MongoDB server could be queried.", nil, nil),
+	}
+
+	if c.TLSConfig != nil {
+		if col.tlsConfig, err = promconfig.NewTLSConfig(c.TLSConfig); err != nil {
+			return nil, fmt.Errorf("invalid tls_config: %w", err)
+		}
+	} else if t.tls {
+		col.tlsConfig = &tls.Config{}
+	}
+	if col.tlsConfig != nil && col.tlsConfig.ServerName == "" {
+		col.tlsConfig.ServerName = strings.Split(t.host, ":")[0]
+	}
+	return col, nil
+}
+
+// Describe implements prometheus.Collector. Nothing is sent since metrics
+// are only known once the server replies, making collector an unchecked
+// collector.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	e := newEmitter(ch)
+	if err := c.collect(ctx, e); err != nil {
+		level.Error(c.log).Log("msg", "failed to collect MongoDB metrics", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+}
+
+// collect runs the commands of every enabled collector. Failures of the
+// optional collectors are logged without failing the scrape.
+func (c *collector) collect(ctx context.Context, e *emitter) error {
+	t := *c.target
+	if c.cfg.PasswordFile != "" {
+		bb, err := ioutil.ReadFile(c.cfg.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("unable to read password file %s: %w", c.cfg.PasswordFile, err)
+		}
+		t.password = strings.TrimSpace(string(bb))
+	}
+
+	conn, err := dial(ctx, &t, c.tlsConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	status, err := conn.run("admin", bsonD{{"serverStatus", int32(1)}})
+	if err != nil {
+		return err
+	}
+	e.flatten("mongodb_ss", "serverStatus", status, nil, nil)
+
+	if c.cfg.EnableDBStats || c.cfg.EnableCollStats {
+		databases, err := listDatabases(conn)
+		if err != nil {
+			level.Warn(c.log).Log("msg", "failed to list databases", "err", err)
+		}
+		if c.cfg.EnableDBStats {
+			for _, db := range databases {
+				stats, err := conn.run(db, bsonD{{"dbStats", int32(1)}})
+				if err != nil {
+					level.Warn(c.log).Log("msg", "failed to get database stats", "database", db, "err", err)
+					continue
+				}
+				e.flatten("mongodb_dbstats", "dbStats", stats, []string{"database"}, []string{db})
+			}
+		}
+		if c.cfg.EnableCollStats {
+			c.collectCollStats(conn, e, databases)
+		}
+	}
+
+	if c.cfg.EnableTopMetrics {
+		top, err := conn.run("admin", bsonD{{"top", int32(1)}})
+		if err != nil {
+			level.Warn(c.log).Log("msg", "failed to get top metrics", "err", err)
+		} else {
+			emitTop(e, top)
+		}
+	}
+	return nil
+}
+
+func listDatabases(conn *conn) ([]string, error) {
+	reply, err := conn.run("admin", bsonD{{"listDatabases", int32(1)}, {"nameOnly", true}})
+	if err != nil {
+		return nil, err
+	}
+	items, _ := reply["databases"].([]interface{})
+
+	var names []string
+	for _, item := range items {
+		if doc, ok := item.(document); ok {
+			if name, ok := doc["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func listCollections(conn *conn, db string) ([]string, error) {
+	docs, err := conn.listCursor(db, bsonD{
+		{"listCollections", int32(1)},
+		{"nameOnly", true},
+		{"authorizedCollections", true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, doc := range docs {
+		name, _ := doc["name"].(string)
+		if typ, _ := doc["type"].(string); name == "" || typ == "view" || strings.HasPrefix(name, "system.") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// collectCollStats collects the statistics of the configured collections,
+// or of every collection outside of the system databases.
+func (c *collector) collectCollStats(conn *conn, e *emitter, databases []string) {
+	var namespaces [][2]string
+	addDatabase := func(db string) {
+		colls, err := listCollections(conn, db)
+		if err != nil {
+			level.Warn(c.log).Log("msg", "failed to list collections", "database", db, "err", err)
+			return
+		}
+		for _, coll := range colls {
+			namespaces = append(namespaces, [2]string{db, coll})
+		}
+	}
+
+	if len(c.cfg.CollStatsCollections) == 0 {
+		for _, db := range databases {
+			if !systemDatabases[db] {
+				addDatabase(db)
+			}
+		}
+	}
+	for _, ns := range c.cfg.CollStatsCollections {
+		if db, coll, ok := cut(ns, "."); ok {
+			namespaces = append(namespaces, [2]string{db, coll})
+		} else {
+			addDatabase(db)
+		}
+	}
+
+	labelNames := []string{"database", "collection"}
+	for _, ns := range namespaces {
+		stats, err := conn.run(ns[0], bsonD{{"collStats", ns[1]}})
+		if err != nil {
+			level.Warn(c.log).Log("msg", "failed to get collection stats", "database", ns[0], "collection", ns[1], "err", err)
+			continue
+		}
+
+		// Index sizes are keyed by index name, so they're exposed as a
+		// label. Storage engine details are too verbose to be collected.
+		if sizes, ok := stats["indexSizes"].(document); ok {
+			for index, v := range sizes {
+				if f, ok := toFloat(v); ok {
+					e.emit("mongodb_collstats_index_size", "collStats.indexSizes", f,
+						[]string{"database", "collection", "index"}, []string{ns[0], ns[1], index})
+				}
+			}
+		}
+		delete(stats, "indexSizes")
+		delete(stats, "indexDetails")
+		delete(stats, "wiredTiger")
+
+		e.flatten("mongodb_collstats", "collStats", stats, labelNames, ns[:])
+	}
+}
+
+// emitTop emits the usage of every collection reported by the top command.
+func emitTop(e *emitter, top document) {
+	totals, _ := top["totals"].(document)
+	labelNames := []string{"database", "collection"}
+
+	for ns, v := range totals {
+		usage, ok := v.(document)
+		db, coll, found := cut(ns, ".")
+		if !ok || !found {
+			continue
+		}
+		for op, v := range usage {
+			counters, ok := v.(document)
+			if !ok {
+				continue
+			}
+			if f, ok := toFloat(counters["time"]); ok {
+				e.emit("mongodb_top_"+sanitize(op)+"_time", "Time spent on "+op+" operations, in microseconds.", f, labelNames, []string{db, coll})
+			}
+			if f, ok := toFloat(counters["count"]); ok {
+				e.emit("mongodb_top_"+sanitize(op)+"_count", "Number of "+op+" operations.", f, labelNames, []string{db, coll})
+			}
+		}
+	}
+}
+
+// emitter sends metrics built from command replies, dropping series which
+// were already sent. Different keys may sanitize to the same metric name, so
+// the help of a name is fixed by the first series using it.
+type emitter struct {
+	ch     chan<- prometheus.Metric
+	help   map[string]string
+	series map[string]bool
+}
+
+func newEmitter(ch chan<- prometheus.Metric) *emitter {
+	return &emitter{
+		ch:     ch,
+		help:   make(map[string]string),
+		series: make(map[string]bool),
+	}
+}
+
+func (e *emitter) emit(name, help string, v float64, labelNames, labelValues []string) {
+	key := name + "\xff" + strings.Join(labelValues, "\xff")
+	if e.series[key] {
+		return
+	}
+	e.series[key] = true
+
+	if h, ok := e.help[name]; ok {
+		help = h
+	} else {
+		e.help[name] = help
+	}
+	desc := prometheus.NewDesc(name, help, labelNames, nil)
+	e.ch <- prometheus.MustNewConstMetric(desc, prometheus.UntypedValue, v, labelValues...)
+}
+
+// flatten emits every numeric field of doc, with nested keys joined by
+// underscores. path is the dotted path of doc, used as the help of metrics.
+func (e *emitter) flatten(prefix, path string, doc document, labelNames, labelValues []string) {
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var (
+			name    = prefix + "_" + sanitize(k)
+			subpath = path + "." + k
+		)
+		switch v := doc[k].(type) {
+		case document:
+			e.flatten(name, subpath, v, labelNames, labelValues)
+		default:
+			if f, ok := toFloat(v); ok && k != "ok" {
+				e.emit(name, subpath, f, labelNames, labelValues)
+			}
+		}
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sanitize replaces characters which are invalid in metric names.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,246 @@
+package mongodb_exporter //nolint:golint
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/xdg-go/scram"
+)
+
+// opMsg is the opcode of OP_MSG, the wire protocol message used by MongoDB
+// 3.6 and later for commands and their replies.
+const opMsg = 2013
+
+// maxMessageSize is the largest reply accepted from the server.
+const maxMessageSize = 48 * 1024 * 1024
+
+// conn is a connection to a single mongod or mongos.
+type conn struct {
+	nc        net.Conn
+	r         *bufio.Reader
+	requestID int32
+}
+
+// dial connects to the server described by t, authenticating when the target
+// has credentials. The deadline of ctx applies to all commands run on the
+// connection.
+func dial(ctx context.Context, t *target, tlsConfig *tls.Config) (*conn, error) {
+	var (
+		d   net.Dialer
+		nc  net.Conn
+		err error
+	)
+	if tlsConfig != nil {
+		nc, err = (&tls.Dialer{NetDialer: &d, Config: tlsConfig}).DialContext(ctx, "tcp", t.host)
+	} else {
+		nc, err = d.DialContext(ctx, "tcp", t.host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = nc.SetDeadline(deadline)
+	}
+
+	c := &conn{nc: nc, r: bufio.NewReader(nc)}
+	if t.username != "" {
+		if err := c.authenticate(t); err != nil {
+			_ = nc.Close()
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *conn) Close() error {
+	return c.nc.Close()
+}
+
+// run runs cmd against db and returns the reply. Replies which aren't ok are
+// returned as an error.
+func (c *conn) run(db string, cmd bsonD) (document, error) {
+	body, err := encodeDocument(append(cmd, bsonE{"$db", db}))
+	if err != nil {
+		return nil, err
+	}
+
+	c.requestID++
+	header := make([]byte, 21)
+	binary.LittleEndian.PutUint32(header[0:], uint32(len(header)+len(body)))
+	binary.LittleEndian.PutUint32(header[4:], uint32(c.requestID))
+	binary.LittleEndian.PutUint32(header[12:], opMsg)
+	// Flag bits are left at zero and header[20] is the kind of the body
+	// section, which is 0.
+	if _, err := c.nc.Write(append(header, body...)); err != nil {
+		return nil, err
+	}
+
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if ok, _ := toFloat(reply["ok"]); ok != 1 {
+		msg, _ := reply["errmsg"].(string)
+		return nil, fmt.Errorf("command %s failed: %s", cmd[0].Key, msg)
+	}
+	return reply, nil
+}
+
+// readReply reads an OP_MSG reply and returns its body.
+func (c *conn) readReply() (document, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int(binary.LittleEndian.Uint32(header[0:]))
+	if size < len(header)+5 || size > maxMessageSize {
+		return nil, fmt.Errorf("invalid reply size %d", size)
+	}
+	if op := binary.LittleEndian.Uint32(header[12:]); op != opMsg {
+		return nil, fmt.Errorf("unexpected reply opcode %d", op)
+	}
+
+	msg := make([]byte, size-len(header))
+	if _, err := io.ReadFull(c.r, msg); err != nil {
+		return nil, err
+	}
+
+	// Ignore the checksum if the server sent one.
+	flags := binary.LittleEndian.Uint32(msg)
+	msg = msg[4:]
+	if flags&1 != 0 {
+		if len(msg) < 4 {
+			return nil, errTruncated
+		}
+		msg = msg[:len(msg)-4]
+	}
+
+	// Find the body section. Document sequences, which the server doesn't
+	// send in replies to the commands used here, are skipped.
+	for len(msg) > 0 {
+		kind := msg[0]
+		msg = msg[1:]
+		if len(msg) < 4 {
+			return nil, errTruncated
+		}
+		size := int(binary.LittleEndian.Uint32(msg))
+		if size > len(msg) {
+			return nil, errTruncated
+		}
+		if kind == 0 {
+			return decodeDocument(msg[:size])
+		}
+		msg = msg[size:]
+	}
+	return nil, errors.New("reply has no body")
+}
+
+// authenticate runs a SCRAM conversation with the server.
+func (c *conn) authenticate(t *target) error {
+	var (
+		client *scram.Client
+		err    error
+	)
+	switch t.mechanism {
+	case "", "SCRAM-SHA-256":
+		client, err = scram.SHA256.NewClient(t.username, t.password, "")
+	case "SCRAM-SHA-1":
+		// SCRAM-SHA-1 uses the digest of MONGODB-CR as the password.
+		digest := md5.Sum([]byte(t.username + ":mongo:" + t.password))
+		client, err = scram.SHA1.NewClientUnprepped(t.username, hex.EncodeToString(digest[:]), "")
+	default:
+		return fmt.Errorf("unsupported authMechanism %q", t.mechanism)
+	}
+	if err != nil {
+		return err
+	}
+	mechanism := t.mechanism
+	if mechanism == "" {
+		mechanism = "SCRAM-SHA-256"
+	}
+
+	conv := client.NewConversation()
+	payload, err := conv.Step("")
+	if err != nil {
+		return err
+	}
+	reply, err := c.run(t.authSource, bsonD{
+		{"saslStart", int32(1)},
+		{"mechanism", mechanism},
+		{"payload", []byte(payload)},
+		{"autoAuthorize", int32(1)},
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		var out string
+		if !conv.Done() {
+			in, _ := reply["payload"].([]byte)
+			if out, err = conv.Step(string(in)); err != nil {
+				return err
+			}
+		}
+		if done, _ := reply["done"].(bool); done {
+			if !conv.Done() {
+				return errors.New("server ended the conversation early")
+			}
+			return nil
+		}
+
+		reply, err = c.run(t.authSource, bsonD{
+			{"saslContinue", int32(1)},
+			{"conversationId", reply["conversationId"]},
+			{"payload", []byte(out)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// listCursor runs a command which returns a cursor and returns every
+// document of the cursor.
+func (c *conn) listCursor(db string, cmd bsonD) ([]document, error) {
+	reply, err := c.run(db, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []document
+	for batch := "firstBatch"; ; batch = "nextBatch" {
+		cursor, _ := reply["cursor"].(document)
+		if cursor == nil {
+			return nil, fmt.Errorf("command %s returned no cursor", cmd[0].Key)
+		}
+		items, _ := cursor[batch].([]interface{})
+		for _, item := range items {
+			if doc, ok := item.(document); ok {
+				docs = append(docs, doc)
+			}
+		}
+
+		id, _ := cursor["id"].(int64)
+		if id == 0 {
+			return docs, nil
+		}
+		ns, _ := cursor["ns"].(string)
+		reply, err = c.run(db, bsonD{
+			{"getMore", id},
+			{"collection", strings.TrimPrefix(ns, db+".")},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
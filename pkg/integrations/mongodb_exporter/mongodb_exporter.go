@@ -0,0 +1,184 @@
+// Package mongodb_exporter implements an integration which collects metrics
+// from a MongoDB server, offering the collectors of
+// https://github.com/percona/mongodb_exporter.
+package mongodb_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	promconfig "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the mongodb_exporter
+// integration.
+var DefaultConfig = Config{
+	Timeout: 10 * time.Second,
+}
+
+// Config controls the mongodb_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// URI of the server to connect to, in the format of
+	// mongodb://[user:password@]host[:port][/database][?options]. Read from
+	// $MONGODB_URI when empty.
+	URI string `yaml:"mongodb_uri,omitempty"`
+
+	// Credentials overriding the ones of the URI. PasswordFile is read on
+	// every scrape.
+	Username     string                `yaml:"username,omitempty"`
+	Password     promconfig.Secret     `yaml:"password,omitempty"`
+	PasswordFile string                `yaml:"password_file,omitempty"`
+	TLSConfig    *promconfig.TLSConfig `yaml:"tls_config,omitempty"`
+
+	// Optional collectors, which run additional commands on every scrape.
+	EnableDBStats    bool `yaml:"enable_dbstats,omitempty"`
+	EnableCollStats  bool `yaml:"enable_collstats,omitempty"`
+	EnableTopMetrics bool `yaml:"enable_topmetrics,omitempty"`
+
+	// Namespaces collected by the collstats collector, either database or
+	// database.collection. All collections are collected when empty.
+	CollStatsCollections []string `yaml:"collstats_colls,omitempty"`
+
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.Password != "" && c.PasswordFile != "" {
+		return fmt.Errorf("at most one of password and password_file must be configured")
+	}
+	if c.URI != "" {
+		if _, err := parseURI(c.URI); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "mongodb_exporter"
+}
+
+// CommonConfig returns the common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new mongodb_exporter integration. The integration connects
+// to the server on every scrape.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	uri := c.URI
+	if uri == "" {
+		uri = os.Getenv("MONGODB_URI")
+	}
+	if uri == "" {
+		return nil, fmt.Errorf("cannot create mongodb_exporter; neither mongodb_exporter.mongodb_uri or $MONGODB_URI is set")
+	}
+
+	col, err := newCollector(l, c, uri)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
+
+// target is the server described by a URI.
+type target struct {
+	host       string
+	username   string
+	password   string
+	authSource string
+	mechanism  string
+	tls        bool
+}
+
+// parseURI parses a mongodb:// URI. Only the first host of the URI is
+// used, since metrics are collected from a single server.
+func parseURI(uri string) (*target, error) {
+	const scheme = "mongodb://"
+	if !strings.HasPrefix(uri, scheme) {
+		return nil, fmt.Errorf("invalid mongodb_uri: must start with %s", scheme)
+	}
+	rest := strings.TrimPrefix(uri, scheme)
+
+	var t target
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		user, password, _ := cut(rest[:i], ":")
+		var err error
+		if t.username, err = url.PathUnescape(user); err != nil {
+			return nil, fmt.Errorf("invalid mongodb_uri: %w", err)
+		}
+		if t.password, err = url.PathUnescape(password); err != nil {
+			return nil, fmt.Errorf("invalid mongodb_uri: %w", err)
+		}
+		rest = rest[i+1:]
+	}
+
+	hosts, rest, _ := cut(rest, "/")
+	database, rawQuery, _ := cut(rest, "?")
+	if h, q, ok := cut(hosts, "?"); ok {
+		hosts, rawQuery = h, q
+	}
+
+	host, _, _ := cut(hosts, ",")
+	if host == "" {
+		return nil, fmt.Errorf("invalid mongodb_uri: missing host")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "27017")
+	}
+	t.host = host
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mongodb_uri: %w", err)
+	}
+	t.authSource = query.Get("authSource")
+	if t.authSource == "" {
+		t.authSource = database
+	}
+	if t.authSource == "" {
+		t.authSource = "admin"
+	}
+	t.mechanism = query.Get("authMechanism")
+	switch t.mechanism {
+	case "", "SCRAM-SHA-1", "SCRAM-SHA-256":
+	default:
+		return nil, fmt.Errorf("invalid mongodb_uri: unsupported authMechanism %q", t.mechanism)
+	}
+	t.tls = query.Get("tls") == "true" || query.Get("ssl") == "true"
+	return &t, nil
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,129 @@
+package nginx_exporter //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+// stubStatus holds the values of a stub_status page.
+type stubStatus struct {
+	Active, Accepted, Handled, Requests, Reading, Writing, Waiting int64
+}
+
+// parseStubStatus parses the output of the stub_status module:
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func parseStubStatus(r io.Reader) (stubStatus, error) {
+	var s stubStatus
+	_, err := fmt.Fscanf(r,
+		"Active connections: %d\nserver accepts handled requests\n%d %d %d\nReading: %d Writing: %d Waiting: %d",
+		&s.Active, &s.Accepted, &s.Handled, &s.Requests, &s.Reading, &s.Writing, &s.Waiting,
+	)
+	if err != nil {
+		return s, fmt.Errorf("invalid stub_status response: %w", err)
+	}
+	return s, nil
+}
+
+type collector struct {
+	log     log.Logger
+	uri     string
+	client  *http.Client
+	timeout time.Duration
+
+	up          *prometheus.Desc
+	connections *prometheus.Desc
+	accepted    *prometheus.Desc
+	handled     *prometheus.Desc
+	requests    *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	tlsConfig, err := config_util.NewTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &collector{
+		log:     l,
+		uri:     c.ScrapeURI,
+		timeout: c.Timeout,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+
+		up:          prometheus.NewDesc("nginx_up", "Status of the last metric scrape.", nil, nil),
+		connections: prometheus.NewDesc("nginx_connections", "Client connections by state.", []string{"state"}, nil),
+		accepted:    prometheus.NewDesc("nginx_connections_accepted", "Accepted client connections.", nil, nil),
+		handled:     prometheus.NewDesc("nginx_connections_handled", "Handled client connections.", nil, nil),
+		requests:    prometheus.NewDesc("nginx_http_requests_total", "Total http requests.", nil, nil),
+	}, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.connections
+	ch <- c.accepted
+	ch <- c.handled
+	ch <- c.requests
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	s, err := c.fetch(ctx)
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to get stub_status", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+	for state, v := range map[string]int64{
+		"active":  s.Active,
+		"reading": s.Reading,
+		"writing": s.Writing,
+		"waiting": s.Waiting,
+	} {
+		ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(v), state)
+	}
+	ch <- prometheus.MustNewConstMetric(c.accepted, prometheus.CounterValue, float64(s.Accepted))
+	ch <- prometheus.MustNewConstMetric(c.handled, prometheus.CounterValue, float64(s.Handled))
+	ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(s.Requests))
+}
+
+func (c *collector) fetch(ctx context.Context) (stubStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.uri, nil)
+	if err != nil {
+		return stubStatus{}, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return stubStatus{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return stubStatus{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return parseStubStatus(resp.Body)
+}
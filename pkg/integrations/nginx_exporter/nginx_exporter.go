@@ -0,0 +1,86 @@
+// Package nginx_exporter implements an integration which collects the
+// stub_status metrics of NGINX, using the metric names of
+// https://github.com/nginxinc/nginx-prometheus-exporter.
+package nginx_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig is the default config for nginx_exporter.
+var DefaultConfig = Config{
+	ScrapeURI: "http://127.0.0.1:8080/stub_status",
+	Timeout:   5 * time.Second,
+}
+
+// Config controls the nginx_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// ScrapeURI is the URI of the stub_status page.
+	ScrapeURI string `yaml:"scrape_uri,omitempty"`
+
+	// TLSConfig is used when ScrapeURI uses https.
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+
+	// Timeout of requesting the stub_status page.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.ScrapeURI)
+	if err != nil {
+		return fmt.Errorf("invalid scrape_uri: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scrape_uri has unsupported scheme %q", u.Scheme)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "nginx_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new nginx_exporter integration. The stub_status page is
+// requested each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
@@ -0,0 +1,61 @@
+package nginx_exporter //nolint:golint
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stub_status" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(rw, "Active connections: 291 \nserver accepts handled requests\n 16630948 16630947 31070465 \nReading: 6 Writing: 179 Waiting: 106 \n")
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig
+	cfg.ScrapeURI = srv.URL + "/stub_status"
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(col))
+
+	expect := `
+# HELP nginx_connections Client connections by state.
+# TYPE nginx_connections gauge
+nginx_connections{state="active"} 291
+nginx_connections{state="reading"} 6
+nginx_connections{state="waiting"} 106
+nginx_connections{state="writing"} 179
+# HELP nginx_connections_accepted Accepted client connections.
+# TYPE nginx_connections_accepted counter
+nginx_connections_accepted 1.6630948e+07
+# HELP nginx_connections_handled Handled client connections.
+# TYPE nginx_connections_handled counter
+nginx_connections_handled 1.6630947e+07
+# HELP nginx_http_requests_total Total http requests.
+# TYPE nginx_http_requests_total counter
+nginx_http_requests_total 3.1070465e+07
+# HELP nginx_up Status of the last metric scrape.
+# TYPE nginx_up gauge
+nginx_up 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect)))
+
+	cfg.ScrapeURI = srv.URL + "/missing"
+	col, err = newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+	// Only nginx_up is reported when the page can't be read.
+	require.Equal(t, 0.0, testutil.ToFloat64(col))
+}
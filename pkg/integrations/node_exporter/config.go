@@ -25,6 +25,8 @@ var (
 
 		DiskStatsIgnoredDevices: "^(ram|loop|fd|(h|s|v|xv)d[a-z]|nvme\\d+n\\d+p)\\d+$",
 
+		FilesystemMountTimeout: 5 * time.Second,
+
 		NetclassIgnoredDevices: "^$",
 		NetstatFields:          "^(.*_(InErrors|InErrs)|Ip_Forwarding|Ip(6|Ext)_(InOctets|OutOctets)|Icmp6?_(InMsgs|OutMsgs)|TcpExt_(Listen.*|Syncookies.*|TCPSynRetrans)|Tcp_(ActiveOpens|InSegs|OutSegs|PassiveOpens|RetransSegs|CurrEstab)|Udp6?_(InDatagrams|OutDatagrams|NoPorts|RcvbufErrors|SndbufErrors))$",
 
@@ -86,6 +88,7 @@ type Config struct {
 	DiskStatsIgnoredDevices       string              `yaml:"diskstats_ignored_devices,omitempty"`
 	FilesystemIgnoredMountPoints  string              `yaml:"filesystem_ignored_mount_points,omitempty"`
 	FilesystemIgnoredFSTypes      string              `yaml:"filesystem_ignored_fs_types,omitempty"`
+	FilesystemMountTimeout        time.Duration       `yaml:"filesystem_mount_timeout,omitempty"`
 	NetclassIgnoredDevices        string              `yaml:"netclass_ignored_devices,omitempty"`
 	NetdevDeviceBlacklist         string              `yaml:"netdev_device_blacklist,omitempty"`
 	NetdevDeviceWhitelist         string              `yaml:"netdev_device_whitelist,omitempty"`
@@ -106,6 +109,7 @@ type Config struct {
 	SystemdEnableTaskMetrics      bool                `yaml:"systemd_enable_task_metrics,omitempty"`
 	SystemdEnableRestartsMetrics  bool                `yaml:"systemd_enable_restarts_metrics,omitempty"`
 	SystemdEnableStartTimeMetrics bool                `yaml:"systemd_enable_start_time_metrics,omitempty"`
+	SystemdPrivate                bool                `yaml:"systemd_private,omitempty"`
 	VMStatFields                  string              `yaml:"vmstat_fields,omitempty"`
 	TextfileDirectory             string              `yaml:"textfile_directory,omitempty"`
 }
@@ -175,6 +179,17 @@ func MapConfigToNodeExporterFlags(c *Config) (accepted []string, ignored []strin
 	DisableUnavailableCollectors(collectors)
 
 	var flags flags
+
+	// Report collectors which were explicitly requested but aren't known,
+	// such as collectors added in newer versions of node_exporter.
+	for _, names := range [][]string{c.SetCollectors, c.EnableCollectors} {
+		for _, name := range names {
+			if _, known := Collectors[name]; !known {
+				flags.ignored = append(flags.ignored, "collector."+name)
+			}
+		}
+	}
+
 	flags.accepted = append(flags.accepted, MapCollectorsToFlags(collectors)...)
 
 	flags.add(
@@ -197,6 +212,7 @@ func MapConfigToNodeExporterFlags(c *Config) (accepted []string, ignored []strin
 		flags.add(
 			"--collector.filesystem.ignored-mount-points", c.FilesystemIgnoredMountPoints,
 			"--collector.filesystem.ignored-fs-types", c.FilesystemIgnoredFSTypes,
+			"--collector.filesystem.mount-timeout", c.FilesystemMountTimeout.String(),
 		)
 	}
 
@@ -259,6 +275,7 @@ func MapConfigToNodeExporterFlags(c *Config) (accepted []string, ignored []strin
 			&c.SystemdEnableTaskMetrics:      "collector.systemd.enable-task-metrics",
 			&c.SystemdEnableRestartsMetrics:  "collector.systemd.enable-restarts-metrics",
 			&c.SystemdEnableStartTimeMetrics: "collector.systemd.enable-start-time-metrics",
+			&c.SystemdPrivate:                "collector.systemd.private",
 		})
 	}
 
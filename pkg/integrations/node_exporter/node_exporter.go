@@ -33,8 +33,11 @@ func New(log log.Logger, c *Config) (*Integration, error) {
 	// kingpin across the codebase. node_exporter may need a PR eventually to pass
 	// in a custom kingpin application or expose methods to explicitly enable/disable
 	// collectors that we can use instead of this command line hack.
-	flags, _ := MapConfigToNodeExporterFlags(c)
+	flags, ignored := MapConfigToNodeExporterFlags(c)
 	level.Debug(log).Log("msg", "initializing node_exporter with flags converted from agent config", "flags", strings.Join(flags, " "))
+	if len(ignored) > 0 {
+		level.Warn(log).Log("msg", "ignoring settings and collectors unsupported on this platform or by the embedded node_exporter", "flags", strings.Join(ignored, " "))
+	}
 
 	_, err := kingpin.CommandLine.Parse(flags)
 	if err != nil {
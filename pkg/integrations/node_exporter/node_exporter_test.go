@@ -84,12 +84,24 @@ func TestNodeExporter_IgnoredFlags(t *testing.T) {
 
 	switch runtime.GOOS {
 	case "darwin":
-		expect = []string{"collector.cpu.info", "collector.diskstats.ignored-devices"}
+		expect = []string{"collector.cpu.info", "collector.diskstats.ignored-devices", "collector.filesystem.mount-timeout"}
 	}
 
 	require.Equal(t, expect, ignored)
 }
 
+// TestNodeExporter_UnknownCollectors ensures that requested collectors which
+// don't exist in the embedded node_exporter are reported.
+func TestNodeExporter_UnknownCollectors(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.EnableCollectors = []string{CollectorPressure, "ethtool", "zoneinfo"}
+
+	flags, ignored := MapConfigToNodeExporterFlags(&cfg)
+	require.Subset(t, ignored, []string{"collector.ethtool", "collector.zoneinfo"})
+	require.NotContains(t, ignored, "collector."+CollectorPressure)
+	require.NotContains(t, flags, "--collector.ethtool")
+}
+
 // TestFlags makes sure that boolean flags and some known non-boolean flags
 // work as expected
 func TestFlags(t *testing.T) {
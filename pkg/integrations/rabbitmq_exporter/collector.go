@@ -0,0 +1,201 @@
+package rabbitmq_exporter //nolint:golint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+// overview is the response of /api/overview.
+type overview struct {
+	ObjectTotals struct {
+		Connections float64 `json:"connections"`
+		Channels    float64 `json:"channels"`
+		Consumers   float64 `json:"consumers"`
+		Queues      float64 `json:"queues"`
+		Exchanges   float64 `json:"exchanges"`
+	} `json:"object_totals"`
+}
+
+// queue is an element of the response of /api/queues.
+type queue struct {
+	Name                   string       `json:"name"`
+	VHost                  string       `json:"vhost"`
+	Messages               float64      `json:"messages"`
+	MessagesReady          float64      `json:"messages_ready"`
+	MessagesUnacknowledged float64      `json:"messages_unacknowledged"`
+	Consumers              float64      `json:"consumers"`
+	MessageStats           messageStats `json:"message_stats"`
+}
+
+// messageStats holds the message counters of a queue. Counters of
+// operations which never happened are missing from the response.
+type messageStats struct {
+	Publish    float64 `json:"publish"`
+	DeliverGet float64 `json:"deliver_get"`
+	Ack        float64 `json:"ack"`
+	Redeliver  float64 `json:"redeliver"`
+}
+
+// queueColumns limits the fields returned by /api/queues, which can be large
+// on brokers with many queues.
+var queueColumns = []string{
+	"name", "vhost", "messages", "messages_ready", "messages_unacknowledged", "consumers",
+	"message_stats.publish", "message_stats.deliver_get", "message_stats.ack", "message_stats.redeliver",
+}
+
+type collector struct {
+	log     log.Logger
+	url     string
+	client  *http.Client
+	timeout time.Duration
+
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+
+	up         *prometheus.Desc
+	totals     map[string]*prometheus.Desc
+	queueDescs struct {
+		messages, ready, unacked, consumers      *prometheus.Desc
+		published, delivered, acked, redelivered *prometheus.Desc
+	}
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	client, err := config_util.NewClientFromConfig(c.HTTPClientConfig, "rabbitmq_exporter")
+	if err != nil {
+		return nil, err
+	}
+
+	col := &collector{
+		log:     l,
+		url:     strings.TrimSuffix(c.URL, "/"),
+		client:  client,
+		timeout: c.Timeout,
+
+		up: prometheus.NewDesc("rabbitmq_up", "Was the last query of the RabbitMQ management API successful.", nil, nil),
+		totals: map[string]*prometheus.Desc{
+			"connections": prometheus.NewDesc("rabbitmq_connections", "Number of open connections.", nil, nil),
+			"channels":    prometheus.NewDesc("rabbitmq_channels", "Number of open channels.", nil, nil),
+			"consumers":   prometheus.NewDesc("rabbitmq_consumers", "Number of message consumers.", nil, nil),
+			"queues":      prometheus.NewDesc("rabbitmq_queues", "Number of queues in use.", nil, nil),
+			"exchanges":   prometheus.NewDesc("rabbitmq_exchanges", "Number of exchanges in use.", nil, nil),
+		},
+	}
+
+	if col.include, err = regexp.Compile("^(?:" + c.IncludeQueues + ")$"); err != nil {
+		return nil, err
+	}
+	if c.ExcludeQueues != "" {
+		if col.exclude, err = regexp.Compile("^(?:" + c.ExcludeQueues + ")$"); err != nil {
+			return nil, err
+		}
+	}
+
+	labels := []string{"vhost", "queue"}
+	q := &col.queueDescs
+	q.messages = prometheus.NewDesc("rabbitmq_queue_messages", "Sum of ready and unacknowledged messages in the queue.", labels, nil)
+	q.ready = prometheus.NewDesc("rabbitmq_queue_messages_ready", "Number of messages ready to be delivered to clients.", labels, nil)
+	q.unacked = prometheus.NewDesc("rabbitmq_queue_messages_unacknowledged", "Number of messages delivered to clients but not yet acknowledged.", labels, nil)
+	q.consumers = prometheus.NewDesc("rabbitmq_queue_consumers", "Number of consumers of the queue.", labels, nil)
+	q.published = prometheus.NewDesc("rabbitmq_queue_messages_published_total", "Count of messages published to the queue.", labels, nil)
+	q.delivered = prometheus.NewDesc("rabbitmq_queue_messages_delivered_total", "Count of messages delivered to consumers.", labels, nil)
+	q.acked = prometheus.NewDesc("rabbitmq_queue_messages_acked_total", "Count of messages acknowledged by consumers.", labels, nil)
+	q.redelivered = prometheus.NewDesc("rabbitmq_queue_messages_redelivered_total", "Count of messages redelivered to consumers.", labels, nil)
+
+	return col, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	for _, d := range c.totals {
+		ch <- d
+	}
+	q := &c.queueDescs
+	for _, d := range []*prometheus.Desc{q.messages, q.ready, q.unacked, q.consumers, q.published, q.delivered, q.acked, q.redelivered} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var (
+		ov     overview
+		queues []queue
+	)
+	err := c.get(ctx, "/api/overview", &ov)
+	if err == nil {
+		err = c.get(ctx, "/api/queues?columns="+strings.Join(queueColumns, ","), &queues)
+	}
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to query the RabbitMQ management API", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+	for name, v := range map[string]float64{
+		"connections": ov.ObjectTotals.Connections,
+		"channels":    ov.ObjectTotals.Channels,
+		"consumers":   ov.ObjectTotals.Consumers,
+		"queues":      ov.ObjectTotals.Queues,
+		"exchanges":   ov.ObjectTotals.Exchanges,
+	} {
+		ch <- prometheus.MustNewConstMetric(c.totals[name], prometheus.GaugeValue, v)
+	}
+
+	q := &c.queueDescs
+	for _, queue := range queues {
+		if !c.include.MatchString(queue.Name) || (c.exclude != nil && c.exclude.MatchString(queue.Name)) {
+			continue
+		}
+
+		labels := []string{queue.VHost, queue.Name}
+		ch <- prometheus.MustNewConstMetric(q.messages, prometheus.GaugeValue, queue.Messages, labels...)
+		ch <- prometheus.MustNewConstMetric(q.ready, prometheus.GaugeValue, queue.MessagesReady, labels...)
+		ch <- prometheus.MustNewConstMetric(q.unacked, prometheus.GaugeValue, queue.MessagesUnacknowledged, labels...)
+		ch <- prometheus.MustNewConstMetric(q.consumers, prometheus.GaugeValue, queue.Consumers, labels...)
+		ch <- prometheus.MustNewConstMetric(q.published, prometheus.CounterValue, queue.MessageStats.Publish, labels...)
+		ch <- prometheus.MustNewConstMetric(q.delivered, prometheus.CounterValue, queue.MessageStats.DeliverGet, labels...)
+		ch <- prometheus.MustNewConstMetric(q.acked, prometheus.CounterValue, queue.MessageStats.Ack, labels...)
+		ch <- prometheus.MustNewConstMetric(q.redelivered, prometheus.CounterValue, queue.MessageStats.Redeliver, labels...)
+	}
+}
+
+// get requests path from the management API and decodes the JSON response
+// into v.
+func (c *collector) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, req.URL.Path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Package rabbitmq_exporter implements an integration which collects queue
+// and broker metrics from the RabbitMQ management API, using the metric names
+// of https://github.com/kbudde/rabbitmq_exporter.
+package rabbitmq_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig is the default config for rabbitmq_exporter.
+var DefaultConfig = Config{
+	URL:              "http://localhost:15672",
+	HTTPClientConfig: config_util.DefaultHTTPClientConfig,
+	IncludeQueues:    ".*",
+	Timeout:          10 * time.Second,
+}
+
+// Config controls the rabbitmq_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// URL of the management API.
+	URL string `yaml:"url,omitempty"`
+
+	// HTTPClientConfig holds the credentials and TLS settings used to
+	// request the management API. Passwords can be read from a file with
+	// basic_auth.password_file.
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:",inline"`
+
+	// IncludeQueues and ExcludeQueues are regular expressions matched
+	// against queue names. A queue is only collected if its name matches
+	// IncludeQueues and doesn't match ExcludeQueues.
+	IncludeQueues string `yaml:"include_queues,omitempty"`
+	ExcludeQueues string `yaml:"exclude_queues,omitempty"`
+
+	// Timeout of requesting the management API.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url has unsupported scheme %q", u.Scheme)
+	}
+	if err := c.HTTPClientConfig.Validate(); err != nil {
+		return err
+	}
+	if _, err := regexp.Compile(c.IncludeQueues); err != nil {
+		return fmt.Errorf("invalid include_queues: %w", err)
+	}
+	if _, err := regexp.Compile(c.ExcludeQueues); err != nil {
+		return fmt.Errorf("invalid exclude_queues: %w", err)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "rabbitmq_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new rabbitmq_exporter integration. The management API is
+// requested each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
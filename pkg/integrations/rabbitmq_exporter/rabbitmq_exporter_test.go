@@ -0,0 +1,112 @@
+package rabbitmq_exporter //nolint:golint
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+url: https://rabbitmq:15671
+basic_auth:
+  username: monitoring
+  password_file: /etc/rabbitmq/password
+exclude_queues: amq\..*
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, "monitoring", cfg.HTTPClientConfig.BasicAuth.Username)
+	require.Equal(t, DefaultConfig.IncludeQueues, cfg.IncludeQueues)
+
+	err = yaml.UnmarshalStrict([]byte(`
+basic_auth:
+  username: monitoring
+  password: secret
+  password_file: /etc/rabbitmq/password
+`), &cfg)
+	require.Error(t, err)
+}
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "monitoring" || pass != "secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/overview":
+			_, _ = io.WriteString(rw, `{"object_totals": {"connections": 3, "channels": 5, "consumers": 2, "queues": 3, "exchanges": 8}}`)
+		case "/api/queues":
+			_, _ = io.WriteString(rw, `[
+				{"name": "orders", "vhost": "/", "messages": 7, "messages_ready": 5, "messages_unacknowledged": 2, "consumers": 2,
+				 "message_stats": {"publish": 100, "deliver_get": 93, "ack": 91}},
+				{"name": "idle", "vhost": "/", "messages": 0, "messages_ready": 0, "messages_unacknowledged": 0, "consumers": 0},
+				{"name": "amq.gen-abc", "vhost": "/", "messages": 1, "messages_ready": 1, "messages_unacknowledged": 0, "consumers": 0}
+			]`)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir(os.TempDir(), "rabbitmq")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600))
+
+	var cfg Config
+	err = yaml.UnmarshalStrict([]byte(`
+url: `+srv.URL+`
+basic_auth:
+  username: monitoring
+  password_file: `+passwordFile+`
+exclude_queues: amq\..*
+`), &cfg)
+	require.NoError(t, err)
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(col))
+
+	expect := `
+# HELP rabbitmq_connections Number of open connections.
+# TYPE rabbitmq_connections gauge
+rabbitmq_connections 3
+# HELP rabbitmq_queue_messages_published_total Count of messages published to the queue.
+# TYPE rabbitmq_queue_messages_published_total counter
+rabbitmq_queue_messages_published_total{queue="idle",vhost="/"} 0
+rabbitmq_queue_messages_published_total{queue="orders",vhost="/"} 100
+# HELP rabbitmq_queue_messages_ready Number of messages ready to be delivered to clients.
+# TYPE rabbitmq_queue_messages_ready gauge
+rabbitmq_queue_messages_ready{queue="idle",vhost="/"} 0
+rabbitmq_queue_messages_ready{queue="orders",vhost="/"} 5
+# HELP rabbitmq_up Was the last query of the RabbitMQ management API successful.
+# TYPE rabbitmq_up gauge
+rabbitmq_up 1
+`
+	names := []string{"rabbitmq_up", "rabbitmq_connections", "rabbitmq_queue_messages_ready", "rabbitmq_queue_messages_published_total"}
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), names...))
+
+	// The password file is read on every request, so rotated passwords are
+	// picked up without reloading.
+	require.NoError(t, ioutil.WriteFile(passwordFile, []byte("rotated"), 0600))
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP rabbitmq_up Was the last query of the RabbitMQ management API successful.
+# TYPE rabbitmq_up gauge
+rabbitmq_up 0
+`), "rabbitmq_up"))
+}
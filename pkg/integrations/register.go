@@ -21,15 +21,47 @@ var (
 // Registered Configs may be loaded using UnmarshalYAML or manually
 // constructed.
 //
-// RegisterIntegration panics if cfg is not a pointer.
+// Integrations which aren't part of this repository may be registered from
+// the init function of their package, which is then imported by a custom
+// agent binary built with the agentcmd package.
+//
+// RegisterIntegration panics if cfg is not a pointer or if its name is empty,
+// already registered, or used by an option of ManagerConfig.
 func RegisterIntegration(cfg Config) {
 	if reflect.TypeOf(cfg).Kind() != reflect.Ptr {
 		panic(fmt.Sprintf("RegisterIntegration must be given a pointer, got %T", cfg))
 	}
+
+	name := cfg.Name()
+	switch {
+	case name == "":
+		panic(fmt.Sprintf("RegisterIntegration must be given a Config with a name, got %T with an empty name", cfg))
+	case isReservedName(name):
+		panic(fmt.Sprintf("integration name %q is reserved for an integrations_config option", name))
+	}
+	for _, other := range registeredIntegrations {
+		if other.Name() == name {
+			panic(fmt.Sprintf("integration %q registered twice, by %T and %T", name, other, cfg))
+		}
+	}
+
 	registeredIntegrations = append(registeredIntegrations, cfg)
 	configFieldNames[reflect.TypeOf(cfg)] = cfg.Name()
 }
 
+// isReservedName returns true if name is the YAML key of an option of
+// ManagerConfig, which integrations are unmarshaled alongside of.
+func isReservedName(name string) bool {
+	typ := reflect.TypeOf(ManagerConfig{})
+	for i := 0; i < typ.NumField(); i++ {
+		key := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Configs is a list of integrations.
 type Configs []Config
 
@@ -46,7 +78,7 @@ func (c *Configs) unmarshalWithIntegrations(integrations []Config, unmarshal fun
 		fields = append(fields, reflect.StructField{
 			Name: "Config_" + cfg.Name(),
 			Tag:  reflect.StructTag(fmt.Sprintf(`yaml:"%s"`, cfg.Name())),
-			Type: reflect.TypeOf(cfg),
+			Type: configListType,
 		})
 	}
 
@@ -54,24 +86,91 @@ func (c *Configs) unmarshalWithIntegrations(integrations []Config, unmarshal fun
 		structType = reflect.StructOf(fields)
 		structVal  = reflect.New(structType)
 	)
+	for i, cfg := range integrations {
+		structVal.Elem().Field(i).Set(reflect.ValueOf(newConfigList(cfg)))
+	}
 	if err := unmarshal(structVal.Interface()); err != nil {
 		return err
 	}
 
-	// Go over all non-nil fields in structVal and append them to c.
+	// Go over all fields in structVal and append their configs to c.
 	structVal = structVal.Elem()
 	for i := 0; i < structVal.NumField(); i++ {
-		if structVal.Field(i).IsNil() {
-			continue
-		}
+		list := structVal.Field(i).Interface().(*configList)
+		*c = append(*c, list.configs...)
+	}
+
+	return nil
+}
 
-		val := structVal.Field(i).Interface().(Config)
-		*c = append(*c, val)
+// configList holds the configs of a single registered integration. In YAML,
+// it is either a single config or a list of configs, one per instance of the
+// integration.
+type configList struct {
+	typ     reflect.Type // Pointer type of the integration's Config.
+	configs []Config
+}
+
+var configListType = reflect.TypeOf(&configList{})
+
+func newConfigList(cfg Config) *configList {
+	return &configList{typ: reflect.TypeOf(cfg)}
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *configList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var probe interface{}
+	if err := unmarshal(&probe); err != nil {
+		return err
+	}
+
+	list := reflect.New(reflect.SliceOf(l.typ)).Elem()
+	if _, isList := probe.([]interface{}); isList {
+		if err := unmarshal(list.Addr().Interface()); err != nil {
+			return err
+		}
+	} else {
+		cfg := reflect.New(l.typ.Elem())
+		if err := unmarshal(cfg.Interface()); err != nil {
+			return err
+		}
+		list = reflect.Append(list, cfg)
 	}
 
+	l.configs = make([]Config, 0, list.Len())
+	instances := make(map[string]struct{}, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		if list.Index(i).IsNil() {
+			return fmt.Errorf("integrations: empty config at index %d", i)
+		}
+		cfg := list.Index(i).Interface().(Config)
+
+		instance := cfg.CommonConfig().Instance
+		switch {
+		case strings.Contains(instance, "/"):
+			return fmt.Errorf("integrations: %s instance %q must not contain a slash", cfg.Name(), instance)
+		case instance == "" && list.Len() > 1:
+			return fmt.Errorf("integrations: %s config at index %d must set instance when more than one instance is configured", cfg.Name(), i)
+		}
+		if _, ok := instances[instance]; ok {
+			return fmt.Errorf("integrations: %s has two instances named %q", cfg.Name(), instance)
+		}
+		instances[instance] = struct{}{}
+
+		l.configs = append(l.configs, cfg)
+	}
 	return nil
 }
 
+// MarshalYAML implements yaml.Marshaler. A single config is marshaled on its
+// own rather than as a list.
+func (l *configList) MarshalYAML() (interface{}, error) {
+	if len(l.configs) == 1 {
+		return l.configs[0], nil
+	}
+	return l.configs, nil
+}
+
 // MarshalYAML helps implement yaml.Marshaller for structs that have a Configs
 // field that should be inlined in the YAML string.
 func MarshalYAML(v interface{}) (interface{}, error) {
@@ -117,7 +216,11 @@ func MarshalYAML(v interface{}) (interface{}, error) {
 			return nil, fmt.Errorf("integrations: cannot marshal unregistered Config type: %T", c)
 		}
 		field := cfgVal.FieldByName("XXX_Config_" + fieldName)
-		field.Set(reflect.ValueOf(c))
+		if field.IsNil() {
+			field.Set(reflect.ValueOf(newConfigList(c)))
+		}
+		list := field.Interface().(*configList)
+		list.configs = append(list.configs, c)
 	}
 
 	return cfgPointer.Interface(), nil
@@ -174,6 +277,12 @@ func unmarshalIntegrationsWithList(integrations []Config, out interface{}, unmar
 		return fmt.Errorf("integrations: No Configs field found in %T", out)
 	}
 
+	// Prepare a list for the configs of every integration, since the
+	// integration's Config type can't be known by the list otherwise.
+	for i, cfg := range integrations {
+		cfgVal.Field(outVal.NumField() + i).Set(reflect.ValueOf(newConfigList(cfg)))
+	}
+
 	// Unmarshal into our dynamic type.
 	if err := unmarshal(cfgPointer.Interface()); err != nil {
 		return replaceYAMLTypeError(err, cfgType, outType)
@@ -188,16 +297,11 @@ func unmarshalIntegrationsWithList(integrations []Config, out interface{}, unmar
 		outVal.Field(i).Set(cfgVal.Field(i))
 	}
 
-	// Iterate through the remainder of our fields, which should all be of
-	// type Config.
+	// Iterate through the remainder of our fields, which should all hold the
+	// configs of a registered integration.
 	for i := outVal.NumField(); i < cfgVal.NumField(); i++ {
-		field := cfgVal.Field(i)
-
-		if field.IsNil() {
-			continue
-		}
-		val := cfgVal.Field(i).Interface().(Config)
-		*configs = append(*configs, val)
+		list := cfgVal.Field(i).Interface().(*configList)
+		*configs = append(*configs, list.configs...)
 	}
 
 	return nil
@@ -226,7 +330,7 @@ func getConfigTypeForIntegrations(integrations []Config, out reflect.Type) refle
 		fields = append(fields, reflect.StructField{
 			Name: fieldName,
 			Tag:  reflect.StructTag(fmt.Sprintf(`yaml:"%s,omitempty"`, cfg.Name())),
-			Type: reflect.TypeOf(cfg),
+			Type: configListType,
 		})
 	}
 	return reflect.StructOf(fields)
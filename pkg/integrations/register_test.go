@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
 )
@@ -42,6 +43,126 @@ test:
 	require.Equal(t, expect, fullCfg)
 }
 
+func TestIntegrationRegistration_Instances(t *testing.T) {
+	var cfgToParse = `
+name: John Doe
+test:
+  text: Hello, world!
+multi:
+- instance: a
+  text: first
+- instance: b
+  autoscrape:
+    extra_labels:
+      team: b
+  text: second
+`
+
+	var fullCfg testFullConfig
+	err := yaml.UnmarshalStrict([]byte(cfgToParse), &fullCfg)
+	require.NoError(t, err)
+
+	expect := testFullConfig{
+		Name:    "John Doe",
+		Default: 12345,
+		Configs: []Config{
+			&testIntegrationA{Text: "Hello, world!", Truth: true},
+			&testIntegrationC{Common: config.Common{Instance: "a"}, Text: "first"},
+			&testIntegrationC{Common: config.Common{Instance: "b", Autoscrape: config.Autoscrape{ExtraLabels: model.LabelSet{"team": "b"}}}, Text: "second"},
+		},
+	}
+	require.Equal(t, expect, fullCfg)
+}
+
+func TestIntegrationRegistration_InvalidInstances(t *testing.T) {
+	tt := []struct {
+		name      string
+		cfg       string
+		expectErr string
+	}{
+		{
+			name: "missing instance",
+			cfg: `
+multi:
+- instance: a
+- text: no instance
+`,
+			expectErr: "integrations: multi config at index 1 must set instance when more than one instance is configured",
+		},
+		{
+			name: "duplicate instance",
+			cfg: `
+multi:
+- instance: a
+- instance: a
+`,
+			expectErr: `integrations: multi has two instances named "a"`,
+		},
+		{
+			name: "instance with slash",
+			cfg: `
+multi:
+  instance: a/b
+`,
+			expectErr: `integrations: multi instance "a/b" must not contain a slash`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var fullCfg testFullConfig
+			err := yaml.UnmarshalStrict([]byte(tc.cfg), &fullCfg)
+			require.EqualError(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestRegisterIntegration_Invalid(t *testing.T) {
+	RegisterIntegration(&testNamedIntegration{name: "registered_once"})
+
+	tt := []struct {
+		name      string
+		cfg       Config
+		expectErr string
+	}{
+		{
+			name:      "not a pointer",
+			cfg:       testNamedIntegration{name: "not_pointer"},
+			expectErr: "RegisterIntegration must be given a pointer, got integrations.testNamedIntegration",
+		},
+		{
+			name:      "empty name",
+			cfg:       &testNamedIntegration{},
+			expectErr: "RegisterIntegration must be given a Config with a name, got *integrations.testNamedIntegration with an empty name",
+		},
+		{
+			name:      "reserved name",
+			cfg:       &testNamedIntegration{name: "prometheus_remote_write"},
+			expectErr: `integration name "prometheus_remote_write" is reserved for an integrations_config option`,
+		},
+		{
+			name:      "duplicate name",
+			cfg:       &testNamedIntegration{name: "registered_once"},
+			expectErr: `integration "registered_once" registered twice, by *integrations.testNamedIntegration and *integrations.testNamedIntegration`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.PanicsWithValue(t, tc.expectErr, func() { RegisterIntegration(tc.cfg) })
+		})
+	}
+}
+
+type testNamedIntegration struct{ name string }
+
+func (i testNamedIntegration) Name() string                { return i.name }
+func (i testNamedIntegration) CommonConfig() config.Common { return config.Common{} }
+
+func (i testNamedIntegration) NewIntegration(l log.Logger) (Integration, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 type testIntegrationA struct {
 	Text  string `yaml:"text"`
 	Truth bool   `yaml:"truth"`
@@ -71,6 +192,18 @@ func (*testIntegrationB) NewIntegration(l log.Logger) (Integration, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 
+type testIntegrationC struct {
+	Common config.Common `yaml:",inline"`
+	Text   string        `yaml:"text"`
+}
+
+func (*testIntegrationC) Name() string                  { return "multi" }
+func (i *testIntegrationC) CommonConfig() config.Common { return i.Common }
+
+func (*testIntegrationC) NewIntegration(l log.Logger) (Integration, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 type testFullConfig struct {
 	// Some random fields that will also be exposed
 	Name     string        `yaml:"name"`
@@ -88,6 +221,7 @@ func (c *testFullConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	registered := []Config{
 		&testIntegrationA{},
 		&testIntegrationB{},
+		&testIntegrationC{},
 	}
 	return unmarshalIntegrationsWithList(registered, c, unmarshal)
 }
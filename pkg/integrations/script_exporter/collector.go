@@ -0,0 +1,231 @@
+package script_exporter //nolint:golint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// maxStderrLength limits how much of the standard error of a failed script
+// is logged.
+const maxStderrLength = 1024
+
+type collector struct {
+	log log.Logger
+	cfg *Config
+
+	success  *prometheus.Desc
+	duration *prometheus.Desc
+	exitCode *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config) *collector {
+	return &collector{
+		log: l,
+		cfg: c,
+
+		success:  prometheus.NewDesc("script_success", "Whether the script succeeded and its output was parsed.", []string{"script"}, nil),
+		duration: prometheus.NewDesc("script_duration_seconds", "How long the script ran.", []string{"script"}, nil),
+		exitCode: prometheus.NewDesc("script_exit_code", "Exit code of the script, or -1 if it was killed or couldn't start.", []string{"script"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector. Only the metrics about scripts
+// are sent since the output of scripts is unknown, making collector an
+// unchecked collector.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// result is the result of running a script.
+type result struct {
+	families map[string]*dto.MetricFamily
+	duration time.Duration
+	exitCode int
+	err      error
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, c.cfg.MaxConcurrency)
+		results = make([]result, len(c.cfg.Scripts))
+	)
+	for i := range c.cfg.Scripts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = runScript(&c.cfg.Scripts[i])
+		}(i)
+	}
+	wg.Wait()
+
+	// Metrics are sent in the order of the scripts, so the first script
+	// exposing a metric decides its help and type.
+	seen := make(map[string]*dto.MetricFamily)
+	for i, r := range results {
+		s := &c.cfg.Scripts[i]
+
+		success := 1.0
+		if r.err != nil {
+			level.Error(c.log).Log("msg", "script failed", "script", s.Name, "err", r.err)
+			success = 0
+		}
+		ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, success, s.Name)
+		ch <- prometheus.MustNewConstMetric(c.duration, prometheus.GaugeValue, r.duration.Seconds(), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.exitCode, prometheus.GaugeValue, float64(r.exitCode), s.Name)
+
+		names := make([]string, 0, len(r.families))
+		for name := range r.families {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			mf := r.families[name]
+			if prev, ok := seen[name]; ok && prev.GetType() != mf.GetType() {
+				level.Warn(c.log).Log("msg", "dropping metric exposed by another script with a different type", "script", s.Name, "metric", name)
+				continue
+			} else if ok {
+				mf.Help = prev.Help
+			}
+			seen[name] = mf
+
+			for _, m := range mf.Metric {
+				metric, err := convertMetric(mf, m, s.Name)
+				if err != nil {
+					level.Warn(c.log).Log("msg", "dropping invalid metric", "script", s.Name, "metric", name, "err", err)
+					continue
+				}
+				ch <- metric
+			}
+		}
+	}
+}
+
+// runScript runs s and parses its output.
+func runScript(s *Script) result {
+	cmd := exec.Command(s.Command, s.Args...)
+	cmd.Dir = s.Dir
+	cmd.Env = os.Environ()
+	for k, v := range s.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	setProcessGroup(cmd)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	res := result{exitCode: -1}
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		res.err = err
+		return res
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(s.Timeout):
+		// Kill the processes started by the script too, since they would
+		// keep Wait from returning while they hold its output open.
+		_ = kill(cmd)
+		<-done
+		res.duration = time.Since(start)
+		res.err = fmt.Errorf("timed out after %s", s.Timeout)
+		return res
+	}
+	res.duration = time.Since(start)
+	res.exitCode = cmd.ProcessState.ExitCode()
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			out := stderr.Bytes()
+			if len(out) > maxStderrLength {
+				out = out[:maxStderrLength]
+			}
+			err = fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+		}
+		res.err = err
+		return res
+	}
+
+	var parser expfmt.TextParser
+	if res.families, err = parser.TextToMetricFamilies(&stdout); err != nil {
+		res.err = fmt.Errorf("invalid output: %w", err)
+	}
+	return res
+}
+
+// convertMetric converts a parsed metric into a const metric, adding the
+// script label. The script label replaces any label of the same name.
+func convertMetric(mf *dto.MetricFamily, m *dto.Metric, script string) (prometheus.Metric, error) {
+	var (
+		names  = []string{"script"}
+		values = []string{script}
+	)
+	for _, lp := range m.Label {
+		if lp.GetName() == "script" {
+			continue
+		}
+		names = append(names, lp.GetName())
+		values = append(values, lp.GetValue())
+	}
+	desc := prometheus.NewDesc(mf.GetName(), mf.GetHelp(), names, nil)
+
+	var (
+		metric prometheus.Metric
+		err    error
+	)
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), values...)
+	case dto.MetricType_GAUGE:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), values...)
+	case dto.MetricType_UNTYPED:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), values...)
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		quantiles := make(map[float64]float64, len(s.Quantile))
+		for _, q := range s.Quantile {
+			quantiles[q.GetQuantile()] = q.GetValue()
+		}
+		metric, err = prometheus.NewConstSummary(desc, s.GetSampleCount(), s.GetSampleSum(), quantiles, values...)
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		buckets := make(map[float64]uint64, len(h.Bucket))
+		for _, b := range h.Bucket {
+			buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+		}
+		metric, err = prometheus.NewConstHistogram(desc, h.GetSampleCount(), h.GetSampleSum(), buckets, values...)
+	default:
+		return nil, fmt.Errorf("unsupported metric type %s", mf.GetType())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if m.TimestampMs != nil {
+		metric = prometheus.NewMetricWithTimestamp(time.Unix(0, m.GetTimestampMs()*int64(time.Millisecond)), metric)
+	}
+	return metric, nil
+}
@@ -0,0 +1,12 @@
+package script_exporter //nolint:golint
+
+import "os/exec"
+
+// setProcessGroup does nothing on Windows, where processes started by cmd
+// aren't killed with it.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// kill kills the process of cmd.
+func kill(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,115 @@
+// Package script_exporter implements an integration which runs commands and
+// exposes the metrics they print in the Prometheus text format.
+package script_exporter //nolint:golint
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+)
+
+// DefaultConfig holds the default settings for the script_exporter
+// integration.
+var DefaultConfig = Config{
+	MaxConcurrency: 4,
+}
+
+// DefaultScript holds the default settings for a Script.
+var DefaultScript = Script{
+	Timeout: 10 * time.Second,
+}
+
+// Config controls the script_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	Scripts []Script `yaml:"scripts,omitempty"`
+
+	// MaxConcurrency is the maximum number of scripts running at the same
+	// time.
+	MaxConcurrency int `yaml:"max_concurrency,omitempty"`
+}
+
+// Script is a command run on every scrape.
+type Script struct {
+	// Name of the script, used as the value of the script label.
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"`
+	Dir     string            `yaml:"working_dir,omitempty"`
+
+	// Timeout after which the command is killed.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.MaxConcurrency <= 0 {
+		return fmt.Errorf("max_concurrency must be positive")
+	}
+	names := make(map[string]bool, len(c.Scripts))
+	for _, s := range c.Scripts {
+		if names[s.Name] {
+			return fmt.Errorf("found multiple scripts named %q", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Script.
+func (s *Script) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*s = DefaultScript
+
+	type plain Script
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+
+	if s.Name == "" {
+		return fmt.Errorf("script name must not be empty")
+	}
+	if s.Command == "" {
+		return fmt.Errorf("script %s: command must not be empty", s.Name)
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("script %s: timeout must be positive", s.Name)
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "script_exporter"
+}
+
+// CommonConfig returns the common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new script_exporter integration. Scripts run on every
+// scrape.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(newCollector(l, c))), nil
+}
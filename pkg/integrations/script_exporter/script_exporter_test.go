@@ -0,0 +1,93 @@
+package script_exporter //nolint:golint
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`scripts: [{name: a, command: /bin/true}]`), &cfg))
+	require.Equal(t, DefaultScript.Timeout, cfg.Scripts[0].Timeout)
+	require.Equal(t, DefaultConfig.MaxConcurrency, cfg.MaxConcurrency)
+
+	tt := []struct {
+		name, in, err string
+	}{
+		{"no command", `scripts: [{name: a}]`, "script a: command must not be empty"},
+		{"duplicate", `scripts: [{name: a, command: x}, {name: a, command: y}]`, `found multiple scripts named "a"`},
+		{"no concurrency", `max_concurrency: -1`, "max_concurrency must be positive"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.in), &cfg), tc.err)
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test scripts require a POSIX shell")
+	}
+
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+max_concurrency: 2
+scripts:
+- name: backups
+  command: /bin/sh
+  args:
+  - -c
+  - |
+    echo '# HELP backup_age_seconds Age of the last backup.'
+    echo '# TYPE backup_age_seconds gauge'
+    echo "backup_age_seconds{target=\"$TARGET\"} 3600"
+    echo '# HELP backup_size_bytes Size of backups.'
+    echo '# TYPE backup_size_bytes histogram'
+    echo 'backup_size_bytes_bucket{le="1024"} 1'
+    echo 'backup_size_bytes_bucket{le="+Inf"} 2'
+    echo 'backup_size_bytes_sum 4096'
+    echo 'backup_size_bytes_count 2'
+  env:
+    TARGET: db
+- name: broken
+  command: /bin/sh
+  args: [-c, 'echo oops >&2; exit 3']
+- name: slow
+  command: /bin/sh
+  args: [-c, 'sleep 5']
+  timeout: 100ms
+`), &cfg))
+
+	expect := `
+# HELP backup_age_seconds Age of the last backup.
+# TYPE backup_age_seconds gauge
+backup_age_seconds{script="backups",target="db"} 3600
+# HELP backup_size_bytes Size of backups.
+# TYPE backup_size_bytes histogram
+backup_size_bytes_bucket{script="backups",le="1024"} 1
+backup_size_bytes_bucket{script="backups",le="+Inf"} 2
+backup_size_bytes_sum{script="backups"} 4096
+backup_size_bytes_count{script="backups"} 2
+# HELP script_exit_code Exit code of the script, or -1 if it was killed or couldn't start.
+# TYPE script_exit_code gauge
+script_exit_code{script="backups"} 0
+script_exit_code{script="broken"} 3
+script_exit_code{script="slow"} -1
+# HELP script_success Whether the script succeeded and its output was parsed.
+# TYPE script_success gauge
+script_success{script="backups"} 1
+script_success{script="broken"} 0
+script_success{script="slow"} 0
+`
+	col := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect),
+		"backup_age_seconds", "backup_size_bytes", "script_exit_code", "script_success"))
+}
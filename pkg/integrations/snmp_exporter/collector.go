@@ -0,0 +1,138 @@
+package snmp_exporter //nolint:golint
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/util/snmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	labelTarget model.LabelName = "target"
+	labelModule model.LabelName = "module"
+)
+
+// collector walks every target when collected.
+type collector struct {
+	log     log.Logger
+	targets []*target
+}
+
+// target is a Target ready to be walked.
+type target struct {
+	name   string
+	module Module
+	client *snmp.Client
+
+	labelNames  []string
+	labelValues []string
+
+	successDesc  *prometheus.Desc
+	durationDesc *prometheus.Desc
+	pdusDesc     *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config) *collector {
+	col := &collector{log: l}
+
+	for _, t := range c.Targets {
+		module := c.Modules[t.Module]
+
+		tt := &target{
+			name:   t.Name,
+			module: module,
+			client: &snmp.Client{
+				Address:        t.Address,
+				Community:      module.Community,
+				Timeout:        module.Timeout,
+				Retries:        module.Retries,
+				MaxRepetitions: module.MaxRepetitions,
+			},
+			labelNames:  []string{string(labelTarget), string(labelModule)},
+			labelValues: []string{t.Name, t.Module},
+		}
+		for _, name := range sortedLabelNames(t.Labels) {
+			tt.labelNames = append(tt.labelNames, string(name))
+			tt.labelValues = append(tt.labelValues, string(t.Labels[name]))
+		}
+
+		tt.successDesc = prometheus.NewDesc("snmp_scrape_success", "Whether every OID of the target was walked successfully.", tt.labelNames, nil)
+		tt.durationDesc = prometheus.NewDesc("snmp_scrape_duration_seconds", "How long walking the target took in seconds.", tt.labelNames, nil)
+		tt.pdusDesc = prometheus.NewDesc("snmp_scrape_pdus_returned", "Number of variables returned by the target.", tt.labelNames, nil)
+
+		col.targets = append(col.targets, tt)
+	}
+
+	return col
+}
+
+func sortedLabelNames(ls model.LabelSet) []model.LabelName {
+	names := make([]model.LabelName, 0, len(ls))
+	for name := range ls {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// Describe implements prometheus.Collector. Nothing is sent since the label
+// names of each target depend on its configured labels, making collector an
+// unchecked collector.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector. Targets are walked concurrently.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for _, t := range c.targets {
+		wg.Add(1)
+		go func(t *target) {
+			defer wg.Done()
+			c.collectTarget(t, ch)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (c *collector) collectTarget(t *target, ch chan<- prometheus.Metric) {
+	var (
+		start   = time.Now()
+		success = 1.0
+		pdus    int
+	)
+
+	for _, m := range t.module.Walk {
+		var (
+			names     = append(append([]string{}, t.labelNames...), m.IndexLabel)
+			desc      = prometheus.NewDesc(m.Name, m.Help, names, nil)
+			valueType = prometheus.GaugeValue
+		)
+		if m.Type == TypeCounter {
+			valueType = prometheus.CounterValue
+		}
+
+		err := t.client.Walk(context.Background(), m.oid, func(v snmp.Variable) {
+			pdus++
+			value, ok := v.Float()
+			if !ok {
+				return
+			}
+			index := snmp.OID(v.OID[len(m.oid):]).String()
+			values := append(append([]string{}, t.labelValues...), index)
+			ch <- prometheus.MustNewConstMetric(desc, valueType, value, values...)
+		})
+		if err != nil {
+			level.Warn(c.log).Log("msg", "failed to walk target", "target", t.name, "oid", m.OID, "err", err)
+			success = 0
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(t.successDesc, prometheus.GaugeValue, success, t.labelValues...)
+	ch <- prometheus.MustNewConstMetric(t.durationDesc, prometheus.GaugeValue, time.Since(start).Seconds(), t.labelValues...)
+	ch <- prometheus.MustNewConstMetric(t.pdusDesc, prometheus.GaugeValue, float64(pdus), t.labelValues...)
+}
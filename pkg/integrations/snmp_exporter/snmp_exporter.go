@@ -0,0 +1,233 @@
+// Package snmp_exporter implements an integration which walks SNMP devices
+// in the style of https://github.com/prometheus/snmp_exporter.
+package snmp_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/grafana/agent/pkg/util/snmp"
+	"github.com/prometheus/common/model"
+)
+
+// Metric types.
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// defaultPort is the port of SNMP agents.
+const defaultPort = "161"
+
+// DefaultConfig is the default config for snmp_exporter.
+var DefaultConfig = Config{}
+
+// DefaultModule holds default settings for a Module.
+var DefaultModule = Module{
+	Community:      "public",
+	Timeout:        5 * time.Second,
+	Retries:        3,
+	MaxRepetitions: 25,
+}
+
+// DefaultMetric holds default settings for a Metric.
+var DefaultMetric = Metric{
+	Type:       TypeGauge,
+	IndexLabel: "index",
+}
+
+// Config controls the snmp_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// Modules define which OIDs to walk, keyed by name.
+	Modules map[string]Module `yaml:"modules,omitempty"`
+
+	// Targets to walk every time the integration is scraped.
+	Targets []Target `yaml:"targets,omitempty"`
+}
+
+// Module configures how to walk a target and which metrics to create from
+// the walked variables.
+type Module struct {
+	// Community used to authenticate with SNMPv2c.
+	Community string `yaml:"community,omitempty"`
+
+	// Timeout of each request, and how many times to retry requests which
+	// time out.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	Retries int           `yaml:"retries,omitempty"`
+
+	// MaxRepetitions is the number of variables requested at once.
+	MaxRepetitions int `yaml:"max_repetitions,omitempty"`
+
+	// Walk lists the metrics to create. The subtree of each metric's OID is
+	// walked.
+	Walk []Metric `yaml:"walk"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Module.
+func (m *Module) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*m = DefaultModule
+
+	type plain Module
+	if err := unmarshal((*plain)(m)); err != nil {
+		return err
+	}
+
+	if m.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	if m.MaxRepetitions <= 0 {
+		return fmt.Errorf("max_repetitions must be greater than 0")
+	}
+	if len(m.Walk) == 0 {
+		return fmt.Errorf("walk must have at least one metric")
+	}
+
+	names := make(map[string]struct{}, len(m.Walk))
+	for _, metric := range m.Walk {
+		if _, ok := names[metric.Name]; ok {
+			return fmt.Errorf("walk has two metrics named %s", metric.Name)
+		}
+		names[metric.Name] = struct{}{}
+	}
+	return nil
+}
+
+// Metric creates a metric from the variables under an OID. Each variable
+// becomes a series, with the OID suffix after the metric's OID as the value
+// of IndexLabel. Variables which don't hold a number are skipped.
+type Metric struct {
+	Name       string `yaml:"name"`
+	OID        string `yaml:"oid"`
+	Help       string `yaml:"help,omitempty"`
+	Type       string `yaml:"type,omitempty"`
+	IndexLabel string `yaml:"index_label,omitempty"`
+
+	oid snmp.OID
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Metric.
+func (m *Metric) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*m = DefaultMetric
+
+	type plain Metric
+	if err := unmarshal((*plain)(m)); err != nil {
+		return err
+	}
+
+	if !model.IsValidMetricName(model.LabelValue(m.Name)) {
+		return fmt.Errorf("invalid metric name %q", m.Name)
+	}
+	oid, err := snmp.ParseOID(m.OID)
+	if err != nil {
+		return fmt.Errorf("metric %s: %w", m.Name, err)
+	}
+	m.oid = oid
+
+	switch m.Type {
+	case TypeGauge, TypeCounter:
+	default:
+		return fmt.Errorf("metric %s: unsupported type %q", m.Name, m.Type)
+	}
+	if !model.LabelName(m.IndexLabel).IsValid() {
+		return fmt.Errorf("metric %s: invalid index_label %q", m.Name, m.IndexLabel)
+	}
+	if m.Help == "" {
+		m.Help = fmt.Sprintf("SNMP OID %s", m.OID)
+	}
+	return nil
+}
+
+// Target is a device to walk.
+type Target struct {
+	// Name of the target, used as the value of the target label.
+	Name string `yaml:"name"`
+
+	// Address of the device in host:port form. The port defaults to 161.
+	Address string `yaml:"address"`
+
+	// Module to walk the target with.
+	Module string `yaml:"module"`
+
+	// Labels added to the metrics of the target.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	names := make(map[string]struct{}, len(c.Targets))
+	for idx := range c.Targets {
+		t := &c.Targets[idx]
+		if t.Name == "" {
+			return fmt.Errorf("snmp_exporter target index %d must have a name", idx)
+		}
+		if _, ok := names[t.Name]; ok {
+			return fmt.Errorf("snmp_exporter has two targets named %s", t.Name)
+		}
+		names[t.Name] = struct{}{}
+
+		if t.Address == "" {
+			return fmt.Errorf("snmp_exporter target %s must have an address", t.Name)
+		}
+		if _, _, err := net.SplitHostPort(t.Address); err != nil {
+			t.Address = net.JoinHostPort(t.Address, defaultPort)
+		}
+
+		module, ok := c.Modules[t.Module]
+		if !ok {
+			return fmt.Errorf("snmp_exporter target %s uses unknown module %q", t.Name, t.Module)
+		}
+		if err := t.Labels.Validate(); err != nil {
+			return fmt.Errorf("snmp_exporter target %s: %w", t.Name, err)
+		}
+
+		reserved := []model.LabelName{labelTarget, labelModule}
+		for _, m := range module.Walk {
+			reserved = append(reserved, model.LabelName(m.IndexLabel))
+		}
+		for _, name := range reserved {
+			if _, ok := t.Labels[name]; ok {
+				return fmt.Errorf("snmp_exporter target %s must not set the %s label", t.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "snmp_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new snmp_exporter integration. Every target is walked each
+// time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(newCollector(l, c))), nil
+}
@@ -0,0 +1,165 @@
+package snmp_exporter //nolint:golint
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/util/snmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+modules:
+  if_mib:
+    walk:
+    - name: ifHCInOctets
+      oid: 1.3.6.1.2.1.31.1.1.1.6
+      type: counter
+      index_label: ifIndex
+targets:
+- name: switch
+  address: 192.168.1.2
+  module: if_mib
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.1.2:161", cfg.Targets[0].Address)
+	require.Equal(t, "public", cfg.Modules["if_mib"].Community)
+	require.Equal(t, "SNMP OID 1.3.6.1.2.1.31.1.1.1.6", cfg.Modules["if_mib"].Walk[0].Help)
+
+	tt := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{
+			name: "unknown module",
+			cfg:  `targets: [{name: a, address: switch, module: if_mib}]`,
+			err:  `snmp_exporter target a uses unknown module "if_mib"`,
+		},
+		{
+			name: "invalid OID",
+			cfg:  `modules: {if_mib: {walk: [{name: ifInOctets, oid: ifInOctets}]}}`,
+			err:  `metric ifInOctets: invalid OID "ifInOctets": must have at least two parts`,
+		},
+		{
+			name: "duplicate metric",
+			cfg:  `modules: {if_mib: {walk: [{name: a, oid: 1.3.6.1}, {name: a, oid: 1.3.6.2}]}}`,
+			err:  "walk has two metrics named a",
+		},
+		{
+			name: "index label set by target",
+			cfg: `
+modules: {if_mib: {walk: [{name: ifInOctets, oid: 1.3.6.1.2.1.2.2.1.10, index_label: ifIndex}]}}
+targets: [{name: a, address: switch, module: if_mib, labels: {ifIndex: "1"}}]`,
+			err: "snmp_exporter target a must not set the ifIndex label",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg), tc.err)
+		})
+	}
+}
+
+// runAgent runs a fake SNMP agent which answers GetBulkRequests with vars.
+func runAgent(t *testing.T, vars []snmp.Variable) string {
+	t.Helper()
+
+	sort.Slice(vars, func(i, j int) bool { return vars[i].OID.Compare(vars[j].OID) < 0 })
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req snmp.Packet
+			if err := req.UnmarshalBinary(buf[:n]); err != nil {
+				continue
+			}
+
+			resp := snmp.Packet{Version: req.Version, Community: req.Community, PDUType: snmp.GetResponse, RequestID: req.RequestID}
+			for _, v := range vars {
+				if v.OID.Compare(req.Variables[0].OID) > 0 && len(resp.Variables) < req.ErrorIndex {
+					resp.Variables = append(resp.Variables, v)
+				}
+			}
+			if len(resp.Variables) < req.ErrorIndex {
+				resp.Variables = append(resp.Variables, snmp.Variable{OID: req.Variables[0].OID, Type: snmp.EndOfMibView})
+			}
+
+			b, _ := resp.MarshalBinary()
+			_, _ = conn.WriteTo(b, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestCollector(t *testing.T) {
+	addr := runAgent(t, []snmp.Variable{
+		{OID: snmp.OID{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 1}, Type: snmp.OctetString, Value: []byte("eth0")},
+		{OID: snmp.OID{1, 3, 6, 1, 2, 1, 2, 2, 1, 8, 1}, Type: snmp.Integer, Value: int64(1)},
+		{OID: snmp.OID{1, 3, 6, 1, 2, 1, 2, 2, 1, 8, 2}, Type: snmp.Integer, Value: int64(2)},
+		{OID: snmp.OID{1, 3, 6, 1, 2, 1, 31, 1, 1, 1, 6, 1}, Type: snmp.Counter64, Value: uint64(1000)},
+	})
+
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+modules:
+  if_mib:
+    walk:
+    - name: ifDescr
+      oid: 1.3.6.1.2.1.2.2.1.2
+    - name: ifOperStatus
+      oid: 1.3.6.1.2.1.2.2.1.8
+      help: The current operational state of the interface.
+      index_label: ifIndex
+    - name: ifHCInOctets
+      oid: 1.3.6.1.2.1.31.1.1.1.6
+      type: counter
+      index_label: ifIndex
+targets:
+- name: switch
+  address: `+addr+`
+  module: if_mib
+  labels:
+    site: office
+`), &cfg)
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(newCollector(log.NewNopLogger(), &cfg)))
+
+	expect := `
+# HELP ifHCInOctets SNMP OID 1.3.6.1.2.1.31.1.1.1.6
+# TYPE ifHCInOctets counter
+ifHCInOctets{ifIndex="1",module="if_mib",site="office",target="switch"} 1000
+# HELP ifOperStatus The current operational state of the interface.
+# TYPE ifOperStatus gauge
+ifOperStatus{ifIndex="1",module="if_mib",site="office",target="switch"} 1
+ifOperStatus{ifIndex="2",module="if_mib",site="office",target="switch"} 2
+# HELP snmp_scrape_pdus_returned Number of variables returned by the target.
+# TYPE snmp_scrape_pdus_returned gauge
+snmp_scrape_pdus_returned{module="if_mib",site="office",target="switch"} 4
+# HELP snmp_scrape_success Whether every OID of the target was walked successfully.
+# TYPE snmp_scrape_success gauge
+snmp_scrape_success{module="if_mib",site="office",target="switch"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect),
+		"ifDescr", "ifOperStatus", "ifHCInOctets", "snmp_scrape_pdus_returned", "snmp_scrape_success"))
+}
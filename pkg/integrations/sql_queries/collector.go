@@ -0,0 +1,207 @@
+package sql_queries //nolint:golint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type collector struct {
+	log     log.Logger
+	cfg     *Config
+	dbs     []*sql.DB
+	metrics [][]*prometheus.Desc
+
+	scrapeError *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	col := &collector{
+		log: l,
+		cfg: c,
+
+		scrapeError: prometheus.NewDesc("sql_queries_scrape_error", "Non-zero if this scrape failed.", nil, nil),
+	}
+
+	for _, d := range c.Databases {
+		db, err := sql.Open(d.Driver, string(d.DSN))
+		if err != nil {
+			for _, db := range col.dbs {
+				_ = db.Close()
+			}
+			return nil, fmt.Errorf("failed to open database %s: %w", d.Name, err)
+		}
+		db.SetMaxOpenConns(d.MaxOpenConns)
+		db.SetMaxIdleConns(d.MaxOpenConns)
+		col.dbs = append(col.dbs, db)
+
+		descs := make([]*prometheus.Desc, len(d.Queries))
+		for i, q := range d.Queries {
+			descs[i] = prometheus.NewDesc(q.MetricName, q.Help, append([]string{"database"}, q.labelNames()...), nil)
+		}
+		col.metrics = append(col.metrics, descs)
+	}
+	return col, nil
+}
+
+// Describe implements prometheus.Collector. Nothing is sent since the same
+// metric may be read from several databases, making collector an unchecked
+// collector.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	var (
+		wg          sync.WaitGroup
+		errMut      sync.Mutex
+		scrapeError float64
+	)
+	for i := range c.cfg.Databases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			d := &c.cfg.Databases[i]
+
+			// Queries of a database run one after the other, so that
+			// max_open_conns connections are enough.
+			for j := range d.Queries {
+				if err := c.runQuery(ctx, c.dbs[i], d, &d.Queries[j], c.metrics[i][j], ch); err != nil {
+					level.Error(c.log).Log("msg", "failed to run query", "database", d.Name, "metric", d.Queries[j].MetricName, "err", err)
+					errMut.Lock()
+					scrapeError = 1
+					errMut.Unlock()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError)
+}
+
+// runQuery runs q and sends a series for each value of each row. Metrics
+// are only sent once all rows have been read, so a failed query sends
+// nothing.
+func (c *collector) runQuery(ctx context.Context, db *sql.DB, d *Database, q *Query, desc *prometheus.Desc, ch chan<- prometheus.Metric) error {
+	rows, err := db.QueryContext(ctx, q.Query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	index := make(map[string]int, len(columns))
+	for i, col := range columns {
+		index[col] = i
+	}
+	for _, col := range append(append([]string(nil), q.KeyLabels...), q.Values...) {
+		if _, ok := index[col]; !ok {
+			return fmt.Errorf("query returned no column %q", col)
+		}
+	}
+
+	valueType := prometheus.GaugeValue
+	if q.Type == "counter" {
+		valueType = prometheus.CounterValue
+	}
+
+	var (
+		metrics []prometheus.Metric
+		values  = make([]interface{}, len(columns))
+		dest    = make([]interface{}, len(columns))
+	)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		labelValues := []string{d.Name}
+		for _, col := range q.KeyLabels {
+			labelValues = append(labelValues, labelValue(values[index[col]]))
+		}
+		for _, col := range q.Values {
+			v, ok, err := floatValue(values[index[col]])
+			if err != nil {
+				return fmt.Errorf("column %s: %w", col, err)
+			} else if !ok {
+				// NULL values don't produce a series.
+				continue
+			}
+
+			lvs := labelValues
+			if q.ValueLabel != "" {
+				lvs = append(labelValues[:len(labelValues):len(labelValues)], col)
+			}
+			m, err := prometheus.NewConstMetric(desc, valueType, v, lvs...)
+			if err != nil {
+				return err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range metrics {
+		ch <- m
+	}
+	return nil
+}
+
+func labelValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// floatValue converts a column to a float. The second result is false for
+// NULL.
+func floatValue(v interface{}) (float64, bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, false, nil
+	case int64:
+		return float64(v), true, nil
+	case float64:
+		return v, true, nil
+	case bool:
+		if v {
+			return 1, true, nil
+		}
+		return 0, true, nil
+	case time.Time:
+		return float64(v.UnixNano()) / 1e9, true, nil
+	case []byte:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil, err
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil, err
+	default:
+		return 0, false, fmt.Errorf("unsupported type %T", v)
+	}
+}
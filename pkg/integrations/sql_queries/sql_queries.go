@@ -0,0 +1,241 @@
+// Package sql_queries implements an integration which turns the results of
+// SQL queries into metrics.
+package sql_queries //nolint:golint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+
+	// Register the supported database drivers.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+// DefaultConfig holds the default settings for the sql_queries integration.
+var DefaultConfig = Config{
+	Timeout: 10 * time.Second,
+}
+
+// DefaultDatabase holds the default settings for a Database.
+var DefaultDatabase = Database{
+	MaxOpenConns: 1,
+}
+
+// DefaultQuery holds the default settings for a Query.
+var DefaultQuery = Query{
+	Type: "gauge",
+}
+
+// Config controls the sql_queries integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	Databases []Database `yaml:"databases,omitempty"`
+
+	// Timeout of running the queries of all databases.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Database is a database to run queries against.
+type Database struct {
+	// Name of the database, used as the value of the database label.
+	Name         string            `yaml:"name"`
+	Driver       string            `yaml:"driver"`
+	DSN          promconfig.Secret `yaml:"dsn"`
+	MaxOpenConns int               `yaml:"max_open_conns,omitempty"`
+	Queries      []Query           `yaml:"queries"`
+}
+
+// Query is a query whose rows are converted into metrics. Each row produces
+// one series per column listed in Values.
+type Query struct {
+	MetricName string `yaml:"metric_name"`
+	Help       string `yaml:"help,omitempty"`
+	// Type is either gauge or counter.
+	Type  string `yaml:"type,omitempty"`
+	Query string `yaml:"query"`
+
+	// KeyLabels are the columns used as labels.
+	KeyLabels []string `yaml:"key_labels,omitempty"`
+	// Values are the columns used as values. When there's more than one,
+	// ValueLabel is the label holding the name of the column.
+	Values     []string `yaml:"values"`
+	ValueLabel string   `yaml:"value_label,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	var (
+		names   = make(map[string]bool, len(c.Databases))
+		metrics = make(map[string]*Query)
+	)
+	for _, db := range c.Databases {
+		if names[db.Name] {
+			return fmt.Errorf("found multiple databases named %q", db.Name)
+		}
+		names[db.Name] = true
+
+		for i := range db.Queries {
+			q := &db.Queries[i]
+			// The same metric may be read from several databases, as long as
+			// it's always read the same way.
+			if prev, ok := metrics[q.MetricName]; ok && !prev.sameMetric(q) {
+				return fmt.Errorf("metric %s is defined with different help, type, or labels", q.MetricName)
+			}
+			metrics[q.MetricName] = q
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Database.
+func (d *Database) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*d = DefaultDatabase
+
+	type plain Database
+	if err := unmarshal((*plain)(d)); err != nil {
+		return err
+	}
+
+	if d.Name == "" {
+		return fmt.Errorf("database name must not be empty")
+	}
+	drivers := sql.Drivers()
+	if i := sort.SearchStrings(drivers, d.Driver); i == len(drivers) || drivers[i] != d.Driver {
+		return fmt.Errorf("database %s: unknown driver %q, must be one of %s", d.Name, d.Driver, strings.Join(drivers, ", "))
+	}
+	if d.DSN == "" {
+		return fmt.Errorf("database %s: dsn must not be empty", d.Name)
+	}
+	if d.MaxOpenConns <= 0 {
+		return fmt.Errorf("database %s: max_open_conns must be positive", d.Name)
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Query.
+func (q *Query) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*q = DefaultQuery
+
+	type plain Query
+	if err := unmarshal((*plain)(q)); err != nil {
+		return err
+	}
+
+	if !model.IsValidMetricName(model.LabelValue(q.MetricName)) {
+		return fmt.Errorf("invalid metric_name %q", q.MetricName)
+	}
+	if q.Help == "" {
+		q.Help = "Result of a SQL query."
+	}
+	if q.Type != "gauge" && q.Type != "counter" {
+		return fmt.Errorf("metric %s: type must be gauge or counter", q.MetricName)
+	}
+	if q.Query == "" {
+		return fmt.Errorf("metric %s: query must not be empty", q.MetricName)
+	}
+	if len(q.Values) == 0 {
+		return fmt.Errorf("metric %s: values must not be empty", q.MetricName)
+	}
+	if len(q.Values) > 1 && q.ValueLabel == "" {
+		return fmt.Errorf("metric %s: value_label must be set when there are multiple values", q.MetricName)
+	}
+
+	seen := map[string]bool{"database": true}
+	for _, l := range q.labelNames() {
+		if !model.LabelName(l).IsValid() {
+			return fmt.Errorf("metric %s: invalid label name %q", q.MetricName, l)
+		}
+		if seen[l] {
+			return fmt.Errorf("metric %s: duplicate label %q", q.MetricName, l)
+		}
+		seen[l] = true
+	}
+	return nil
+}
+
+// labelNames returns the labels of the series of q, except for database.
+func (q *Query) labelNames() []string {
+	names := append([]string(nil), q.KeyLabels...)
+	if q.ValueLabel != "" {
+		names = append(names, q.ValueLabel)
+	}
+	return names
+}
+
+func (q *Query) sameMetric(o *Query) bool {
+	if q.Help != o.Help || q.Type != o.Type {
+		return false
+	}
+	a, b := q.labelNames(), o.labelNames()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "sql_queries"
+}
+
+// CommonConfig returns the common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new sql_queries integration. Connections to the databases
+// are opened when queries first run and closed when the integration stops.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+
+	run := func(ctx context.Context) error {
+		<-ctx.Done()
+		for _, db := range col.dbs {
+			if err := db.Close(); err != nil {
+				level.Warn(l).Log("msg", "failed to close database", "err", err)
+			}
+		}
+		return nil
+	}
+
+	return integrations.NewCollectorIntegration(c.Name(),
+		integrations.WithCollectors(col),
+		integrations.WithRunner(run),
+	), nil
+}
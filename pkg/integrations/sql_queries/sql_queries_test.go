@@ -0,0 +1,155 @@
+package sql_queries //nolint:golint
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// testResults are the results of the queries run against the sqltest driver.
+var testResults = map[string]*testRows{
+	"SELECT status, count(*) AS count, sum(total) AS total FROM orders GROUP BY status": {
+		columns: []string{"status", "count", "total"},
+		rows: [][]driver.Value{
+			{[]byte("pending"), int64(3), []byte("42.5")},
+			{"shipped", int64(10), nil},
+		},
+	},
+	"SELECT 1 AS up": {
+		columns: []string{"up"},
+		rows:    [][]driver.Value{{true}},
+	},
+}
+
+func init() {
+	sql.Register("sqltest", testDriver{})
+}
+
+type testDriver struct{}
+
+func (testDriver) Open(string) (driver.Conn, error) { return testConn{}, nil }
+
+type testConn struct{}
+
+func (testConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (testConn) Close() error                        { return nil }
+func (testConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (testConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	rows, ok := testResults[query]
+	if !ok {
+		return nil, errors.New("syntax error")
+	}
+	return &testRows{columns: rows.columns, rows: rows.rows}, nil
+}
+
+type testRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestConfig(t *testing.T) {
+	tt := []struct {
+		name, in, err string
+	}{
+		{
+			name: "unknown driver",
+			in:   `databases: [{name: a, driver: oracle, dsn: x}]`,
+			err:  `database a: unknown driver "oracle", must be one of mysql, postgres, sqltest`,
+		},
+		{
+			name: "duplicate database",
+			in:   `databases: [{name: a, driver: sqltest, dsn: x}, {name: a, driver: sqltest, dsn: x}]`,
+			err:  `found multiple databases named "a"`,
+		},
+		{
+			name: "missing value label",
+			in:   `databases: [{name: a, driver: sqltest, dsn: x, queries: [{metric_name: m, query: q, values: [a, b]}]}]`,
+			err:  "metric m: value_label must be set when there are multiple values",
+		},
+		{
+			name: "reserved label",
+			in:   `databases: [{name: a, driver: sqltest, dsn: x, queries: [{metric_name: m, query: q, values: [a], key_labels: [database]}]}]`,
+			err:  `metric m: duplicate label "database"`,
+		},
+		{
+			name: "inconsistent metric",
+			in: `databases:
+- {name: a, driver: sqltest, dsn: x, queries: [{metric_name: m, query: q, values: [a]}]}
+- {name: b, driver: sqltest, dsn: x, queries: [{metric_name: m, query: q, values: [a], type: counter}]}`,
+			err: "metric m is defined with different help, type, or labels",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.in), &cfg), tc.err)
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+databases:
+- name: shop
+  driver: sqltest
+  dsn: shop
+  queries:
+  - metric_name: shop_orders
+    help: Orders by status.
+    query: SELECT status, count(*) AS count, sum(total) AS total FROM orders GROUP BY status
+    key_labels: [status]
+    values: [count, total]
+    value_label: column
+  - metric_name: shop_up
+    query: SELECT 1 AS up
+    values: [up]
+- name: broken
+  driver: sqltest
+  dsn: broken
+  queries:
+  - metric_name: shop_up
+    query: SELECT up
+    values: [up]
+`), &cfg))
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP shop_orders Orders by status.
+# TYPE shop_orders gauge
+shop_orders{column="count",database="shop",status="pending"} 3
+shop_orders{column="count",database="shop",status="shipped"} 10
+shop_orders{column="total",database="shop",status="pending"} 42.5
+# HELP shop_up Result of a SQL query.
+# TYPE shop_up gauge
+shop_up{database="shop"} 1
+# HELP sql_queries_scrape_error Non-zero if this scrape failed.
+# TYPE sql_queries_scrape_error gauge
+sql_queries_scrape_error 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
@@ -0,0 +1,139 @@
+package squid_exporter //nolint:golint
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// counters are the cache manager counters exposed as metrics, along with
+// their help text.
+var counters = []struct{ key, help string }{
+	{"client_http.requests", "The total number of client requests."},
+	{"client_http.hits", "The total number of client cache hits."},
+	{"client_http.errors", "The total number of client errors."},
+	{"client_http.kbytes_in", "The total number of kilobytes received from clients."},
+	{"client_http.kbytes_out", "The total number of kilobytes sent to clients."},
+	{"client_http.hit_kbytes_out", "The total number of kilobytes sent to clients from cache hits."},
+	{"server.all.requests", "The total number of requests to servers."},
+	{"server.all.errors", "The total number of errors from servers."},
+	{"server.all.kbytes_in", "The total number of kilobytes received from servers."},
+	{"server.all.kbytes_out", "The total number of kilobytes sent to servers."},
+	{"server.http.requests", "The total number of HTTP requests to servers."},
+	{"server.http.errors", "The total number of HTTP errors from servers."},
+	{"server.http.kbytes_in", "The total number of kilobytes received from HTTP servers."},
+	{"server.http.kbytes_out", "The total number of kilobytes sent to HTTP servers."},
+	{"swap.outs", "The total number of objects saved to disk."},
+	{"swap.ins", "The total number of objects read from disk."},
+	{"swap.files_cleaned", "The total number of orphaned cache files removed."},
+	{"aborted_requests", "The total number of requests aborted by the client."},
+}
+
+type collector struct {
+	log     log.Logger
+	cfg     *Config
+	client  *http.Client
+	timeout time.Duration
+
+	up    *prometheus.Desc
+	descs map[string]*prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config) *collector {
+	col := &collector{
+		log:     l,
+		cfg:     c,
+		client:  &http.Client{},
+		timeout: c.Timeout,
+
+		up:    prometheus.NewDesc("squid_up", "Was the last query of Squid successful.", nil, nil),
+		descs: make(map[string]*prometheus.Desc, len(counters)),
+	}
+	for _, counter := range counters {
+		name := "squid_" + strings.ReplaceAll(counter.key, ".", "_") + "_total"
+		col.descs[counter.key] = prometheus.NewDesc(name, counter.help, nil, nil)
+	}
+	return col
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	values, err := c.fetch(ctx)
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to get Squid counters", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+	for key, desc := range c.descs {
+		if v, ok := values[key]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v)
+		}
+	}
+}
+
+// fetch requests the counters page of the cache manager.
+func (c *collector) fetch(ctx context.Context) (map[string]float64, error) {
+	url := fmt.Sprintf("http://%s/squid-internal-mgr/counters", c.cfg.Address)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.Username != "" || c.cfg.Password != "" {
+		req.SetBasicAuth(c.cfg.Username, string(c.cfg.Password))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return parseCounters(resp.Body)
+}
+
+// parseCounters parses lines of the form "key = value". Lines which don't
+// hold a number, such as sample_time, are ignored.
+func parseCounters(r io.Reader) (map[string]float64, error) {
+	values := make(map[string]float64)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		parts := strings.SplitN(s.Text(), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(parts[0])] = v
+	}
+	return values, s.Err()
+}
@@ -0,0 +1,80 @@
+// Package squid_exporter implements an integration which collects the
+// counters of the Squid cache manager, using the metric names of
+// https://github.com/boynux/squid-exporter.
+package squid_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig is the default config for squid_exporter.
+var DefaultConfig = Config{
+	Address: "localhost:3128",
+	Timeout: 5 * time.Second,
+}
+
+// Config controls the squid_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// Address of the Squid proxy (host:port).
+	Address string `yaml:"address,omitempty"`
+
+	// Username and Password authenticate against the cache manager when
+	// cachemgr_passwd is set in the Squid config.
+	Username string             `yaml:"username,omitempty"`
+	Password config_util.Secret `yaml:"password,omitempty"`
+
+	// Timeout of requesting the counters.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "squid_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new squid_exporter integration. The counters are requested
+// each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(newCollector(l, c))), nil
+}
@@ -0,0 +1,77 @@
+package squid_exporter //nolint:golint
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const countersPage = `sample_time = 1586447546.498658 (Thu, 09 Apr 2020 15:52:26 GMT)
+client_http.requests = 12
+client_http.hits = 3
+client_http.errors = 1
+client_http.kbytes_in = 4
+client_http.kbytes_out = 40
+server.all.requests = 9
+`
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`{username: admin, password: secret}`), &cfg))
+	require.Equal(t, DefaultConfig.Address, cfg.Address)
+
+	err := yaml.UnmarshalStrict([]byte(`address: squid`), &cfg)
+	require.EqualError(t, err, "invalid address: address squid: missing port in address")
+}
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "admin" || pass != "secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/squid-internal-mgr/counters" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(rw, countersPage)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig
+	cfg.Address = srv.Listener.Addr().String()
+	cfg.Username = "admin"
+	cfg.Password = "secret"
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(newCollector(log.NewNopLogger(), &cfg)))
+
+	expect := `
+# HELP squid_client_http_hits_total The total number of client cache hits.
+# TYPE squid_client_http_hits_total counter
+squid_client_http_hits_total 3
+# HELP squid_client_http_requests_total The total number of client requests.
+# TYPE squid_client_http_requests_total counter
+squid_client_http_requests_total 12
+# HELP squid_server_all_requests_total The total number of requests to servers.
+# TYPE squid_server_all_requests_total counter
+squid_server_all_requests_total 9
+# HELP squid_up Was the last query of Squid successful.
+# TYPE squid_up gauge
+squid_up 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect),
+		"squid_up", "squid_client_http_hits_total", "squid_client_http_requests_total", "squid_server_all_requests_total"))
+
+	// Wrong credentials only report squid_up.
+	cfg.Password = "wrong"
+	require.Equal(t, 0.0, testutil.ToFloat64(newCollector(log.NewNopLogger(), &cfg)))
+}
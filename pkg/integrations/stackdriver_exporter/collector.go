@@ -0,0 +1,169 @@
+package stackdriver_exporter //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+)
+
+type collector struct {
+	log     log.Logger
+	cfg     *Config
+	service *monitoring.Service
+	now     func() time.Time
+
+	scrapeError *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config, opts ...option.ClientOption) (*collector, error) {
+	opts = append([]option.ClientOption{option.WithScopes(monitoring.MonitoringReadScope)}, opts...)
+	if c.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(c.CredentialsFile))
+	}
+
+	svc, err := monitoring.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Monitoring client: %w", err)
+	}
+
+	return &collector{
+		log:     l,
+		cfg:     c,
+		service: svc,
+		now:     time.Now,
+
+		scrapeError: prometheus.NewDesc("stackdriver_exporter_scrape_error", "Non-zero if this scrape failed.", nil, nil),
+	}, nil
+}
+
+// Describe implements prometheus.Collector. Nothing is sent since the labels
+// of metrics are only known once they are requested, making collector an
+// unchecked collector.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	end := c.now().Add(-c.cfg.Delay)
+	start := end.Add(-c.cfg.Interval)
+
+	var scrapeError float64
+	for _, project := range c.cfg.ProjectIDs {
+		for _, m := range c.cfg.Metrics {
+			if err := c.collectMetric(ctx, project, m, start, end, ch); err != nil {
+				level.Error(c.log).Log("msg", "failed to get Cloud Monitoring metric", "project", project, "type", m.Type, "err", err)
+				scrapeError = 1
+			}
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError)
+}
+
+func (c *collector) collectMetric(ctx context.Context, project string, m Metric, start, end time.Time, ch chan<- prometheus.Metric) error {
+	filter := fmt.Sprintf("metric.type = %q", m.Type)
+	if m.Filter != "" {
+		filter += " AND " + m.Filter
+	}
+
+	call := c.service.Projects.TimeSeries.List("projects/" + project).
+		Filter(filter).
+		IntervalStartTime(start.UTC().Format(time.RFC3339)).
+		IntervalEndTime(end.UTC().Format(time.RFC3339)).
+		View("FULL")
+	if m.Aligner != "" {
+		call = call.
+			AggregationPerSeriesAligner(m.Aligner).
+			AggregationAlignmentPeriod(fmt.Sprintf("%ds", int64(m.AlignmentPeriod/time.Second)))
+	}
+	if m.Reducer != "" {
+		call = call.AggregationCrossSeriesReducer(m.Reducer).AggregationGroupByFields(m.GroupBy...)
+	}
+
+	return call.Pages(ctx, func(resp *monitoring.ListTimeSeriesResponse) error {
+		for _, ts := range resp.TimeSeries {
+			c.collectTimeSeries(m, ts, ch)
+		}
+		return nil
+	})
+}
+
+// collectTimeSeries sends the most recent point of ts. Points are returned
+// in reverse time order.
+func (c *collector) collectTimeSeries(m Metric, ts *monitoring.TimeSeries, ch chan<- prometheus.Metric) {
+	if len(ts.Points) == 0 || ts.Points[0].Value == nil {
+		return
+	}
+	v, ok := pointValue(ts.Points[0].Value)
+	if !ok {
+		return
+	}
+
+	var resourceType string
+	labels := make(map[string]string)
+	if ts.Resource != nil {
+		resourceType = ts.Resource.Type
+		for k, v := range ts.Resource.Labels {
+			labels[sanitize(k)] = v
+		}
+	}
+	if ts.Metric != nil {
+		for k, v := range ts.Metric.Labels {
+			labels[sanitize(k)] = v
+		}
+	}
+	if ts.Unit != "" {
+		labels["unit"] = ts.Unit
+	}
+
+	names := make([]string, 0, len(labels))
+	values := make([]string, 0, len(labels))
+	for k, v := range labels {
+		names = append(names, k)
+		values = append(values, v)
+	}
+
+	name := "stackdriver_" + sanitize(resourceType) + "_" + sanitize(m.Type)
+	desc := prometheus.NewDesc(name, "Cloud Monitoring metric "+m.Type+".", names, nil)
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, values...)
+}
+
+// pointValue returns the numeric value of a point. Distributions are
+// exposed as their mean.
+func pointValue(v *monitoring.TypedValue) (float64, bool) {
+	switch {
+	case v.DoubleValue != nil:
+		return *v.DoubleValue, true
+	case v.Int64Value != nil:
+		return float64(*v.Int64Value), true
+	case v.BoolValue != nil:
+		if *v.BoolValue {
+			return 1, true
+		}
+		return 0, true
+	case v.DistributionValue != nil:
+		return v.DistributionValue.Mean, true
+	default:
+		return 0, false
+	}
+}
+
+// sanitize converts a metric type or label name into a valid Prometheus
+// name, such as compute.googleapis.com/instance/cpu/utilization into
+// compute_googleapis_com_instance_cpu_utilization.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,150 @@
+// Package stackdriver_exporter implements an integration which pulls metrics
+// from Google Cloud Monitoring, using the metric names of
+// https://github.com/prometheus-community/stackdriver_exporter.
+package stackdriver_exporter //nolint:golint
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+)
+
+// DefaultConfig is the default config for stackdriver_exporter.
+var DefaultConfig = Config{
+	Interval: 5 * time.Minute,
+	Timeout:  30 * time.Second,
+}
+
+// Config controls the stackdriver_exporter integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// ProjectIDs are the projects to request metrics from.
+	ProjectIDs []string `yaml:"project_ids"`
+
+	// CredentialsFile is a service account key file. When unset,
+	// application default credentials are used.
+	CredentialsFile string `yaml:"credentials_file,omitempty"`
+
+	// Interval is how far in the past points are requested. The most
+	// recent point of each time series is exposed.
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	// Delay is how far in the past the requested interval ends.
+	Delay time.Duration `yaml:"delay,omitempty"`
+
+	// Timeout of requesting all metrics.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Metrics to request.
+	Metrics []Metric `yaml:"metrics,omitempty"`
+}
+
+// Metric selects a metric type to request and how to aggregate it.
+type Metric struct {
+	// Type of the metric, such as
+	// compute.googleapis.com/instance/cpu/utilization.
+	Type string `yaml:"type"`
+
+	// Filter is appended to the metric type filter to select resources,
+	// such as resource.labels.zone = "us-central1-a".
+	Filter string `yaml:"filter,omitempty"`
+
+	// Aligner aligns the points of each time series to AlignmentPeriod,
+	// such as ALIGN_MEAN or ALIGN_RATE.
+	Aligner         string        `yaml:"aligner,omitempty"`
+	AlignmentPeriod time.Duration `yaml:"alignment_period,omitempty"`
+
+	// Reducer combines aligned time series into one per GroupBy
+	// combination, such as REDUCE_SUM.
+	Reducer string   `yaml:"reducer,omitempty"`
+	GroupBy []string `yaml:"group_by,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if len(c.ProjectIDs) == 0 {
+		return fmt.Errorf("stackdriver_exporter requires at least one project_id")
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("stackdriver_exporter interval must be greater than 0")
+	}
+	if c.Delay < 0 {
+		return fmt.Errorf("stackdriver_exporter delay must not be negative")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("stackdriver_exporter timeout must be greater than 0")
+	}
+
+	types := make(map[string]struct{}, len(c.Metrics))
+	for idx := range c.Metrics {
+		m := &c.Metrics[idx]
+		if m.Type == "" {
+			return fmt.Errorf("stackdriver_exporter metric index %d must have a type", idx)
+		}
+		if _, ok := types[m.Type]; ok {
+			return fmt.Errorf("stackdriver_exporter metric %s is configured twice", m.Type)
+		}
+		types[m.Type] = struct{}{}
+
+		if m.Aligner != "" && !strings.HasPrefix(m.Aligner, "ALIGN_") {
+			return fmt.Errorf("stackdriver_exporter metric %s has invalid aligner %q", m.Type, m.Aligner)
+		}
+		if m.Reducer != "" && !strings.HasPrefix(m.Reducer, "REDUCE_") {
+			return fmt.Errorf("stackdriver_exporter metric %s has invalid reducer %q", m.Type, m.Reducer)
+		}
+		aligned := m.Aligner != "" && m.Aligner != "ALIGN_NONE"
+		if m.Reducer != "" && m.Reducer != "REDUCE_NONE" && !aligned {
+			return fmt.Errorf("stackdriver_exporter metric %s must set an aligner to use a reducer", m.Type)
+		}
+		if len(m.GroupBy) > 0 && (m.Reducer == "" || m.Reducer == "REDUCE_NONE") {
+			return fmt.Errorf("stackdriver_exporter metric %s must set a reducer to use group_by", m.Type)
+		}
+		if m.AlignmentPeriod == 0 {
+			m.AlignmentPeriod = c.Interval
+		} else if m.AlignmentPeriod < time.Minute || m.AlignmentPeriod%time.Second != 0 {
+			return fmt.Errorf("stackdriver_exporter metric %s alignment_period must be at least 1m and a whole number of seconds", m.Type)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "stackdriver_exporter"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new stackdriver_exporter integration. Metrics are requested
+// from Cloud Monitoring each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
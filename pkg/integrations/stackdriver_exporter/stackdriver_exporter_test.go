@@ -0,0 +1,132 @@
+package stackdriver_exporter //nolint:golint
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+project_ids: [my-project]
+metrics:
+- type: compute.googleapis.com/instance/cpu/utilization
+  filter: resource.labels.zone = "us-central1-a"
+- type: loadbalancing.googleapis.com/https/request_count
+  aligner: ALIGN_RATE
+  alignment_period: 1m
+  reducer: REDUCE_SUM
+  group_by: [resource.labels.url_map_name]
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, DefaultConfig.Interval, cfg.Metrics[0].AlignmentPeriod)
+	require.Equal(t, time.Minute, cfg.Metrics[1].AlignmentPeriod)
+
+	tt := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{
+			name: "no projects",
+			cfg:  `metrics: []`,
+			err:  "stackdriver_exporter requires at least one project_id",
+		},
+		{
+			name: "reducer without aligner",
+			cfg:  `{project_ids: [p], metrics: [{type: a, reducer: REDUCE_SUM}]}`,
+			err:  "stackdriver_exporter metric a must set an aligner to use a reducer",
+		},
+		{
+			name: "group_by without reducer",
+			cfg:  `{project_ids: [p], metrics: [{type: a, aligner: ALIGN_MEAN, group_by: [resource.labels.zone]}]}`,
+			err:  "stackdriver_exporter metric a must set a reducer to use group_by",
+		},
+		{
+			name: "invalid aligner",
+			cfg:  `{project_ids: [p], metrics: [{type: a, aligner: MEAN}]}`,
+			err:  `stackdriver_exporter metric a has invalid aligner "MEAN"`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg), tc.err)
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/projects/my-project/timeSeries" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.Query()
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(rw, `{"timeSeries": [
+			{
+				"metric": {"type": "compute.googleapis.com/instance/cpu/utilization", "labels": {"instance_name": "web-1"}},
+				"resource": {"type": "gce_instance", "labels": {"project_id": "my-project", "zone": "us-central1-a"}},
+				"metricKind": "GAUGE",
+				"valueType": "DOUBLE",
+				"points": [
+					{"interval": {"endTime": "2021-06-01T12:30:00Z"}, "value": {"doubleValue": 0.25}},
+					{"interval": {"endTime": "2021-06-01T12:29:00Z"}, "value": {"doubleValue": 0.5}}
+				]
+			},
+			{
+				"metric": {"type": "compute.googleapis.com/instance/cpu/utilization", "labels": {"instance_name": "web-2"}},
+				"resource": {"type": "gce_instance", "labels": {"project_id": "my-project", "zone": "us-central1-a"}},
+				"points": []
+			}
+		]}`)
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+project_ids: [my-project]
+metrics:
+- type: compute.googleapis.com/instance/cpu/utilization
+  filter: resource.labels.zone = "us-central1-a"
+  aligner: ALIGN_MEAN
+  alignment_period: 1m
+`), &cfg)
+	require.NoError(t, err)
+
+	col, err := newCollector(log.NewNopLogger(), &cfg, option.WithEndpoint(srv.URL+"/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	col.now = func() time.Time { return time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC) }
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(col))
+
+	expect := `
+# HELP stackdriver_exporter_scrape_error Non-zero if this scrape failed.
+# TYPE stackdriver_exporter_scrape_error gauge
+stackdriver_exporter_scrape_error 0
+# HELP stackdriver_gce_instance_compute_googleapis_com_instance_cpu_utilization Cloud Monitoring metric compute.googleapis.com/instance/cpu/utilization.
+# TYPE stackdriver_gce_instance_compute_googleapis_com_instance_cpu_utilization gauge
+stackdriver_gce_instance_compute_googleapis_com_instance_cpu_utilization{instance_name="web-1",project_id="my-project",zone="us-central1-a"} 0.25
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect)))
+
+	require.Equal(t, `metric.type = "compute.googleapis.com/instance/cpu/utilization" AND resource.labels.zone = "us-central1-a"`, query.Get("filter"))
+	require.Equal(t, "2021-06-01T12:25:00Z", query.Get("interval.startTime"))
+	require.Equal(t, "ALIGN_MEAN", query.Get("aggregation.perSeriesAligner"))
+	require.Equal(t, "60s", query.Get("aggregation.alignmentPeriod"))
+}
@@ -0,0 +1,104 @@
+package synthetic_checks //nolint:golint
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/integrations/blackbox_exporter/prober"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// runner runs every check on its interval and records the results.
+type runner struct {
+	log    log.Logger
+	checks []Check
+	client *http.Client
+
+	up       *prometheus.GaugeVec
+	duration *prometheus.GaugeVec
+}
+
+func newRunner(l log.Logger, c *Config) *runner {
+	return &runner{
+		log:    l,
+		checks: c.Checks,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				DisableKeepAlives: true,
+			},
+		},
+
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "synthetic_check_up",
+			Help: "Whether the latest run of the check succeeded.",
+		}, []string{"check", "type"}),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "synthetic_check_duration_seconds",
+			Help: "How long the latest run of the check took in seconds.",
+		}, []string{"check", "type"}),
+	}
+}
+
+// run runs the checks until ctx is canceled. Each check runs once
+// immediately and then on its interval.
+func (r *runner) run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, check := range r.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(check.Interval)
+			defer ticker.Stop()
+
+			for {
+				r.runCheck(ctx, check)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(check)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (r *runner) runCheck(ctx context.Context, check Check) {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	typ, target := check.typeAndTarget()
+
+	var err error
+	start := time.Now()
+	switch typ {
+	case TypeHTTP:
+		_, err = prober.HTTP(ctx, r.client, prober.HTTPOptions{}, target)
+	case TypeTCP:
+		_, err = prober.TCP(ctx, nil, target)
+	case TypeICMP:
+		_, err = prober.ICMP(ctx, target)
+	}
+	duration := time.Since(start)
+
+	// Don't record checks interrupted by the integration stopping.
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	up := 1.0
+	if err != nil {
+		level.Debug(r.log).Log("msg", "check failed", "check", check.Name, "err", err)
+		up = 0
+	}
+	r.duration.WithLabelValues(check.Name, typ).Set(duration.Seconds())
+	r.up.WithLabelValues(check.Name, typ).Set(up)
+}
@@ -0,0 +1,161 @@
+// Package synthetic_checks implements lightweight availability checks of
+// URLs and hosts defined inline in the agent config. It trades the
+// flexibility of the blackbox_exporter integration for simpler
+// configuration.
+package synthetic_checks //nolint:golint
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+)
+
+// Types of checks.
+const (
+	TypeHTTP = "http"
+	TypeTCP  = "tcp"
+	TypeICMP = "icmp"
+)
+
+// DefaultConfig is the default config for synthetic_checks.
+var DefaultConfig = Config{
+	Interval: time.Minute,
+	Timeout:  5 * time.Second,
+}
+
+// Config controls the synthetic_checks integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// Interval is the default interval between runs of a check.
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	// Timeout is the default timeout of a check.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Checks to run.
+	Checks []Check `yaml:"checks,omitempty"`
+}
+
+// Check is a single availability check. Exactly one of HTTP, TCP, or ICMP
+// must be set.
+type Check struct {
+	// Name of the check, used as the value of the check label. Defaults to
+	// the checked URL or host.
+	Name string `yaml:"name,omitempty"`
+
+	// HTTP is a URL to request. The check succeeds on a 2xx response.
+	HTTP string `yaml:"http,omitempty"`
+
+	// TCP is a host:port address to connect to.
+	TCP string `yaml:"tcp,omitempty"`
+
+	// ICMP is a host to send an echo request to.
+	ICMP string `yaml:"icmp,omitempty"`
+
+	// Interval and Timeout override the defaults of the Config when set.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+}
+
+// typeAndTarget returns the type of the check and what it checks. typ is
+// empty if not exactly one type is set.
+func (c *Check) typeAndTarget() (typ, target string) {
+	var n int
+	for _, t := range []struct{ typ, target string }{
+		{TypeHTTP, c.HTTP},
+		{TypeTCP, c.TCP},
+		{TypeICMP, c.ICMP},
+	} {
+		if t.target != "" {
+			typ, target = t.typ, t.target
+			n++
+		}
+	}
+	if n != 1 {
+		return "", ""
+	}
+	return typ, target
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.Interval <= 0 {
+		return fmt.Errorf("synthetic_checks interval must be greater than 0")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("synthetic_checks timeout must be greater than 0")
+	}
+
+	names := make(map[string]struct{}, len(c.Checks))
+	for idx := range c.Checks {
+		check := &c.Checks[idx]
+
+		_, target := check.typeAndTarget()
+		if target == "" {
+			return fmt.Errorf("synthetic_checks check index %d must set exactly one of http, tcp, or icmp", idx)
+		}
+		if check.Name == "" {
+			check.Name = target
+		}
+		if _, ok := names[check.Name]; ok {
+			return fmt.Errorf("synthetic_checks has two checks named %s", check.Name)
+		}
+		names[check.Name] = struct{}{}
+
+		if check.Interval == 0 {
+			check.Interval = c.Interval
+		}
+		if check.Timeout == 0 {
+			check.Timeout = c.Timeout
+		}
+		if check.Interval < 0 || check.Timeout < 0 {
+			return fmt.Errorf("synthetic_checks check %s must not have a negative interval or timeout", check.Name)
+		}
+		if check.Timeout > check.Interval {
+			return fmt.Errorf("synthetic_checks check %s must not have a timeout longer than its interval", check.Name)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "synthetic_checks"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new synthetic_checks integration. Checks run in the
+// background on their own interval, and scrapes report the result of the
+// latest run of each check.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	r := newRunner(l, c)
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(r.up, r.duration),
+		integrations.WithRunner(r.run),
+	), nil
+}
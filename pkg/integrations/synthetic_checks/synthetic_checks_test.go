@@ -0,0 +1,124 @@
+package synthetic_checks //nolint:golint
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+interval: 30s
+checks:
+- http: https://example.com
+- name: ssh
+  tcp: example.com:22
+  interval: 10s
+  timeout: 1s
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, []Check{
+		{Name: "https://example.com", HTTP: "https://example.com", Interval: 30 * time.Second, Timeout: DefaultConfig.Timeout},
+		{Name: "ssh", TCP: "example.com:22", Interval: 10 * time.Second, Timeout: time.Second},
+	}, cfg.Checks)
+
+	tt := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{
+			name: "no type",
+			cfg:  `checks: [{name: a}]`,
+			err:  "synthetic_checks check index 0 must set exactly one of http, tcp, or icmp",
+		},
+		{
+			name: "two types",
+			cfg:  `checks: [{tcp: "a:1", icmp: a}]`,
+			err:  "synthetic_checks check index 0 must set exactly one of http, tcp, or icmp",
+		},
+		{
+			name: "duplicate names",
+			cfg:  `checks: [{icmp: a}, {icmp: a}]`,
+			err:  "synthetic_checks has two checks named a",
+		},
+		{
+			name: "timeout longer than interval",
+			cfg:  `checks: [{icmp: a, interval: 1s, timeout: 2s}]`,
+			err:  "synthetic_checks check a must not have a timeout longer than its interval",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.EqualError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg), tc.err)
+		})
+	}
+}
+
+func TestRunner(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	// Find an address nothing is listening on by closing a listener.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	var cfg Config
+	err = yaml.UnmarshalStrict([]byte(`
+checks:
+- {name: ok, http: `+srv.URL+`}
+- {name: missing, http: `+srv.URL+`/missing}
+- {name: tcp_ok, tcp: `+srv.Listener.Addr().String()+`}
+- {name: tcp_closed, tcp: `+closedAddr+`}
+`), &cfg)
+	require.NoError(t, err)
+
+	r := newRunner(log.NewNopLogger(), &cfg)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(r.up, r.duration)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = r.run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Checks run once immediately after starting.
+	require.Eventually(t, func() bool {
+		return testutil.CollectAndCount(r.up) == len(cfg.Checks)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	expect := `
+# HELP synthetic_check_up Whether the latest run of the check succeeded.
+# TYPE synthetic_check_up gauge
+synthetic_check_up{check="missing",type="http"} 0
+synthetic_check_up{check="ok",type="http"} 1
+synthetic_check_up{check="tcp_closed",type="tcp"} 0
+synthetic_check_up{check="tcp_ok",type="tcp"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), "synthetic_check_up"))
+	require.Equal(t, len(cfg.Checks), testutil.CollectAndCount(r.duration))
+}
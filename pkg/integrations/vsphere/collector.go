@@ -0,0 +1,288 @@
+package vsphere
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+// sessionHeader holds the session ID of requests to the vCenter REST API.
+const sessionHeader = "vmware-api-session-id"
+
+// errUnauthorized is returned when vCenter rejects the session ID, which
+// happens after the session expires.
+var errUnauthorized = fmt.Errorf("unauthorized")
+
+// vm is an element of the response of /rest/vcenter/vm.
+type vm struct {
+	Name       string  `json:"name"`
+	PowerState string  `json:"power_state"`
+	CPUCount   float64 `json:"cpu_count"`
+	MemoryMiB  float64 `json:"memory_size_MiB"`
+}
+
+// host is an element of the response of /rest/vcenter/host.
+type host struct {
+	Name            string `json:"name"`
+	ConnectionState string `json:"connection_state"`
+	PowerState      string `json:"power_state"`
+}
+
+// datastore is an element of the response of /rest/vcenter/datastore.
+type datastore struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Capacity  float64 `json:"capacity"`
+	FreeSpace float64 `json:"free_space"`
+}
+
+// filter is a compiled ObjectFilter.
+type filter struct {
+	enabled bool
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+func newFilter(f ObjectFilter) (*filter, error) {
+	res := &filter{enabled: f.Enabled}
+
+	var err error
+	if res.include, err = regexp.Compile("^(?:" + f.Include + ")$"); err != nil {
+		return nil, err
+	}
+	if f.Exclude != "" {
+		if res.exclude, err = regexp.Compile("^(?:" + f.Exclude + ")$"); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (f *filter) match(name string) bool {
+	return f.include.MatchString(name) && (f.exclude == nil || !f.exclude.MatchString(name))
+}
+
+type collector struct {
+	log     log.Logger
+	url     string
+	client  *http.Client
+	cfg     *Config
+	timeout time.Duration
+
+	vms, hosts, datastores *filter
+
+	// mut protects session, which is reused across scrapes until vCenter
+	// rejects it.
+	mut     sync.Mutex
+	session string
+
+	up        *prometheus.Desc
+	vmDescs   struct{ powerState, numCPU, memoryMax *prometheus.Desc }
+	hostDescs struct{ powerState, connectionState *prometheus.Desc }
+	dsDescs   struct{ capacity, freeSpace *prometheus.Desc }
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	client, err := config_util.NewClientFromConfig(config_util.HTTPClientConfig{TLSConfig: c.TLSConfig}, "vsphere")
+	if err != nil {
+		return nil, err
+	}
+
+	col := &collector{
+		log:     l,
+		url:     strings.TrimSuffix(c.VCenterURL, "/"),
+		client:  client,
+		cfg:     c,
+		timeout: c.Timeout,
+
+		up: prometheus.NewDesc("vmware_up", "Was the last query of the vCenter API successful.", nil, nil),
+	}
+
+	if col.vms, err = newFilter(c.VMs); err != nil {
+		return nil, err
+	}
+	if col.hosts, err = newFilter(c.Hosts); err != nil {
+		return nil, err
+	}
+	if col.datastores, err = newFilter(c.Datastores); err != nil {
+		return nil, err
+	}
+
+	vmLabels := []string{"vm_name"}
+	col.vmDescs.powerState = prometheus.NewDesc("vmware_vm_power_state", "Whether the VM is powered on.", vmLabels, nil)
+	col.vmDescs.numCPU = prometheus.NewDesc("vmware_vm_num_cpu", "Number of virtual CPUs of the VM.", vmLabels, nil)
+	col.vmDescs.memoryMax = prometheus.NewDesc("vmware_vm_memory_max", "Memory of the VM in MiB.", vmLabels, nil)
+
+	hostLabels := []string{"host_name"}
+	col.hostDescs.powerState = prometheus.NewDesc("vmware_host_power_state", "Whether the host is powered on.", hostLabels, nil)
+	col.hostDescs.connectionState = prometheus.NewDesc("vmware_host_connection_state", "Whether the host is connected to vCenter.", hostLabels, nil)
+
+	dsLabels := []string{"ds_name", "ds_type"}
+	col.dsDescs.capacity = prometheus.NewDesc("vmware_datastore_capacity_size", "Capacity of the datastore in bytes.", dsLabels, nil)
+	col.dsDescs.freeSpace = prometheus.NewDesc("vmware_datastore_freespace_size", "Free space of the datastore in bytes.", dsLabels, nil)
+
+	return col, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.up,
+		c.vmDescs.powerState, c.vmDescs.numCPU, c.vmDescs.memoryMax,
+		c.hostDescs.powerState, c.hostDescs.connectionState,
+		c.dsDescs.capacity, c.dsDescs.freeSpace,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var (
+		vms        []vm
+		hosts      []host
+		datastores []datastore
+		err        error
+	)
+	if c.vms.enabled {
+		err = c.get(ctx, "/rest/vcenter/vm", &vms)
+	}
+	if err == nil && c.hosts.enabled {
+		err = c.get(ctx, "/rest/vcenter/host", &hosts)
+	}
+	if err == nil && c.datastores.enabled {
+		err = c.get(ctx, "/rest/vcenter/datastore", &datastores)
+	}
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to query the vCenter API", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+	for _, v := range vms {
+		if !c.vms.match(v.Name) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.vmDescs.powerState, prometheus.GaugeValue, boolToFloat(v.PowerState == "POWERED_ON"), v.Name)
+		ch <- prometheus.MustNewConstMetric(c.vmDescs.numCPU, prometheus.GaugeValue, v.CPUCount, v.Name)
+		ch <- prometheus.MustNewConstMetric(c.vmDescs.memoryMax, prometheus.GaugeValue, v.MemoryMiB, v.Name)
+	}
+	for _, h := range hosts {
+		if !c.hosts.match(h.Name) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.hostDescs.powerState, prometheus.GaugeValue, boolToFloat(h.PowerState == "POWERED_ON"), h.Name)
+		ch <- prometheus.MustNewConstMetric(c.hostDescs.connectionState, prometheus.GaugeValue, boolToFloat(h.ConnectionState == "CONNECTED"), h.Name)
+	}
+	for _, ds := range datastores {
+		if !c.datastores.match(ds.Name) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.dsDescs.capacity, prometheus.GaugeValue, ds.Capacity, ds.Name, ds.Type)
+		ch <- prometheus.MustNewConstMetric(c.dsDescs.freeSpace, prometheus.GaugeValue, ds.FreeSpace, ds.Name, ds.Type)
+	}
+}
+
+// get requests path from the vCenter REST API and decodes the value of the
+// JSON response into v. A new session is created if there's no session yet
+// or the current one expired.
+func (c *collector) get(ctx context.Context, path string, v interface{}) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.session != "" {
+		if err := c.do(ctx, path, v); err != errUnauthorized {
+			return err
+		}
+	}
+
+	session, err := c.login(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	c.session = session
+	return c.do(ctx, path, v)
+}
+
+// login creates a new session with the configured credentials.
+func (c *collector) login(ctx context.Context) (string, error) {
+	password := string(c.cfg.Password)
+	if c.cfg.PasswordFile != "" {
+		bb, err := ioutil.ReadFile(c.cfg.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read password file %s: %w", c.cfg.PasswordFile, err)
+		}
+		password = strings.TrimSpace(string(bb))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/rest/com/vmware/cis/session", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.cfg.Username, password)
+
+	var session string
+	if err := c.send(req, &session); err != nil {
+		return "", err
+	}
+	return session, nil
+}
+
+// do requests path with the current session.
+func (c *collector) do(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(sessionHeader, c.session)
+	return c.send(req, v)
+}
+
+// send sends req and decodes the value of the JSON response into v. The REST
+// API wraps every response in an object with a single value field.
+func (c *collector) send(req *http.Request, v interface{}) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return errUnauthorized
+	} else if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, req.URL.Path)
+	}
+
+	body := struct {
+		Value interface{} `json:"value"`
+	}{Value: v}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
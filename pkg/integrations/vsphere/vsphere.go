@@ -0,0 +1,140 @@
+// Package vsphere implements an integration which collects the state and
+// capacity of VMs, hosts, and datastores from the vCenter REST API, using
+// the metric names of https://github.com/pryorda/vmware_exporter.
+package vsphere
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig is the default config for vsphere.
+var DefaultConfig = Config{
+	VMs:        DefaultObjectFilter,
+	Hosts:      DefaultObjectFilter,
+	Datastores: DefaultObjectFilter,
+	Timeout:    30 * time.Second,
+}
+
+// DefaultObjectFilter collects every object of a type.
+var DefaultObjectFilter = ObjectFilter{
+	Enabled: true,
+	Include: ".*",
+}
+
+// Config controls the vsphere integration.
+type Config struct {
+	Common config.Common `yaml:",inline"`
+
+	// VCenterURL is the base URL of vCenter, such as https://vcenter.local.
+	VCenterURL string `yaml:"vcenter_url"`
+
+	// Username and Password of a vCenter user with read-only access.
+	Username     string             `yaml:"username"`
+	Password     config_util.Secret `yaml:"password,omitempty"`
+	PasswordFile string             `yaml:"password_file,omitempty"`
+
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+
+	// VMs, Hosts, and Datastores select the objects to collect.
+	VMs        ObjectFilter `yaml:"vms,omitempty"`
+	Hosts      ObjectFilter `yaml:"hosts,omitempty"`
+	Datastores ObjectFilter `yaml:"datastores,omitempty"`
+
+	// Timeout of collecting all objects.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// ObjectFilter selects the objects of a type to collect.
+type ObjectFilter struct {
+	// Enabled collects objects of the type.
+	Enabled bool `yaml:"enabled"`
+
+	// Include and Exclude are regular expressions matched against object
+	// names. An object is only collected if its name matches Include and
+	// doesn't match Exclude.
+	Include string `yaml:"include,omitempty"`
+	Exclude string `yaml:"exclude,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for ObjectFilter.
+func (f *ObjectFilter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*f = DefaultObjectFilter
+
+	type plain ObjectFilter
+	if err := unmarshal((*plain)(f)); err != nil {
+		return err
+	}
+
+	if _, err := regexp.Compile(f.Include); err != nil {
+		return fmt.Errorf("invalid include: %w", err)
+	}
+	if _, err := regexp.Compile(f.Exclude); err != nil {
+		return fmt.Errorf("invalid exclude: %w", err)
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.VCenterURL)
+	if err != nil {
+		return fmt.Errorf("invalid vcenter_url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("vcenter_url must be an http or https URL")
+	}
+	if c.Username == "" {
+		return fmt.Errorf("vsphere requires a username")
+	}
+	if c.Password != "" && c.PasswordFile != "" {
+		return fmt.Errorf("at most one of password and password_file must be configured")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config is for.
+func (c *Config) Name() string {
+	return "vsphere"
+}
+
+// CommonConfig returns the set of common settings shared across all integrations.
+func (c *Config) CommonConfig() config.Common {
+	return c.Common
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new vsphere integration. Objects are listed from vCenter
+// each time the integration is scraped.
+func New(l log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(l, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
@@ -0,0 +1,125 @@
+package vsphere
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+vcenter_url: https://vcenter.local
+username: monitoring
+password: secret
+vms:
+  exclude: template-.*
+datastores:
+  enabled: false
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, ObjectFilter{Enabled: true, Include: ".*", Exclude: "template-.*"}, cfg.VMs)
+	require.Equal(t, DefaultObjectFilter, cfg.Hosts)
+	require.False(t, cfg.Datastores.Enabled)
+
+	err = yaml.UnmarshalStrict([]byte(`
+vcenter_url: vcenter.local
+username: monitoring
+`), &cfg)
+	require.EqualError(t, err, "vcenter_url must be an http or https URL")
+
+	err = yaml.UnmarshalStrict([]byte(`
+vcenter_url: https://vcenter.local
+username: monitoring
+hosts:
+  include: "("
+`), &cfg)
+	require.Error(t, err)
+}
+
+func TestCollector(t *testing.T) {
+	var logins int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/com/vmware/cis/session" {
+			if user, pass, _ := r.BasicAuth(); r.Method != http.MethodPost || user != "monitoring" || pass != "secret" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			logins++
+			_, _ = io.WriteString(rw, `{"value": "session-1"}`)
+			return
+		}
+		if r.Header.Get(sessionHeader) != "session-1" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/rest/vcenter/vm":
+			_, _ = io.WriteString(rw, `{"value": [
+				{"vm": "vm-1", "name": "web", "power_state": "POWERED_ON", "cpu_count": 2, "memory_size_MiB": 4096},
+				{"vm": "vm-2", "name": "template-ubuntu", "power_state": "POWERED_OFF", "cpu_count": 1, "memory_size_MiB": 1024}
+			]}`)
+		case "/rest/vcenter/host":
+			_, _ = io.WriteString(rw, `{"value": [
+				{"host": "host-1", "name": "esx1", "connection_state": "CONNECTED", "power_state": "POWERED_ON"}
+			]}`)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+vcenter_url: `+srv.URL+`
+username: monitoring
+password: secret
+vms:
+  exclude: template-.*
+datastores:
+  enabled: false
+`), &cfg)
+	require.NoError(t, err)
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP vmware_host_connection_state Whether the host is connected to vCenter.
+# TYPE vmware_host_connection_state gauge
+vmware_host_connection_state{host_name="esx1"} 1
+# HELP vmware_host_power_state Whether the host is powered on.
+# TYPE vmware_host_power_state gauge
+vmware_host_power_state{host_name="esx1"} 1
+# HELP vmware_up Was the last query of the vCenter API successful.
+# TYPE vmware_up gauge
+vmware_up 1
+# HELP vmware_vm_memory_max Memory of the VM in MiB.
+# TYPE vmware_vm_memory_max gauge
+vmware_vm_memory_max{vm_name="web"} 4096
+# HELP vmware_vm_num_cpu Number of virtual CPUs of the VM.
+# TYPE vmware_vm_num_cpu gauge
+vmware_vm_num_cpu{vm_name="web"} 2
+# HELP vmware_vm_power_state Whether the VM is powered on.
+# TYPE vmware_vm_power_state gauge
+vmware_vm_power_state{vm_name="web"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+
+	// The session is reused across scrapes.
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+	require.Equal(t, 1, logins)
+
+	cfg.Password = "wrong"
+	col, err = newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, 0.0, testutil.ToFloat64(col))
+}
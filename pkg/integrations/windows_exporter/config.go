@@ -1,5 +1,8 @@
 package windows_exporter //nolint:golint
 import (
+	"strings"
+
+	"github.com/cortexproject/cortex/pkg/util/flagext"
 	"github.com/go-kit/kit/log"
 	"github.com/grafana/agent/pkg/integrations"
 	"github.com/grafana/agent/pkg/integrations/config"
@@ -9,6 +12,14 @@ func init() {
 	integrations.RegisterIntegration(&Config{})
 }
 
+// DefaultEnabledCollectors is the set of collectors used when
+// enabled_collectors isn't set.
+const DefaultEnabledCollectors = "cpu,cs,logical_disk,net,os,service,system,textfile"
+
+// defaultCollectorsPlaceholder expands to DefaultEnabledCollectors in
+// enabled_collectors.
+const defaultCollectorsPlaceholder = "[defaults]"
+
 // Config controls the windows_exporter integration.
 // All of these and their child fields are pointers so we can determine if the value was set or not.
 type Config struct {
@@ -16,6 +27,10 @@ type Config struct {
 
 	EnabledCollectors string `yaml:"enabled_collectors"`
 
+	// Collectors to enable or disable on top of EnabledCollectors.
+	EnableCollectors  flagext.StringSlice `yaml:"enable_collectors,omitempty"`
+	DisableCollectors flagext.StringSlice `yaml:"disable_collectors,omitempty"`
+
 	Exchange    ExchangeConfig    `yaml:"exchange,omitempty"`
 	IIS         IISConfig         `yaml:"iis,omitempty"`
 	TextFile    TextFileConfig    `yaml:"text_file,omitempty"`
@@ -43,6 +58,40 @@ func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error)
 	return New(l, c)
 }
 
+// collectorList returns the comma-separated list of collectors to run.
+func (c *Config) collectorList() string {
+	enabled := c.EnabledCollectors
+	if enabled == "" {
+		enabled = DefaultEnabledCollectors
+	}
+	enabled = strings.ReplaceAll(enabled, defaultCollectorsPlaceholder, DefaultEnabledCollectors)
+
+	disabled := make(map[string]struct{}, len(c.DisableCollectors))
+	for _, name := range c.DisableCollectors {
+		disabled[name] = struct{}{}
+	}
+
+	var (
+		res  []string
+		seen = make(map[string]struct{})
+	)
+	for _, name := range append(strings.Split(enabled, ","), c.EnableCollectors...) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := disabled[name]; ok {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		res = append(res, name)
+	}
+	return strings.Join(res, ",")
+}
+
 // ExchangeConfig handles settings for the windows_exporter Exchange collector
 type ExchangeConfig struct {
 	EnabledList string `yaml:"enabled_list,omitempty"`
@@ -70,6 +119,11 @@ type SMTPConfig struct {
 // ServiceConfig handles settings for the windows_exporter service collector
 type ServiceConfig struct {
 	Where string `yaml:"where_clause,omitempty"`
+
+	// WhiteList and BlackList are regular expressions matched against
+	// service names after the where clause is applied.
+	WhiteList string `yaml:"whitelist,omitempty"`
+	BlackList string `yaml:"blacklist,omitempty"`
 }
 
 // ProcessConfig handles settings for the windows_exporter process collector
@@ -0,0 +1,43 @@
+package windows_exporter //nolint:golint
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig_CollectorList(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`{}`), &cfg))
+	require.Equal(t, DefaultEnabledCollectors, cfg.collectorList())
+
+	err := yaml.UnmarshalStrict([]byte(`
+enabled_collectors: "[defaults],iis"
+enable_collectors: [mssql, iis]
+disable_collectors: [service, textfile]
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, "cpu,cs,logical_disk,net,os,system,iis,mssql", cfg.collectorList())
+}
+
+func TestServiceFilter(t *testing.T) {
+	f, err := newServiceFilter(ServiceConfig{})
+	require.NoError(t, err)
+	require.Nil(t, f)
+
+	f, err = newServiceFilter(ServiceConfig{WhiteList: "w3svc|mssql.*", BlackList: "mssqlfdlauncher"})
+	require.NoError(t, err)
+
+	desc := prometheus.NewDesc("windows_service_state", "", []string{"name", "state"}, nil)
+	for name, keep := range map[string]bool{
+		"w3svc":           true,
+		"mssqlserver":     true,
+		"mssqlfdlauncher": false,
+		"spooler":         false,
+	} {
+		m := prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, name, "running")
+		require.Equal(t, keep, f.keep(m), name)
+	}
+}
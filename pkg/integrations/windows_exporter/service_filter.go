@@ -0,0 +1,61 @@
+package windows_exporter //nolint:golint
+
+import (
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// serviceFilter drops metrics of the service collector whose service name
+// doesn't match the whitelist or matches the blacklist. windows_exporter
+// only supports filtering services with a WQL where clause, which can't
+// express regular expressions.
+type serviceFilter struct {
+	whitelist *regexp.Regexp
+	blacklist *regexp.Regexp
+}
+
+// newServiceFilter returns a nil filter if neither the whitelist nor the
+// blacklist is set.
+func newServiceFilter(c ServiceConfig) (*serviceFilter, error) {
+	if c.WhiteList == "" && c.BlackList == "" {
+		return nil, nil
+	}
+
+	var (
+		f   serviceFilter
+		err error
+	)
+	if c.WhiteList != "" {
+		if f.whitelist, err = regexp.Compile("^(?:" + c.WhiteList + ")$"); err != nil {
+			return nil, err
+		}
+	}
+	if c.BlackList != "" {
+		if f.blacklist, err = regexp.Compile("^(?:" + c.BlackList + ")$"); err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// keep returns whether m should be kept. Every metric of the service
+// collector has a name label with the name of the service.
+func (f *serviceFilter) keep(m prometheus.Metric) bool {
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		return false
+	}
+	for _, l := range pb.Label {
+		if l.GetName() != "name" {
+			continue
+		}
+		name := l.GetValue()
+		if f.whitelist != nil && !f.whitelist.MatchString(name) {
+			return false
+		}
+		return f.blacklist == nil || !f.blacklist.MatchString(name)
+	}
+	return true
+}
@@ -4,17 +4,52 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/grafana/agent/pkg/integrations"
+	"github.com/prometheus-community/windows_exporter/collector"
 	"github.com/prometheus-community/windows_exporter/exporter"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // New creates a new windows_exporter integration.
 func New(log log.Logger, c *Config) (integrations.Integration, error) {
+	filter, err := newServiceFilter(c.Service)
+	if err != nil {
+		return nil, err
+	}
+
 	configMap := exporter.GenerateConfigs()
 	c.applyConfig(configMap)
-	wc, err := exporter.NewWindowsCollector(c.Name(), c.EnabledCollectors, configMap)
+	wc, err := exporter.NewWindowsCollector(c.Name(), c.collectorList(), configMap)
 	if err != nil {
 		return nil, err
 	}
+	if sc, ok := wc.Collectors["service"]; ok && filter != nil {
+		wc.Collectors["service"] = &filteredServiceCollector{Collector: sc, filter: filter}
+	}
 	_ = level.Info(log).Log("msg", "Enabled windows_exporter collectors")
 	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(wc)), nil
 }
+
+// filteredServiceCollector applies a serviceFilter to the metrics of the
+// service collector.
+type filteredServiceCollector struct {
+	collector.Collector
+	filter *serviceFilter
+}
+
+func (c *filteredServiceCollector) Collect(ctx *collector.ScrapeContext, ch chan<- prometheus.Metric) error {
+	var (
+		inner = make(chan prometheus.Metric)
+		errCh = make(chan error, 1)
+	)
+	go func() {
+		defer close(inner)
+		errCh <- c.Collector.Collect(ctx, inner)
+	}()
+
+	for m := range inner {
+		if c.filter.keep(m) {
+			ch <- m
+		}
+	}
+	return <-errCh
+}
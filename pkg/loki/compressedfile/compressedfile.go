@@ -0,0 +1,345 @@
+package compressedfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/positions"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+const labelFilename = "filename"
+
+// PositionsFile returns the path of the positions file to use for
+// compressed files, given the positions file used by Promtail. Compressed
+// files use their own positions file since they track more than an offset
+// per file.
+func PositionsFile(promtailFile string) string {
+	ext := filepath.Ext(promtailFile)
+	return strings.TrimSuffix(promtailFile, ext) + ".compressed" + ext
+}
+
+// Manager reads compressed files for each Config.
+type Manager struct {
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	positions positions.Positions
+	targets   []*target
+}
+
+// NewManager creates and starts a Manager. Read lines are sent to handler
+// after being processed by the pipeline stages of their Config. How far each
+// file has been read is stored using posConfig.
+func NewManager(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, posConfig positions.Config, cfgs []Config) (*Manager, error) {
+	pos, err := positions.New(l, posConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create positions for compressed files: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{cancel: cancel, positions: pos}
+
+	for i := range cfgs {
+		t, err := newTarget(l, reg, handler, pos, &cfgs[i])
+		if err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("failed to create compressed file job %s: %w", cfgs[i].JobName, err)
+		}
+		m.targets = append(m.targets, t)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			t.run(ctx)
+		}()
+	}
+
+	return m, nil
+}
+
+// Stop stops reading files. Positions are saved before Stop returns.
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+	for _, t := range m.targets {
+		t.handler.Stop()
+	}
+	m.positions.Stop()
+}
+
+// target reads files for a single Config.
+type target struct {
+	cfg       *Config
+	log       log.Logger
+	handler   api.EntryHandler
+	positions positions.Positions
+
+	// known holds the files which have an entry in positions, so entries can
+	// be removed once the file is deleted.
+	known map[string]struct{}
+}
+
+func newTarget(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, pos positions.Positions, cfg *Config) (*target, error) {
+	l = log.With(l, "component", "compressedfile", "job", cfg.JobName)
+
+	pipeline, err := stages.NewPipeline(l, cfg.PipelineStages, &cfg.JobName, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &target{
+		cfg:       cfg,
+		log:       l,
+		handler:   limit.NewHandler(l, cfg.Limits, cfg.JobName, pipeline.Wrap(handler)),
+		positions: pos,
+		known:     make(map[string]struct{}),
+	}, nil
+}
+
+func (t *target) run(ctx context.Context) {
+	ticker := time.NewTicker(t.cfg.SyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		t.sync(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync reads every matching file which hasn't been fully read yet.
+//
+// Files are identified by their size and modification time rather than by
+// their path, since logrotate renames compressed files when rotating (e.g.,
+// app.log.1.gz becomes app.log.2.gz). Files modified within the last sync
+// period are skipped until they're no longer being written to.
+func (t *target) sync(ctx context.Context, now time.Time) {
+	files := t.matchFiles()
+
+	// Look up the positions of all files before any are updated, so a file
+	// which has been renamed finds the position stored under its old path.
+	read := make(map[fileID]position, len(files))
+	for path := range files {
+		if p, ok := parsePosition(t.positions.GetString(path)); ok {
+			read[p.id] = p
+		}
+	}
+
+	for path := range t.known {
+		if _, ok := files[path]; !ok {
+			t.positions.Remove(path)
+			delete(t.known, path)
+		}
+	}
+
+	for path, fi := range files {
+		if ctx.Err() != nil {
+			return
+		}
+
+		id := fileID{size: fi.Size(), modTime: fi.ModTime().UnixNano()}
+		if now.Sub(fi.ModTime()) < t.cfg.SyncPeriod {
+			continue
+		}
+
+		p, ok := read[id]
+		if !ok {
+			if t.cfg.MaxAge > 0 && now.Sub(fi.ModTime()) > t.cfg.MaxAge {
+				continue
+			}
+			p = position{id: id}
+		}
+		t.putPosition(path, p)
+		if p.complete {
+			continue
+		}
+
+		if err := t.readFile(ctx, path, p); err != nil {
+			level.Warn(t.log).Log("msg", "failed to read compressed file", "path", path, "err", err)
+		}
+	}
+}
+
+// matchFiles returns the regular files matching the configured paths which
+// can be decompressed.
+func (t *target) matchFiles() map[string]os.FileInfo {
+	files := make(map[string]os.FileInfo)
+	for _, pattern := range t.cfg.Paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			level.Warn(t.log).Log("msg", "invalid path pattern", "pattern", pattern, "err", err)
+			continue
+		}
+		for _, path := range matches {
+			if compression(path) == "" {
+				continue
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				level.Warn(t.log).Log("msg", "failed to stat file", "path", path, "err", err)
+				continue
+			} else if !fi.Mode().IsRegular() {
+				continue
+			}
+			files[path] = fi
+		}
+	}
+	return files
+}
+
+// readFile sends lines from path to the handler, starting at the
+// decompressed offset in p. The position is updated after every line, so
+// reading resumes where it left off if interrupted.
+func (t *target) readFile(ctx context.Context, path string, p position) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompress(compression(path), f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if p.offset > 0 {
+		if _, err := io.CopyN(ioutil.Discard, r, p.offset); err != nil {
+			return fmt.Errorf("failed to skip to offset %d: %w", p.offset, err)
+		}
+	}
+	if p.offset == 0 {
+		level.Info(t.log).Log("msg", "reading compressed file", "path", path)
+	}
+
+	lset := make(model.LabelSet, len(t.cfg.Labels)+1)
+	for k, v := range t.cfg.Labels {
+		lset[k] = v
+	}
+	lset[labelFilename] = model.LabelValue(path)
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if line != "" {
+			entry := api.Entry{
+				Labels: lset.Clone(),
+				Entry:  logproto.Entry{Timestamp: time.Now(), Line: strings.TrimRight(line, "\r\n")},
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case t.handler.Chan() <- entry:
+			}
+			p.offset += int64(len(line))
+		}
+
+		p.complete = err == io.EOF
+		t.putPosition(path, p)
+		if p.complete {
+			return nil
+		}
+	}
+}
+
+func (t *target) putPosition(path string, p position) {
+	t.positions.PutString(path, p.String())
+	t.known[path] = struct{}{}
+}
+
+// compression returns the compression used by path based on its extension,
+// or an empty string if path isn't compressed.
+func compression(path string) string {
+	switch filepath.Ext(path) {
+	case ".gz":
+		return "gzip"
+	case ".zst", ".zstd":
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+func decompress(kind string, r io.Reader) (io.ReadCloser, error) {
+	switch kind {
+	case "gzip":
+		return gzip.NewReader(r)
+	case "zstd":
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", kind)
+	}
+}
+
+// fileID identifies a compressed file independently of its path.
+type fileID struct {
+	size    int64
+	modTime int64
+}
+
+// position is how far a compressed file has been read.
+type position struct {
+	id fileID
+	// offset is the number of decompressed bytes which have been read.
+	offset   int64
+	complete bool
+}
+
+// String encodes p to be stored in a positions file.
+func (p position) String() string {
+	return fmt.Sprintf("%d:%d:%d:%t", p.id.size, p.id.modTime, p.offset, p.complete)
+}
+
+func parsePosition(s string) (position, bool) {
+	var (
+		p     position
+		parts = strings.Split(s, ":")
+		err   error
+	)
+	if len(parts) != 4 {
+		return p, false
+	}
+	if p.id.size, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return p, false
+	}
+	if p.id.modTime, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return p, false
+	}
+	if p.offset, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+		return p, false
+	}
+	if p.complete, err = strconv.ParseBool(parts[3]); err != nil {
+		return p, false
+	}
+	return p, true
+}
@@ -0,0 +1,185 @@
+package compressedfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/positions"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`{job_name: rotated, paths: [/var/log/*.gz]}`), &cfg))
+	require.Equal(t, 24*time.Hour, cfg.MaxAge)
+	require.Equal(t, 10*time.Second, cfg.SyncPeriod)
+
+	err := yaml.UnmarshalStrict([]byte(`job_name: rotated`), &cfg)
+	require.EqualError(t, err, "compressedfile: at least one path must be provided")
+}
+
+func TestPositionsFile(t *testing.T) {
+	require.Equal(t, "/tmp/positions/a.compressed.yml", PositionsFile("/tmp/positions/a.yml"))
+	require.Equal(t, "/tmp/positions/a.compressed", PositionsFile("/tmp/positions/a"))
+}
+
+func TestPosition(t *testing.T) {
+	p := position{id: fileID{size: 10, modTime: 20}, offset: 30, complete: true}
+	parsed, ok := parsePosition(p.String())
+	require.True(t, ok)
+	require.Equal(t, p, parsed)
+
+	// Offsets stored by Promtail's file target aren't valid positions.
+	_, ok = parsePosition("30")
+	require.False(t, ok)
+}
+
+// writeFile writes content to path compressed based on its extension, and
+// sets its modification time to mtime.
+func writeFile(t *testing.T, path string, content string, mtime time.Time) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	switch compression(path) {
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	case "zstd":
+		w, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	default:
+		buf.WriteString(content)
+	}
+
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+	require.NoError(t, os.Chtimes(path, mtime, mtime))
+}
+
+type testTarget struct {
+	*target
+	entries chan api.Entry
+}
+
+func newTestTarget(t *testing.T, dir string, cfg Config) *testTarget {
+	t.Helper()
+
+	pos, err := positions.New(log.NewNopLogger(), positions.Config{
+		SyncPeriod:    time.Minute,
+		PositionsFile: filepath.Join(dir, "positions.yml"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(pos.Stop)
+
+	entries := make(chan api.Entry, 100)
+	tgt, err := newTarget(log.NewNopLogger(), nil, api.NewEntryHandler(entries, func() {}), pos, &cfg)
+	require.NoError(t, err)
+	t.Cleanup(tgt.handler.Stop)
+
+	return &testTarget{target: tgt, entries: entries}
+}
+
+// lines waits for n entries to be sent and returns their lines.
+func (tt *testTarget) lines(t *testing.T, n int) []string {
+	t.Helper()
+
+	var res []string
+	for len(res) < n {
+		select {
+		case e := <-tt.entries:
+			require.Equal(t, model.LabelValue("rotated"), e.Labels["job"])
+			res = append(res, e.Line)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for entries", "got %v", res)
+		}
+	}
+	return res
+}
+
+func TestTarget_Sync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compressedfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		now   = time.Now()
+		mtime = now.Add(-time.Hour)
+	)
+	writeFile(t, filepath.Join(dir, "app.log.1.gz"), "a\nb\n", mtime)
+	writeFile(t, filepath.Join(dir, "app.log.2.zst"), "c\nd", mtime.Add(-time.Minute))
+	writeFile(t, filepath.Join(dir, "app.log"), "not compressed\n", mtime)
+	writeFile(t, filepath.Join(dir, "app.log.3.gz"), "too old\n", now.Add(-48*time.Hour))
+	writeFile(t, filepath.Join(dir, "app.log.4.gz"), "still being written\n", now)
+
+	tt := newTestTarget(t, dir, Config{
+		JobName:    "rotated",
+		Paths:      []string{filepath.Join(dir, "app.log*")},
+		MaxAge:     24 * time.Hour,
+		SyncPeriod: 10 * time.Second,
+		Labels:     model.LabelSet{"job": "rotated"},
+	})
+
+	tt.sync(context.Background(), now)
+	require.ElementsMatch(t, []string{"a", "b", "c", "d"}, tt.lines(t, 4))
+
+	// Files which were fully read aren't read again, even when they're
+	// renamed while rotating. Only the new file is read.
+	tt.sync(context.Background(), now)
+	require.NoError(t, os.Rename(filepath.Join(dir, "app.log.1.gz"), filepath.Join(dir, "app.log.2.gz")))
+	writeFile(t, filepath.Join(dir, "app.log.1.gz"), "e\n", mtime.Add(time.Minute))
+	tt.sync(context.Background(), now)
+	require.Equal(t, []string{"e"}, tt.lines(t, 1))
+
+	// Files are read once they stop being written to.
+	tt.sync(context.Background(), now.Add(time.Minute))
+	require.Equal(t, []string{"still being written"}, tt.lines(t, 1))
+	require.Empty(t, tt.entries)
+}
+
+func TestTarget_Resume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compressedfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		now  = time.Now()
+		path = filepath.Join(dir, "app.log.1.gz")
+	)
+	writeFile(t, path, "a\nb\nc\n", now.Add(-time.Hour))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+
+	tt := newTestTarget(t, dir, Config{
+		JobName:    "rotated",
+		Paths:      []string{path},
+		SyncPeriod: 10 * time.Second,
+		Labels:     model.LabelSet{"job": "rotated"},
+	})
+
+	// Pretend that the first line was read before the agent was restarted.
+	p := position{id: fileID{size: fi.Size(), modTime: fi.ModTime().UnixNano()}, offset: 2}
+	tt.positions.PutString(path, p.String())
+
+	tt.sync(context.Background(), now)
+	require.Equal(t, []string{"b", "c"}, tt.lines(t, 2))
+
+	p, ok := parsePosition(tt.positions.GetString(path))
+	require.True(t, ok)
+	require.Equal(t, int64(6), p.offset)
+	require.True(t, p.complete)
+}
@@ -0,0 +1,64 @@
+// Package compressedfile implements a logs target which reads rotated log
+// files that have been compressed with gzip or zstd.
+package compressedfile
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/prometheus/common/model"
+)
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	MaxAge:     24 * time.Hour,
+	SyncPeriod: 10 * time.Second,
+	Limits:     limit.DefaultConfig,
+}
+
+// Config configures reading compressed files.
+type Config struct {
+	// JobName identifies the config in logs and metrics.
+	JobName string `yaml:"job_name"`
+
+	// Paths are glob patterns of files to read. Only files ending in .gz,
+	// .zst, or .zstd are read.
+	Paths []string `yaml:"paths"`
+
+	// MaxAge skips files which were last modified longer than MaxAge ago and
+	// haven't been read before. 0 reads every file.
+	MaxAge time.Duration `yaml:"max_age"`
+
+	// SyncPeriod is how often to look for new files.
+	SyncPeriod time.Duration `yaml:"sync_period,omitempty"`
+
+	// Labels are added to every line, along with a filename label holding the
+	// path of the file.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// PipelineStages process each line read.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+
+	// Limits restrict the rate and size of lines before they are processed by
+	// PipelineStages.
+	Limits limit.Config `yaml:"limits_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if len(c.Paths) == 0 {
+		return fmt.Errorf("compressedfile: at least one path must be provided")
+	}
+	if c.SyncPeriod <= 0 {
+		return fmt.Errorf("compressedfile: sync_period must be greater than 0")
+	}
+	return nil
+}
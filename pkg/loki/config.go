@@ -4,7 +4,16 @@ import (
 	"flag"
 	"fmt"
 	"path/filepath"
+	"time"
 
+	"github.com/grafana/agent/pkg/loki/compressedfile"
+	"github.com/grafana/agent/pkg/loki/docker"
+	"github.com/grafana/agent/pkg/loki/fluentforward"
+	"github.com/grafana/agent/pkg/loki/gelf"
+	"github.com/grafana/agent/pkg/loki/heroku"
+	"github.com/grafana/agent/pkg/loki/kafka"
+	"github.com/grafana/agent/pkg/loki/snmptrap"
+	"github.com/grafana/agent/pkg/loki/spool"
 	"github.com/grafana/loki/clients/pkg/promtail/client"
 	"github.com/grafana/loki/clients/pkg/promtail/positions"
 	"github.com/grafana/loki/clients/pkg/promtail/scrapeconfig"
@@ -37,15 +46,50 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 //   3. No InstanceConfig may have an empty name.
 //   4. If InstanceConfig positions path is empty, shared PositionsDirectory
 //      must not be empty.
+//   5. windows_events scrape configs must set eventlog_name or xpath_query.
+//   6. No two windows_events scrape configs may have the same bookmark path.
+//   7. syslog scrape configs must set listen_address.
+//   8. No two syslog scrape configs may have the same listen address.
+//   9. Docker scrape configs must have a job name unique within their
+//      InstanceConfig.
+//  10. Kafka scrape configs must have a job name unique within their
+//      InstanceConfig.
+//  11. pipeline_metrics must set prom_instance and a positive write_interval.
+//  12. GELF scrape configs must have a job name unique within their
+//      InstanceConfig.
+//  13. Fluent forward scrape configs must have a job name unique within
+//      their InstanceConfig.
+//  14. journal scrape configs must have a job name unique within their
+//      InstanceConfig, since the journal cursor is stored in the positions
+//      file by job name.
+//  15. Heroku scrape configs must have a job name unique within their
+//      InstanceConfig.
+//  16. loki_push_api scrape configs must have a job name unique across all
+//      InstanceConfigs, since the metrics of their servers are named after
+//      the job.
+//  17. Compressed file scrape configs must have a job name unique within
+//      their InstanceConfig.
+//  18. SNMP trap scrape configs must have a job name unique within their
+//      InstanceConfig.
+//  19. No two InstanceConfigs may have the same spool directory.
 //
 // Defaults:
 //
 //   1. If a positions config is empty, it will be generated based on
 //      the InstanceConfig name and Config.PositionsDirectory.
+//   2. If a windows_events bookmark path is empty, it will be generated
+//      next to the positions file based on the InstanceConfig name and the
+//      job name.
+//   3. If a spool directory is empty, it will be generated next to the
+//      positions file based on the InstanceConfig name.
 func (c *Config) ApplyDefaults() error {
 	var (
 		names     = map[string]struct{}{}
 		positions = map[string]string{} // positions file name -> config using it
+		bookmarks = map[string]string{} // bookmark path -> config using it
+		listeners = map[string]string{} // syslog listen address -> config using it
+		pushJobs  = map[string]string{} // loki_push_api job name -> config using it
+		spools    = map[string]string{} // spool directory -> config using it
 	)
 
 	for idx, ic := range c.Configs {
@@ -67,6 +111,147 @@ func (c *Config) ApplyDefaults() error {
 			return fmt.Errorf("Loki configs %s and %s must have different positions file paths", orig, ic.Name)
 		}
 		positions[ic.PositionsConfig.PositionsFile] = ic.Name
+
+		if sc := ic.Spool; sc != nil {
+			if sc.Directory == "" {
+				dir := filepath.Dir(ic.PositionsConfig.PositionsFile)
+				sc.Directory = filepath.Join(dir, ic.Name+".spool")
+			}
+			if orig, ok := spools[sc.Directory]; ok {
+				return fmt.Errorf("Loki configs %s and %s must have different spool directories", orig, ic.Name)
+			}
+			spools[sc.Directory] = ic.Name
+		}
+
+		dockerJobs := map[string]struct{}{}
+		for idx, dc := range ic.DockerScrapeConfigs {
+			if dc.JobName == "" {
+				return fmt.Errorf("Loki config %s docker_scrape_configs index %d must have a job_name", ic.Name, idx)
+			}
+			if _, ok := dockerJobs[dc.JobName]; ok {
+				return fmt.Errorf("Loki config %s has two docker_scrape_configs with job_name %s", ic.Name, dc.JobName)
+			}
+			dockerJobs[dc.JobName] = struct{}{}
+		}
+
+		if pm := ic.PipelineMetrics; pm != nil {
+			if pm.PromInstance == "" {
+				return fmt.Errorf("Loki config %s: pipeline_metrics must set prom_instance", ic.Name)
+			}
+			if pm.WriteInterval <= 0 {
+				return fmt.Errorf("Loki config %s: pipeline_metrics write_interval must be greater than 0", ic.Name)
+			}
+		}
+
+		kafkaJobs := map[string]struct{}{}
+		for idx, kc := range ic.KafkaScrapeConfigs {
+			if kc.JobName == "" {
+				return fmt.Errorf("Loki config %s kafka_scrape_configs index %d must have a job_name", ic.Name, idx)
+			}
+			if _, ok := kafkaJobs[kc.JobName]; ok {
+				return fmt.Errorf("Loki config %s has two kafka_scrape_configs with job_name %s", ic.Name, kc.JobName)
+			}
+			kafkaJobs[kc.JobName] = struct{}{}
+		}
+
+		gelfJobs := map[string]struct{}{}
+		for idx, gc := range ic.GelfScrapeConfigs {
+			if gc.JobName == "" {
+				return fmt.Errorf("Loki config %s gelf_scrape_configs index %d must have a job_name", ic.Name, idx)
+			}
+			if _, ok := gelfJobs[gc.JobName]; ok {
+				return fmt.Errorf("Loki config %s has two gelf_scrape_configs with job_name %s", ic.Name, gc.JobName)
+			}
+			gelfJobs[gc.JobName] = struct{}{}
+		}
+
+		fluentForwardJobs := map[string]struct{}{}
+		for idx, fc := range ic.FluentForwardScrapeConfigs {
+			if fc.JobName == "" {
+				return fmt.Errorf("Loki config %s fluentforward_scrape_configs index %d must have a job_name", ic.Name, idx)
+			}
+			if _, ok := fluentForwardJobs[fc.JobName]; ok {
+				return fmt.Errorf("Loki config %s has two fluentforward_scrape_configs with job_name %s", ic.Name, fc.JobName)
+			}
+			fluentForwardJobs[fc.JobName] = struct{}{}
+		}
+
+		herokuJobs := map[string]struct{}{}
+		for idx, hc := range ic.HerokuScrapeConfigs {
+			if hc.JobName == "" {
+				return fmt.Errorf("Loki config %s heroku_scrape_configs index %d must have a job_name", ic.Name, idx)
+			}
+			if _, ok := herokuJobs[hc.JobName]; ok {
+				return fmt.Errorf("Loki config %s has two heroku_scrape_configs with job_name %s", ic.Name, hc.JobName)
+			}
+			herokuJobs[hc.JobName] = struct{}{}
+		}
+
+		compressedFileJobs := map[string]struct{}{}
+		for idx, cc := range ic.CompressedFileScrapeConfigs {
+			if cc.JobName == "" {
+				return fmt.Errorf("Loki config %s compressed_file_scrape_configs index %d must have a job_name", ic.Name, idx)
+			}
+			if _, ok := compressedFileJobs[cc.JobName]; ok {
+				return fmt.Errorf("Loki config %s has two compressed_file_scrape_configs with job_name %s", ic.Name, cc.JobName)
+			}
+			compressedFileJobs[cc.JobName] = struct{}{}
+		}
+
+		snmpTrapJobs := map[string]struct{}{}
+		for idx, sc := range ic.SnmpTrapScrapeConfigs {
+			if sc.JobName == "" {
+				return fmt.Errorf("Loki config %s snmptrap_scrape_configs index %d must have a job_name", ic.Name, idx)
+			}
+			if _, ok := snmpTrapJobs[sc.JobName]; ok {
+				return fmt.Errorf("Loki config %s has two snmptrap_scrape_configs with job_name %s", ic.Name, sc.JobName)
+			}
+			snmpTrapJobs[sc.JobName] = struct{}{}
+		}
+
+		journalJobs := map[string]struct{}{}
+		for _, sc := range ic.ScrapeConfig {
+			if sc.PushConfig != nil {
+				if orig, ok := pushJobs[sc.JobName]; ok {
+					return fmt.Errorf("Loki configs %s and %s must have different job names for loki_push_api scrape configs, found %s in both", orig, ic.Name, sc.JobName)
+				}
+				pushJobs[sc.JobName] = ic.Name
+			}
+
+			if sc.JournalConfig != nil {
+				if _, ok := journalJobs[sc.JobName]; ok {
+					return fmt.Errorf("Loki config %s has two journal scrape configs with job_name %s", ic.Name, sc.JobName)
+				}
+				journalJobs[sc.JobName] = struct{}{}
+			}
+
+			if syslog := sc.SyslogConfig; syslog != nil {
+				if syslog.ListenAddress == "" {
+					return fmt.Errorf("Loki config %s job %s: syslog must set listen_address", ic.Name, sc.JobName)
+				}
+				if orig, ok := listeners[syslog.ListenAddress]; ok {
+					return fmt.Errorf("Loki configs %s and %s must have different syslog listen addresses", orig, ic.Name)
+				}
+				listeners[syslog.ListenAddress] = ic.Name
+			}
+
+			wc := sc.WindowsConfig
+			if wc == nil {
+				continue
+			}
+			if wc.EventlogName == "" && wc.Query == "" {
+				return fmt.Errorf("Loki config %s job %s: windows_events must set eventlog_name or xpath_query", ic.Name, sc.JobName)
+			}
+
+			if wc.BookmarkPath == "" {
+				dir := filepath.Dir(ic.PositionsConfig.PositionsFile)
+				wc.BookmarkPath = filepath.Join(dir, fmt.Sprintf("%s.%s.bookmark.xml", ic.Name, sc.JobName))
+			}
+			if orig, ok := bookmarks[wc.BookmarkPath]; ok {
+				return fmt.Errorf("Loki configs %s and %s must have different windows_events bookmark paths", orig, ic.Name)
+			}
+			bookmarks[wc.BookmarkPath] = ic.Name
+		}
 	}
 
 	return nil
@@ -80,6 +265,47 @@ type InstanceConfig struct {
 	PositionsConfig positions.Config      `yaml:"positions,omitempty"`
 	ScrapeConfig    []scrapeconfig.Config `yaml:"scrape_configs,omitempty"`
 	TargetConfig    file.Config           `yaml:"target_config,omitempty"`
+
+	// PositionsMaxAge removes entries from the positions file when the
+	// instance starts if the file they track hasn't been modified for longer
+	// than PositionsMaxAge. 0 only removes entries of files which no longer
+	// exist.
+	PositionsMaxAge time.Duration `yaml:"positions_max_age,omitempty"`
+
+	// DockerScrapeConfigs discover containers from a Docker daemon and tail
+	// their logs. Promtail doesn't support Docker, so they are configured
+	// separately from ScrapeConfig.
+	DockerScrapeConfigs []docker.Config `yaml:"docker_scrape_configs,omitempty"`
+
+	// KafkaScrapeConfigs consume log lines from Kafka topics. Promtail doesn't
+	// support Kafka, so they are configured separately from ScrapeConfig.
+	KafkaScrapeConfigs []kafka.Config `yaml:"kafka_scrape_configs,omitempty"`
+
+	// GelfScrapeConfigs receive GELF messages over UDP or TCP, as sent by
+	// Graylog shippers.
+	GelfScrapeConfigs []gelf.Config `yaml:"gelf_scrape_configs,omitempty"`
+
+	// FluentForwardScrapeConfigs receive events sent with the Fluentd forward
+	// protocol, as sent by Fluentd and Fluent Bit.
+	FluentForwardScrapeConfigs []fluentforward.Config `yaml:"fluentforward_scrape_configs,omitempty"`
+
+	// HerokuScrapeConfigs receive logs from Heroku HTTPS log drains.
+	HerokuScrapeConfigs []heroku.Config `yaml:"heroku_scrape_configs,omitempty"`
+
+	// CompressedFileScrapeConfigs read rotated log files which have been
+	// compressed with gzip or zstd.
+	CompressedFileScrapeConfigs []compressedfile.Config `yaml:"compressed_file_scrape_configs,omitempty"`
+
+	// SnmpTrapScrapeConfigs receive SNMP traps and inform requests over UDP.
+	SnmpTrapScrapeConfigs []snmptrap.Config `yaml:"snmptrap_scrape_configs,omitempty"`
+
+	// PipelineMetrics configures writing metrics created by metrics pipeline
+	// stages to a metrics instance.
+	PipelineMetrics *PipelineMetricsConfig `yaml:"pipeline_metrics,omitempty"`
+
+	// Spool writes entries to disk before sending them to the clients, so
+	// they survive restarts and outages of Loki.
+	Spool *spool.Config `yaml:"spool,omitempty"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
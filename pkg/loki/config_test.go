@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/grafana/agent/pkg/loki/spool"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
 )
@@ -88,6 +89,238 @@ func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 				- name: config-b
 		  `),
 		},
+		{
+			name: "windows_events without channel or query",
+			err:  fmt.Errorf("Loki config config-a job windows: windows_events must set eventlog_name or xpath_query"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  scrape_configs:
+				  - job_name: windows
+				    windows_events:
+				      use_incoming_timestamp: true
+		  `),
+		},
+		{
+			name: "re-used windows_events bookmark path",
+			err:  fmt.Errorf("Loki configs config-a and config-b must have different windows_events bookmark paths"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  scrape_configs:
+				  - job_name: windows
+				    windows_events:
+				      eventlog_name: Application
+				      bookmark_path: /tmp/bookmark.xml
+				- name: config-b
+				  scrape_configs:
+				  - job_name: windows
+				    windows_events:
+				      eventlog_name: System
+				      bookmark_path: /tmp/bookmark.xml
+		  `),
+		},
+		{
+			name: "re-used spool directory",
+			err:  fmt.Errorf("Loki configs config-a and config-b must have different spool directories"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  spool:
+				    directory: /tmp/spool
+				- name: config-b
+				  spool:
+				    directory: /tmp/spool
+		  `),
+		},
+		{
+			name: "syslog without listen address",
+			err:  fmt.Errorf("Loki config config-a job syslog: syslog must set listen_address"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  scrape_configs:
+				  - job_name: syslog
+				    syslog:
+				      label_structured_data: true
+		  `),
+		},
+		{
+			name: "re-used syslog listen address",
+			err:  fmt.Errorf("Loki configs config-a and config-b must have different syslog listen addresses"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  scrape_configs:
+				  - job_name: syslog
+				    syslog:
+				      listen_address: 0.0.0.0:1514
+				- name: config-b
+				  scrape_configs:
+				  - job_name: syslog
+				    syslog:
+				      listen_address: 0.0.0.0:1514
+		  `),
+		},
+		{
+			name: "docker scrape config without job name",
+			err:  fmt.Errorf("Loki config config-a docker_scrape_configs index 0 must have a job_name"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  docker_scrape_configs:
+				  - docker_sd_config:
+				      host: unix:///var/run/docker.sock
+		  `),
+		},
+		{
+			name: "re-used docker job name",
+			err:  fmt.Errorf("Loki config config-a has two docker_scrape_configs with job_name docker"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  docker_scrape_configs:
+				  - job_name: docker
+				  - job_name: docker
+		  `),
+		},
+		{
+			name: "re-used kafka job name",
+			err:  fmt.Errorf("Loki config config-a has two kafka_scrape_configs with job_name kafka"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  kafka_scrape_configs:
+				  - job_name: kafka
+				    brokers: [localhost:9092]
+				    topics: [a]
+				  - job_name: kafka
+				    brokers: [localhost:9092]
+				    topics: [b]
+		  `),
+		},
+		{
+			name: "gelf scrape config without job name",
+			err:  fmt.Errorf("Loki config config-a gelf_scrape_configs index 0 must have a job_name"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  gelf_scrape_configs:
+				  - protocol: tcp
+		  `),
+		},
+		{
+			name: "re-used snmptrap job name",
+			err:  fmt.Errorf("Loki config config-a has two snmptrap_scrape_configs with job_name traps"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  snmptrap_scrape_configs:
+				  - job_name: traps
+				    listen_address: 0.0.0.0:162
+				  - job_name: traps
+				    listen_address: 0.0.0.0:1162
+		  `),
+		},
+		{
+			name: "re-used fluentforward job name",
+			err:  fmt.Errorf("Loki config config-a has two fluentforward_scrape_configs with job_name fluent"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  fluentforward_scrape_configs:
+				  - job_name: fluent
+				    listen_address: 0.0.0.0:24224
+				  - job_name: fluent
+				    listen_address: 0.0.0.0:24225
+		  `),
+		},
+		{
+			name: "re-used journal job name",
+			err:  fmt.Errorf("Loki config config-a has two journal scrape configs with job_name journal"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  scrape_configs:
+				  - job_name: journal
+				    journal:
+				      path: /var/log/journal
+				  - job_name: journal
+				    journal:
+				      path: /mnt/other/var/log/journal
+		  `),
+		},
+		{
+			name: "re-used heroku job name",
+			err:  fmt.Errorf("Loki config config-a has two heroku_scrape_configs with job_name heroku"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  heroku_scrape_configs:
+				  - job_name: heroku
+				    listen_address: 0.0.0.0:8080
+				  - job_name: heroku
+				    listen_address: 0.0.0.0:8081
+		  `),
+		},
+		{
+			name: "re-used compressed file job name",
+			err:  fmt.Errorf("Loki config config-a has two compressed_file_scrape_configs with job_name rotated"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  compressed_file_scrape_configs:
+				  - job_name: rotated
+				    paths: [/var/log/*.gz]
+				  - job_name: rotated
+				    paths: [/var/log/*.zst]
+		  `),
+		},
+		{
+			name: "re-used loki_push_api job name",
+			err:  fmt.Errorf("Loki configs config-a and config-b must have different job names for loki_push_api scrape configs, found push in both"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  scrape_configs:
+				  - job_name: push
+				    loki_push_api:
+				      server:
+				        http_listen_port: 3500
+				- name: config-b
+				  scrape_configs:
+				  - job_name: push
+				    loki_push_api:
+				      server:
+				        http_listen_port: 3501
+		  `),
+		},
+		{
+			name: "pipeline_metrics without prom_instance",
+			err:  fmt.Errorf("Loki config config-a: pipeline_metrics must set prom_instance"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  pipeline_metrics:
+				    write_interval: 30s
+		  `),
+		},
 	}
 
 	for _, tc := range tt {
@@ -125,6 +358,53 @@ func TestConfig_ApplyDefaults_Defaults(t *testing.T) {
 	require.Equal(t, filepath.Join("/tmp", "config-b.yml"), pathB)
 }
 
+func TestConfig_ApplyDefaults_WindowsEventsBookmark(t *testing.T) {
+	cfgText := untab(`
+		positions_directory: /tmp
+		configs:
+		- name: config-a
+			scrape_configs:
+			- job_name: application
+				windows_events:
+					eventlog_name: Application
+			- job_name: system
+				windows_events:
+					eventlog_name: System
+					bookmark_path: /var/lib/agent/system.xml
+	`)
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(cfgText), &cfg)
+	require.NoError(t, err)
+
+	var (
+		pathApplication = cfg.Configs[0].ScrapeConfig[0].WindowsConfig.BookmarkPath
+		pathSystem      = cfg.Configs[0].ScrapeConfig[1].WindowsConfig.BookmarkPath
+	)
+
+	require.Equal(t, filepath.Join("/tmp", "config-a.application.bookmark.xml"), pathApplication)
+	require.Equal(t, "/var/lib/agent/system.xml", pathSystem)
+}
+
+func TestConfig_ApplyDefaults_Spool(t *testing.T) {
+	cfgText := untab(`
+		positions_directory: /tmp
+		configs:
+		- name: config-a
+			spool:
+				max_size: 100MB
+		- name: config-b
+			spool:
+				directory: /var/lib/agent/spool
+	`)
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(cfgText), &cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, filepath.Join("/tmp", "config-a.spool"), cfg.Configs[0].Spool.Directory)
+	require.Equal(t, "/var/lib/agent/spool", cfg.Configs[1].Spool.Directory)
+	require.Equal(t, spool.DefaultConfig.MaxAge, cfg.Configs[1].Spool.MaxAge)
+}
+
 // untab is a utility function to make it easier to write YAML tests, where some editors
 // will insert tabs into strings by default.
 func untab(s string) string {
@@ -0,0 +1,55 @@
+// Package docker implements a logs target which discovers containers through
+// the Docker daemon and tails their logs.
+package docker
+
+import (
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/prometheus/prometheus/discovery/moby"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// DefaultHost is the address of the Docker daemon used when docker_sd_config
+// isn't provided.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	DockerSDConfig: defaultDockerSDConfig(),
+	Limits:         limit.DefaultConfig,
+}
+
+func defaultDockerSDConfig() moby.DockerSDConfig {
+	c := moby.DefaultDockerSDConfig
+	c.Host = DefaultHost
+	return c
+}
+
+// Config configures discovering containers from a Docker daemon and tailing
+// their logs.
+type Config struct {
+	// JobName identifies the config in logs and metrics.
+	JobName string `yaml:"job_name"`
+
+	// DockerSDConfig configures how to connect to the Docker daemon and which
+	// containers to discover.
+	DockerSDConfig moby.DockerSDConfig `yaml:"docker_sd_config,omitempty"`
+
+	// RelabelConfigs are applied to the labels of each discovered container.
+	// Containers whose labels are dropped are not tailed.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process each log line read from a container.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+
+	// Limits restrict the rate and size of lines before they are processed by
+	// PipelineStages.
+	Limits limit.Config `yaml:"limits_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	return unmarshal((*plain)(c))
+}
@@ -0,0 +1,268 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/moby"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+const (
+	containerIDLabel   = model.MetaLabelPrefix + "docker_container_id"
+	containerNameLabel = model.MetaLabelPrefix + "docker_container_name"
+)
+
+// Manager runs a job for each Config, tailing the logs of every discovered
+// container.
+type Manager struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	jobs   []*job
+}
+
+// NewManager creates and starts a Manager. Entries read from containers are
+// sent to handler after being processed by the pipeline stages of their
+// Config. Containers are tailed starting from when the Manager was created.
+func NewManager(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfgs []Config) (*Manager, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{cancel: cancel}
+
+	since := time.Now()
+	for i := range cfgs {
+		j, err := newJob(l, reg, handler, &cfgs[i], since)
+		if err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("failed to create docker job %s: %w", cfgs[i].JobName, err)
+		}
+		m.jobs = append(m.jobs, j)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			j.run(ctx)
+		}()
+	}
+
+	return m, nil
+}
+
+// Stop stops tailing all containers. Stop blocks until every entry read has
+// been sent to the handler.
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+	for _, j := range m.jobs {
+		j.handler.Stop()
+	}
+}
+
+// job discovers containers for a single Config and tails their logs.
+type job struct {
+	cfg        *Config
+	log        log.Logger
+	client     *client.Client
+	discoverer *moby.DockerDiscovery
+	handler    api.EntryHandler
+
+	// wg tracks running tailers.
+	wg sync.WaitGroup
+
+	mut     sync.Mutex
+	tailers map[string]*tailer
+	// positions holds the time to resume tailing each container from.
+	positions map[string]time.Time
+	since     time.Time
+}
+
+func newJob(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfg *Config, since time.Time) (*job, error) {
+	l = log.With(l, "component", "docker", "job", cfg.JobName)
+
+	discoverer, err := moby.NewDockerDiscovery(&cfg.DockerSDConfig, l)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := newClient(cfg.DockerSDConfig)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := stages.NewPipeline(l, cfg.PipelineStages, &cfg.JobName, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job{
+		cfg:        cfg,
+		log:        l,
+		client:     cli,
+		discoverer: discoverer,
+		handler:    limit.NewHandler(l, cfg.Limits, cfg.JobName, pipeline.Wrap(handler)),
+		tailers:    make(map[string]*tailer),
+		positions:  make(map[string]time.Time),
+		since:      since,
+	}, nil
+}
+
+// newClient creates a Docker client the same way as the Docker service
+// discovery, except without a timeout so log streams may be followed.
+func newClient(cfg moby.DockerSDConfig) (*client.Client, error) {
+	hostURL, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Opt{
+		client.WithHost(cfg.Host),
+		client.WithAPIVersionNegotiation(),
+	}
+	if hostURL.Scheme == "http" || hostURL.Scheme == "https" {
+		rt, err := config.NewRoundTripperFromConfig(cfg.HTTPClientConfig, "docker_logs", config.WithHTTP2Disabled())
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts,
+			client.WithHTTPClient(&http.Client{Transport: rt}),
+			client.WithScheme(hostURL.Scheme),
+		)
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+func (j *job) run(ctx context.Context) {
+	ch := make(chan []*targetgroup.Group)
+	go j.discoverer.Run(ctx, ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Tailers are stopped along with ctx.
+			j.wg.Wait()
+			return
+		case groups := <-ch:
+			j.sync(ctx, groups)
+		}
+	}
+}
+
+// sync starts tailing newly discovered containers and stops tailing
+// containers which are no longer discovered.
+func (j *job) sync(ctx context.Context, groups []*targetgroup.Group) {
+	discovered := containerLabels(groups)
+
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	for id, t := range j.tailers {
+		if _, ok := discovered[id]; !ok {
+			t.stop()
+			delete(j.tailers, id)
+		}
+	}
+	for id := range j.positions {
+		if _, ok := discovered[id]; !ok {
+			delete(j.positions, id)
+		}
+	}
+
+	for id, lset := range discovered {
+		if _, running := j.tailers[id]; running {
+			continue
+		}
+
+		since, ok := j.positions[id]
+		if !ok {
+			since = j.since
+		}
+		j.tailers[id] = newTailer(ctx, j, id, lset, since)
+	}
+}
+
+// containerLabels returns the discovered labels of each container by ID. The
+// Docker service discovery returns a target for each network and port of a
+// container; only the first target found for each container is used.
+func containerLabels(groups []*targetgroup.Group) map[string]model.LabelSet {
+	res := make(map[string]model.LabelSet)
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, target := range group.Targets {
+			lset := group.Labels.Merge(target)
+
+			id := string(lset[containerIDLabel])
+			if id == "" {
+				continue
+			}
+			if _, ok := res[id]; !ok {
+				res[id] = lset
+			}
+		}
+	}
+	return res
+}
+
+// targetLabels builds the labels to attach to the logs of a container from
+// its discovered labels and image. The container and image labels are set
+// before relabeling so they can be changed or dropped. Returns nil if the
+// container was dropped by relabeling.
+func targetLabels(discovered model.LabelSet, image string, cfgs []*relabel.Config) model.LabelSet {
+	lbls := make(map[string]string, len(discovered)+2)
+	for k, v := range discovered {
+		lbls[string(k)] = string(v)
+	}
+	lbls["container"] = strings.TrimPrefix(string(discovered[containerNameLabel]), "/")
+	lbls["image"] = image
+
+	processed := relabel.Process(labels.FromMap(lbls), cfgs...)
+	if processed == nil {
+		return nil
+	}
+
+	res := make(model.LabelSet, len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		res[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return res
+}
+
+// setPosition records the timestamp of the last entry read from a container.
+func (j *job) setPosition(id string, ts time.Time) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	if ts.After(j.positions[id]) {
+		j.positions[id] = ts
+	}
+}
+
+// tailerExited removes t from the running tailers, allowing the container to
+// be tailed again the next time it's discovered.
+func (j *job) tailerExited(t *tailer, err error) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	if err != nil {
+		level.Warn(j.log).Log("msg", "stopped tailing container", "container", t.id, "err", err)
+	}
+	if j.tailers[t.id] == t {
+		delete(j.tailers, t.id)
+	}
+}
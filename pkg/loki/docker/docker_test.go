@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig_Defaults(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte("job_name: docker"), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, DefaultHost, cfg.DockerSDConfig.Host)
+
+	err = yaml.UnmarshalStrict([]byte(`
+job_name: docker
+docker_sd_config:
+  host: tcp://localhost:2375
+  refresh_interval: 5s
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, "tcp://localhost:2375", cfg.DockerSDConfig.Host)
+	require.Equal(t, model.Duration(5*time.Second), cfg.DockerSDConfig.RefreshInterval)
+}
+
+func TestContainerLabels(t *testing.T) {
+	groups := []*targetgroup.Group{{
+		Labels: model.LabelSet{"group": "docker"},
+		Targets: []model.LabelSet{
+			{containerIDLabel: "a", "__meta_docker_port_private": "80"},
+			{containerIDLabel: "a", "__meta_docker_port_private": "443"},
+			{containerIDLabel: "b"},
+			{"__address__": "no-container-id:80"},
+		},
+	}, nil}
+
+	expect := map[string]model.LabelSet{
+		"a": {containerIDLabel: "a", "__meta_docker_port_private": "80", "group": "docker"},
+		"b": {containerIDLabel: "b", "group": "docker"},
+	}
+	require.Equal(t, expect, containerLabels(groups))
+}
+
+func TestTargetLabels(t *testing.T) {
+	discovered := model.LabelSet{
+		containerIDLabel:                    "abc",
+		containerNameLabel:                  "/nginx",
+		"__meta_docker_container_label_app": "web",
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		lset := targetLabels(discovered, "nginx:latest", nil)
+		require.Equal(t, model.LabelSet{"container": "nginx", "image": "nginx:latest"}, lset)
+	})
+
+	t.Run("relabel", func(t *testing.T) {
+		cfgs := []*relabel.Config{{
+			SourceLabels: model.LabelNames{"__meta_docker_container_label_app"},
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Separator:    ";",
+			Replacement:  "$1",
+			TargetLabel:  "app",
+			Action:       relabel.Replace,
+		}, {
+			Regex:  relabel.MustNewRegexp("image"),
+			Action: relabel.LabelDrop,
+		}}
+		lset := targetLabels(discovered, "nginx:latest", cfgs)
+		require.Equal(t, model.LabelSet{"container": "nginx", "app": "web"}, lset)
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		cfgs := []*relabel.Config{{
+			SourceLabels: model.LabelNames{"image"},
+			Regex:        relabel.MustNewRegexp("nginx:.*"),
+			Separator:    ";",
+			Action:       relabel.Drop,
+		}}
+		require.Nil(t, targetLabels(discovered, "nginx:latest", cfgs))
+	})
+}
+
+func TestReadLines(t *testing.T) {
+	input := strings.Join([]string{
+		"2021-06-01T12:00:00.000000001Z first line",
+		"2021-06-01T12:00:01Z second line",
+		"not timestamped",
+		"2021-06-01T12:00:02Z no trailing newline",
+	}, "\n")
+
+	type entry struct {
+		ts   time.Time
+		line string
+	}
+	var entries []entry
+	err := readLines(strings.NewReader(input), "stdout", func(stream string, ts time.Time, line string) bool {
+		require.Equal(t, "stdout", stream)
+		entries = append(entries, entry{ts, line})
+		return true
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	require.Equal(t, entry{time.Date(2021, 6, 1, 12, 0, 0, 1, time.UTC), "first line"}, entries[0])
+	require.Equal(t, entry{time.Date(2021, 6, 1, 12, 0, 1, 0, time.UTC), "second line"}, entries[1])
+	require.Equal(t, "not timestamped", entries[2].line)
+	require.Equal(t, entry{time.Date(2021, 6, 1, 12, 0, 2, 0, time.UTC), "no trailing newline"}, entries[3])
+}
+
+func TestReadLines_Stop(t *testing.T) {
+	var lines int
+	err := readLines(strings.NewReader("a\nb\nc\n"), "stderr", func(string, time.Time, string) bool {
+		lines++
+		return false
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, lines)
+}
+
+func TestFormatSince(t *testing.T) {
+	require.Equal(t, "", formatSince(time.Time{}))
+	require.Equal(t, "1622548800.000000001", formatSince(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,180 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+)
+
+// tailer follows the logs of a single container.
+type tailer struct {
+	id     string
+	cancel context.CancelFunc
+}
+
+// newTailer starts tailing the logs of the container with the given ID,
+// starting from since. The tailer is tracked by the job's WaitGroup and is
+// stopped when ctx is canceled.
+func newTailer(ctx context.Context, j *job, id string, discovered model.LabelSet, since time.Time) *tailer {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &tailer{id: id, cancel: cancel}
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		j.tailerExited(t, t.run(ctx, j, discovered, since))
+	}()
+	return t
+}
+
+// stop stops tailing the container without waiting for the tailer to exit.
+func (t *tailer) stop() {
+	t.cancel()
+}
+
+func (t *tailer) run(ctx context.Context, j *job, discovered model.LabelSet, since time.Time) error {
+	info, err := j.client.ContainerInspect(ctx, t.id)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	var image string
+	var tty bool
+	if info.Config != nil {
+		image, tty = info.Config.Image, info.Config.Tty
+	}
+	lset := targetLabels(discovered, image, j.cfg.RelabelConfigs)
+	if lset == nil {
+		level.Debug(j.log).Log("msg", "container dropped by relabeling", "container", t.id)
+
+		// Keep the tailer registered until the container is no longer
+		// discovered so it isn't inspected again on every refresh.
+		<-ctx.Done()
+		return nil
+	}
+
+	level.Debug(j.log).Log("msg", "tailing container", "container", t.id, "labels", lset.String())
+	rc, err := j.client.ContainerLogs(ctx, t.id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Timestamps: true,
+		Since:      formatSince(since),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read container logs: %w", err)
+	}
+	defer rc.Close()
+
+	send := func(stream string, ts time.Time, line string) bool {
+		entry := api.Entry{
+			Labels: lset.Merge(model.LabelSet{"stream": model.LabelValue(stream)}),
+			Entry:  logproto.Entry{Timestamp: ts, Line: line},
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case j.handler.Chan() <- entry:
+			j.setPosition(t.id, ts)
+			return true
+		}
+	}
+
+	// Containers with a TTY write both streams to a single raw stream.
+	// Otherwise, stdout and stderr are multiplexed over the connection.
+	if tty {
+		return readLines(rc, "stdout", send)
+	}
+
+	var (
+		wg                   sync.WaitGroup
+		stdoutR, stdoutW     = io.Pipe()
+		stderrR, stderrW     = io.Pipe()
+		stdoutErr, stderrErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stdoutErr = readLines(stdoutR, "stdout", send)
+		stdoutR.CloseWithError(stdoutErr)
+	}()
+	go func() {
+		defer wg.Done()
+		stderrErr = readLines(stderrR, "stderr", send)
+		stderrR.CloseWithError(stderrErr)
+	}()
+
+	_, err = stdcopy.StdCopy(stdoutW, stderrW, rc)
+	stdoutW.CloseWithError(err)
+	stderrW.CloseWithError(err)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	for _, e := range []error{err, stdoutErr, stderrErr} {
+		if e != nil && e != io.ErrClosedPipe {
+			return e
+		}
+	}
+	return nil
+}
+
+// readLines reads timestamped log lines from r and calls send for each of
+// them until r is exhausted or send returns false.
+func readLines(r io.Reader, stream string, send func(stream string, ts time.Time, line string) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			ts, text := parseLine(line)
+			if !send(stream, ts, text) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// parseLine splits a line written by Docker with timestamps enabled into its
+// timestamp and text. The current time is used if the line doesn't start with
+// a timestamp.
+func parseLine(line string) (time.Time, string) {
+	line = strings.TrimSuffix(line, "\n")
+
+	idx := strings.IndexByte(line, ' ')
+	if idx < 0 {
+		return time.Now(), line
+	}
+	ts, err := time.Parse(time.RFC3339Nano, line[:idx])
+	if err != nil {
+		return time.Now(), line
+	}
+	return ts, line[idx+1:]
+}
+
+// formatSince formats the time to read logs from. Docker includes entries
+// written at exactly since, so the next nanosecond is used to avoid reading
+// the last entry sent twice.
+func formatSince(since time.Time) string {
+	if since.IsZero() {
+		return ""
+	}
+	since = since.Add(time.Nanosecond)
+	return fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond())
+}
@@ -0,0 +1,54 @@
+// Package fluentforward implements a logs target which receives events sent
+// with the Fluentd forward protocol, as used by Fluentd and Fluent Bit.
+package fluentforward
+
+import (
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	ListenAddress: "0.0.0.0:24224",
+	Limits:        limit.DefaultConfig,
+}
+
+// Config configures a listener for the Fluentd forward protocol.
+type Config struct {
+	// JobName identifies the config in logs and metrics.
+	JobName string `yaml:"job_name"`
+
+	// ListenAddress is the TCP address to listen for events on.
+	ListenAddress string `yaml:"listen_address,omitempty"`
+
+	// MessageKey is the field of each record to use as the log line. When
+	// empty, or when a record doesn't have the field, the whole record is
+	// encoded as JSON and used as the log line.
+	MessageKey string `yaml:"message_key,omitempty"`
+
+	// UseIncomingTimestamp sets the timestamp of entries to the time of the
+	// event instead of the time it was received.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// Labels are added to every entry before relabeling.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// RelabelConfigs are applied to the labels of each received message.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process each received event.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+
+	// Limits restrict the rate and size of lines before they are processed by
+	// PipelineStages.
+	Limits limit.Config `yaml:"limits_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	return unmarshal((*plain)(c))
+}
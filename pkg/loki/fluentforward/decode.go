@@ -0,0 +1,230 @@
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/hashicorp/go-msgpack/codec"
+)
+
+// eventTimeExt is the msgpack extension type of EventTime, which encodes
+// seconds and nanoseconds as two big-endian 32-bit integers.
+const eventTimeExt = 0
+
+// newHandle returns the msgpack handle used to decode forward protocol
+// messages.
+func newHandle() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{RawToString: true}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}
+
+// event is a single event decoded from a forward protocol message.
+type event struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// message is a decoded forward protocol message. Messages may be sent in
+// Message, Forward, or PackedForward mode; all modes are decoded into a list
+// of events.
+type message struct {
+	tag    string
+	events []event
+	option map[string]interface{}
+}
+
+// chunk returns the chunk ID the client expects to be acknowledged, if any.
+func (m *message) chunk() (string, bool) {
+	chunk, ok := m.option["chunk"].(string)
+	return chunk, ok && chunk != ""
+}
+
+// decodeMessage decodes a forward protocol message from its msgpack array
+// representation.
+func decodeMessage(h *codec.MsgpackHandle, arr []interface{}) (*message, error) {
+	if len(arr) < 2 {
+		return nil, fmt.Errorf("message has %d elements, expected at least 2", len(arr))
+	}
+
+	var (
+		m  message
+		ok bool
+	)
+	if m.tag, ok = arr[0].(string); !ok {
+		return nil, fmt.Errorf("unexpected tag type %T", arr[0])
+	}
+
+	// The type of the second element identifies the mode. Message mode has a
+	// time and record after the tag, while the other modes have their entries
+	// as the second element. The option map is always the last element.
+	var packed []byte
+	switch v := arr[1].(type) {
+	case []interface{}:
+		// Forward mode.
+	case string:
+		// PackedForward mode; the entries are a msgpack stream.
+		packed = []byte(v)
+	case []byte:
+		packed = v
+	default:
+		// Message mode.
+		if len(arr) < 3 {
+			return nil, fmt.Errorf("message has %d elements, expected at least 3", len(arr))
+		}
+		option, err := optionAt(arr, 3)
+		if err != nil {
+			return nil, err
+		}
+		ev, err := decodeEntry(arr[1:3])
+		if err != nil {
+			return nil, err
+		}
+		m.events, m.option = []event{ev}, option
+		return &m, nil
+	}
+
+	var err error
+	if m.option, err = optionAt(arr, 2); err != nil {
+		return nil, err
+	}
+	if packed != nil {
+		m.events, err = decodePackedEntries(h, packed, m.option)
+	} else {
+		m.events, err = decodeEntries(arr[1].([]interface{}))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// optionAt returns the option map at index i of arr. A nil map is returned if
+// arr has no element at index i.
+func optionAt(arr []interface{}, i int) (map[string]interface{}, error) {
+	if len(arr) <= i || arr[i] == nil {
+		return nil, nil
+	}
+	option, ok := arr[i].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected option type %T", arr[i])
+	}
+	return option, nil
+}
+
+func decodeEntries(entries []interface{}) ([]event, error) {
+	events := make([]event, 0, len(entries))
+	for _, e := range entries {
+		arr, ok := e.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected entry type %T", e)
+		}
+		ev, err := decodeEntry(arr)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func decodePackedEntries(h *codec.MsgpackHandle, packed []byte, option map[string]interface{}) ([]event, error) {
+	var r io.Reader = bytes.NewReader(packed)
+	if compressed, _ := option["compressed"].(string); compressed != "" {
+		if compressed != "gzip" {
+			return nil, fmt.Errorf("unsupported compression %q", compressed)
+		}
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	var (
+		events []event
+		dec    = codec.NewDecoder(r, h)
+	)
+	for {
+		var arr []interface{}
+		if err := dec.Decode(&arr); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode packed entry: %w", err)
+		}
+		ev, err := decodeEntry(arr)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+}
+
+// decodeEntry decodes a [time, record] entry.
+func decodeEntry(arr []interface{}) (event, error) {
+	if len(arr) != 2 {
+		return event{}, fmt.Errorf("entry has %d elements, expected 2", len(arr))
+	}
+	ts, err := decodeTime(arr[0])
+	if err != nil {
+		return event{}, err
+	}
+	record, ok := arr[1].(map[string]interface{})
+	if !ok {
+		return event{}, fmt.Errorf("unexpected record type %T", arr[1])
+	}
+	return event{time: ts, record: record}, nil
+}
+
+// decodeTime decodes an event time, which is either an integer number of
+// seconds or an EventTime extension.
+func decodeTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case int64:
+		return time.Unix(v, 0), nil
+	case uint64:
+		return time.Unix(int64(v), 0), nil
+	case float64:
+		return time.Unix(0, int64(v*float64(time.Second))), nil
+	case codec.RawExt:
+		if v.Tag != eventTimeExt || len(v.Data) != 8 {
+			return time.Time{}, fmt.Errorf("invalid EventTime extension (type %d, length %d)", v.Tag, len(v.Data))
+		}
+		sec := binary.BigEndian.Uint32(v.Data[:4])
+		nsec := binary.BigEndian.Uint32(v.Data[4:])
+		return time.Unix(int64(sec), int64(nsec)), nil
+	default:
+		return time.Time{}, fmt.Errorf("unexpected time type %T", v)
+	}
+}
+
+// jsonValue converts v into a value which can be encoded as JSON. Binary
+// values are converted to strings instead of being base64 encoded.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			res[k] = jsonValue(e)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, e := range v {
+			res[i] = jsonValue(e)
+		}
+		return res
+	case codec.RawExt:
+		return v.Data
+	default:
+		return v
+	}
+}
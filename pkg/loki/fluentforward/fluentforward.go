@@ -0,0 +1,240 @@
+package fluentforward
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+const labelTag = "__fluentforward_tag"
+
+// Manager runs a listener for each Config.
+type Manager struct {
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	targets []*target
+}
+
+// NewManager creates and starts a Manager. Received events are sent to
+// handler after being processed by the pipeline stages of their Config.
+func NewManager(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfgs []Config) (*Manager, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{cancel: cancel}
+
+	for i := range cfgs {
+		t, err := newTarget(l, reg, handler, &cfgs[i])
+		if err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("failed to create fluentforward job %s: %w", cfgs[i].JobName, err)
+		}
+		m.targets = append(m.targets, t)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			t.acceptConnections(ctx, &m.wg)
+		}()
+	}
+
+	return m, nil
+}
+
+// Stop closes all listeners and connections. Stop blocks until every
+// received event has been sent to the handler.
+func (m *Manager) Stop() {
+	m.cancel()
+	for _, t := range m.targets {
+		_ = t.listener.Close()
+	}
+	m.wg.Wait()
+	for _, t := range m.targets {
+		t.handler.Stop()
+	}
+}
+
+// target listens for events for a single Config.
+type target struct {
+	cfg      *Config
+	log      log.Logger
+	handler  api.EntryHandler
+	listener net.Listener
+	mh       *codec.MsgpackHandle
+}
+
+func newTarget(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfg *Config) (*target, error) {
+	l = log.With(l, "component", "fluentforward", "job", cfg.JobName)
+
+	pipeline, err := stages.NewPipeline(l, cfg.PipelineStages, &cfg.JobName, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	lis, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	level.Info(l).Log("msg", "listening for forward protocol messages", "address", lis.Addr())
+
+	return &target{
+		cfg:      cfg,
+		log:      l,
+		handler:  limit.NewHandler(l, cfg.Limits, cfg.JobName, pipeline.Wrap(handler)),
+		listener: lis,
+		mh:       newHandle(),
+	}, nil
+}
+
+func (t *target) acceptConnections(ctx context.Context, wg *sync.WaitGroup) {
+	for {
+		conn, err := t.listener.Accept()
+		if ctx.Err() != nil {
+			return
+		} else if err != nil {
+			level.Warn(t.log).Log("msg", "failed to accept connection", "err", err)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.handleConnection(ctx, conn)
+		}()
+	}
+}
+
+// handleConnection decodes messages from conn until it's closed or ctx is
+// canceled. The connection is closed after a message fails to decode, since
+// there's no way to find the start of the next message.
+func (t *target) handleConnection(ctx context.Context, conn net.Conn) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+		_ = conn.Close()
+	}()
+
+	var (
+		dec = codec.NewDecoder(bufio.NewReader(conn), t.mh)
+		enc = codec.NewEncoder(conn, t.mh)
+	)
+	for {
+		var arr []interface{}
+		if err := dec.Decode(&arr); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				level.Warn(t.log).Log("msg", "failed to decode message", "remote", conn.RemoteAddr(), "err", err)
+			}
+			return
+		}
+
+		msg, err := decodeMessage(t.mh, arr)
+		if err != nil {
+			level.Warn(t.log).Log("msg", "dropping invalid message", "remote", conn.RemoteAddr(), "err", err)
+			continue
+		}
+		if !t.handleMessage(ctx, msg) {
+			return
+		}
+
+		// Clients which set a chunk option wait for it to be acknowledged
+		// before sending more messages.
+		if chunk, ok := msg.chunk(); ok {
+			if err := enc.Encode(map[string]string{"ack": chunk}); err != nil {
+				level.Warn(t.log).Log("msg", "failed to acknowledge message", "remote", conn.RemoteAddr(), "err", err)
+				return
+			}
+		}
+	}
+}
+
+// handleMessage sends the events of msg to the handler. Returns false if ctx
+// was canceled before all events were sent.
+func (t *target) handleMessage(ctx context.Context, msg *message) bool {
+	lset := messageLabels(t.cfg, msg.tag)
+	if lset == nil {
+		return true
+	}
+
+	for _, ev := range msg.events {
+		line, err := eventLine(t.cfg, ev.record)
+		if err != nil {
+			level.Warn(t.log).Log("msg", "dropping event which can't be encoded", "tag", msg.tag, "err", err)
+			continue
+		}
+
+		ts := time.Now()
+		if t.cfg.UseIncomingTimestamp {
+			ts = ev.time
+		}
+
+		entry := api.Entry{
+			Labels: lset.Clone(),
+			Entry:  logproto.Entry{Timestamp: ts, Line: line},
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case t.handler.Chan() <- entry:
+		}
+	}
+	return true
+}
+
+// eventLine returns the log line for a record.
+func eventLine(cfg *Config, record map[string]interface{}) (string, error) {
+	if cfg.MessageKey != "" {
+		switch v := record[cfg.MessageKey].(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		}
+	}
+
+	bb, err := json.Marshal(jsonValue(record))
+	if err != nil {
+		return "", err
+	}
+	return string(bb), nil
+}
+
+// messageLabels builds the labels to attach to the events of a message with
+// the given tag. Returns nil if the message was dropped by relabeling.
+func messageLabels(cfg *Config, tag string) model.LabelSet {
+	lbls := make(map[string]string, len(cfg.Labels)+1)
+	for k, v := range cfg.Labels {
+		lbls[string(k)] = string(v)
+	}
+	lbls[labelTag] = tag
+
+	processed := relabel.Process(labels.FromMap(lbls), cfg.RelabelConfigs...)
+	if processed == nil {
+		return nil
+	}
+
+	res := make(model.LabelSet, len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		res[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return res
+}
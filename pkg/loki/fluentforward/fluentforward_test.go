@@ -0,0 +1,214 @@
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig_Defaults(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`job_name: fluent`), &cfg))
+	require.Equal(t, "0.0.0.0:24224", cfg.ListenAddress)
+}
+
+var testTime = time.Date(2021, 6, 1, 12, 0, 0, 500, time.UTC)
+
+func eventTime(ts time.Time) codec.RawExt {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data[:4], uint32(ts.Unix()))
+	binary.BigEndian.PutUint32(data[4:], uint32(ts.Nanosecond()))
+	return codec.RawExt{Tag: eventTimeExt, Data: data}
+}
+
+// encode encodes values the same way clients do.
+func encode(t *testing.T, vs ...interface{}) []byte {
+	t.Helper()
+
+	var (
+		buf bytes.Buffer
+		enc = codec.NewEncoder(&buf, &codec.MsgpackHandle{WriteExt: true})
+	)
+	for _, v := range vs {
+		require.NoError(t, enc.Encode(v))
+	}
+	return buf.Bytes()
+}
+
+// decode decodes a message the same way the target does.
+func decode(t *testing.T, raw []byte) *message {
+	t.Helper()
+
+	h := newHandle()
+	var arr []interface{}
+	require.NoError(t, codec.NewDecoderBytes(raw, h).Decode(&arr))
+
+	msg, err := decodeMessage(h, arr)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestDecodeMessage(t *testing.T) {
+	var (
+		record = map[string]interface{}{"log": "hello"}
+		entry  = []interface{}{eventTime(testTime), record}
+	)
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write(encode(t, entry, entry))
+	require.NoError(t, gw.Close())
+
+	tt := []struct {
+		name string
+		msg  []interface{}
+	}{
+		{
+			name: "message",
+			msg:  []interface{}{"app", eventTime(testTime), record, map[string]interface{}{"chunk": "abc"}},
+		},
+		{
+			name: "message with integer time",
+			msg:  []interface{}{"app", testTime.Unix(), record, map[string]interface{}{"chunk": "abc"}},
+		},
+		{
+			name: "forward",
+			msg:  []interface{}{"app", []interface{}{entry, entry}, map[string]interface{}{"chunk": "abc"}},
+		},
+		{
+			name: "packed forward",
+			msg:  []interface{}{"app", encode(t, entry, entry), map[string]interface{}{"chunk": "abc"}},
+		},
+		{
+			name: "compressed packed forward",
+			msg:  []interface{}{"app", gz.Bytes(), map[string]interface{}{"chunk": "abc", "compressed": "gzip"}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := decode(t, encode(t, tc.msg))
+			require.Equal(t, "app", msg.tag)
+
+			chunk, ok := msg.chunk()
+			require.True(t, ok)
+			require.Equal(t, "abc", chunk)
+
+			require.NotEmpty(t, msg.events)
+			for _, ev := range msg.events {
+				require.Equal(t, map[string]interface{}{"log": "hello"}, ev.record)
+				if _, isInt := tc.msg[1].(int64); isInt {
+					require.Equal(t, testTime.Unix(), ev.time.Unix())
+				} else {
+					require.True(t, testTime.Equal(ev.time))
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeMessage_Invalid(t *testing.T) {
+	h := newHandle()
+
+	_, err := decodeMessage(h, []interface{}{"app"})
+	require.EqualError(t, err, "message has 1 elements, expected at least 2")
+
+	_, err = decodeMessage(h, []interface{}{"app", int64(1)})
+	require.EqualError(t, err, "message has 2 elements, expected at least 3")
+
+	_, err = decodeMessage(h, []interface{}{"app", int64(1), "record"})
+	require.EqualError(t, err, "unexpected record type string")
+
+	_, err = decodeMessage(h, []interface{}{"app", []interface{}{}, map[string]interface{}{"compressed": "zstd"}})
+	require.NoError(t, err)
+
+	_, err = decodeMessage(h, []interface{}{"app", []byte{}, map[string]interface{}{"compressed": "zstd"}})
+	require.EqualError(t, err, `unsupported compression "zstd"`)
+}
+
+func TestEventLine(t *testing.T) {
+	record := map[string]interface{}{"log": "hello", "stream": []byte("stdout")}
+
+	line, err := eventLine(&Config{}, record)
+	require.NoError(t, err)
+	require.Equal(t, `{"log":"hello","stream":"stdout"}`, line)
+
+	line, err = eventLine(&Config{MessageKey: "log"}, record)
+	require.NoError(t, err)
+	require.Equal(t, "hello", line)
+
+	line, err = eventLine(&Config{MessageKey: "message"}, record)
+	require.NoError(t, err)
+	require.Equal(t, `{"log":"hello","stream":"stdout"}`, line)
+}
+
+func TestMessageLabels(t *testing.T) {
+	cfg := &Config{
+		Labels: model.LabelSet{"job": "fluent"},
+		RelabelConfigs: []*relabel.Config{{
+			SourceLabels: model.LabelNames{"__fluentforward_tag"},
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Separator:    ";",
+			Replacement:  "$1",
+			TargetLabel:  "tag",
+			Action:       relabel.Replace,
+		}, {
+			SourceLabels: model.LabelNames{"__fluentforward_tag"},
+			Regex:        relabel.MustNewRegexp("debug.*"),
+			Separator:    ";",
+			Action:       relabel.Drop,
+		}},
+	}
+
+	require.Equal(t, model.LabelSet{"job": "fluent", "tag": "app"}, messageLabels(cfg, "app"))
+	require.Nil(t, messageLabels(cfg, "debug.app"))
+}
+
+func TestManager(t *testing.T) {
+	entries := make(chan api.Entry, 10)
+	handler := api.NewEntryHandler(entries, func() {})
+
+	m, err := NewManager(log.NewNopLogger(), prometheus.NewRegistry(), handler, []Config{{
+		JobName:              "fluent",
+		ListenAddress:        "127.0.0.1:0",
+		MessageKey:           "log",
+		UseIncomingTimestamp: true,
+		Labels:               model.LabelSet{"job": "fluent"},
+	}})
+	require.NoError(t, err)
+	defer m.Stop()
+
+	conn, err := net.Dial("tcp", m.targets[0].listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	entry := []interface{}{eventTime(testTime), map[string]interface{}{"log": "hello"}}
+	_, err = conn.Write(encode(t, []interface{}{"app", []interface{}{entry}, map[string]interface{}{"chunk": "abc"}}))
+	require.NoError(t, err)
+
+	select {
+	case e := <-entries:
+		require.Equal(t, model.LabelSet{"job": "fluent"}, e.Labels)
+		require.Equal(t, "hello", e.Line)
+		require.True(t, testTime.Equal(e.Timestamp))
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for entry")
+	}
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var ack map[string]interface{}
+	require.NoError(t, codec.NewDecoder(conn, newHandle()).Decode(&ack))
+	require.Equal(t, map[string]interface{}{"ack": "abc"}, ack)
+}
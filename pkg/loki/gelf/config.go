@@ -0,0 +1,71 @@
+// Package gelf implements a logs target which receives Graylog Extended Log
+// Format (GELF) messages over UDP or TCP.
+package gelf
+
+import (
+	"fmt"
+
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Supported protocols.
+const (
+	ProtocolUDP = "udp"
+	ProtocolTCP = "tcp"
+)
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	ListenAddress: "0.0.0.0:12201",
+	Protocol:      ProtocolUDP,
+	Limits:        limit.DefaultConfig,
+}
+
+// Config configures a listener for GELF messages.
+type Config struct {
+	// JobName identifies the config in logs and metrics.
+	JobName string `yaml:"job_name"`
+
+	// ListenAddress is the address to listen for messages on.
+	ListenAddress string `yaml:"listen_address,omitempty"`
+	// Protocol to receive messages over; udp or tcp. Messages sent over UDP
+	// may be chunked and compressed with gzip or zlib. Messages sent over TCP
+	// must be uncompressed and delimited by a null byte.
+	Protocol string `yaml:"protocol,omitempty"`
+
+	// UseIncomingTimestamp sets the timestamp of entries to the timestamp of
+	// the GELF message instead of the time it was received.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// Labels are added to every entry before relabeling.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// RelabelConfigs are applied to the labels of each message.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process each received message.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+
+	// Limits restrict the rate and size of lines before they are processed by
+	// PipelineStages.
+	Limits limit.Config `yaml:"limits_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	switch c.Protocol {
+	case ProtocolUDP, ProtocolTCP:
+	default:
+		return fmt.Errorf("gelf: invalid protocol %q: must be udp or tcp", c.Protocol)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+const (
+	// maxChunks is the maximum number of chunks a message may be split into.
+	maxChunks = 128
+	// chunkTimeout is how long to wait for all chunks of a message to arrive.
+	chunkTimeout = 5 * time.Second
+	// chunkHeaderSize is the size of the header of each chunk: 2 magic bytes,
+	// an 8 byte message ID, a sequence number, and a sequence count.
+	chunkHeaderSize = 12
+)
+
+var (
+	magicChunked = []byte{0x1e, 0x0f}
+	magicGzip    = []byte{0x1f, 0x8b}
+)
+
+// decompress returns the payload of an unchunked UDP datagram, decompressing
+// it if needed.
+func decompress(payload []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(payload, magicGzip):
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+
+	case len(payload) >= 2 && payload[0] == 0x78 && binary.BigEndian.Uint16(payload[:2])%31 == 0:
+		// zlib streams start with 0x78 for the default window size, followed by
+		// a byte which makes the first two bytes a multiple of 31.
+		r, err := zlib.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+
+	default:
+		return payload, nil
+	}
+}
+
+// chunkedMessage is a message whose chunks are still being received.
+type chunkedMessage struct {
+	chunks   [][]byte
+	received int
+	first    time.Time
+}
+
+// assembler reassembles chunked UDP messages.
+type assembler struct {
+	mut      sync.Mutex
+	messages map[uint64]*chunkedMessage
+}
+
+func newAssembler() *assembler {
+	return &assembler{messages: make(map[uint64]*chunkedMessage)}
+}
+
+// add adds a datagram to the assembler. If the datagram isn't chunked or
+// completes a message, the decompressed message is returned. nil is returned
+// while waiting for more chunks.
+func (a *assembler) add(datagram []byte, now time.Time) ([]byte, error) {
+	if !bytes.HasPrefix(datagram, magicChunked) {
+		return decompress(datagram)
+	}
+	if len(datagram) < chunkHeaderSize {
+		return nil, errors.New("chunk is too short")
+	}
+
+	var (
+		id    = binary.BigEndian.Uint64(datagram[2:10])
+		seq   = int(datagram[10])
+		count = int(datagram[11])
+	)
+	if count == 0 || count > maxChunks {
+		return nil, fmt.Errorf("invalid chunk count %d", count)
+	}
+	if seq >= count {
+		return nil, fmt.Errorf("chunk sequence number %d is out of range for %d chunks", seq, count)
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	msg, ok := a.messages[id]
+	if !ok {
+		msg = &chunkedMessage{chunks: make([][]byte, count), first: now}
+		a.messages[id] = msg
+	}
+	if len(msg.chunks) != count {
+		delete(a.messages, id)
+		return nil, fmt.Errorf("chunk count changed from %d to %d", len(msg.chunks), count)
+	}
+	if msg.chunks[seq] == nil {
+		msg.chunks[seq] = append([]byte(nil), datagram[chunkHeaderSize:]...)
+		msg.received++
+	}
+	if msg.received < count {
+		return nil, nil
+	}
+
+	delete(a.messages, id)
+	return decompress(bytes.Join(msg.chunks, nil))
+}
+
+// expire drops messages which didn't receive all of their chunks in time.
+// Returns the number of dropped messages.
+func (a *assembler) expire(now time.Time) int {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	var expired int
+	for id, msg := range a.messages {
+		if now.Sub(msg.first) > chunkTimeout {
+			delete(a.messages, id)
+			expired++
+		}
+	}
+	return expired
+}
@@ -0,0 +1,310 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+const (
+	gelfLabel         = "__gelf_message_"
+	gelfLabelHost     = gelfLabel + "host"
+	gelfLabelLevel    = gelfLabel + "level"
+	gelfLabelFacility = gelfLabel + "facility"
+	gelfLabelVersion  = gelfLabel + "version"
+
+	// maxDatagramSize is the largest UDP datagram which can be received.
+	maxDatagramSize = 65536
+)
+
+// Manager runs a listener for each Config.
+type Manager struct {
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	targets []*target
+}
+
+// NewManager creates and starts a Manager. Received messages are sent to
+// handler after being processed by the pipeline stages of their Config.
+func NewManager(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfgs []Config) (*Manager, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{cancel: cancel}
+
+	for i := range cfgs {
+		t, err := newTarget(l, reg, handler, &cfgs[i])
+		if err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("failed to create gelf job %s: %w", cfgs[i].JobName, err)
+		}
+		m.targets = append(m.targets, t)
+		t.run(ctx, &m.wg)
+	}
+
+	return m, nil
+}
+
+// Stop closes all listeners. Stop blocks until every received message has
+// been sent to the handler.
+func (m *Manager) Stop() {
+	m.cancel()
+	for _, t := range m.targets {
+		t.close()
+	}
+	m.wg.Wait()
+	for _, t := range m.targets {
+		t.handler.Stop()
+	}
+}
+
+// target listens for messages for a single Config.
+type target struct {
+	cfg     *Config
+	log     log.Logger
+	handler api.EntryHandler
+
+	// Only one of packetConn or listener is set, depending on the protocol.
+	packetConn net.PacketConn
+	listener   net.Listener
+	assembler  *assembler
+}
+
+func newTarget(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfg *Config) (*target, error) {
+	l = log.With(l, "component", "gelf", "job", cfg.JobName)
+
+	pipeline, err := stages.NewPipeline(l, cfg.PipelineStages, &cfg.JobName, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &target{cfg: cfg, log: l}
+	switch cfg.Protocol {
+	case ProtocolTCP:
+		t.listener, err = net.Listen("tcp", cfg.ListenAddress)
+	default:
+		t.packetConn, err = net.ListenPacket("udp", cfg.ListenAddress)
+		t.assembler = newAssembler()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.handler = limit.NewHandler(l, cfg.Limits, cfg.JobName, pipeline.Wrap(handler))
+	level.Info(l).Log("msg", "listening for GELF messages", "protocol", cfg.Protocol, "address", t.addr())
+	return t, nil
+}
+
+// addr returns the address the target is listening on.
+func (t *target) addr() net.Addr {
+	if t.listener != nil {
+		return t.listener.Addr()
+	}
+	return t.packetConn.LocalAddr()
+}
+
+func (t *target) run(ctx context.Context, wg *sync.WaitGroup) {
+	if t.listener != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.acceptConnections(ctx, wg)
+		}()
+		return
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		t.readDatagrams(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		t.expireChunks(ctx)
+	}()
+}
+
+func (t *target) close() {
+	if t.listener != nil {
+		_ = t.listener.Close()
+	}
+	if t.packetConn != nil {
+		_ = t.packetConn.Close()
+	}
+}
+
+func (t *target) readDatagrams(ctx context.Context) {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := t.packetConn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return
+		} else if err != nil {
+			level.Warn(t.log).Log("msg", "failed to read datagram", "err", err)
+			continue
+		}
+
+		msg, err := t.assembler.add(buf[:n], time.Now())
+		if err != nil {
+			level.Warn(t.log).Log("msg", "dropping invalid datagram", "err", err)
+			continue
+		} else if msg == nil {
+			continue
+		}
+		t.handleMessage(ctx, msg)
+	}
+}
+
+func (t *target) expireChunks(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := t.assembler.expire(now); n > 0 {
+				level.Warn(t.log).Log("msg", "dropped chunked messages which didn't receive all chunks in time", "count", n)
+			}
+		}
+	}
+}
+
+func (t *target) acceptConnections(ctx context.Context, wg *sync.WaitGroup) {
+	for {
+		conn, err := t.listener.Accept()
+		if ctx.Err() != nil {
+			return
+		} else if err != nil {
+			level.Warn(t.log).Log("msg", "failed to accept connection", "err", err)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.handleConnection(ctx, conn)
+		}()
+	}
+}
+
+// handleConnection reads null byte delimited messages from conn until it's
+// closed or ctx is canceled.
+func (t *target) handleConnection(ctx context.Context, conn net.Conn) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := r.ReadBytes(0)
+		if msg = bytes.TrimRight(msg, "\x00\n"); len(msg) > 0 {
+			t.handleMessage(ctx, msg)
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				level.Warn(t.log).Log("msg", "failed to read from connection", "remote", conn.RemoteAddr(), "err", err)
+			}
+			return
+		}
+	}
+}
+
+// handleMessage sends a GELF message to the handler. The message is used as
+// the log line as is.
+func (t *target) handleMessage(ctx context.Context, raw []byte) {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		level.Warn(t.log).Log("msg", "dropping message which isn't valid JSON", "err", err)
+		return
+	}
+
+	lset := messageLabels(t.cfg, msg)
+	if lset == nil {
+		return
+	}
+
+	entry := api.Entry{
+		Labels: lset,
+		Entry:  logproto.Entry{Timestamp: t.timestamp(msg), Line: string(raw)},
+	}
+	select {
+	case <-ctx.Done():
+	case t.handler.Chan() <- entry:
+	}
+}
+
+func (t *target) timestamp(msg map[string]interface{}) time.Time {
+	if !t.cfg.UseIncomingTimestamp {
+		return time.Now()
+	}
+	ts, ok := msg["timestamp"].(float64)
+	if !ok {
+		return time.Now()
+	}
+	sec, frac := math.Modf(ts)
+	// Round to microseconds to hide floating point errors.
+	return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3)
+}
+
+// messageLabels builds the labels to attach to a message. Returns nil if the
+// message was dropped by relabeling.
+func messageLabels(cfg *Config, msg map[string]interface{}) model.LabelSet {
+	lbls := make(map[string]string, len(cfg.Labels)+4)
+	for k, v := range cfg.Labels {
+		lbls[string(k)] = string(v)
+	}
+
+	setField := func(name string, keys ...string) {
+		for _, key := range keys {
+			switch v := msg[key].(type) {
+			case string:
+				lbls[name] = v
+				return
+			case float64:
+				lbls[name] = strconv.FormatFloat(v, 'f', -1, 64)
+				return
+			}
+		}
+	}
+	setField(gelfLabelHost, "host")
+	setField(gelfLabelLevel, "level")
+	setField(gelfLabelFacility, "facility", "_facility")
+	setField(gelfLabelVersion, "version")
+
+	processed := relabel.Process(labels.FromMap(lbls), cfg.RelabelConfigs...)
+	if processed == nil {
+		return nil
+	}
+
+	res := make(model.LabelSet, len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		res[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return res
+}
@@ -0,0 +1,186 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`job_name: gelf`), &cfg))
+	require.Equal(t, "0.0.0.0:12201", cfg.ListenAddress)
+	require.Equal(t, ProtocolUDP, cfg.Protocol)
+
+	err := yaml.UnmarshalStrict([]byte(`{job_name: gelf, protocol: http}`), &cfg)
+	require.EqualError(t, err, `gelf: invalid protocol "http": must be udp or tcp`)
+}
+
+const testMessage = `{"version":"1.1","host":"example.org","short_message":"hello","level":3,"_facility":"app","timestamp":1622548800.123}`
+
+func TestDecompress(t *testing.T) {
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write([]byte(testMessage))
+	require.NoError(t, gw.Close())
+
+	var zl bytes.Buffer
+	zw := zlib.NewWriter(&zl)
+	_, _ = zw.Write([]byte(testMessage))
+	require.NoError(t, zw.Close())
+
+	for name, payload := range map[string][]byte{
+		"plain": []byte(testMessage),
+		"gzip":  gz.Bytes(),
+		"zlib":  zl.Bytes(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			msg, err := decompress(payload)
+			require.NoError(t, err)
+			require.Equal(t, testMessage, string(msg))
+		})
+	}
+}
+
+func chunk(id uint64, seq, count int, data string) []byte {
+	buf := append([]byte(nil), magicChunked...)
+	buf = append(buf, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(buf[2:], id)
+	buf = append(buf, byte(seq), byte(count))
+	return append(buf, data...)
+}
+
+func TestAssembler(t *testing.T) {
+	var (
+		a   = newAssembler()
+		now = time.Now()
+	)
+
+	// Chunks may arrive out of order and duplicated.
+	msg, err := a.add(chunk(1, 2, 3, testMessage[20:]), now)
+	require.NoError(t, err)
+	require.Nil(t, msg)
+	msg, err = a.add(chunk(1, 0, 3, testMessage[:10]), now)
+	require.NoError(t, err)
+	require.Nil(t, msg)
+	msg, err = a.add(chunk(1, 0, 3, testMessage[:10]), now)
+	require.NoError(t, err)
+	require.Nil(t, msg)
+
+	msg, err = a.add(chunk(1, 1, 3, testMessage[10:20]), now)
+	require.NoError(t, err)
+	require.Equal(t, testMessage, string(msg))
+	require.Empty(t, a.messages)
+
+	_, err = a.add(chunk(2, 3, 3, ""), now)
+	require.EqualError(t, err, "chunk sequence number 3 is out of range for 3 chunks")
+	_, err = a.add(chunk(2, 0, maxChunks+1, ""), now)
+	require.EqualError(t, err, "invalid chunk count 129")
+}
+
+func TestAssembler_Expire(t *testing.T) {
+	var (
+		a   = newAssembler()
+		now = time.Now()
+	)
+
+	_, err := a.add(chunk(1, 0, 2, "a"), now)
+	require.NoError(t, err)
+	_, err = a.add(chunk(2, 0, 2, "b"), now.Add(3*time.Second))
+	require.NoError(t, err)
+
+	require.Equal(t, 0, a.expire(now.Add(chunkTimeout)))
+	require.Equal(t, 1, a.expire(now.Add(chunkTimeout+time.Second)))
+	require.Len(t, a.messages, 1)
+}
+
+func TestMessageLabels(t *testing.T) {
+	cfg := &Config{
+		Labels: model.LabelSet{"job": "gelf"},
+		RelabelConfigs: []*relabel.Config{{
+			SourceLabels: model.LabelNames{"__gelf_message_host"},
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Separator:    ";",
+			Replacement:  "$1",
+			TargetLabel:  "host",
+			Action:       relabel.Replace,
+		}, {
+			SourceLabels: model.LabelNames{"__gelf_message_level", "__gelf_message_facility"},
+			Regex:        relabel.MustNewRegexp("3;app"),
+			Separator:    ";",
+			Replacement:  "error",
+			TargetLabel:  "level",
+			Action:       relabel.Replace,
+		}, {
+			SourceLabels: model.LabelNames{"__gelf_message_version"},
+			Regex:        relabel.MustNewRegexp("1.0"),
+			Separator:    ";",
+			Action:       relabel.Drop,
+		}},
+	}
+
+	lset := messageLabels(cfg, map[string]interface{}{
+		"version":   "1.1",
+		"host":      "example.org",
+		"level":     float64(3),
+		"_facility": "app",
+	})
+	require.Equal(t, model.LabelSet{"job": "gelf", "host": "example.org", "level": "error"}, lset)
+
+	require.Nil(t, messageLabels(cfg, map[string]interface{}{"version": "1.0"}))
+}
+
+func TestTarget_Timestamp(t *testing.T) {
+	tgt := &target{cfg: &Config{UseIncomingTimestamp: true}}
+	ts := tgt.timestamp(map[string]interface{}{"timestamp": 1622548800.123})
+	require.Equal(t, time.Unix(1622548800, 123000000), ts)
+}
+
+func TestManager(t *testing.T) {
+	for _, protocol := range []string{ProtocolUDP, ProtocolTCP} {
+		t.Run(protocol, func(t *testing.T) {
+			entries := make(chan api.Entry, 10)
+			handler := api.NewEntryHandler(entries, func() {})
+
+			m, err := NewManager(log.NewNopLogger(), prometheus.NewRegistry(), handler, []Config{{
+				JobName:       "gelf",
+				ListenAddress: "127.0.0.1:0",
+				Protocol:      protocol,
+				Labels:        model.LabelSet{"job": "gelf"},
+			}})
+			require.NoError(t, err)
+			defer m.Stop()
+
+			conn, err := net.Dial(protocol, m.targets[0].addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			payload := []byte(testMessage)
+			if protocol == ProtocolTCP {
+				payload = append(payload, 0)
+			}
+			_, err = conn.Write(payload)
+			require.NoError(t, err)
+
+			select {
+			case e := <-entries:
+				require.Equal(t, model.LabelSet{"job": "gelf"}, e.Labels)
+				require.Equal(t, testMessage, e.Line)
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "timed out waiting for entry")
+			}
+		})
+	}
+}
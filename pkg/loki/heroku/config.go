@@ -0,0 +1,50 @@
+// Package heroku implements a logs target which receives logs from Heroku
+// HTTPS log drains.
+package heroku
+
+import (
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	ListenAddress: "0.0.0.0:8080",
+	Limits:        limit.DefaultConfig,
+}
+
+// Config configures an HTTP server which receives logs from Heroku drains.
+type Config struct {
+	// JobName identifies the config in logs and metrics.
+	JobName string `yaml:"job_name"`
+
+	// ListenAddress is the address the HTTP server listens on. Drains must
+	// send logs to the /heroku/api/v1/drain path.
+	ListenAddress string `yaml:"listen_address,omitempty"`
+
+	// UseIncomingTimestamp sets the timestamp of entries to the timestamp of
+	// the drained message instead of the time it was received.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// Labels are added to every entry before relabeling.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// RelabelConfigs are applied to the labels of each message.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process each received message.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+
+	// Limits restrict the rate and size of lines before they are processed by
+	// PipelineStages.
+	Limits limit.Config `yaml:"limits_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	return unmarshal((*plain)(c))
+}
@@ -0,0 +1,90 @@
+package heroku
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxFrameSize is the largest message which can be received. Heroku
+// truncates log lines to 10KB, so this leaves room for the header.
+const maxFrameSize = 64 * 1024
+
+// message is a log message received from a drain. Heroku messages look like
+// RFC5424 syslog messages, but never have structured data:
+//
+//	<PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID MSG
+//
+// Header fields which are "-" are left empty.
+type message struct {
+	timestamp time.Time
+	hostname  string
+	appname   string
+	procID    string
+	msgID     string
+	msg       string
+}
+
+// readFrames reads octet counted frames from r, calling fn with each frame.
+func readFrames(r io.Reader, fn func(frame []byte)) error {
+	br := bufio.NewReader(r)
+	for {
+		prefix, err := br.ReadString(' ')
+		if err == io.EOF && strings.TrimSpace(prefix) == "" {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read frame length: %w", err)
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(prefix))
+		if err != nil {
+			return fmt.Errorf("invalid frame length %q", strings.TrimSpace(prefix))
+		}
+		if size <= 0 || size > maxFrameSize {
+			return fmt.Errorf("frame length %d is out of range", size)
+		}
+
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return fmt.Errorf("failed to read frame: %w", err)
+		}
+		fn(frame)
+	}
+}
+
+// parseMessage parses a single frame into a message.
+func parseMessage(frame []byte) (message, error) {
+	var m message
+
+	fields := strings.SplitN(strings.TrimRight(string(frame), "\r\n"), " ", 7)
+	if len(fields) < 6 {
+		return m, fmt.Errorf("message has %d header fields, expected 6", len(fields))
+	}
+	if !strings.HasPrefix(fields[0], "<") || !strings.Contains(fields[0], ">") {
+		return m, fmt.Errorf("invalid priority and version %q", fields[0])
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, fields[1])
+	if err != nil {
+		return m, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	m.timestamp = ts
+
+	value := func(s string) string {
+		if s == "-" {
+			return ""
+		}
+		return s
+	}
+	m.hostname = value(fields[2])
+	m.appname = value(fields[3])
+	m.procID = value(fields[4])
+	m.msgID = value(fields[5])
+	if len(fields) == 7 {
+		m.msg = fields[6]
+	}
+	return m, nil
+}
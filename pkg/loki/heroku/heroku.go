@@ -0,0 +1,213 @@
+package heroku
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+const (
+	herokuLabel      = "__heroku_drain_"
+	herokuLabelHost  = herokuLabel + "host"
+	herokuLabelApp   = herokuLabel + "app"
+	herokuLabelProc  = herokuLabel + "proc"
+	herokuLabelLogID = herokuLabel + "log_id"
+	herokuLabelToken = herokuLabel + "token"
+
+	// drainPath is the path drains send logs to.
+	drainPath = "/heroku/api/v1/drain"
+)
+
+// Manager runs an HTTP server for each Config.
+type Manager struct {
+	cancel  context.CancelFunc
+	targets []*target
+}
+
+// NewManager creates and starts a Manager. Received messages are sent to
+// handler after being processed by the pipeline stages of their Config.
+func NewManager(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfgs []Config) (*Manager, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{cancel: cancel}
+
+	for i := range cfgs {
+		t, err := newTarget(ctx, l, reg, handler, &cfgs[i])
+		if err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("failed to create heroku job %s: %w", cfgs[i].JobName, err)
+		}
+		m.targets = append(m.targets, t)
+	}
+
+	return m, nil
+}
+
+// Stop shuts down all HTTP servers. Stop blocks until every in-flight
+// request has finished.
+func (m *Manager) Stop() {
+	m.cancel()
+	for _, t := range m.targets {
+		t.stop()
+	}
+}
+
+// target receives drained logs for a single Config.
+type target struct {
+	ctx      context.Context
+	cfg      *Config
+	log      log.Logger
+	handler  api.EntryHandler
+	listener net.Listener
+	srv      *http.Server
+	done     chan struct{}
+}
+
+func newTarget(ctx context.Context, l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfg *Config) (*target, error) {
+	l = log.With(l, "component", "heroku", "job", cfg.JobName)
+
+	pipeline, err := stages.NewPipeline(l, cfg.PipelineStages, &cfg.JobName, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	lis, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &target{
+		ctx:      ctx,
+		cfg:      cfg,
+		log:      l,
+		handler:  limit.NewHandler(l, cfg.Limits, cfg.JobName, pipeline.Wrap(handler)),
+		listener: lis,
+		done:     make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(drainPath, t.drain)
+	t.srv = &http.Server{Handler: mux}
+
+	go func() {
+		defer close(t.done)
+		level.Info(l).Log("msg", "listening for Heroku drains", "address", lis.Addr())
+		if err := t.srv.Serve(lis); err != nil && err != http.ErrServerClosed {
+			level.Error(l).Log("msg", "Heroku drain server stopped unexpectedly", "err", err)
+		}
+	}()
+
+	return t, nil
+}
+
+func (t *target) stop() {
+	// Requests stop waiting on the handler once the context of the Manager is
+	// canceled, so shutting down won't block for long.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := t.srv.Shutdown(ctx); err != nil {
+		level.Warn(t.log).Log("msg", "failed to gracefully shut down Heroku drain server", "err", err)
+	}
+	<-t.done
+	t.handler.Stop()
+}
+
+// drain handles a request from a Heroku drain, which holds one or more octet
+// counted messages.
+func (t *target) drain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get("Logplex-Drain-Token")
+
+	var messages []message
+	err := readFrames(r.Body, func(frame []byte) {
+		m, err := parseMessage(frame)
+		if err != nil {
+			level.Warn(t.log).Log("msg", "dropping invalid message", "err", err)
+			return
+		}
+		messages = append(messages, m)
+	})
+	if err != nil {
+		level.Warn(t.log).Log("msg", "failed to read drain request", "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, m := range messages {
+		lset := messageLabels(t.cfg, token, m)
+		if lset == nil {
+			continue
+		}
+
+		ts := time.Now()
+		if t.cfg.UseIncomingTimestamp {
+			ts = m.timestamp
+		}
+
+		entry := api.Entry{
+			Labels: lset,
+			Entry:  logproto.Entry{Timestamp: ts, Line: m.msg},
+		}
+		select {
+		case <-t.ctx.Done():
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		case t.handler.Chan() <- entry:
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// messageLabels builds the labels to attach to a message. Returns nil if the
+// message was dropped by relabeling.
+func messageLabels(cfg *Config, token string, m message) model.LabelSet {
+	lbls := make(map[string]string, len(cfg.Labels)+5)
+	for k, v := range cfg.Labels {
+		lbls[string(k)] = string(v)
+	}
+
+	set := func(name, value string) {
+		if value != "" {
+			lbls[name] = value
+		}
+	}
+	set(herokuLabelHost, m.hostname)
+	set(herokuLabelApp, m.appname)
+	set(herokuLabelProc, m.procID)
+	set(herokuLabelLogID, m.msgID)
+	set(herokuLabelToken, token)
+
+	processed := relabel.Process(labels.FromMap(lbls), cfg.RelabelConfigs...)
+	if processed == nil {
+		return nil
+	}
+
+	res := make(model.LabelSet, len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		res[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return res
+}
@@ -0,0 +1,132 @@
+package heroku
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+// frame encodes msgs with octet counting, the same way Heroku does.
+func frame(msgs ...string) string {
+	var sb strings.Builder
+	for _, m := range msgs {
+		fmt.Fprintf(&sb, "%d %s", len(m), m)
+	}
+	return sb.String()
+}
+
+const (
+	routerMessage = `<158>1 2021-06-01T12:00:00.123456+00:00 host heroku router - at=info method=GET path="/"`
+	appMessage    = "<190>1 2021-06-01T12:00:01+00:00 host app web.1 - Hello, world!\n"
+)
+
+func TestReadFrames(t *testing.T) {
+	var frames []string
+	err := readFrames(strings.NewReader(frame(routerMessage, appMessage)), func(f []byte) {
+		frames = append(frames, string(f))
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{routerMessage, appMessage}, frames)
+
+	err = readFrames(strings.NewReader("abc "+routerMessage), func([]byte) {})
+	require.EqualError(t, err, `invalid frame length "abc"`)
+
+	err = readFrames(strings.NewReader("500 "+routerMessage), func([]byte) {})
+	require.EqualError(t, err, "failed to read frame: unexpected EOF")
+}
+
+func TestParseMessage(t *testing.T) {
+	m, err := parseMessage([]byte(routerMessage))
+	require.NoError(t, err)
+	require.Equal(t, message{
+		timestamp: time.Date(2021, 6, 1, 12, 0, 0, 123456000, time.UTC),
+		hostname:  "host",
+		appname:   "heroku",
+		procID:    "router",
+		msg:       `at=info method=GET path="/"`,
+	}, withUTC(m))
+
+	m, err = parseMessage([]byte(appMessage))
+	require.NoError(t, err)
+	require.Equal(t, "Hello, world!", m.msg)
+
+	_, err = parseMessage([]byte("<190>1 yesterday host app web.1 - hi"))
+	require.Error(t, err)
+
+	_, err = parseMessage([]byte("hello world"))
+	require.EqualError(t, err, "message has 2 header fields, expected 6")
+}
+
+func withUTC(m message) message {
+	m.timestamp = m.timestamp.UTC()
+	return m
+}
+
+func TestMessageLabels(t *testing.T) {
+	cfg := &Config{
+		Labels: model.LabelSet{"job": "heroku"},
+		RelabelConfigs: []*relabel.Config{{
+			SourceLabels: model.LabelNames{"__heroku_drain_app", "__heroku_drain_proc"},
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Separator:    "/",
+			Replacement:  "$1",
+			TargetLabel:  "source",
+			Action:       relabel.Replace,
+		}, {
+			SourceLabels: model.LabelNames{"__heroku_drain_token"},
+			Regex:        relabel.MustNewRegexp("d.123"),
+			Separator:    ";",
+			Action:       relabel.Keep,
+		}},
+	}
+
+	m := message{appname: "app", procID: "web.1"}
+	require.Equal(t, model.LabelSet{"job": "heroku", "source": "app/web.1"}, messageLabels(cfg, "d.123", m))
+	require.Nil(t, messageLabels(cfg, "d.456", m))
+}
+
+func TestManager(t *testing.T) {
+	entries := make(chan api.Entry, 10)
+	handler := api.NewEntryHandler(entries, func() {})
+
+	m, err := NewManager(log.NewNopLogger(), prometheus.NewRegistry(), handler, []Config{{
+		JobName:              "heroku",
+		ListenAddress:        "127.0.0.1:0",
+		UseIncomingTimestamp: true,
+		Labels:               model.LabelSet{"job": "heroku"},
+	}})
+	require.NoError(t, err)
+	defer m.Stop()
+
+	url := fmt.Sprintf("http://%s%s", m.targets[0].listener.Addr(), drainPath)
+	resp, err := http.Post(url, "application/logplex-1", strings.NewReader(frame(routerMessage, appMessage)))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var lines []string
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-entries:
+			require.Equal(t, model.LabelSet{"job": "heroku"}, e.Labels)
+			lines = append(lines, e.Line)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for entry")
+		}
+	}
+	require.Equal(t, []string{`at=info method=GET path="/"`, "Hello, world!"}, lines)
+
+	resp, err = http.Get(url)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,112 @@
+// Package kafka implements a logs target which consumes log lines from Kafka
+// topics.
+package kafka
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/grafana/agent/pkg/loki/limit"
+	kafkautil "github.com/grafana/agent/pkg/util/kafka"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	GroupID:  "grafana-agent",
+	Version:  "2.2.1",
+	Assignor: "range",
+	Limits:   limit.DefaultConfig,
+}
+
+// Config configures consuming log lines from Kafka topics as part of a
+// consumer group.
+type Config struct {
+	// JobName identifies the config in logs and metrics.
+	JobName string `yaml:"job_name"`
+
+	// Brokers to bootstrap the connection to the Kafka cluster from.
+	Brokers []string `yaml:"brokers"`
+	// Topics to consume from.
+	Topics []string `yaml:"topics"`
+	// GroupID is the consumer group to join. Partitions of the topics are
+	// balanced across all consumers of the group.
+	GroupID string `yaml:"group_id,omitempty"`
+	// Version of Kafka the brokers are running.
+	Version string `yaml:"version,omitempty"`
+	// Assignor is the strategy used to assign partitions to consumers of the
+	// group. One of range, roundrobin, or sticky.
+	Assignor string `yaml:"assignor,omitempty"`
+
+	Authentication kafkautil.Authentication `yaml:"authentication,omitempty"`
+
+	// UseIncomingTimestamp sets the timestamp of entries to the timestamp of
+	// their Kafka message instead of the time they were consumed.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// Labels are added to every entry before relabeling.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// RelabelConfigs are applied to the labels of each claimed partition.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process each log line read from Kafka.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+
+	// Limits restrict the rate and size of lines before they are processed by
+	// PipelineStages.
+	Limits limit.Config `yaml:"limits_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if len(c.Brokers) == 0 {
+		return errors.New("kafka: at least one broker must be provided")
+	}
+	if len(c.Topics) == 0 {
+		return errors.New("kafka: at least one topic must be provided")
+	}
+	if c.GroupID == "" {
+		return errors.New("kafka: group_id must not be empty")
+	}
+	_, err := c.saramaConfig()
+	return err
+}
+
+// saramaConfig builds the configuration for the Kafka client.
+func (c *Config) saramaConfig() (*sarama.Config, error) {
+	cfg := sarama.NewConfig()
+	cfg.ClientID = "grafana-agent"
+
+	version, err := sarama.ParseKafkaVersion(c.Version)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: invalid version %q: %w", c.Version, err)
+	}
+	cfg.Version = version
+
+	switch c.Assignor {
+	case "range":
+		cfg.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRange
+	case "roundrobin":
+		cfg.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
+	case "sticky":
+		cfg.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategySticky
+	default:
+		return nil, fmt.Errorf("kafka: invalid assignor %q: must be range, roundrobin, or sticky", c.Assignor)
+	}
+
+	if err := c.Authentication.Apply(cfg); err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+
+	return cfg, nil
+}
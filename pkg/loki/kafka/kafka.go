@@ -0,0 +1,197 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	cortex_util "github.com/cortexproject/cortex/pkg/util"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+const (
+	kafkaLabel          = model.MetaLabelPrefix + "kafka_"
+	kafkaLabelTopic     = kafkaLabel + "topic"
+	kafkaLabelPartition = kafkaLabel + "partition"
+	kafkaLabelMemberID  = kafkaLabel + "member_id"
+	kafkaLabelGroupID   = kafkaLabel + "group_id"
+)
+
+// consumeBackoff is used to retry joining the consumer group after an error.
+// It retries forever.
+var consumeBackoff = cortex_util.BackoffConfig{
+	MinBackoff: time.Second,
+	MaxBackoff: time.Minute,
+}
+
+// Manager runs a consumer for each Config.
+type Manager struct {
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	consumers []*consumer
+}
+
+// NewManager creates and starts a Manager. Entries read from Kafka are sent
+// to handler after being processed by the pipeline stages of their Config.
+func NewManager(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfgs []Config) (*Manager, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{cancel: cancel}
+
+	for i := range cfgs {
+		c, err := newConsumer(l, reg, handler, &cfgs[i])
+		if err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("failed to create kafka job %s: %w", cfgs[i].JobName, err)
+		}
+		m.consumers = append(m.consumers, c)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			c.run(ctx)
+		}()
+	}
+
+	return m, nil
+}
+
+// Stop leaves all consumer groups. Stop blocks until every entry read has
+// been sent to the handler.
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+	for _, c := range m.consumers {
+		if err := c.group.Close(); err != nil {
+			level.Warn(c.log).Log("msg", "failed to leave consumer group", "err", err)
+		}
+		c.handler.Stop()
+	}
+}
+
+// consumer consumes the topics of a single Config.
+type consumer struct {
+	cfg     *Config
+	log     log.Logger
+	group   sarama.ConsumerGroup
+	handler api.EntryHandler
+}
+
+func newConsumer(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfg *Config) (*consumer, error) {
+	l = log.With(l, "component", "kafka", "job", cfg.JobName)
+
+	saramaConfig, err := cfg.saramaConfig()
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := stages.NewPipeline(l, cfg.PipelineStages, &cfg.JobName, reg)
+	if err != nil {
+		return nil, err
+	}
+	group, err := sarama.NewConsumerGroup(cfg.Brokers, cfg.GroupID, saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &consumer{
+		cfg:     cfg,
+		log:     l,
+		group:   group,
+		handler: limit.NewHandler(l, cfg.Limits, cfg.JobName, pipeline.Wrap(handler)),
+	}, nil
+}
+
+// run consumes from the topics until ctx is canceled. Consume returns
+// whenever the group is rebalanced, so it's called in a loop.
+func (c *consumer) run(ctx context.Context) {
+	backoff := cortex_util.NewBackoff(ctx, consumeBackoff)
+	for backoff.Ongoing() {
+		err := c.group.Consume(ctx, c.cfg.Topics, c)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to consume from kafka", "err", err)
+			backoff.Wait()
+			continue
+		}
+		backoff.Reset()
+	}
+}
+
+// Setup implements sarama.ConsumerGroupHandler.
+func (c *consumer) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+// Cleanup implements sarama.ConsumerGroupHandler.
+func (c *consumer) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler. Messages are marked as
+// consumed once they have been sent to the handler.
+func (c *consumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	lset := claimLabels(c.cfg, sess.MemberID(), claim.Topic(), claim.Partition())
+	if lset == nil {
+		level.Debug(c.log).Log("msg", "partition dropped by relabeling, discarding its messages", "topic", claim.Topic(), "partition", claim.Partition())
+	}
+
+	for msg := range claim.Messages() {
+		if lset != nil {
+			entry := api.Entry{
+				Labels: lset.Clone(),
+				Entry:  logproto.Entry{Timestamp: c.timestamp(msg), Line: string(msg.Value)},
+			}
+			select {
+			case <-sess.Context().Done():
+				return nil
+			case c.handler.Chan() <- entry:
+			}
+		}
+		sess.MarkMessage(msg, "")
+	}
+	return nil
+}
+
+func (c *consumer) timestamp(msg *sarama.ConsumerMessage) time.Time {
+	if c.cfg.UseIncomingTimestamp && !msg.Timestamp.IsZero() {
+		return msg.Timestamp
+	}
+	return time.Now()
+}
+
+// claimLabels builds the labels to attach to entries read from a partition.
+// Returns nil if the partition was dropped by relabeling.
+func claimLabels(cfg *Config, memberID, topic string, partition int32) model.LabelSet {
+	lbls := make(map[string]string, len(cfg.Labels)+4)
+	for k, v := range cfg.Labels {
+		lbls[string(k)] = string(v)
+	}
+	lbls[kafkaLabelTopic] = topic
+	lbls[kafkaLabelPartition] = strconv.Itoa(int(partition))
+	lbls[kafkaLabelMemberID] = memberID
+	lbls[kafkaLabelGroupID] = cfg.GroupID
+
+	processed := relabel.Process(labels.FromMap(lbls), cfg.RelabelConfigs...)
+	if processed == nil {
+		return nil
+	}
+
+	res := make(model.LabelSet, len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		res[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return res
+}
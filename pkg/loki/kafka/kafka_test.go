@@ -0,0 +1,220 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig_Validation(t *testing.T) {
+	tt := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{
+			name: "valid",
+			cfg: `
+job_name: kafka
+brokers: [localhost:9092]
+topics: [logs]
+authentication:
+  sasl_config:
+    mechanism: SCRAM-SHA-512
+    user: agent
+    password: secret`,
+		},
+		{
+			name: "missing brokers",
+			cfg:  `{job_name: kafka, topics: [logs]}`,
+			err:  "kafka: at least one broker must be provided",
+		},
+		{
+			name: "missing topics",
+			cfg:  `{job_name: kafka, brokers: [localhost:9092]}`,
+			err:  "kafka: at least one topic must be provided",
+		},
+		{
+			name: "invalid version",
+			cfg:  `{job_name: kafka, brokers: [localhost:9092], topics: [logs], version: abc}`,
+			err:  `kafka: invalid version "abc": invalid version ` + "`abc`",
+		},
+		{
+			name: "invalid assignor",
+			cfg:  `{job_name: kafka, brokers: [localhost:9092], topics: [logs], assignor: random}`,
+			err:  `kafka: invalid assignor "random": must be range, roundrobin, or sticky`,
+		},
+		{
+			name: "invalid SASL mechanism",
+			cfg: `
+job_name: kafka
+brokers: [localhost:9092]
+topics: [logs]
+authentication:
+  sasl_config: {mechanism: GSSAPI, user: agent, password: secret}`,
+			err: `kafka: invalid SASL mechanism "GSSAPI": must be PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512`,
+		},
+		{
+			name: "SASL without password",
+			cfg: `
+job_name: kafka
+brokers: [localhost:9092]
+topics: [logs]
+authentication:
+  sasl_config: {mechanism: PLAIN, user: agent}`,
+			err: "kafka: sasl_config must set user and password",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			err := yaml.UnmarshalStrict([]byte(tc.cfg), &cfg)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestConfig_Defaults(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`{job_name: kafka, brokers: [localhost:9092], topics: [logs]}`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, "grafana-agent", cfg.GroupID)
+
+	sc, err := cfg.saramaConfig()
+	require.NoError(t, err)
+	require.Equal(t, "2.2.1", sc.Version.String())
+	require.Equal(t, sarama.BalanceStrategyRange, sc.Consumer.Group.Rebalance.Strategy)
+	require.False(t, sc.Net.TLS.Enable)
+	require.False(t, sc.Net.SASL.Enable)
+}
+
+func TestClaimLabels(t *testing.T) {
+	cfg := &Config{
+		GroupID: "agent",
+		Labels:  model.LabelSet{"job": "kafka"},
+		RelabelConfigs: []*relabel.Config{{
+			SourceLabels: model.LabelNames{"__meta_kafka_topic"},
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Separator:    ";",
+			Replacement:  "$1",
+			TargetLabel:  "topic",
+			Action:       relabel.Replace,
+		}, {
+			SourceLabels: model.LabelNames{"__meta_kafka_partition"},
+			Regex:        relabel.MustNewRegexp("3"),
+			Separator:    ";",
+			Action:       relabel.Drop,
+		}},
+	}
+
+	lset := claimLabels(cfg, "member-1", "logs", 0)
+	require.Equal(t, model.LabelSet{"job": "kafka", "topic": "logs"}, lset)
+
+	require.Nil(t, claimLabels(cfg, "member-1", "logs", 3))
+}
+
+func TestConsumer_ConsumeClaim(t *testing.T) {
+	entries := make(chan api.Entry, 10)
+	c := &consumer{
+		cfg: &Config{
+			GroupID:              "agent",
+			UseIncomingTimestamp: true,
+			Labels:               model.LabelSet{"job": "kafka"},
+		},
+		log:     log.NewNopLogger(),
+		handler: api.NewEntryHandler(entries, func() {}),
+	}
+
+	ts := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	claim := newFakeClaim("logs", 1,
+		&sarama.ConsumerMessage{Topic: "logs", Partition: 1, Offset: 10, Value: []byte("first"), Timestamp: ts},
+		&sarama.ConsumerMessage{Topic: "logs", Partition: 1, Offset: 11, Value: []byte("second"), Timestamp: ts.Add(time.Second)},
+	)
+	sess := &fakeSession{ctx: context.Background()}
+
+	require.NoError(t, c.ConsumeClaim(sess, claim))
+	close(entries)
+
+	var (
+		lines      []string
+		timestamps []time.Time
+	)
+	for e := range entries {
+		require.Equal(t, model.LabelSet{"job": "kafka"}, e.Labels)
+		lines = append(lines, e.Line)
+		timestamps = append(timestamps, e.Timestamp)
+	}
+	require.Equal(t, []string{"first", "second"}, lines)
+	require.Equal(t, []time.Time{ts, ts.Add(time.Second)}, timestamps)
+	require.Equal(t, []int64{10, 11}, sess.marked)
+}
+
+func TestConsumer_ConsumeClaim_Dropped(t *testing.T) {
+	entries := make(chan api.Entry, 10)
+	c := &consumer{
+		cfg: &Config{
+			GroupID: "agent",
+			RelabelConfigs: []*relabel.Config{{
+				SourceLabels: model.LabelNames{"__meta_kafka_topic"},
+				Regex:        relabel.MustNewRegexp("logs"),
+				Separator:    ";",
+				Action:       relabel.Drop,
+			}},
+		},
+		log:     log.NewNopLogger(),
+		handler: api.NewEntryHandler(entries, func() {}),
+	}
+
+	claim := newFakeClaim("logs", 0, &sarama.ConsumerMessage{Topic: "logs", Offset: 5, Value: []byte("dropped")})
+	sess := &fakeSession{ctx: context.Background()}
+
+	require.NoError(t, c.ConsumeClaim(sess, claim))
+	require.Len(t, entries, 0)
+	require.Equal(t, []int64{5}, sess.marked, "messages of dropped partitions should still be marked")
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	ctx    context.Context
+	marked []int64
+}
+
+func (s *fakeSession) Context() context.Context { return s.ctx }
+func (s *fakeSession) MemberID() string         { return "member-1" }
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	topic     string
+	partition int32
+	messages  chan *sarama.ConsumerMessage
+}
+
+func newFakeClaim(topic string, partition int32, msgs ...*sarama.ConsumerMessage) *fakeClaim {
+	ch := make(chan *sarama.ConsumerMessage, len(msgs))
+	for _, m := range msgs {
+		ch <- m
+	}
+	close(ch)
+	return &fakeClaim{topic: topic, partition: partition, messages: ch}
+}
+
+func (c *fakeClaim) Topic() string                            { return c.topic }
+func (c *fakeClaim) Partition() int32                         { return c.partition }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
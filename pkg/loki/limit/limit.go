@@ -0,0 +1,188 @@
+// Package limit implements limits on the rate and size of log lines read by a
+// logs target.
+package limit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/util/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// Reasons lines are dropped.
+const (
+	ReasonRateLimited = "rate_limited"
+	ReasonLineTooLong = "line_too_long"
+)
+
+var (
+	droppedLines = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "agent_logs_limit_dropped_lines_total",
+			Help: "Lines dropped by the limits of a logs job",
+		},
+		[]string{"job", "reason"},
+	)
+
+	truncatedLines = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "agent_logs_limit_truncated_lines_total",
+			Help: "Lines truncated because they were longer than the max line size of a logs job",
+		},
+		[]string{"job"},
+	)
+)
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	ReadlineRateDrop: true,
+}
+
+// Config configures limits for the lines read by a logs job. The zero value
+// doesn't limit lines.
+type Config struct {
+	// ReadlineRate is the maximum number of lines per second. 0 disables the
+	// rate limit.
+	ReadlineRate float64 `yaml:"readline_rate,omitempty"`
+	// ReadlineBurst is the maximum number of lines which may be read at once
+	// above ReadlineRate. Defaults to ReadlineRate when 0.
+	ReadlineBurst int `yaml:"readline_burst,omitempty"`
+	// ReadlineRateDrop drops lines over the rate limit when true. Otherwise,
+	// reading lines is delayed until the rate limit allows it.
+	ReadlineRateDrop bool `yaml:"readline_rate_drop"`
+
+	// MaxLineSize is the maximum size of a line. 0 disables the limit.
+	MaxLineSize flagext.ByteSize `yaml:"max_line_size,omitempty"`
+	// MaxLineSizeTruncate truncates lines longer than MaxLineSize when true.
+	// Otherwise, they are dropped.
+	MaxLineSizeTruncate bool `yaml:"max_line_size_truncate,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.ReadlineRate < 0 {
+		return fmt.Errorf("limits_config: readline_rate must not be negative")
+	}
+	if c.ReadlineBurst < 0 {
+		return fmt.Errorf("limits_config: readline_burst must not be negative")
+	}
+	return nil
+}
+
+// enabled returns true if any limit is configured.
+func (c *Config) enabled() bool {
+	return c.ReadlineRate > 0 || c.MaxLineSize > 0
+}
+
+// handler enforces a Config on the entries sent to it before passing them to
+// the next handler.
+type handler struct {
+	log     log.Logger
+	cfg     Config
+	job     string
+	next    api.EntryHandler
+	limiter *rate.Limiter
+
+	entries chan api.Entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewHandler returns a handler which enforces cfg on the entries of a job
+// before passing them to next. next is returned as is when cfg has no limits.
+// Stopping the returned handler stops next.
+func NewHandler(l log.Logger, cfg Config, job string, next api.EntryHandler) api.EntryHandler {
+	if !cfg.enabled() {
+		return next
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &handler{
+		log:     l,
+		cfg:     cfg,
+		job:     job,
+		next:    next,
+		entries: make(chan api.Entry),
+		cancel:  cancel,
+	}
+	if cfg.ReadlineRate > 0 {
+		burst := cfg.ReadlineBurst
+		if burst == 0 {
+			burst = int(math.Max(1, cfg.ReadlineRate))
+		}
+		h.limiter = rate.NewLimiter(rate.Limit(cfg.ReadlineRate), burst)
+	}
+
+	h.wg.Add(1)
+	go h.run(ctx)
+	return h
+}
+
+// Chan implements api.EntryHandler.
+func (h *handler) Chan() chan<- api.Entry { return h.entries }
+
+// Stop implements api.EntryHandler. An entry waiting for the rate limit when
+// Stop is called is dropped.
+func (h *handler) Stop() {
+	h.once.Do(func() {
+		h.cancel()
+		close(h.entries)
+		h.wg.Wait()
+		h.next.Stop()
+	})
+}
+
+func (h *handler) run(ctx context.Context) {
+	defer h.wg.Done()
+
+	for e := range h.entries {
+		// next reads entries until it's stopped, which only happens after
+		// this loop exits, so sending to it never blocks forever.
+		if e, ok := h.limit(ctx, e); ok {
+			h.next.Chan() <- e
+		}
+	}
+}
+
+// limit applies the limits to e. Returns false if e should be dropped.
+func (h *handler) limit(ctx context.Context, e api.Entry) (api.Entry, bool) {
+	if max := h.cfg.MaxLineSize.Val(); max > 0 && len(e.Line) > max {
+		if !h.cfg.MaxLineSizeTruncate {
+			droppedLines.WithLabelValues(h.job, ReasonLineTooLong).Inc()
+			return e, false
+		}
+		e.Line = e.Line[:max]
+		truncatedLines.WithLabelValues(h.job).Inc()
+	}
+
+	if h.limiter == nil {
+		return e, true
+	}
+	if h.cfg.ReadlineRateDrop {
+		if !h.limiter.Allow() {
+			droppedLines.WithLabelValues(h.job, ReasonRateLimited).Inc()
+			return e, false
+		}
+		return e, true
+	}
+	if err := h.limiter.Wait(ctx); err != nil {
+		level.Debug(h.log).Log("msg", "stopped waiting for rate limit", "err", err)
+		return e, false
+	}
+	return e, true
+}
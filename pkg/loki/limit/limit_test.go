@@ -0,0 +1,76 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`{readline_rate: 10, max_line_size: 1KB}`), &cfg))
+	require.Equal(t, Config{ReadlineRate: 10, ReadlineRateDrop: true, MaxLineSize: 1024}, cfg)
+
+	err := yaml.UnmarshalStrict([]byte(`readline_rate: -1`), &cfg)
+	require.EqualError(t, err, "limits_config: readline_rate must not be negative")
+}
+
+func TestNewHandler_NoLimits(t *testing.T) {
+	next := api.NewEntryHandler(make(chan api.Entry), func() {})
+	_, limited := NewHandler(log.NewNopLogger(), DefaultConfig, "job", next).(*handler)
+	require.False(t, limited)
+}
+
+// send sends lines to h and returns the lines which were passed through.
+func send(t *testing.T, cfg Config, job string, lines ...string) []string {
+	t.Helper()
+
+	out := make(chan api.Entry, len(lines))
+	h := NewHandler(log.NewNopLogger(), cfg, job, api.NewEntryHandler(out, func() { close(out) }))
+	for _, l := range lines {
+		h.Chan() <- api.Entry{Entry: logproto.Entry{Timestamp: time.Now(), Line: l}}
+	}
+	h.Stop()
+
+	var res []string
+	for e := range out {
+		res = append(res, e.Line)
+	}
+	return res
+}
+
+func TestHandler_MaxLineSize(t *testing.T) {
+	cfg := Config{MaxLineSize: 5}
+	require.Equal(t, []string{"short"}, send(t, cfg, "drop", "short", "too long"))
+	require.Equal(t, 1.0, testutil.ToFloat64(droppedLines.WithLabelValues("drop", ReasonLineTooLong)))
+
+	cfg.MaxLineSizeTruncate = true
+	require.Equal(t, []string{"short", "too l"}, send(t, cfg, "truncate", "short", "too long"))
+	require.Equal(t, 1.0, testutil.ToFloat64(truncatedLines.WithLabelValues("truncate")))
+}
+
+func TestHandler_ReadlineRate(t *testing.T) {
+	cfg := Config{ReadlineRate: 1, ReadlineBurst: 2, ReadlineRateDrop: true}
+	require.Equal(t, []string{"a", "b"}, send(t, cfg, "rate-drop", "a", "b", "c", "d"))
+	require.Equal(t, 2.0, testutil.ToFloat64(droppedLines.WithLabelValues("rate-drop", ReasonRateLimited)))
+
+	// Without dropping, lines are delayed instead.
+	out := make(chan api.Entry, 3)
+	h := NewHandler(log.NewNopLogger(), Config{ReadlineRate: 20, ReadlineBurst: 1}, "rate-wait", api.NewEntryHandler(out, func() {}))
+	defer h.Stop()
+
+	start := time.Now()
+	for _, l := range []string{"a", "b", "c"} {
+		h.Chan() <- api.Entry{Entry: logproto.Entry{Timestamp: time.Now(), Line: l}}
+	}
+	for _, l := range []string{"a", "b", "c"} {
+		require.Equal(t, l, (<-out).Line)
+	}
+	require.GreaterOrEqual(t, int64(time.Since(start)), int64(90*time.Millisecond))
+}
@@ -9,14 +9,26 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/health"
+	"github.com/grafana/agent/pkg/loki/compressedfile"
+	"github.com/grafana/agent/pkg/loki/docker"
+	"github.com/grafana/agent/pkg/loki/fluentforward"
+	"github.com/grafana/agent/pkg/loki/gelf"
+	"github.com/grafana/agent/pkg/loki/heroku"
+	"github.com/grafana/agent/pkg/loki/kafka"
+	"github.com/grafana/agent/pkg/loki/snmptrap"
+	"github.com/grafana/agent/pkg/loki/spool"
+	"github.com/grafana/agent/pkg/prom/instance"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/grafana/loki/clients/pkg/promtail"
 	"github.com/grafana/loki/clients/pkg/promtail/api"
 	"github.com/grafana/loki/clients/pkg/promtail/client"
 	"github.com/grafana/loki/clients/pkg/promtail/config"
 	"github.com/grafana/loki/clients/pkg/promtail/server"
+	"github.com/grafana/loki/clients/pkg/promtail/targets"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/version"
+	"github.com/prometheus/prometheus/pkg/labels"
 )
 
 func init() {
@@ -29,17 +41,20 @@ type Loki struct {
 	mut sync.Mutex
 
 	reg       prometheus.Registerer
+	im        instance.Manager
 	l         log.Logger
 	instances map[string]*Instance
 }
 
-// New creates and starts Loki log collection.
-func New(reg prometheus.Registerer, c Config, l log.Logger) (*Loki, error) {
+// New creates and starts Loki log collection. im is used to write metrics
+// created by pipeline stages to metrics instances.
+func New(reg prometheus.Registerer, c Config, im instance.Manager, l log.Logger) (*Loki, error) {
 	l = log.With(l, "component", "loki")
 
 	loki := &Loki{
 		instances: make(map[string]*Instance),
 		reg:       reg,
+		im:        im,
 		l:         log.With(l, "component", "loki"),
 	}
 	if err := loki.ApplyConfig(c); err != nil {
@@ -74,7 +89,7 @@ func (l *Loki) ApplyConfig(c Config) error {
 			continue
 		}
 
-		inst, err := NewInstance(l.reg, ic, l.l)
+		inst, err := NewInstance(l.reg, ic, l.im, l.l)
 		if err != nil {
 			return fmt.Errorf("unable to apply config for %s: %w", ic.Name, err)
 		}
@@ -104,6 +119,20 @@ func (l *Loki) Stop() {
 	}
 }
 
+// Health returns the status of every running instance. Instances which
+// failed to start are rejected by ApplyConfig, so every running instance is
+// reported as healthy.
+func (l *Loki) Health() health.Subsystem {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	components := make([]health.Component, 0, len(l.instances))
+	for name := range l.instances {
+		components = append(components, health.Component{Name: name, Healthy: true, Ready: true})
+	}
+	return health.NewSubsystem("loki", components)
+}
+
 // Instance is used to retrieve a named Loki instance
 func (l *Loki) Instance(name string) *Instance {
 	l.mut.Lock()
@@ -119,16 +148,33 @@ type Instance struct {
 	cfg *InstanceConfig
 	log log.Logger
 	reg *util.Unregisterer
+	im  instance.Manager
 
-	promtail *promtail.Promtail
+	promtail      promtailRunner
+	targets       []targetManager
+	metricsWriter *pipelineMetricsWriter
+}
+
+// promtailRunner runs the targets of Promtail, sending entries to its
+// client.
+type promtailRunner interface {
+	Client() client.Client
+	Shutdown()
+}
+
+// targetManager runs targets which aren't supported by Promtail alongside it,
+// sending entries to its client.
+type targetManager interface {
+	Stop()
 }
 
 // NewInstance creates and starts a Loki instance.
-func NewInstance(reg prometheus.Registerer, c *InstanceConfig, l log.Logger) (*Instance, error) {
+func NewInstance(reg prometheus.Registerer, c *InstanceConfig, im instance.Manager, l log.Logger) (*Instance, error) {
 	instReg := prometheus.WrapRegistererWith(prometheus.Labels{"loki_config": c.Name}, reg)
 
 	inst := Instance{
 		reg: util.WrapWithUnregisterer(instReg),
+		im:  im,
 		log: log.With(l, "loki_config", c.Name),
 	}
 	if err := inst.ApplyConfig(c); err != nil {
@@ -150,11 +196,7 @@ func (i *Instance) ApplyConfig(c *InstanceConfig) error {
 		return nil
 	}
 	i.cfg = c
-
-	if i.promtail != nil {
-		i.promtail.Shutdown()
-		i.promtail = nil
-	}
+	i.stop()
 
 	// Unregister all existing metrics before trying to create a new instance.
 	if !i.reg.UnregisterAll() {
@@ -168,19 +210,179 @@ func (i *Instance) ApplyConfig(c *InstanceConfig) error {
 		return nil
 	}
 
-	p, err := promtail.New(config.Config{
+	i.compactPositions(c)
+	i.reg.MustRegister(newPositionsSizeGauge(c.PositionsConfig.PositionsFile))
+
+	// Metrics from pipeline stages are gathered separately from the other
+	// Promtail metrics when they need to be written to a metrics instance.
+	var (
+		reg          prometheus.Registerer = i.reg
+		stageMetrics *prometheus.Registry
+	)
+	if c.PipelineMetrics != nil {
+		if i.im == nil {
+			return fmt.Errorf("pipeline_metrics can't be used because metrics instances are unavailable")
+		}
+		stageMetrics = prometheus.NewRegistry()
+		reg = &stageRegisterer{Registerer: i.reg, stages: stageMetrics}
+	}
+
+	p, err := i.newPromtail(c, reg)
+	if err != nil {
+		return fmt.Errorf("unable to create Loki logging instance: %w", err)
+	}
+
+	i.promtail = p
+
+	if len(c.DockerScrapeConfigs) > 0 {
+		m, err := docker.NewManager(i.log, reg, p.Client(), c.DockerScrapeConfigs)
+		if err != nil {
+			i.stop()
+			return fmt.Errorf("unable to create Loki Docker targets: %w", err)
+		}
+		i.targets = append(i.targets, m)
+	}
+	if len(c.KafkaScrapeConfigs) > 0 {
+		m, err := kafka.NewManager(i.log, reg, p.Client(), c.KafkaScrapeConfigs)
+		if err != nil {
+			i.stop()
+			return fmt.Errorf("unable to create Loki Kafka targets: %w", err)
+		}
+		i.targets = append(i.targets, m)
+	}
+	if len(c.GelfScrapeConfigs) > 0 {
+		m, err := gelf.NewManager(i.log, reg, p.Client(), c.GelfScrapeConfigs)
+		if err != nil {
+			i.stop()
+			return fmt.Errorf("unable to create Loki GELF targets: %w", err)
+		}
+		i.targets = append(i.targets, m)
+	}
+	if len(c.FluentForwardScrapeConfigs) > 0 {
+		m, err := fluentforward.NewManager(i.log, reg, p.Client(), c.FluentForwardScrapeConfigs)
+		if err != nil {
+			i.stop()
+			return fmt.Errorf("unable to create Loki fluent forward targets: %w", err)
+		}
+		i.targets = append(i.targets, m)
+	}
+	if len(c.HerokuScrapeConfigs) > 0 {
+		m, err := heroku.NewManager(i.log, reg, p.Client(), c.HerokuScrapeConfigs)
+		if err != nil {
+			i.stop()
+			return fmt.Errorf("unable to create Loki Heroku targets: %w", err)
+		}
+		i.targets = append(i.targets, m)
+	}
+	if len(c.CompressedFileScrapeConfigs) > 0 {
+		posConfig := c.PositionsConfig
+		posConfig.PositionsFile = compressedfile.PositionsFile(posConfig.PositionsFile)
+
+		m, err := compressedfile.NewManager(i.log, reg, p.Client(), posConfig, c.CompressedFileScrapeConfigs)
+		if err != nil {
+			i.stop()
+			return fmt.Errorf("unable to create Loki compressed file targets: %w", err)
+		}
+		i.targets = append(i.targets, m)
+	}
+	if len(c.SnmpTrapScrapeConfigs) > 0 {
+		m, err := snmptrap.NewManager(i.log, reg, p.Client(), c.SnmpTrapScrapeConfigs)
+		if err != nil {
+			i.stop()
+			return fmt.Errorf("unable to create Loki SNMP trap targets: %w", err)
+		}
+		i.targets = append(i.targets, m)
+	}
+
+	if c.PipelineMetrics != nil {
+		i.metricsWriter = newPipelineMetricsWriter(i.log, *c.PipelineMetrics, i.im, stageMetrics, labels.FromStrings("loki_config", c.Name))
+	}
+	return nil
+}
+
+// newPromtail creates a Promtail for c. When c has a spool, the targets of
+// Promtail send entries to the spool instead of Promtail's client.
+func (i *Instance) newPromtail(c *InstanceConfig, reg prometheus.Registerer) (promtailRunner, error) {
+	cfg := config.Config{
 		ServerConfig:    server.Config{Disable: true},
 		ClientConfigs:   c.ClientConfigs,
 		PositionsConfig: c.PositionsConfig,
 		ScrapeConfig:    c.ScrapeConfig,
 		TargetConfig:    c.TargetConfig,
-	}, false, promtail.WithLogger(i.log), promtail.WithRegisterer(i.reg))
+	}
+	if c.Spool == nil {
+		return promtail.New(cfg, false, promtail.WithLogger(i.log), promtail.WithRegisterer(reg))
+	}
+	return newSpooledPromtail(i.log, i.reg, reg, *c.Spool, cfg)
+}
+
+// spooledPromtail runs the targets of Promtail with a spool as their client.
+type spooledPromtail struct {
+	client  client.Client
+	targets *targets.TargetManagers
+	once    sync.Once
+}
+
+// newSpooledPromtail creates the spool of cfg's clients and starts the
+// targets of cfg. Spool metrics are registered to spoolReg and target
+// metrics to reg.
+func newSpooledPromtail(l log.Logger, spoolReg, reg prometheus.Registerer, spoolCfg spool.Config, cfg config.Config) (*spooledPromtail, error) {
+	// Like Promtail, loki_push_api servers use the log settings of Promtail's
+	// own server.
+	for _, sc := range cfg.ScrapeConfig {
+		if sc.PushConfig != nil {
+			sc.PushConfig.Server.LogLevel = cfg.ServerConfig.LogLevel
+			sc.PushConfig.Server.LogFormat = cfg.ServerConfig.LogFormat
+		}
+	}
+
+	s, err := spool.New(l, spoolReg, spoolCfg, cfg.ClientConfigs...)
 	if err != nil {
-		return fmt.Errorf("unable to create Loki logging instance: %w", err)
+		return nil, fmt.Errorf("unable to create spool: %w", err)
 	}
 
-	i.promtail = p
-	return nil
+	p := &spooledPromtail{client: s}
+	p.targets, err = targets.NewTargetManagers(p, reg, l, cfg.PositionsConfig, s, cfg.ScrapeConfig, &cfg.TargetConfig)
+	if err != nil {
+		s.Stop()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Client returns the spool.
+func (p *spooledPromtail) Client() client.Client {
+	return p.client
+}
+
+// Shutdown stops the targets and then the spool.
+func (p *spooledPromtail) Shutdown() {
+	p.once.Do(func() {
+		if p.targets != nil {
+			p.targets.Stop()
+		}
+		p.client.Stop()
+	})
+}
+
+// compactPositions removes stale entries from the positions file of c. It
+// must be called before Promtail is created, since Promtail only reads the
+// positions file when starting.
+func (i *Instance) compactPositions(c *InstanceConfig) {
+	journalJobs := make(map[string]struct{})
+	for _, sc := range c.ScrapeConfig {
+		if sc.JournalConfig != nil {
+			journalJobs[sc.JobName] = struct{}{}
+		}
+	}
+
+	path := c.PositionsConfig.PositionsFile
+	removed, err := compactPositions(path, c.PositionsMaxAge, journalJobs, time.Now())
+	if err != nil {
+		level.Warn(i.log).Log("msg", "failed to remove stale entries from positions file", "path", path, "err", err)
+	} else if removed > 0 {
+		level.Info(i.log).Log("msg", "removed stale entries from positions file", "path", path, "count", removed)
+	}
 }
 
 // SendEntry passes an entry to the internal promtail client and returns true if successfully sent. It is
@@ -206,6 +408,21 @@ func (i *Instance) SendEntry(entry api.Entry, dur time.Duration) bool {
 func (i *Instance) Stop() {
 	i.mut.Lock()
 	defer i.mut.Unlock()
+	i.stop()
+}
+
+// stop stops all targets and Promtail. Targets must be stopped first since
+// they send entries to the Promtail client. i.mut must be held when calling.
+func (i *Instance) stop() {
+	for _, t := range i.targets {
+		t.Stop()
+	}
+	i.targets = nil
+
+	if i.metricsWriter != nil {
+		i.metricsWriter.Stop()
+		i.metricsWriter = nil
+	}
 
 	if i.promtail != nil {
 		i.promtail.Shutdown()
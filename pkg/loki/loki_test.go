@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -83,7 +84,7 @@ configs:
 	require.NoError(t, dec.Decode(&cfg))
 
 	logger := log.NewSyncLogger(log.NewNopLogger())
-	l, err := New(prometheus.NewRegistry(), cfg, logger)
+	l, err := New(prometheus.NewRegistry(), cfg, nil, logger)
 	require.NoError(t, err)
 	defer l.Stop()
 
@@ -133,3 +134,248 @@ configs:
 		require.Equal(t, "Hello again!", req.Streams[0].Entries[0].Line)
 	}
 }
+
+func TestLoki_TenantRouting(t *testing.T) {
+	positionsDir, err := ioutil.TempDir(os.TempDir(), "positions-*")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(positionsDir)
+	})
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "*.log")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(tmpFile.Name())
+	})
+
+	addr, pushes := listenForPushes(t)
+
+	//
+	// Launch Loki with a tenant stage which sets the tenant from the line.
+	// Lines without a tenant go to the tenant of the client.
+	//
+	cfgText := util.Untab(fmt.Sprintf(`
+positions_directory: %s
+configs:
+- name: default
+  clients:
+  - url: http://%s/loki/api/v1/push
+		tenant_id: default
+		batchwait: 50ms
+		batchsize: 1
+  scrape_configs:
+  - job_name: system
+    static_configs:
+    - targets: [localhost]
+      labels:
+        job: test
+        __path__: %s
+    pipeline_stages:
+    - regex:
+        expression: '^team=(?P<team>\S+)'
+    - tenant:
+        source: team
+	`, positionsDir, addr, tmpFile.Name()))
+
+	var cfg Config
+	dec := yaml.NewDecoder(strings.NewReader(cfgText))
+	dec.SetStrict(true)
+	require.NoError(t, dec.Decode(&cfg))
+
+	l, err := New(prometheus.NewRegistry(), cfg, nil, log.NewSyncLogger(log.NewNopLogger()))
+	require.NoError(t, err)
+	defer l.Stop()
+
+	fmt.Fprintf(tmpFile, "team=a first\nteam=b second\nthird\n")
+
+	tenants := map[string]string{}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-time.After(time.Second * 30):
+			require.FailNow(t, "timed out waiting for data to be pushed")
+		case p := <-pushes:
+			require.NotContains(t, p.labels, "__tenant_id__")
+			tenants[p.line] = p.tenant
+		}
+	}
+	require.Equal(t, map[string]string{
+		"team=a first":  "a",
+		"team=b second": "b",
+		"third":         "default",
+	}, tenants)
+}
+
+func TestLoki_Multiline(t *testing.T) {
+	positionsDir, err := ioutil.TempDir(os.TempDir(), "positions-*")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(positionsDir)
+	})
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "*.log")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(tmpFile.Name())
+	})
+
+	addr, pushes := listenForPushes(t)
+
+	//
+	// Launch Loki with a multiline stage which joins stack traces with the
+	// line that started them.
+	//
+	cfgText := util.Untab(fmt.Sprintf(`
+positions_directory: %s
+configs:
+- name: default
+  clients:
+  - url: http://%s/loki/api/v1/push
+		batchwait: 50ms
+		batchsize: 1
+  scrape_configs:
+  - job_name: system
+    static_configs:
+    - targets: [localhost]
+      labels:
+        job: test
+        __path__: %s
+    pipeline_stages:
+    - multiline:
+        firstline: '^\d{4}-\d{2}-\d{2}'
+        max_wait_time: 100ms
+	`, positionsDir, addr, tmpFile.Name()))
+
+	var cfg Config
+	dec := yaml.NewDecoder(strings.NewReader(cfgText))
+	dec.SetStrict(true)
+	require.NoError(t, dec.Decode(&cfg))
+
+	l, err := New(prometheus.NewRegistry(), cfg, nil, log.NewSyncLogger(log.NewNopLogger()))
+	require.NoError(t, err)
+	defer l.Stop()
+
+	fmt.Fprint(tmpFile, "2021-06-01 12:00:00 ERROR request failed\n"+
+		"java.lang.NullPointerException\n"+
+		"\tat com.example.Handler.handle(Handler.java:42)\n"+
+		"\tat com.example.Server.run(Server.java:7)\n"+
+		"2021-06-01 12:00:01 INFO request succeeded\n")
+
+	// The last block is flushed once max_wait_time passes without a new
+	// first line.
+	var lines []string
+	for i := 0; i < 2; i++ {
+		select {
+		case <-time.After(time.Second * 30):
+			require.FailNow(t, "timed out waiting for data to be pushed")
+		case p := <-pushes:
+			lines = append(lines, p.line)
+		}
+	}
+	require.Equal(t, []string{
+		"2021-06-01 12:00:00 ERROR request failed\n" +
+			"java.lang.NullPointerException\n" +
+			"\tat com.example.Handler.handle(Handler.java:42)\n" +
+			"\tat com.example.Server.run(Server.java:7)",
+		"2021-06-01 12:00:01 INFO request succeeded",
+	}, lines)
+}
+
+type push struct{ tenant, labels, line string }
+
+// listenForPushes runs a server which passes the tenant, labels, and line of
+// each pushed entry through to the returned channel.
+func listenForPushes(t *testing.T) (addr string, pushes <-chan push) {
+	t.Helper()
+
+	ch := make(chan push, 10)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, lis.Close())
+	})
+	go func() {
+		_ = http.Serve(lis, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			req, err := loki_util.ParseRequest(log.NewNopLogger(), "user_id", r)
+			require.NoError(t, err)
+
+			for _, s := range req.Streams {
+				for _, e := range s.Entries {
+					ch <- push{tenant: r.Header.Get("X-Scope-OrgID"), labels: s.Labels, line: e.Line}
+				}
+			}
+			_, _ = rw.Write(nil)
+		}))
+	}()
+
+	return lis.Addr().String(), ch
+}
+
+func TestLoki_Spool(t *testing.T) {
+	positionsDir := t.TempDir()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "*.log")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(tmpFile.Name())
+	})
+
+	pushes := make(chan *logproto.PushRequest)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, lis.Close())
+	})
+	go func() {
+		_ = http.Serve(lis, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			req, err := loki_util.ParseRequest(log.NewNopLogger(), "user_id", r)
+			require.NoError(t, err)
+
+			pushes <- req
+			_, _ = rw.Write(nil)
+		}))
+	}()
+
+	cfgText := util.Untab(fmt.Sprintf(`
+positions_directory: %s
+configs:
+- name: default
+  clients:
+  - url: http://%s/loki/api/v1/push
+		batchwait: 50ms
+		batchsize: 1
+  spool:
+    max_size: 10MB
+  scrape_configs:
+  - job_name: system
+    static_configs:
+    - targets: [localhost]
+      labels:
+        job: test
+        __path__: %s
+	`, positionsDir, lis.Addr().String(), tmpFile.Name()))
+
+	var cfg Config
+	dec := yaml.NewDecoder(strings.NewReader(cfgText))
+	dec.SetStrict(true)
+	require.NoError(t, dec.Decode(&cfg))
+
+	logger := log.NewSyncLogger(log.NewNopLogger())
+	l, err := New(prometheus.NewRegistry(), cfg, nil, logger)
+	require.NoError(t, err)
+	defer l.Stop()
+
+	fmt.Fprintf(tmpFile, "Hello, world!\n")
+	select {
+	case <-time.After(time.Second * 30):
+		require.FailNow(t, "timed out waiting for data to be pushed")
+	case req := <-pushes:
+		require.Equal(t, "Hello, world!", req.Streams[0].Entries[0].Line)
+		require.Equal(t, `{filename="`+tmpFile.Name()+`", job="test"}`, req.Streams[0].Labels)
+	}
+
+	// The spool is stored next to the positions file.
+	_, err = os.Stat(filepath.Join(positionsDir, "default.spool"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,222 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/loki/clients/pkg/logentry/metric"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// DefaultPipelineMetricsConfig holds default settings for a
+// PipelineMetricsConfig.
+var DefaultPipelineMetricsConfig = PipelineMetricsConfig{
+	WriteInterval: 15 * time.Second,
+}
+
+// PipelineMetricsConfig configures writing the metrics created by metrics
+// pipeline stages to a metrics instance, in addition to exposing them on the
+// Agent's /metrics endpoint.
+type PipelineMetricsConfig struct {
+	// PromInstance is the name of the metrics instance to write to.
+	PromInstance string `yaml:"prom_instance"`
+	// WriteInterval is how often the current value of the metrics is written.
+	WriteInterval time.Duration `yaml:"write_interval,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *PipelineMetricsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultPipelineMetricsConfig
+	type plain PipelineMetricsConfig
+	return unmarshal((*plain)(c))
+}
+
+// stageRegisterer registers collectors to a Registerer. Collectors created by
+// metrics pipeline stages are additionally registered to stages so they can
+// be gathered separately from the other Promtail metrics.
+type stageRegisterer struct {
+	prometheus.Registerer
+	stages *prometheus.Registry
+}
+
+// Register implements prometheus.Registerer.
+func (r *stageRegisterer) Register(c prometheus.Collector) error {
+	if err := r.Registerer.Register(c); err != nil {
+		return err
+	}
+
+	switch c.(type) {
+	case *metric.Counters, *metric.Gauges, *metric.Histograms:
+		if err := r.stages.Register(c); err != nil {
+			r.Registerer.Unregister(c)
+			return err
+		}
+	}
+	return nil
+}
+
+// MustRegister implements prometheus.Registerer.
+func (r *stageRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister implements prometheus.Registerer.
+func (r *stageRegisterer) Unregister(c prometheus.Collector) bool {
+	r.stages.Unregister(c)
+	return r.Registerer.Unregister(c)
+}
+
+// pipelineMetricsWriter periodically writes the metrics gathered from a
+// Gatherer to a metrics instance.
+type pipelineMetricsWriter struct {
+	log      log.Logger
+	cfg      PipelineMetricsConfig
+	im       instance.Manager
+	gatherer prometheus.Gatherer
+	// extraLabels are added to every written series.
+	extraLabels labels.Labels
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newPipelineMetricsWriter(l log.Logger, cfg PipelineMetricsConfig, im instance.Manager, g prometheus.Gatherer, extraLabels labels.Labels) *pipelineMetricsWriter {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &pipelineMetricsWriter{
+		log:         log.With(l, "component", "pipeline metrics", "prom_instance", cfg.PromInstance),
+		cfg:         cfg,
+		im:          im,
+		gatherer:    g,
+		extraLabels: extraLabels,
+
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go w.run(ctx)
+	return w
+}
+
+func (w *pipelineMetricsWriter) run(ctx context.Context) {
+	defer close(w.done)
+
+	t := time.NewTicker(w.cfg.WriteInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := w.write(ctx, time.Now()); err != nil {
+				level.Warn(w.log).Log("msg", "failed to write pipeline metrics", "err", err)
+			}
+		}
+	}
+}
+
+// Stop stops writing metrics.
+func (w *pipelineMetricsWriter) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+// write appends the current value of every gathered metric at time now.
+func (w *pipelineMetricsWriter) write(ctx context.Context, now time.Time) error {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	if len(families) == 0 {
+		return nil
+	}
+
+	inst, err := w.im.GetInstance(w.cfg.PromInstance)
+	if err != nil {
+		return err
+	}
+
+	app := inst.Appender(ctx)
+	ts := timestamp.FromTime(now)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			if err := w.appendMetric(app, mf, m, ts); err != nil {
+				_ = app.Rollback()
+				return err
+			}
+		}
+	}
+	return app.Commit()
+}
+
+func (w *pipelineMetricsWriter) appendMetric(app storage.Appender, mf *dto.MetricFamily, m *dto.Metric, ts int64) error {
+	lb := labels.NewBuilder(w.extraLabels)
+	for _, lp := range m.GetLabel() {
+		lb.Set(lp.GetName(), lp.GetValue())
+	}
+
+	add := func(name string, v float64, extra ...labels.Label) error {
+		lb.Set(labels.MetricName, name)
+		for _, l := range extra {
+			lb.Set(l.Name, l.Value)
+		}
+		_, err := app.Append(0, lb.Labels(), ts, v)
+		for _, l := range extra {
+			lb.Del(l.Name)
+		}
+		return err
+	}
+
+	name := mf.GetName()
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		return add(name, m.GetCounter().GetValue())
+	case dto.MetricType_GAUGE:
+		return add(name, m.GetGauge().GetValue())
+	case dto.MetricType_UNTYPED:
+		return add(name, m.GetUntyped().GetValue())
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		var hasInf bool
+		for _, b := range h.GetBucket() {
+			hasInf = hasInf || math.IsInf(b.GetUpperBound(), +1)
+			le := labels.Label{Name: labels.BucketLabel, Value: formatFloat(b.GetUpperBound())}
+			if err := add(name+"_bucket", float64(b.GetCumulativeCount()), le); err != nil {
+				return err
+			}
+		}
+		if !hasInf {
+			le := labels.Label{Name: labels.BucketLabel, Value: "+Inf"}
+			if err := add(name+"_bucket", float64(h.GetSampleCount()), le); err != nil {
+				return err
+			}
+		}
+		if err := add(name+"_sum", h.GetSampleSum()); err != nil {
+			return err
+		}
+		return add(name+"_count", float64(h.GetSampleCount()))
+	default:
+		// Metrics pipeline stages don't create any other type of metric.
+		return nil
+	}
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,139 @@
+package loki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/loki/clients/pkg/logentry/metric"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageRegisterer(t *testing.T) {
+	var (
+		all    = prometheus.NewRegistry()
+		stages = prometheus.NewRegistry()
+		reg    = &stageRegisterer{Registerer: all, stages: stages}
+	)
+
+	counters, err := metric.NewCounters("promtail_custom_lines_total", "lines", map[string]interface{}{"action": "inc"}, int64(time.Hour.Seconds()))
+	require.NoError(t, err)
+	counters.With(model.LabelSet{"job": "test"}).Inc()
+
+	other := prometheus.NewCounter(prometheus.CounterOpts{Name: "promtail_read_lines_total"})
+	other.Inc()
+
+	reg.MustRegister(counters, other)
+
+	require.Equal(t, []string{"promtail_custom_lines_total", "promtail_read_lines_total"}, familyNames(t, all))
+	require.Equal(t, []string{"promtail_custom_lines_total"}, familyNames(t, stages))
+}
+
+func familyNames(t *testing.T, g prometheus.Gatherer) []string {
+	t.Helper()
+
+	families, err := g.Gather()
+	require.NoError(t, err)
+
+	var names []string
+	for _, mf := range families {
+		names = append(names, mf.GetName())
+	}
+	return names
+}
+
+func TestPipelineMetricsWriter_Write(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors_total"}, []string{"level"})
+	counter.WithLabelValues("error").Add(3)
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "queue_size"})
+	gauge.Set(5)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Buckets: []float64{1, 5}})
+	histogram.Observe(0.5)
+	histogram.Observe(10)
+	reg.MustRegister(counter, gauge, histogram)
+
+	app := &fakeAppender{}
+	im := instance.MockManager{
+		GetInstanceFunc: func(name string) (instance.ManagedInstance, error) {
+			require.Equal(t, "logs-metrics", name)
+			return &fakeInstance{app: app}, nil
+		},
+	}
+
+	w := &pipelineMetricsWriter{
+		log:         log.NewNopLogger(),
+		cfg:         PipelineMetricsConfig{PromInstance: "logs-metrics"},
+		im:          im,
+		gatherer:    reg,
+		extraLabels: labels.FromStrings("loki_config", "default"),
+	}
+
+	now := time.Now()
+	require.NoError(t, w.write(context.Background(), now))
+	require.True(t, app.committed)
+
+	ts := timestamp.FromTime(now)
+	expect := []sample{
+		{labels.FromStrings("__name__", "errors_total", "level", "error", "loki_config", "default"), ts, 3},
+		{labels.FromStrings("__name__", "latency_seconds_bucket", "le", "1", "loki_config", "default"), ts, 1},
+		{labels.FromStrings("__name__", "latency_seconds_bucket", "le", "5", "loki_config", "default"), ts, 1},
+		{labels.FromStrings("__name__", "latency_seconds_bucket", "le", "+Inf", "loki_config", "default"), ts, 2},
+		{labels.FromStrings("__name__", "latency_seconds_sum", "loki_config", "default"), ts, 10.5},
+		{labels.FromStrings("__name__", "latency_seconds_count", "loki_config", "default"), ts, 2},
+		{labels.FromStrings("__name__", "queue_size", "loki_config", "default"), ts, 5},
+	}
+	require.Equal(t, expect, app.samples)
+}
+
+func TestPipelineMetricsWriter_Write_NoMetrics(t *testing.T) {
+	w := &pipelineMetricsWriter{
+		log:      log.NewNopLogger(),
+		cfg:      PipelineMetricsConfig{PromInstance: "logs-metrics"},
+		im:       instance.MockManager{},
+		gatherer: prometheus.NewRegistry(),
+	}
+
+	// The instance shouldn't be looked up when there's nothing to write, which
+	// would panic with an empty MockManager.
+	require.NoError(t, w.write(context.Background(), time.Now()))
+}
+
+type fakeInstance struct {
+	instance.ManagedInstance
+	app *fakeAppender
+}
+
+func (i *fakeInstance) Appender(context.Context) storage.Appender { return i.app }
+
+type sample struct {
+	l labels.Labels
+	t int64
+	v float64
+}
+
+type fakeAppender struct {
+	storage.Appender
+	samples   []sample
+	committed bool
+}
+
+func (a *fakeAppender) Append(_ uint64, l labels.Labels, t int64, v float64) (uint64, error) {
+	a.samples = append(a.samples, sample{l, t, v})
+	return 0, nil
+}
+
+func (a *fakeAppender) Commit() error {
+	a.committed = true
+	return nil
+}
+
+func (a *fakeAppender) Rollback() error { return nil }
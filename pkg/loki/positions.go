@@ -0,0 +1,100 @@
+package loki
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/loki/clients/pkg/promtail/positions"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+// journalPositionPrefix is the prefix of positions entries which hold the
+// cursor of a journal scrape config rather than the offset of a file.
+const journalPositionPrefix = "journal-"
+
+// compactPositions removes stale entries from the positions file at path
+// before Promtail loads it. Promtail only removes entries of files which
+// no longer exist, leaving behind entries of files which are never written
+// to again and the cursors of journal scrape configs which were removed.
+//
+// An entry is removed when:
+//
+//  1. It's a journal cursor for a job not in journalJobs.
+//  2. Its file doesn't exist.
+//  3. maxAge is non-zero and its file hasn't been modified for longer than
+//     maxAge.
+//
+// Returns the number of entries removed. The file is only rewritten if an
+// entry was removed.
+func compactPositions(path string, maxAge time.Duration, journalJobs map[string]struct{}, now time.Time) (int, error) {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	var f positions.File
+	if err := yaml.Unmarshal(buf, &f); err != nil {
+		return 0, err
+	}
+
+	var removed int
+	for key := range f.Positions {
+		if isStalePosition(key, maxAge, journalJobs, now) {
+			delete(f.Positions, key)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	buf, err = yaml.Marshal(f)
+	if err != nil {
+		return 0, err
+	}
+
+	// Write to a temporary file first so the positions file is never left
+	// half written.
+	temp := filepath.Clean(path) + "-new"
+	if err := ioutil.WriteFile(temp, buf, 0600); err != nil {
+		return 0, err
+	}
+	return removed, os.Rename(temp, path)
+}
+
+func isStalePosition(key string, maxAge time.Duration, journalJobs map[string]struct{}, now time.Time) bool {
+	if strings.HasPrefix(key, journalPositionPrefix) {
+		_, ok := journalJobs[strings.TrimPrefix(key, journalPositionPrefix)]
+		return !ok
+	}
+
+	fi, err := os.Stat(key)
+	if os.IsNotExist(err) {
+		return true
+	} else if err != nil {
+		// Keep entries we can't check, such as files we lack permission to.
+		return false
+	}
+	return maxAge > 0 && now.Sub(fi.ModTime()) > maxAge
+}
+
+// newPositionsSizeGauge returns a gauge reporting the size of the positions
+// file at path in bytes.
+func newPositionsSizeGauge(path string) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "agent_logs_positions_file_bytes",
+		Help: "Size of the positions file in bytes.",
+	}, func() float64 {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return 0
+		}
+		return float64(fi.Size())
+	})
+}
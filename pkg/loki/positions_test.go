@@ -0,0 +1,95 @@
+package loki
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/clients/pkg/promtail/positions"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestCompactPositions(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "positions-*")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	var (
+		now      = time.Now()
+		recent   = filepath.Join(dir, "recent.log")
+		idle     = filepath.Join(dir, "idle.log")
+		deleted  = filepath.Join(dir, "deleted.log")
+		posFile  = filepath.Join(dir, "positions.yml")
+		idleTime = now.Add(-48 * time.Hour)
+	)
+	require.NoError(t, ioutil.WriteFile(recent, []byte("hello\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(idle, []byte("hello\n"), 0644))
+	require.NoError(t, os.Chtimes(idle, idleTime, idleTime))
+
+	writePositions := func() {
+		buf, err := yaml.Marshal(positions.File{Positions: map[string]string{
+			recent:           "6",
+			idle:             "6",
+			deleted:          "6",
+			"journal-system": "cursor",
+			"journal-old":    "cursor",
+		}})
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(posFile, buf, 0600))
+	}
+	readPositions := func() map[string]string {
+		buf, err := ioutil.ReadFile(posFile)
+		require.NoError(t, err)
+		var f positions.File
+		require.NoError(t, yaml.Unmarshal(buf, &f))
+		return f.Positions
+	}
+	journalJobs := map[string]struct{}{"system": {}}
+
+	// Without a max age, only entries of deleted files and removed journal
+	// jobs are removed.
+	writePositions()
+	removed, err := compactPositions(posFile, 0, journalJobs, now)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+	require.Equal(t, map[string]string{
+		recent:           "6",
+		idle:             "6",
+		"journal-system": "cursor",
+	}, readPositions())
+
+	writePositions()
+	removed, err = compactPositions(posFile, 24*time.Hour, journalJobs, now)
+	require.NoError(t, err)
+	require.Equal(t, 3, removed)
+	require.Equal(t, map[string]string{
+		recent:           "6",
+		"journal-system": "cursor",
+	}, readPositions())
+
+	// A missing positions file is left alone.
+	removed, err = compactPositions(filepath.Join(dir, "missing.yml"), 0, journalJobs, now)
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+}
+
+func TestPositionsSizeGauge(t *testing.T) {
+	f, err := ioutil.TempFile(os.TempDir(), "positions-*.yml")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.Remove(f.Name())
+	})
+
+	_, err = f.WriteString("positions: {}\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.Equal(t, 14.0, testutil.ToFloat64(newPositionsSizeGauge(f.Name())))
+	require.Equal(t, 0.0, testutil.ToFloat64(newPositionsSizeGauge(f.Name()+".missing")))
+}
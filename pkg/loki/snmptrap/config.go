@@ -0,0 +1,68 @@
+// Package snmptrap implements a logs target which receives SNMP traps and
+// inform requests over UDP.
+package snmptrap
+
+import (
+	"fmt"
+
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/agent/pkg/util/snmp"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	ListenAddress: "0.0.0.0:162",
+	Limits:        limit.DefaultConfig,
+}
+
+// Config configures a listener for SNMP traps.
+type Config struct {
+	// JobName identifies the config in logs and metrics.
+	JobName string `yaml:"job_name"`
+
+	// ListenAddress is the UDP address to listen for traps on.
+	ListenAddress string `yaml:"listen_address,omitempty"`
+
+	// Communities lists the accepted communities. Traps of any community are
+	// accepted when empty.
+	Communities []string `yaml:"communities,omitempty"`
+
+	// MIBs maps names to OIDs, translating the OIDs of traps and variables
+	// in addition to the built-in names of common traps.
+	MIBs map[string]string `yaml:"mibs,omitempty"`
+
+	// TrapMetrics enables counting received traps by job and trap name.
+	TrapMetrics bool `yaml:"trap_metrics,omitempty"`
+
+	// Labels are added to every entry before relabeling.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// RelabelConfigs are applied to the labels of each trap.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process each received trap.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+
+	// Limits restrict the rate and size of lines before they are processed by
+	// PipelineStages.
+	Limits limit.Config `yaml:"limits_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	for name, oid := range c.MIBs {
+		if _, err := snmp.ParseOID(oid); err != nil {
+			return fmt.Errorf("snmptrap: invalid OID for %s: %w", name, err)
+		}
+	}
+	return nil
+}
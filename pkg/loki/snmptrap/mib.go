@@ -0,0 +1,84 @@
+package snmptrap
+
+import (
+	"sort"
+
+	"github.com/grafana/agent/pkg/util/snmp"
+)
+
+// builtinMIBs holds the names of the generic traps and of the variables they
+// usually carry.
+var builtinMIBs = map[string]string{
+	// SNMPv2-MIB
+	"sysUpTime":             "1.3.6.1.2.1.1.3",
+	"snmpTrapOID":           "1.3.6.1.6.3.1.1.4.1",
+	"snmpTrapEnterprise":    "1.3.6.1.6.3.1.1.4.3",
+	"coldStart":             "1.3.6.1.6.3.1.1.5.1",
+	"warmStart":             "1.3.6.1.6.3.1.1.5.2",
+	"authenticationFailure": "1.3.6.1.6.3.1.1.5.5",
+
+	// IF-MIB
+	"linkDown":      "1.3.6.1.6.3.1.1.5.3",
+	"linkUp":        "1.3.6.1.6.3.1.1.5.4",
+	"ifIndex":       "1.3.6.1.2.1.2.2.1.1",
+	"ifDescr":       "1.3.6.1.2.1.2.2.1.2",
+	"ifType":        "1.3.6.1.2.1.2.2.1.3",
+	"ifAdminStatus": "1.3.6.1.2.1.2.2.1.7",
+	"ifOperStatus":  "1.3.6.1.2.1.2.2.1.8",
+	"ifName":        "1.3.6.1.2.1.31.1.1.1.1",
+	"ifAlias":       "1.3.6.1.2.1.31.1.1.1.18",
+}
+
+type mibEntry struct {
+	oid  snmp.OID
+	name string
+}
+
+// translator converts OIDs into names.
+type translator struct {
+	// entries are sorted by decreasing OID length, so that the first entry
+	// prefixing an OID is the most specific one.
+	entries []mibEntry
+}
+
+// newTranslator creates a translator knowing the built-in names and mibs.
+// mibs take precedence over the built-in names.
+func newTranslator(mibs map[string]string) (*translator, error) {
+	byOID := make(map[string]string)
+	for _, m := range []map[string]string{builtinMIBs, mibs} {
+		for name, oid := range m {
+			byOID[oid] = name
+		}
+	}
+
+	t := &translator{}
+	for s, name := range byOID {
+		oid, err := snmp.ParseOID(s)
+		if err != nil {
+			return nil, err
+		}
+		t.entries = append(t.entries, mibEntry{oid: oid, name: name})
+	}
+	sort.Slice(t.entries, func(i, j int) bool {
+		if a, b := t.entries[i].oid, t.entries[j].oid; len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return t.entries[i].oid.Compare(t.entries[j].oid) < 0
+	})
+	return t, nil
+}
+
+// translate returns the name of oid, followed by the suffix of oid after
+// the named OID, such as ifDescr.2. OIDs without a known prefix are
+// returned in dotted notation.
+func (t *translator) translate(oid snmp.OID) string {
+	for _, e := range t.entries {
+		if oid.HasPrefix(e.oid) {
+			if suffix := oid[len(e.oid):]; len(suffix) > 0 {
+				return e.name + "." + suffix.String()
+			}
+			return e.name
+		}
+	}
+	return oid.String()
+}
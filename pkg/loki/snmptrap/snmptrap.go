@@ -0,0 +1,326 @@
+package snmptrap
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/loki/limit"
+	"github.com/grafana/agent/pkg/util/snmp"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+const (
+	snmptrapLabel        = "__snmptrap_"
+	snmptrapLabelSource  = snmptrapLabel + "source"
+	snmptrapLabelVersion = snmptrapLabel + "version"
+	snmptrapLabelTrap    = snmptrapLabel + "trap"
+	snmptrapLabelTrapOID = snmptrapLabel + "trap_oid"
+
+	// maxDatagramSize is the largest UDP datagram which can be received.
+	maxDatagramSize = 65535
+)
+
+// Manager runs a listener for each Config.
+type Manager struct {
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	targets []*target
+}
+
+// NewManager creates and starts a Manager. Received traps are sent to
+// handler after being processed by the pipeline stages of their Config.
+func NewManager(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, cfgs []Config) (*Manager, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{cancel: cancel}
+
+	// The counter is shared by every Config enabling trap_metrics, since
+	// they're registered to the same registerer.
+	var traps *prometheus.CounterVec
+	for _, cfg := range cfgs {
+		if cfg.TrapMetrics {
+			traps = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: "loki_snmptrap_traps_total",
+				Help: "Total number of SNMP traps received.",
+			}, []string{"job", "trap"})
+			if err := reg.Register(traps); err != nil {
+				cancel()
+				return nil, err
+			}
+			break
+		}
+	}
+
+	for i := range cfgs {
+		var counter *prometheus.CounterVec
+		if cfgs[i].TrapMetrics {
+			counter = traps
+		}
+		t, err := newTarget(l, reg, handler, counter, &cfgs[i])
+		if err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("failed to create snmptrap job %s: %w", cfgs[i].JobName, err)
+		}
+		m.targets = append(m.targets, t)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			t.readDatagrams(ctx)
+		}()
+	}
+
+	return m, nil
+}
+
+// Stop closes all listeners. Stop blocks until every received trap has been
+// sent to the handler.
+func (m *Manager) Stop() {
+	m.cancel()
+	for _, t := range m.targets {
+		_ = t.conn.Close()
+	}
+	m.wg.Wait()
+	for _, t := range m.targets {
+		t.handler.Stop()
+	}
+}
+
+// target listens for traps for a single Config.
+type target struct {
+	cfg        *Config
+	log        log.Logger
+	handler    api.EntryHandler
+	conn       net.PacketConn
+	translator *translator
+
+	// traps is nil when trap_metrics is disabled.
+	traps *prometheus.CounterVec
+}
+
+func newTarget(l log.Logger, reg prometheus.Registerer, handler api.EntryHandler, traps *prometheus.CounterVec, cfg *Config) (*target, error) {
+	l = log.With(l, "component", "snmptrap", "job", cfg.JobName)
+
+	pipeline, err := stages.NewPipeline(l, cfg.PipelineStages, &cfg.JobName, reg)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := newTranslator(cfg.MIBs)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket("udp", cfg.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	level.Info(l).Log("msg", "listening for SNMP traps", "address", conn.LocalAddr())
+
+	return &target{
+		cfg:        cfg,
+		log:        l,
+		handler:    limit.NewHandler(l, cfg.Limits, cfg.JobName, pipeline.Wrap(handler)),
+		conn:       conn,
+		translator: tr,
+		traps:      traps,
+	}, nil
+}
+
+func (t *target) readDatagrams(ctx context.Context) {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := t.conn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return
+		} else if err != nil {
+			level.Warn(t.log).Log("msg", "failed to read datagram", "err", err)
+			continue
+		}
+
+		var trap snmp.Trap
+		if err := trap.UnmarshalBinary(buf[:n]); err != nil {
+			level.Warn(t.log).Log("msg", "dropping invalid trap", "remote", addr, "err", err)
+			continue
+		}
+		if !t.acceptCommunity(trap.Community) {
+			level.Debug(t.log).Log("msg", "dropping trap with unknown community", "remote", addr)
+			continue
+		}
+
+		// Inform requests are retried by the agent until acknowledged.
+		if trap.PDUType == snmp.InformRequest {
+			if err := t.acknowledge(&trap, addr); err != nil {
+				level.Warn(t.log).Log("msg", "failed to acknowledge inform request", "remote", addr, "err", err)
+			}
+		}
+		t.handleTrap(ctx, &trap, addr)
+	}
+}
+
+func (t *target) acceptCommunity(community string) bool {
+	if len(t.cfg.Communities) == 0 {
+		return true
+	}
+	for _, c := range t.cfg.Communities {
+		if c == community {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *target) acknowledge(trap *snmp.Trap, addr net.Addr) error {
+	b, err := trap.Response().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = t.conn.WriteTo(b, addr)
+	return err
+}
+
+// handleTrap sends a trap to the handler as a JSON log line.
+func (t *target) handleTrap(ctx context.Context, trap *snmp.Trap, addr net.Addr) {
+	name := t.translator.translate(trap.TrapOID)
+	if t.traps != nil {
+		t.traps.WithLabelValues(t.cfg.JobName, name).Inc()
+	}
+
+	source := addr.String()
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		source = udpAddr.IP.String()
+	}
+
+	line, err := json.Marshal(t.trapLine(trap, name, source))
+	if err != nil {
+		level.Warn(t.log).Log("msg", "dropping trap which can't be encoded", "remote", addr, "err", err)
+		return
+	}
+
+	lset := trapLabels(t.cfg, trap, name, source)
+	if lset == nil {
+		return
+	}
+
+	entry := api.Entry{
+		Labels: lset,
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: string(line)},
+	}
+	select {
+	case <-ctx.Done():
+	case t.handler.Chan() <- entry:
+	}
+}
+
+// trapLine is the log line of a trap.
+type trapLine struct {
+	Trap         string                 `json:"trap"`
+	TrapOID      string                 `json:"trap_oid"`
+	Source       string                 `json:"source"`
+	AgentAddress string                 `json:"agent_address,omitempty"`
+	Version      string                 `json:"version"`
+	Uptime       uint64                 `json:"uptime"`
+	Variables    map[string]interface{} `json:"variables"`
+}
+
+func (t *target) trapLine(trap *snmp.Trap, name, source string) *trapLine {
+	line := &trapLine{
+		Trap:      name,
+		TrapOID:   trap.TrapOID.String(),
+		Source:    source,
+		Version:   versionName(trap.Version),
+		Uptime:    trap.Uptime,
+		Variables: make(map[string]interface{}, len(trap.Variables)),
+	}
+	if trap.AgentAddress != nil {
+		line.AgentAddress = trap.AgentAddress.String()
+	}
+
+	for _, v := range trap.Variables {
+		// sysUpTime.0 and snmpTrapOID.0 are already part of the line.
+		if v.OID.Compare(snmp.SysUpTimeOID) == 0 || v.OID.Compare(snmp.SnmpTrapOID) == 0 {
+			continue
+		}
+		line.Variables[t.translator.translate(v.OID)] = t.variableValue(v)
+	}
+	return line
+}
+
+// variableValue converts the value of v into a value which can be encoded
+// to JSON. Octet strings which aren't printable text are hex encoded.
+func (t *target) variableValue(v snmp.Variable) interface{} {
+	switch val := v.Value.(type) {
+	case []byte:
+		if isPrintable(val) {
+			return string(val)
+		}
+		return hex.EncodeToString(val)
+	case snmp.OID:
+		return t.translator.translate(val)
+	case net.IP:
+		return val.String()
+	default:
+		// Numbers are used as is and other types have a nil value.
+		return val
+	}
+}
+
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func versionName(v snmp.Version) string {
+	if v == snmp.Version1 {
+		return "v1"
+	}
+	return "v2c"
+}
+
+// trapLabels builds the labels to attach to a trap. Returns nil if the trap
+// was dropped by relabeling.
+func trapLabels(cfg *Config, trap *snmp.Trap, name, source string) model.LabelSet {
+	lbls := make(map[string]string, len(cfg.Labels)+4)
+	for k, v := range cfg.Labels {
+		lbls[string(k)] = string(v)
+	}
+	lbls[snmptrapLabelSource] = source
+	lbls[snmptrapLabelVersion] = versionName(trap.Version)
+	lbls[snmptrapLabelTrap] = name
+	lbls[snmptrapLabelTrapOID] = trap.TrapOID.String()
+
+	processed := relabel.Process(labels.FromMap(lbls), cfg.RelabelConfigs...)
+	if processed == nil {
+		return nil
+	}
+
+	res := make(model.LabelSet, len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		res[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package snmptrap
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/util/snmp"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func mustParseOID(t *testing.T, s string) snmp.OID {
+	t.Helper()
+	oid, err := snmp.ParseOID(s)
+	require.NoError(t, err)
+	return oid
+}
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`job_name: traps`), &cfg))
+	require.Equal(t, "0.0.0.0:162", cfg.ListenAddress)
+
+	err := yaml.UnmarshalStrict([]byte(`{job_name: traps, mibs: {diskFull: disk}}`), &cfg)
+	require.EqualError(t, err, `snmptrap: invalid OID for diskFull: invalid OID "disk": must have at least two parts`)
+}
+
+func TestTranslator(t *testing.T) {
+	tr, err := newTranslator(map[string]string{
+		"acme":     "1.3.6.1.4.1.99",
+		"diskFull": "1.3.6.1.4.1.99.0.1",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "linkDown", tr.translate(mustParseOID(t, "1.3.6.1.6.3.1.1.5.3")))
+	require.Equal(t, "ifDescr.2", tr.translate(mustParseOID(t, "1.3.6.1.2.1.2.2.1.2.2")))
+	require.Equal(t, "diskFull", tr.translate(mustParseOID(t, "1.3.6.1.4.1.99.0.1")))
+	require.Equal(t, "acme.0.2", tr.translate(mustParseOID(t, "1.3.6.1.4.1.99.0.2")))
+	require.Equal(t, "1.3.6.1.4.1.100", tr.translate(mustParseOID(t, "1.3.6.1.4.1.100")))
+}
+
+func TestManager(t *testing.T) {
+	entries := make(chan api.Entry, 10)
+	handler := api.NewEntryHandler(entries, func() {})
+	reg := prometheus.NewRegistry()
+
+	m, err := NewManager(log.NewNopLogger(), reg, handler, []Config{{
+		JobName:       "traps",
+		ListenAddress: "127.0.0.1:0",
+		Communities:   []string{"public"},
+		MIBs:          map[string]string{"diskFull": "1.3.6.1.4.1.99.0.1"},
+		TrapMetrics:   true,
+		Labels:        model.LabelSet{"job": "traps"},
+		RelabelConfigs: []*relabel.Config{{
+			SourceLabels: model.LabelNames{snmptrapLabelTrap},
+			TargetLabel:  "trap",
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Replacement:  "$1",
+			Action:       relabel.Replace,
+		}},
+	}})
+	require.NoError(t, err)
+	defer m.Stop()
+
+	conn, err := net.Dial("udp", m.targets[0].conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	send := func(community string, pduType snmp.PDUType) {
+		p := snmp.Packet{
+			Version:   snmp.Version2c,
+			Community: community,
+			PDUType:   pduType,
+			RequestID: 42,
+			Variables: []snmp.Variable{
+				{OID: snmp.SysUpTimeOID, Type: snmp.TimeTicks, Value: uint64(1234)},
+				{OID: snmp.SnmpTrapOID, Type: snmp.ObjectIdentifier, Value: mustParseOID(t, "1.3.6.1.4.1.99.0.1")},
+				{OID: mustParseOID(t, "1.3.6.1.2.1.2.2.1.2.2"), Type: snmp.OctetString, Value: []byte("eth0")},
+				{OID: mustParseOID(t, "1.3.6.1.4.1.99.1.1"), Type: snmp.OctetString, Value: []byte{0x00, 0xff}},
+				{OID: mustParseOID(t, "1.3.6.1.4.1.99.1.2"), Type: snmp.Gauge32, Value: uint64(95)},
+			},
+		}
+		b, err := p.MarshalBinary()
+		require.NoError(t, err)
+		_, err = conn.Write(b)
+		require.NoError(t, err)
+	}
+
+	// Traps with another community are dropped.
+	send("private", snmp.TrapV2)
+	send("public", snmp.InformRequest)
+
+	// Inform requests are acknowledged.
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	var resp snmp.Packet
+	require.NoError(t, resp.UnmarshalBinary(buf[:n]))
+	require.Equal(t, snmp.GetResponse, resp.PDUType)
+	require.Equal(t, int32(42), resp.RequestID)
+
+	select {
+	case e := <-entries:
+		require.Equal(t, model.LabelSet{"job": "traps", "trap": "diskFull"}, e.Labels)
+		require.JSONEq(t, `{
+			"trap": "diskFull",
+			"trap_oid": "1.3.6.1.4.1.99.0.1",
+			"source": "127.0.0.1",
+			"version": "v2c",
+			"uptime": 1234,
+			"variables": {"ifDescr.2": "eth0", "1.3.6.1.4.1.99.1.1": "00ff", "1.3.6.1.4.1.99.1.2": 95}
+		}`, e.Line)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for entry")
+	}
+	require.Len(t, entries, 0)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.targets[0].traps.WithLabelValues("traps", "diskFull")))
+}
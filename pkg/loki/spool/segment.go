@@ -0,0 +1,251 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+)
+
+// Every record in a segment is a header followed by an entry encoded as
+// JSON. The header holds the length of the entry and its CRC32 checksum, so
+// a partially written record at the end of a segment can be detected.
+const recordHeaderSize = 8
+
+var (
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+	errCorrupted = errors.New("corrupted record")
+)
+
+// record is an entry as stored in a segment.
+type record struct {
+	Labels    model.LabelSet `json:"labels"`
+	Timestamp time.Time      `json:"ts"`
+	Line      string         `json:"line"`
+}
+
+// encodeRecord returns e encoded as a record including its header.
+func encodeRecord(e api.Entry) ([]byte, error) {
+	data, err := json.Marshal(record{Labels: e.Labels, Timestamp: e.Timestamp, Line: e.Line})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(data, castagnoli))
+	copy(buf[recordHeaderSize:], data)
+	return buf, nil
+}
+
+// readRecord reads the next record from r. It returns io.EOF when r has no
+// more records, and errCorrupted when the record is incomplete or its
+// checksum doesn't match.
+func readRecord(r io.Reader) (api.Entry, int64, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+		return api.Entry{}, 0, io.EOF
+	} else if err != nil {
+		return api.Entry{}, 0, errCorrupted
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(header[0:4]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return api.Entry{}, 0, errCorrupted
+	}
+	if crc32.Checksum(data, castagnoli) != binary.BigEndian.Uint32(header[4:8]) {
+		return api.Entry{}, 0, errCorrupted
+	}
+
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return api.Entry{}, 0, errCorrupted
+	}
+	return api.Entry{
+		Labels: rec.Labels,
+		Entry:  logproto.Entry{Timestamp: rec.Timestamp, Line: rec.Line},
+	}, int64(recordHeaderSize + len(data)), nil
+}
+
+// segmentWriter appends records to a segment.
+type segmentWriter struct {
+	segment int
+	size    int64
+
+	f *os.File
+	w *bufio.Writer
+}
+
+// createSegment creates a new segment in dir.
+func createSegment(dir string, segment int) (*segmentWriter, error) {
+	f, err := os.OpenFile(segmentPath(dir, segment), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create segment: %w", err)
+	}
+	return &segmentWriter{segment: segment, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// Write appends rec to the segment.
+func (w *segmentWriter) Write(rec []byte) error {
+	n, err := w.w.Write(rec)
+	w.size += int64(n)
+	return err
+}
+
+// Flush writes buffered records to the segment file.
+func (w *segmentWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Sync flushes buffered records and syncs the segment file to disk.
+func (w *segmentWriter) Sync() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+// Close syncs and closes the segment file.
+func (w *segmentWriter) Close() error {
+	if err := w.Sync(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// read reads entries from the spool starting at from, until the head of the
+// spool is reached or the lines read are at least maxBytes long. It returns
+// the entries and the position after the last entry read.
+func (s *Spool) read(from position, maxBytes int) ([]api.Entry, position, error) {
+	head, segments, _ := s.state()
+
+	var (
+		entries []api.Entry
+		bytes   int
+		pos     = from
+	)
+	for pos.before(head) && bytes < maxBytes {
+		// The segment at pos may have been removed by the budget or, when
+		// pos is the end of a segment, the next segment needs to be read.
+		if !containsSegment(segments, pos.Segment) {
+			next, ok := nextSegment(segments, pos.Segment)
+			if !ok {
+				break
+			}
+			pos = position{Segment: next}
+			continue
+		}
+
+		end := int64(-1)
+		if pos.Segment == head.Segment {
+			end = head.Offset
+		}
+
+		read, next, done, err := s.readSegment(pos, end, maxBytes-bytes)
+		for _, e := range read {
+			bytes += len(e.Line)
+		}
+		entries = append(entries, read...)
+		pos = next
+
+		switch {
+		case os.IsNotExist(err):
+			// Removed since segments was listed.
+			segments = removeSegment(segments, pos.Segment)
+		case err != nil:
+			return entries, pos, err
+		case done:
+			n, ok := nextSegment(segments, pos.Segment)
+			if !ok {
+				return entries, pos, nil
+			}
+			pos = position{Segment: n}
+		}
+	}
+	return entries, pos, nil
+}
+
+// readSegment reads entries from the segment at from, up to end or the end
+// of the segment when end is -1. Reading stops once the lines read are at
+// least maxBytes long. done is true when the end of a segment which isn't
+// being written anymore was reached.
+func (s *Spool) readSegment(from position, end int64, maxBytes int) (entries []api.Entry, next position, done bool, err error) {
+	f, err := os.Open(segmentPath(s.cfg.Directory, from.Segment))
+	if err != nil {
+		return nil, from, false, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(from.Offset, io.SeekStart); err != nil {
+		return nil, from, false, err
+	}
+
+	var (
+		r     = bufio.NewReader(f)
+		bytes int
+		pos   = from
+	)
+	for (end < 0 || pos.Offset < end) && bytes < maxBytes {
+		e, n, err := readRecord(r)
+		switch {
+		case err == io.EOF && end < 0:
+			return entries, pos, true, nil
+		case err == errCorrupted && end < 0:
+			// Segments which aren't being written anymore can end with a
+			// partially written record when the Agent crashed. The rest of
+			// the segment is skipped.
+			level.Warn(s.log).Log("msg", "skipping corrupted end of segment", "segment", pos.Segment, "offset", pos.Offset)
+			return entries, pos, true, nil
+		case err != nil:
+			return entries, pos, false, fmt.Errorf("failed to read segment %d at offset %d: %w", pos.Segment, pos.Offset, err)
+		}
+
+		entries = append(entries, e)
+		bytes += len(e.Line)
+		pos.Offset += n
+	}
+	return entries, pos, false, nil
+}
+
+// containsSegment returns true if segment is in segments.
+func containsSegment(segments []int, segment int) bool {
+	for _, s := range segments {
+		if s == segment {
+			return true
+		}
+	}
+	return false
+}
+
+// removeSegment returns segments without segment.
+func removeSegment(segments []int, segment int) []int {
+	res := make([]int, 0, len(segments))
+	for _, s := range segments {
+		if s != segment {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// nextSegment returns the first segment in segments after segment.
+func nextSegment(segments []int, segment int) (int, bool) {
+	for _, s := range segments {
+		if s > segment {
+			return s, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,347 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/client"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+const maxErrMsgLen = 1024
+
+// sender pushes the entries of a spool to a Loki endpoint. The position of
+// the last entry Loki accepted is stored in a cursor file in the spool
+// directory, so sending continues from there after a restart.
+type sender struct {
+	spool  *Spool
+	cfg    client.Config
+	log    log.Logger
+	client *http.Client
+
+	// key identifies the client across restarts.
+	key        string
+	cursorPath string
+
+	mut  sync.Mutex
+	sent position
+}
+
+func newSender(s *Spool, cfg client.Config) (*sender, error) {
+	if cfg.URL.URL == nil {
+		return nil, errors.New("client needs target URL")
+	}
+	if err := cfg.Client.Validate(); err != nil {
+		return nil, err
+	}
+	httpClient, err := config.NewClientFromConfig(cfg.Client, "promtail", config.WithHTTP2Disabled())
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Timeout = cfg.Timeout
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(cfg.URL.String() + "\x00" + cfg.TenantID))
+	key := fmt.Sprintf("%016x", h.Sum64())
+
+	snd := &sender{
+		spool:      s,
+		cfg:        cfg,
+		log:        log.With(s.log, "host", cfg.URL.Host),
+		client:     httpClient,
+		key:        key,
+		cursorPath: filepath.Join(s.cfg.Directory, key+".cursor"),
+	}
+
+	// Clients without a cursor start at the oldest spooled entry.
+	_, segments, _ := s.state()
+	snd.sent = position{Segment: segments[0]}
+	if p, err := readCursor(snd.cursorPath); err == nil {
+		snd.sent = p
+	} else if !os.IsNotExist(err) {
+		level.Warn(snd.log).Log("msg", "failed to read spool cursor, sending all spooled entries", "err", err)
+	}
+	return snd, nil
+}
+
+// Sent returns the position after the last entry Loki accepted.
+func (s *sender) Sent() position {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.sent
+}
+
+// run sends batches of entries until stop is closed.
+func (s *sender) run(stop chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	// waitingSince is when the oldest entry of the batch being filled was
+	// first read.
+	var waitingSince time.Time
+	for {
+		_, _, notify := s.spool.state()
+		from := s.Sent()
+
+		entries, next, err := s.spool.read(from, s.cfg.BatchSize)
+		if errors.Is(err, errCorrupted) {
+			level.Error(s.log).Log("msg", "failed to read spool, skipping to the next segment", "err", err)
+			s.commit(position{Segment: from.Segment + 1})
+			continue
+		} else if err != nil {
+			level.Error(s.log).Log("msg", "failed to read spool, will retry", "err", err)
+			if !s.wait(ctx, nil, time.Now().Add(syncInterval)) {
+				return
+			}
+			continue
+		}
+
+		if len(entries) > 0 && waitingSince.IsZero() {
+			waitingSince = time.Now()
+		}
+
+		// Batches are sent once they're full or their oldest entry waited
+		// for BatchWait.
+		full := batchBytes(entries) >= s.cfg.BatchSize
+		if len(entries) == 0 || (!full && time.Since(waitingSince) < s.cfg.BatchWait) {
+			var deadline time.Time
+			if len(entries) > 0 {
+				deadline = waitingSince.Add(s.cfg.BatchWait)
+			}
+			if !s.wait(ctx, notify, deadline) {
+				return
+			}
+			continue
+		}
+		waitingSince = time.Time{}
+
+		if !s.send(ctx, entries) {
+			return
+		}
+		s.commit(next)
+	}
+}
+
+// wait waits until notify is closed or deadline is reached. A zero deadline
+// waits for notify only. wait returns false if ctx was canceled.
+func (s *sender) wait(ctx context.Context, notify <-chan struct{}, deadline time.Time) bool {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-notify:
+	case <-timeout:
+	case <-ctx.Done():
+		return false
+	}
+	return true
+}
+
+// commit stores p as the position after the last entry Loki accepted.
+func (s *sender) commit(p position) {
+	s.mut.Lock()
+	s.sent = p
+	s.mut.Unlock()
+
+	if err := writeCursor(s.cursorPath, p); err != nil {
+		level.Warn(s.log).Log("msg", "failed to write spool cursor", "err", err)
+	}
+}
+
+// send pushes entries to Loki, retrying until Loki accepts them. Entries
+// rejected with a non-retryable error are dropped. send returns false if ctx
+// was canceled before the entries were sent.
+func (s *sender) send(ctx context.Context, entries []api.Entry) bool {
+	batches := make(map[string][]api.Entry)
+	for _, e := range entries {
+		if len(s.cfg.ExternalLabels.LabelSet) > 0 {
+			e.Labels = s.cfg.ExternalLabels.LabelSet.Merge(e.Labels)
+		}
+		tenantID := s.tenantID(e.Labels)
+		batches[tenantID] = append(batches[tenantID], e)
+	}
+
+	for tenantID, batch := range batches {
+		buf, err := encodeBatch(batch)
+		if err != nil {
+			level.Error(s.log).Log("msg", "error encoding batch", "err", err)
+			s.spool.metrics.droppedEntries.WithLabelValues(s.cfg.URL.Host).Add(float64(len(batch)))
+			continue
+		}
+
+		// Spooled entries are retried until they're sent or dropped by the
+		// spool budget, so max_retries isn't used.
+		backoff := util.NewBackoff(ctx, util.BackoffConfig{
+			MinBackoff: s.cfg.BackoffConfig.MinBackoff,
+			MaxBackoff: s.cfg.BackoffConfig.MaxBackoff,
+		})
+		for {
+			status, err := s.push(ctx, tenantID, buf)
+			if err == nil {
+				s.spool.metrics.sentEntries.WithLabelValues(s.cfg.URL.Host).Add(float64(len(batch)))
+				s.spool.metrics.sentBytes.WithLabelValues(s.cfg.URL.Host).Add(float64(len(buf)))
+				break
+			}
+			if ctx.Err() != nil {
+				return false
+			}
+
+			// Only retry 429s, 500s and connection-level errors.
+			if status > 0 && status != 429 && status/100 != 5 {
+				level.Error(s.log).Log("msg", "final error sending spooled batch", "status", status, "err", err)
+				s.spool.metrics.droppedEntries.WithLabelValues(s.cfg.URL.Host).Add(float64(len(batch)))
+				break
+			}
+
+			level.Warn(s.log).Log("msg", "error sending spooled batch, will retry", "status", status, "err", err)
+			backoff.Wait()
+			if ctx.Err() != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// tenantID returns the tenant to send an entry with labels to.
+func (s *sender) tenantID(labels model.LabelSet) string {
+	if value, ok := labels[client.ReservedLabelTenantID]; ok {
+		return string(value)
+	}
+	return s.cfg.TenantID
+}
+
+// push sends an encoded batch to Loki.
+func (s *sender) push(ctx context.Context, tenantID string, buf []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL.String(), bytes.NewReader(buf))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", client.UserAgent)
+	if tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", tenantID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxErrMsgLen))
+		line := ""
+		if scanner.Scan() {
+			line = scanner.Text()
+		}
+		return resp.StatusCode, fmt.Errorf("server returned HTTP status %s (%d): %s", resp.Status, resp.StatusCode, line)
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// encodeBatch encodes entries as a snappy-compressed push request.
+func encodeBatch(entries []api.Entry) ([]byte, error) {
+	streams := make(map[string]*logproto.Stream)
+	var order []string
+	for _, e := range entries {
+		labels := labelsString(e.Labels)
+		stream, ok := streams[labels]
+		if !ok {
+			stream = &logproto.Stream{Labels: labels}
+			streams[labels] = stream
+			order = append(order, labels)
+		}
+		stream.Entries = append(stream.Entries, e.Entry)
+	}
+
+	req := logproto.PushRequest{Streams: make([]logproto.Stream, 0, len(streams))}
+	for _, labels := range order {
+		req.Streams = append(req.Streams, *streams[labels])
+	}
+
+	buf, err := proto.Marshal(&req)
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, buf), nil
+}
+
+// labelsString returns ls in the Prometheus text format, without the
+// reserved tenant ID label.
+func labelsString(ls model.LabelSet) string {
+	strs := make([]string, 0, len(ls))
+	for l, v := range ls {
+		if l == client.ReservedLabelTenantID {
+			continue
+		}
+		strs = append(strs, fmt.Sprintf("%s=%q", l, v))
+	}
+	sort.Strings(strs)
+	return fmt.Sprintf("{%s}", strings.Join(strs, ", "))
+}
+
+// batchBytes returns the size of the lines of entries.
+func batchBytes(entries []api.Entry) int {
+	var n int
+	for _, e := range entries {
+		n += len(e.Line)
+	}
+	return n
+}
+
+// readCursor reads a position from a cursor file.
+func readCursor(path string) (position, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return position{}, err
+	}
+
+	var p position
+	if _, err := fmt.Sscanf(string(data), "%d %d", &p.Segment, &p.Offset); err != nil {
+		return position{}, fmt.Errorf("invalid cursor file %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// writeCursor atomically writes p to a cursor file.
+func writeCursor(path string, p position) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", p.Segment, p.Offset)), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,458 @@
+// Package spool implements a logs client which writes entries to disk before
+// sending them to Loki. Spooled entries survive restarts of the Agent and
+// outages of Loki, up to a size and age budget.
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/client"
+	"github.com/grafana/loki/pkg/util/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultConfig holds default settings for a Config.
+var DefaultConfig = Config{
+	MaxSize: 1 << 30,
+	MaxAge:  24 * time.Hour,
+}
+
+const (
+	// maxSegmentSize is the largest size of a segment file. Segments are
+	// smaller when the spool is small, so the budget is enforced in
+	// reasonably small steps.
+	maxSegmentSize = 64 << 20
+
+	// syncInterval is how often the segment being written is synced to disk
+	// and segments outside of the budget are removed.
+	syncInterval = time.Second
+)
+
+// Config configures a spool.
+type Config struct {
+	// Directory to store spooled entries in.
+	Directory string `yaml:"directory,omitempty"`
+	// MaxSize is the maximum size of the spool on disk. The oldest entries
+	// are dropped when the spool grows larger.
+	MaxSize flagext.ByteSize `yaml:"max_size,omitempty"`
+	// MaxAge is the maximum age of spooled entries. Older entries are
+	// dropped. 0 disables the limit.
+	MaxAge time.Duration `yaml:"max_age,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.MaxSize == 0 {
+		return fmt.Errorf("spool: max_size must be greater than 0")
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("spool: max_age must not be negative")
+	}
+	return nil
+}
+
+// segmentSize returns the size at which segments are cut.
+func (c *Config) segmentSize() int64 {
+	size := int64(c.MaxSize) / 4
+	if size > maxSegmentSize {
+		size = maxSegmentSize
+	}
+	return size
+}
+
+// position is a position in the spool.
+type position struct {
+	Segment int
+	Offset  int64
+}
+
+// before returns true if p is before o.
+func (p position) before(o position) bool {
+	return p.Segment < o.Segment || (p.Segment == o.Segment && p.Offset < o.Offset)
+}
+
+type metrics struct {
+	sizeBytes      prometheus.Gauge
+	droppedBytes   prometheus.Counter
+	sentEntries    *prometheus.CounterVec
+	sentBytes      *prometheus.CounterVec
+	droppedEntries *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		sizeBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_logs_spool_size_bytes",
+			Help: "Size of the segments of the spool on disk.",
+		}),
+		droppedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_logs_spool_dropped_bytes_total",
+			Help: "Bytes of segments removed from the spool by max_size or max_age before every client sent them.",
+		}),
+		sentEntries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_logs_spool_sent_entries_total",
+			Help: "Number of spooled log entries sent to Loki.",
+		}, []string{client.HostLabel}),
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_logs_spool_sent_bytes_total",
+			Help: "Number of encoded bytes of spooled log entries sent to Loki.",
+		}, []string{client.HostLabel}),
+		droppedEntries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_logs_spool_dropped_entries_total",
+			Help: "Number of spooled log entries dropped because Loki rejected them with a non-retryable error.",
+		}, []string{client.HostLabel}),
+	}
+
+	for _, c := range []prometheus.Collector{m.sizeBytes, m.droppedBytes, m.sentEntries, m.sentBytes, m.droppedEntries} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Spool is a client.Client which appends entries to segment files in a
+// directory. Every client config has a sender which reads the segments and
+// pushes them to Loki, only moving past entries once Loki accepted them.
+type Spool struct {
+	log     log.Logger
+	cfg     Config
+	metrics *metrics
+	entries chan api.Entry
+	senders []*sender
+
+	// file is the segment being written, only used by run.
+	file *segmentWriter
+
+	mut      sync.Mutex
+	segments []int
+	head     position      // End of the entries flushed to the segments.
+	notify   chan struct{} // Closed when head moves.
+
+	writerDone chan struct{}
+	stopSend   chan struct{}
+	sendersWg  sync.WaitGroup
+	once       sync.Once
+}
+
+// New creates and starts a Spool storing entries in cfg.Directory and sending
+// them to the clients of cfgs. Entries spooled before a restart are sent
+// again from the last entry each client sent.
+func New(l log.Logger, reg prometheus.Registerer, cfg Config, cfgs ...client.Config) (*Spool, error) {
+	if len(cfgs) == 0 {
+		return nil, errors.New("at least one client config should be provided")
+	}
+	if err := os.MkdirAll(cfg.Directory, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	m, err := newMetrics(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := listSegments(cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	// Segments are never appended to after a restart, since the last one may
+	// end with an entry which was only partially written.
+	next := 0
+	if len(segments) > 0 {
+		next = segments[len(segments)-1] + 1
+	}
+	file, err := createSegment(cfg.Directory, next)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		log:      log.With(l, "component", "spool"),
+		cfg:      cfg,
+		metrics:  m,
+		entries:  make(chan api.Entry),
+		file:     file,
+		segments: append(segments, next),
+		head:     position{Segment: next},
+		notify:   make(chan struct{}),
+
+		writerDone: make(chan struct{}),
+		stopSend:   make(chan struct{}),
+	}
+
+	keys := make(map[string]struct{}, len(cfgs))
+	for _, cc := range cfgs {
+		snd, err := newSender(s, cc)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if _, ok := keys[snd.key]; ok {
+			file.Close()
+			return nil, fmt.Errorf("clients must have different URLs or tenant IDs to be spooled, found %s twice", cc.URL.String())
+		}
+		keys[snd.key] = struct{}{}
+		s.senders = append(s.senders, snd)
+	}
+
+	s.cleanup(time.Now())
+
+	go s.run()
+	for _, snd := range s.senders {
+		s.sendersWg.Add(1)
+		go func(snd *sender) {
+			defer s.sendersWg.Done()
+			snd.run(s.stopSend)
+		}(snd)
+	}
+	return s, nil
+}
+
+// Chan implements client.Client.
+func (s *Spool) Chan() chan<- api.Entry {
+	return s.entries
+}
+
+// run writes entries to segments until the entries channel is closed.
+func (s *Spool) run() {
+	defer close(s.writerDone)
+
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	defer func() {
+		s.flush()
+		if err := s.file.Close(); err != nil {
+			level.Error(s.log).Log("msg", "failed to close segment", "err", err)
+		}
+	}()
+
+	for {
+		select {
+		case e, ok := <-s.entries:
+			if !ok {
+				return
+			}
+			s.write(e)
+
+			// Entries are only flushed once no more are waiting, so bursts
+			// of entries are written to the segment at once.
+		drain:
+			for {
+				select {
+				case e, ok := <-s.entries:
+					if !ok {
+						return
+					}
+					s.write(e)
+				default:
+					break drain
+				}
+			}
+			s.flush()
+
+		case now := <-ticker.C:
+			if err := s.file.Sync(); err != nil {
+				level.Error(s.log).Log("msg", "failed to sync segment", "err", err)
+			}
+			s.cleanup(now)
+		}
+	}
+}
+
+// write appends e to the current segment, cutting a new segment when the
+// current one is full. Entries which can't be written are dropped.
+func (s *Spool) write(e api.Entry) {
+	rec, err := encodeRecord(e)
+	if err != nil {
+		level.Error(s.log).Log("msg", "failed to encode entry, dropping it", "err", err)
+		return
+	}
+
+	if s.file.size > 0 && s.file.size+int64(len(rec)) > s.cfg.segmentSize() {
+		if err := s.cut(); err != nil {
+			level.Error(s.log).Log("msg", "failed to cut segment, dropping entry", "err", err)
+			return
+		}
+	}
+	if err := s.file.Write(rec); err != nil {
+		level.Error(s.log).Log("msg", "failed to write entry, dropping it", "err", err)
+	}
+}
+
+// cut closes the current segment and starts writing to a new one.
+func (s *Spool) cut() error {
+	s.flush()
+	if err := s.file.Close(); err != nil {
+		level.Warn(s.log).Log("msg", "failed to close segment", "err", err)
+	}
+
+	next := s.file.segment + 1
+	file, err := createSegment(s.cfg.Directory, next)
+	if err != nil {
+		return err
+	}
+	s.file = file
+
+	s.mut.Lock()
+	s.segments = append(s.segments, next)
+	s.moveHead(position{Segment: next})
+	s.mut.Unlock()
+
+	s.cleanup(time.Now())
+	return nil
+}
+
+// flush makes the written entries visible to senders.
+func (s *Spool) flush() {
+	if err := s.file.Flush(); err != nil {
+		level.Error(s.log).Log("msg", "failed to flush segment", "err", err)
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.moveHead(position{Segment: s.file.segment, Offset: s.file.size})
+}
+
+// moveHead moves the head to p and notifies senders. mut must be held.
+func (s *Spool) moveHead(p position) {
+	if s.head == p {
+		return
+	}
+	s.head = p
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// state returns the head of the spool, the existing segments, and a channel
+// which is closed once the head moves.
+func (s *Spool) state() (head position, segments []int, notify <-chan struct{}) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.head, s.segments, s.notify
+}
+
+// cleanup removes segments which every sender has sent, and segments which
+// are outside of the size or age budget. The segment being written is never
+// removed.
+func (s *Spool) cleanup(now time.Time) {
+	sent := s.senders[0].Sent()
+	for _, snd := range s.senders[1:] {
+		if p := snd.Sent(); p.before(sent) {
+			sent = p
+		}
+	}
+
+	s.mut.Lock()
+	segments := append([]int(nil), s.segments...)
+	s.mut.Unlock()
+
+	type segmentInfo struct {
+		size    int64
+		modTime time.Time
+	}
+	var (
+		infos = make([]segmentInfo, len(segments))
+		total int64
+	)
+	for i, seg := range segments {
+		if fi, err := os.Stat(segmentPath(s.cfg.Directory, seg)); err == nil {
+			infos[i] = segmentInfo{size: fi.Size(), modTime: fi.ModTime()}
+			total += fi.Size()
+		}
+	}
+
+	var removed int
+Segments:
+	for i, seg := range segments[:len(segments)-1] {
+		// Segments are ordered from oldest to newest, so once a segment is
+		// kept, every newer segment is kept too.
+		var reason string
+		switch {
+		case seg < sent.Segment:
+		case total > int64(s.cfg.MaxSize):
+			reason = "max_size"
+		case s.cfg.MaxAge > 0 && now.Sub(infos[i].modTime) > s.cfg.MaxAge:
+			reason = "max_age"
+		default:
+			break Segments
+		}
+
+		if err := os.Remove(segmentPath(s.cfg.Directory, seg)); err != nil && !os.IsNotExist(err) {
+			level.Warn(s.log).Log("msg", "failed to remove segment", "segment", seg, "err", err)
+			break
+		}
+		if reason != "" {
+			level.Warn(s.log).Log("msg", "dropped spooled entries which weren't sent", "reason", reason, "segment", seg, "bytes", infos[i].size)
+			s.metrics.droppedBytes.Add(float64(infos[i].size))
+		}
+		total -= infos[i].size
+		removed++
+	}
+
+	s.mut.Lock()
+	s.segments = s.segments[removed:]
+	s.mut.Unlock()
+
+	s.metrics.sizeBytes.Set(float64(total))
+}
+
+// Stop stops the spool. Entries which weren't sent yet stay in the spool and
+// are sent once a spool is created again for the same directory.
+func (s *Spool) Stop() {
+	s.once.Do(func() {
+		close(s.entries)
+		<-s.writerDone
+
+		close(s.stopSend)
+		s.sendersWg.Wait()
+	})
+}
+
+// StopNow implements client.Client. It is the same as Stop, since unsent
+// entries are kept in the spool.
+func (s *Spool) StopNow() {
+	s.Stop()
+}
+
+// segmentPath returns the path of a segment in dir.
+func segmentPath(dir string, segment int) string {
+	return filepath.Join(dir, fmt.Sprintf("%08d", segment))
+}
+
+// listSegments returns the numbers of the segments in dir in ascending
+// order.
+func listSegments(dir string) ([]int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var segments []int
+	for _, f := range files {
+		n, err := strconv.Atoi(f.Name())
+		if err != nil || f.IsDir() {
+			continue
+		}
+		segments = append(segments, n)
+	}
+	sort.Ints(segments)
+	return segments, nil
+}
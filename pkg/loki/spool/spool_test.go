@@ -0,0 +1,227 @@
+package spool
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/flagext"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/snappy"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/client"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// fakeLoki is a Loki push API which can be made unavailable.
+type fakeLoki struct {
+	srv *httptest.Server
+
+	mut         sync.Mutex
+	unavailable bool
+	lines       []string
+	tenants     []string
+}
+
+func newFakeLoki(t *testing.T) *fakeLoki {
+	l := &fakeLoki{}
+	l.srv = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		l.mut.Lock()
+		defer l.mut.Unlock()
+
+		if l.unavailable {
+			http.Error(rw, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		compressed, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		buf, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+
+		var req logproto.PushRequest
+		require.NoError(t, req.Unmarshal(buf))
+		for _, s := range req.Streams {
+			for _, e := range s.Entries {
+				l.lines = append(l.lines, e.Line)
+			}
+		}
+		l.tenants = append(l.tenants, r.Header.Get("X-Scope-OrgID"))
+	}))
+	t.Cleanup(l.srv.Close)
+	return l
+}
+
+func (l *fakeLoki) SetUnavailable(unavailable bool) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.unavailable = unavailable
+}
+
+func (l *fakeLoki) Lines() []string {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func (l *fakeLoki) ClientConfig(t *testing.T) client.Config {
+	u, err := url.Parse(l.srv.URL + "/loki/api/v1/push")
+	require.NoError(t, err)
+
+	return client.Config{
+		URL:           flagext.URLValue{URL: u},
+		BatchWait:     10 * time.Millisecond,
+		BatchSize:     client.BatchSize,
+		BackoffConfig: util.BackoffConfig{MinBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+		Timeout:       time.Second,
+		TenantID:      "tenant",
+	}
+}
+
+func sendLines(s *Spool, lines ...string) {
+	for _, line := range lines {
+		s.Chan() <- api.Entry{
+			Labels: model.LabelSet{"job": "test"},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`{directory: /tmp/spool, max_size: 1MB}`), &cfg))
+	require.Equal(t, Config{Directory: "/tmp/spool", MaxSize: 1 << 20, MaxAge: 24 * time.Hour}, cfg)
+
+	err := yaml.UnmarshalStrict([]byte(`max_age: -1s`), &cfg)
+	require.EqualError(t, err, "spool: max_age must not be negative")
+}
+
+func TestSpool(t *testing.T) {
+	loki := newFakeLoki(t)
+
+	cfg := DefaultConfig
+	cfg.Directory = t.TempDir()
+
+	s, err := New(log.NewNopLogger(), prometheus.NewRegistry(), cfg, loki.ClientConfig(t))
+	require.NoError(t, err)
+	defer s.Stop()
+
+	sendLines(s, "a", "b", "c")
+	require.Eventually(t, func() bool {
+		return len(loki.Lines()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"a", "b", "c"}, loki.Lines())
+	require.Equal(t, 3.0, testutil.ToFloat64(s.metrics.sentEntries))
+}
+
+func TestSpool_Restart(t *testing.T) {
+	loki := newFakeLoki(t)
+	loki.SetUnavailable(true)
+
+	cfg := DefaultConfig
+	cfg.Directory = t.TempDir()
+
+	// Entries accepted while Loki is unavailable are kept in the spool when
+	// it's stopped.
+	s, err := New(log.NewNopLogger(), prometheus.NewRegistry(), cfg, loki.ClientConfig(t))
+	require.NoError(t, err)
+	sendLines(s, "a", "b")
+	s.Stop()
+
+	loki.SetUnavailable(false)
+
+	s, err = New(log.NewNopLogger(), prometheus.NewRegistry(), cfg, loki.ClientConfig(t))
+	require.NoError(t, err)
+	sendLines(s, "c")
+	require.Eventually(t, func() bool {
+		return len(loki.Lines()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	s.Stop()
+	require.Equal(t, []string{"a", "b", "c"}, loki.Lines())
+
+	// Entries which were sent aren't sent again.
+	s, err = New(log.NewNopLogger(), prometheus.NewRegistry(), cfg, loki.ClientConfig(t))
+	require.NoError(t, err)
+	sendLines(s, "d")
+	require.Eventually(t, func() bool {
+		return len(loki.Lines()) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	s.Stop()
+	require.Equal(t, []string{"a", "b", "c", "d"}, loki.Lines())
+}
+
+func TestSpool_MaxSize(t *testing.T) {
+	loki := newFakeLoki(t)
+	loki.SetUnavailable(true)
+
+	cfg := DefaultConfig
+	cfg.Directory = t.TempDir()
+	cfg.MaxSize = 4096
+
+	s, err := New(log.NewNopLogger(), prometheus.NewRegistry(), cfg, loki.ClientConfig(t))
+	require.NoError(t, err)
+	defer s.Stop()
+
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf("line %03d", i))
+	}
+	sendLines(s, lines...)
+
+	// The oldest segments are removed to stay within max_size. The newest
+	// entries are still sent once Loki is available.
+	require.Greater(t, testutil.ToFloat64(s.metrics.droppedBytes), 0.0)
+	require.LessOrEqual(t, testutil.ToFloat64(s.metrics.sizeBytes), 4096.0)
+
+	loki.SetUnavailable(false)
+	require.Eventually(t, func() bool {
+		sent := loki.Lines()
+		return len(sent) > 0 && sent[len(sent)-1] == "line 199"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NotEqual(t, "line 000", loki.Lines()[0])
+}
+
+func TestSpool_CorruptedSegment(t *testing.T) {
+	loki := newFakeLoki(t)
+
+	cfg := DefaultConfig
+	cfg.Directory = t.TempDir()
+
+	// Write a segment ending with a partially written record, as left behind
+	// by a crash.
+	w, err := createSegment(cfg.Directory, 0)
+	require.NoError(t, err)
+	rec, err := encodeRecord(api.Entry{Labels: model.LabelSet{"job": "test"}, Entry: logproto.Entry{Timestamp: time.Now(), Line: "a"}})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Write(rec[:len(rec)-1]))
+	require.NoError(t, w.Close())
+
+	s, err := New(log.NewNopLogger(), prometheus.NewRegistry(), cfg, loki.ClientConfig(t))
+	require.NoError(t, err)
+	defer s.Stop()
+
+	sendLines(s, "b")
+	require.Eventually(t, func() bool {
+		return len(loki.Lines()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"a", "b"}, loki.Lines())
+
+	// The segment is removed once it was sent.
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(segmentPath(cfg.Directory, 0))
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+}
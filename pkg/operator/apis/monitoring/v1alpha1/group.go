@@ -26,5 +26,11 @@ func init() {
 		&GrafanaAgentList{},
 		&PrometheusInstance{},
 		&PrometheusInstanceList{},
+		&LogsInstance{},
+		&LogsInstanceList{},
+		&PodLogs{},
+		&PodLogsList{},
+		&TracesInstance{},
+		&TracesInstanceList{},
 	)
 }
@@ -124,6 +124,12 @@ type GrafanaAgentSpec struct {
 	// Prometheus controls the Prometheus subsystem of the Agent and settings
 	// unique to Prometheus-specific pods that are deployed.
 	Prometheus PrometheusSubsystemSpec `json:"prometheus,omitempty"`
+	// Logs controls the logging subsystem of the Agent and settings unique to
+	// logging-specific pods that are deployed.
+	Logs LogsSubsystemSpec `json:"logs,omitempty"`
+	// Traces controls the traces subsystem of the Agent. Traces instances run
+	// in the same pods as the Prometheus subsystem.
+	Traces TracesSubsystemSpec `json:"traces,omitempty"`
 }
 
 // RemoteWriteSpec defines the remote_write configuration for Prometheus.
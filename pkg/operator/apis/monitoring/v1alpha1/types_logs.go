@@ -0,0 +1,264 @@
+package v1alpha1
+
+import (
+	prom_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LogsSubsystemSpec defines global settings to apply across the logging
+// subsystem.
+type LogsSubsystemSpec struct {
+	// Clients controls the default clients to use for sending logs. If an
+	// instance does not provide its own clients, these will be used instead.
+	Clients []LogsClientSpec `json:"clients,omitempty"`
+	// LogsExternalLabelName is the name of the external label used to denote
+	// Grafana Agent cluster. Defaults to "cluster." External label will _not_
+	// be added when value is set to the empty string.
+	LogsExternalLabelName *string `json:"logsExternalLabelName,omitempty"`
+	// InstanceSelector determines which LogsInstances should be selected for
+	// running. Each instance runs its own set of tailers and clients.
+	InstanceSelector *metav1.LabelSelector `json:"instanceSelector,omitempty"`
+	// InstanceNamespaceSelector are the set of labels to determine which
+	// namespaces to watch for LogsInstances. If not provided, only checks own
+	// namespace.
+	InstanceNamespaceSelector *metav1.LabelSelector `json:"instanceNamespaceSelector,omitempty"`
+	// IgnoreNamespaceSelectors, if true, will ignore NamespaceSelector settings
+	// from the PodLogs configs, and they will only discover endpoints within
+	// their current namespace.
+	IgnoreNamespaceSelectors bool `json:"ignoreNamespaceSelectors,omitempty"`
+	// EnforcedNamespaceLabel enforces adding a namespace label of origin for
+	// each log entry that is user-created. The label value will always be the
+	// namespace of the object that is being created.
+	EnforcedNamespaceLabel string `json:"enforcedNamespaceLabel,omitempty"`
+}
+
+// LogsClientSpec defines the client integration for logs, indicating which
+// Loki server to send logs to.
+type LogsClientSpec struct {
+	// URL is the URL where Loki is listening. Must be a full HTTP URL, including
+	// protocol. Required.
+	// Example: https://logs-prod-us-central1.grafana.net/loki/api/v1/push.
+	URL string `json:"url"`
+	// Tenant ID used by default to push logs to Loki. If omitted assumes remote
+	// Loki is running in single-tenant mode or an authentication layer is used
+	// to inject an X-Scope-OrgID header.
+	TenantID string `json:"tenantId,omitempty"`
+	// Maximum amount of time to wait before sending a batch, even if that batch
+	// isn't full.
+	BatchWait string `json:"batchWait,omitempty"`
+	// Maximum batch size (in bytes) of logs to accumulate before sending the
+	// batch to Loki.
+	BatchSize int `json:"batchSize,omitempty"`
+	// BasicAuth for the Loki server.
+	BasicAuth *prom_v1.BasicAuth `json:"basicAuth,omitempty"`
+	// BearerToken used for the Loki server.
+	BearerToken string `json:"bearerToken,omitempty"`
+	// BearerTokenFile used to read bearer token.
+	BearerTokenFile string `json:"bearerTokenFile,omitempty"`
+	// ProxyURL to proxy requests through. Optional.
+	ProxyURL string `json:"proxyUrl,omitempty"`
+	// TLSConfig to use for the client. Only used when the protocol of the URL
+	// is https.
+	TLSConfig *prom_v1.TLSConfig `json:"tlsConfig,omitempty"`
+	// Configures how to retry requests to Loki when a request fails.
+	// Defaults to a minPeriod of 500ms, maxPeriod of 5m, and maxRetries of 10.
+	BackoffConfig *LogsBackoffConfigSpec `json:"backoffConfig,omitempty"`
+	// ExternalLabels are labels to add to any log entries when sending them to
+	// Loki.
+	ExternalLabels map[string]string `json:"externalLabels,omitempty"`
+	// Maximum time to wait for a server to respond to a request.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// LogsBackoffConfigSpec configures timing for retrying failed requests.
+type LogsBackoffConfigSpec struct {
+	// Initial backoff time between retries. Time between retries is
+	// increased exponentially.
+	MinPeriod string `json:"minPeriod,omitempty"`
+	// Maximum backoff time between retries.
+	MaxPeriod string `json:"maxPeriod,omitempty"`
+	// Maximum number of retries to perform before giving up a request.
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="logs-instances"
+// +kubebuilder:resource:singular="logs-instance"
+// +kubebuilder:resource:categories="agent-operator"
+
+// LogsInstance controls an individual logs instance within a Grafana Agent
+// deployment.
+type LogsInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec holds the specification of the desired behavior for the logs
+	// instance.
+	Spec LogsInstanceSpec `json:"spec,omitempty"`
+}
+
+// LogsInstanceSpec controls how an individual instance will be used to
+// discover PodLogs.
+type LogsInstanceSpec struct {
+	// Clients controls where logs are written to for this instance.
+	Clients []LogsClientSpec `json:"clients,omitempty"`
+	// PodLogsSelector determines which PodLogs should be selected for target
+	// discovery.
+	PodLogsSelector *metav1.LabelSelector `json:"podLogsSelector,omitempty"`
+	// PodLogsNamespaceSelector are the set of labels to determine which
+	// namespaces to watch for PodLogs discovery. If nil, only checks own
+	// namespace.
+	PodLogsNamespaceSelector *metav1.LabelSelector `json:"podLogsNamespaceSelector,omitempty"`
+	// AdditionalScrapeConfigs allows specifying a key of a Secret containing
+	// additional Grafana Agent logging scrape configurations. Scrape
+	// configurations specified are appended to the configurations generated by
+	// the Grafana Agent Operator. Job configurations specified must have the
+	// form as specified in the official Promtail documentation:
+	// https://grafana.com/docs/loki/latest/clients/promtail/configuration/#scrape_configs.
+	// As scrape configs are appended, the user is responsible to make sure it
+	// is valid. Note that using this feature may expose the possibility to
+	// break upgrades of Grafana Agent. It is advised to review both Grafana
+	// Agent and Promtail release notes to ensure that no incompatible scrape
+	// configs are going to break Grafana Agent after the upgrade.
+	AdditionalScrapeConfigs *v1.SecretKeySelector `json:"additionalScrapeConfigs,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LogsInstanceList is a list of LogsInstance.
+type LogsInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// Items is the list of LogsInstance.
+	Items []*LogsInstance `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="podlogs"
+// +kubebuilder:resource:singular="podlogs"
+// +kubebuilder:resource:categories="agent-operator"
+
+// PodLogs defines how to collect logs for a pod.
+type PodLogs struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec holds the specification of the desired behavior for the PodLogs.
+	Spec PodLogsSpec `json:"spec,omitempty"`
+}
+
+// PodLogsSpec defines how to collect logs for a pod.
+type PodLogsSpec struct {
+	// The label to use to retrieve the job name from.
+	JobLabel string `json:"jobLabel,omitempty"`
+	// PodTargetLabels transfers labels on the Kubernetes Pod onto the target.
+	PodTargetLabels []string `json:"podTargetLabels,omitempty"`
+	// Selector to select Pod objects. Required.
+	Selector metav1.LabelSelector `json:"selector"`
+	// Selector to select which namespaces the Pod objects are discovered from.
+	NamespaceSelector prom_v1.NamespaceSelector `json:"namespaceSelector,omitempty"`
+	// Pipeline stages for this pod. Pipeline stages support transforming and
+	// filtering log lines.
+	PipelineStages []*PipelineStageSpec `json:"pipelineStages,omitempty"`
+	// RelabelConfigs to apply to logs before delivering.
+	RelabelConfigs []*prom_v1.RelabelConfig `json:"relabelings,omitempty"`
+}
+
+// PipelineStageSpec defines an individual pipeline stage. Each stage type is
+// mutually exclusive and no more than one may be set per stage.
+type PipelineStageSpec struct {
+	// CRI is a parsing stage that reads log lines using the standard CRI
+	// logging format. Supply cri: {} to enable.
+	CRI *CRIStageSpec `json:"cri,omitempty"`
+	// Docker is a parsing stage that reads log lines using the standard Docker
+	// logging format. Supply docker: {} to enable.
+	Docker *DockerStageSpec `json:"docker,omitempty"`
+	// JSON is a parsing stage that reads the log line as JSON and accepts
+	// JMESPath expressions to extract data.
+	JSON *JSONStageSpec `json:"json,omitempty"`
+	// Labels is an action stage that takes data from the extracted map and
+	// modifies the label set that is sent to Loki with the log entry. The key
+	// is REQUIRED and represents the name for the label that will be created.
+	// The value is OPTIONAL and will be the name from extracted data to use for
+	// the value of the label. If the value is not provided, it defaults to
+	// match the key.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Output stage is an action stage that takes data from the extracted map
+	// and changes the log line that will be sent to Loki.
+	Output *OutputStageSpec `json:"output,omitempty"`
+	// Regex is a parsing stage that parses a log line using a regular
+	// expression. Named capture groups in the regex allows for adding data into
+	// the extracted map.
+	Regex *RegexStageSpec `json:"regex,omitempty"`
+	// Timestamp is an action stage that can change the timestamp of a log line
+	// before it is sent to Loki. If not present, the timestamp of a log line
+	// defaults to the time when the log line was read.
+	Timestamp *TimestampStageSpec `json:"timestamp,omitempty"`
+}
+
+// CRIStageSpec is a parsing stage that reads log lines using the standard CRI
+// logging format. It needs no defined fields.
+type CRIStageSpec struct{}
+
+// DockerStageSpec is a parsing stage that reads log lines using the standard
+// Docker logging format. It needs no defined fields.
+type DockerStageSpec struct{}
+
+// JSONStageSpec is a parsing stage that reads the log line as JSON and accepts
+// JMESPath expressions to extract data.
+type JSONStageSpec struct {
+	// Name from the extracted data to parse as JSON. If empty, uses entire log
+	// message.
+	Source string `json:"source,omitempty"`
+	// Set of the key/value pairs of JMESPath expressions. The key will be the
+	// key in the extracted data while the expression will be the value,
+	// evaluated as a JMESPath from the source data.
+	Expressions map[string]string `json:"expressions,omitempty"`
+}
+
+// OutputStageSpec is an action stage that takes data from the extracted map
+// and changes the log line that will be sent to Loki.
+type OutputStageSpec struct {
+	// Name from extract data to use for the log entry. Required.
+	Source string `json:"source"`
+}
+
+// RegexStageSpec is a parsing stage that parses a log line using a regular
+// expression. Named capture groups in the regex allows for adding data into
+// the extracted map.
+type RegexStageSpec struct {
+	// Name from extracted data to parse. If empty, defaults to using the log
+	// message.
+	Source string `json:"source,omitempty"`
+	// RE2 regular expression. Each capture group MUST be named. Required.
+	Expression string `json:"expression"`
+}
+
+// TimestampStageSpec is an action stage that can change the timestamp of a log
+// line before it is sent to Loki.
+type TimestampStageSpec struct {
+	// Name from extracted data to use for the timestamp. Required.
+	Source string `json:"source"`
+	// Determines format of the time string. Required. Can be one of:
+	// ANSIC, UnixDate, RubyDate, RFC822, RFC822Z, RFC850, RFC1123, RFC1123Z,
+	// RFC3339, RFC3339Nano, Unix, UnixMs, UnixUs, UnixNs.
+	Format string `json:"format"`
+	// Fallback formats to try if format fails.
+	FallbackFormats []string `json:"fallbackFormats,omitempty"`
+	// IANA Timezone Database string.
+	Location string `json:"location,omitempty"`
+	// Action to take when the timestamp can't be extracted or parsed.
+	// Can be skip or fudge. Defaults to fudge.
+	ActionOnFailure string `json:"actionOnFailure,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PodLogsList is a list of PodLogs.
+type PodLogsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// Items is the list of PodLogs.
+	Items []*PodLogs `json:"items"`
+}
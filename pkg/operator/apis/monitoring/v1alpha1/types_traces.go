@@ -0,0 +1,183 @@
+package v1alpha1
+
+import (
+	prom_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TracesSubsystemSpec defines global settings to apply across the traces
+// subsystem.
+type TracesSubsystemSpec struct {
+	// RemoteWrite controls default remote_write settings for all instances. If
+	// an instance does not provide its own remoteWrite settings, these will be
+	// used instead.
+	RemoteWrite []TracesRemoteWriteSpec `json:"remoteWrite,omitempty"`
+	// InstanceSelector determines which TracesInstances should be selected for
+	// running. Each instance runs its own trace pipeline, including receivers,
+	// processors, and remote_write.
+	InstanceSelector *metav1.LabelSelector `json:"instanceSelector,omitempty"`
+	// InstanceNamespaceSelector are the set of labels to determine which
+	// namespaces to watch for TracesInstances. If not provided, only checks own
+	// namespace.
+	InstanceNamespaceSelector *metav1.LabelSelector `json:"instanceNamespaceSelector,omitempty"`
+}
+
+// TracesRemoteWriteSpec defines an endpoint to send traces to.
+type TracesRemoteWriteSpec struct {
+	// Endpoint is the host:port to send traces to. Required.
+	Endpoint string `json:"endpoint"`
+	// Compression controls whether compression is enabled. Can be "none" or
+	// "gzip". Defaults to gzip.
+	Compression string `json:"compression,omitempty"`
+	// Protocol controls what protocol to use when exporting traces. Can be
+	// "grpc" or "http". Defaults to grpc.
+	Protocol string `json:"protocol,omitempty"`
+	// Insecure disables TLS for the connection to the endpoint.
+	Insecure bool `json:"insecure,omitempty"`
+	// Headers is a set of custom HTTP headers to be sent along with each
+	// request.
+	Headers map[string]string `json:"headers,omitempty"`
+	// BasicAuth for the endpoint.
+	BasicAuth *prom_v1.BasicAuth `json:"basicAuth,omitempty"`
+	// TLSConfig to use for the endpoint. Only used when insecure is false.
+	TLSConfig *prom_v1.TLSConfig `json:"tlsConfig,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="traces-instances"
+// +kubebuilder:resource:singular="traces-instance"
+// +kubebuilder:resource:categories="agent-operator"
+
+// TracesInstance controls an individual traces instance within a Grafana
+// Agent deployment.
+type TracesInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec holds the specification of the desired behavior for the traces
+	// instance.
+	Spec TracesInstanceSpec `json:"spec,omitempty"`
+}
+
+// TracesInstanceSpec controls the trace pipeline of an individual instance.
+type TracesInstanceSpec struct {
+	// Receivers controls which protocols spans are received with.
+	Receivers TracesReceiversSpec `json:"receivers,omitempty"`
+	// RemoteWrite controls where spans are sent to for this instance.
+	RemoteWrite []TracesRemoteWriteSpec `json:"remoteWrite,omitempty"`
+	// TailSampling configures tail-based sampling of traces. Note that all
+	// spans of a trace must be received by the same pod for sampling decisions
+	// to be correct.
+	TailSampling *TailSamplingSpec `json:"tailSampling,omitempty"`
+}
+
+// TracesReceiversSpec configures the receivers of a traces instance. At least
+// one receiver must be enabled.
+type TracesReceiversSpec struct {
+	// OTLP enables receiving spans using the OpenTelemetry protocol.
+	OTLP *OTLPReceiverSpec `json:"otlp,omitempty"`
+	// Jaeger enables receiving spans using the Jaeger protocols.
+	Jaeger *JaegerReceiverSpec `json:"jaeger,omitempty"`
+	// Zipkin enables receiving spans using the Zipkin protocol.
+	Zipkin *TracesReceiverEndpointSpec `json:"zipkin,omitempty"`
+	// OpenCensus enables receiving spans using the OpenCensus protocol.
+	OpenCensus *TracesReceiverEndpointSpec `json:"opencensus,omitempty"`
+}
+
+// OTLPReceiverSpec configures the protocols of the OTLP receiver.
+type OTLPReceiverSpec struct {
+	// GRPC enables receiving spans over gRPC. Listens on 0.0.0.0:4317 by
+	// default.
+	GRPC *TracesReceiverEndpointSpec `json:"grpc,omitempty"`
+	// HTTP enables receiving spans over HTTP. Listens on 0.0.0.0:55681 by
+	// default.
+	HTTP *TracesReceiverEndpointSpec `json:"http,omitempty"`
+}
+
+// JaegerReceiverSpec configures the protocols of the Jaeger receiver.
+type JaegerReceiverSpec struct {
+	// GRPC enables receiving spans over gRPC. Listens on 0.0.0.0:14250 by
+	// default.
+	GRPC *TracesReceiverEndpointSpec `json:"grpc,omitempty"`
+	// ThriftHTTP enables receiving spans with Thrift over HTTP. Listens on
+	// 0.0.0.0:14268 by default.
+	ThriftHTTP *TracesReceiverEndpointSpec `json:"thriftHttp,omitempty"`
+	// ThriftCompact enables receiving spans with compact Thrift over UDP.
+	// Listens on 0.0.0.0:6831 by default.
+	ThriftCompact *TracesReceiverEndpointSpec `json:"thriftCompact,omitempty"`
+	// ThriftBinary enables receiving spans with binary Thrift over UDP.
+	// Listens on 0.0.0.0:6832 by default.
+	ThriftBinary *TracesReceiverEndpointSpec `json:"thriftBinary,omitempty"`
+}
+
+// TracesReceiverEndpointSpec configures a receiver protocol.
+type TracesReceiverEndpointSpec struct {
+	// Endpoint is the host:port to listen on. Uses the default of the protocol
+	// if empty.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// TailSamplingSpec configures tail-based sampling of traces.
+type TailSamplingSpec struct {
+	// Policies define the rules by which traces will be sampled. A trace is
+	// sampled if any of the policies sample it.
+	Policies []TailSamplingPolicySpec `json:"policies,omitempty"`
+	// DecisionWait is the time that will be waited before making a decision
+	// for a trace. Defaults to 5s.
+	DecisionWait string `json:"decisionWait,omitempty"`
+}
+
+// TailSamplingPolicySpec defines a single sampling policy. Each policy type
+// is mutually exclusive and no more than one may be set per policy.
+type TailSamplingPolicySpec struct {
+	// AlwaysSample samples all traces. Supply alwaysSample: {} to enable.
+	AlwaysSample *AlwaysSamplePolicySpec `json:"alwaysSample,omitempty"`
+	// StringAttribute samples traces with an attribute matching one of the
+	// given values.
+	StringAttribute *StringAttributePolicySpec `json:"stringAttribute,omitempty"`
+	// NumericAttribute samples traces with a numeric attribute within the
+	// given range.
+	NumericAttribute *NumericAttributePolicySpec `json:"numericAttribute,omitempty"`
+	// RateLimiting samples traces up to a rate of spans per second.
+	RateLimiting *RateLimitingPolicySpec `json:"rateLimiting,omitempty"`
+}
+
+// AlwaysSamplePolicySpec samples all traces. It needs no defined fields.
+type AlwaysSamplePolicySpec struct{}
+
+// StringAttributePolicySpec samples traces by the value of a string
+// attribute.
+type StringAttributePolicySpec struct {
+	// Key of the attribute. Required.
+	Key string `json:"key"`
+	// Values to match against. Required.
+	Values []string `json:"values"`
+}
+
+// NumericAttributePolicySpec samples traces by the value of a numeric
+// attribute.
+type NumericAttributePolicySpec struct {
+	// Key of the attribute. Required.
+	Key string `json:"key"`
+	// MinValue is the minimum value of the attribute, inclusive.
+	MinValue int64 `json:"minValue"`
+	// MaxValue is the maximum value of the attribute, inclusive.
+	MaxValue int64 `json:"maxValue"`
+}
+
+// RateLimitingPolicySpec samples traces up to a rate of spans per second.
+type RateLimitingPolicySpec struct {
+	// SpansPerSecond is the maximum number of spans to sample per second.
+	// Required.
+	SpansPerSecond int64 `json:"spansPerSecond"`
+}
+
+// +kubebuilder:object:root=true
+
+// TracesInstanceList is a list of TracesInstance.
+type TracesInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// Items is the list of TracesInstance.
+	Items []*TracesInstance `json:"items"`
+}
@@ -11,6 +11,51 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlwaysSamplePolicySpec) DeepCopyInto(out *AlwaysSamplePolicySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlwaysSamplePolicySpec.
+func (in *AlwaysSamplePolicySpec) DeepCopy() *AlwaysSamplePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AlwaysSamplePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CRIStageSpec) DeepCopyInto(out *CRIStageSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CRIStageSpec.
+func (in *CRIStageSpec) DeepCopy() *CRIStageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CRIStageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerStageSpec) DeepCopyInto(out *DockerStageSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerStageSpec.
+func (in *DockerStageSpec) DeepCopy() *DockerStageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DockerStageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GrafanaAgent) DeepCopyInto(out *GrafanaAgent) {
 	*out = *in
@@ -172,6 +217,8 @@ func (in *GrafanaAgentSpec) DeepCopyInto(out *GrafanaAgentSpec) {
 		}
 	}
 	in.Prometheus.DeepCopyInto(&out.Prometheus)
+	in.Logs.DeepCopyInto(&out.Logs)
+	in.Traces.DeepCopyInto(&out.Traces)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaAgentSpec.
@@ -185,40 +232,134 @@ func (in *GrafanaAgentSpec) DeepCopy() *GrafanaAgentSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MetadataConfig) DeepCopyInto(out *MetadataConfig) {
+func (in *JSONStageSpec) DeepCopyInto(out *JSONStageSpec) {
 	*out = *in
+	if in.Expressions != nil {
+		in, out := &in.Expressions, &out.Expressions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataConfig.
-func (in *MetadataConfig) DeepCopy() *MetadataConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JSONStageSpec.
+func (in *JSONStageSpec) DeepCopy() *JSONStageSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(MetadataConfig)
+	out := new(JSONStageSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PrometheusInstance) DeepCopyInto(out *PrometheusInstance) {
+func (in *JaegerReceiverSpec) DeepCopyInto(out *JaegerReceiverSpec) {
+	*out = *in
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = new(TracesReceiverEndpointSpec)
+		**out = **in
+	}
+	if in.ThriftHTTP != nil {
+		in, out := &in.ThriftHTTP, &out.ThriftHTTP
+		*out = new(TracesReceiverEndpointSpec)
+		**out = **in
+	}
+	if in.ThriftCompact != nil {
+		in, out := &in.ThriftCompact, &out.ThriftCompact
+		*out = new(TracesReceiverEndpointSpec)
+		**out = **in
+	}
+	if in.ThriftBinary != nil {
+		in, out := &in.ThriftBinary, &out.ThriftBinary
+		*out = new(TracesReceiverEndpointSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JaegerReceiverSpec.
+func (in *JaegerReceiverSpec) DeepCopy() *JaegerReceiverSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JaegerReceiverSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogsBackoffConfigSpec) DeepCopyInto(out *LogsBackoffConfigSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsBackoffConfigSpec.
+func (in *LogsBackoffConfigSpec) DeepCopy() *LogsBackoffConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LogsBackoffConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogsClientSpec) DeepCopyInto(out *LogsClientSpec) {
+	*out = *in
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(v1.BasicAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(v1.TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BackoffConfig != nil {
+		in, out := &in.BackoffConfig, &out.BackoffConfig
+		*out = new(LogsBackoffConfigSpec)
+		**out = **in
+	}
+	if in.ExternalLabels != nil {
+		in, out := &in.ExternalLabels, &out.ExternalLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsClientSpec.
+func (in *LogsClientSpec) DeepCopy() *LogsClientSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LogsClientSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogsInstance) DeepCopyInto(out *LogsInstance) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusInstance.
-func (in *PrometheusInstance) DeepCopy() *PrometheusInstance {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsInstance.
+func (in *LogsInstance) DeepCopy() *LogsInstance {
 	if in == nil {
 		return nil
 	}
-	out := new(PrometheusInstance)
+	out := new(LogsInstance)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PrometheusInstance) DeepCopyObject() runtime.Object {
+func (in *LogsInstance) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -226,35 +367,35 @@ func (in *PrometheusInstance) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PrometheusInstanceList) DeepCopyInto(out *PrometheusInstanceList) {
+func (in *LogsInstanceList) DeepCopyInto(out *LogsInstanceList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]*PrometheusInstance, len(*in))
+		*out = make([]*LogsInstance, len(*in))
 		for i := range *in {
 			if (*in)[i] != nil {
 				in, out := &(*in)[i], &(*out)[i]
-				*out = new(PrometheusInstance)
+				*out = new(LogsInstance)
 				(*in).DeepCopyInto(*out)
 			}
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusInstanceList.
-func (in *PrometheusInstanceList) DeepCopy() *PrometheusInstanceList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsInstanceList.
+func (in *LogsInstanceList) DeepCopy() *LogsInstanceList {
 	if in == nil {
 		return nil
 	}
-	out := new(PrometheusInstanceList)
+	out := new(LogsInstanceList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PrometheusInstanceList) DeepCopyObject() runtime.Object {
+func (in *LogsInstanceList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -262,50 +403,25 @@ func (in *PrometheusInstanceList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PrometheusInstanceSpec) DeepCopyInto(out *PrometheusInstanceSpec) {
+func (in *LogsInstanceSpec) DeepCopyInto(out *LogsInstanceSpec) {
 	*out = *in
-	if in.WriteStaleOnShutdown != nil {
-		in, out := &in.WriteStaleOnShutdown, &out.WriteStaleOnShutdown
-		*out = new(bool)
-		**out = **in
-	}
-	if in.ServiceMonitorSelector != nil {
-		in, out := &in.ServiceMonitorSelector, &out.ServiceMonitorSelector
-		*out = new(metav1.LabelSelector)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.ServiceMonitorNamespaceSelector != nil {
-		in, out := &in.ServiceMonitorNamespaceSelector, &out.ServiceMonitorNamespaceSelector
-		*out = new(metav1.LabelSelector)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.PodMonitorSelector != nil {
-		in, out := &in.PodMonitorSelector, &out.PodMonitorSelector
-		*out = new(metav1.LabelSelector)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.PodMonitorNamespaceSelector != nil {
-		in, out := &in.PodMonitorNamespaceSelector, &out.PodMonitorNamespaceSelector
-		*out = new(metav1.LabelSelector)
-		(*in).DeepCopyInto(*out)
+	if in.Clients != nil {
+		in, out := &in.Clients, &out.Clients
+		*out = make([]LogsClientSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	if in.ProbeSelector != nil {
-		in, out := &in.ProbeSelector, &out.ProbeSelector
+	if in.PodLogsSelector != nil {
+		in, out := &in.PodLogsSelector, &out.PodLogsSelector
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.ProbeNamespaceSelector != nil {
-		in, out := &in.ProbeNamespaceSelector, &out.ProbeNamespaceSelector
+	if in.PodLogsNamespaceSelector != nil {
+		in, out := &in.PodLogsNamespaceSelector, &out.PodLogsNamespaceSelector
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.RemoteWrite != nil {
-		in, out := &in.RemoteWrite, &out.RemoteWrite
-		*out = make([]RemoteWriteSpec, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 	if in.AdditionalScrapeConfigs != nil {
 		in, out := &in.AdditionalScrapeConfigs, &out.AdditionalScrapeConfigs
 		*out = new(corev1.SecretKeySelector)
@@ -313,64 +429,31 @@ func (in *PrometheusInstanceSpec) DeepCopyInto(out *PrometheusInstanceSpec) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusInstanceSpec.
-func (in *PrometheusInstanceSpec) DeepCopy() *PrometheusInstanceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsInstanceSpec.
+func (in *LogsInstanceSpec) DeepCopy() *LogsInstanceSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PrometheusInstanceSpec)
+	out := new(LogsInstanceSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PrometheusSubsystemSpec) DeepCopyInto(out *PrometheusSubsystemSpec) {
+func (in *LogsSubsystemSpec) DeepCopyInto(out *LogsSubsystemSpec) {
 	*out = *in
-	if in.RemoteWrite != nil {
-		in, out := &in.RemoteWrite, &out.RemoteWrite
-		*out = make([]RemoteWriteSpec, len(*in))
+	if in.Clients != nil {
+		in, out := &in.Clients, &out.Clients
+		*out = make([]LogsClientSpec, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.Replicas != nil {
-		in, out := &in.Replicas, &out.Replicas
-		*out = new(int32)
-		**out = **in
-	}
-	if in.Shards != nil {
-		in, out := &in.Shards, &out.Shards
-		*out = new(int32)
-		**out = **in
-	}
-	if in.ReplicaExternalLabelName != nil {
-		in, out := &in.ReplicaExternalLabelName, &out.ReplicaExternalLabelName
-		*out = new(string)
-		**out = **in
-	}
-	if in.PrometheusExternalLabelName != nil {
-		in, out := &in.PrometheusExternalLabelName, &out.PrometheusExternalLabelName
+	if in.LogsExternalLabelName != nil {
+		in, out := &in.LogsExternalLabelName, &out.LogsExternalLabelName
 		*out = new(string)
 		**out = **in
 	}
-	if in.ExternalLabels != nil {
-		in, out := &in.ExternalLabels, &out.ExternalLabels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	out.ArbitraryFSAccessThroughSMs = in.ArbitraryFSAccessThroughSMs
-	if in.EnforcedSampleLimit != nil {
-		in, out := &in.EnforcedSampleLimit, &out.EnforcedSampleLimit
-		*out = new(uint64)
-		**out = **in
-	}
-	if in.EnforcedTargetLimit != nil {
-		in, out := &in.EnforcedTargetLimit, &out.EnforcedTargetLimit
-		*out = new(uint64)
-		**out = **in
-	}
 	if in.InstanceSelector != nil {
 		in, out := &in.InstanceSelector, &out.InstanceSelector
 		*out = new(metav1.LabelSelector)
@@ -383,89 +466,541 @@ func (in *PrometheusSubsystemSpec) DeepCopyInto(out *PrometheusSubsystemSpec) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSubsystemSpec.
-func (in *PrometheusSubsystemSpec) DeepCopy() *PrometheusSubsystemSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsSubsystemSpec.
+func (in *LogsSubsystemSpec) DeepCopy() *LogsSubsystemSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PrometheusSubsystemSpec)
+	out := new(LogsSubsystemSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *QueueConfig) DeepCopyInto(out *QueueConfig) {
+func (in *MetadataConfig) DeepCopyInto(out *MetadataConfig) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueueConfig.
-func (in *QueueConfig) DeepCopy() *QueueConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataConfig.
+func (in *MetadataConfig) DeepCopy() *MetadataConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(QueueConfig)
+	out := new(MetadataConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RemoteWriteSpec) DeepCopyInto(out *RemoteWriteSpec) {
+func (in *NumericAttributePolicySpec) DeepCopyInto(out *NumericAttributePolicySpec) {
 	*out = *in
-	if in.Headers != nil {
-		in, out := &in.Headers, &out.Headers
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.WriteRelabelConfigs != nil {
-		in, out := &in.WriteRelabelConfigs, &out.WriteRelabelConfigs
-		*out = make([]v1.RelabelConfig, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.BasicAuth != nil {
-		in, out := &in.BasicAuth, &out.BasicAuth
-		*out = new(v1.BasicAuth)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.SigV4 != nil {
-		in, out := &in.SigV4, &out.SigV4
-		*out = new(SigV4Config)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.TLSConfig != nil {
-		in, out := &in.TLSConfig, &out.TLSConfig
-		*out = new(v1.TLSConfig)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NumericAttributePolicySpec.
+func (in *NumericAttributePolicySpec) DeepCopy() *NumericAttributePolicySpec {
+	if in == nil {
+		return nil
 	}
-	if in.QueueConfig != nil {
-		in, out := &in.QueueConfig, &out.QueueConfig
-		*out = new(QueueConfig)
+	out := new(NumericAttributePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OTLPReceiverSpec) DeepCopyInto(out *OTLPReceiverSpec) {
+	*out = *in
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = new(TracesReceiverEndpointSpec)
 		**out = **in
 	}
-	if in.MetadataConfig != nil {
-		in, out := &in.MetadataConfig, &out.MetadataConfig
-		*out = new(MetadataConfig)
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(TracesReceiverEndpointSpec)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteWriteSpec.
-func (in *RemoteWriteSpec) DeepCopy() *RemoteWriteSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OTLPReceiverSpec.
+func (in *OTLPReceiverSpec) DeepCopy() *OTLPReceiverSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RemoteWriteSpec)
+	out := new(OTLPReceiverSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SigV4Config) DeepCopyInto(out *SigV4Config) {
+func (in *OutputStageSpec) DeepCopyInto(out *OutputStageSpec) {
 	*out = *in
-	if in.AccessKey != nil {
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputStageSpec.
+func (in *OutputStageSpec) DeepCopy() *OutputStageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputStageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineStageSpec) DeepCopyInto(out *PipelineStageSpec) {
+	*out = *in
+	if in.CRI != nil {
+		in, out := &in.CRI, &out.CRI
+		*out = new(CRIStageSpec)
+		**out = **in
+	}
+	if in.Docker != nil {
+		in, out := &in.Docker, &out.Docker
+		*out = new(DockerStageSpec)
+		**out = **in
+	}
+	if in.JSON != nil {
+		in, out := &in.JSON, &out.JSON
+		*out = new(JSONStageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(OutputStageSpec)
+		**out = **in
+	}
+	if in.Regex != nil {
+		in, out := &in.Regex, &out.Regex
+		*out = new(RegexStageSpec)
+		**out = **in
+	}
+	if in.Timestamp != nil {
+		in, out := &in.Timestamp, &out.Timestamp
+		*out = new(TimestampStageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineStageSpec.
+func (in *PipelineStageSpec) DeepCopy() *PipelineStageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineStageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodLogs) DeepCopyInto(out *PodLogs) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodLogs.
+func (in *PodLogs) DeepCopy() *PodLogs {
+	if in == nil {
+		return nil
+	}
+	out := new(PodLogs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PodLogs) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodLogsList) DeepCopyInto(out *PodLogsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]*PodLogs, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(PodLogs)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodLogsList.
+func (in *PodLogsList) DeepCopy() *PodLogsList {
+	if in == nil {
+		return nil
+	}
+	out := new(PodLogsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PodLogsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodLogsSpec) DeepCopyInto(out *PodLogsSpec) {
+	*out = *in
+	if in.PodTargetLabels != nil {
+		in, out := &in.PodTargetLabels, &out.PodTargetLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Selector.DeepCopyInto(&out.Selector)
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	if in.PipelineStages != nil {
+		in, out := &in.PipelineStages, &out.PipelineStages
+		*out = make([]*PipelineStageSpec, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(PipelineStageSpec)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	if in.RelabelConfigs != nil {
+		in, out := &in.RelabelConfigs, &out.RelabelConfigs
+		*out = make([]*v1.RelabelConfig, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(v1.RelabelConfig)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodLogsSpec.
+func (in *PodLogsSpec) DeepCopy() *PodLogsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodLogsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusInstance) DeepCopyInto(out *PrometheusInstance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusInstance.
+func (in *PrometheusInstance) DeepCopy() *PrometheusInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PrometheusInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusInstanceList) DeepCopyInto(out *PrometheusInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]*PrometheusInstance, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(PrometheusInstance)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusInstanceList.
+func (in *PrometheusInstanceList) DeepCopy() *PrometheusInstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusInstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PrometheusInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusInstanceSpec) DeepCopyInto(out *PrometheusInstanceSpec) {
+	*out = *in
+	if in.WriteStaleOnShutdown != nil {
+		in, out := &in.WriteStaleOnShutdown, &out.WriteStaleOnShutdown
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ServiceMonitorSelector != nil {
+		in, out := &in.ServiceMonitorSelector, &out.ServiceMonitorSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceMonitorNamespaceSelector != nil {
+		in, out := &in.ServiceMonitorNamespaceSelector, &out.ServiceMonitorNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodMonitorSelector != nil {
+		in, out := &in.PodMonitorSelector, &out.PodMonitorSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodMonitorNamespaceSelector != nil {
+		in, out := &in.PodMonitorNamespaceSelector, &out.PodMonitorNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProbeSelector != nil {
+		in, out := &in.ProbeSelector, &out.ProbeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProbeNamespaceSelector != nil {
+		in, out := &in.ProbeNamespaceSelector, &out.ProbeNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RemoteWrite != nil {
+		in, out := &in.RemoteWrite, &out.RemoteWrite
+		*out = make([]RemoteWriteSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdditionalScrapeConfigs != nil {
+		in, out := &in.AdditionalScrapeConfigs, &out.AdditionalScrapeConfigs
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusInstanceSpec.
+func (in *PrometheusInstanceSpec) DeepCopy() *PrometheusInstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusInstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSubsystemSpec) DeepCopyInto(out *PrometheusSubsystemSpec) {
+	*out = *in
+	if in.RemoteWrite != nil {
+		in, out := &in.RemoteWrite, &out.RemoteWrite
+		*out = make([]RemoteWriteSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Shards != nil {
+		in, out := &in.Shards, &out.Shards
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReplicaExternalLabelName != nil {
+		in, out := &in.ReplicaExternalLabelName, &out.ReplicaExternalLabelName
+		*out = new(string)
+		**out = **in
+	}
+	if in.PrometheusExternalLabelName != nil {
+		in, out := &in.PrometheusExternalLabelName, &out.PrometheusExternalLabelName
+		*out = new(string)
+		**out = **in
+	}
+	if in.ExternalLabels != nil {
+		in, out := &in.ExternalLabels, &out.ExternalLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.ArbitraryFSAccessThroughSMs = in.ArbitraryFSAccessThroughSMs
+	if in.EnforcedSampleLimit != nil {
+		in, out := &in.EnforcedSampleLimit, &out.EnforcedSampleLimit
+		*out = new(uint64)
+		**out = **in
+	}
+	if in.EnforcedTargetLimit != nil {
+		in, out := &in.EnforcedTargetLimit, &out.EnforcedTargetLimit
+		*out = new(uint64)
+		**out = **in
+	}
+	if in.InstanceSelector != nil {
+		in, out := &in.InstanceSelector, &out.InstanceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InstanceNamespaceSelector != nil {
+		in, out := &in.InstanceNamespaceSelector, &out.InstanceNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSubsystemSpec.
+func (in *PrometheusSubsystemSpec) DeepCopy() *PrometheusSubsystemSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSubsystemSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueueConfig) DeepCopyInto(out *QueueConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueueConfig.
+func (in *QueueConfig) DeepCopy() *QueueConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(QueueConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitingPolicySpec) DeepCopyInto(out *RateLimitingPolicySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitingPolicySpec.
+func (in *RateLimitingPolicySpec) DeepCopy() *RateLimitingPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitingPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegexStageSpec) DeepCopyInto(out *RegexStageSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegexStageSpec.
+func (in *RegexStageSpec) DeepCopy() *RegexStageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RegexStageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteWriteSpec) DeepCopyInto(out *RemoteWriteSpec) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.WriteRelabelConfigs != nil {
+		in, out := &in.WriteRelabelConfigs, &out.WriteRelabelConfigs
+		*out = make([]v1.RelabelConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(v1.BasicAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SigV4 != nil {
+		in, out := &in.SigV4, &out.SigV4
+		*out = new(SigV4Config)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(v1.TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.QueueConfig != nil {
+		in, out := &in.QueueConfig, &out.QueueConfig
+		*out = new(QueueConfig)
+		**out = **in
+	}
+	if in.MetadataConfig != nil {
+		in, out := &in.MetadataConfig, &out.MetadataConfig
+		*out = new(MetadataConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteWriteSpec.
+func (in *RemoteWriteSpec) DeepCopy() *RemoteWriteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteWriteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SigV4Config) DeepCopyInto(out *SigV4Config) {
+	*out = *in
+	if in.AccessKey != nil {
 		in, out := &in.AccessKey, &out.AccessKey
 		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
@@ -486,3 +1021,304 @@ func (in *SigV4Config) DeepCopy() *SigV4Config {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StringAttributePolicySpec) DeepCopyInto(out *StringAttributePolicySpec) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StringAttributePolicySpec.
+func (in *StringAttributePolicySpec) DeepCopy() *StringAttributePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StringAttributePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TailSamplingPolicySpec) DeepCopyInto(out *TailSamplingPolicySpec) {
+	*out = *in
+	if in.AlwaysSample != nil {
+		in, out := &in.AlwaysSample, &out.AlwaysSample
+		*out = new(AlwaysSamplePolicySpec)
+		**out = **in
+	}
+	if in.StringAttribute != nil {
+		in, out := &in.StringAttribute, &out.StringAttribute
+		*out = new(StringAttributePolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NumericAttribute != nil {
+		in, out := &in.NumericAttribute, &out.NumericAttribute
+		*out = new(NumericAttributePolicySpec)
+		**out = **in
+	}
+	if in.RateLimiting != nil {
+		in, out := &in.RateLimiting, &out.RateLimiting
+		*out = new(RateLimitingPolicySpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TailSamplingPolicySpec.
+func (in *TailSamplingPolicySpec) DeepCopy() *TailSamplingPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TailSamplingPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TailSamplingSpec) DeepCopyInto(out *TailSamplingSpec) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]TailSamplingPolicySpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TailSamplingSpec.
+func (in *TailSamplingSpec) DeepCopy() *TailSamplingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TailSamplingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimestampStageSpec) DeepCopyInto(out *TimestampStageSpec) {
+	*out = *in
+	if in.FallbackFormats != nil {
+		in, out := &in.FallbackFormats, &out.FallbackFormats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimestampStageSpec.
+func (in *TimestampStageSpec) DeepCopy() *TimestampStageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TimestampStageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracesInstance) DeepCopyInto(out *TracesInstance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracesInstance.
+func (in *TracesInstance) DeepCopy() *TracesInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(TracesInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TracesInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracesInstanceList) DeepCopyInto(out *TracesInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]*TracesInstance, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(TracesInstance)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracesInstanceList.
+func (in *TracesInstanceList) DeepCopy() *TracesInstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(TracesInstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TracesInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracesInstanceSpec) DeepCopyInto(out *TracesInstanceSpec) {
+	*out = *in
+	in.Receivers.DeepCopyInto(&out.Receivers)
+	if in.RemoteWrite != nil {
+		in, out := &in.RemoteWrite, &out.RemoteWrite
+		*out = make([]TracesRemoteWriteSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TailSampling != nil {
+		in, out := &in.TailSampling, &out.TailSampling
+		*out = new(TailSamplingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracesInstanceSpec.
+func (in *TracesInstanceSpec) DeepCopy() *TracesInstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TracesInstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracesReceiverEndpointSpec) DeepCopyInto(out *TracesReceiverEndpointSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracesReceiverEndpointSpec.
+func (in *TracesReceiverEndpointSpec) DeepCopy() *TracesReceiverEndpointSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TracesReceiverEndpointSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracesReceiversSpec) DeepCopyInto(out *TracesReceiversSpec) {
+	*out = *in
+	if in.OTLP != nil {
+		in, out := &in.OTLP, &out.OTLP
+		*out = new(OTLPReceiverSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Jaeger != nil {
+		in, out := &in.Jaeger, &out.Jaeger
+		*out = new(JaegerReceiverSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Zipkin != nil {
+		in, out := &in.Zipkin, &out.Zipkin
+		*out = new(TracesReceiverEndpointSpec)
+		**out = **in
+	}
+	if in.OpenCensus != nil {
+		in, out := &in.OpenCensus, &out.OpenCensus
+		*out = new(TracesReceiverEndpointSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracesReceiversSpec.
+func (in *TracesReceiversSpec) DeepCopy() *TracesReceiversSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TracesReceiversSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracesRemoteWriteSpec) DeepCopyInto(out *TracesRemoteWriteSpec) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(v1.BasicAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(v1.TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracesRemoteWriteSpec.
+func (in *TracesRemoteWriteSpec) DeepCopy() *TracesRemoteWriteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TracesRemoteWriteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracesSubsystemSpec) DeepCopyInto(out *TracesSubsystemSpec) {
+	*out = *in
+	if in.RemoteWrite != nil {
+		in, out := &in.RemoteWrite, &out.RemoteWrite
+		*out = make([]TracesRemoteWriteSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InstanceSelector != nil {
+		in, out := &in.InstanceSelector, &out.InstanceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InstanceNamespaceSelector != nil {
+		in, out := &in.InstanceNamespaceSelector, &out.InstanceNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracesSubsystemSpec.
+func (in *TracesSubsystemSpec) DeepCopy() *TracesSubsystemSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TracesSubsystemSpec)
+	in.DeepCopyInto(out)
+	return out
+}
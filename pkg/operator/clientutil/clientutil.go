@@ -124,6 +124,43 @@ func CreateOrUpdateStatefulSet(ctx context.Context, c client.Client, ss *apps_v1
 	return nil
 }
 
+// CreateOrUpdateDaemonSet applies the given DaemonSet against the client.
+func CreateOrUpdateDaemonSet(ctx context.Context, c client.Client, ds *apps_v1.DaemonSet) error {
+	var exist apps_v1.DaemonSet
+	err := c.Get(ctx, client.ObjectKeyFromObject(ds), &exist)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("failed to retrieve existing daemonset: %w", err)
+	}
+
+	if k8s_errors.IsNotFound(err) {
+		err := c.Create(ctx, ds)
+		if err != nil {
+			return fmt.Errorf("failed to create daemonset: %w", err)
+		}
+	} else {
+		ds.ResourceVersion = exist.ResourceVersion
+		ds.SetOwnerReferences(mergeOwnerReferences(ds.GetOwnerReferences(), exist.GetOwnerReferences()))
+		ds.SetLabels(mergeMaps(ds.Labels, exist.Labels))
+		ds.SetAnnotations(mergeMaps(ds.Annotations, exist.Annotations))
+
+		err := c.Update(ctx, ds)
+		if k8s_errors.IsNotAcceptable(err) {
+			err = c.Delete(ctx, ds)
+			if err != nil {
+				return fmt.Errorf("failed to update daemonset: deleting old daemonset: %w", err)
+			}
+			err = c.Create(ctx, ds)
+			if err != nil {
+				return fmt.Errorf("failed to update daemonset: creating new daemonset: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to update daemonset: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func mergeOwnerReferences(new, old []meta_v1.OwnerReference) []meta_v1.OwnerReference {
 	existing := make(map[types.UID]bool)
 	for _, ref := range old {
@@ -27,6 +27,12 @@ type Deployment struct {
 	Agent *grafana.GrafanaAgent
 	// Prometheis is the set of prometheus instances discovered from the root Agent resource.
 	Prometheis []PrometheusInstance
+	// Logs is the set of logs instances discovered from the root Agent
+	// resource.
+	Logs []LogInstance
+	// Traces is the set of traces instances discovered from the root Agent
+	// resource.
+	Traces []*grafana.TracesInstance
 }
 
 // DeepCopy creates a deep copy of d.
@@ -58,17 +64,60 @@ func (d *Deployment) DeepCopy() *Deployment {
 		})
 	}
 
+	l := make([]LogInstance, 0, len(d.Logs))
+	for _, i := range d.Logs {
+		inst := i.Instance.DeepCopy()
+		podLogs := make([]*grafana.PodLogs, 0, len(i.PodLogs))
+		for _, pl := range i.PodLogs {
+			podLogs = append(podLogs, pl.DeepCopy())
+		}
+
+		l = append(l, LogInstance{
+			Instance: inst,
+			PodLogs:  podLogs,
+		})
+	}
+
+	t := make([]*grafana.TracesInstance, 0, len(d.Traces))
+	for _, i := range d.Traces {
+		t = append(t, i.DeepCopy())
+	}
+
 	return &Deployment{
 		Agent:      d.Agent.DeepCopy(),
 		Prometheis: p,
+		Logs:       l,
+		Traces:     t,
 	}
 }
 
+// DefaultSecretsDir is the directory where generated configs expect the
+// values of referenced Secrets and ConfigMaps to be mounted as files.
+const DefaultSecretsDir = "/var/lib/grafana-agent/secrets"
+
 // TODO(rfratto): the "Optional" field of secrets is currently ignored.
 
-// BuildConfig builds an Agent configuration file.
+// BuildConfig builds an Agent configuration file for the pods running the
+// Prometheus and traces subsystems.
 func (d *Deployment) BuildConfig(secrets assets.SecretStore) (string, error) {
-	vm, err := createVM(secrets)
+	return d.buildConfig(secrets, DefaultSecretsDir, "./agent.libsonnet")
+}
+
+// BuildConfigWithSecretsDir is like BuildConfig, but generates paths to the
+// files of referenced Secrets and ConfigMaps relative to secretsDir. The
+// files must be named after the sanitized asset key; see SecretFileName.
+func (d *Deployment) BuildConfigWithSecretsDir(secrets assets.SecretStore, secretsDir string) (string, error) {
+	return d.buildConfig(secrets, secretsDir, "./agent.libsonnet")
+}
+
+// BuildLogsConfig builds an Agent configuration file for the pods running the
+// logs subsystem.
+func (d *Deployment) BuildLogsConfig(secrets assets.SecretStore) (string, error) {
+	return d.buildConfig(secrets, DefaultSecretsDir, "./agent-logs.libsonnet")
+}
+
+func (d *Deployment) buildConfig(secrets assets.SecretStore, secretsDir, entrypoint string) (string, error) {
+	vm, err := createVM(secrets, secretsDir)
 	if err != nil {
 		return "", err
 	}
@@ -79,10 +128,16 @@ func (d *Deployment) BuildConfig(secrets assets.SecretStore) (string, error) {
 	}
 
 	vm.TLACode("ctx", string(bb))
-	return vm.EvaluateFile("./agent.libsonnet")
+	return vm.EvaluateFile(entrypoint)
 }
 
-func createVM(secrets assets.SecretStore) (*jsonnet.VM, error) {
+// SecretFileName returns the name of the file holding the value of k within
+// a secrets directory.
+func SecretFileName(k assets.Key) string {
+	return SanitizeLabelName(string(k))
+}
+
+func createVM(secrets assets.SecretStore, secretsDir string) (*jsonnet.VM, error) {
 	vm := jsonnet.MakeVM()
 	vm.StringOutput = true
 
@@ -143,8 +198,8 @@ func createVM(secrets assets.SecretStore) (*jsonnet.VM, error) {
 				return nil, nil
 			}
 
-			key := SanitizeLabelName(i[0].(string))
-			return path.Join("/var/lib/grafana-agent/secrets", key), nil
+			key := SecretFileName(assets.Key(i[0].(string)))
+			return path.Join(secretsDir, key), nil
 		},
 	})
 
@@ -186,3 +241,10 @@ type PrometheusInstance struct {
 	PodMonitors     []*prom.PodMonitor
 	Probes          []*prom.Probe
 }
+
+// LogInstance is an instance with a set of associated PodLogs, which compose
+// the final configuration of the generated logs instance.
+type LogInstance struct {
+	Instance *grafana.LogsInstance
+	PodLogs  []*grafana.PodLogs
+}
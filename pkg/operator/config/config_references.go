@@ -84,6 +84,30 @@ func (d *Deployment) AssetReferences() []AssetReference {
 		}
 	}
 
+	// Retrieve references from logs clients
+	for _, c := range d.Agent.Spec.Logs.Clients {
+		res = append(res, logsClientAssetReferences(d.Agent.Namespace, &c)...)
+	}
+	for _, inst := range d.Logs {
+		res = append(res, AssetReference{
+			Namespace: inst.Instance.Namespace,
+			Reference: prom.SecretOrConfigMap{Secret: inst.Instance.Spec.AdditionalScrapeConfigs},
+		})
+		for _, c := range inst.Instance.Spec.Clients {
+			res = append(res, logsClientAssetReferences(inst.Instance.Namespace, &c)...)
+		}
+	}
+
+	// Retrieve references from traces remote_writes
+	for _, rw := range d.Agent.Spec.Traces.RemoteWrite {
+		res = append(res, tracesRemoteWriteAssetReferences(d.Agent.Namespace, &rw)...)
+	}
+	for _, inst := range d.Traces {
+		for _, rw := range inst.Spec.RemoteWrite {
+			res = append(res, tracesRemoteWriteAssetReferences(inst.Namespace, &rw)...)
+		}
+	}
+
 	return filterEmptyReferences(res)
 }
 
@@ -136,6 +160,30 @@ func remoteWriteAssetReferences(namespace string, rw *grafana.RemoteWriteSpec) [
 	return filterEmptyReferences(res)
 }
 
+func logsClientAssetReferences(namespace string, c *grafana.LogsClientSpec) []AssetReference {
+	var res []AssetReference
+
+	res = append(res, basicAuthAssetReferences(namespace, c.BasicAuth)...)
+
+	if c.TLSConfig != nil {
+		res = append(res, tlsConfigReferences(namespace, c.TLSConfig)...)
+	}
+
+	return filterEmptyReferences(res)
+}
+
+func tracesRemoteWriteAssetReferences(namespace string, rw *grafana.TracesRemoteWriteSpec) []AssetReference {
+	var res []AssetReference
+
+	res = append(res, basicAuthAssetReferences(namespace, rw.BasicAuth)...)
+
+	if rw.TLSConfig != nil {
+		res = append(res, tlsConfigReferences(namespace, rw.TLSConfig)...)
+	}
+
+	return filterEmptyReferences(res)
+}
+
 func tlsConfigReferences(namespace string, cfg *prom.TLSConfig) []AssetReference {
 	return filterEmptyReferences([]AssetReference{
 		{Namespace: namespace, Reference: cfg.CA},
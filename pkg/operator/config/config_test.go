@@ -5,9 +5,11 @@ import (
 	"testing"
 
 	"github.com/grafana/agent/pkg/operator/assets"
+	"github.com/grafana/agent/pkg/tempo"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 	v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8s_yaml "sigs.k8s.io/yaml"
@@ -214,4 +216,230 @@ prometheus:
 	}
 }
 
+func TestBuildLogsConfig(t *testing.T) {
+	var store = make(assets.SecretStore)
+
+	input := Deployment{
+		Agent: &grafana.GrafanaAgent{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "operator",
+				Name:      "agent",
+			},
+			Spec: grafana.GrafanaAgentSpec{
+				LogLevel: "debug",
+				Logs: grafana.LogsSubsystemSpec{
+					Clients: []grafana.LogsClientSpec{{
+						URL: "http://loki:3100/loki/api/v1/push",
+					}},
+				},
+			},
+		},
+		Logs: []LogInstance{{
+			Instance: &grafana.LogsInstance{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Namespace: "operator",
+					Name:      "primary",
+				},
+			},
+			PodLogs: []*grafana.PodLogs{{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Namespace: "operator",
+					Name:      "podlogs",
+				},
+			}},
+		}, {
+			Instance: &grafana.LogsInstance{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Namespace: "operator",
+					Name:      "secondary",
+				},
+				Spec: grafana.LogsInstanceSpec{
+					Clients: []grafana.LogsClientSpec{{
+						URL:            "http://other-loki:3100/loki/api/v1/push",
+						ExternalLabels: map[string]string{"foo": "bar"},
+					}},
+				},
+			},
+		}},
+	}
+
+	expect := util.Untab(`
+server:
+  http_listen_port: 8080
+  log_level: debug
+
+loki:
+  positions_directory: /var/lib/grafana-agent/data
+  configs:
+  - name: operator/primary
+    clients:
+    - url: http://loki:3100/loki/api/v1/push
+      external_labels:
+        cluster: operator/agent
+    scrape_configs:
+    - job_name: podLogs/operator/podlogs
+      kubernetes_sd_configs:
+      - role: pod
+        namespaces:
+          names: [operator]
+      relabel_configs:
+      - source_labels: [__meta_kubernetes_namespace]
+        target_label: namespace
+      - source_labels: [__meta_kubernetes_pod_name]
+        target_label: pod
+      - source_labels: [__meta_kubernetes_pod_container_name]
+        target_label: container
+      - target_label: job
+        replacement: operator/podlogs
+      - source_labels: [__meta_kubernetes_pod_node_name]
+        target_label: __host__
+      - source_labels: [__meta_kubernetes_pod_uid, __meta_kubernetes_pod_container_name]
+        separator: /
+        target_label: __path__
+        replacement: /var/log/pods/*$1/*.log
+  - name: operator/secondary
+    clients:
+    - url: http://other-loki:3100/loki/api/v1/push
+      external_labels:
+        cluster: operator/agent
+        foo: bar
+	`)
+
+	result, err := input.BuildLogsConfig(store)
+	require.NoError(t, err)
+
+	if !assert.YAMLEq(t, expect, result) {
+		fmt.Println(result)
+	}
+}
+
+func TestBuildTracesConfig(t *testing.T) {
+	var store = make(assets.SecretStore)
+
+	input := Deployment{
+		Agent: &grafana.GrafanaAgent{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "operator",
+				Name:      "agent",
+			},
+			Spec: grafana.GrafanaAgentSpec{
+				Traces: grafana.TracesSubsystemSpec{
+					RemoteWrite: []grafana.TracesRemoteWriteSpec{{
+						Endpoint: "tempo:4317",
+					}},
+				},
+			},
+		},
+		Traces: []*grafana.TracesInstance{{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "operator",
+				Name:      "primary",
+			},
+			Spec: grafana.TracesInstanceSpec{
+				Receivers: grafana.TracesReceiversSpec{
+					OTLP: &grafana.OTLPReceiverSpec{
+						GRPC: &grafana.TracesReceiverEndpointSpec{},
+					},
+					Jaeger: &grafana.JaegerReceiverSpec{
+						ThriftCompact: &grafana.TracesReceiverEndpointSpec{Endpoint: "0.0.0.0:6833"},
+					},
+				},
+			},
+		}, {
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "operator",
+				Name:      "secondary",
+			},
+			Spec: grafana.TracesInstanceSpec{
+				Receivers: grafana.TracesReceiversSpec{
+					Zipkin: &grafana.TracesReceiverEndpointSpec{},
+				},
+				RemoteWrite: []grafana.TracesRemoteWriteSpec{{
+					Endpoint: "other-tempo:4317",
+					Insecure: true,
+				}},
+				TailSampling: &grafana.TailSamplingSpec{
+					Policies: []grafana.TailSamplingPolicySpec{
+						{AlwaysSample: &grafana.AlwaysSamplePolicySpec{}},
+						{StringAttribute: &grafana.StringAttributePolicySpec{
+							Key:    "http.status_code",
+							Values: []string{"500"},
+						}},
+						{NumericAttribute: &grafana.NumericAttributePolicySpec{
+							Key:      "retries",
+							MinValue: 0,
+							MaxValue: 5,
+						}},
+						{RateLimiting: &grafana.RateLimitingPolicySpec{SpansPerSecond: 35}},
+					},
+					DecisionWait: "10s",
+				},
+			},
+		}},
+	}
+
+	expect := util.Untab(`
+server:
+  http_listen_port: 8080
+
+prometheus:
+  wal_directory: /var/lib/grafana-agent/data
+  global:
+    external_labels:
+      cluster: operator/agent
+      __replica__: replica-$(STATEFULSET_ORDINAL_NUMBER)
+
+tempo:
+  configs:
+  - name: operator/primary
+    receivers:
+      otlp:
+        protocols:
+          grpc: {}
+      jaeger:
+        protocols:
+          thrift_compact:
+            endpoint: 0.0.0.0:6833
+    remote_write:
+    - endpoint: tempo:4317
+  - name: operator/secondary
+    receivers:
+      zipkin: {}
+    remote_write:
+    - endpoint: other-tempo:4317
+      insecure: true
+    tail_sampling:
+      decision_wait: 10s
+      policies:
+      - always_sample: {}
+      - string_attribute:
+          key: http.status_code
+          values: ["500"]
+      - numeric_attribute:
+          key: retries
+          min_value: 0
+          max_value: 5
+      - rate_limiting:
+          spans_per_second: 35
+	`)
+
+	result, err := input.BuildConfig(store)
+	require.NoError(t, err)
+
+	if !assert.YAMLEq(t, expect, result) {
+		fmt.Println(result)
+	}
+
+	// The generated config must be loadable by the traces subsystem.
+	var cfg struct {
+		Server     interface{}  `yaml:"server"`
+		Prometheus interface{}  `yaml:"prometheus"`
+		Tempo      tempo.Config `yaml:"tempo"`
+	}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(result), &cfg))
+	for _, inst := range cfg.Tempo.Configs {
+		require.NoError(t, inst.Validate())
+	}
+}
+
 func strPointer(s string) *string { return &s }
@@ -0,0 +1,49 @@
+// agent-logs.libsonnet is the entrypoint for rendering a Grafana Agent config
+// file for the logs subsystem based on the Operator custom resources.
+//
+// See agent.libsonnet for the conventions used when writing objects.
+
+local marshal = import './ext/marshal.libsonnet';
+local optionals = import './ext/optionals.libsonnet';
+
+local new_logs_instance = import './logs.libsonnet';
+
+// @param {config.Deployment} ctx
+function(ctx) marshal.YAML(optionals.trim({
+  local spec = ctx.Agent.Spec,
+  local logs = spec.Logs,
+  local meta = ctx.Agent.ObjectMeta,
+
+  // The cluster label is added to all clients by default. Like the Prometheus
+  // subsystem, the user may override it through LogsExternalLabelName.
+  local clusterLabels = (
+    local clusterValue = '%s/%s' % [meta.Namespace, meta.Name];
+    local clusterLabel = logs.LogsExternalLabelName;
+
+    if clusterLabel == null then { cluster: clusterValue }
+    else if clusterLabel != '' then { [clusterLabel]: clusterValue }
+    else {}
+  ),
+
+  server: {
+    http_listen_port: 8080,
+    log_level: optionals.string(spec.LogLevel),
+    log_format: optionals.string(spec.LogFormat),
+  },
+
+  loki: {
+    positions_directory: '/var/lib/grafana-agent/data',
+    configs: optionals.array(std.map(
+      function(inst) new_logs_instance(
+        agentNamespace=meta.Namespace,
+        instance=inst,
+        apiServer=spec.APIServerConfig,
+        defaultClients=logs.Clients,
+        clusterLabels=clusterLabels,
+        ignoreNamespaceSelectors=logs.IgnoreNamespaceSelectors,
+        enforcedNamespaceLabel=logs.EnforcedNamespaceLabel,
+      ),
+      ctx.Logs,
+    )),
+  },
+}))
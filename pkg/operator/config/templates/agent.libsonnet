@@ -13,10 +13,12 @@
 
 local marshal = import './ext/marshal.libsonnet';
 local optionals = import './ext/optionals.libsonnet';
+local k8s = import './utils/k8s.libsonnet';
 
 local new_external_labels = import './component/external_labels.libsonnet';
 local new_remote_write = import './component/remote_write.libsonnet';
 local new_prometheus_instance = import './prometheus.libsonnet';
+local new_traces_instance = import './traces.libsonnet';
 
 local calculateShards(requested) =
   if requested == null then 1
@@ -62,4 +64,17 @@ function(ctx) marshal.YAML(optionals.trim({
       ctx.Prometheis,
     )),
   },
+
+  tempo: (
+    if std.length(k8s.array(ctx.Traces)) > 0 then {
+      configs: std.map(
+        function(inst) new_traces_instance(
+          agentNamespace=ctx.Agent.ObjectMeta.Namespace,
+          instance=inst,
+          defaultRemoteWrite=spec.Traces.RemoteWrite,
+        ),
+        ctx.Traces,
+      ),
+    }
+  ),
 }))
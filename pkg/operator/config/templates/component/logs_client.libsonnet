@@ -0,0 +1,46 @@
+local optionals = import '../ext/optionals.libsonnet';
+local secrets = import '../ext/secrets.libsonnet';
+
+local new_tls_config = import './tls_config.libsonnet';
+
+// Generates the contents of a logs client object.
+//
+// @param {string} namespace - namespace of the LogsClientSpec.
+// @param {LogsClientSpec} spec
+// @param {object} clusterLabels - external labels to add to the client. Labels
+//   from the spec take precedence.
+function(namespace, spec, clusterLabels) {
+  url: spec.URL,
+  tenant_id: optionals.string(spec.TenantID),
+  batchwait: optionals.string(spec.BatchWait),
+  batchsize: optionals.number(spec.BatchSize),
+  proxy_url: optionals.string(spec.ProxyURL),
+  timeout: optionals.string(spec.Timeout),
+
+  tls_config: (
+    if spec.TLSConfig != null then
+      new_tls_config(namespace, spec.TLSConfig)
+  ),
+
+  basic_auth: (
+    if spec.BasicAuth != null then {
+      username: secrets.valueForSecret(namespace, spec.BasicAuth.Username),
+      password: secrets.valueForSecret(namespace, spec.BasicAuth.Password),
+    }
+  ),
+  bearer_token: optionals.string(spec.BearerToken),
+  bearer_token_file: optionals.string(spec.BearerTokenFile),
+
+  backoff_config: (
+    if spec.BackoffConfig != null then {
+      min_period: optionals.string(spec.BackoffConfig.MinPeriod),
+      max_period: optionals.string(spec.BackoffConfig.MaxPeriod),
+      max_retries: optionals.number(spec.BackoffConfig.MaxRetries),
+    }
+  ),
+
+  external_labels: optionals.object(
+    clusterLabels +
+    (if spec.ExternalLabels != null then spec.ExternalLabels else {})
+  ),
+}
@@ -0,0 +1,39 @@
+local optionals = import '../ext/optionals.libsonnet';
+
+// Generates a pipeline stage. Exactly one field of the stage is expected to
+// be set, so all others are left null to be trimmed.
+//
+// @param {PipelineStageSpec} stage
+function(stage) {
+  cri: if stage.CRI != null then {},
+  docker: if stage.Docker != null then {},
+
+  json: if stage.JSON != null then {
+    source: optionals.string(stage.JSON.Source),
+    expressions: optionals.object(stage.JSON.Expressions),
+  },
+
+  // An empty value means to use the extracted value with the same name as the
+  // label. Set the name explicitly so the label isn't trimmed away.
+  labels: if stage.Labels != null then std.mapWithKey(
+    function(k, v) if v == '' then k else v,
+    stage.Labels,
+  ),
+
+  output: if stage.Output != null then {
+    source: stage.Output.Source,
+  },
+
+  regex: if stage.Regex != null then {
+    source: optionals.string(stage.Regex.Source),
+    expression: stage.Regex.Expression,
+  },
+
+  timestamp: if stage.Timestamp != null then {
+    source: stage.Timestamp.Source,
+    format: stage.Timestamp.Format,
+    fallback_formats: optionals.array(stage.Timestamp.FallbackFormats),
+    location: optionals.string(stage.Timestamp.Location),
+    action_on_failure: optionals.string(stage.Timestamp.ActionOnFailure),
+  },
+}
@@ -0,0 +1,151 @@
+local optionals = import '../ext/optionals.libsonnet';
+local k8s = import '../utils/k8s.libsonnet';
+
+local new_kube_sd_config = import './kube_sd_config.libsonnet';
+local new_pipeline_stage = import './pipeline_stage.libsonnet';
+local new_relabel_config = import './relabel_config.libsonnet';
+
+// Generates a scrape_config from a PodLogs.
+//
+// @param {string} agentNamespace - Namespace the GrafanaAgent CR is in.
+// @param {PodLogs} podLogs
+// @param {APIServerConfig} apiServer
+// @param {boolean} ignoreNamespaceSelectors
+// @param {string} enforcedNamespaceLabel
+function(
+  agentNamespace,
+  podLogs,
+  apiServer,
+  ignoreNamespaceSelectors,
+  enforcedNamespaceLabel,
+) {
+  local meta = podLogs.ObjectMeta,
+
+  job_name: 'podLogs/%s/%s' % [meta.Namespace, meta.Name],
+
+  kubernetes_sd_configs: [
+    new_kube_sd_config(
+      namespace=agentNamespace,
+      namespaces=k8s.namespacesFromSelector(
+        podLogs.Spec.NamespaceSelector,
+        meta.Namespace,
+        ignoreNamespaceSelectors,
+      ),
+      apiServer=apiServer,
+      role='pod',
+    ),
+  ],
+
+  pipeline_stages: optionals.array(std.map(
+    new_pipeline_stage,
+    k8s.array(podLogs.Spec.PipelineStages),
+  )),
+
+  relabel_configs: (
+    // Match on pod labels.
+    std.map(
+      function(k) {
+        source_labels: ['__meta_kubernetes_pod_label_' + k8s.sanitize(k)],
+        regex: podLogs.Spec.Selector.MatchLabels[k],
+        action: 'keep',
+      },
+      // Keep the output consistent by sorting the keys first.
+      std.sort(std.objectFields(
+        if podLogs.Spec.Selector.MatchLabels != null
+        then podLogs.Spec.Selector.MatchLabels
+        else {}
+      )),
+    ) +
+
+    // Set-based label matching. we have to map the valid relations
+    // `In`, `NotIn`, `Exists`, and `DoesNotExist` into relabling rules.
+    std.map(
+      function(exp) (
+        if exp.Operator == 'In' then {
+          source_labels: ['__meta_kubernetes_pod_label_' + k8s.sanitize(exp.Key)],
+          regex: std.join('|', exp.Values),
+          action: 'keep',
+        } else if exp.Operator == 'NotIn' then {
+          source_labels: ['__meta_kubernetes_pod_label_' + k8s.sanitize(exp.Key)],
+          regex: std.join('|', exp.Values),
+          action: 'drop',
+        } else if exp.Operator == 'Exists' then {
+          source_labels: ['__meta_kubernetes_pod_labelpresent_' + k8s.sanitize(exp.Key)],
+          regex: 'true',
+          action: 'keep',
+        } else if exp.Operator == 'DoesNotExist' then {
+          source_labels: ['__meta_kubernetes_pod_labelpresent_' + k8s.sanitize(exp.Key)],
+          regex: 'true',
+          action: 'drop',
+        }
+      ),
+      k8s.array(podLogs.Spec.Selector.MatchExpressions),
+    ) +
+
+    // Relabel namespace, pod, and container metalabels into proper labels.
+    [{
+      source_labels: ['__meta_kubernetes_namespace'],
+      target_label: 'namespace',
+    }, {
+      source_labels: ['__meta_kubernetes_pod_name'],
+      target_label: 'pod',
+    }, {
+      source_labels: ['__meta_kubernetes_pod_container_name'],
+      target_label: 'container',
+    }] +
+
+    // Relabel podTargetLabels from the pod onto the target.
+    std.map(
+      function(l) {
+        source_labels: ['__meta_kubernetes_pod_label_' + k8s.sanitize(l)],
+        target_label: k8s.sanitize(l),
+        regex: '(.+)',
+        replacement: '$1',
+      },
+      k8s.array(podLogs.Spec.PodTargetLabels)
+    ) +
+
+    // By default, generate a safe job name from the PodLogs. We also keep
+    // this around if a jobLabel is set just in case targets don't actually
+    // have a value for it.
+    std.filter(function(e) e != null, [
+      {
+        target_label: 'job',
+        replacement: '%s/%s' % [meta.Namespace, meta.Name],
+      },
+      if podLogs.Spec.JobLabel != '' then {
+        source_labels: ['__meta_kubernetes_pod_label_' + k8s.sanitize(podLogs.Spec.JobLabel)],
+        target_label: 'job',
+        regex: '(.+)',
+        replacement: '$1',
+      },
+    ]) +
+
+    // Only tail the logs of pods running on the same node as the agent, and
+    // find the log files of the container on that node.
+    [{
+      source_labels: ['__meta_kubernetes_pod_node_name'],
+      target_label: '__host__',
+    }, {
+      source_labels: ['__meta_kubernetes_pod_uid', '__meta_kubernetes_pod_container_name'],
+      separator: '/',
+      target_label: '__path__',
+      replacement: '/var/log/pods/*$1/*.log',
+    }] +
+
+    std.map(
+      function(c) new_relabel_config(c),
+      k8s.array(podLogs.Spec.RelabelConfigs),
+    ) +
+
+    // Because of security risks, whenever enforcedNamespaceLabel is set,
+    // we want to append it to the relabel_configs as the last relabling to
+    // ensure it overrides all other relabelings.
+    std.filter(function(e) e != null, [
+      if enforcedNamespaceLabel != '' then {
+        target_label: enforcedNamespaceLabel,
+        replacement: podLogs.ObjectMeta.Namespace,
+      },
+    ])
+  ),
+}
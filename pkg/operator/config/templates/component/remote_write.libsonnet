@@ -10,7 +10,6 @@ local new_tls_config = import './tls_config.libsonnet';
 // @param {RemoteWriteSpec} rw
 function(namespace, rw) {
   // TODO(rfratto): follow_redirects
-  // TODO(rfratto): retry_on_http_429, currently experimental
 
   url: rw.URL,
   name: optionals.string(rw.Name),
@@ -53,6 +52,7 @@ function(namespace, rw) {
       batch_send_deadline: optionals.string(rw.QueueConfig.BatchSendDeadline),
       min_backoff: optionals.string(rw.QueueConfig.MinBackoff),
       max_backoff: optionals.string(rw.QueueConfig.MaxBackoff),
+      retry_on_http_429: optionals.bool(rw.QueueConfig.RetryOnRateLimit),
     }
   ),
 
@@ -0,0 +1,24 @@
+// Generates a tail_sampling policy. Only one policy type is expected to be
+// set; the first defined type is used.
+//
+// @param {TailSamplingPolicySpec} policy
+function(policy) (
+  if policy.AlwaysSample != null then {
+    always_sample: {},
+  } else if policy.StringAttribute != null then {
+    string_attribute: {
+      key: policy.StringAttribute.Key,
+      values: policy.StringAttribute.Values,
+    },
+  } else if policy.NumericAttribute != null then {
+    numeric_attribute: {
+      key: policy.NumericAttribute.Key,
+      min_value: policy.NumericAttribute.MinValue,
+      max_value: policy.NumericAttribute.MaxValue,
+    },
+  } else if policy.RateLimiting != null then {
+    rate_limiting: {
+      spans_per_second: policy.RateLimiting.SpansPerSecond,
+    },
+  }
+)
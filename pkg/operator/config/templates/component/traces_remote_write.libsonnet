@@ -0,0 +1,28 @@
+local optionals = import '../ext/optionals.libsonnet';
+local secrets = import '../ext/secrets.libsonnet';
+
+local new_tls_config = import './tls_config.libsonnet';
+
+// Generates the contents of a traces remote_write object.
+//
+// @param {string} namespace - namespace of the TracesRemoteWriteSpec.
+// @param {TracesRemoteWriteSpec} rw
+function(namespace, rw) {
+  endpoint: rw.Endpoint,
+  compression: optionals.string(rw.Compression),
+  protocol: optionals.string(rw.Protocol),
+  insecure: optionals.bool(rw.Insecure),
+  headers: optionals.object(rw.Headers),
+
+  tls_config: (
+    if rw.TLSConfig != null && !rw.Insecure then
+      new_tls_config(namespace, rw.TLSConfig)
+  ),
+
+  basic_auth: (
+    if rw.BasicAuth != null then {
+      username: secrets.valueForSecret(namespace, rw.BasicAuth.Username),
+      password: secrets.valueForSecret(namespace, rw.BasicAuth.Password),
+    }
+  ),
+}
@@ -0,0 +1,66 @@
+local marshal = import './ext/marshal.libsonnet';
+local optionals = import './ext/optionals.libsonnet';
+local secrets = import './ext/secrets.libsonnet';
+local k8s = import './utils/k8s.libsonnet';
+
+local new_logs_client = import './component/logs_client.libsonnet';
+local new_pod_logs = import './component/pod_logs.libsonnet';
+
+// Generates a logs instance.
+//
+// @param {string} agentNamespace - namespace of the GrafanaAgent
+// @param {LogInstance} instance
+// @param {APIServerConfig} apiServer
+// @param {LogsClientSpec[]} defaultClients - clients to use if the instance has none
+// @param {object} clusterLabels - external labels to add to each client
+// @param {boolean} ignoreNamespaceSelectors
+// @param {string} enforcedNamespaceLabel
+function(
+  agentNamespace,
+  instance,
+  apiServer,
+  defaultClients,
+  clusterLabels,
+  ignoreNamespaceSelectors,
+  enforcedNamespaceLabel,
+) {
+  local namespace = instance.Instance.ObjectMeta.Namespace,
+  local spec = instance.Instance.Spec,
+
+  name: '%s/%s' % [namespace, instance.Instance.ObjectMeta.Name],
+
+  // Clients from the instance take precedence over the default clients from
+  // the GrafanaAgent. Secrets of the default clients are read from the
+  // namespace of the GrafanaAgent.
+  clients: optionals.array(
+    if std.length(k8s.array(spec.Clients)) > 0 then std.map(
+      function(c) new_logs_client(namespace, c, clusterLabels),
+      spec.Clients,
+    ) else std.map(
+      function(c) new_logs_client(agentNamespace, c, clusterLabels),
+      k8s.array(defaultClients),
+    )
+  ),
+
+  scrape_configs: optionals.array(
+    std.map(
+      function(podLogs) new_pod_logs(
+        agentNamespace=agentNamespace,
+        podLogs=podLogs,
+        apiServer=apiServer,
+        ignoreNamespaceSelectors=ignoreNamespaceSelectors,
+        enforcedNamespaceLabel=enforcedNamespaceLabel,
+      ),
+      k8s.array(instance.PodLogs),
+    ) +
+
+    // If the user specified additional scrape configs, we need to extract
+    // their value from the secret and then unmarshal them into the array.
+    k8s.array(
+      if spec.AdditionalScrapeConfigs != null then (
+        local rawYAML = secrets.valueForSecret(namespace, spec.AdditionalScrapeConfigs);
+        marshal.fromYAML(rawYAML)
+      )
+    ),
+  ),
+}
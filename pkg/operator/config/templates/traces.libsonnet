@@ -0,0 +1,68 @@
+local optionals = import './ext/optionals.libsonnet';
+local k8s = import './utils/k8s.libsonnet';
+
+local new_sampling_policy = import './component/sampling_policy.libsonnet';
+local new_traces_remote_write = import './component/traces_remote_write.libsonnet';
+
+// endpoint converts a TracesReceiverEndpointSpec into a receiver protocol
+// object. Receivers which aren't enabled are null.
+local endpoint(spec) =
+  if spec == null then null
+  else { endpoint: optionals.string(spec.Endpoint) };
+
+// Generates a traces instance.
+//
+// @param {string} agentNamespace - namespace of the GrafanaAgent
+// @param {TracesInstance} instance
+// @param {TracesRemoteWriteSpec[]} defaultRemoteWrite - remote_write to use
+//   if the instance has none
+function(agentNamespace, instance, defaultRemoteWrite) {
+  local namespace = instance.ObjectMeta.Namespace,
+  local spec = instance.Spec,
+  local receivers = spec.Receivers,
+
+  name: '%s/%s' % [namespace, instance.ObjectMeta.Name],
+
+  receivers: optionals.object(optionals.trim({
+    otlp: (
+      if receivers.OTLP != null then {
+        protocols: {
+          grpc: endpoint(receivers.OTLP.GRPC),
+          http: endpoint(receivers.OTLP.HTTP),
+        },
+      }
+    ),
+    jaeger: (
+      if receivers.Jaeger != null then {
+        protocols: {
+          grpc: endpoint(receivers.Jaeger.GRPC),
+          thrift_http: endpoint(receivers.Jaeger.ThriftHTTP),
+          thrift_compact: endpoint(receivers.Jaeger.ThriftCompact),
+          thrift_binary: endpoint(receivers.Jaeger.ThriftBinary),
+        },
+      }
+    ),
+    zipkin: endpoint(receivers.Zipkin),
+    opencensus: endpoint(receivers.OpenCensus),
+  })),
+
+  // remote_write from the instance takes precedence over the defaults from
+  // the GrafanaAgent. Secrets of the defaults are read from the namespace of
+  // the GrafanaAgent.
+  remote_write: optionals.array(
+    if std.length(k8s.array(spec.RemoteWrite)) > 0 then std.map(
+      function(rw) new_traces_remote_write(namespace, rw),
+      spec.RemoteWrite,
+    ) else std.map(
+      function(rw) new_traces_remote_write(agentNamespace, rw),
+      k8s.array(defaultRemoteWrite),
+    )
+  ),
+
+  tail_sampling: (
+    if spec.TailSampling != null then {
+      policies: std.map(new_sampling_policy, k8s.array(spec.TailSampling.Policies)),
+      decision_wait: optionals.string(spec.TailSampling.DecisionWait),
+    }
+  ),
+}
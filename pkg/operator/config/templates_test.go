@@ -86,7 +86,7 @@ func TestExternalLabels(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(nil)
+			vm, err := createVM(nil, DefaultSecretsDir)
 			require.NoError(t, err)
 			bb, err := jsonnetMarshal(tc.input)
 			require.NoError(t, err)
@@ -204,7 +204,7 @@ func TestKubeSDConfig(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(testStore())
+			vm, err := createVM(testStore(), DefaultSecretsDir)
 			require.NoError(t, err)
 
 			args := []string{"namespace", "namespaces", "apiServer", "role"}
@@ -221,6 +221,190 @@ func TestKubeSDConfig(t *testing.T) {
 	}
 }
 
+func TestLogsClient(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  map[string]interface{}
+		expect string
+	}{
+		{
+			name: "defaults",
+			input: map[string]interface{}{
+				"namespace": "operator",
+				"spec": &v1alpha1.LogsClientSpec{
+					URL: "http://loki:3100/loki/api/v1/push",
+				},
+				"clusterLabels": map[string]string{"cluster": "operator/agent"},
+			},
+			expect: util.Untab(`
+				url: http://loki:3100/loki/api/v1/push
+				external_labels:
+					cluster: operator/agent
+			`),
+		},
+		{
+			name: "all fields",
+			input: map[string]interface{}{
+				"namespace": "operator",
+				"spec": &v1alpha1.LogsClientSpec{
+					URL:       "http://loki:3100/loki/api/v1/push",
+					TenantID:  "tenant",
+					BatchWait: "1s",
+					BatchSize: 1024,
+					BasicAuth: &prom_v1.BasicAuth{
+						Username: v1.SecretKeySelector{
+							LocalObjectReference: v1.LocalObjectReference{Name: "obj"},
+							Key:                  "key",
+						},
+						Password: v1.SecretKeySelector{
+							LocalObjectReference: v1.LocalObjectReference{Name: "obj"},
+							Key:                  "key",
+						},
+					},
+					ProxyURL: "http://proxy:8080",
+					BackoffConfig: &v1alpha1.LogsBackoffConfigSpec{
+						MinPeriod:  "500ms",
+						MaxPeriod:  "5m",
+						MaxRetries: 10,
+					},
+					ExternalLabels: map[string]string{"cluster": "prod", "foo": "bar"},
+					Timeout:        "10s",
+				},
+				"clusterLabels": map[string]string{"cluster": "operator/agent"},
+			},
+			expect: util.Untab(`
+				url: http://loki:3100/loki/api/v1/push
+				tenant_id: tenant
+				batchwait: 1s
+				batchsize: 1024
+				proxy_url: http://proxy:8080
+				timeout: 10s
+				basic_auth:
+					username: secretkey
+					password: secretkey
+				backoff_config:
+					min_period: 500ms
+					max_period: 5m
+					max_retries: 10
+				external_labels:
+					cluster: prod
+					foo: bar
+			`),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			vm, err := createVM(testStore(), DefaultSecretsDir)
+			require.NoError(t, err)
+
+			args := []string{"namespace", "spec", "clusterLabels"}
+			for _, arg := range args {
+				bb, err := jsonnetMarshal(tc.input[arg])
+				require.NoError(t, err)
+				vm.TLACode(arg, string(bb))
+			}
+
+			actual, err := runSnippet(vm, "./component/logs_client.libsonnet", args...)
+			require.NoError(t, err)
+			if !assert.YAMLEq(t, tc.expect, actual) {
+				fmt.Fprintln(os.Stderr, actual)
+			}
+		})
+	}
+}
+
+func TestPodLogs(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  map[string]interface{}
+		expect string
+	}{
+		{
+			name: "default",
+			input: map[string]interface{}{
+				"agentNamespace": "operator",
+				"podLogs": v1alpha1.PodLogs{
+					ObjectMeta: meta_v1.ObjectMeta{
+						Namespace: "operator",
+						Name:      "podlogs",
+					},
+					Spec: v1alpha1.PodLogsSpec{
+						JobLabel: "app",
+						Selector: meta_v1.LabelSelector{
+							MatchLabels: map[string]string{"app": "foo"},
+						},
+						PipelineStages: []*v1alpha1.PipelineStageSpec{
+							{CRI: &v1alpha1.CRIStageSpec{}},
+							{Labels: map[string]string{"level": "", "foo": "bar"}},
+						},
+					},
+				},
+				"apiServer":                prom_v1.APIServerConfig{},
+				"ignoreNamespaceSelectors": false,
+				"enforcedNamespaceLabel":   "",
+			},
+			expect: util.Untab(`
+				job_name: podLogs/operator/podlogs
+				kubernetes_sd_configs:
+				- role: pod
+					namespaces:
+						names: [operator]
+				pipeline_stages:
+				- cri: {}
+				- labels:
+						level: level
+						foo: bar
+				relabel_configs:
+				- source_labels: [__meta_kubernetes_pod_label_app]
+					regex: foo
+					action: keep
+				- source_labels: [__meta_kubernetes_namespace]
+					target_label: namespace
+				- source_labels: [__meta_kubernetes_pod_name]
+					target_label: pod
+				- source_labels: [__meta_kubernetes_pod_container_name]
+					target_label: container
+				- target_label: job
+					replacement: operator/podlogs
+				- source_labels: [__meta_kubernetes_pod_label_app]
+					target_label: job
+					regex: (.+)
+					replacement: $1
+				- source_labels: [__meta_kubernetes_pod_node_name]
+					target_label: __host__
+				- source_labels: [__meta_kubernetes_pod_uid, __meta_kubernetes_pod_container_name]
+					separator: /
+					target_label: __path__
+					replacement: /var/log/pods/*$1/*.log
+			`),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			vm, err := createVM(testStore(), DefaultSecretsDir)
+			require.NoError(t, err)
+
+			args := []string{
+				"agentNamespace", "podLogs", "apiServer",
+				"ignoreNamespaceSelectors", "enforcedNamespaceLabel",
+			}
+			for _, arg := range args {
+				bb, err := jsonnetMarshal(tc.input[arg])
+				require.NoError(t, err)
+				vm.TLACode(arg, string(bb))
+			}
+
+			actual, err := runSnippet(vm, "./component/pod_logs.libsonnet", args...)
+			require.NoError(t, err)
+			if !assert.YAMLEq(t, tc.expect, actual) {
+				fmt.Fprintln(os.Stderr, actual)
+			}
+		})
+	}
+}
+
 func TestPodMonitor(t *testing.T) {
 	tt := []struct {
 		name   string
@@ -290,7 +474,7 @@ func TestPodMonitor(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(testStore())
+			vm, err := createVM(testStore(), DefaultSecretsDir)
 			require.NoError(t, err)
 
 			args := []string{
@@ -388,7 +572,7 @@ func TestProbe(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(testStore())
+			vm, err := createVM(testStore(), DefaultSecretsDir)
 			require.NoError(t, err)
 
 			args := []string{
@@ -442,7 +626,7 @@ func TestRelabelConfig(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(nil)
+			vm, err := createVM(nil, DefaultSecretsDir)
 			require.NoError(t, err)
 			bb, err := jsonnetMarshal(tc.input)
 			require.NoError(t, err)
@@ -600,6 +784,7 @@ func TestRemoteWrite(t *testing.T) {
 						BatchSendDeadline: "5m",
 						MinBackoff:        "1m",
 						MaxBackoff:        "5m",
+						RetryOnRateLimit:  true,
 					},
 				},
 			},
@@ -613,6 +798,7 @@ func TestRemoteWrite(t *testing.T) {
 					batch_send_deadline: 5m
 					min_backoff: 1m
 					max_backoff: 5m
+					retry_on_http_429: true
 			`),
 		},
 		{
@@ -638,7 +824,7 @@ func TestRemoteWrite(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(testStore())
+			vm, err := createVM(testStore(), DefaultSecretsDir)
 			require.NoError(t, err)
 
 			args := []string{"namespace", "rw"}
@@ -731,7 +917,7 @@ func TestSafeTLSConfig(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(testStore())
+			vm, err := createVM(testStore(), DefaultSecretsDir)
 			require.NoError(t, err)
 
 			args := []string{"namespace", "config"}
@@ -841,7 +1027,7 @@ func TestServiceMonitor(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(testStore())
+			vm, err := createVM(testStore(), DefaultSecretsDir)
 			require.NoError(t, err)
 
 			args := []string{
@@ -947,7 +1133,7 @@ func TestTLSConfig(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			vm, err := createVM(testStore())
+			vm, err := createVM(testStore(), DefaultSecretsDir)
 			require.NoError(t, err)
 
 			args := []string{"namespace", "config"}
@@ -964,6 +1150,103 @@ func TestTLSConfig(t *testing.T) {
 	}
 }
 
+func TestTracesRemoteWrite(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  map[string]interface{}
+		expect string
+	}{
+		{
+			name: "defaults",
+			input: map[string]interface{}{
+				"namespace": "operator",
+				"rw": &v1alpha1.TracesRemoteWriteSpec{
+					Endpoint: "tempo:4317",
+				},
+			},
+			expect: util.Untab(`
+				endpoint: tempo:4317
+			`),
+		},
+		{
+			name: "all fields",
+			input: map[string]interface{}{
+				"namespace": "operator",
+				"rw": &v1alpha1.TracesRemoteWriteSpec{
+					Endpoint:    "tempo:4317",
+					Compression: "none",
+					Protocol:    "http",
+					Headers:     map[string]string{"X-Scope-OrgID": "tenant"},
+					BasicAuth: &prom_v1.BasicAuth{
+						Username: v1.SecretKeySelector{
+							LocalObjectReference: v1.LocalObjectReference{Name: "obj"},
+							Key:                  "key",
+						},
+						Password: v1.SecretKeySelector{
+							LocalObjectReference: v1.LocalObjectReference{Name: "obj"},
+							Key:                  "key",
+						},
+					},
+					TLSConfig: &prom_v1.TLSConfig{
+						SafeTLSConfig: prom_v1.SafeTLSConfig{InsecureSkipVerify: true},
+						CAFile:        "ca",
+					},
+				},
+			},
+			expect: util.Untab(`
+				endpoint: tempo:4317
+				compression: none
+				protocol: http
+				headers:
+					X-Scope-OrgID: tenant
+				basic_auth:
+					username: secretkey
+					password: secretkey
+				tls_config:
+					ca_file: ca
+					insecure_skip_verify: true
+			`),
+		},
+		{
+			name: "insecure ignores tls_config",
+			input: map[string]interface{}{
+				"namespace": "operator",
+				"rw": &v1alpha1.TracesRemoteWriteSpec{
+					Endpoint: "tempo:4317",
+					Insecure: true,
+					TLSConfig: &prom_v1.TLSConfig{
+						CAFile: "ca",
+					},
+				},
+			},
+			expect: util.Untab(`
+				endpoint: tempo:4317
+				insecure: true
+			`),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			vm, err := createVM(testStore(), DefaultSecretsDir)
+			require.NoError(t, err)
+
+			args := []string{"namespace", "rw"}
+			for _, arg := range args {
+				bb, err := jsonnetMarshal(tc.input[arg])
+				require.NoError(t, err)
+				vm.TLACode(arg, string(bb))
+			}
+
+			actual, err := runSnippet(vm, "./component/traces_remote_write.libsonnet", args...)
+			require.NoError(t, err)
+			if !assert.YAMLEq(t, tc.expect, actual) {
+				fmt.Fprintln(os.Stderr, actual)
+			}
+		})
+	}
+}
+
 func runSnippet(vm *jsonnet.VM, filename string, args ...string) (string, error) {
 	boundArgs := make([]string, len(args))
 	for i := range args {
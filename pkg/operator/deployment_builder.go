@@ -58,9 +58,34 @@ func (b *deploymentBuilder) Build(ctx context.Context, l log.Logger) (config.Dep
 		})
 	}
 
+	logsInstances, err := b.getLogsInstances(ctx)
+	if err != nil {
+		return config.Deployment{}, err
+	}
+	logInstances := make([]config.LogInstance, 0, len(logsInstances))
+
+	for _, inst := range logsInstances {
+		podLogs, err := b.getPodLogs(ctx, inst)
+		if err != nil {
+			return config.Deployment{}, fmt.Errorf("unable to fetch PodLogs: %w", err)
+		}
+
+		logInstances = append(logInstances, config.LogInstance{
+			Instance: inst,
+			PodLogs:  podLogs,
+		})
+	}
+
+	tracesInstances, err := b.getTracesInstances(ctx)
+	if err != nil {
+		return config.Deployment{}, err
+	}
+
 	return config.Deployment{
 		Agent:      b.Agent,
 		Prometheis: promInstances,
+		Logs:       logInstances,
+		Traces:     tracesInstances,
 	}, nil
 }
 
@@ -311,3 +336,99 @@ func (b *deploymentBuilder) getProbes(
 	}
 	return items, nil
 }
+
+func (b *deploymentBuilder) getLogsInstances(ctx context.Context) ([]*grafana_v1alpha1.LogsInstance, error) {
+	sel, err := b.getResourceSelector(
+		b.Agent.Namespace,
+		b.Agent.Spec.Logs.InstanceNamespaceSelector,
+		b.Agent.Spec.Logs.InstanceSelector,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build logs resource selector: %w", err)
+	}
+	b.ResourceSelectors[resourceLogsInstance] = append(b.ResourceSelectors[resourceLogsInstance], sel)
+
+	var (
+		list        grafana_v1alpha1.LogsInstanceList
+		namespace   = namespaceFromSelector(sel)
+		listOptions = &client.ListOptions{LabelSelector: sel.Labels, Namespace: namespace}
+	)
+	if err := b.List(ctx, &list, listOptions); err != nil {
+		return nil, err
+	}
+
+	items := make([]*grafana_v1alpha1.LogsInstance, 0, len(list.Items))
+	for _, item := range list.Items {
+		if match, err := b.matchNamespace(ctx, &item.ObjectMeta, sel); match {
+			items = append(items, item)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed getting namespace: %w", err)
+		}
+	}
+	return items, nil
+}
+
+func (b *deploymentBuilder) getPodLogs(
+	ctx context.Context,
+	inst *grafana_v1alpha1.LogsInstance,
+) ([]*grafana_v1alpha1.PodLogs, error) {
+	sel, err := b.getResourceSelector(
+		inst.Namespace,
+		inst.Spec.PodLogsNamespaceSelector,
+		inst.Spec.PodLogsSelector,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build pod logs resource selector: %w", err)
+	}
+	b.ResourceSelectors[resourcePodLogs] = append(b.ResourceSelectors[resourcePodLogs], sel)
+
+	var (
+		list        grafana_v1alpha1.PodLogsList
+		namespace   = namespaceFromSelector(sel)
+		listOptions = &client.ListOptions{LabelSelector: sel.Labels, Namespace: namespace}
+	)
+	if err := b.List(ctx, &list, listOptions); err != nil {
+		return nil, err
+	}
+
+	items := make([]*grafana_v1alpha1.PodLogs, 0, len(list.Items))
+	for _, item := range list.Items {
+		if match, err := b.matchNamespace(ctx, &item.ObjectMeta, sel); match {
+			items = append(items, item)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed getting namespace: %w", err)
+		}
+	}
+	return items, nil
+}
+
+func (b *deploymentBuilder) getTracesInstances(ctx context.Context) ([]*grafana_v1alpha1.TracesInstance, error) {
+	sel, err := b.getResourceSelector(
+		b.Agent.Namespace,
+		b.Agent.Spec.Traces.InstanceNamespaceSelector,
+		b.Agent.Spec.Traces.InstanceSelector,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build traces resource selector: %w", err)
+	}
+	b.ResourceSelectors[resourceTracesInstance] = append(b.ResourceSelectors[resourceTracesInstance], sel)
+
+	var (
+		list        grafana_v1alpha1.TracesInstanceList
+		namespace   = namespaceFromSelector(sel)
+		listOptions = &client.ListOptions{LabelSelector: sel.Labels, Namespace: namespace}
+	)
+	if err := b.List(ctx, &list, listOptions); err != nil {
+		return nil, err
+	}
+
+	items := make([]*grafana_v1alpha1.TracesInstance, 0, len(list.Items))
+	for _, item := range list.Items {
+		if match, err := b.matchNamespace(ctx, &item.ObjectMeta, sel); match {
+			items = append(items, item)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed getting namespace: %w", err)
+		}
+	}
+	return items, nil
+}
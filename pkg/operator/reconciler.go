@@ -86,6 +86,9 @@ func (r *reconciler) Reconcile(ctx context.Context, req controller.Request) (con
 		r.createSecrets,
 		r.createGoverningService,
 		r.createStatefulSets,
+		r.createTracesService,
+		r.createLogsConfigurationSecret,
+		r.createLogsDaemonSet,
 	}
 	for _, actor := range actors {
 		err := actor(ctx, l, deployment, secrets)
@@ -151,8 +154,42 @@ func (r *reconciler) createConfigurationSecret(
 	d config.Deployment,
 	s assets.SecretStore,
 ) error {
+	name := fmt.Sprintf("%s-config", d.Agent.Name)
+	return r.createAgentConfigSecret(ctx, l, d, name, func() (string, error) {
+		return d.BuildConfig(s)
+	})
+}
+
+// createLogsConfigurationSecret creates the Grafana Agent configuration for
+// the logs DaemonSet and stores it into a secret. No secret is created if
+// there are no logs instances.
+func (r *reconciler) createLogsConfigurationSecret(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	s assets.SecretStore,
+) error {
+	if len(d.Logs) == 0 {
+		return nil
+	}
 
-	rawConfig, err := d.BuildConfig(s)
+	name := fmt.Sprintf("%s-logs-config", d.Agent.Name)
+	return r.createAgentConfigSecret(ctx, l, d, name, func() (string, error) {
+		return d.BuildLogsConfig(s)
+	})
+}
+
+// createAgentConfigSecret stores the config generated by build into a secret
+// with the given name.
+func (r *reconciler) createAgentConfigSecret(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	name string,
+	build func() (string, error),
+) error {
+
+	rawConfig, err := build()
 
 	var jsonnetError jsonnet.RuntimeError
 	if errors.As(err, &jsonnetError) {
@@ -169,7 +206,7 @@ func (r *reconciler) createConfigurationSecret(
 	secret := core_v1.Secret{
 		ObjectMeta: v1.ObjectMeta{
 			Namespace: d.Agent.Namespace,
-			Name:      fmt.Sprintf("%s-config", d.Agent.Name),
+			Name:      name,
 			Labels:    r.config.Labels.Merge(managedByOperatorLabels),
 			OwnerReferences: []v1.OwnerReference{{
 				APIVersion:         d.Agent.APIVersion,
@@ -202,7 +239,7 @@ func (r *reconciler) createSecrets(
 
 	data := make(map[string][]byte)
 	for k, value := range s {
-		data[config.SanitizeLabelName(string(k))] = []byte(value)
+		data[config.SecretFileName(k)] = []byte(value)
 	}
 
 	secret := core_v1.Secret{
@@ -303,3 +340,82 @@ func (r *reconciler) createStatefulSets(
 
 	return nil
 }
+
+// createTracesService creates the Service which exposes the traces receivers
+// of the StatefulSet pods. The Service is deleted if there are no traces
+// instances.
+func (r *reconciler) createTracesService(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	s assets.SecretStore,
+) error {
+	name := fmt.Sprintf("%s-traces", d.Agent.Name)
+
+	if len(d.Traces) == 0 {
+		var svc core_v1.Service
+		err := r.Get(ctx, types.NamespacedName{Namespace: d.Agent.Namespace, Name: name}, &svc)
+		if k8s_errors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to get traces service: %w", err)
+		}
+
+		level.Info(l).Log("msg", "deleting traces service with no traces instances", "name", svc.Name)
+		if err := r.Client.Delete(ctx, &svc); err != nil && !k8s_errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete traces service %s: %w", svc.Name, err)
+		}
+		return nil
+	}
+
+	svc, err := generateTracesService(r.config, name, d)
+	if err != nil {
+		return fmt.Errorf("failed to generate traces service: %w", err)
+	}
+
+	level.Info(l).Log("msg", "reconciling traces service", "service", svc.Name)
+	err = clientutil.CreateOrUpdateService(ctx, r.Client, svc)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile traces service: %w", err)
+	}
+	return nil
+}
+
+// createLogsDaemonSet creates the Grafana Agent DaemonSet which collects logs.
+// The DaemonSet is deleted if there are no logs instances.
+func (r *reconciler) createLogsDaemonSet(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	s assets.SecretStore,
+) error {
+	name := fmt.Sprintf("%s-logs", d.Agent.Name)
+
+	if len(d.Logs) == 0 {
+		var ds apps_v1.DaemonSet
+		err := r.Get(ctx, types.NamespacedName{Namespace: d.Agent.Namespace, Name: name}, &ds)
+		if k8s_errors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to get logs daemonset: %w", err)
+		}
+
+		level.Info(l).Log("msg", "deleting logs daemonset with no logs instances", "name", ds.Name)
+		if err := r.Client.Delete(ctx, &ds); err != nil && !k8s_errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete logs daemonset %s: %w", ds.Name, err)
+		}
+		return nil
+	}
+
+	ds, err := generateLogsDaemonSet(r.config, name, d)
+	if err != nil {
+		return fmt.Errorf("failed to generate logs daemonset: %w", err)
+	}
+
+	level.Info(l).Log("msg", "reconciling logs daemonset", "daemonset", ds.Name)
+	err = clientutil.CreateOrUpdateDaemonSet(ctx, r.Client, ds)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile logs daemonset: %w", err)
+	}
+	return nil
+}
@@ -197,6 +197,83 @@ func generateStatefulSetSpec(
 		shards = *reqShards
 	}
 
+	walVolumeName := fmt.Sprintf("%s-wal", name)
+	if d.Agent.Spec.Storage != nil {
+		if d.Agent.Spec.Storage.VolumeClaimTemplate.Name != "" {
+			walVolumeName = d.Agent.Spec.Storage.VolumeClaimTemplate.Name
+		}
+	}
+
+	tracesPorts, err := generateTracesPorts(d)
+	if err != nil {
+		return nil, err
+	}
+
+	template, selector, err := generatePodTemplate(cfg, d, podTemplateOptions{
+		Name:             "grafana-agent",
+		ExtraPorts:       tracesPorts,
+		ConfigSecretName: fmt.Sprintf("%s-config", d.Agent.Name),
+		ExtraSelectorLabels: map[string]string{
+			shardLabelName: fmt.Sprintf("%d", shard),
+		},
+		ExtraVolumeMounts: []v1.VolumeMount{{
+			Name:      walVolumeName,
+			ReadOnly:  false,
+			MountPath: "/var/lib/grafana-agent/data",
+		}},
+		ExtraEnvVars: []v1.EnvVar{
+			{
+				Name:  "SHARD",
+				Value: fmt.Sprintf("%d", shard),
+			},
+			{
+				Name:  "SHARDS",
+				Value: fmt.Sprintf("%d", shards),
+			},
+		},
+		ExtraReloaderArgs: []string{"--statefulset-ordinal-from-envvar=POD_NAME"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &apps_v1.StatefulSetSpec{
+		ServiceName:         governingServiceName,
+		Replicas:            d.Agent.Spec.Prometheus.Replicas,
+		PodManagementPolicy: apps_v1.ParallelPodManagement,
+		UpdateStrategy: apps_v1.StatefulSetUpdateStrategy{
+			Type: apps_v1.RollingUpdateStatefulSetStrategyType,
+		},
+		Selector: selector,
+		Template: template,
+	}, nil
+}
+
+// podTemplateOptions customizes the pod template shared by the StatefulSets
+// and the logs DaemonSet.
+type podTemplateOptions struct {
+	// Name is used for the app.kubernetes.io/name label of pods.
+	Name string
+	// ConfigSecretName is the name of the Secret holding the agent.yml to run.
+	ConfigSecretName string
+
+	ExtraSelectorLabels map[string]string
+	ExtraPorts          []v1.ContainerPort
+	ExtraVolumes        []v1.Volume
+	ExtraVolumeMounts   []v1.VolumeMount
+	ExtraEnvVars        []v1.EnvVar
+	ExtraReloaderArgs   []string
+
+	// Privileged runs the grafana-agent container as a privileged root user.
+	Privileged bool
+}
+
+func generatePodTemplate(
+	cfg *Config,
+	d config.Deployment,
+	opts podTemplateOptions,
+) (v1.PodTemplateSpec, *meta_v1.LabelSelector, error) {
+
 	terminationGracePeriodSeconds := int64(4800)
 
 	imagePath := fmt.Sprintf("%s:%s", DefaultAgentBaseImage, d.Agent.Spec.Version)
@@ -218,13 +295,14 @@ func generateStatefulSetSpec(
 		ContainerPort: 8080,
 		Protocol:      v1.ProtocolTCP,
 	}}
+	ports = append(ports, opts.ExtraPorts...)
 
 	volumes := []v1.Volume{
 		{
 			Name: "config",
 			VolumeSource: v1.VolumeSource{
 				Secret: &v1.SecretVolumeSource{
-					SecretName: fmt.Sprintf("%s-config", d.Agent.Name),
+					SecretName: opts.ConfigSecretName,
 				},
 			},
 		},
@@ -248,13 +326,7 @@ func generateStatefulSetSpec(
 			},
 		},
 	}
-
-	walVolumeName := fmt.Sprintf("%s-wal", name)
-	if d.Agent.Spec.Storage != nil {
-		if d.Agent.Spec.Storage.VolumeClaimTemplate.Name != "" {
-			walVolumeName = d.Agent.Spec.Storage.VolumeClaimTemplate.Name
-		}
-	}
+	volumes = append(volumes, opts.ExtraVolumes...)
 
 	volumeMounts := []v1.VolumeMount{
 		{
@@ -266,17 +338,13 @@ func generateStatefulSetSpec(
 			Name:      "config-out",
 			MountPath: "/var/lib/grafana-agent/config",
 		},
-		{
-			Name:      walVolumeName,
-			ReadOnly:  false,
-			MountPath: "/var/lib/grafana-agent/data",
-		},
 		{
 			Name:      "secrets",
 			ReadOnly:  true,
 			MountPath: "/var/lib/grafana-agent/secrets",
 		},
 	}
+	volumeMounts = append(volumeMounts, opts.ExtraVolumeMounts...)
 	volumeMounts = append(volumeMounts, d.Agent.Spec.VolumeMounts...)
 
 	for _, s := range d.Agent.Spec.Secrets {
@@ -312,14 +380,16 @@ func generateStatefulSetSpec(
 	podAnnotations := map[string]string{}
 	podLabels := map[string]string{}
 	podSelectorLabels := map[string]string{
-		"app.kubernetes.io/name":       "grafana-agent",
+		"app.kubernetes.io/name":       opts.Name,
 		"app.kubernetes.io/version":    build.Version,
 		"app.kubernetes.io/managed-by": "grafana-agent-operator",
 		"app.kubernetes.io/instance":   d.Agent.Name,
 		"grafana-agent":                d.Agent.Name,
-		shardLabelName:                 fmt.Sprintf("%d", shard),
 		agentNameLabelName:             d.Agent.Name,
 	}
+	for k, v := range opts.ExtraSelectorLabels {
+		podSelectorLabels[k] = v
+	}
 	if d.Agent.Spec.PodMetadata != nil {
 		for k, v := range d.Agent.Spec.PodMetadata.Labels {
 			podLabels[k] = v
@@ -346,14 +416,32 @@ func generateStatefulSetSpec(
 				FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"},
 			},
 		},
-		{
-			Name:  "SHARD",
-			Value: fmt.Sprintf("%d", shard),
-		},
-		{
-			Name:  "SHARDS",
-			Value: fmt.Sprintf("%d", shards),
-		},
+	}
+	envVars = append(envVars, opts.ExtraEnvVars...)
+
+	reloaderArgs := []string{
+		"--config-file=/var/lib/grafana-agent/config-in/agent.yml",
+		"--config-envsubst-file=/var/lib/grafana-agent/config/agent.yml",
+
+		"--watch-interval=1m",
+	}
+	reloaderArgs = append(reloaderArgs, opts.ExtraReloaderArgs...)
+	reloaderArgs = append(reloaderArgs,
+		// Use specifically the reload-port for reloading, since the primary
+		// server can shut down in between reloads.
+		"--reload-url=http://127.0.0.1:8081/-/reload",
+	)
+
+	var securityContext *v1.SecurityContext
+	if opts.Privileged {
+		var (
+			privileged = true
+			rootUser   = int64(0)
+		)
+		securityContext = &v1.SecurityContext{
+			Privileged: &privileged,
+			RunAsUser:  &rootUser,
+		}
 	}
 
 	operatorContainers := []v1.Container{
@@ -362,17 +450,7 @@ func generateStatefulSetSpec(
 			Image:        "quay.io/prometheus-operator/prometheus-config-reloader:v0.47.0",
 			VolumeMounts: volumeMounts,
 			Env:          envVars,
-			Args: []string{
-				"--config-file=/var/lib/grafana-agent/config-in/agent.yml",
-				"--config-envsubst-file=/var/lib/grafana-agent/config/agent.yml",
-
-				"--watch-interval=1m",
-				"--statefulset-ordinal-from-envvar=POD_NAME",
-
-				// Use specifically the reload-port for reloading, since the primary
-				// server can shut down in between reloads.
-				"--reload-url=http://127.0.0.1:8081/-/reload",
-			},
+			Args:         reloaderArgs,
 		},
 		{
 			Name:         "grafana-agent",
@@ -393,43 +471,34 @@ func generateStatefulSetSpec(
 				FailureThreshold: 120, // Allow up to 10m on startup for data recovery
 			},
 			Resources:                d.Agent.Spec.Resources,
+			SecurityContext:          securityContext,
 			TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
 		},
 	}
 
 	containers, err := clientutil.MergePatchContainers(operatorContainers, d.Agent.Spec.Containers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to merge containers spec: %w", err)
+		return v1.PodTemplateSpec{}, nil, fmt.Errorf("failed to merge containers spec: %w", err)
 	}
 
-	return &apps_v1.StatefulSetSpec{
-		ServiceName:         governingServiceName,
-		Replicas:            d.Agent.Spec.Prometheus.Replicas,
-		PodManagementPolicy: apps_v1.ParallelPodManagement,
-		UpdateStrategy: apps_v1.StatefulSetUpdateStrategy{
-			Type: apps_v1.RollingUpdateStatefulSetStrategyType,
+	template := v1.PodTemplateSpec{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Labels:      finalLabels,
+			Annotations: podAnnotations,
 		},
-		Selector: &meta_v1.LabelSelector{
-			MatchLabels: finalSelectorLabels,
+		Spec: v1.PodSpec{
+			Containers:                    containers,
+			InitContainers:                d.Agent.Spec.InitContainers,
+			SecurityContext:               d.Agent.Spec.SecurityContext,
+			ServiceAccountName:            d.Agent.Spec.ServiceAccountName,
+			NodeSelector:                  d.Agent.Spec.NodeSelector,
+			PriorityClassName:             d.Agent.Spec.PriorityClassName,
+			TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
+			Volumes:                       volumes,
+			Tolerations:                   d.Agent.Spec.Tolerations,
+			Affinity:                      d.Agent.Spec.Affinity,
+			TopologySpreadConstraints:     d.Agent.Spec.TopologySpreadConstraints,
 		},
-		Template: v1.PodTemplateSpec{
-			ObjectMeta: meta_v1.ObjectMeta{
-				Labels:      finalLabels,
-				Annotations: podAnnotations,
-			},
-			Spec: v1.PodSpec{
-				Containers:                    containers,
-				InitContainers:                d.Agent.Spec.InitContainers,
-				SecurityContext:               d.Agent.Spec.SecurityContext,
-				ServiceAccountName:            d.Agent.Spec.ServiceAccountName,
-				NodeSelector:                  d.Agent.Spec.NodeSelector,
-				PriorityClassName:             d.Agent.Spec.PriorityClassName,
-				TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
-				Volumes:                       volumes,
-				Tolerations:                   d.Agent.Spec.Tolerations,
-				Affinity:                      d.Agent.Spec.Affinity,
-				TopologySpreadConstraints:     d.Agent.Spec.TopologySpreadConstraints,
-			},
-		},
-	}, nil
+	}
+	return template, &meta_v1.LabelSelector{MatchLabels: finalSelectorLabels}, nil
 }
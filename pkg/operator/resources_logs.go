@@ -0,0 +1,133 @@
+package operator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/grafana/agent/pkg/operator/config"
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// generateLogsDaemonSet generates the DaemonSet which runs the logs subsystem
+// of the Agent on every node. Pods only tail logs from the node they run on.
+func generateLogsDaemonSet(
+	cfg *Config,
+	name string,
+	d config.Deployment,
+) (*apps_v1.DaemonSet, error) {
+	d = *d.DeepCopy()
+
+	if d.Agent.Spec.PortName == "" {
+		d.Agent.Spec.PortName = defaultPortName
+	}
+	if d.Agent.Spec.Resources.Requests == nil {
+		d.Agent.Spec.Resources.Requests = v1.ResourceList{}
+	}
+
+	hostPathDirectoryOrCreate := v1.HostPathDirectoryOrCreate
+
+	template, selector, err := generatePodTemplate(cfg, d, podTemplateOptions{
+		Name:             "grafana-agent-logs",
+		ConfigSecretName: fmt.Sprintf("%s-logs-config", d.Agent.Name),
+		ExtraVolumes: []v1.Volume{
+			{
+				Name: "varlog",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{Path: "/var/log"},
+				},
+			},
+			{
+				// Needed for reading Docker logs from the /var/log/pods/ route.
+				Name: "dockerlogs",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{Path: "/var/lib/docker/containers"},
+				},
+			},
+			{
+				// Positions are stored on the host so restarted pods continue
+				// tailing where they left off.
+				Name: "data",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{
+						Path: path.Join("/var/lib/grafana-agent", d.Agent.Namespace, d.Agent.Name, "data"),
+						Type: &hostPathDirectoryOrCreate,
+					},
+				},
+			},
+		},
+		ExtraVolumeMounts: []v1.VolumeMount{
+			{
+				Name:      "varlog",
+				ReadOnly:  true,
+				MountPath: "/var/log",
+			},
+			{
+				Name:      "dockerlogs",
+				ReadOnly:  true,
+				MountPath: "/var/lib/docker/containers",
+			},
+			{
+				Name:      "data",
+				MountPath: "/var/lib/grafana-agent/data",
+			},
+		},
+		ExtraEnvVars: []v1.EnvVar{{
+			// Targets are only tailed when their __host__ label matches the
+			// HOSTNAME environment variable.
+			Name: "HOSTNAME",
+			ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+			},
+		}},
+		Privileged: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.Agent.Spec.ImagePullSecrets) > 0 {
+		template.Spec.ImagePullSecrets = d.Agent.Spec.ImagePullSecrets
+	}
+	template.Spec.Volumes = append(template.Spec.Volumes, d.Agent.Spec.Volumes...)
+
+	// Don't transfer any kubectl annotations to the DaemonSet so it doesn't
+	// get pruned by kubectl.
+	annotations := make(map[string]string)
+	for k, v := range d.Agent.ObjectMeta.Annotations {
+		if !strings.HasPrefix(k, "kubectl.kubernetes.io/") {
+			annotations[k] = v
+		}
+	}
+
+	labels := make(map[string]string)
+	for k, v := range template.Labels {
+		labels[k] = v
+	}
+	labels[agentNameLabelName] = d.Agent.Name
+
+	boolTrue := true
+
+	return &apps_v1.DaemonSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        name,
+			Namespace:   d.Agent.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+			OwnerReferences: []meta_v1.OwnerReference{{
+				APIVersion:         d.Agent.APIVersion,
+				Kind:               d.Agent.Kind,
+				BlockOwnerDeletion: &boolTrue,
+				Controller:         &boolTrue,
+				Name:               d.Agent.Name,
+				UID:                d.Agent.UID,
+			}},
+		},
+		Spec: apps_v1.DaemonSetSpec{
+			Selector: selector,
+			Template: template,
+		},
+	}, nil
+}
@@ -0,0 +1,153 @@
+package operator
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	grafana "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/config"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// receiverEndpoint is an enabled traces receiver protocol along with the
+// default endpoint it listens on.
+type receiverEndpoint struct {
+	Spec            *grafana.TracesReceiverEndpointSpec
+	DefaultEndpoint string
+	Protocol        v1.Protocol
+}
+
+// tracesReceiverEndpoints returns all enabled receiver protocols for a traces
+// instance. Defaults match the defaults used by the OpenTelemetry Collector.
+func tracesReceiverEndpoints(inst *grafana.TracesInstance) []receiverEndpoint {
+	var (
+		res       []receiverEndpoint
+		receivers = inst.Spec.Receivers
+	)
+
+	add := func(spec *grafana.TracesReceiverEndpointSpec, defaultEndpoint string, protocol v1.Protocol) {
+		if spec == nil {
+			return
+		}
+		res = append(res, receiverEndpoint{
+			Spec:            spec,
+			DefaultEndpoint: defaultEndpoint,
+			Protocol:        protocol,
+		})
+	}
+
+	if otlp := receivers.OTLP; otlp != nil {
+		add(otlp.GRPC, "0.0.0.0:4317", v1.ProtocolTCP)
+		add(otlp.HTTP, "0.0.0.0:55681", v1.ProtocolTCP)
+	}
+	if jaeger := receivers.Jaeger; jaeger != nil {
+		add(jaeger.GRPC, "0.0.0.0:14250", v1.ProtocolTCP)
+		add(jaeger.ThriftHTTP, "0.0.0.0:14268", v1.ProtocolTCP)
+		add(jaeger.ThriftCompact, "0.0.0.0:6831", v1.ProtocolUDP)
+		add(jaeger.ThriftBinary, "0.0.0.0:6832", v1.ProtocolUDP)
+	}
+	add(receivers.Zipkin, "0.0.0.0:9411", v1.ProtocolTCP)
+	add(receivers.OpenCensus, "0.0.0.0:55678", v1.ProtocolTCP)
+
+	return res
+}
+
+// generateTracesPorts returns the container ports used by the receivers of
+// all traces instances in d. Ports are deduplicated and sorted so the result
+// is stable across reconciles.
+func generateTracesPorts(d config.Deployment) ([]v1.ContainerPort, error) {
+	type portKey struct {
+		Port     int32
+		Protocol v1.Protocol
+	}
+	seen := make(map[portKey]struct{})
+
+	var res []v1.ContainerPort
+	for _, inst := range d.Traces {
+		for _, ep := range tracesReceiverEndpoints(inst) {
+			endpoint := ep.Spec.Endpoint
+			if endpoint == "" {
+				endpoint = ep.DefaultEndpoint
+			}
+
+			_, portText, err := net.SplitHostPort(endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("invalid receiver endpoint %q in traces instance %s/%s: %w", endpoint, inst.Namespace, inst.Name, err)
+			}
+			port, err := strconv.ParseInt(portText, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid receiver port %q in traces instance %s/%s: %w", portText, inst.Namespace, inst.Name, err)
+			}
+
+			key := portKey{Port: int32(port), Protocol: ep.Protocol}
+			if _, found := seen[key]; found {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			name := fmt.Sprintf("traces-%d", port)
+			if ep.Protocol == v1.ProtocolUDP {
+				name += "-udp"
+			}
+
+			res = append(res, v1.ContainerPort{
+				Name:          name,
+				ContainerPort: int32(port),
+				Protocol:      ep.Protocol,
+			})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// generateTracesService generates a Service which exposes the traces
+// receivers of all StatefulSet pods of the GrafanaAgent.
+func generateTracesService(cfg *Config, name string, d config.Deployment) (*v1.Service, error) {
+	ports, err := generateTracesPorts(d)
+	if err != nil {
+		return nil, err
+	}
+
+	servicePorts := make([]v1.ServicePort, 0, len(ports))
+	for _, p := range ports {
+		servicePorts = append(servicePorts, v1.ServicePort{
+			Name:       p.Name,
+			Port:       p.ContainerPort,
+			Protocol:   p.Protocol,
+			TargetPort: intstr.FromString(p.Name),
+		})
+	}
+
+	boolTrue := true
+
+	return &v1.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: d.Agent.Namespace,
+			OwnerReferences: []meta_v1.OwnerReference{{
+				APIVersion:         d.Agent.APIVersion,
+				Kind:               d.Agent.Kind,
+				Name:               d.Agent.Name,
+				BlockOwnerDeletion: &boolTrue,
+				Controller:         &boolTrue,
+				UID:                d.Agent.UID,
+			}},
+			Labels: cfg.Labels.Merge(map[string]string{
+				agentNameLabelName: d.Agent.Name,
+			}),
+		},
+		Spec: v1.ServiceSpec{
+			Ports: servicePorts,
+			Selector: map[string]string{
+				"app.kubernetes.io/name": "grafana-agent",
+				agentNameLabelName:       d.Agent.Name,
+			},
+		},
+	}, nil
+}
@@ -18,6 +18,9 @@ const (
 	resourcePodMonitor
 	resourceProbe
 	resourceSecret
+	resourceLogsInstance
+	resourcePodLogs
+	resourceTracesInstance
 )
 
 // secondaryResources is the list of valid secondaryResources.
@@ -27,6 +30,9 @@ var secondaryResources = []secondaryResource{
 	resourcePodMonitor,
 	resourceProbe,
 	resourceSecret,
+	resourceLogsInstance,
+	resourcePodLogs,
+	resourceTracesInstance,
 }
 
 // eventHandlers is a set of EnqueueRequestForSelector event handlers, one per
@@ -0,0 +1,54 @@
+package prom
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/grafana/agent/pkg/agentproto"
+)
+
+// ListInstances implements the ListInstances method of
+// agentproto.AdminServiceServer.
+func (a *Agent) ListInstances(context.Context, *agentproto.ListInstancesRequest) (*agentproto.ListInstancesResponse, error) {
+	return &agentproto.ListInstancesResponse{Instances: a.instanceNames()}, nil
+}
+
+// ListTargets implements the ListTargets method of
+// agentproto.AdminServiceServer.
+func (a *Agent) ListTargets(context.Context, *agentproto.ListTargetsRequest) (*agentproto.ListTargetsResponse, error) {
+	targets := a.listTargets()
+
+	resp := &agentproto.ListTargetsResponse{
+		Targets: make([]*agentproto.Target, 0, len(targets)),
+	}
+	for _, t := range targets {
+		var lastScrape int64
+		if !t.LastScrape.IsZero() {
+			lastScrape = t.LastScrape.UnixNano() / 1e6
+		}
+
+		resp.Targets = append(resp.Targets, &agentproto.Target{
+			Instance:              t.InstanceName,
+			TargetGroup:           t.TargetGroup,
+			Endpoint:              t.Endpoint,
+			State:                 t.State,
+			Labels:                t.Labels.Map(),
+			DiscoveredLabels:      t.DiscoveredLabels.Map(),
+			LastScrapeTimestampMs: lastScrape,
+			ScrapeDurationMs:      t.ScrapeDuration,
+			ScrapeError:           t.ScrapeError,
+		})
+	}
+	return resp, nil
+}
+
+// PutConfig implements the PutConfig method of agentproto.AdminServiceServer.
+func (a *Agent) PutConfig(ctx context.Context, req *agentproto.PutConfigRequest) (*agentproto.PutConfigResponse, error) {
+	return a.cluster.PutConfig(ctx, req)
+}
+
+// DeleteConfig implements the DeleteConfig method of
+// agentproto.AdminServiceServer.
+func (a *Agent) DeleteConfig(ctx context.Context, req *agentproto.DeleteConfigRequest) (*empty.Empty, error) {
+	return a.cluster.DeleteConfig(ctx, req)
+}
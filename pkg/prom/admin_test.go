@@ -0,0 +1,103 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/agentproto"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAgent_ListTargets(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+
+	tgt := scrape.NewTarget(labels.FromMap(map[string]string{
+		model.JobLabel:         "job",
+		model.InstanceLabel:    "instance",
+		model.SchemeLabel:      "http",
+		model.AddressLabel:     "localhost:12345",
+		model.MetricsPathLabel: "/metrics",
+	}), labels.FromMap(map[string]string{
+		"__discovered__": "yes",
+	}), nil)
+
+	lastScrape := time.Date(1994, time.January, 12, 0, 0, 0, 0, time.UTC)
+	tgt.Report(lastScrape, time.Minute, fmt.Errorf("something went wrong"))
+
+	mockManager := &instance.MockManager{
+		ListInstancesFunc: func() map[string]instance.ManagedInstance {
+			return map[string]instance.ManagedInstance{
+				"test_instance": &mockInstanceScrape{
+					tgts: map[string][]*scrape.Target{"group_a": {tgt}},
+				},
+			}
+		},
+		ListConfigsFunc:  func() map[string]instance.Config { return nil },
+		ApplyConfigFunc:  func(_ instance.Config) error { return nil },
+		DeleteConfigFunc: func(name string) error { return nil },
+		StopFunc:         func() {},
+	}
+	a.mm, err = instance.NewModalManager(prometheus.NewRegistry(), a.logger, mockManager, instance.ModeDistinct)
+	require.NoError(t, err)
+
+	resp, err := a.ListTargets(context.Background(), &agentproto.ListTargetsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []*agentproto.Target{{
+		Instance:    "test_instance",
+		TargetGroup: "group_a",
+		Endpoint:    "http://localhost:12345/metrics",
+		State:       "down",
+		Labels: map[string]string{
+			"instance": "instance",
+			"job":      "job",
+		},
+		DiscoveredLabels: map[string]string{
+			"__discovered__": "yes",
+		},
+		LastScrapeTimestampMs: lastScrape.UnixNano() / 1e6,
+		ScrapeDurationMs:      60000,
+		ScrapeError:           "something went wrong",
+	}}, resp.Targets)
+}
+
+func TestAgent_PutConfig(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+	defer a.Stop()
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := a.PutConfig(context.Background(), &agentproto.PutConfigRequest{
+			Name:   "test",
+			Config: "not_a_field: true",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("scraping service disabled", func(t *testing.T) {
+		_, err := a.PutConfig(context.Background(), &agentproto.PutConfigRequest{
+			Name:   "test",
+			Config: "scrape_configs: []",
+		})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = a.DeleteConfig(context.Background(), &agentproto.DeleteConfigRequest{Name: "test"})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
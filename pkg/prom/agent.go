@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/agent/pkg/prom/cluster"
 	"github.com/grafana/agent/pkg/prom/cluster/client"
 	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/prom/monitors"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
@@ -28,21 +29,29 @@ var DefaultConfig = Config{
 	WALCleanupPeriod:       DefaultCleanupPeriod,
 	ServiceConfig:          cluster.DefaultConfig,
 	ServiceClientConfig:    client.DefaultConfig,
+	TargetSharding:         cluster.DefaultShardingConfig,
 	InstanceMode:           instance.DefaultMode,
+	KubernetesMonitors:     monitors.DefaultConfig,
 }
 
 // Config defines the configuration for the entire set of Prometheus client
 // instances, along with a global configuration.
 type Config struct {
-	Global                 instance.GlobalConfig `yaml:"global,omitempty"`
-	WALDir                 string                `yaml:"wal_directory,omitempty"`
-	WALCleanupAge          time.Duration         `yaml:"wal_cleanup_age,omitempty"`
-	WALCleanupPeriod       time.Duration         `yaml:"wal_cleanup_period,omitempty"`
-	ServiceConfig          cluster.Config        `yaml:"scraping_service,omitempty"`
-	ServiceClientConfig    client.Config         `yaml:"scraping_service_client,omitempty"`
-	Configs                []instance.Config     `yaml:"configs,omitempty,omitempty"`
-	InstanceRestartBackoff time.Duration         `yaml:"instance_restart_backoff,omitempty"`
-	InstanceMode           instance.Mode         `yaml:"instance_mode,omitempty"`
+	Global                 instance.GlobalConfig  `yaml:"global,omitempty"`
+	WALDir                 string                 `yaml:"wal_directory,omitempty"`
+	WALCleanupAge          time.Duration          `yaml:"wal_cleanup_age,omitempty"`
+	WALCleanupPeriod       time.Duration          `yaml:"wal_cleanup_period,omitempty"`
+	ServiceConfig          cluster.Config         `yaml:"scraping_service,omitempty"`
+	ServiceClientConfig    client.Config          `yaml:"scraping_service_client,omitempty"`
+	TargetSharding         cluster.ShardingConfig `yaml:"target_sharding,omitempty"`
+	Configs                []instance.Config      `yaml:"configs,omitempty,omitempty"`
+	InstanceRestartBackoff time.Duration          `yaml:"instance_restart_backoff,omitempty"`
+	InstanceMode           instance.Mode          `yaml:"instance_mode,omitempty"`
+
+	// KubernetesMonitors generates an instance from ServiceMonitors,
+	// PodMonitors, and Probes. It is run by the entrypoint rather than the
+	// Agent.
+	KubernetesMonitors monitors.Config `yaml:"kubernetes_monitors,omitempty"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -61,7 +70,7 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // ApplyDefaults applies default values to the Config and validates it.
 func (c *Config) ApplyDefaults() error {
-	needWAL := len(c.Configs) > 0 || c.ServiceConfig.Enabled
+	needWAL := len(c.Configs) > 0 || c.ServiceConfig.Enabled || c.KubernetesMonitors.Enabled
 	if needWAL && c.WALDir == "" {
 		return errors.New("no wal_directory configured")
 	}
@@ -70,6 +79,10 @@ func (c *Config) ApplyDefaults() error {
 		return errors.New("cannot use configs when scraping_service mode is enabled")
 	}
 
+	if c.ServiceConfig.Enabled && c.TargetSharding.Enabled {
+		return errors.New("cannot use target_sharding when scraping_service mode is enabled")
+	}
+
 	usedNames := map[string]struct{}{}
 
 	for i := range c.Configs {
@@ -92,6 +105,15 @@ func (c *Config) ApplyDefaults() error {
 		usedNames[name] = struct{}{}
 	}
 
+	if err := c.KubernetesMonitors.Validate(); err != nil {
+		return fmt.Errorf("invalid kubernetes_monitors: %w", err)
+	}
+	if name := c.KubernetesMonitors.InstanceName; c.KubernetesMonitors.Enabled {
+		if _, ok := usedNames[name]; ok {
+			return fmt.Errorf("kubernetes_monitors instance_name %s is already used by an instance", name)
+		}
+	}
+
 	return nil
 }
 
@@ -104,6 +126,7 @@ func (c *Config) RegisterFlags(f *flag.FlagSet) {
 
 	c.ServiceConfig.RegisterFlagsWithPrefix("prometheus.service.", f)
 	c.ServiceClientConfig.RegisterFlags(f)
+	c.TargetSharding.RegisterFlagsWithPrefix("prometheus.target-sharding.", f)
 }
 
 // Agent is an agent for collecting Prometheus metrics. It acts as a
@@ -126,6 +149,7 @@ type Agent struct {
 	instanceFactory instanceFactory
 
 	cluster *cluster.Cluster
+	sharder *cluster.Sharder
 
 	stopped  bool
 	stopOnce sync.Once
@@ -160,6 +184,11 @@ func newAgent(reg prometheus.Registerer, cfg Config, logger log.Logger, fact ins
 		return nil, err
 	}
 
+	a.sharder, err = cluster.NewSharder(reg, a.logger, cfg.TargetSharding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create target sharder: %w", err)
+	}
+
 	if err := a.ApplyConfig(cfg); err != nil {
 		return nil, err
 	}
@@ -182,7 +211,14 @@ func (a *Agent) newInstance(c instance.Config) (instance.ManagedInstance, error)
 		instanceLabel: c.Name,
 	}, a.reg)
 
-	return a.instanceFactory(reg, c, a.cfg.WALDir, a.logger)
+	// Only pass the sharder when target sharding is enabled so instances
+	// don't filter their targets at all otherwise.
+	var sharder instance.TargetSharder
+	if a.cfg.TargetSharding.Enabled {
+		sharder = a.sharder
+	}
+
+	return a.instanceFactory(reg, c, a.cfg.WALDir, sharder, a.logger)
 }
 
 // Validate will validate the incoming Config and mutate it to apply defaults.
@@ -216,7 +252,8 @@ func (a *Agent) ApplyConfig(cfg Config) error {
 	// 2. Basic manager
 	// 3. Modal Manager
 	// 4. Cluster
-	// 5. Local configs
+	// 5. Target sharder
+	// 6. Local configs
 
 	if a.cleaner != nil {
 		a.cleaner.Stop()
@@ -241,12 +278,19 @@ func (a *Agent) ApplyConfig(cfg Config) error {
 		return fmt.Errorf("failed to apply cluster config: %w", err)
 	}
 
+	if err := a.sharder.ApplyConfig(cfg.TargetSharding); err != nil {
+		return fmt.Errorf("failed to apply target sharding config: %w", err)
+	}
+
 	// Queue an actor in the background to sync the instances. This is required
 	// because creating both this function and newInstance grab the mutex.
+	// Instances are given the sharder when they're created, so they must be
+	// recreated when target sharding is toggled.
 	oldConfig := a.cfg
+	recreate := oldConfig.TargetSharding.Enabled != cfg.TargetSharding.Enabled
 
 	a.actor <- func() {
-		a.syncInstances(oldConfig, cfg)
+		a.syncInstances(oldConfig, cfg, recreate)
 	}
 
 	a.cfg = cfg
@@ -255,8 +299,18 @@ func (a *Agent) ApplyConfig(cfg Config) error {
 
 // syncInstances syncs the state of the instance manager to newConfig by
 // applying all configs from newConfig and deleting any configs from oldConfig
-// that are not in newConfig.
-func (a *Agent) syncInstances(oldConfig, newConfig Config) {
+// that are not in newConfig. If recreate is true, all configs from oldConfig
+// are deleted first so every instance is recreated.
+func (a *Agent) syncInstances(oldConfig, newConfig Config, recreate bool) {
+	if recreate {
+		for _, oc := range oldConfig.Configs {
+			if err := a.mm.DeleteConfig(oc.Name); err != nil {
+				level.Error(a.logger).Log("msg", "failed to delete config for recreating", "name", oc.Name, "err", err)
+			}
+		}
+		oldConfig.Configs = nil
+	}
+
 	// Apply the new configs
 	for _, c := range newConfig.Configs {
 		if err := a.mm.ApplyConfig(c); err != nil {
@@ -313,6 +367,10 @@ func (a *Agent) Stop() {
 
 	a.cluster.Stop()
 
+	if err := a.sharder.Stop(); err != nil {
+		level.Warn(a.logger).Log("msg", "failed to stop target sharder", "err", err)
+	}
+
 	a.cleaner.Stop()
 
 	// Only need to stop the ModalManager, which will passthrough everything to the
@@ -322,8 +380,8 @@ func (a *Agent) Stop() {
 	a.stopped = true
 }
 
-type instanceFactory = func(reg prometheus.Registerer, cfg instance.Config, walDir string, logger log.Logger) (instance.ManagedInstance, error)
+type instanceFactory = func(reg prometheus.Registerer, cfg instance.Config, walDir string, sharder instance.TargetSharder, logger log.Logger) (instance.ManagedInstance, error)
 
-func defaultInstanceFactory(reg prometheus.Registerer, cfg instance.Config, walDir string, logger log.Logger) (instance.ManagedInstance, error) {
-	return instance.New(reg, cfg, walDir, logger)
+func defaultInstanceFactory(reg prometheus.Registerer, cfg instance.Config, walDir string, sharder instance.TargetSharder, logger log.Logger) (instance.ManagedInstance, error) {
+	return instance.New(reg, cfg, walDir, sharder, logger)
 }
@@ -217,6 +217,26 @@ func TestAgent_NormalInstanceExits(t *testing.T) {
 	}
 }
 
+func TestAgent_TargetShardingDisabled(t *testing.T) {
+	cfg := Config{
+		WALDir:                 "/tmp/wal",
+		Configs:                []instance.Config{makeInstanceConfig("instance_a")},
+		InstanceRestartBackoff: time.Duration(0),
+		InstanceMode:           instance.ModeDistinct,
+	}
+
+	fact := newFakeInstanceFactory()
+
+	a, err := newAgent(prometheus.NewRegistry(), cfg, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+	defer a.Stop()
+
+	test.Poll(t, time.Second*30, int64(1), func() interface{} {
+		return fact.created.Load()
+	})
+	require.Nil(t, fact.Mocks()[0].sharder, "instances should not get a sharder when target sharding is disabled")
+}
+
 func TestAgent_Stop(t *testing.T) {
 	// Lanch two instances
 	cfg := Config{
@@ -251,7 +271,8 @@ func TestAgent_Stop(t *testing.T) {
 }
 
 type fakeInstance struct {
-	cfg instance.Config
+	cfg     instance.Config
+	sharder instance.TargetSharder
 
 	err          chan error
 	startedCount *atomic.Int64
@@ -306,7 +327,7 @@ func (f *fakeInstanceFactory) Mocks() []*fakeInstance {
 	return f.mocks
 }
 
-func (f *fakeInstanceFactory) factory(_ prometheus.Registerer, cfg instance.Config, _ string, _ log.Logger) (instance.ManagedInstance, error) {
+func (f *fakeInstanceFactory) factory(_ prometheus.Registerer, cfg instance.Config, _ string, sharder instance.TargetSharder, _ log.Logger) (instance.ManagedInstance, error) {
 	f.created.Add(1)
 
 	f.mut.Lock()
@@ -314,6 +335,7 @@ func (f *fakeInstanceFactory) factory(_ prometheus.Registerer, cfg instance.Conf
 
 	inst := &fakeInstance{
 		cfg:          cfg,
+		sharder:      sharder,
 		running:      atomic.NewBool(false),
 		startedCount: atomic.NewInt64(0),
 		err:          make(chan error),
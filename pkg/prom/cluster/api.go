@@ -0,0 +1,75 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/prom/cluster/configapi"
+	"github.com/grafana/agent/pkg/prom/instance/configstore"
+)
+
+// OwnershipHandler returns the agents in the cluster along with the
+// configurations each of them owns.
+func (c *Cluster) OwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := c.store.List(r.Context())
+	if errors.Is(err, configstore.ErrNotConnected) {
+		c.writeError(w, http.StatusNotFound, fmt.Errorf("no config store running"))
+		return
+	} else if err != nil {
+		c.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list configs: %w", err))
+		return
+	}
+
+	resp, err := c.node.Ownership(keys)
+	if errors.Is(err, errNodeDisabled) {
+		c.writeError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		c.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get ownership: %w", err))
+		return
+	}
+	c.writeResponse(w, http.StatusOK, resp)
+}
+
+// ReshardHandler informs every agent in the cluster to reshard, rebalancing
+// configurations across agents.
+func (c *Cluster) ReshardHandler(w http.ResponseWriter, r *http.Request) {
+	err := c.node.ReshardCluster(r.Context())
+	if errors.Is(err, errNodeDisabled) {
+		c.writeError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		c.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to reshard cluster: %w", err))
+		return
+	}
+	c.writeResponse(w, http.StatusOK, nil)
+}
+
+// DrainHandler moves all configurations owned by the local agent to other
+// agents in the cluster. The agent won't own any configurations until it's
+// restarted.
+func (c *Cluster) DrainHandler(w http.ResponseWriter, r *http.Request) {
+	err := c.node.Drain(r.Context())
+	if errors.Is(err, errNodeDisabled) {
+		c.writeError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		c.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	c.writeResponse(w, http.StatusOK, nil)
+}
+
+func (c *Cluster) writeResponse(w http.ResponseWriter, statusCode int, resp interface{}) {
+	if err := configapi.WriteResponse(w, statusCode, resp); err != nil {
+		level.Error(c.log).Log("msg", "failed to write response", "err", err)
+	}
+}
+
+func (c *Cluster) writeError(w http.ResponseWriter, statusCode int, err error) {
+	if err := configapi.WriteError(w, statusCode, err); err != nil {
+		level.Error(c.log).Log("msg", "failed to write response", "err", err)
+	}
+}
@@ -2,7 +2,9 @@ package cluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/go-kit/kit/log"
@@ -15,6 +17,8 @@ import (
 	"github.com/grafana/agent/pkg/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Cluster connects an Agent to other Agents and allows them to distribute
@@ -101,7 +105,13 @@ func (c *Cluster) storeValidate(cfg *instance.Config) error {
 
 	// If configs aren't allowed to read from the store, we need to make sure no
 	// configs coming in from the API set files for passwords.
-	return validateNofiles(cfg)
+	if err := validateNofiles(cfg); err != nil {
+		return err
+	}
+
+	// Clients can only be validated when they don't read files, since files
+	// are read by the agent which runs the config rather than this one.
+	return validateClients(cfg)
 }
 
 // Reshard implements agentproto.ScrapingServiceServer, and syncs the state of
@@ -114,6 +124,58 @@ func (c *Cluster) Reshard(ctx context.Context, _ *agentproto.ReshardRequest) (*e
 	return &empty.Empty{}, err
 }
 
+// PutConfig implements the PutConfig method of agentproto.AdminServiceServer,
+// validating an instance config and adding or updating it in the configstore.
+func (c *Cluster) PutConfig(ctx context.Context, req *agentproto.PutConfigRequest) (*agentproto.PutConfigResponse, error) {
+	cfg, err := instance.UnmarshalConfig(strings.NewReader(req.Config))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not unmarshal config: %s", err)
+	}
+	cfg.Name = req.Name
+
+	// Validation may mutate the config, so validate a separate copy. The
+	// original config is stored so defaults can change over time.
+	validateCfg, err := instance.UnmarshalConfig(strings.NewReader(req.Config))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not unmarshal config: %s", err)
+	}
+	validateCfg.Name = req.Name
+	if err := c.storeValidate(validateCfg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to validate config: %s", err)
+	}
+
+	created, err := c.store.Put(ctx, *cfg)
+	if err != nil {
+		return nil, storeStatusError(err)
+	}
+	return &agentproto.PutConfigResponse{Created: created}, nil
+}
+
+// DeleteConfig implements the DeleteConfig method of
+// agentproto.AdminServiceServer, removing an instance config from the
+// configstore.
+func (c *Cluster) DeleteConfig(ctx context.Context, req *agentproto.DeleteConfigRequest) (*empty.Empty, error) {
+	if err := c.store.Delete(ctx, req.Name); err != nil {
+		return nil, storeStatusError(err)
+	}
+	return &empty.Empty{}, nil
+}
+
+// storeStatusError converts an error from the configstore into a gRPC status
+// error.
+func storeStatusError(err error) error {
+	switch {
+	case errors.Is(err, configstore.ErrNotConnected):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &configstore.NotUniqueError{}):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &configstore.NotExistError{}):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
 // ApplyConfig applies configuration changes to Cluster.
 func (c *Cluster) ApplyConfig(
 	cfg Config,
@@ -162,6 +224,10 @@ func (c *Cluster) ApplyConfig(
 func (c *Cluster) WireAPI(r *mux.Router) {
 	c.storeAPI.WireAPI(r)
 	c.node.WireAPI(r)
+
+	r.HandleFunc("/agent/api/v1/scraping-service/ownership", c.OwnershipHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/scraping-service/reshard", c.ReshardHandler).Methods("POST")
+	r.HandleFunc("/agent/api/v1/scraping-service/drain", c.DrainHandler).Methods("POST")
 }
 
 // WireGRPC injects gRPC server handlers into the provided gRPC server.
@@ -20,6 +20,11 @@ var (
 		Name: "agent_prometheus_scraping_service_reshard_duration",
 		Help: "How long it took for resharding to run.",
 	}, []string{"success"})
+
+	ownedConfigs = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "agent_prometheus_scraping_service_owned_configs",
+		Help: "Number of configs owned and run by this agent.",
+	})
 )
 
 // configWatcher connects to a configstore and will apply configs to an
@@ -227,6 +232,7 @@ func (w *configWatcher) handleEvent(ev configstore.WatchEvent) error {
 
 		err := w.im.DeleteConfig(ev.Key)
 		delete(w.instances, ev.Key)
+		ownedConfigs.Set(float64(len(w.instances)))
 		if err != nil {
 			return fmt.Errorf("failed to delete: %w", err)
 		}
@@ -247,6 +253,7 @@ func (w *configWatcher) handleEvent(ev configstore.WatchEvent) error {
 			return fmt.Errorf("failed to apply config: %w", err)
 		}
 		w.instances[ev.Key] = struct{}{}
+		ownedConfigs.Set(float64(len(w.instances)))
 	}
 
 	return nil
@@ -275,6 +282,7 @@ func (w *configWatcher) Stop() error {
 		}
 	}
 	w.instances = make(map[string]struct{})
+	ownedConfigs.Set(0)
 
 	return nil
 }
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/labels"
 )
 
 // APIResponse is the base object returned for any API call.
@@ -47,6 +50,10 @@ type ErrorResponse struct {
 type ListConfigurationsResponse struct {
 	// Configs is the list of configuration names.
 	Configs []string `json:"configs"`
+
+	// Next is set when there are more configurations to list, and should be
+	// passed as the after parameter to get the next page.
+	Next string `json:"next,omitempty"`
 }
 
 // GetConfigurationResponse is contained inside an APIResponse
@@ -57,6 +64,68 @@ type GetConfigurationResponse struct {
 	Value string `json:"value"`
 }
 
+// PutConfigurationResponse is contained inside an APIResponse and provides
+// the validated configuration with defaults applied. Returned by
+// PutConfiguration for dry runs.
+type PutConfigurationResponse struct {
+	// Value is the stringified YAML configuration.
+	Value string `json:"value"`
+}
+
+// OwnershipResponse is contained inside an APIResponse and describes which
+// agent in the scraping service cluster owns each configuration. Returned by
+// the scraping service ownership API.
+type OwnershipResponse struct {
+	// Agents is the list of agents in the cluster.
+	Agents []AgentOwnership `json:"agents"`
+
+	// Unowned is the list of configurations which aren't owned by any agent,
+	// such as when there aren't enough healthy agents.
+	Unowned []string `json:"unowned"`
+}
+
+// AgentOwnership describes the part of the hash ring and configurations owned
+// by a single agent in the scraping service cluster.
+type AgentOwnership struct {
+	// Addr is the address of the agent.
+	Addr string `json:"addr"`
+
+	// State is the state of the agent in the ring. Agents in a state other
+	// than ACTIVE don't own any configurations.
+	State string `json:"state"`
+
+	// Local is true for the agent that handled the request.
+	Local bool `json:"local"`
+
+	// Tokens is the number of tokens the agent has in the ring.
+	Tokens int `json:"tokens"`
+
+	// RingPercent is the percentage of the hash ring covered by the tokens of
+	// the agent.
+	RingPercent float64 `json:"ring_percent"`
+
+	// Configs is the list of configurations owned by the agent.
+	Configs []string `json:"configs"`
+}
+
+// ListTargetsResponse is contained inside an APIResponse and lists the scrape
+// targets of every running instance. Returned by ListTargets.
+type ListTargetsResponse []TargetInfo
+
+// TargetInfo describes a specific target.
+type TargetInfo struct {
+	InstanceName string `json:"instance"`
+	TargetGroup  string `json:"target_group"`
+
+	Endpoint         string        `json:"endpoint"`
+	State            string        `json:"state"`
+	Labels           labels.Labels `json:"labels"`
+	DiscoveredLabels labels.Labels `json:"discovered_labels"`
+	LastScrape       time.Time     `json:"last_scrape"`
+	ScrapeDuration   int64         `json:"scrape_duration_ms"`
+	ScrapeError      string        `json:"scrape_error"`
+}
+
 // WriteResponse writes a response object to the provided ResponseWriter w and with a
 // status code of statusCode. resp is marshaled to JSON.
 func WriteResponse(w http.ResponseWriter, statusCode int, resp interface{}) error {
@@ -2,9 +2,12 @@ package cluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/gorilla/mux"
 	pb "github.com/grafana/agent/pkg/agentproto"
 	"github.com/grafana/agent/pkg/prom/cluster/client"
+	"github.com/grafana/agent/pkg/prom/cluster/configapi"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weaveworks/common/user"
@@ -27,6 +31,10 @@ const (
 	agentKey = "agent"
 )
 
+// errNodeDisabled is returned when an operation requires the node to be a
+// member of the ring.
+var errNodeDisabled = errors.New("scraping service not enabled")
+
 var backoffConfig = cortex_util.BackoffConfig{
 	MinBackoff: time.Second,
 	MaxBackoff: 2 * time.Minute,
@@ -373,6 +381,127 @@ func (n *node) Owns(key string) (bool, error) {
 	return false, nil
 }
 
+// Ownership returns the agents in the ring along with the keys each of them
+// owns.
+func (n *node) Ownership(keys []string) (*configapi.OwnershipResponse, error) {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+
+	if n.ring == nil || n.lc == nil {
+		return nil, errNodeDisabled
+	}
+
+	rs, err := n.ring.GetAllHealthy(ring.Reporting)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		percents = ringPercents(rs.Instances)
+		agents   = make(map[string]*configapi.AgentOwnership, len(rs.Instances))
+		resp     = &configapi.OwnershipResponse{
+			Agents:  make([]configapi.AgentOwnership, 0, len(rs.Instances)),
+			Unowned: []string{},
+		}
+	)
+	for _, inst := range rs.Instances {
+		agents[inst.Addr] = &configapi.AgentOwnership{
+			Addr:        inst.Addr,
+			State:       inst.State.String(),
+			Local:       inst.Addr == n.lc.Addr,
+			Tokens:      len(inst.Tokens),
+			RingPercent: percents[inst.Addr],
+			Configs:     []string{},
+		}
+	}
+
+	for _, key := range keys {
+		owners, err := n.ring.Get(keyHash(key), ring.Write, nil, nil, nil)
+		if err != nil || len(owners.Instances) == 0 {
+			resp.Unowned = append(resp.Unowned, key)
+			continue
+		}
+		for _, owner := range owners.Instances {
+			if agent, ok := agents[owner.Addr]; ok {
+				agent.Configs = append(agent.Configs, key)
+			}
+		}
+	}
+
+	for _, agent := range agents {
+		resp.Agents = append(resp.Agents, *agent)
+	}
+	sort.Slice(resp.Agents, func(i, j int) bool {
+		return resp.Agents[i].Addr < resp.Agents[j].Addr
+	})
+	return resp, nil
+}
+
+// ringPercents returns the percentage of the hash ring covered by the tokens
+// of each instance, keyed by address. A token covers the range of hashes
+// after the previous token up to and including itself.
+func ringPercents(instances []ring.InstanceDesc) map[string]float64 {
+	type token struct {
+		value uint32
+		addr  string
+	}
+
+	var tokens []token
+	for _, inst := range instances {
+		for _, t := range inst.Tokens {
+			tokens = append(tokens, token{value: t, addr: inst.Addr})
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].value < tokens[j].value })
+
+	res := make(map[string]float64, len(instances))
+	for i, t := range tokens {
+		var size uint64
+		if i == 0 {
+			// The first token also covers the range wrapping around from the
+			// last token.
+			size = uint64(t.value) + (math.MaxUint32 - uint64(tokens[len(tokens)-1].value)) + 1
+		} else {
+			size = uint64(t.value) - uint64(tokens[i-1].value)
+		}
+		res[t.addr] += float64(size) / (math.MaxUint32 + 1) * 100
+	}
+	return res
+}
+
+// Drain moves the node into the LEAVING state so it no longer owns any keys,
+// and then informs the cluster, including the local agent, to reshard. A
+// drained node rejoins the cluster when it's restarted or when a changed
+// config is applied, since ApplyConfig recreates the lifecycler.
+func (n *node) Drain(ctx context.Context) error {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+
+	if n.ring == nil || n.lc == nil {
+		return errNodeDisabled
+	}
+
+	if n.lc.GetState() != ring.LEAVING {
+		level.Info(n.log).Log("msg", "draining node")
+		if err := n.lc.ChangeState(ctx, ring.LEAVING); err != nil {
+			return fmt.Errorf("failed to drain node: %w", err)
+		}
+	}
+
+	return n.performClusterReshard(ctx, true)
+}
+
+// ReshardCluster informs the cluster, including the local agent, to reshard.
+func (n *node) ReshardCluster(ctx context.Context) error {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+
+	if n.ring == nil || n.lc == nil {
+		return errNodeDisabled
+	}
+	return n.performClusterReshard(ctx, true)
+}
+
 func keyHash(key string) uint32 {
 	h := fnv.New32()
 	_, _ = h.Write([]byte(key))
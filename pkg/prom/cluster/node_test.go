@@ -150,6 +150,67 @@ func Test_node_ApplyConfig(t *testing.T) {
 	waitAll(t, localReshard)
 }
 
+func Test_node_Drain(t *testing.T) {
+	var (
+		reg    = prometheus.NewRegistry()
+		logger = util.TestLogger(t)
+
+		localReshard = make(chan struct{}, 10)
+	)
+
+	local := &agentproto.FuncScrapingServiceServer{
+		ReshardFunc: func(c context.Context, rr *agentproto.ReshardRequest) (*empty.Empty, error) {
+			localReshard <- struct{}{}
+			return &empty.Empty{}, nil
+		},
+	}
+
+	nodeConfig := DefaultConfig
+	nodeConfig.Enabled = true
+	nodeConfig.Lifecycler = testLifecyclerConfig(t)
+
+	n, err := newNode(reg, logger, nodeConfig, local)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = n.Stop() })
+	require.NoError(t, n.WaitJoined(context.Background()))
+	waitAll(t, localReshard)
+
+	keys := []string{"a", "b"}
+
+	resp, err := n.Ownership(keys)
+	require.NoError(t, err)
+	require.Len(t, resp.Agents, 1)
+	require.True(t, resp.Agents[0].Local)
+	require.Equal(t, "ACTIVE", resp.Agents[0].State)
+	require.Equal(t, keys, resp.Agents[0].Configs)
+	require.InDelta(t, 100, resp.Agents[0].RingPercent, 0.001)
+	require.Empty(t, resp.Unowned)
+
+	require.NoError(t, n.Drain(context.Background()))
+	waitAll(t, localReshard)
+
+	require.Eventually(t, func() bool {
+		owned, err := n.Owns("a")
+		return err != nil || !owned
+	}, 5*time.Second, 10*time.Millisecond, "drained node should not own any keys")
+
+	resp, err = n.Ownership(keys)
+	require.NoError(t, err)
+	require.Len(t, resp.Agents, 1)
+	require.Equal(t, "LEAVING", resp.Agents[0].State)
+	require.Empty(t, resp.Agents[0].Configs)
+	require.Equal(t, keys, resp.Unowned)
+}
+
+func Test_ringPercents(t *testing.T) {
+	percents := ringPercents([]ring.InstanceDesc{
+		{Addr: "a", Tokens: []uint32{1 << 30, 3 << 30}},
+		{Addr: "b", Tokens: []uint32{2 << 30}},
+	})
+	require.InDelta(t, 75, percents["a"], 0.001)
+	require.InDelta(t, 25, percents["b"], 0.001)
+}
+
 // startNode launches srv as a gRPC server and registers it to the ring.
 func startNode(t *testing.T, srv agentproto.ScrapingServiceServer) {
 	t.Helper()
@@ -0,0 +1,277 @@
+package cluster
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/ring"
+	"github.com/cortexproject/cortex/pkg/util/services"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// targetsKey is the key used for storing the target sharding hash ring.
+	targetsKey = "agent_targets"
+)
+
+// DefaultShardingConfig provides default values for the ShardingConfig.
+var DefaultShardingConfig = *util.DefaultConfigFromFlags(&ShardingConfig{}).(*ShardingConfig)
+
+// ShardingConfig describes how to distribute scrape targets across a set of
+// agents.
+type ShardingConfig struct {
+	Enabled        bool                  `yaml:"enabled"`
+	ResyncInterval time.Duration         `yaml:"resync_interval"`
+	Lifecycler     ring.LifecyclerConfig `yaml:"lifecycler"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *ShardingConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultShardingConfig
+
+	type plain ShardingConfig
+	return unmarshal((*plain)(c))
+}
+
+// RegisterFlagsWithPrefix adds the flags required to config this to the given
+// FlagSet with a specified prefix.
+func (c *ShardingConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.BoolVar(&c.Enabled, prefix+"enabled", false, "enables sharding scrape targets across agents")
+	f.DurationVar(&c.ResyncInterval, prefix+"resync-interval", 10*time.Second, "how often to check the ring for membership changes")
+	c.Lifecycler.RegisterFlagsWithPrefix(prefix, f)
+}
+
+// Sharder distributes ownership of discovered scrape targets across all
+// healthy agents in a hash ring. It implements instance.TargetSharder.
+type Sharder struct {
+	log log.Logger
+	reg *util.Unregisterer
+
+	mut     sync.RWMutex
+	cfg     ShardingConfig
+	ring    *ring.Ring
+	lc      *ring.Lifecycler
+	members string
+	changed chan struct{}
+
+	exited bool
+	done   chan struct{}
+}
+
+// NewSharder creates a new Sharder. If target sharding is enabled in cfg, the
+// Sharder will join the ring.
+func NewSharder(reg prometheus.Registerer, l log.Logger, cfg ShardingConfig) (*Sharder, error) {
+	s := &Sharder{
+		log: log.With(l, "component", "target sharder"),
+		reg: util.WrapWithUnregisterer(reg),
+
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := s.ApplyConfig(cfg); err != nil {
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+// ApplyConfig applies a new ShardingConfig, leaving and re-joining the ring
+// as needed.
+func (s *Sharder) ApplyConfig(cfg ShardingConfig) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Each target must be owned by exactly one agent.
+	cfg.Lifecycler.RingConfig.ReplicationFactor = 1
+
+	if util.CompareYAML(s.cfg, cfg) {
+		return nil
+	}
+
+	if s.exited {
+		return fmt.Errorf("sharder already exited")
+	}
+
+	level.Info(s.log).Log("msg", "applying config")
+
+	// Shut down old components before re-creating the updated ones.
+	s.reg.UnregisterAll()
+
+	if err := s.stopDependencies(ctx); err != nil {
+		return err
+	}
+
+	// Whether enabling, disabling, or changing the ring, ownership of
+	// targets may have changed.
+	s.notifyChanged("")
+
+	if !cfg.Enabled {
+		s.cfg = cfg
+		return nil
+	}
+
+	r, err := newRing(cfg.Lifecycler.RingConfig, "agent_targets_viewer", targetsKey, s.reg)
+	if err != nil {
+		return fmt.Errorf("failed to create ring: %w", err)
+	}
+	if err := s.reg.Register(r); err != nil {
+		return fmt.Errorf("failed to register ring metrics: %w", err)
+	}
+	if err := services.StartAndAwaitRunning(context.Background(), r); err != nil {
+		return fmt.Errorf("failed to start ring: %w", err)
+	}
+	s.ring = r
+
+	lc, err := ring.NewLifecycler(cfg.Lifecycler, nil, "agent_targets", targetsKey, false, s.reg)
+	if err != nil {
+		return fmt.Errorf("failed to create lifecycler: %w", err)
+	}
+	if err := services.StartAndAwaitRunning(context.Background(), lc); err != nil {
+		if err := services.StopAndAwaitTerminated(ctx, r); err != nil {
+			level.Error(s.log).Log("msg", "failed to stop ring when returning error. next config reload will fail", "err", err)
+		}
+		s.ring = nil
+		return fmt.Errorf("failed to start lifecycler: %w", err)
+	}
+	s.lc = lc
+
+	s.cfg = cfg
+	return nil
+}
+
+// stopDependencies stops the lifecycler and the ring. The mutex must be held
+// when calling stopDependencies.
+func (s *Sharder) stopDependencies(ctx context.Context) error {
+	if s.lc != nil {
+		err := services.StopAndAwaitTerminated(ctx, s.lc)
+		if err != nil {
+			return fmt.Errorf("failed to stop lifecycler: %w", err)
+		}
+		s.lc = nil
+	}
+
+	if s.ring != nil {
+		err := services.StopAndAwaitTerminated(ctx, s.ring)
+		if err != nil {
+			return fmt.Errorf("failed to stop ring: %w", err)
+		}
+		s.ring = nil
+	}
+
+	return nil
+}
+
+// run periodically checks the ring for membership changes until the Sharder
+// is stopped.
+func (s *Sharder) run() {
+	for {
+		s.mut.RLock()
+		interval := s.cfg.ResyncInterval
+		s.mut.RUnlock()
+
+		if interval <= 0 {
+			interval = DefaultShardingConfig.ResyncInterval
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(interval):
+			s.checkMembers()
+		}
+	}
+}
+
+// checkMembers notifies listeners when the set of healthy agents in the ring
+// has changed.
+func (s *Sharder) checkMembers() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.ring == nil {
+		return
+	}
+
+	rs, err := s.ring.GetAllHealthy(ring.Read)
+	if err != nil {
+		level.Debug(s.log).Log("msg", "failed to get healthy agents", "err", err)
+		return
+	}
+
+	addrs := make([]string, 0, len(rs.Instances))
+	for _, inst := range rs.Instances {
+		addrs = append(addrs, inst.Addr)
+	}
+	sort.Strings(addrs)
+
+	if members := strings.Join(addrs, ","); members != s.members {
+		level.Info(s.log).Log("msg", "ring membership changed, resharding targets", "members", members)
+		s.notifyChanged(members)
+	}
+}
+
+// notifyChanged wakes up anyone waiting on Changed and records the current
+// set of members. The mutex must be held when calling notifyChanged.
+func (s *Sharder) notifyChanged(members string) {
+	s.members = members
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Changed implements instance.TargetSharder.
+func (s *Sharder) Changed() <-chan struct{} {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.changed
+}
+
+// Owns implements instance.TargetSharder. When target sharding is disabled,
+// all targets are owned by the local agent. Owns will return an error if the
+// ring is empty or if there aren't enough healthy agents.
+func (s *Sharder) Owns(key string) (bool, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if s.ring == nil || s.lc == nil {
+		return true, nil
+	}
+
+	rs, err := s.ring.Get(keyHash(key), ring.Write, nil, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range rs.Instances {
+		if r.Addr == s.lc.Addr {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Stop leaves the ring and stops the Sharder. The Sharder cannot be used
+// again once Stop is called.
+func (s *Sharder) Stop() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.exited {
+		return fmt.Errorf("sharder already exited")
+	}
+	s.exited = true
+	close(s.done)
+
+	level.Info(s.log).Log("msg", "shutting down target sharder")
+	s.reg.UnregisterAll()
+	return s.stopDependencies(context.Background())
+}
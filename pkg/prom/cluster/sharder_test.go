@@ -0,0 +1,78 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharder_Disabled(t *testing.T) {
+	s, err := NewSharder(prometheus.NewRegistry(), util.TestLogger(t), DefaultShardingConfig)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Stop() })
+
+	owned, err := s.Owns("job/target")
+	require.NoError(t, err)
+	require.True(t, owned, "all targets should be owned when sharding is disabled")
+}
+
+func TestSharder_Owns(t *testing.T) {
+	lcConfig := testLifecyclerConfig(t)
+
+	newSharder := func(addr string) *Sharder {
+		cfg := DefaultShardingConfig
+		cfg.Enabled = true
+		cfg.ResyncInterval = 100 * time.Millisecond
+		cfg.Lifecycler = lcConfig
+		cfg.Lifecycler.ID = addr
+		cfg.Lifecycler.Addr = addr
+
+		s, err := NewSharder(prometheus.NewRegistry(), util.TestLogger(t), cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = s.Stop() })
+
+		require.NoError(t, waitJoined(context.Background(), targetsKey, s.ring.KVClient, s.lc.ID))
+		return s
+	}
+
+	a := newSharder("agent-a")
+	changed := a.Changed()
+	b := newSharder("agent-b")
+
+	// a should notice b joining the ring.
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for membership change")
+	}
+
+	require.Eventually(t, func() bool {
+		var ownedA, ownedB int
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("job/target-%d", i)
+
+			owns, err := a.Owns(key)
+			if err != nil {
+				return false
+			} else if owns {
+				ownedA++
+			}
+
+			owns, err = b.Owns(key)
+			if err != nil {
+				return false
+			} else if owns {
+				ownedB++
+			}
+		}
+
+		// Every target should be owned by exactly one agent, and both agents
+		// should own some targets.
+		return ownedA+ownedB == 100 && ownedA > 0 && ownedB > 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -3,6 +3,7 @@ package cluster
 import (
 	"fmt"
 
+	http_sd "github.com/grafana/agent/pkg/prom/discovery/http"
 	"github.com/grafana/agent/pkg/prom/instance"
 	"github.com/prometheus/common/config"
 	"github.com/prometheus/prometheus/discovery"
@@ -22,6 +23,7 @@ import (
 	"github.com/prometheus/prometheus/discovery/scaleway"
 	"github.com/prometheus/prometheus/discovery/triton"
 	"github.com/prometheus/prometheus/discovery/zookeeper"
+	"github.com/prometheus/prometheus/storage/remote"
 )
 
 func validateNofiles(c *instance.Config) error {
@@ -46,6 +48,37 @@ func validateNofiles(c *instance.Config) error {
 	return nil
 }
 
+// validateClients ensures that the HTTP clients for the remote_write and
+// scrape_configs of c can be created, rejecting invalid settings before the
+// config is picked up by an agent.
+func validateClients(c *instance.Config) error {
+	for i, rw := range c.RemoteWrite {
+		if rw.URL == nil {
+			return fmt.Errorf("failed to validate remote_write at index %d: missing url", i)
+		}
+
+		_, err := remote.NewWriteClient(rw.Name, &remote.ClientConfig{
+			URL:              rw.URL,
+			Timeout:          rw.RemoteTimeout,
+			HTTPClientConfig: rw.HTTPClientConfig,
+			SigV4Config:      rw.SigV4Config,
+			Headers:          rw.Headers,
+			RetryOnRateLimit: rw.QueueConfig.RetryOnRateLimit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to validate remote_write at index %d: %w", i, err)
+		}
+	}
+
+	for i, sc := range c.ScrapeConfigs {
+		if _, err := config.NewClientFromConfig(sc.HTTPClientConfig, sc.JobName); err != nil {
+			return fmt.Errorf("failed to validate scrape_config at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
 func validateHTTPNoFiles(cfg *config.HTTPClientConfig) error {
 	checks := []struct {
 		name  string
@@ -54,6 +87,7 @@ func validateHTTPNoFiles(cfg *config.HTTPClientConfig) error {
 		{"bearer_token_file", func() bool { return cfg.BearerTokenFile != "" }},
 		{"password_file", func() bool { return cfg.BasicAuth != nil && cfg.BasicAuth.PasswordFile != "" }},
 		{"credentials_file", func() bool { return cfg.Authorization != nil && cfg.Authorization.CredentialsFile != "" }},
+		{"client_secret_file", func() bool { return cfg.OAuth2 != nil && cfg.OAuth2.ClientSecretFile != "" }},
 		{"ca_file", func() bool { return cfg.TLSConfig.CAFile != "" }},
 		{"cert_file", func() bool { return cfg.TLSConfig.CertFile != "" }},
 		{"key_file", func() bool { return cfg.TLSConfig.KeyFile != "" }},
@@ -96,6 +130,10 @@ func validateDiscoveryNoFiles(disc discovery.Config) error {
 		// no-op
 	case *gce.SDConfig:
 		// no-op
+	case *http_sd.SDConfig:
+		if err := validateHTTPNoFiles(&d.HTTPClientConfig); err != nil {
+			return err
+		}
 	case *hetzner.SDConfig:
 		if err := validateHTTPNoFiles(&d.HTTPClientConfig); err != nil {
 			return err
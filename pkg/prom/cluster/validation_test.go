@@ -60,6 +60,8 @@ func Test_validateNoFiles(t *testing.T) {
 					zone: fake
 				hetzner_sd_configs:
 				- role: hcloud
+				http_sd_configs:
+				- url: http://localhost:8080/sd
 				kubernetes_sd_configs:
 				- role: pod
 				marathon_sd_configs:
@@ -100,6 +102,33 @@ func Test_validateNoFiles(t *testing.T) {
 			`),
 			expect: fmt.Errorf("failed to validate scrape_config at index 0: password_file must be empty unless dangerous_allow_reading_files is set"),
 		},
+		{
+			name: "invalid oauth2 config",
+			input: util.Untab(`
+			scrape_configs:
+			- job_name: malicious_scrape
+				static_configs:
+					- targets: ['badsite.com']
+				oauth2:
+					client_id: file_leak
+					client_secret_file: /etc/password
+					token_url: https://badsite.com/token
+			`),
+			expect: fmt.Errorf("failed to validate scrape_config at index 0: client_secret_file must be empty unless dangerous_allow_reading_files is set"),
+		},
+		{
+			name: "invalid http_sd config",
+			input: util.Untab(`
+			scrape_configs:
+			- job_name: malicious_scrape
+				http_sd_configs:
+				- url: http://localhost:8080/sd
+					basic_auth:
+						username: file_leak
+						password_file: /etc/password
+			`),
+			expect: fmt.Errorf("failed to validate service discovery at index 0 within scrape_config at index 0: password_file must be empty unless dangerous_allow_reading_files is set"),
+		},
 	}
 
 	for _, tc := range tt {
@@ -116,3 +145,52 @@ func Test_validateNoFiles(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateClients(t *testing.T) {
+	t.Run("valid config", func(t *testing.T) {
+		cfg, err := instance.UnmarshalConfig(strings.NewReader(util.Untab(`
+		scrape_configs:
+		- job_name: test
+			static_configs:
+				- targets: ['127.0.0.1:12345']
+		remote_write:
+		- url: http://localhost:9009/api/prom/push
+		`)))
+		require.NoError(t, err)
+		require.NoError(t, validateClients(cfg))
+	})
+
+	t.Run("authorization and oauth2", func(t *testing.T) {
+		cfg, err := instance.UnmarshalConfig(strings.NewReader(util.Untab(`
+		scrape_configs:
+		- job_name: authorization
+			static_configs:
+				- targets: ['127.0.0.1:12345']
+			authorization:
+				type: Bearer
+				credentials: secret
+			proxy_url: http://proxy:3128
+			follow_redirects: false
+		- job_name: oauth2
+			static_configs:
+				- targets: ['127.0.0.1:12345']
+			oauth2:
+				client_id: client
+				client_secret: secret
+				scopes: [metrics]
+				token_url: https://example.com/token
+				endpoint_params:
+					audience: metrics
+		`)))
+		require.NoError(t, err)
+		require.NoError(t, validateClients(cfg))
+		require.Equal(t, "client", cfg.ScrapeConfigs[1].HTTPClientConfig.OAuth2.ClientID)
+		require.False(t, cfg.ScrapeConfigs[0].HTTPClientConfig.FollowRedirects)
+	})
+
+	t.Run("missing remote_write url", func(t *testing.T) {
+		cfg := instance.DefaultConfig
+		cfg.RemoteWrite = []*instance.RemoteWriteConfig{{}}
+		require.EqualError(t, validateClients(&cfg), "failed to validate remote_write at index 0: missing url")
+	})
+}
@@ -0,0 +1,190 @@
+// Package http implements the http_sd_configs service discovery mechanism,
+// which periodically fetches targets from an HTTP endpoint returning the
+// Prometheus HTTP SD JSON format.
+//
+// Importing this package registers http_sd_configs for use in scrape_configs.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/build"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/refresh"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+// urlLabel is added to every discovered target group and holds the URL
+// targets were fetched from.
+const urlLabel = model.MetaLabelPrefix + "url"
+
+var (
+	// DefaultSDConfig is the default HTTP SD configuration.
+	DefaultSDConfig = SDConfig{
+		RefreshInterval:  model.Duration(60 * time.Second),
+		HTTPClientConfig: config.DefaultHTTPClientConfig,
+	}
+
+	userAgent        = fmt.Sprintf("GrafanaAgent/%s", build.Version)
+	matchContentType = regexp.MustCompile(`^(?i:application\/json(;\s*charset=("utf-8"|utf-8))?)$`)
+)
+
+func init() {
+	discovery.RegisterConfig(&SDConfig{})
+}
+
+// SDConfig is the configuration for HTTP based service discovery.
+type SDConfig struct {
+	HTTPClientConfig config.HTTPClientConfig `yaml:",inline"`
+	RefreshInterval  model.Duration          `yaml:"refresh_interval,omitempty"`
+	URL              string                  `yaml:"url"`
+}
+
+// Name returns the name of the Config.
+func (*SDConfig) Name() string { return "http" }
+
+// NewDiscoverer returns a Discoverer for the Config.
+func (c *SDConfig) NewDiscoverer(opts discovery.DiscovererOptions) (discovery.Discoverer, error) {
+	return NewDiscovery(c, opts.Logger)
+}
+
+// SetDirectory joins any relative file paths with dir.
+func (c *SDConfig) SetDirectory(dir string) {
+	c.HTTPClientConfig.SetDirectory(dir)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SDConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultSDConfig
+	type plain SDConfig
+	err := unmarshal((*plain)(c))
+	if err != nil {
+		return err
+	}
+
+	if c.URL == "" {
+		return errors.New("URL is missing")
+	}
+	parsedURL, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return errors.New("URL scheme must be 'http' or 'https'")
+	}
+	if parsedURL.Host == "" {
+		return errors.New("host is missing in URL")
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+// Discovery periodically fetches targets from an HTTP endpoint. It implements
+// the Discoverer interface.
+type Discovery struct {
+	*refresh.Discovery
+
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	// tgLastLength is the number of target groups returned by the last
+	// refresh, used to send empty groups for sources which disappeared.
+	tgLastLength int
+}
+
+// NewDiscovery returns a new HTTP discovery for the given config.
+func NewDiscovery(conf *SDConfig, logger log.Logger) (*Discovery, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	client, err := config.NewClientFromConfig(conf.HTTPClientConfig, "http", config.WithHTTP2Disabled())
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = time.Duration(conf.RefreshInterval)
+
+	d := &Discovery{
+		url:             conf.URL,
+		client:          client,
+		refreshInterval: time.Duration(conf.RefreshInterval),
+	}
+	d.Discovery = refresh.NewDiscovery(logger, "http", time.Duration(conf.RefreshInterval), d.refresh)
+	return d, nil
+}
+
+func (d *Discovery) refresh(ctx context.Context) ([]*targetgroup.Group, error) {
+	req, err := http.NewRequest("GET", d.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Prometheus-Refresh-Interval-Seconds", strconv.FormatFloat(d.refreshInterval.Seconds(), 'f', -1, 64))
+
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("server returned HTTP status %s", resp.Status)
+	}
+
+	if !matchContentType.MatchString(resp.Header.Get("Content-Type")) {
+		return nil, errors.Errorf("unsupported content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var targetGroups []*targetgroup.Group
+	if err := json.Unmarshal(b, &targetGroups); err != nil {
+		return nil, err
+	}
+
+	for i, tg := range targetGroups {
+		if tg == nil {
+			return nil, errors.Errorf("nil target group item found (index %d)", i)
+		}
+
+		tg.Source = urlSource(d.url, i)
+		if tg.Labels == nil {
+			tg.Labels = model.LabelSet{}
+		}
+		tg.Labels[urlLabel] = model.LabelValue(d.url)
+	}
+
+	// Generate empty updates for sources that disappeared.
+	l := len(targetGroups)
+	for i := l; i < d.tgLastLength; i++ {
+		targetGroups = append(targetGroups, &targetgroup.Group{Source: urlSource(d.url, i)})
+	}
+	d.tgLastLength = l
+
+	return targetGroups, nil
+}
+
+// urlSource returns a source ID for the i-th target group per URL.
+func urlSource(url string, i int) string {
+	return fmt.Sprintf("%s:%d", url, i)
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSDConfig_Unmarshal(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "valid", input: `url: http://localhost:8080/sd`},
+		{name: "missing url", input: `refresh_interval: 1m`, err: "URL is missing"},
+		{name: "bad scheme", input: `url: ftp://localhost/sd`, err: "URL scheme must be 'http' or 'https'"},
+		{name: "missing host", input: `url: http:///sd`, err: "host is missing in URL"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg SDConfig
+			err := yaml.Unmarshal([]byte(tc.input), &cfg)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, DefaultSDConfig.RefreshInterval, cfg.RefreshInterval)
+		})
+	}
+}
+
+func TestDiscovery_Refresh(t *testing.T) {
+	var response string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "60", r.Header.Get("X-Prometheus-Refresh-Interval-Seconds"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, response)
+	}))
+	defer srv.Close()
+
+	d, err := NewDiscovery(&SDConfig{
+		URL:              srv.URL,
+		RefreshInterval:  model.Duration(time.Minute),
+		HTTPClientConfig: config.DefaultHTTPClientConfig,
+	}, nil)
+	require.NoError(t, err)
+
+	response = `[
+		{"targets": ["127.0.0.1:9090"], "labels": {"env": "test"}},
+		{"targets": ["127.0.0.1:9091"]}
+	]`
+	tgs, err := d.refresh(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []*targetgroup.Group{
+		{
+			Targets: []model.LabelSet{{model.AddressLabel: "127.0.0.1:9090"}},
+			Labels:  model.LabelSet{"env": "test", urlLabel: model.LabelValue(srv.URL)},
+			Source:  srv.URL + ":0",
+		},
+		{
+			Targets: []model.LabelSet{{model.AddressLabel: "127.0.0.1:9091"}},
+			Labels:  model.LabelSet{urlLabel: model.LabelValue(srv.URL)},
+			Source:  srv.URL + ":1",
+		},
+	}, tgs)
+
+	// Groups that disappear should be sent as empty groups.
+	response = `[{"targets": ["127.0.0.1:9090"]}]`
+	tgs, err = d.refresh(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tgs, 2)
+	require.Equal(t, &targetgroup.Group{Source: srv.URL + ":1"}, tgs[1])
+
+	response = `[null]`
+	_, err = d.refresh(context.Background())
+	require.EqualError(t, err, "nil target group item found (index 0)")
+}
+
+func TestDiscovery_Refresh_BadResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "[]")
+	}))
+	defer srv.Close()
+
+	d, err := NewDiscovery(&SDConfig{
+		URL:              srv.URL,
+		RefreshInterval:  model.Duration(time.Minute),
+		HTTPClientConfig: config.DefaultHTTPClientConfig,
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = d.refresh(context.Background())
+	require.EqualError(t, err, `unsupported content type "text/plain"`)
+}
@@ -0,0 +1,34 @@
+package prom
+
+import (
+	"fmt"
+
+	"github.com/grafana/agent/pkg/health"
+)
+
+// Health returns the status of every running instance. Instances are ready
+// once they have replayed their WAL, and unhealthy while waiting to be
+// restarted after stopping abnormally.
+func (a *Agent) Health() health.Subsystem {
+	restarting := a.bm.RestartingInstances()
+
+	var components []health.Component
+	for name, inst := range a.mm.ListInstances() {
+		c := health.Component{Name: name, Healthy: true, Ready: true}
+
+		if replayer, ok := inst.(walReplayer); ok {
+			if status := replayer.WALReplayStatus(); !status.Done {
+				c.Ready = false
+				c.Message = fmt.Sprintf("replaying WAL: %d/%d segments replayed", status.SegmentsReplayed, status.SegmentsTotal)
+			}
+		}
+		if err, ok := restarting[name]; ok {
+			c.Healthy = false
+			c.Message = fmt.Sprintf("restarting after stopping abnormally: %s", err)
+		}
+
+		components = append(components, c)
+	}
+
+	return health.NewSubsystem("prometheus", components)
+}
@@ -0,0 +1,50 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/health"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/prom/wal"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_Health(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+
+	mockManager := &instance.MockManager{
+		ListInstancesFunc: func() map[string]instance.ManagedInstance {
+			return map[string]instance.ManagedInstance{
+				"replaying": &mockInstanceReplay{status: wal.ReplayStatus{
+					SegmentsTotal:    3,
+					SegmentsReplayed: 1,
+				}},
+				"replayed": &mockInstanceReplay{status: wal.ReplayStatus{Done: true}},
+				"no_wal":   &mockInstanceScrape{},
+			}
+		},
+		ListConfigsFunc:  func() map[string]instance.Config { return nil },
+		ApplyConfigFunc:  func(_ instance.Config) error { return nil },
+		DeleteConfigFunc: func(name string) error { return nil },
+		StopFunc:         func() {},
+	}
+	a.mm, err = instance.NewModalManager(prometheus.NewRegistry(), a.logger, mockManager, instance.ModeDistinct)
+	require.NoError(t, err)
+
+	require.Equal(t, health.Subsystem{
+		Name:    "prometheus",
+		Healthy: true,
+		Ready:   false,
+		Components: []health.Component{
+			{Name: "no_wal", Healthy: true, Ready: true},
+			{Name: "replayed", Healthy: true, Ready: true},
+			{Name: "replaying", Healthy: true, Ready: false, Message: "replaying WAL: 1/3 segments replayed"},
+		},
+	}, a.Health())
+}
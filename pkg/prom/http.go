@@ -1,15 +1,20 @@
 package prom
 
 import (
+	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/go-kit/kit/log/level"
 	"github.com/gorilla/mux"
 	"github.com/grafana/agent/pkg/prom/cluster/configapi"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/prom/wal"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 )
 
 // WireAPI adds API routes to the provided mux router.
@@ -17,27 +22,47 @@ func (a *Agent) WireAPI(r *mux.Router) {
 	a.cluster.WireAPI(r)
 
 	r.HandleFunc("/agent/api/v1/instances", a.ListInstancesHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/metrics/targets", a.ListTargetsHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/metrics/relabel", a.RelabelHandler).Methods("POST")
+	r.HandleFunc("/agent/api/v1/metrics/wal/replay", a.WALReplayHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/metrics/remote_write/relabel_drops", a.RelabelDropsHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/metrics/instance/{instance}/delete_series", a.DeleteSeriesHandler).Methods("POST")
+
+	// Deprecated: use /agent/api/v1/metrics/targets instead.
 	r.HandleFunc("/agent/api/v1/targets", a.ListTargetsHandler).Methods("GET")
 }
 
 // ListInstancesHandler writes the set of currently running instances to the http.ResponseWriter.
 func (a *Agent) ListInstancesHandler(w http.ResponseWriter, _ *http.Request) {
+	err := configapi.WriteResponse(w, http.StatusOK, a.instanceNames())
+	if err != nil {
+		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
+	}
+}
+
+// instanceNames returns the sorted names of the currently running instances.
+func (a *Agent) instanceNames() []string {
 	cfgs := a.mm.ListConfigs()
 	instanceNames := make([]string, 0, len(cfgs))
 	for k := range cfgs {
 		instanceNames = append(instanceNames, k)
 	}
 	sort.Strings(instanceNames)
+	return instanceNames
+}
 
-	err := configapi.WriteResponse(w, http.StatusOK, instanceNames)
+// ListTargetsHandler retrieves the full set of targets across all instances and shows
+// information on them.
+func (a *Agent) ListTargetsHandler(w http.ResponseWriter, _ *http.Request) {
+	err := configapi.WriteResponse(w, http.StatusOK, a.listTargets())
 	if err != nil {
 		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
 	}
 }
 
-// ListTargetsHandler retrieves the full set of targets across all instances and shows
-// information on them.
-func (a *Agent) ListTargetsHandler(w http.ResponseWriter, _ *http.Request) {
+// listTargets returns the targets of every running instance, sorted by
+// instance, target group, job, and instance label.
+func (a *Agent) listTargets() ListTargetsResponse {
 	instances := a.mm.ListInstances()
 	resp := ListTargetsResponse{}
 
@@ -93,25 +118,208 @@ func (a *Agent) ListTargetsHandler(w http.ResponseWriter, _ *http.Request) {
 		}
 	})
 
+	return resp
+}
+
+// ListTargetsResponse is returned by the ListTargetsHandler.
+type ListTargetsResponse = configapi.ListTargetsResponse
+
+// TargetInfo describes a specific target.
+type TargetInfo = configapi.TargetInfo
+
+// walReplayer is implemented by instances that can report the progress of
+// replaying their WAL.
+type walReplayer interface {
+	WALReplayStatus() wal.ReplayStatus
+}
+
+// WALReplayHandler reports the progress of replaying the WAL for each running
+// instance.
+func (a *Agent) WALReplayHandler(w http.ResponseWriter, _ *http.Request) {
+	instances := a.mm.ListInstances()
+	resp := WALReplayResponse{}
+
+	for instName, inst := range instances {
+		replayer, ok := inst.(walReplayer)
+		if !ok {
+			continue
+		}
+		status := replayer.WALReplayStatus()
+
+		info := WALReplayInfo{
+			InstanceName:      instName,
+			Done:              status.Done,
+			SegmentsTotal:     status.SegmentsTotal,
+			SegmentsReplayed:  status.SegmentsReplayed,
+			SegmentsRemaining: status.SegmentsRemaining(),
+			SamplesReplayed:   status.SamplesReplayed,
+		}
+		if !status.StartTime.IsZero() {
+			end := status.EndTime
+			if end.IsZero() {
+				end = time.Now()
+			}
+			info.StartTime = &status.StartTime
+			info.Duration = end.Sub(status.StartTime).Milliseconds()
+		}
+		resp = append(resp, info)
+	}
+
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].InstanceName < resp[j].InstanceName
+	})
+
 	err := configapi.WriteResponse(w, http.StatusOK, resp)
 	if err != nil {
 		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
 	}
 }
 
-// ListTargetsResponse is returned by the ListTargetsHandler.
-type ListTargetsResponse []TargetInfo
+// WALReplayResponse is returned by the WALReplayHandler.
+type WALReplayResponse []WALReplayInfo
 
-// TargetInfo describes a specific target.
-type TargetInfo struct {
+// WALReplayInfo describes the progress of replaying the WAL of an instance.
+type WALReplayInfo struct {
 	InstanceName string `json:"instance"`
-	TargetGroup  string `json:"target_group"`
-
-	Endpoint         string        `json:"endpoint"`
-	State            string        `json:"state"`
-	Labels           labels.Labels `json:"labels"`
-	DiscoveredLabels labels.Labels `json:"discovered_labels"`
-	LastScrape       time.Time     `json:"last_scrape"`
-	ScrapeDuration   int64         `json:"scrape_duration_ms"`
-	ScrapeError      string        `json:"scrape_error"`
+	Done         bool   `json:"done"`
+
+	SegmentsTotal     int   `json:"segments_total"`
+	SegmentsReplayed  int   `json:"segments_replayed"`
+	SegmentsRemaining int   `json:"segments_remaining"`
+	SamplesReplayed   int64 `json:"samples_replayed"`
+
+	StartTime *time.Time `json:"start_time,omitempty"`
+	Duration  int64      `json:"duration_ms"`
+}
+
+// defaultRelabelDropsLimit is the default number of metric names reported by
+// the RelabelDropsHandler for each remote_write config.
+const defaultRelabelDropsLimit = 10
+
+// relabelDropReporter is implemented by instances that can report the
+// samples dropped by write_relabel_configs.
+type relabelDropReporter interface {
+	RelabelDrops(limit int) []instance.RelabelDropStatus
+}
+
+// RelabelDropsHandler reports the samples dropped by the
+// write_relabel_configs of each remote_write config of every running
+// instance, along with the metric names that had the most samples dropped.
+// The number of metric names is set by the limit query parameter.
+func (a *Agent) RelabelDropsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRelabelDropsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			err := configapi.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q: must be a positive integer", v))
+			if err != nil {
+				level.Error(a.logger).Log("msg", "failed to write response", "err", err)
+			}
+			return
+		}
+		limit = parsed
+	}
+
+	instances := a.mm.ListInstances()
+	resp := RelabelDropsResponse{}
+
+	for instName, inst := range instances {
+		reporter, ok := inst.(relabelDropReporter)
+		if !ok {
+			continue
+		}
+		for _, status := range reporter.RelabelDrops(limit) {
+			resp = append(resp, RelabelDropsInfo{
+				InstanceName:      instName,
+				RelabelDropStatus: status,
+			})
+		}
+	}
+
+	sort.Slice(resp, func(i, j int) bool {
+		if resp[i].InstanceName != resp[j].InstanceName {
+			return resp[i].InstanceName < resp[j].InstanceName
+		}
+		return resp[i].RemoteName < resp[j].RemoteName
+	})
+
+	err := configapi.WriteResponse(w, http.StatusOK, resp)
+	if err != nil {
+		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
+	}
+}
+
+// RelabelDropsResponse is returned by the RelabelDropsHandler.
+type RelabelDropsResponse []RelabelDropsInfo
+
+// RelabelDropsInfo describes the samples dropped by the
+// write_relabel_configs of a remote_write config of an instance.
+type RelabelDropsInfo struct {
+	InstanceName string `json:"instance"`
+	instance.RelabelDropStatus
+}
+
+// seriesDeleter is implemented by instances that can delete series from
+// their WAL.
+type seriesDeleter interface {
+	DeleteSeries(selectors ...[]*labels.Matcher) (int, error)
+}
+
+// DeleteSeriesHandler deletes the series matching any of the match[]
+// selectors from the WAL of an instance, so they're no longer replayed or
+// kept in checkpoints. Selectors are read from the query string or a form
+// encoded body.
+func (a *Agent) DeleteSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["instance"]
+
+	if err := r.ParseForm(); err != nil {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to parse form: %w", err))
+		return
+	}
+	if len(r.Form["match[]"]) == 0 {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("no match[] parameter provided"))
+		return
+	}
+
+	selectors := make([][]*labels.Matcher, 0, len(r.Form["match[]"]))
+	for _, s := range r.Form["match[]"] {
+		matchers, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid selector %q: %w", s, err))
+			return
+		}
+		selectors = append(selectors, matchers)
+	}
+
+	inst, ok := a.mm.ListInstances()[name]
+	if !ok {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("instance %s not found", name))
+		return
+	}
+	deleter, ok := inst.(seriesDeleter)
+	if !ok {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("instance %s does not support deleting series", name))
+		return
+	}
+
+	deleted, err := deleter.DeleteSeries(selectors...)
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to delete series: %w", err))
+		return
+	}
+	level.Info(a.logger).Log("msg", "deleted series from WAL", "instance", name, "match", fmt.Sprint(r.Form["match[]"]), "deleted", deleted)
+
+	err = configapi.WriteResponse(w, http.StatusOK, DeleteSeriesResponse{
+		InstanceName:  name,
+		DeletedSeries: deleted,
+	})
+	if err != nil {
+		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
+	}
+}
+
+// DeleteSeriesResponse is returned by the DeleteSeriesHandler.
+type DeleteSeriesResponse struct {
+	InstanceName  string `json:"instance"`
+	DeletedSeries int    `json:"deleted_series"`
 }
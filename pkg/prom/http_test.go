@@ -10,7 +10,9 @@ import (
 
 	"github.com/cortexproject/cortex/pkg/util/test"
 	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
 	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/prom/wal"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
@@ -132,6 +134,246 @@ func TestAgent_ListTargetsHandler(t *testing.T) {
 		require.JSONEq(t, expect, rr.Body.String())
 		require.Equal(t, http.StatusOK, rr.Result().StatusCode)
 	})
+
+	t.Run("routes", func(t *testing.T) {
+		mockManager.ListInstancesFunc = func() map[string]instance.ManagedInstance { return nil }
+
+		router := mux.NewRouter()
+		a.WireAPI(router)
+
+		for _, path := range []string{"/agent/api/v1/metrics/targets", "/agent/api/v1/targets"} {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+			require.Equal(t, http.StatusOK, rr.Result().StatusCode, path)
+			require.JSONEq(t, `{"status": "success", "data": []}`, rr.Body.String(), path)
+		}
+	})
+}
+
+func TestAgent_WALReplayHandler(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+
+	startTime := time.Date(1994, time.January, 12, 0, 0, 0, 0, time.UTC)
+
+	mockManager := &instance.MockManager{
+		ListInstancesFunc: func() map[string]instance.ManagedInstance {
+			return map[string]instance.ManagedInstance{
+				"b": &mockInstanceReplay{status: wal.ReplayStatus{
+					Done:             true,
+					SegmentsTotal:    3,
+					SegmentsReplayed: 3,
+					SamplesReplayed:  1000,
+					StartTime:        startTime,
+					EndTime:          startTime.Add(5 * time.Second),
+				}},
+				"a": &mockInstanceReplay{},
+				// Instances which can't report their progress are skipped.
+				"c": &mockInstanceScrape{},
+			}
+		},
+		ListConfigsFunc:  func() map[string]instance.Config { return nil },
+		ApplyConfigFunc:  func(_ instance.Config) error { return nil },
+		DeleteConfigFunc: func(name string) error { return nil },
+		StopFunc:         func() {},
+	}
+	a.mm, err = instance.NewModalManager(prometheus.NewRegistry(), a.logger, mockManager, instance.ModeDistinct)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	a.WALReplayHandler(rr, httptest.NewRequest("GET", "/agent/api/v1/metrics/wal/replay", nil))
+	expect := `{
+		"status": "success",
+		"data": [
+			{
+				"instance": "a",
+				"done": false,
+				"segments_total": 0,
+				"segments_replayed": 0,
+				"segments_remaining": 0,
+				"samples_replayed": 0,
+				"duration_ms": 0
+			},
+			{
+				"instance": "b",
+				"done": true,
+				"segments_total": 3,
+				"segments_replayed": 3,
+				"segments_remaining": 0,
+				"samples_replayed": 1000,
+				"start_time": "1994-01-12T00:00:00Z",
+				"duration_ms": 5000
+			}
+		]
+	}`
+	require.JSONEq(t, expect, rr.Body.String())
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+}
+
+func TestAgent_RelabelDropsHandler(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+
+	mockManager := &instance.MockManager{
+		ListInstancesFunc: func() map[string]instance.ManagedInstance {
+			return map[string]instance.ManagedInstance{
+				"b": &mockInstanceRelabelDrops{status: []instance.RelabelDropStatus{{
+					RemoteName:     "cloud",
+					DroppedSamples: 10,
+					TopMetrics: []instance.DroppedMetric{
+						{Name: "go_goroutines", Samples: 6},
+						{Name: "go_threads", Samples: 4},
+					},
+				}}},
+				"a": &mockInstanceRelabelDrops{status: []instance.RelabelDropStatus{{
+					RemoteName: "local",
+					TopMetrics: []instance.DroppedMetric{},
+				}}},
+				// Instances which can't report drops are skipped.
+				"c": &mockInstanceScrape{},
+			}
+		},
+		ListConfigsFunc:  func() map[string]instance.Config { return nil },
+		ApplyConfigFunc:  func(_ instance.Config) error { return nil },
+		DeleteConfigFunc: func(name string) error { return nil },
+		StopFunc:         func() {},
+	}
+	a.mm, err = instance.NewModalManager(prometheus.NewRegistry(), a.logger, mockManager, instance.ModeDistinct)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	a.RelabelDropsHandler(rr, httptest.NewRequest("GET", "/agent/api/v1/metrics/remote_write/relabel_drops?limit=1", nil))
+	expect := `{
+		"status": "success",
+		"data": [
+			{
+				"instance": "a",
+				"remote_name": "local",
+				"dropped_samples": 0,
+				"top_metrics": []
+			},
+			{
+				"instance": "b",
+				"remote_name": "cloud",
+				"dropped_samples": 10,
+				"top_metrics": [{"name": "go_goroutines", "samples": 6}]
+			}
+		]
+	}`
+	require.JSONEq(t, expect, rr.Body.String())
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+
+	rr = httptest.NewRecorder()
+	a.RelabelDropsHandler(rr, httptest.NewRequest("GET", "/agent/api/v1/metrics/remote_write/relabel_drops?limit=-1", nil))
+	require.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+}
+
+func TestAgent_DeleteSeriesHandler(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+
+	deleter := &mockInstanceDeleteSeries{}
+	mockManager := &instance.MockManager{
+		ListInstancesFunc: func() map[string]instance.ManagedInstance {
+			return map[string]instance.ManagedInstance{
+				"a": deleter,
+				// Instances which can't delete series are rejected.
+				"b": &mockInstanceScrape{},
+			}
+		},
+		ListConfigsFunc:  func() map[string]instance.Config { return nil },
+		ApplyConfigFunc:  func(_ instance.Config) error { return nil },
+		DeleteConfigFunc: func(name string) error { return nil },
+		StopFunc:         func() {},
+	}
+	a.mm, err = instance.NewModalManager(prometheus.NewRegistry(), a.logger, mockManager, instance.ModeDistinct)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	a.WireAPI(router)
+
+	deleteSeries := func(instance, query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		path := fmt.Sprintf("/agent/api/v1/metrics/instance/%s/delete_series?%s", instance, query)
+		router.ServeHTTP(rr, httptest.NewRequest("POST", path, nil))
+		return rr
+	}
+
+	rr := deleteSeries("a", `match[]=up{job="a"}&match[]={__name__=~"go_.*"}`)
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	require.JSONEq(t, `{
+		"status": "success",
+		"data": {"instance": "a", "deleted_series": 2}
+	}`, rr.Body.String())
+	require.Equal(t, [][]*labels.Matcher{
+		{
+			labels.MustNewMatcher(labels.MatchEqual, "job", "a"),
+			labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"),
+		},
+		{labels.MustNewMatcher(labels.MatchRegexp, "__name__", "go_.*")},
+	}, deleter.selectors)
+
+	tt := []struct {
+		name     string
+		instance string
+		query    string
+		status   int
+	}{
+		{name: "no selector", instance: "a", query: "", status: http.StatusBadRequest},
+		{name: "invalid selector", instance: "a", query: "match[]=up{", status: http.StatusBadRequest},
+		{name: "unknown instance", instance: "c", query: "match[]=up", status: http.StatusNotFound},
+		{name: "unsupported instance", instance: "b", query: "match[]=up", status: http.StatusBadRequest},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := deleteSeries(tc.instance, tc.query)
+			require.Equal(t, tc.status, rr.Result().StatusCode)
+		})
+	}
+}
+
+type mockInstanceDeleteSeries struct {
+	mockInstanceScrape
+	selectors [][]*labels.Matcher
+}
+
+func (i *mockInstanceDeleteSeries) DeleteSeries(selectors ...[]*labels.Matcher) (int, error) {
+	i.selectors = selectors
+	return len(selectors), nil
+}
+
+type mockInstanceRelabelDrops struct {
+	mockInstanceScrape
+	status []instance.RelabelDropStatus
+}
+
+func (i *mockInstanceRelabelDrops) RelabelDrops(limit int) []instance.RelabelDropStatus {
+	res := make([]instance.RelabelDropStatus, 0, len(i.status))
+	for _, s := range i.status {
+		if len(s.TopMetrics) > limit {
+			s.TopMetrics = s.TopMetrics[:limit]
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+type mockInstanceReplay struct {
+	mockInstanceScrape
+	status wal.ReplayStatus
+}
+
+func (i *mockInstanceReplay) WALReplayStatus() wal.ReplayStatus {
+	return i.status
 }
 
 type mockInstanceScrape struct {
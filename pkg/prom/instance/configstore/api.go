@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -15,6 +17,8 @@ import (
 	"github.com/grafana/agent/pkg/prom/cluster/configapi"
 	"github.com/grafana/agent/pkg/prom/instance"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 )
 
 // API is an HTTP API to interact with a configstore.
@@ -82,7 +86,12 @@ func (api *API) Collect(mm chan<- prometheus.Metric) {
 	mm <- api.totalDeletedConfigs
 }
 
-// ListConfigurations returns a list of configurations.
+// ListConfigurations returns a list of configurations, sorted by name.
+//
+// Configs may be filtered by their labels with the selector query parameter,
+// e.g., {team="infra"}. Results may be paginated with the limit and after
+// query parameters, where after is the name of the last config from the
+// previous page.
 func (api *API) ListConfigurations(rw http.ResponseWriter, r *http.Request) {
 	api.storeMut.Lock()
 	defer api.storeMut.Unlock()
@@ -91,15 +100,95 @@ func (api *API) ListConfigurations(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	keys, err := api.store.List(r.Context())
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		api.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	keys, err := api.listKeys(r, opts)
 	if errors.Is(err, ErrNotConnected) {
 		api.writeError(rw, http.StatusNotFound, fmt.Errorf("no config store running"))
 		return
 	} else if err != nil {
-		api.writeError(rw, http.StatusInternalServerError, fmt.Errorf("failed to write config: %w", err))
+		api.writeError(rw, http.StatusInternalServerError, fmt.Errorf("failed to list configs: %w", err))
 		return
 	}
-	api.writeResponse(rw, http.StatusOK, configapi.ListConfigurationsResponse{Configs: keys})
+
+	sort.Strings(keys)
+
+	// listKeys only filters by opts.after when reading full configs, so find
+	// the start of the page here.
+	start := sort.Search(len(keys), func(i int) bool { return keys[i] > opts.after })
+
+	resp := configapi.ListConfigurationsResponse{Configs: keys[start:]}
+	if opts.limit > 0 && len(resp.Configs) > opts.limit {
+		resp.Configs = resp.Configs[:opts.limit]
+		resp.Next = resp.Configs[opts.limit-1]
+	}
+	api.writeResponse(rw, http.StatusOK, resp)
+}
+
+type listOptions struct {
+	matchers []*labels.Matcher
+	limit    int
+	after    string
+}
+
+func parseListOptions(v url.Values) (listOptions, error) {
+	var (
+		opts listOptions
+		err  error
+	)
+
+	if selector := v.Get("selector"); selector != "" {
+		opts.matchers, err = parser.ParseMetricSelector(selector)
+		if err != nil {
+			return opts, fmt.Errorf("invalid selector: %w", err)
+		}
+	}
+	if limit := v.Get("limit"); limit != "" {
+		opts.limit, err = strconv.Atoi(limit)
+		if err != nil || opts.limit < 0 {
+			return opts, fmt.Errorf("invalid limit %q: must be a non-negative integer", limit)
+		}
+	}
+	opts.after = v.Get("after")
+
+	return opts, nil
+}
+
+// listKeys returns the unsorted list of config names that match opts. When
+// there are matchers, every config after opts.after is read from the store to
+// check its labels.
+func (api *API) listKeys(r *http.Request, opts listOptions) ([]string, error) {
+	if len(opts.matchers) == 0 {
+		return api.store.List(r.Context())
+	}
+
+	ch, err := api.store.All(r.Context(), func(key string) bool { return key > opts.after })
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for cfg := range ch {
+		if matchLabels(opts.matchers, cfg.Labels) {
+			keys = append(keys, cfg.Name)
+		}
+	}
+	return keys, nil
+}
+
+// matchLabels returns true if lset matches all matchers. Labels that
+// aren't set are treated as having an empty value.
+func matchLabels(matchers []*labels.Matcher, lset map[string]string) bool {
+	for _, m := range matchers {
+		if !m.Matches(lset[m.Name]) {
+			return false
+		}
+	}
+	return true
 }
 
 // GetConfiguration gets an individual configuration.
@@ -137,7 +226,9 @@ func (api *API) GetConfiguration(rw http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// PutConfiguration creates or updates a configuration.
+// PutConfiguration creates or updates a configuration. If the dry_run query
+// parameter is true, the configuration is only validated and returned with
+// defaults applied.
 func (api *API) PutConfiguration(rw http.ResponseWriter, r *http.Request) {
 	api.storeMut.Lock()
 	defer api.storeMut.Unlock()
@@ -152,6 +243,15 @@ func (api *API) PutConfiguration(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var dryRun bool
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			api.writeError(rw, http.StatusBadRequest, fmt.Errorf("invalid dry_run value: %w", err))
+			return
+		}
+	}
+
 	var config strings.Builder
 	if _, err := io.Copy(&config, r.Body); err != nil {
 		api.writeError(rw, http.StatusInternalServerError, err)
@@ -165,6 +265,11 @@ func (api *API) PutConfiguration(rw http.ResponseWriter, r *http.Request) {
 	}
 	cfg.Name = configName
 
+	// resolvedCfg is the config with defaults applied by the validator. It's
+	// only returned for dry runs; the original config is stored so defaults
+	// can change over time.
+	resolvedCfg := cfg
+
 	if api.validator != nil {
 		validateCfg, err := instance.UnmarshalConfig(strings.NewReader(config.String()))
 		if err != nil {
@@ -177,6 +282,19 @@ func (api *API) PutConfiguration(rw http.ResponseWriter, r *http.Request) {
 			api.writeError(rw, http.StatusBadRequest, fmt.Errorf("failed to validate config: %w", err))
 			return
 		}
+		resolvedCfg = validateCfg
+	}
+
+	if dryRun {
+		bb, err := instance.MarshalConfig(resolvedCfg, false)
+		if err != nil {
+			api.writeError(rw, http.StatusInternalServerError, fmt.Errorf("could not marshal config for response: %w", err))
+			return
+		}
+		api.writeResponse(rw, http.StatusOK, &configapi.PutConfigurationResponse{
+			Value: string(bb),
+		})
+		return
 	}
 
 	created, err := api.store.Put(r.Context(), *cfg)
@@ -3,10 +3,12 @@ package configstore
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -53,6 +55,91 @@ func TestAPI_ListConfigurations(t *testing.T) {
 	})
 }
 
+func TestAPI_ListConfigurations_Options(t *testing.T) {
+	configs := []instance.Config{
+		{Name: "d", Labels: map[string]string{"team": "infra"}},
+		{Name: "a", Labels: map[string]string{"team": "infra", "owner": "alice"}},
+		{Name: "c", Labels: map[string]string{"team": "app"}},
+		{Name: "b"},
+	}
+
+	s := &Mock{
+		ListFunc: func(ctx context.Context) ([]string, error) {
+			keys := make([]string, 0, len(configs))
+			for _, cfg := range configs {
+				keys = append(keys, cfg.Name)
+			}
+			return keys, nil
+		},
+		AllFunc: func(ctx context.Context, keep func(key string) bool) (<-chan instance.Config, error) {
+			ch := make(chan instance.Config, len(configs))
+			for _, cfg := range configs {
+				if keep == nil || keep(cfg.Name) {
+					ch <- cfg
+				}
+			}
+			close(ch)
+			return ch, nil
+		},
+	}
+
+	api := NewAPI(log.NewNopLogger(), s, nil)
+	env := newAPITestEnvironment(t, api)
+	cli := client.New(env.srv.URL)
+
+	tt := []struct {
+		name   string
+		opts   client.ListConfigsOptions
+		expect configapi.ListConfigurationsResponse
+	}{
+		{
+			name:   "sorted",
+			expect: configapi.ListConfigurationsResponse{Configs: []string{"a", "b", "c", "d"}},
+		},
+		{
+			name:   "first page",
+			opts:   client.ListConfigsOptions{Limit: 2},
+			expect: configapi.ListConfigurationsResponse{Configs: []string{"a", "b"}, Next: "b"},
+		},
+		{
+			name:   "last page",
+			opts:   client.ListConfigsOptions{Limit: 2, After: "b"},
+			expect: configapi.ListConfigurationsResponse{Configs: []string{"c", "d"}},
+		},
+		{
+			name:   "selector",
+			opts:   client.ListConfigsOptions{Selector: `{team="infra"}`},
+			expect: configapi.ListConfigurationsResponse{Configs: []string{"a", "d"}},
+		},
+		{
+			name:   "selector with missing label",
+			opts:   client.ListConfigsOptions{Selector: `{team=""}`},
+			expect: configapi.ListConfigurationsResponse{Configs: []string{"b"}},
+		},
+		{
+			name:   "paginated selector",
+			opts:   client.ListConfigsOptions{Selector: `{team=~"infra|app"}`, Limit: 1, After: "a"},
+			expect: configapi.ListConfigurationsResponse{Configs: []string{"c"}, Next: "c"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := cli.ListConfigsWithOptions(context.Background(), tc.opts)
+			require.NoError(t, err)
+			require.Equal(t, &tc.expect, resp)
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		for _, query := range []string{"limit=-1", "limit=foo", "selector=foo{"} {
+			resp, err := http.Get(env.srv.URL + "/agent/api/v1/configs?" + query)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+}
+
 func TestAPI_GetConfiguration_Invalid(t *testing.T) {
 	s := &Mock{
 		GetFunc: func(ctx context.Context, key string) (instance.Config, error) {
@@ -188,6 +275,51 @@ func TestServer_PutConfiguration_Invalid(t *testing.T) {
 	require.JSONEq(t, expect, string(body))
 }
 
+func TestServer_PutConfiguration_DryRun(t *testing.T) {
+	var s Mock
+	s.PutFunc = func(ctx context.Context, c instance.Config) (created bool, err error) {
+		require.FailNow(t, "dry runs should not store the config")
+		return false, nil
+	}
+
+	api := NewAPI(log.NewNopLogger(), &s, func(c *instance.Config) error {
+		return c.ApplyDefaults(instance.DefaultGlobalConfig)
+	})
+	env := newAPITestEnvironment(t, api)
+
+	bb := []byte(`
+scrape_configs:
+- job_name: test
+  static_configs:
+  - targets: ['127.0.0.1:12345']`)
+
+	t.Run("Valid", func(t *testing.T) {
+		resp, err := http.Post(env.srv.URL+"/agent/api/v1/config/newconfig?dry_run=true", "", bytes.NewReader(bb))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var apiResp struct {
+			Status string                             `json:"status"`
+			Data   configapi.PutConfigurationResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiResp))
+		require.Equal(t, "success", apiResp.Status)
+
+		// The returned config should have defaults applied.
+		resolved, err := instance.UnmarshalConfig(strings.NewReader(apiResp.Data.Value))
+		require.NoError(t, err)
+		require.Equal(t, "newconfig", resolved.Name)
+		require.Len(t, resolved.ScrapeConfigs, 1)
+		require.Equal(t, instance.DefaultGlobalConfig.Prometheus.ScrapeInterval, resolved.ScrapeConfigs[0].ScrapeInterval)
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		resp, err := http.Post(env.srv.URL+"/agent/api/v1/config/newconfig?dry_run=maybe", "", bytes.NewReader(bb))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
 func TestServer_PutConfiguration_WithClient(t *testing.T) {
 	var s Mock
 	api := NewAPI(log.NewNopLogger(), &s, nil)
@@ -263,8 +263,9 @@ func hashConfig(c Config) (string, error) {
 		return "", err
 	}
 
-	// Ignore name and scrape configs when hashing
+	// Ignore name, labels, and scrape configs when hashing
 	groupable.Name = ""
+	groupable.Labels = nil
 	groupable.ScrapeConfigs = nil
 
 	// Assign names to remote_write configs if they're not present already.
@@ -325,6 +326,7 @@ func groupConfigs(groupName string, grouped groupedConfigs) (Config, error) {
 		return Config{}, err
 	}
 	combined.Name = groupName
+	combined.Labels = nil
 	combined.ScrapeConfigs = []*config.ScrapeConfig{}
 
 	// Assign all remote_write configs in the group a consistent set of remote_names.
@@ -307,6 +307,25 @@ remote_write: []`
 		require.Equal(t, hashA, hashB)
 	})
 
+	t.Run("labels are ignored", func(t *testing.T) {
+		configAText := `
+name: configA
+labels:
+  team: a
+scrape_configs: []
+remote_write: []`
+
+		configBText := `
+name: configB
+labels:
+  team: b
+scrape_configs: []
+remote_write: []`
+
+		hashA, hashB := getHashesFromConfigs(t, configAText, configBText)
+		require.Equal(t, hashA, hashB)
+	})
+
 	t.Run("remote_writes are unordered", func(t *testing.T) {
 		configAText := `
 name: configA
@@ -133,7 +133,8 @@ func (f *HostFilter) SyncCh() GroupChannel {
 // FilterGroups takes a set of DiscoveredGroups as input and filters out
 // any Target that is not running on the host machine provided by host.
 //
-// This is done by looking at HostFilterLabelMatchers and __address__.
+// This is done by looking at HostFilterLabelMatchers and __address__ after
+// applying configs to the target. Targets dropped by configs are filtered out.
 //
 // If the discovered address is localhost or 127.0.0.1, the group is never
 // filtered out.
@@ -154,6 +155,12 @@ func FilterGroups(in DiscoveredGroups, host string, configs []*relabel.Config) D
 				allLabels := mergeSets(target, group.Labels)
 				processedLabels := relabel.Process(toLabelSlice(allLabels), configs...)
 
+				// Targets dropped by the relabel rules are never considered to
+				// be running on the host.
+				if processedLabels == nil {
+					continue
+				}
+
 				if !shouldFilterTarget(processedLabels, host) {
 					newGroup.Targets = append(newGroup.Targets, target)
 				}
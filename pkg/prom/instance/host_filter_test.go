@@ -160,6 +160,42 @@ func TestFilterGroups_Relabel(t *testing.T) {
 	}
 }
 
+func TestFilterGroups_RelabelDrop(t *testing.T) {
+	// Use a custom node label to decide ownership, dropping targets without
+	// it.
+	relabelConfig := []*relabel.Config{
+		{
+			SourceLabels: model.LabelNames{"__meta_kubernetes_node_label_custom_host"},
+			Action:       relabel.Keep,
+			Separator:    ";",
+			Regex:        relabel.MustNewRegexp(".+"),
+		},
+		{
+			SourceLabels: model.LabelNames{"__meta_kubernetes_node_label_custom_host"},
+			Action:       relabel.Replace,
+			Separator:    ";",
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Replacement:  "$1",
+			TargetLabel:  "__host__",
+		},
+	}
+
+	group := makeGroup([]model.LabelSet{
+		// Local target using the custom label.
+		{model.AddressLabel: "10.0.0.1:80", "__meta_kubernetes_node_label_custom_host": "myhost"},
+		// Remote target using the custom label.
+		{model.AddressLabel: "10.0.0.2:80", "__meta_kubernetes_node_label_custom_host": "otherhost"},
+		// Dropped target; would have been local by the default meta labels.
+		{model.AddressLabel: "10.0.0.3:80", "__meta_kubernetes_pod_node_name": "myhost"},
+	})
+
+	groups := DiscoveredGroups{"test": []*targetgroup.Group{group}}
+	result := FilterGroups(groups, "myhost", relabelConfig)
+
+	require.Len(t, result["test"], 1)
+	require.Equal(t, []model.LabelSet{group.Targets[0]}, result["test"][0].Targets)
+}
+
 func TestHostFilter_PatchSD(t *testing.T) {
 	rawInput := util.Untab(`
 - job_name: default
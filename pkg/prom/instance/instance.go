@@ -23,8 +23,10 @@ import (
 	"github.com/grafana/agent/pkg/util"
 	"github.com/oklog/run"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/relabel"
 	"github.com/prometheus/prometheus/pkg/timestamp"
 	"github.com/prometheus/prometheus/scrape"
@@ -58,11 +60,23 @@ var (
 // Config is a specific agent that runs within the overall Prometheus
 // agent. It has its own set of scrape_configs and remote_write rules.
 type Config struct {
-	Name                     string                      `yaml:"name,omitempty"`
-	HostFilter               bool                        `yaml:"host_filter,omitempty"`
-	HostFilterRelabelConfigs []*relabel.Config           `yaml:"host_filter_relabel_configs,omitempty"`
-	ScrapeConfigs            []*config.ScrapeConfig      `yaml:"scrape_configs,omitempty"`
-	RemoteWrite              []*config.RemoteWriteConfig `yaml:"remote_write,omitempty"`
+	Name                     string                 `yaml:"name,omitempty"`
+	HostFilter               bool                   `yaml:"host_filter,omitempty"`
+	HostFilterRelabelConfigs []*relabel.Config      `yaml:"host_filter_relabel_configs,omitempty"`
+	ScrapeConfigs            []*config.ScrapeConfig `yaml:"scrape_configs,omitempty"`
+	RemoteWrite              []*RemoteWriteConfig   `yaml:"remote_write,omitempty"`
+
+	// Labels organize configs in the config management API, such as by owner
+	// or team. They aren't added to scraped metrics.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Priority of the instance's scrape jobs when the resource governor
+	// pauses scraping. Instances with the lowest priority are paused first.
+	Priority int `yaml:"priority,omitempty"`
+
+	// ExternalLabels are added to series sent over remote_write, overriding
+	// global external_labels with the same name.
+	ExternalLabels labels.Labels `yaml:"external_labels,omitempty"`
 
 	// How frequently the WAL should be truncated.
 	WALTruncateFrequency time.Duration `yaml:"wal_truncate_frequency,omitempty"`
@@ -74,6 +88,13 @@ type Config struct {
 	RemoteFlushDeadline  time.Duration `yaml:"remote_flush_deadline,omitempty"`
 	WriteStaleOnShutdown bool          `yaml:"write_stale_on_shutdown,omitempty"`
 
+	// Default limits for scrape_configs that don't set their own.
+	SampleLimit           uint `yaml:"sample_limit,omitempty"`
+	TargetLimit           uint `yaml:"target_limit,omitempty"`
+	LabelLimit            uint `yaml:"label_limit,omitempty"`
+	LabelNameLengthLimit  uint `yaml:"label_name_length_limit,omitempty"`
+	LabelValueLengthLimit uint `yaml:"label_value_length_limit,omitempty"`
+
 	global GlobalConfig `yaml:"-"`
 }
 
@@ -123,6 +144,17 @@ func (c *Config) ApplyDefaults(global GlobalConfig) error {
 		return errors.New("min_wal_time must be less than max_wal_time")
 	}
 
+	for name := range c.Labels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	for _, l := range c.ExternalLabels {
+		if !model.LabelName(l.Name).IsValid() {
+			return fmt.Errorf("invalid external label name %q", l.Name)
+		}
+	}
+
 	jobNames := map[string]struct{}{}
 	for _, sc := range c.ScrapeConfigs {
 		if sc == nil {
@@ -148,6 +180,8 @@ func (c *Config) ApplyDefaults(global GlobalConfig) error {
 			}
 		}
 
+		c.applyScrapeLimits(sc)
+
 		if _, exists := jobNames[sc.JobName]; exists {
 			return fmt.Errorf("found multiple scrape configs with job name %q", sc.JobName)
 		}
@@ -155,6 +189,7 @@ func (c *Config) ApplyDefaults(global GlobalConfig) error {
 	}
 
 	rwNames := map[string]struct{}{}
+	tenantLabels := map[string]*RemoteWriteConfig{}
 
 	// If the instance remote write is not filled in, then apply the prometheus write config
 	if len(c.RemoteWrite) == 0 {
@@ -170,7 +205,14 @@ func (c *Config) ApplyDefaults(global GlobalConfig) error {
 		// an instance.
 		var generatedName bool
 		if cfg.Name == "" {
-			hash, err := getHash(cfg)
+			// Only include the agent-specific settings in the hash when they're
+			// set so generated names don't change for existing configs.
+			var hashed interface{} = cfg.RemoteWriteConfig
+			if cfg.TenantLabel != "" || cfg.AzureAD != nil || cfg.Google != nil {
+				hashed = cfg
+			}
+
+			hash, err := getHash(hashed)
 			if err != nil {
 				return err
 			}
@@ -189,11 +231,73 @@ func (c *Config) ApplyDefaults(global GlobalConfig) error {
 			return fmt.Errorf("found duplicate remote write configs with name %q", cfg.Name)
 		}
 		rwNames[cfg.Name] = struct{}{}
+
+		if err := cfg.validateAuth(); err != nil {
+			return fmt.Errorf("invalid remote write config with name %q: %w", cfg.Name, err)
+		}
+
+		cfg.applyQueueDefaults()
+		if err := cfg.validateQueue(); err != nil {
+			return fmt.Errorf("invalid remote write config with name %q: %w", cfg.Name, err)
+		}
+
+		if cfg.TenantLabel == "" {
+			continue
+		}
+		if !model.LabelName(cfg.TenantLabel).IsValid() {
+			return fmt.Errorf("invalid tenant_label %q for remote write config with name %q", cfg.TenantLabel, cfg.Name)
+		}
+		if cfg.MaxTenants == 0 {
+			cfg.MaxTenants = DefaultMaxTenants
+		}
+		if cfg.TenantIdleTimeout == 0 {
+			cfg.TenantIdleTimeout = DefaultTenantIdleTimeout
+		}
+
+		// Tenants are tracked per label, so configs sharing a label must
+		// agree on how many tenants to track and for how long.
+		if other, ok := tenantLabels[cfg.TenantLabel]; ok {
+			if other.MaxTenants != cfg.MaxTenants || other.TenantIdleTimeout != cfg.TenantIdleTimeout {
+				return fmt.Errorf("remote write configs %q and %q use tenant_label %q with different max_tenants or tenant_idle_timeout", other.Name, cfg.Name, cfg.TenantLabel)
+			}
+		}
+		tenantLabels[cfg.TenantLabel] = cfg
 	}
 
 	return nil
 }
 
+// externalLabels returns the global external labels merged with the
+// external labels of c. Labels from c take precedence, and labels from c with
+// an empty value remove the global label.
+func (c *Config) externalLabels() labels.Labels {
+	lb := labels.NewBuilder(c.global.Prometheus.ExternalLabels)
+	for _, l := range c.ExternalLabels {
+		lb.Set(l.Name, l.Value)
+	}
+	return lb.Labels()
+}
+
+// applyScrapeLimits sets the instance-wide scrape limits on sc for every
+// limit that sc doesn't define.
+func (c *Config) applyScrapeLimits(sc *config.ScrapeConfig) {
+	if sc.SampleLimit == 0 {
+		sc.SampleLimit = c.SampleLimit
+	}
+	if sc.TargetLimit == 0 {
+		sc.TargetLimit = c.TargetLimit
+	}
+	if sc.LabelLimit == 0 {
+		sc.LabelLimit = c.LabelLimit
+	}
+	if sc.LabelNameLengthLimit == 0 {
+		sc.LabelNameLengthLimit = c.LabelNameLengthLimit
+	}
+	if sc.LabelValueLengthLimit == 0 {
+		sc.LabelValueLengthLimit = c.LabelValueLengthLimit
+	}
+}
+
 // Clone makes a deep copy of the config along with global settings.
 func (c *Config) Clone() (Config, error) {
 	bb, err := MarshalConfig(c, false)
@@ -213,7 +317,7 @@ func (c *Config) Clone() (Config, error) {
 		cp.ScrapeConfigs = []*config.ScrapeConfig{}
 	}
 	if cp.RemoteWrite == nil && c.RemoteWrite != nil {
-		cp.RemoteWrite = []*config.RemoteWriteConfig{}
+		cp.RemoteWrite = []*RemoteWriteConfig{}
 	}
 
 	return *cp, nil
@@ -234,33 +338,52 @@ type Instance struct {
 	discovery          *discoveryService
 	readyScrapeManager *readyScrapeManager
 	remoteStore        *remote.Storage
+	remoteWrite        []*config.RemoteWriteConfig
+	tenants            *tenantTracker
+	tokens             *remoteWriteTokens
+	relabelDrops       *relabelDropTracker
 	storage            storage.Storage
 
 	hostFilter *HostFilter
+	sharder    TargetSharder
+	pause      *scrapePause
 
 	logger log.Logger
 
 	reg    prometheus.Registerer
 	newWal walStorageFactory
 
+	// replay tracks the progress of replaying the WAL. May be nil if the
+	// instance doesn't use a wal.Storage.
+	replay *wal.ReplayProgress
+
 	vc *MetricValueCollector
 }
 
 // New creates a new Instance with a directory for storing the WAL. The instance
 // will not start until Run is called on the instance.
-func New(reg prometheus.Registerer, cfg Config, walDir string, logger log.Logger) (*Instance, error) {
+//
+// If sharder is non-nil, discovered targets not owned by sharder will not be
+// scraped.
+func New(reg prometheus.Registerer, cfg Config, walDir string, sharder TargetSharder, logger log.Logger) (*Instance, error) {
 	logger = log.With(logger, "instance", cfg.Name)
 
 	instWALDir := filepath.Join(walDir, cfg.Name)
+	replay := wal.NewReplayProgress()
 
 	newWal := func(reg prometheus.Registerer) (walStorage, error) {
-		return wal.NewStorage(logger, reg, instWALDir)
+		return wal.NewStorageWithProgress(logger, reg, instWALDir, replay)
 	}
 
-	return newInstance(cfg, reg, logger, newWal)
+	inst, err := newInstance(cfg, reg, logger, sharder, newWal)
+	if err != nil {
+		return nil, err
+	}
+	inst.replay = replay
+	return inst, nil
 }
 
-func newInstance(cfg Config, reg prometheus.Registerer, logger log.Logger, newWal walStorageFactory) (*Instance, error) {
+func newInstance(cfg Config, reg prometheus.Registerer, logger log.Logger, sharder TargetSharder, newWal walStorageFactory) (*Instance, error) {
 	vc := NewMetricValueCollector(prometheus.DefaultGatherer, remoteWriteMetricName)
 
 	hostname, err := Hostname()
@@ -273,6 +396,11 @@ func newInstance(cfg Config, reg prometheus.Registerer, logger log.Logger, newWa
 		logger:     logger,
 		vc:         vc,
 		hostFilter: NewHostFilter(hostname, cfg.HostFilterRelabelConfigs),
+		sharder:    sharder,
+		pause:      newScrapePause(),
+		tenants:    newTenantTracker(),
+
+		relabelDrops: newRelabelDropTracker(),
 
 		reg:    reg,
 		newWal: newWal,
@@ -306,6 +434,10 @@ func (i *Instance) Run(ctx context.Context) error {
 	trackingReg := util.WrapWithUnregisterer(i.reg)
 	defer trackingReg.UnregisterAll()
 
+	// Stop refreshing remote_write access tokens once the remote storage has
+	// been closed.
+	defer i.stopTokens()
+
 	if err := i.initialize(ctx, trackingReg, &cfg); err != nil {
 		level.Error(i.logger).Log("msg", "failed to initialize instance", "err", err)
 		return fmt.Errorf("failed to initialize instance: %w", err)
@@ -341,6 +473,20 @@ func (i *Instance) Run(ctx context.Context) error {
 			},
 		)
 	}
+	{
+		// Tenant watcher
+		ctx, contextCancel := context.WithCancel(context.Background())
+		defer contextCancel()
+		rg.Add(
+			func() error {
+				i.tenantLoop(ctx)
+				return nil
+			},
+			func(err error) {
+				contextCancel()
+			},
+		)
+	}
 	{
 		sm, err := i.readyScrapeManager.Get()
 		if err != nil {
@@ -418,17 +564,30 @@ func (i *Instance) initialize(ctx context.Context, reg prometheus.Registerer, cf
 	// Setup the remote storage
 	remoteLogger := log.With(i.logger, "component", "remote")
 	i.remoteStore = remote.NewStorage(remoteLogger, reg, i.wal.StartTime, i.wal.Directory(), cfg.RemoteFlushDeadline, i.readyScrapeManager)
-	err = i.remoteStore.ApplyConfig(&config.Config{
-		GlobalConfig:       cfg.global.Prometheus,
-		RemoteWriteConfigs: cfg.RemoteWrite,
+	i.tokens = newRemoteWriteTokens(filepath.Join(i.wal.Directory(), "remote_write_tokens"), remoteLogger)
+
+	// Seed tenants from series loaded from the WAL so tenants found before a
+	// restart have their queues created immediately.
+	i.tenants.SetLabels(cfg.RemoteWrite)
+	walTenants := make(map[tenantKey]struct{})
+	tenantLabels := i.tenants.labelNames()
+	i.wal.ForEachSeries(func(lset labels.Labels) {
+		collectTenants(walTenants, tenantLabels, lset)
 	})
-	if err != nil {
+	i.tenants.record(walTenants, time.Now())
+
+	if err := i.applyRemoteWrite(cfg); err != nil {
 		return fmt.Errorf("failed applying config to remote storage: %w", err)
 	}
 
 	i.storage = storage.NewFanout(i.logger, i.wal, i.remoteStore)
 
-	scrapeManager := newScrapeManager(log.With(i.logger, "component", "scrape manager"), i.storage)
+	if err := reg.Register(i.relabelDrops.Collector()); err != nil {
+		return fmt.Errorf("failed to register write_relabel_configs metrics: %w", err)
+	}
+	appendable := i.tenants.Appendable(i.relabelDrops.Appendable(i.storage))
+
+	scrapeManager := newScrapeManager(log.With(i.logger, "component", "scrape manager"), appendable)
 	err = scrapeManager.ApplyConfig(&config.Config{
 		GlobalConfig:  cfg.global.Prometheus,
 		ScrapeConfigs: cfg.ScrapeConfigs,
@@ -501,10 +660,8 @@ func (i *Instance) Update(c Config) (err error) {
 		i.hostFilter.PatchSD(c.ScrapeConfigs)
 	}
 
-	err = i.remoteStore.ApplyConfig(&config.Config{
-		GlobalConfig:       c.global.Prometheus,
-		RemoteWriteConfigs: c.RemoteWrite,
-	})
+	i.tenants.SetLabels(c.RemoteWrite)
+	err = i.applyRemoteWrite(&c)
 	if err != nil {
 		return fmt.Errorf("error applying new remote_write configs: %w", err)
 	}
@@ -533,6 +690,109 @@ func (i *Instance) Update(c Config) (err error) {
 	return nil
 }
 
+// ScrapePriority returns the priority of the instance's scrape jobs.
+func (i *Instance) ScrapePriority() int {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	return i.cfg.Priority
+}
+
+// SetScrapePaused pauses or resumes scraping all targets of the instance.
+// Scraping stays paused when the instance is updated or restarted.
+func (i *Instance) SetScrapePaused(paused bool) {
+	i.pause.Set(paused)
+}
+
+// applyRemoteWrite applies the remote_write configs from cfg to the remote
+// storage, creating a queue for each tenant found so far. The mutex must be
+// held when calling applyRemoteWrite.
+func (i *Instance) applyRemoteWrite(cfg *Config) error {
+	withTokens, err := i.tokens.ApplyConfig(cfg.RemoteWrite)
+	if err != nil {
+		return err
+	}
+	rw := prometheusRemoteWriteConfigs(withTokens, i.tenants.Tenants)
+
+	global := cfg.global.Prometheus
+	global.ExternalLabels = cfg.externalLabels()
+
+	err = i.remoteStore.ApplyConfig(&config.Config{
+		GlobalConfig:       global,
+		RemoteWriteConfigs: rw,
+	})
+	if err != nil {
+		return err
+	}
+	i.remoteWrite = rw
+	i.relabelDrops.SetConfigs(cfg.RemoteWrite, global.ExternalLabels)
+	return nil
+}
+
+// stopTokens stops refreshing remote_write access tokens.
+func (i *Instance) stopTokens() {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.tokens != nil {
+		i.tokens.Stop()
+	}
+}
+
+// tenantLoop re-applies the remote_write configs whenever a new tenant is
+// found so that a queue is created for it, and periodically removes the
+// queues of idle tenants. tenantLoop runs until ctx is canceled.
+func (i *Instance) tenantLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	var lastRejected int64
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.tenants.Changed():
+			i.applyTenants()
+		case <-ticker.C:
+			if i.tenants.expire(time.Now()) {
+				i.applyTenants()
+			}
+
+			if rejected := i.tenants.Rejected(); rejected > lastRejected {
+				level.Warn(i.logger).Log("msg", "dropped samples for new tenants because max_tenants was reached", "count", rejected-lastRejected)
+				lastRejected = rejected
+			}
+		}
+	}
+}
+
+// applyTenants re-applies the remote_write configs so there is a queue for
+// each currently tracked tenant.
+func (i *Instance) applyTenants() {
+	i.mut.Lock()
+	cfg := i.cfg
+	err := i.applyRemoteWrite(&cfg)
+	i.mut.Unlock()
+
+	if err != nil {
+		level.Error(i.logger).Log("msg", "failed to update remote_write queues for tenants", "err", err)
+	}
+}
+
+// observeTargetTenants records tenants found in the labels of discovered
+// targets. Queues for new tenants are created before returning so they exist
+// before the targets are first scraped.
+func (i *Instance) observeTargetTenants(groups DiscoveredGroups) {
+	i.mut.Lock()
+	scrapeConfigs := i.cfg.ScrapeConfigs
+	i.mut.Unlock()
+
+	seen := targetTenants(groups, scrapeConfigs, i.tenants.labelNames())
+	if i.tenants.record(seen, time.Now()) {
+		i.applyTenants()
+	}
+}
+
 // TargetsActive returns the set of active targets from the scrape manager. Returns nil
 // if the scrape manager is not ready yet.
 func (i *Instance) TargetsActive() map[string][]*scrape.Target {
@@ -553,6 +813,37 @@ func (i *Instance) TargetsActive() map[string][]*scrape.Target {
 	return mgr.TargetsActive()
 }
 
+// WALReplayStatus returns the progress of replaying the WAL from the last time
+// the instance was started. Replaying the WAL happens before the instance
+// starts scraping.
+func (i *Instance) WALReplayStatus() wal.ReplayStatus {
+	if i.replay == nil {
+		return wal.ReplayStatus{}
+	}
+	return i.replay.Status()
+}
+
+// RelabelDrops returns the samples dropped by the write_relabel_configs of
+// each remote_write config since the instance started, with up to limit
+// metric names that had the most samples dropped.
+func (i *Instance) RelabelDrops(limit int) []RelabelDropStatus {
+	return i.relabelDrops.Status(limit)
+}
+
+// DeleteSeries removes the series matching any of the selectors from the
+// WAL and returns the number of removed series. It fails if the instance
+// isn't running.
+func (i *Instance) DeleteSeries(selectors ...[]*labels.Matcher) (int, error) {
+	i.mut.Lock()
+	w := i.wal
+	i.mut.Unlock()
+
+	if w == nil {
+		return 0, fmt.Errorf("instance is not running")
+	}
+	return w.DeleteSeries(selectors...)
+}
+
 // StorageDirectory returns the directory where this Instance is writing series
 // and samples to for the WAL.
 func (i *Instance) StorageDirectory() string {
@@ -561,7 +852,7 @@ func (i *Instance) StorageDirectory() string {
 
 // Appender returns a storage.Appender from the instance's WAL
 func (i *Instance) Appender(ctx context.Context) storage.Appender {
-	return i.wal.Appender(ctx)
+	return i.tenants.Appendable(i.relabelDrops.Appendable(i.wal)).Appender(ctx)
 }
 
 type discoveryService struct {
@@ -628,6 +919,57 @@ func (i *Instance) newDiscoveryManager(ctx context.Context, cfg *Config) (*disco
 		syncChFunc = i.hostFilter.SyncCh
 	}
 
+	// If target sharding is enabled, run it after host filtering and use its
+	// channel for discovered targets.
+	if i.sharder != nil {
+		shardFilter := NewShardFilter(log.With(i.logger, "component", "shard filter"), i.sharder)
+		inputCh := syncChFunc()
+
+		rg.Add(func() error {
+			shardFilter.Run(inputCh)
+			level.Info(i.logger).Log("msg", "shard filterer stopped")
+			return nil
+		}, func(_ error) {
+			level.Info(i.logger).Log("msg", "stopping shard filterer...")
+			shardFilter.Stop()
+		})
+
+		syncChFunc = shardFilter.SyncCh
+	}
+
+	// Record tenants from the remaining targets so their queues exist before
+	// they're scraped. This always runs since tenant_label may be set by a
+	// later update.
+	{
+		tenantDiscoverer := newTenantDiscoverer(i.observeTargetTenants)
+		inputCh := syncChFunc()
+
+		rg.Add(func() error {
+			tenantDiscoverer.Run(inputCh)
+			return nil
+		}, func(_ error) {
+			tenantDiscoverer.Stop()
+		})
+
+		syncChFunc = tenantDiscoverer.SyncCh
+	}
+
+	// Remove all targets while scraping is paused. This runs after tenants
+	// are discovered so paused targets keep their tenant's queue.
+	{
+		pauseFilter := newPauseFilter(i.pause)
+		inputCh := syncChFunc()
+
+		rg.Add(func() error {
+			pauseFilter.Run(inputCh)
+			return nil
+		}, func(_ error) {
+			pauseFilter.Stop()
+		})
+
+		syncChFunc = pauseFilter.SyncCh
+	}
+
 	return &discoveryService{
 		Manager: manager,
 
@@ -691,13 +1033,13 @@ func (i *Instance) getRemoteWriteTimestamp() int64 {
 	i.mut.Lock()
 	defer i.mut.Unlock()
 
-	if len(i.cfg.RemoteWrite) == 0 {
+	if len(i.remoteWrite) == 0 {
 		return timestamp.FromTime(time.Now())
 	}
 
-	lbls := make([]string, len(i.cfg.RemoteWrite))
+	lbls := make([]string, len(i.remoteWrite))
 	for idx := 0; idx < len(lbls); idx++ {
-		lbls[idx] = i.cfg.RemoteWrite[idx].Name
+		lbls[idx] = i.remoteWrite[idx].Name
 	}
 
 	vals, err := i.vc.GetValues("remote_name", lbls...)
@@ -736,6 +1078,8 @@ type walStorage interface {
 	WriteStalenessMarkers(remoteTsFunc func() int64) error
 	Appender(context.Context) storage.Appender
 	Truncate(mint int64) error
+	ForEachSeries(fn func(labels.Labels))
+	DeleteSeries(selectors ...[]*labels.Matcher) (int, error)
 
 	Close() error
 }
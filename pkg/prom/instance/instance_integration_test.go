@@ -65,7 +65,7 @@ name: integration_test
 scrape_configs: []
 remote_write: []
 `)
-	inst, err := New(prometheus.NewRegistry(), initialConfig, walDir, logger)
+	inst, err := New(prometheus.NewRegistry(), initialConfig, walDir, nil, logger)
 	require.NoError(t, err)
 
 	instCtx, cancel := context.WithCancel(context.Background())
@@ -131,7 +131,7 @@ name: integration_test
 scrape_configs: []
 remote_write: []
 `)
-	inst, err := New(prometheus.NewRegistry(), initialConfig, walDir, logger)
+	inst, err := New(prometheus.NewRegistry(), initialConfig, walDir, nil, logger)
 	require.NoError(t, err)
 
 	instCtx, cancel := context.WithCancel(context.Background())
@@ -184,7 +184,7 @@ name: integration_test
 scrape_configs: []
 remote_write: []
 `)
-	inst, err := New(prometheus.NewRegistry(), initialConfig, walDir, logger)
+	inst, err := New(prometheus.NewRegistry(), initialConfig, walDir, nil, logger)
 	require.NoError(t, err)
 
 	instCtx, cancel := context.WithCancel(context.Background())
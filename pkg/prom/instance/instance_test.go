@@ -16,6 +16,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	config_util "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/discovery"
@@ -54,6 +55,94 @@ remote_write:
 	}
 }
 
+func TestConfig_ScrapeConfigHonorSettings(t *testing.T) {
+	cfgText := `name: test
+scrape_configs:
+  - job_name: pushgateway
+    honor_labels: true
+    honor_timestamps: false
+    static_configs:
+      - targets: ['127.0.0.1:9091']
+  - job_name: defaults
+    static_configs:
+      - targets: ['127.0.0.1:12345']`
+
+	cfg, err := UnmarshalConfig(strings.NewReader(cfgText))
+	require.NoError(t, err)
+	require.NoError(t, cfg.ApplyDefaults(DefaultGlobalConfig))
+
+	// The settings should be passed through to Prometheus unchanged, including
+	// after being stored by the scraping service.
+	bb, err := MarshalConfig(cfg, false)
+	require.NoError(t, err)
+	cfg, err = UnmarshalConfig(strings.NewReader(string(bb)))
+	require.NoError(t, err)
+
+	require.True(t, cfg.ScrapeConfigs[0].HonorLabels)
+	require.False(t, cfg.ScrapeConfigs[0].HonorTimestamps)
+
+	require.False(t, cfg.ScrapeConfigs[1].HonorLabels)
+	require.True(t, cfg.ScrapeConfigs[1].HonorTimestamps)
+}
+
+func TestConfig_ExternalLabels(t *testing.T) {
+	global := DefaultGlobalConfig
+	global.Prometheus.ExternalLabels = labels.FromStrings("cluster", "global", "region", "us", "env", "prod")
+
+	cfgText := `name: test
+external_labels:
+  cluster: local
+  env: ""
+  team: infra`
+
+	cfg, err := UnmarshalConfig(strings.NewReader(cfgText))
+	require.NoError(t, err)
+	require.NoError(t, cfg.ApplyDefaults(global))
+
+	// Instance labels override global labels, and empty values remove them.
+	expect := labels.FromStrings("cluster", "local", "region", "us", "team", "infra")
+	require.Equal(t, expect, cfg.externalLabels())
+
+	// The global labels should not have been modified.
+	require.Equal(t, labels.FromStrings("cluster", "global", "region", "us", "env", "prod"), global.Prometheus.ExternalLabels)
+}
+
+func TestConfig_ApplyDefaults_ScrapeLimits(t *testing.T) {
+	cfgText := `name: test
+sample_limit: 1000
+target_limit: 10
+label_limit: 30
+label_name_length_limit: 100
+label_value_length_limit: 200
+scrape_configs:
+  - job_name: defaults
+    static_configs:
+      - targets: ['127.0.0.1:12345']
+  - job_name: overrides
+    sample_limit: 50
+    label_limit: 5
+    static_configs:
+      - targets: ['127.0.0.1:12345']`
+
+	cfg, err := UnmarshalConfig(strings.NewReader(cfgText))
+	require.NoError(t, err)
+	require.NoError(t, cfg.ApplyDefaults(DefaultGlobalConfig))
+
+	defaults := cfg.ScrapeConfigs[0]
+	require.Equal(t, uint(1000), defaults.SampleLimit)
+	require.Equal(t, uint(10), defaults.TargetLimit)
+	require.Equal(t, uint(30), defaults.LabelLimit)
+	require.Equal(t, uint(100), defaults.LabelNameLengthLimit)
+	require.Equal(t, uint(200), defaults.LabelValueLengthLimit)
+
+	overrides := cfg.ScrapeConfigs[1]
+	require.Equal(t, uint(50), overrides.SampleLimit)
+	require.Equal(t, uint(10), overrides.TargetLimit)
+	require.Equal(t, uint(5), overrides.LabelLimit)
+	require.Equal(t, uint(100), overrides.LabelNameLengthLimit)
+	require.Equal(t, uint(200), overrides.LabelValueLengthLimit)
+}
+
 func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 	global := DefaultGlobalConfig
 	cfg := DefaultConfig
@@ -69,7 +158,9 @@ func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 			}},
 		},
 	}}
-	cfg.RemoteWrite = []*config.RemoteWriteConfig{{Name: "write"}}
+	cfg.RemoteWrite = []*RemoteWriteConfig{{
+		RemoteWriteConfig: config.RemoteWriteConfig{Name: "write"},
+	}}
 
 	tt := []struct {
 		name     string
@@ -101,6 +192,16 @@ func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 			func(c *Config) { c.RemoteFlushDeadline = 0 },
 			fmt.Errorf("remote_flush_deadline must be greater than 0s"),
 		},
+		{
+			"invalid label name",
+			func(c *Config) { c.Labels = map[string]string{"not-a-label": "value"} },
+			fmt.Errorf("invalid label name \"not-a-label\""),
+		},
+		{
+			"invalid external label name",
+			func(c *Config) { c.ExternalLabels = labels.FromStrings("not-a-label", "value") },
+			fmt.Errorf("invalid external label name \"not-a-label\""),
+		},
 		{
 			"scrape timeout too high",
 			func(c *Config) { c.ScrapeConfigs[0].ScrapeTimeout = global.Prometheus.ScrapeInterval + 1 },
@@ -128,13 +229,88 @@ func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 		{
 			"multiple remote writes with same name",
 			func(c *Config) {
-				c.RemoteWrite = []*config.RemoteWriteConfig{
-					{Name: "foo"},
-					{Name: "foo"},
+				c.RemoteWrite = []*RemoteWriteConfig{
+					{RemoteWriteConfig: config.RemoteWriteConfig{Name: "foo"}},
+					{RemoteWriteConfig: config.RemoteWriteConfig{Name: "foo"}},
 				}
 			},
 			fmt.Errorf("found duplicate remote write configs with name \"foo\""),
 		},
+		{
+			"invalid tenant label",
+			func(c *Config) { c.RemoteWrite[0].TenantLabel = "not-a-label" },
+			fmt.Errorf("invalid tenant_label \"not-a-label\" for remote write config with name \"write\""),
+		},
+		{
+			"tenant label with different limits",
+			func(c *Config) {
+				c.RemoteWrite = []*RemoteWriteConfig{
+					{RemoteWriteConfig: config.RemoteWriteConfig{Name: "a"}, TenantLabel: "tenant"},
+					{RemoteWriteConfig: config.RemoteWriteConfig{Name: "b"}, TenantLabel: "tenant", MaxTenants: 5},
+				}
+			},
+			fmt.Errorf("remote write configs \"a\" and \"b\" use tenant_label \"tenant\" with different max_tenants or tenant_idle_timeout"),
+		},
+		{
+			"azuread and google",
+			func(c *Config) {
+				c.RemoteWrite[0].AzureAD = &AzureADConfig{Cloud: AzurePublic, ManagedIdentity: &AzureManagedIdentityConfig{}}
+				c.RemoteWrite[0].Google = &GoogleConfig{}
+			},
+			fmt.Errorf("invalid remote write config with name \"write\": must set at most one of azuread and google"),
+		},
+		{
+			"google with basic auth",
+			func(c *Config) {
+				c.RemoteWrite[0].Google = &GoogleConfig{}
+				c.RemoteWrite[0].HTTPClientConfig.BasicAuth = &config_util.BasicAuth{Username: "user"}
+			},
+			fmt.Errorf("invalid remote write config with name \"write\": azuread and google can't be used with basic_auth, authorization, bearer_token or bearer_token_file"),
+		},
+		{
+			"azuread unknown cloud",
+			func(c *Config) {
+				c.RemoteWrite[0].AzureAD = &AzureADConfig{Cloud: "AzureMoon", ManagedIdentity: &AzureManagedIdentityConfig{}}
+			},
+			fmt.Errorf("invalid remote write config with name \"write\": invalid azuread config: unsupported cloud \"AzureMoon\", expected AzurePublic, AzureChina or AzureGovernment"),
+		},
+		{
+			"azuread without credentials",
+			func(c *Config) { c.RemoteWrite[0].AzureAD = &AzureADConfig{Cloud: AzurePublic} },
+			fmt.Errorf("invalid remote write config with name \"write\": invalid azuread config: must set one of managed_identity and oauth"),
+		},
+		{
+			"azuread oauth missing tenant",
+			func(c *Config) {
+				c.RemoteWrite[0].AzureAD = &AzureADConfig{
+					Cloud: AzurePublic,
+					OAuth: &AzureOAuthConfig{ClientID: "client", ClientSecret: "secret"},
+				}
+			},
+			fmt.Errorf("invalid remote write config with name \"write\": invalid azuread config: oauth must set client_id, client_secret and tenant_id"),
+		},
+		{
+			"queue config defaults",
+			func(c *Config) {
+				c.RemoteWrite[0].QueueConfig = config.QueueConfig{MaxShards: 1000, MaxSamplesPerSend: 2000}
+			},
+			nil,
+		},
+		{
+			"negative max_samples_per_send",
+			func(c *Config) { c.RemoteWrite[0].QueueConfig.MaxSamplesPerSend = -1 },
+			fmt.Errorf("invalid remote write config with name \"write\": queue_config.max_samples_per_send must not be negative"),
+		},
+		{
+			"min_shards greater than max_shards",
+			func(c *Config) { c.RemoteWrite[0].QueueConfig = config.QueueConfig{MinShards: 10, MaxShards: 5} },
+			fmt.Errorf("invalid remote write config with name \"write\": queue_config.min_shards (10) must not be greater than max_shards (5)"),
+		},
+		{
+			"min_backoff greater than max_backoff",
+			func(c *Config) { c.RemoteWrite[0].QueueConfig.MinBackoff = model.Duration(time.Second) },
+			fmt.Errorf("invalid remote write config with name \"write\": queue_config.min_backoff (1s) must not be greater than max_backoff (100ms)"),
+		},
 	}
 
 	for _, tc := range tt {
@@ -150,7 +326,7 @@ func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 			}
 			input.ScrapeConfigs = scrapeConfigs
 
-			var remoteWrites []*config.RemoteWriteConfig
+			var remoteWrites []*RemoteWriteConfig
 			for _, rw := range input.RemoteWrite {
 				rwCopy := *rw
 				remoteWrites = append(remoteWrites, &rwCopy)
@@ -200,7 +376,7 @@ func TestInstance_Path(t *testing.T) {
 	cfg.RemoteFlushDeadline = time.Hour
 
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
-	inst, err := New(prometheus.NewRegistry(), cfg, walDir, logger)
+	inst, err := New(prometheus.NewRegistry(), cfg, walDir, nil, logger)
 	require.NoError(t, err)
 	runInstance(t, inst)
 
@@ -234,7 +410,7 @@ func TestInstance(t *testing.T) {
 	newWal := func(_ prometheus.Registerer) (walStorage, error) { return &mockStorage, nil }
 
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
-	inst, err := newInstance(cfg, nil, logger, newWal)
+	inst, err := newInstance(cfg, nil, logger, nil, newWal)
 	require.NoError(t, err)
 	runInstance(t, inst)
 
@@ -264,7 +440,7 @@ func TestInstance_Recreate(t *testing.T) {
 	cfg.RemoteFlushDeadline = time.Hour
 
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
-	inst, err := New(prometheus.NewRegistry(), cfg, walDir, logger)
+	inst, err := New(prometheus.NewRegistry(), cfg, walDir, nil, logger)
 	require.NoError(t, err)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -284,7 +460,7 @@ func TestInstance_Recreate(t *testing.T) {
 
 	// Recreate the instance, no panic should happen.
 	require.NotPanics(t, func() {
-		inst, err := New(prometheus.NewRegistry(), cfg, walDir, logger)
+		inst, err := New(prometheus.NewRegistry(), cfg, walDir, nil, logger)
 		require.NoError(t, err)
 		runInstance(t, inst)
 
@@ -411,11 +587,13 @@ type mockWalStorage struct {
 	series    map[uint64]int
 }
 
-func (s *mockWalStorage) Directory() string                          { return s.directory }
-func (s *mockWalStorage) StartTime() (int64, error)                  { return 0, nil }
-func (s *mockWalStorage) WriteStalenessMarkers(f func() int64) error { return nil }
-func (s *mockWalStorage) Close() error                               { return nil }
-func (s *mockWalStorage) Truncate(mint int64) error                  { return nil }
+func (s *mockWalStorage) Directory() string                              { return s.directory }
+func (s *mockWalStorage) StartTime() (int64, error)                      { return 0, nil }
+func (s *mockWalStorage) WriteStalenessMarkers(f func() int64) error     { return nil }
+func (s *mockWalStorage) Close() error                                   { return nil }
+func (s *mockWalStorage) Truncate(mint int64) error                      { return nil }
+func (s *mockWalStorage) ForEachSeries(fn func(labels.Labels))           {}
+func (s *mockWalStorage) DeleteSeries(...[]*labels.Matcher) (int, error) { return 0, nil }
 
 func (s *mockWalStorage) Appender(context.Context) storage.Appender {
 	return &mockAppender{s: s}
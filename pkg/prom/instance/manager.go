@@ -13,6 +13,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
 )
 
 var (
@@ -97,6 +98,10 @@ type managedProcess struct {
 	inst   ManagedInstance
 	cancel context.CancelFunc
 	done   chan bool
+
+	// restartErr is set to the error the instance stopped with while waiting
+	// to restart it.
+	restartErr *atomic.Error
 }
 
 func (p managedProcess) Stop() {
@@ -166,6 +171,21 @@ func (m *BasicManager) ListConfigs() map[string]Config {
 	return res
 }
 
+// RestartingInstances returns the instances which stopped abnormally and are
+// waiting to be restarted, along with the error they stopped with.
+func (m *BasicManager) RestartingInstances() map[string]error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	res := make(map[string]error)
+	for name, process := range m.processes {
+		if err := process.restartErr.Load(); err != nil {
+			res[name] = err
+		}
+	}
+	return res
+}
+
 // ApplyConfig takes a Config and either starts a new managed instance or
 // updates an existing managed instance. The value for Name in c is used to
 // uniquely identify the Config and determine whether the Config has an
@@ -222,11 +242,13 @@ func (m *BasicManager) spawnProcess(c Config) error {
 		done:   done,
 		cfg:    c,
 		inst:   inst,
+
+		restartErr: atomic.NewError(nil),
 	}
 	m.processes[c.Name] = proc
 
 	go func() {
-		m.runProcess(ctx, c.Name, inst)
+		m.runProcess(ctx, c.Name, inst, proc.restartErr)
 		close(done)
 
 		// Now that the process has stopped, we can remove it from our managed
@@ -253,8 +275,9 @@ func (m *BasicManager) spawnProcess(c Config) error {
 }
 
 // runProcess runs and instance and keeps it alive until it is explicitly stopped
-// by cancelling the context.
-func (m *BasicManager) runProcess(ctx context.Context, name string, inst ManagedInstance) {
+// by cancelling the context. restartErr is set to the error the instance
+// stopped with while waiting to restart it.
+func (m *BasicManager) runProcess(ctx context.Context, name string, inst ManagedInstance, restartErr *atomic.Error) {
 	for {
 		err := inst.Run(ctx)
 		if err != nil && err != context.Canceled {
@@ -262,7 +285,9 @@ func (m *BasicManager) runProcess(ctx context.Context, name string, inst Managed
 
 			instanceAbnormalExits.WithLabelValues(name).Inc()
 			level.Error(m.logger).Log("msg", "instance stopped abnormally, restarting after backoff period", "err", err, "backoff", backoff, "instance", name)
+			restartErr.Store(err)
 			time.Sleep(backoff)
+			restartErr.Store(nil)
 		} else {
 			level.Info(m.logger).Log("msg", "stopped instance", "instance", name)
 			break
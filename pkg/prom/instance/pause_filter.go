@@ -0,0 +1,130 @@
+package instance
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+// scrapePause holds whether scraping an instance is paused. It outlives
+// runs of the instance so scraping stays paused when it restarts.
+type scrapePause struct {
+	mut     sync.Mutex
+	paused  bool
+	changed chan struct{}
+}
+
+func newScrapePause() *scrapePause {
+	return &scrapePause{changed: make(chan struct{})}
+}
+
+// Set pauses or resumes scraping.
+func (p *scrapePause) Set(paused bool) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.paused == paused {
+		return
+	}
+	p.paused = paused
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// State returns whether scraping is paused and a channel which is closed the
+// next time that changes.
+func (p *scrapePause) State() (paused bool, changed <-chan struct{}) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return p.paused, p.changed
+}
+
+// pauseFilter acts as a MITM between the discovery manager and the scrape
+// manager, removing all targets while scraping is paused. The last set of
+// discovered groups is sent again when scraping is resumed, so targets are
+// scraped again without waiting for service discovery to find changes.
+type pauseFilter struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	pause    *scrapePause
+	outputCh chan DiscoveredGroups
+}
+
+func newPauseFilter(pause *scrapePause) *pauseFilter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &pauseFilter{
+		ctx:    ctx,
+		cancel: cancel,
+
+		pause:    pause,
+		outputCh: make(chan DiscoveredGroups),
+	}
+}
+
+// Run reads groups from syncCh until the pauseFilter is stopped.
+func (f *pauseFilter) Run(syncCh GroupChannel) {
+	var (
+		last       DiscoveredGroups
+		sentPaused bool
+	)
+
+	for {
+		paused, changed := f.pause.State()
+
+		// Wait for new groups unless the last groups have to be sent again
+		// because scraping was paused or resumed.
+		if last == nil || paused == sentPaused {
+			select {
+			case <-f.ctx.Done():
+				return
+			case data := <-syncCh:
+				last = data
+			case <-changed:
+				continue
+			}
+			paused, _ = f.pause.State()
+		}
+
+		out := last
+		if paused {
+			out = pausedGroups(last)
+		}
+
+		select {
+		case <-f.ctx.Done():
+			return
+		case f.outputCh <- out:
+			sentPaused = paused
+		}
+	}
+}
+
+// Stop stops the pauseFilter.
+func (f *pauseFilter) Stop() {
+	f.cancel()
+}
+
+// SyncCh returns a read only channel used by all the clients to receive
+// target updates.
+func (f *pauseFilter) SyncCh() GroupChannel {
+	return f.outputCh
+}
+
+// pausedGroups returns in without any targets. Groups are kept so the scrape
+// manager stops scraping their targets.
+func pausedGroups(in DiscoveredGroups) DiscoveredGroups {
+	out := make(DiscoveredGroups, len(in))
+	for name, groups := range in {
+		groupList := make([]*targetgroup.Group, 0, len(groups))
+		for _, group := range groups {
+			groupList = append(groupList, &targetgroup.Group{
+				Labels: group.Labels,
+				Source: group.Source,
+			})
+		}
+		out[name] = groupList
+	}
+	return out
+}
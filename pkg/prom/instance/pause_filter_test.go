@@ -0,0 +1,72 @@
+package instance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseFilter(t *testing.T) {
+	in := DiscoveredGroups{
+		"job": {{
+			Targets: []model.LabelSet{{model.AddressLabel: "localhost:12345"}},
+			Labels:  model.LabelSet{"env": "test"},
+			Source:  "source",
+		}},
+	}
+	paused := DiscoveredGroups{
+		"job": {{
+			Labels: model.LabelSet{"env": "test"},
+			Source: "source",
+		}},
+	}
+
+	pause := newScrapePause()
+	f := newPauseFilter(pause)
+	syncCh := make(chan DiscoveredGroups)
+	go f.Run(syncCh)
+	defer f.Stop()
+
+	receive := func() DiscoveredGroups {
+		t.Helper()
+		select {
+		case out := <-f.SyncCh():
+			return out
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for groups")
+			return nil
+		}
+	}
+
+	syncCh <- in
+	require.Equal(t, in, receive())
+
+	// Pausing removes the targets of the last groups.
+	pause.Set(true)
+	require.Equal(t, paused, receive())
+
+	// New groups are also sent without targets while paused.
+	syncCh <- in
+	require.Equal(t, paused, receive())
+
+	// Resuming sends the last groups again.
+	pause.Set(false)
+	require.Equal(t, in, receive())
+}
+
+func TestPausedGroups(t *testing.T) {
+	in := DiscoveredGroups{
+		"job": {&targetgroup.Group{
+			Targets: []model.LabelSet{{model.AddressLabel: "localhost:12345"}},
+			Source:  "source",
+		}},
+	}
+	out := pausedGroups(in)
+	require.Equal(t, DiscoveredGroups{"job": {&targetgroup.Group{Source: "source"}}}, out)
+
+	// The input must not be modified.
+	require.Len(t, in["job"][0].Targets, 1)
+}
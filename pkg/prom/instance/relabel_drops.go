@@ -0,0 +1,283 @@
+package instance
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Limits for tracking samples dropped by write_relabel_configs.
+var (
+	// maxDroppedMetricNames is the maximum number of metric names tracked per
+	// remote_write config. Samples for further metric names are still counted
+	// in the total.
+	maxDroppedMetricNames = 1000
+
+	// maxRelabelCacheSize is the maximum number of series to cache the
+	// relabeling result for per remote_write config before the cache is
+	// reset.
+	maxRelabelCacheSize = 100000
+)
+
+// RelabelDropStatus reports the samples dropped by the write_relabel_configs
+// of a remote_write config since the instance started.
+type RelabelDropStatus struct {
+	RemoteName     string          `json:"remote_name"`
+	DroppedSamples int64           `json:"dropped_samples"`
+	TopMetrics     []DroppedMetric `json:"top_metrics"`
+}
+
+// DroppedMetric is the number of dropped samples for a metric name.
+type DroppedMetric struct {
+	Name    string `json:"name"`
+	Samples int64  `json:"samples"`
+}
+
+// relabelDropTracker counts the samples appended to the instance which the
+// write_relabel_configs of each remote_write config drop.
+//
+// Prometheus applies write_relabel_configs inside its remote write queues,
+// which don't report what they drop, so relabeling is done again here with
+// the result cached per series.
+type relabelDropTracker struct {
+	dropped *prometheus.CounterVec
+
+	mut       sync.RWMutex
+	endpoints []*relabelDropEndpoint
+	external  labels.Labels
+}
+
+type relabelDropEndpoint struct {
+	name     string
+	relabels []*relabel.Config
+
+	mut   sync.Mutex
+	cache map[uint64]bool // Series hash to whether the series is dropped.
+	total int64
+	names map[string]int64
+}
+
+func newRelabelDropTracker() *relabelDropTracker {
+	return &relabelDropTracker{
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_remote_write_relabel_dropped_samples_total",
+			Help: "Total number of samples dropped by the write_relabel_configs of a remote_write config.",
+		}, []string{"remote_name"}),
+	}
+}
+
+// Collector returns the metrics of the tracker.
+func (t *relabelDropTracker) Collector() prometheus.Collector {
+	return t.dropped
+}
+
+// SetConfigs sets the remote_write configs to track drops for. external are
+// the external labels of the instance, which are added to series before
+// relabeling like in Prometheus. Counts for configs which are kept are
+// retained.
+func (t *relabelDropTracker) SetConfigs(rw []*RemoteWriteConfig, external labels.Labels) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	existing := make(map[string]*relabelDropEndpoint, len(t.endpoints))
+	for _, e := range t.endpoints {
+		existing[e.name] = e
+	}
+
+	endpoints := make([]*relabelDropEndpoint, 0, len(rw))
+	for _, c := range rw {
+		if len(c.WriteRelabelConfigs) == 0 {
+			continue
+		}
+
+		e := existing[c.Name]
+		if e == nil {
+			e = &relabelDropEndpoint{name: c.Name, names: make(map[string]int64)}
+		}
+		e.mut.Lock()
+		e.relabels = c.WriteRelabelConfigs
+		e.cache = make(map[uint64]bool)
+		e.mut.Unlock()
+
+		endpoints = append(endpoints, e)
+		delete(existing, c.Name)
+	}
+
+	for name := range existing {
+		t.dropped.DeleteLabelValues(name)
+	}
+	t.endpoints = endpoints
+	t.external = external
+}
+
+// Status returns the drops for each tracked remote_write config, sorted by
+// name, with up to limit metric names with the most dropped samples.
+func (t *relabelDropTracker) Status(limit int) []RelabelDropStatus {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	res := make([]RelabelDropStatus, 0, len(t.endpoints))
+	for _, e := range t.endpoints {
+		e.mut.Lock()
+		status := RelabelDropStatus{
+			RemoteName:     e.name,
+			DroppedSamples: e.total,
+			TopMetrics:     make([]DroppedMetric, 0, len(e.names)),
+		}
+		for name, samples := range e.names {
+			status.TopMetrics = append(status.TopMetrics, DroppedMetric{Name: name, Samples: samples})
+		}
+		e.mut.Unlock()
+
+		sort.Slice(status.TopMetrics, func(i, j int) bool {
+			a, b := status.TopMetrics[i], status.TopMetrics[j]
+			if a.Samples != b.Samples {
+				return a.Samples > b.Samples
+			}
+			return a.Name < b.Name
+		})
+		if limit > 0 && len(status.TopMetrics) > limit {
+			status.TopMetrics = status.TopMetrics[:limit]
+		}
+		res = append(res, status)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].RemoteName < res[j].RemoteName
+	})
+	return res
+}
+
+// droppedBy returns the endpoints whose write_relabel_configs drop lset.
+func (t *relabelDropTracker) droppedBy(lset labels.Labels) []*relabelDropEndpoint {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	if len(t.endpoints) == 0 {
+		return nil
+	}
+
+	var (
+		hash     = lset.Hash()
+		withExt  labels.Labels
+		droppers []*relabelDropEndpoint
+	)
+	for _, e := range t.endpoints {
+		e.mut.Lock()
+		dropped, ok := e.cache[hash]
+		if !ok {
+			if withExt == nil {
+				withExt = withExternalLabels(lset, t.external)
+			}
+			dropped = relabel.Process(withExt, e.relabels...) == nil
+
+			if len(e.cache) >= maxRelabelCacheSize {
+				e.cache = make(map[uint64]bool)
+			}
+			e.cache[hash] = dropped
+		}
+		e.mut.Unlock()
+
+		if dropped {
+			droppers = append(droppers, e)
+		}
+	}
+	return droppers
+}
+
+// record adds dropped samples per metric name to e.
+func (t *relabelDropTracker) record(e *relabelDropEndpoint, names map[string]int64) {
+	var total int64
+
+	e.mut.Lock()
+	for name, samples := range names {
+		total += samples
+		if _, ok := e.names[name]; ok || len(e.names) < maxDroppedMetricNames {
+			e.names[name] += samples
+		}
+	}
+	e.total += total
+	e.mut.Unlock()
+
+	t.dropped.WithLabelValues(e.name).Add(float64(total))
+}
+
+// withExternalLabels adds the external labels to lset which it doesn't
+// already have.
+func withExternalLabels(lset, external labels.Labels) labels.Labels {
+	if len(external) == 0 {
+		return lset
+	}
+	b := labels.NewBuilder(lset)
+	for _, l := range external {
+		if lset.Get(l.Name) == "" {
+			b.Set(l.Name, l.Value)
+		}
+	}
+	return b.Labels()
+}
+
+// Appendable wraps next, counting the samples appended to it which are
+// dropped by write_relabel_configs.
+func (t *relabelDropTracker) Appendable(next storage.Appendable) storage.Appendable {
+	return &relabelDropAppendable{next: next, t: t}
+}
+
+type relabelDropAppendable struct {
+	next storage.Appendable
+	t    *relabelDropTracker
+}
+
+func (a *relabelDropAppendable) Appender(ctx context.Context) storage.Appender {
+	return &relabelDropAppender{Appender: a.next.Appender(ctx), t: a.t}
+}
+
+type relabelDropAppender struct {
+	storage.Appender
+	t *relabelDropTracker
+
+	// dropped holds the dropped samples per metric name for each endpoint
+	// until the appender is committed.
+	dropped map[*relabelDropEndpoint]map[string]int64
+}
+
+func (a *relabelDropAppender) Append(ref uint64, l labels.Labels, t int64, v float64) (uint64, error) {
+	ref, err := a.Appender.Append(ref, l, t, v)
+	if err != nil {
+		return ref, err
+	}
+
+	for _, e := range a.t.droppedBy(l) {
+		if a.dropped == nil {
+			a.dropped = make(map[*relabelDropEndpoint]map[string]int64)
+		}
+		names := a.dropped[e]
+		if names == nil {
+			names = make(map[string]int64)
+			a.dropped[e] = names
+		}
+		names[l.Get(labels.MetricName)]++
+	}
+	return ref, nil
+}
+
+func (a *relabelDropAppender) Commit() error {
+	err := a.Appender.Commit()
+	if err == nil {
+		for e, names := range a.dropped {
+			a.t.record(e, names)
+		}
+	}
+	a.dropped = nil
+	return err
+}
+
+func (a *relabelDropAppender) Rollback() error {
+	a.dropped = nil
+	return a.Appender.Rollback()
+}
@@ -0,0 +1,84 @@
+package instance
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelabelDropTracker(t *testing.T) {
+	dropGo := newRelabelConfig(relabel.Drop, model.MetricNameLabel, "go_.*")
+	keepProd := newRelabelConfig(relabel.Keep, "env", "prod")
+
+	tracker := newRelabelDropTracker()
+	tracker.SetConfigs([]*RemoteWriteConfig{
+		{RemoteWriteConfig: config.RemoteWriteConfig{Name: "go", WriteRelabelConfigs: []*relabel.Config{dropGo}}},
+		{RemoteWriteConfig: config.RemoteWriteConfig{Name: "prod", WriteRelabelConfigs: []*relabel.Config{keepProd}}},
+		// Configs without write_relabel_configs aren't tracked.
+		{RemoteWriteConfig: config.RemoteWriteConfig{Name: "all"}},
+	}, labels.FromStrings("env", "prod"))
+
+	app := tracker.Appendable(noopAppendable{}).Appender(context.Background())
+	appendSamples := func(lset labels.Labels, n int) {
+		for i := 0; i < n; i++ {
+			_, err := app.Append(0, lset, int64(i), 1)
+			require.NoError(t, err)
+		}
+	}
+
+	appendSamples(labels.FromStrings("__name__", "go_goroutines"), 3)
+	appendSamples(labels.FromStrings("__name__", "go_threads"), 1)
+	appendSamples(labels.FromStrings("__name__", "up"), 2)
+	// The env label of the series takes precedence over the external label,
+	// so prod drops it.
+	appendSamples(labels.FromStrings("__name__", "up", "env", "dev"), 2)
+
+	// Nothing is counted before committing.
+	require.Equal(t, int64(0), tracker.Status(0)[0].DroppedSamples)
+	require.NoError(t, app.Commit())
+
+	// Rolled back samples are not counted.
+	app = tracker.Appendable(noopAppendable{}).Appender(context.Background())
+	appendSamples(labels.FromStrings("__name__", "go_threads"), 5)
+	require.NoError(t, app.Rollback())
+
+	expect := []RelabelDropStatus{
+		{
+			RemoteName:     "go",
+			DroppedSamples: 4,
+			TopMetrics:     []DroppedMetric{{Name: "go_goroutines", Samples: 3}},
+		},
+		{
+			RemoteName:     "prod",
+			DroppedSamples: 2,
+			TopMetrics:     []DroppedMetric{{Name: "up", Samples: 2}},
+		},
+	}
+	require.Equal(t, expect, tracker.Status(1))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(tracker.Collector())
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP agent_remote_write_relabel_dropped_samples_total Total number of samples dropped by the write_relabel_configs of a remote_write config.
+# TYPE agent_remote_write_relabel_dropped_samples_total counter
+agent_remote_write_relabel_dropped_samples_total{remote_name="go"} 4
+agent_remote_write_relabel_dropped_samples_total{remote_name="prod"} 2
+`)))
+
+	// Counts are kept for configs which still exist.
+	tracker.SetConfigs([]*RemoteWriteConfig{
+		{RemoteWriteConfig: config.RemoteWriteConfig{Name: "go", WriteRelabelConfigs: []*relabel.Config{dropGo}}},
+	}, nil)
+	status := tracker.Status(0)
+	require.Len(t, status, 1)
+	require.Equal(t, int64(4), status[0].DroppedSamples)
+	require.Len(t, status[0].TopMetrics, 2)
+}
@@ -0,0 +1,521 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/pkg/exemplar"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+	"gopkg.in/yaml.v2"
+)
+
+// tenantHeader is the header used by Cortex to identify the tenant that
+// samples are written for.
+const tenantHeader = "X-Scope-OrgID"
+
+// RemoteWriteConfig is a Prometheus remote_write config with additional
+// settings specific to the agent.
+type RemoteWriteConfig struct {
+	config.RemoteWriteConfig `yaml:",inline"`
+
+	// TenantLabel, when set, splits samples into one queue per value of the
+	// label. Each queue sends its samples with the value as the
+	// X-Scope-OrgID header.
+	TenantLabel string `yaml:"tenant_label,omitempty"`
+
+	// MaxTenants is the maximum number of tenants to create queues for when
+	// TenantLabel is set. Samples for additional tenants are dropped.
+	MaxTenants int `yaml:"max_tenants,omitempty"`
+
+	// TenantIdleTimeout is how long a tenant may go without samples or
+	// discovered targets before its queue is removed.
+	TenantIdleTimeout model.Duration `yaml:"tenant_idle_timeout,omitempty"`
+
+	// AzureAD authenticates requests with Azure AD access tokens.
+	AzureAD *AzureADConfig `yaml:"azuread,omitempty"`
+
+	// Google authenticates requests with Google OAuth access tokens.
+	Google *GoogleConfig `yaml:"google,omitempty"`
+}
+
+// Default settings for remote_write configs that set a tenant_label.
+var (
+	DefaultMaxTenants        = 100
+	DefaultTenantIdleTimeout = model.Duration(time.Hour)
+)
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *RemoteWriteConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// The embedded Prometheus config strictly unmarshals itself, so it can't
+	// be passed agent-specific fields. Pull out the agent fields first and
+	// pass everything else through.
+	var ext struct {
+		TenantLabel       string                 `yaml:"tenant_label,omitempty"`
+		MaxTenants        int                    `yaml:"max_tenants,omitempty"`
+		TenantIdleTimeout model.Duration         `yaml:"tenant_idle_timeout,omitempty"`
+		AzureAD           *AzureADConfig         `yaml:"azuread,omitempty"`
+		Google            *GoogleConfig          `yaml:"google,omitempty"`
+		Rest              map[string]interface{} `yaml:",inline"`
+	}
+	if err := unmarshal(&ext); err != nil {
+		return err
+	}
+
+	bb, err := yaml.Marshal(ext.Rest)
+	if err != nil {
+		return err
+	}
+
+	var inner config.RemoteWriteConfig
+	if err := yaml.UnmarshalStrict(bb, &inner); err != nil {
+		return err
+	}
+
+	*c = RemoteWriteConfig{
+		RemoteWriteConfig: inner,
+		TenantLabel:       ext.TenantLabel,
+		MaxTenants:        ext.MaxTenants,
+		TenantIdleTimeout: ext.TenantIdleTimeout,
+		AzureAD:           ext.AzureAD,
+		Google:            ext.Google,
+	}
+	return nil
+}
+
+// applyQueueDefaults sets the queue_config settings of c which are zero to
+// their Prometheus defaults. Configs unmarshaled from YAML already have the
+// defaults, but configs built in code may not.
+func (c *RemoteWriteConfig) applyQueueDefaults() {
+	qc := &c.QueueConfig
+	if qc.Capacity == 0 {
+		qc.Capacity = config.DefaultQueueConfig.Capacity
+	}
+	if qc.MaxShards == 0 {
+		qc.MaxShards = config.DefaultQueueConfig.MaxShards
+	}
+	if qc.MinShards == 0 {
+		qc.MinShards = config.DefaultQueueConfig.MinShards
+	}
+	if qc.MaxSamplesPerSend == 0 {
+		qc.MaxSamplesPerSend = config.DefaultQueueConfig.MaxSamplesPerSend
+	}
+	if qc.BatchSendDeadline == 0 {
+		qc.BatchSendDeadline = config.DefaultQueueConfig.BatchSendDeadline
+	}
+	if qc.MinBackoff == 0 {
+		qc.MinBackoff = config.DefaultQueueConfig.MinBackoff
+	}
+	if qc.MaxBackoff == 0 {
+		qc.MaxBackoff = config.DefaultQueueConfig.MaxBackoff
+	}
+}
+
+// validateQueue checks that the queue_config settings of c can be used by a
+// remote_write queue. It must be called after applyQueueDefaults.
+func (c *RemoteWriteConfig) validateQueue() error {
+	qc := c.QueueConfig
+	switch {
+	case qc.Capacity < 0:
+		return errors.New("queue_config.capacity must not be negative")
+	case qc.MaxShards < 0:
+		return errors.New("queue_config.max_shards must not be negative")
+	case qc.MinShards < 0:
+		return errors.New("queue_config.min_shards must not be negative")
+	case qc.MaxSamplesPerSend < 0:
+		return errors.New("queue_config.max_samples_per_send must not be negative")
+	case qc.BatchSendDeadline < 0:
+		return errors.New("queue_config.batch_send_deadline must not be negative")
+	case qc.MinShards > qc.MaxShards:
+		return fmt.Errorf("queue_config.min_shards (%d) must not be greater than max_shards (%d)", qc.MinShards, qc.MaxShards)
+	case qc.MinBackoff > qc.MaxBackoff:
+		return fmt.Errorf("queue_config.min_backoff (%s) must not be greater than max_backoff (%s)", qc.MinBackoff, qc.MaxBackoff)
+	}
+	return nil
+}
+
+// prometheusRemoteWriteConfigs converts rw into the Prometheus remote_write
+// configs to apply to remote storage. tenants is used to look up the
+// currently known tenants for configs with a tenant_label.
+//
+// Each config with a tenant_label is expanded into one config per known
+// tenant, which only sends series with that tenant's label value and the
+// tenant as the X-Scope-OrgID header. The tenant_label itself is removed from
+// sent series. The original config is kept to send series which don't have
+// the tenant_label.
+func prometheusRemoteWriteConfigs(rw []*RemoteWriteConfig, tenants func(label string) []string) []*config.RemoteWriteConfig {
+	res := make([]*config.RemoteWriteConfig, 0, len(rw))
+
+	for _, c := range rw {
+		if c.TenantLabel == "" {
+			inner := c.RemoteWriteConfig
+			res = append(res, &inner)
+			continue
+		}
+
+		untenanted := c.RemoteWriteConfig
+		untenanted.WriteRelabelConfigs = append([]*relabel.Config{
+			newRelabelConfig(relabel.Drop, c.TenantLabel, ".+"),
+		}, c.RemoteWriteConfig.WriteRelabelConfigs...)
+		res = append(res, &untenanted)
+
+		for _, tenant := range tenants(c.TenantLabel) {
+			tenanted := c.RemoteWriteConfig
+			tenanted.Name = c.Name + "-" + tenant
+
+			tenanted.Headers = make(map[string]string, len(c.Headers)+1)
+			for k, v := range c.Headers {
+				tenanted.Headers[k] = v
+			}
+			tenanted.Headers[tenantHeader] = tenant
+
+			relabels := make([]*relabel.Config, 0, len(c.WriteRelabelConfigs)+2)
+			relabels = append(relabels, newRelabelConfig(relabel.Keep, c.TenantLabel, regexp.QuoteMeta(tenant)))
+			relabels = append(relabels, c.WriteRelabelConfigs...)
+			relabels = append(relabels, newRelabelConfig(relabel.LabelDrop, "", regexp.QuoteMeta(c.TenantLabel)))
+			tenanted.WriteRelabelConfigs = relabels
+
+			res = append(res, &tenanted)
+		}
+	}
+
+	return res
+}
+
+func newRelabelConfig(action relabel.Action, sourceLabel string, regex string) *relabel.Config {
+	rc := relabel.DefaultRelabelConfig
+	rc.Action = action
+	rc.Regex = relabel.MustNewRegexp(regex)
+	if sourceLabel != "" {
+		rc.SourceLabels = model.LabelNames{model.LabelName(sourceLabel)}
+	}
+	return &rc
+}
+
+// tenantKey identifies a tenant found for a tenant label.
+type tenantKey struct {
+	label, tenant string
+}
+
+// trackedLabel holds the tenants found for a tenant label.
+type trackedLabel struct {
+	maxTenants  int
+	idleTimeout time.Duration
+
+	// tenants maps each tenant to the last time it was seen.
+	tenants map[string]time.Time
+}
+
+// tenantTracker records the values of tenant labels found in appended
+// samples and discovered targets.
+type tenantTracker struct {
+	mut    sync.RWMutex
+	labels map[string]*trackedLabel
+
+	// rejected counts tenants that were not tracked because their label
+	// already had max_tenants tenants.
+	rejected atomic.Int64
+
+	// changed receives a value when a new tenant has been found.
+	changed chan struct{}
+}
+
+func newTenantTracker() *tenantTracker {
+	return &tenantTracker{
+		labels:  make(map[string]*trackedLabel),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// SetLabels sets the tenant labels to track from rw. Tenants found for
+// labels that are still tracked are retained.
+func (t *tenantTracker) SetLabels(rw []*RemoteWriteConfig) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	tracked := make(map[string]*trackedLabel)
+	for _, c := range rw {
+		if c.TenantLabel == "" {
+			continue
+		}
+		if _, ok := tracked[c.TenantLabel]; ok {
+			continue
+		}
+
+		tl := t.labels[c.TenantLabel]
+		if tl == nil {
+			tl = &trackedLabel{tenants: make(map[string]time.Time)}
+		}
+		tl.maxTenants = c.MaxTenants
+		tl.idleTimeout = time.Duration(c.TenantIdleTimeout)
+		tracked[c.TenantLabel] = tl
+	}
+	t.labels = tracked
+}
+
+// Tenants returns the sorted list of tenants found for label.
+func (t *tenantTracker) Tenants(label string) []string {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	tl := t.labels[label]
+	if tl == nil {
+		return []string{}
+	}
+
+	res := make([]string, 0, len(tl.tenants))
+	for tenant := range tl.tenants {
+		res = append(res, tenant)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Changed returns a channel that receives a value when a new tenant is
+// found.
+func (t *tenantTracker) Changed() <-chan struct{} {
+	return t.changed
+}
+
+// Rejected returns the total number of times a new tenant was ignored
+// because its label already had max_tenants tenants.
+func (t *tenantTracker) Rejected() int64 {
+	return t.rejected.Load()
+}
+
+// Appendable wraps next, recording tenants from all samples appended to it.
+func (t *tenantTracker) Appendable(next storage.Appendable) storage.Appendable {
+	return &tenantAppendable{next: next, t: t}
+}
+
+// labelNames returns the names of the tracked tenant labels.
+func (t *tenantTracker) labelNames() []string {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	res := make([]string, 0, len(t.labels))
+	for name := range t.labels {
+		res = append(res, name)
+	}
+	return res
+}
+
+// collectTenants adds the tenants of lset for the tenant labels in names to seen.
+func collectTenants(seen map[tenantKey]struct{}, names []string, lset labels.Labels) {
+	for _, name := range names {
+		if tenant := lset.Get(name); tenant != "" {
+			seen[tenantKey{label: name, tenant: tenant}] = struct{}{}
+		}
+	}
+}
+
+// record marks the tenants in seen as last seen at now, tracking any new
+// tenants as long as their label hasn't reached its max_tenants. record
+// returns true if a new tenant was tracked.
+func (t *tenantTracker) record(seen map[tenantKey]struct{}, now time.Time) bool {
+	if len(seen) == 0 {
+		return false
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	var added bool
+	for key := range seen {
+		tl := t.labels[key.label]
+		if tl == nil {
+			continue
+		}
+		if _, ok := tl.tenants[key.tenant]; !ok {
+			if tl.maxTenants > 0 && len(tl.tenants) >= tl.maxTenants {
+				t.rejected.Inc()
+				continue
+			}
+			added = true
+		}
+		tl.tenants[key.tenant] = now
+	}
+	return added
+}
+
+// expire forgets tenants which haven't been seen within the idle timeout of
+// their label, returning true if any tenants were removed.
+func (t *tenantTracker) expire(now time.Time) bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	var removed bool
+	for _, tl := range t.labels {
+		if tl.idleTimeout <= 0 {
+			continue
+		}
+		for tenant, lastSeen := range tl.tenants {
+			if now.Sub(lastSeen) > tl.idleTimeout {
+				delete(tl.tenants, tenant)
+				removed = true
+			}
+		}
+	}
+	return removed
+}
+
+func (t *tenantTracker) notify() {
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+}
+
+type tenantAppendable struct {
+	next storage.Appendable
+	t    *tenantTracker
+}
+
+func (a *tenantAppendable) Appender(ctx context.Context) storage.Appender {
+	return &tenantAppender{
+		Appender: a.next.Appender(ctx),
+		t:        a.t,
+		names:    a.t.labelNames(),
+	}
+}
+
+type tenantAppender struct {
+	storage.Appender
+	t     *tenantTracker
+	names []string
+
+	seen map[tenantKey]struct{}
+}
+
+func (a *tenantAppender) observe(l labels.Labels) {
+	if len(a.names) == 0 {
+		return
+	}
+	if a.seen == nil {
+		a.seen = make(map[tenantKey]struct{})
+	}
+	collectTenants(a.seen, a.names, l)
+}
+
+func (a *tenantAppender) Append(ref uint64, l labels.Labels, t int64, v float64) (uint64, error) {
+	a.observe(l)
+	return a.Appender.Append(ref, l, t, v)
+}
+
+func (a *tenantAppender) AppendExemplar(ref uint64, l labels.Labels, e exemplar.Exemplar) (uint64, error) {
+	a.observe(l)
+	return a.Appender.AppendExemplar(ref, l, e)
+}
+
+func (a *tenantAppender) Commit() error {
+	err := a.Appender.Commit()
+	if err == nil && a.t.record(a.seen, time.Now()) {
+		a.t.notify()
+	}
+	a.seen = nil
+	return err
+}
+
+func (a *tenantAppender) Rollback() error {
+	a.seen = nil
+	return a.Appender.Rollback()
+}
+
+// targetTenants returns the tenants found in the labels of discovered
+// targets after applying the relabel_configs of their scrape config. names
+// are the tenant labels to look for.
+func targetTenants(groups DiscoveredGroups, scrapeConfigs []*config.ScrapeConfig, names []string) map[tenantKey]struct{} {
+	seen := make(map[tenantKey]struct{})
+	if len(names) == 0 {
+		return seen
+	}
+
+	jobs := make(map[string]*config.ScrapeConfig, len(scrapeConfigs))
+	for _, sc := range scrapeConfigs {
+		jobs[sc.JobName] = sc
+	}
+
+	for job, groups := range groups {
+		sc := jobs[job]
+		if sc == nil {
+			continue
+		}
+
+		for _, group := range groups {
+			for _, target := range group.Targets {
+				allLabels := mergeSets(target, group.Labels)
+				if _, ok := allLabels[model.JobLabel]; !ok {
+					allLabels[model.JobLabel] = model.LabelValue(sc.JobName)
+				}
+
+				lset := relabel.Process(toLabelSlice(allLabels), sc.RelabelConfigs...)
+				if lset == nil {
+					continue
+				}
+				collectTenants(seen, names, lset)
+			}
+		}
+	}
+
+	return seen
+}
+
+// tenantDiscoverer sits between service discovery and the scrape manager,
+// recording tenants from the labels of discovered targets before passing the
+// targets along. This allows queues for new tenants to be created before
+// their targets are first scraped.
+type tenantDiscoverer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	observe  func(DiscoveredGroups)
+	outputCh chan DiscoveredGroups
+}
+
+func newTenantDiscoverer(observe func(DiscoveredGroups)) *tenantDiscoverer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &tenantDiscoverer{
+		ctx:    ctx,
+		cancel: cancel,
+
+		observe:  observe,
+		outputCh: make(chan DiscoveredGroups),
+	}
+}
+
+// Run reads groups from syncCh until the tenantDiscoverer is stopped.
+func (d *tenantDiscoverer) Run(syncCh GroupChannel) {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case data := <-syncCh:
+			d.observe(data)
+
+			select {
+			case <-d.ctx.Done():
+				return
+			case d.outputCh <- data:
+			}
+		}
+	}
+}
+
+// Stop stops the tenantDiscoverer.
+func (d *tenantDiscoverer) Stop() {
+	d.cancel()
+}
+
+// SyncCh returns the channel targets are sent to after being observed.
+func (d *tenantDiscoverer) SyncCh() GroupChannel {
+	return d.outputCh
+}
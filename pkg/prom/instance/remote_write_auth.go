@@ -0,0 +1,382 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	config_util "github.com/prometheus/common/config"
+	"golang.org/x/oauth2/google"
+)
+
+// Clouds supported by AzureADConfig.
+const (
+	AzurePublic     = "AzurePublic"
+	AzureChina      = "AzureChina"
+	AzureGovernment = "AzureGovernment"
+)
+
+// azureClouds holds the Active Directory endpoint and the Azure Monitor
+// resource to request tokens for in each cloud.
+var azureClouds = map[string]struct{ activeDirectory, resource string }{
+	AzurePublic:     {"https://login.microsoftonline.com/", "https://monitor.azure.com"},
+	AzureChina:      {"https://login.chinacloudapi.cn/", "https://monitor.azure.cn"},
+	AzureGovernment: {"https://login.microsoftonline.us/", "https://monitor.azure.us"},
+}
+
+// googleMonitoringScope is the OAuth scope needed to write to Google Cloud
+// Managed Service for Prometheus.
+const googleMonitoringScope = "https://www.googleapis.com/auth/monitoring.write"
+
+// AzureADConfig authenticates remote_write requests with Azure AD tokens, as
+// needed by Azure Monitor managed Prometheus. Exactly one of ManagedIdentity
+// and OAuth must be set.
+type AzureADConfig struct {
+	// Cloud is the Azure cloud to authenticate with.
+	Cloud string `yaml:"cloud,omitempty"`
+
+	// ManagedIdentity requests tokens for the managed identity of the host.
+	ManagedIdentity *AzureManagedIdentityConfig `yaml:"managed_identity,omitempty"`
+
+	// OAuth requests tokens for an app registration with a client secret.
+	OAuth *AzureOAuthConfig `yaml:"oauth,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *AzureADConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = AzureADConfig{Cloud: AzurePublic}
+
+	type plain AzureADConfig
+	return unmarshal((*plain)(c))
+}
+
+// AzureManagedIdentityConfig selects a managed identity.
+type AzureManagedIdentityConfig struct {
+	// ClientID of a user-assigned managed identity. The system-assigned
+	// identity is used when empty.
+	ClientID string `yaml:"client_id,omitempty"`
+}
+
+// AzureOAuthConfig holds the credentials of an app registration.
+type AzureOAuthConfig struct {
+	ClientID     string             `yaml:"client_id"`
+	ClientSecret config_util.Secret `yaml:"client_secret"`
+	TenantID     string             `yaml:"tenant_id"`
+}
+
+// validate checks that c can be used to request tokens.
+func (c *AzureADConfig) validate() error {
+	if _, ok := azureClouds[c.Cloud]; !ok {
+		return fmt.Errorf("unsupported cloud %q, expected %s, %s or %s", c.Cloud, AzurePublic, AzureChina, AzureGovernment)
+	}
+
+	switch {
+	case c.ManagedIdentity == nil && c.OAuth == nil:
+		return errors.New("must set one of managed_identity and oauth")
+	case c.ManagedIdentity != nil && c.OAuth != nil:
+		return errors.New("must set at most one of managed_identity and oauth")
+	case c.OAuth != nil && (c.OAuth.ClientID == "" || c.OAuth.ClientSecret == "" || c.OAuth.TenantID == ""):
+		return errors.New("oauth must set client_id, client_secret and tenant_id")
+	}
+	return nil
+}
+
+// GoogleConfig authenticates remote_write requests with Google OAuth tokens,
+// as needed by Google Cloud Managed Service for Prometheus.
+type GoogleConfig struct {
+	// CredentialsFile is the path of a service account key. Application
+	// Default Credentials are used when empty.
+	CredentialsFile string `yaml:"credentials_file,omitempty"`
+}
+
+// validateAuth checks that the azuread and google blocks of c don't conflict
+// with each other or with the other authentication settings of c.
+func (c *RemoteWriteConfig) validateAuth() error {
+	if c.AzureAD == nil && c.Google == nil {
+		return nil
+	}
+
+	hc := c.HTTPClientConfig
+	switch {
+	case c.AzureAD != nil && c.Google != nil:
+		return errors.New("must set at most one of azuread and google")
+	case hc.BasicAuth != nil || hc.Authorization != nil || hc.BearerToken != "" || hc.BearerTokenFile != "":
+		return errors.New("azuread and google can't be used with basic_auth, authorization, bearer_token or bearer_token_file")
+	}
+
+	if c.AzureAD != nil {
+		if err := c.AzureAD.validate(); err != nil {
+			return fmt.Errorf("invalid azuread config: %w", err)
+		}
+	}
+	return nil
+}
+
+// tokenSource returns a tokenSource for the azuread or google block of c, or
+// nil if neither is set.
+func (c *RemoteWriteConfig) tokenSource() (tokenSource, error) {
+	switch {
+	case c.AzureAD != nil:
+		return newAzureADTokenSource(c.AzureAD)
+	case c.Google != nil:
+		return newGoogleTokenSource(c.Google)
+	default:
+		return nil, nil
+	}
+}
+
+// tokenSource requests access tokens.
+type tokenSource interface {
+	// Token returns a valid access token along with its expiry.
+	Token(ctx context.Context) (token string, expiry time.Time, err error)
+}
+
+type azureADTokenSource struct {
+	spt *adal.ServicePrincipalToken
+}
+
+func newAzureADTokenSource(c *AzureADConfig) (tokenSource, error) {
+	cloud := azureClouds[c.Cloud]
+
+	if c.ManagedIdentity != nil {
+		spt, err := adal.NewServicePrincipalTokenFromManagedIdentity(cloud.resource, &adal.ManagedIdentityOptions{
+			ClientID: c.ManagedIdentity.ClientID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &azureADTokenSource{spt: spt}, nil
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(cloud.activeDirectory, c.OAuth.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	spt, err := adal.NewServicePrincipalToken(*oauthConfig, c.OAuth.ClientID, string(c.OAuth.ClientSecret), cloud.resource)
+	if err != nil {
+		return nil, err
+	}
+	return &azureADTokenSource{spt: spt}, nil
+}
+
+func (s *azureADTokenSource) Token(ctx context.Context) (string, time.Time, error) {
+	if err := s.spt.EnsureFreshWithContext(ctx); err != nil {
+		return "", time.Time{}, err
+	}
+	tok := s.spt.Token()
+	return tok.AccessToken, tok.Expires(), nil
+}
+
+type googleTokenSource struct {
+	c *GoogleConfig
+}
+
+func newGoogleTokenSource(c *GoogleConfig) (tokenSource, error) {
+	return &googleTokenSource{c: c}, nil
+}
+
+func (s *googleTokenSource) Token(ctx context.Context) (string, time.Time, error) {
+	var (
+		creds *google.Credentials
+		err   error
+	)
+	if s.c.CredentialsFile != "" {
+		var bb []byte
+		bb, err = ioutil.ReadFile(s.c.CredentialsFile)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		creds, err = google.CredentialsFromJSON(ctx, bb, googleMonitoringScope)
+	} else {
+		creds, err = google.FindDefaultCredentials(ctx, googleMonitoringScope)
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	tok, err := creds.TokenSource.Token()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tok.AccessToken, tok.Expiry, nil
+}
+
+// Settings for refreshing tokens.
+var (
+	// tokenRefreshMargin is how long before its expiry a token is refreshed.
+	tokenRefreshMargin = 5 * time.Minute
+	// tokenRetryInterval is how long to wait after failing to get a token.
+	tokenRetryInterval = 30 * time.Second
+)
+
+// remoteWriteTokens writes access tokens for remote_write configs with an
+// azuread or google block to files, which are sent as the bearer token of
+// remote write requests through the authorization.credentials_file setting.
+// Prometheus reads the file for every request, so refreshed tokens are used
+// without reapplying the remote_write configs.
+type remoteWriteTokens struct {
+	dir    string
+	logger log.Logger
+
+	mut        sync.Mutex
+	refreshers map[string]*tokenRefresher
+}
+
+func newRemoteWriteTokens(dir string, logger log.Logger) *remoteWriteTokens {
+	return &remoteWriteTokens{
+		dir:        dir,
+		logger:     logger,
+		refreshers: make(map[string]*tokenRefresher),
+	}
+}
+
+// ApplyConfig starts refreshing the tokens of the configs in rw which have an
+// azuread or google block and stops refreshing tokens for other configs. It
+// returns a copy of rw where those configs send the token as their
+// authorization.
+func (t *remoteWriteTokens) ApplyConfig(rw []*RemoteWriteConfig) ([]*RemoteWriteConfig, error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	res := make([]*RemoteWriteConfig, 0, len(rw))
+	active := make(map[string]*tokenRefresher)
+
+	for _, c := range rw {
+		if c.AzureAD == nil && c.Google == nil {
+			res = append(res, c)
+			continue
+		}
+		if t.dir == "" {
+			return nil, fmt.Errorf("remote_write %s: no directory to store access tokens", c.Name)
+		}
+
+		hash, err := getHash(c)
+		if err != nil {
+			return nil, err
+		}
+
+		r, ok := t.refreshers[c.Name]
+		if !ok || r.hash != hash {
+			if ok {
+				r.Stop()
+			}
+
+			src, err := c.tokenSource()
+			if err != nil {
+				return nil, fmt.Errorf("remote_write %s: %w", c.Name, err)
+			}
+			if err := os.MkdirAll(t.dir, 0700); err != nil {
+				return nil, fmt.Errorf("failed to create access token directory: %w", err)
+			}
+
+			path := filepath.Join(t.dir, hash)
+			r = newTokenRefresher(log.With(t.logger, "remote_name", c.Name), src, path, hash)
+		}
+		active[c.Name] = r
+
+		withToken := *c
+		withToken.HTTPClientConfig.Authorization = &config_util.Authorization{
+			Type:            "Bearer",
+			CredentialsFile: r.path,
+		}
+		res = append(res, &withToken)
+	}
+
+	for name, r := range t.refreshers {
+		if active[name] != r {
+			r.Stop()
+		}
+	}
+	t.refreshers = active
+	return res, nil
+}
+
+// Stop stops refreshing all tokens.
+func (t *remoteWriteTokens) Stop() {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	for _, r := range t.refreshers {
+		r.Stop()
+	}
+	t.refreshers = make(map[string]*tokenRefresher)
+}
+
+// tokenRefresher keeps a file updated with a valid access token.
+type tokenRefresher struct {
+	logger log.Logger
+	src    tokenSource
+	path   string
+	hash   string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newTokenRefresher(logger log.Logger, src tokenSource, path, hash string) *tokenRefresher {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &tokenRefresher{
+		logger: logger,
+		src:    src,
+		path:   path,
+		hash:   hash,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go r.run(ctx)
+	return r
+}
+
+func (r *tokenRefresher) run(ctx context.Context) {
+	defer close(r.done)
+
+	for {
+		wait := tokenRetryInterval
+
+		expiry, err := r.refresh(ctx)
+		if err != nil {
+			level.Error(r.logger).Log("msg", "failed to refresh remote_write access token", "err", err)
+		} else if untilRefresh := time.Until(expiry) - tokenRefreshMargin; untilRefresh > wait {
+			wait = untilRefresh
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// refresh writes a new token to the file of r, returning the token's expiry.
+func (r *tokenRefresher) refresh(ctx context.Context) (time.Time, error) {
+	token, expiry, err := r.src.Token(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Write to a temporary file first so requests never read a partial token.
+	tmp := r.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(token), 0600); err != nil {
+		return time.Time{}, err
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return time.Time{}, err
+	}
+	return expiry, nil
+}
+
+// Stop stops refreshing the token and removes its file.
+func (r *tokenRefresher) Stop() {
+	r.cancel()
+	<-r.done
+	_ = os.Remove(r.path)
+}
@@ -0,0 +1,117 @@
+package instance
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/util/test"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/prometheus/config"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRemoteWriteConfig_Unmarshal_Auth(t *testing.T) {
+	in := `
+url: https://example.com/api/v1/write
+azuread:
+  oauth:
+    client_id: client
+    client_secret: secret
+    tenant_id: tenant
+`
+
+	var cfg RemoteWriteConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+	require.Equal(t, &AzureADConfig{
+		Cloud: AzurePublic,
+		OAuth: &AzureOAuthConfig{ClientID: "client", ClientSecret: "secret", TenantID: "tenant"},
+	}, cfg.AzureAD)
+	require.NoError(t, cfg.validateAuth())
+
+	in = `
+url: https://example.com/api/v1/write
+google:
+  credentials_file: /etc/agent/key.json
+`
+	cfg = RemoteWriteConfig{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+	require.Equal(t, &GoogleConfig{CredentialsFile: "/etc/agent/key.json"}, cfg.Google)
+	require.NoError(t, cfg.validateAuth())
+}
+
+type fakeTokenSource struct {
+	tokens chan string
+}
+
+func (s *fakeTokenSource) Token(ctx context.Context) (string, time.Time, error) {
+	select {
+	case <-ctx.Done():
+		return "", time.Time{}, ctx.Err()
+	case tok := <-s.tokens:
+		// Expire immediately so the next token is requested after
+		// tokenRetryInterval.
+		return tok, time.Now(), nil
+	}
+}
+
+func TestTokenRefresher(t *testing.T) {
+	defer func(interval time.Duration) { tokenRetryInterval = interval }(tokenRetryInterval)
+	tokenRetryInterval = 10 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "token")
+	src := &fakeTokenSource{tokens: make(chan string)}
+
+	r := newTokenRefresher(log.NewNopLogger(), src, path, "hash")
+
+	readToken := func() interface{} {
+		bb, _ := ioutil.ReadFile(path)
+		return string(bb)
+	}
+
+	src.tokens <- "first"
+	test.Poll(t, time.Second, "first", readToken)
+	src.tokens <- "second"
+	test.Poll(t, time.Second, "second", readToken)
+
+	r.Stop()
+	require.NoFileExists(t, path)
+}
+
+func TestRemoteWriteTokens_ApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	tokens := newRemoteWriteTokens(dir, log.NewNopLogger())
+	defer tokens.Stop()
+
+	plain := &RemoteWriteConfig{RemoteWriteConfig: config.RemoteWriteConfig{Name: "plain"}}
+	withGoogle := &RemoteWriteConfig{
+		RemoteWriteConfig: config.RemoteWriteConfig{Name: "google"},
+		Google:            &GoogleConfig{CredentialsFile: filepath.Join(dir, "missing.json")},
+	}
+
+	rw, err := tokens.ApplyConfig([]*RemoteWriteConfig{plain, withGoogle})
+	require.NoError(t, err)
+	require.Len(t, rw, 2)
+	require.Same(t, plain, rw[0])
+
+	// The original config must not be modified.
+	require.Nil(t, withGoogle.HTTPClientConfig.Authorization)
+
+	auth := rw[1].HTTPClientConfig.Authorization
+	require.NotNil(t, auth)
+	require.Equal(t, "Bearer", auth.Type)
+	require.Equal(t, dir, filepath.Dir(auth.CredentialsFile))
+
+	// Reapplying the same config keeps the same token file.
+	rw, err = tokens.ApplyConfig([]*RemoteWriteConfig{withGoogle})
+	require.NoError(t, err)
+	require.Equal(t, auth, rw[0].HTTPClientConfig.Authorization)
+
+	// Removing the config stops its refresher.
+	_, err = tokens.ApplyConfig([]*RemoteWriteConfig{plain})
+	require.NoError(t, err)
+	require.Empty(t, tokens.refreshers)
+}
@@ -0,0 +1,258 @@
+package instance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/pkg/exemplar"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRemoteWriteConfig_Unmarshal(t *testing.T) {
+	in := `
+url: http://localhost:9009/api/prom/push
+name: default
+tenant_label: __tenant__
+headers:
+  X-Custom: value
+queue_config:
+  capacity: 1234
+`
+
+	var cfg RemoteWriteConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+	require.Equal(t, "http://localhost:9009/api/prom/push", cfg.URL.String())
+	require.Equal(t, "default", cfg.Name)
+	require.Equal(t, "__tenant__", cfg.TenantLabel)
+	require.Equal(t, map[string]string{"X-Custom": "value"}, cfg.Headers)
+	require.Equal(t, 1234, cfg.QueueConfig.Capacity)
+
+	// Defaults from the Prometheus config should still be applied.
+	require.NotZero(t, cfg.RemoteTimeout)
+
+	t.Run("round trip", func(t *testing.T) {
+		bb, err := yaml.Marshal(&cfg)
+		require.NoError(t, err)
+
+		var cp RemoteWriteConfig
+		require.NoError(t, yaml.UnmarshalStrict(bb, &cp))
+		require.Equal(t, cfg.URL.String(), cp.URL.String())
+		require.Equal(t, cfg.TenantLabel, cp.TenantLabel)
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		var cfg RemoteWriteConfig
+		err := yaml.UnmarshalStrict([]byte(in+"\nunknown_field: true\n"), &cfg)
+		require.Error(t, err)
+	})
+}
+
+func TestRemoteWriteConfig_ApplyQueueDefaults(t *testing.T) {
+	var cfg RemoteWriteConfig
+	cfg.QueueConfig.MaxSamplesPerSend = 5000
+	cfg.QueueConfig.RetryOnRateLimit = true
+	cfg.applyQueueDefaults()
+
+	expect := config.DefaultQueueConfig
+	expect.MaxSamplesPerSend = 5000
+	expect.RetryOnRateLimit = true
+	require.Equal(t, expect, cfg.QueueConfig)
+	require.NoError(t, cfg.validateQueue())
+}
+
+func TestPrometheusRemoteWriteConfigs(t *testing.T) {
+	in := `
+url: http://localhost:9009/api/prom/push
+name: default
+tenant_label: tenant
+headers:
+  X-Custom: value
+write_relabel_configs:
+- source_labels: [__name__]
+  regex: unwanted
+  action: drop
+`
+	var cfg RemoteWriteConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+
+	tenants := func(label string) []string {
+		require.Equal(t, "tenant", label)
+		return []string{"team-a", "team-b"}
+	}
+	res := prometheusRemoteWriteConfigs([]*RemoteWriteConfig{&cfg}, tenants)
+	require.Len(t, res, 3)
+
+	// The first config sends series without a tenant.
+	require.Equal(t, "default", res[0].Name)
+	require.Equal(t, map[string]string{"X-Custom": "value"}, res[0].Headers)
+	require.Len(t, res[0].WriteRelabelConfigs, 2)
+	require.Equal(t, relabel.Drop, res[0].WriteRelabelConfigs[0].Action)
+
+	for i, tenant := range []string{"team-a", "team-b"} {
+		rw := res[i+1]
+		require.Equal(t, "default-"+tenant, rw.Name)
+		require.Equal(t, map[string]string{"X-Custom": "value", "X-Scope-OrgID": tenant}, rw.Headers)
+
+		lset := labels.FromStrings("__name__", "up", "tenant", tenant, "job", "test")
+		for _, rc := range rw.WriteRelabelConfigs {
+			lset = relabel.Process(lset, rc)
+		}
+		require.Equal(t, labels.FromStrings("__name__", "up", "job", "test"), lset)
+
+		// Series for other tenants and series dropped by the user's rules
+		// shouldn't be sent.
+		for _, lset := range []labels.Labels{
+			labels.FromStrings("__name__", "up", "tenant", "other"),
+			labels.FromStrings("__name__", "up"),
+			labels.FromStrings("__name__", "unwanted", "tenant", tenant),
+		} {
+			require.Nil(t, relabel.Process(lset, rw.WriteRelabelConfigs...))
+		}
+	}
+
+	// The original config should not have been modified.
+	require.Len(t, cfg.WriteRelabelConfigs, 1)
+	require.Equal(t, map[string]string{"X-Custom": "value"}, cfg.Headers)
+}
+
+func TestTenantTracker(t *testing.T) {
+	tracker := newTenantTracker()
+	tracker.SetLabels([]*RemoteWriteConfig{{TenantLabel: "tenant"}})
+
+	app := tracker.Appendable(noopAppendable{}).Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "up"), 0, 1)
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "tenant", "b"), 0, 1)
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "tenant", "a"), 0, 1)
+	require.NoError(t, err)
+
+	select {
+	case <-tracker.Changed():
+		require.FailNow(t, "tracker should not notify before commit")
+	default:
+	}
+
+	require.NoError(t, app.Commit())
+	require.Equal(t, []string{"a", "b"}, tracker.Tenants("tenant"))
+
+	select {
+	case <-tracker.Changed():
+	default:
+		require.FailNow(t, "tracker should notify after commit")
+	}
+
+	// Seeing known tenants again shouldn't notify.
+	app = tracker.Appendable(noopAppendable{}).Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "tenant", "a"), 0, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	select {
+	case <-tracker.Changed():
+		require.FailNow(t, "tracker should not notify for known tenants")
+	default:
+	}
+
+	// Tenants should be forgotten when their label is no longer tracked.
+	tracker.SetLabels([]*RemoteWriteConfig{{TenantLabel: "other"}})
+	require.Empty(t, tracker.Tenants("tenant"))
+}
+
+func TestTenantTracker_Limits(t *testing.T) {
+	tracker := newTenantTracker()
+	tracker.SetLabels([]*RemoteWriteConfig{{
+		TenantLabel:       "tenant",
+		MaxTenants:        2,
+		TenantIdleTimeout: model.Duration(time.Minute),
+	}})
+
+	now := time.Now()
+	seen := func(tenants ...string) map[tenantKey]struct{} {
+		res := make(map[tenantKey]struct{}, len(tenants))
+		for _, tenant := range tenants {
+			res[tenantKey{label: "tenant", tenant: tenant}] = struct{}{}
+		}
+		return res
+	}
+
+	require.True(t, tracker.record(seen("a", "b"), now))
+	require.False(t, tracker.record(seen("c"), now), "tenant over max_tenants should be rejected")
+	require.Equal(t, []string{"a", "b"}, tracker.Tenants("tenant"))
+	require.Equal(t, int64(1), tracker.Rejected())
+
+	// Only b is seen again, so a should expire.
+	require.False(t, tracker.record(seen("b"), now.Add(45*time.Second)))
+	require.False(t, tracker.expire(now.Add(30*time.Second)))
+	require.True(t, tracker.expire(now.Add(90*time.Second)))
+	require.Equal(t, []string{"b"}, tracker.Tenants("tenant"))
+
+	// Expiring a frees up room for c.
+	require.True(t, tracker.record(seen("c"), now.Add(90*time.Second)))
+	require.Equal(t, []string{"b", "c"}, tracker.Tenants("tenant"))
+}
+
+func TestTenantTracker_Rollback(t *testing.T) {
+	tracker := newTenantTracker()
+	tracker.SetLabels([]*RemoteWriteConfig{{TenantLabel: "tenant"}})
+
+	app := tracker.Appendable(noopAppendable{}).Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "up", "tenant", "a"), 0, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Rollback())
+	require.Empty(t, tracker.Tenants("tenant"))
+}
+
+func TestTargetTenants(t *testing.T) {
+	in := `
+job_name: test
+relabel_configs:
+- source_labels: [__meta_team]
+  target_label: tenant
+- source_labels: [__meta_drop]
+  regex: "true"
+  action: drop
+`
+	var sc config.ScrapeConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &sc))
+
+	groups := DiscoveredGroups{
+		"test": {{
+			Targets: []model.LabelSet{
+				{"__address__": "a:80", "__meta_team": "team-a"},
+				{"__address__": "b:80"},
+				{"__address__": "c:80", "__meta_team": "team-c", "__meta_drop": "true"},
+			},
+			Labels: model.LabelSet{"__meta_team": "team-b"},
+		}},
+		"unknown": {{
+			Targets: []model.LabelSet{{"__address__": "d:80", "tenant": "team-d"}},
+		}},
+	}
+
+	res := targetTenants(groups, []*config.ScrapeConfig{&sc}, []string{"tenant"})
+	require.Equal(t, map[tenantKey]struct{}{
+		{label: "tenant", tenant: "team-a"}: {},
+		{label: "tenant", tenant: "team-b"}: {},
+	}, res)
+}
+
+type noopAppendable struct{}
+
+func (noopAppendable) Appender(_ context.Context) storage.Appender { return noopAppender{} }
+
+type noopAppender struct{}
+
+func (noopAppender) Append(uint64, labels.Labels, int64, float64) (uint64, error) { return 0, nil }
+func (noopAppender) Commit() error                                                { return nil }
+func (noopAppender) Rollback() error                                              { return nil }
+func (noopAppender) AppendExemplar(uint64, labels.Labels, exemplar.Exemplar) (uint64, error) {
+	return 0, nil
+}
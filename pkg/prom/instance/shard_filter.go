@@ -0,0 +1,152 @@
+package instance
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// TargetSharder determines which agent in a cluster is responsible for
+// scraping a target.
+type TargetSharder interface {
+	// Owns returns true if the target identified by key should be scraped by
+	// this agent.
+	Owns(key string) (bool, error)
+
+	// Changed returns a channel that is closed the next time target ownership
+	// may have changed, such as when agents join or leave the cluster.
+	Changed() <-chan struct{}
+}
+
+// ShardFilter acts as a MITM between the discovery manager and the scrape
+// manager, filtering out discovered targets that are owned by another agent
+// according to a TargetSharder.
+type ShardFilter struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	log     log.Logger
+	sharder TargetSharder
+
+	outputCh chan map[string][]*targetgroup.Group
+}
+
+// NewShardFilter creates a new ShardFilter.
+func NewShardFilter(l log.Logger, sharder TargetSharder) *ShardFilter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ShardFilter{
+		ctx:    ctx,
+		cancel: cancel,
+
+		log:     l,
+		sharder: sharder,
+
+		outputCh: make(chan map[string][]*targetgroup.Group),
+	}
+}
+
+// shardRetryInterval is how long ShardFilter waits to filter groups again
+// after ownership of targets could not be determined.
+var shardRetryInterval = 5 * time.Second
+
+// Run starts the ShardFilter. It only exits when the ShardFilter is stopped.
+// Run will continually read from syncCh and filter groups discovered down to
+// targets owned by this agent. The last set of groups is filtered again
+// whenever the TargetSharder reports that ownership may have changed.
+//
+// If ownership can't be determined, such as when the ring is unhealthy, the
+// last successfully filtered set of targets continues to be scraped and
+// filtering is retried. Until groups have been filtered successfully once, no
+// targets are scraped.
+func (f *ShardFilter) Run(syncCh GroupChannel) {
+	var (
+		last  DiscoveredGroups
+		retry <-chan time.Time
+	)
+
+	for {
+		changed := f.sharder.Changed()
+
+		select {
+		case <-f.ctx.Done():
+			return
+		case data := <-syncCh:
+			last = data
+		case <-changed:
+			if last == nil {
+				continue
+			}
+		case <-retry:
+		}
+		retry = nil
+
+		out, err := ShardGroups(last, f.sharder)
+		if err != nil {
+			level.Warn(f.log).Log("msg", "could not determine target ownership, keeping last known targets", "err", err)
+			retry = time.After(shardRetryInterval)
+			continue
+		}
+
+		select {
+		case <-f.ctx.Done():
+			return
+		case f.outputCh <- out:
+		}
+	}
+}
+
+// Stop stops the shard filter from processing more target updates.
+func (f *ShardFilter) Stop() {
+	f.cancel()
+}
+
+// SyncCh returns a read only channel used by all the clients to receive
+// target updates.
+func (f *ShardFilter) SyncCh() GroupChannel {
+	return f.outputCh
+}
+
+// ShardGroups takes a set of DiscoveredGroups as input and filters out any
+// Target that is not owned by sharder. Targets are identified by their job
+// name and the full set of labels discovered for them.
+//
+// If ownership of any target cannot be determined, ShardGroups returns the
+// error and no groups.
+func ShardGroups(in DiscoveredGroups, sharder TargetSharder) (DiscoveredGroups, error) {
+	out := make(DiscoveredGroups, len(in))
+
+	for name, groups := range in {
+		groupList := make([]*targetgroup.Group, 0, len(groups))
+
+		for _, group := range groups {
+			newGroup := &targetgroup.Group{
+				Targets: make([]model.LabelSet, 0, len(group.Targets)),
+				Labels:  group.Labels,
+				Source:  group.Source,
+			}
+
+			for _, target := range group.Targets {
+				allLabels := labels.New(toLabelSlice(mergeSets(target, group.Labels))...)
+
+				owned, err := sharder.Owns(name + "/" + allLabels.String())
+				if err != nil {
+					return nil, err
+				}
+				if owned {
+					newGroup.Targets = append(newGroup.Targets, target)
+				}
+			}
+
+			groupList = append(groupList, newGroup)
+		}
+
+		out[name] = groupList
+	}
+
+	return out, nil
+}
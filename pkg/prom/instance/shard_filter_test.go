@@ -0,0 +1,131 @@
+package instance
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/require"
+)
+
+type funcSharder func(key string) (bool, error)
+
+func (f funcSharder) Owns(key string) (bool, error) { return f(key) }
+func (f funcSharder) Changed() <-chan struct{}      { return nil }
+
+func TestShardGroups(t *testing.T) {
+	in := DiscoveredGroups{
+		"job": {{
+			Targets: []model.LabelSet{
+				{model.AddressLabel: "keep:12345"},
+				{model.AddressLabel: "drop:12345"},
+			},
+			Labels: model.LabelSet{"env": "test"},
+			Source: "source",
+		}},
+	}
+
+	var keys []string
+	sharder := funcSharder(func(key string) (bool, error) {
+		keys = append(keys, key)
+		return strings.Contains(key, "keep"), nil
+	})
+
+	out, err := ShardGroups(in, sharder)
+	require.NoError(t, err)
+	require.Equal(t, DiscoveredGroups{
+		"job": {{
+			Targets: []model.LabelSet{{model.AddressLabel: "keep:12345"}},
+			Labels:  model.LabelSet{"env": "test"},
+			Source:  "source",
+		}},
+	}, out)
+
+	// Keys should contain the job name and the merged set of labels.
+	require.Equal(t, []string{
+		`job/{__address__="keep:12345", env="test"}`,
+		`job/{__address__="drop:12345", env="test"}`,
+	}, keys)
+}
+
+func TestShardGroups_Error(t *testing.T) {
+	in := DiscoveredGroups{
+		"job": {&targetgroup.Group{
+			Targets: []model.LabelSet{{model.AddressLabel: "localhost:12345"}},
+			Labels:  model.LabelSet{},
+		}},
+	}
+
+	sharder := funcSharder(func(key string) (bool, error) {
+		return false, fmt.Errorf("empty ring")
+	})
+
+	out, err := ShardGroups(in, sharder)
+	require.EqualError(t, err, "empty ring")
+	require.Nil(t, out)
+}
+
+func TestShardFilter_KeepsLastTargetsOnError(t *testing.T) {
+	in := DiscoveredGroups{
+		"job": {{
+			Targets: []model.LabelSet{{model.AddressLabel: "localhost:12345"}},
+		}},
+	}
+
+	var (
+		mut     sync.Mutex
+		healthy = true
+		changed = make(chan struct{})
+	)
+	sharder := &testSharder{
+		owns: func(string) (bool, error) {
+			mut.Lock()
+			defer mut.Unlock()
+			if !healthy {
+				return false, fmt.Errorf("empty ring")
+			}
+			return true, nil
+		},
+		changed: func() <-chan struct{} {
+			mut.Lock()
+			defer mut.Unlock()
+			return changed
+		},
+	}
+
+	f := NewShardFilter(log.NewNopLogger(), sharder)
+	syncCh := make(chan DiscoveredGroups)
+	go f.Run(syncCh)
+	defer f.Stop()
+
+	syncCh <- in
+	require.Equal(t, in, <-f.SyncCh())
+
+	// Ownership can't be determined anymore; the filter should not send
+	// anything new so the last targets keep being scraped.
+	mut.Lock()
+	healthy = false
+	close(changed)
+	changed = make(chan struct{})
+	mut.Unlock()
+
+	select {
+	case out := <-f.SyncCh():
+		require.FailNow(t, "unexpected targets sent while ring is unhealthy", "got %v", out)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+type testSharder struct {
+	owns    func(key string) (bool, error)
+	changed func() <-chan struct{}
+}
+
+func (s *testSharder) Owns(key string) (bool, error) { return s.owns(key) }
+func (s *testSharder) Changed() <-chan struct{}      { return s.changed() }
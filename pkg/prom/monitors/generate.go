@@ -0,0 +1,198 @@
+package monitors
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	grafana "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/assets"
+	"github.com/grafana/agent/pkg/operator/config"
+	"github.com/grafana/agent/pkg/prom/instance"
+	prom "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"gopkg.in/yaml.v2"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// buildDeployment lists all monitors matching cfg and returns them as a
+// Deployment with a single PrometheusInstance. Monitors are sorted so the
+// generated config is stable.
+func buildDeployment(ctx context.Context, r client.Reader, cfg Config) (config.Deployment, error) {
+	sel, err := labels.Parse(cfg.Selector)
+	if err != nil {
+		return config.Deployment{}, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	namespaces := cfg.Namespaces
+	if len(namespaces) == 0 {
+		// An empty namespace lists across all namespaces.
+		namespaces = []string{""}
+	}
+
+	inst := config.PrometheusInstance{
+		Instance: &grafana.PrometheusInstance{
+			ObjectMeta: meta_v1.ObjectMeta{Name: cfg.InstanceName},
+		},
+	}
+
+	for _, ns := range namespaces {
+		opts := &client.ListOptions{Namespace: ns, LabelSelector: sel}
+
+		var sMons prom.ServiceMonitorList
+		if err := r.List(ctx, &sMons, opts); err != nil {
+			return config.Deployment{}, fmt.Errorf("failed to list ServiceMonitors: %w", err)
+		}
+		inst.ServiceMonitors = append(inst.ServiceMonitors, sMons.Items...)
+
+		var pMons prom.PodMonitorList
+		if err := r.List(ctx, &pMons, opts); err != nil {
+			return config.Deployment{}, fmt.Errorf("failed to list PodMonitors: %w", err)
+		}
+		inst.PodMonitors = append(inst.PodMonitors, pMons.Items...)
+
+		var probes prom.ProbeList
+		if err := r.List(ctx, &probes, opts); err != nil {
+			return config.Deployment{}, fmt.Errorf("failed to list Probes: %w", err)
+		}
+		inst.Probes = append(inst.Probes, probes.Items...)
+	}
+
+	sort.Slice(inst.ServiceMonitors, func(i, j int) bool {
+		return objectKey(inst.ServiceMonitors[i]) < objectKey(inst.ServiceMonitors[j])
+	})
+	sort.Slice(inst.PodMonitors, func(i, j int) bool {
+		return objectKey(inst.PodMonitors[i]) < objectKey(inst.PodMonitors[j])
+	})
+	sort.Slice(inst.Probes, func(i, j int) bool {
+		return objectKey(inst.Probes[i]) < objectKey(inst.Probes[j])
+	})
+
+	return config.Deployment{
+		Agent: &grafana.GrafanaAgent{
+			ObjectMeta: meta_v1.ObjectMeta{Name: cfg.InstanceName},
+		},
+		Prometheis: []config.PrometheusInstance{inst},
+	}, nil
+}
+
+func objectKey(o client.Object) string {
+	return client.ObjectKeyFromObject(o).String()
+}
+
+// fillStore retrieves all the values from refs and caches them in the
+// provided store.
+func fillStore(ctx context.Context, r client.Reader, refs []config.AssetReference, store assets.SecretStore) error {
+	for _, ref := range refs {
+		var value string
+
+		if ref.Reference.ConfigMap != nil {
+			var cm core_v1.ConfigMap
+			name := types.NamespacedName{
+				Namespace: ref.Namespace,
+				Name:      ref.Reference.ConfigMap.Name,
+			}
+
+			if err := r.Get(ctx, name, &cm); err != nil {
+				return err
+			}
+
+			if rawValue, ok := cm.Data[ref.Reference.ConfigMap.Key]; ok {
+				value = rawValue
+			} else if rawValue, ok := cm.BinaryData[ref.Reference.ConfigMap.Key]; ok {
+				value = string(rawValue)
+			} else {
+				return fmt.Errorf("no key %s in ConfigMap %s", ref.Reference.ConfigMap.Key, name)
+			}
+		} else if ref.Reference.Secret != nil {
+			var secret core_v1.Secret
+			name := types.NamespacedName{
+				Namespace: ref.Namespace,
+				Name:      ref.Reference.Secret.Name,
+			}
+
+			if err := r.Get(ctx, name, &secret); err != nil {
+				return err
+			}
+
+			rawValue, ok := secret.Data[ref.Reference.Secret.Key]
+			if !ok {
+				return fmt.Errorf("no key %s in Secret %s", ref.Reference.Secret.Key, name)
+			}
+			value = string(rawValue)
+		}
+
+		store[assets.KeyForSelector(ref.Namespace, &ref.Reference)] = value
+	}
+
+	return nil
+}
+
+// writeSecrets writes every value of store into dir, so they can be used by
+// generated configs which refer to files.
+func writeSecrets(dir string, store assets.SecretStore) error {
+	if len(store) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for k, v := range store {
+		path := filepath.Join(dir, config.SecretFileName(k))
+		if err := ioutil.WriteFile(path, []byte(v), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateInstanceConfig converts the monitors of d into an instance config.
+func generateInstanceConfig(
+	cfg Config,
+	global instance.GlobalConfig,
+	d config.Deployment,
+	store assets.SecretStore,
+) (*instance.Config, error) {
+	out, err := d.BuildConfigWithSecretsDir(store, cfg.SecretsDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate config: %w", err)
+	}
+
+	// Generated scrape configs only keep the targets of the shard in the
+	// SHARD environment variable, which the Operator provides. The generated
+	// instance always runs as the only shard.
+	out = strings.ReplaceAll(out, "$(SHARD)", "0")
+
+	var generated struct {
+		Prometheus struct {
+			Configs []instance.Config `yaml:"configs"`
+		} `yaml:"prometheus"`
+	}
+	if err := yaml.Unmarshal([]byte(out), &generated); err != nil {
+		return nil, fmt.Errorf("failed to parse generated config: %w", err)
+	}
+	if n := len(generated.Prometheus.Configs); n != 1 {
+		return nil, fmt.Errorf("expected one generated instance, got %d", n)
+	}
+
+	ic := generated.Prometheus.Configs[0]
+	ic.Name = cfg.InstanceName
+
+	// Copy remote_writes since applying defaults modifies them.
+	for _, rw := range cfg.RemoteWrite {
+		rwCopy := *rw
+		ic.RemoteWrite = append(ic.RemoteWrite, &rwCopy)
+	}
+
+	if err := ic.ApplyDefaults(global); err != nil {
+		return nil, fmt.Errorf("invalid generated instance: %w", err)
+	}
+	return &ic, nil
+}
@@ -0,0 +1,187 @@
+package monitors
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/agent/pkg/operator/assets"
+	"github.com/grafana/agent/pkg/prom/instance"
+	prom "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGenerateInstanceConfig(t *testing.T) {
+	objects := []client.Object{
+		&prom.ServiceMonitor{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "app",
+				Labels:    map[string]string{"team": "a"},
+			},
+			Spec: prom.ServiceMonitorSpec{
+				Selector: meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "app"}},
+				Endpoints: []prom.Endpoint{{
+					Port: "http-metrics",
+					BasicAuth: &prom.BasicAuth{
+						Username: core_v1.SecretKeySelector{
+							LocalObjectReference: core_v1.LocalObjectReference{Name: "creds"},
+							Key:                  "username",
+						},
+						Password: core_v1.SecretKeySelector{
+							LocalObjectReference: core_v1.LocalObjectReference{Name: "creds"},
+							Key:                  "password",
+						},
+					},
+					TLSConfig: &prom.TLSConfig{
+						SafeTLSConfig: prom.SafeTLSConfig{
+							CA: prom.SecretOrConfigMap{
+								ConfigMap: &core_v1.ConfigMapKeySelector{
+									LocalObjectReference: core_v1.LocalObjectReference{Name: "ca"},
+									Key:                  "ca.crt",
+								},
+							},
+						},
+					},
+				}},
+			},
+		},
+		&prom.PodMonitor{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "other",
+				Name:      "pods",
+				Labels:    map[string]string{"team": "a"},
+			},
+			Spec: prom.PodMonitorSpec{
+				PodMetricsEndpoints: []prom.PodMetricsEndpoint{{Port: "metrics"}},
+			},
+		},
+		&prom.ServiceMonitor{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "default",
+				Name:      "unselected",
+				Labels:    map[string]string{"team": "b"},
+			},
+			Spec: prom.ServiceMonitorSpec{
+				Endpoints: []prom.Endpoint{{Port: "http-metrics"}},
+			},
+		},
+		&core_v1.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "creds"},
+			Data: map[string][]byte{
+				"username": []byte("user"),
+				"password": []byte("pass"),
+			},
+		},
+		&core_v1.ConfigMap{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "ca"},
+			Data:       map[string]string{"ca.crt": "certificate"},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, core_v1.AddToScheme(scheme))
+	require.NoError(t, prom.AddToScheme(scheme))
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	cfg := DefaultConfig
+	cfg.Enabled = true
+	cfg.Selector = "team=a"
+	cfg.SecretsDirectory = t.TempDir()
+
+	var rw instance.RemoteWriteConfig
+	require.NoError(t, yaml.Unmarshal([]byte("url: http://localhost:9009/api/prom/push"), &rw))
+	cfg.RemoteWrite = []*instance.RemoteWriteConfig{&rw}
+
+	ctx := context.Background()
+	d, err := buildDeployment(ctx, cli, cfg)
+	require.NoError(t, err)
+
+	store := make(assets.SecretStore)
+	require.NoError(t, fillStore(ctx, cli, d.AssetReferences(), store))
+	require.NoError(t, writeSecrets(cfg.SecretsDirectory, store))
+
+	ic, err := generateInstanceConfig(cfg, instance.DefaultGlobalConfig, d, store)
+	require.NoError(t, err)
+
+	require.Equal(t, "kubernetes-monitors", ic.Name)
+	require.Len(t, ic.RemoteWrite, 1)
+	require.Equal(t, "http://localhost:9009/api/prom/push", ic.RemoteWrite[0].URL.String())
+	// The original remote_write must not be modified by applying defaults.
+	require.Empty(t, rw.Name)
+
+	require.Len(t, ic.ScrapeConfigs, 2)
+
+	sc := ic.ScrapeConfigs[0]
+	require.Equal(t, "serviceMonitor/default/app/0", sc.JobName)
+	require.Equal(t, "user", sc.HTTPClientConfig.BasicAuth.Username)
+	require.Equal(t, "pass", string(sc.HTTPClientConfig.BasicAuth.Password))
+
+	caFile := sc.HTTPClientConfig.TLSConfig.CAFile
+	require.Equal(t, cfg.SecretsDirectory, filepath.Dir(caFile))
+	ca, err := ioutil.ReadFile(caFile)
+	require.NoError(t, err)
+	require.Equal(t, "certificate", string(ca))
+
+	// The shard relabel rules must keep all targets.
+	var foundShardRule bool
+	for _, rc := range sc.RelabelConfigs {
+		if rc.Action == "keep" && len(rc.SourceLabels) == 1 && rc.SourceLabels[0] == "__tmp_hash" {
+			foundShardRule = true
+			require.True(t, rc.Regex.MatchString("0"))
+		}
+	}
+	require.True(t, foundShardRule, "shard relabel rule not generated")
+
+	require.Equal(t, "podMonitor/other/pods/0", ic.ScrapeConfigs[1].JobName)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		modify func(c *Config)
+		expect string
+	}{
+		{
+			name:   "disabled",
+			modify: func(c *Config) { c.Enabled = false; c.InstanceName = "" },
+		},
+		{
+			name:   "enabled",
+			modify: func(c *Config) {},
+		},
+		{
+			name:   "empty instance name",
+			modify: func(c *Config) { c.InstanceName = "" },
+			expect: "instance_name must not be empty",
+		},
+		{
+			name:   "invalid selector",
+			modify: func(c *Config) { c.Selector = "team in (a" },
+			expect: "invalid selector",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig
+			cfg.Enabled = true
+			tc.modify(&cfg)
+
+			err := cfg.Validate()
+			if tc.expect == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package monitors generates a Prometheus instance from the ServiceMonitor,
+// PodMonitor, and Probe custom resources of the Prometheus Operator. It allows
+// an Agent running in Kubernetes to follow the conventions of the Prometheus
+// Operator without running the Grafana Agent Operator.
+package monitors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/util"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DefaultConfig holds the default settings for watching monitors.
+var DefaultConfig = Config{
+	InstanceName:     "kubernetes-monitors",
+	RefreshInterval:  5 * time.Minute,
+	SecretsDirectory: filepath.Join(os.TempDir(), "grafana-agent-monitors"),
+}
+
+// Config controls watching ServiceMonitors, PodMonitors, and Probes.
+type Config struct {
+	// Enabled enables watching monitors.
+	Enabled bool `yaml:"enabled"`
+
+	// InstanceName is the name of the generated instance. It must not be used
+	// by any other instance.
+	InstanceName string `yaml:"instance_name,omitempty"`
+
+	// KubeconfigFile is used to connect to Kubernetes. The in-cluster config
+	// is used when empty.
+	KubeconfigFile string `yaml:"kubeconfig_file,omitempty"`
+
+	// Namespaces to watch monitors in. All namespaces are watched when empty.
+	Namespaces []string `yaml:"namespaces,omitempty"`
+
+	// Selector is a Kubernetes label selector which monitors must match.
+	Selector string `yaml:"selector,omitempty"`
+
+	// RefreshInterval is how often the instance is regenerated in addition
+	// to changes of monitors. This picks up changes to referenced Secrets and
+	// ConfigMaps, which aren't watched.
+	RefreshInterval time.Duration `yaml:"refresh_interval,omitempty"`
+
+	// SecretsDirectory is where the values of Secrets and ConfigMaps
+	// referenced as files (such as TLS certificates) are written to.
+	SecretsDirectory string `yaml:"secrets_directory,omitempty"`
+
+	// RemoteWrite for the generated instance. Defaults to the global
+	// remote_write.
+	RemoteWrite []*instance.RemoteWriteConfig `yaml:"remote_write,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Validate returns an error if c is invalid. Validate only checks settings
+// when c is enabled.
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	switch {
+	case c.InstanceName == "":
+		return errors.New("instance_name must not be empty")
+	case c.RefreshInterval <= 0:
+		return errors.New("refresh_interval must be greater than 0s")
+	case c.SecretsDirectory == "":
+		return errors.New("secrets_directory must not be empty")
+	}
+
+	if _, err := labels.Parse(c.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	return nil
+}
+
+// Manager runs a watcher of monitors for the current Config.
+type Manager struct {
+	log log.Logger
+	im  instance.Manager
+
+	mut     sync.Mutex
+	cfg     Config
+	global  instance.GlobalConfig
+	watcher *watcher
+	stopped bool
+}
+
+// New creates a new Manager. Generated instance configs are applied to im.
+// The Manager does nothing until ApplyConfig is called with an enabled
+// Config.
+func New(l log.Logger, im instance.Manager) *Manager {
+	return &Manager{
+		log: log.With(l, "component", "kubernetes_monitors"),
+		im:  im,
+	}
+}
+
+// ApplyConfig starts, restarts, or stops watching monitors. global is used to
+// apply defaults to the generated instance.
+func (m *Manager) ApplyConfig(cfg Config, global instance.GlobalConfig) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.stopped {
+		return errors.New("manager stopped")
+	}
+
+	running := m.watcher != nil
+	if running == cfg.Enabled && util.CompareYAML(m.cfg, cfg) && util.CompareYAML(m.global, global) {
+		return nil
+	}
+
+	if m.watcher != nil {
+		m.watcher.Stop()
+		m.watcher = nil
+	}
+
+	// Remove the previously generated instance if it's not going to be
+	// replaced by the new watcher.
+	if m.cfg.Enabled && (!cfg.Enabled || m.cfg.InstanceName != cfg.InstanceName) {
+		_ = m.im.DeleteConfig(m.cfg.InstanceName)
+	}
+
+	// Store the config before starting the watcher so a failed config can
+	// be rolled back by applying the previous one.
+	m.cfg, m.global = cfg, global
+	if !cfg.Enabled {
+		return nil
+	}
+
+	w, err := newWatcher(m.log, m.im, cfg, global)
+	if err != nil {
+		return fmt.Errorf("failed to watch monitors: %w", err)
+	}
+	m.watcher = w
+	return nil
+}
+
+// Stop stops the Manager. The generated instance is left running in the
+// instance manager.
+func (m *Manager) Stop() {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.watcher != nil {
+		m.watcher.Stop()
+		m.watcher = nil
+	}
+	m.stopped = true
+}
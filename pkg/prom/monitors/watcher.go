@@ -0,0 +1,186 @@
+package monitors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/operator/assets"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/util"
+	prom "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// watcher watches monitors and applies the instance generated from them.
+type watcher struct {
+	log    log.Logger
+	im     instance.Manager
+	cfg    Config
+	global instance.GlobalConfig
+
+	// cache holds the watched monitors. client reads Secrets and ConfigMaps
+	// directly from the API server so they don't have to be watched.
+	cache  cache.Cache
+	client client.Reader
+
+	notify chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// last is the most recently applied instance config.
+	last *instance.Config
+}
+
+func newWatcher(l log.Logger, im instance.Manager, cfg Config, global instance.GlobalConfig) (*watcher, error) {
+	restConfig, err := restConfig(cfg.KubeconfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		core_v1.AddToScheme,
+		prom.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			return nil, fmt.Errorf("unable to register scheme: %w", err)
+		}
+	}
+
+	newCache := cache.New
+	if len(cfg.Namespaces) > 0 {
+		newCache = cache.MultiNamespacedCacheBuilder(cfg.Namespaces)
+	}
+	c, err := newCache(restConfig, cache.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+	cli, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watcher{
+		log:    l,
+		im:     im,
+		cfg:    cfg,
+		global: global,
+
+		cache:  c,
+		client: cli,
+
+		notify: make(chan struct{}, 1),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	// Every change to a monitor triggers regenerating the instance. Changes
+	// are coalesced while a previous change is being handled.
+	handler := toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(_ interface{}) { w.trigger() },
+		UpdateFunc: func(_, _ interface{}) { w.trigger() },
+		DeleteFunc: func(_ interface{}) { w.trigger() },
+	}
+	for _, obj := range []client.Object{&prom.ServiceMonitor{}, &prom.PodMonitor{}, &prom.Probe{}} {
+		inf, err := c.GetInformer(ctx, obj)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to get informer: %w", err)
+		}
+		inf.AddEventHandler(handler)
+	}
+
+	go w.run(ctx)
+	return w, nil
+}
+
+func restConfig(kubeconfigFile string) (*rest.Config, error) {
+	if kubeconfigFile != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfigFile)
+	}
+	return rest.InClusterConfig()
+}
+
+func (w *watcher) trigger() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) run(ctx context.Context) {
+	defer close(w.done)
+
+	go func() {
+		if err := w.cache.Start(ctx); err != nil {
+			level.Error(w.log).Log("msg", "failed to watch monitors", "err", err)
+		}
+	}()
+	if !w.cache.WaitForCacheSync(ctx) {
+		return
+	}
+
+	ticker := time.NewTicker(w.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.reconcile(ctx); err != nil {
+			level.Error(w.log).Log("msg", "failed to generate instance from monitors", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile generates the instance config from the current set of monitors
+// and applies it if it changed.
+func (w *watcher) reconcile(ctx context.Context) error {
+	d, err := buildDeployment(ctx, w.cache, w.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to list monitors: %w", err)
+	}
+
+	store := make(assets.SecretStore)
+	if err := fillStore(ctx, w.client, d.AssetReferences(), store); err != nil {
+		return fmt.Errorf("failed to read referenced secrets: %w", err)
+	}
+	if err := writeSecrets(w.cfg.SecretsDirectory, store); err != nil {
+		return fmt.Errorf("failed to write referenced secrets: %w", err)
+	}
+
+	cfg, err := generateInstanceConfig(w.cfg, w.global, d, store)
+	if err != nil {
+		return err
+	}
+	if w.last != nil && util.CompareYAML(w.last, cfg) {
+		return nil
+	}
+
+	level.Info(w.log).Log("msg", "applying instance generated from monitors", "instance", cfg.Name, "scrape_configs", len(cfg.ScrapeConfigs))
+	if err := w.im.ApplyConfig(*cfg); err != nil {
+		return fmt.Errorf("failed to apply instance: %w", err)
+	}
+	w.last = cfg
+	return nil
+}
+
+// Stop stops the watcher and waits for it to exit.
+func (w *watcher) Stop() {
+	w.cancel()
+	<-w.done
+}
@@ -0,0 +1,167 @@
+package prom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/grafana/agent/pkg/prom/cluster/configapi"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// RelabelRequest is the request body accepted by the RelabelHandler.
+type RelabelRequest struct {
+	// Instance is the name of the instance config to use.
+	Instance string `json:"instance"`
+	// Job is the job_name of the scrape_config to use.
+	Job string `json:"job"`
+	// Labels is the set of labels to relabel, such as the labels of a
+	// discovered target.
+	Labels labels.Labels `json:"labels"`
+}
+
+// RelabelResponse is returned by the RelabelHandler.
+type RelabelResponse struct {
+	RelabelConfigs RelabelPhase `json:"relabel_configs"`
+
+	// MetricRelabelConfigs is nil when the target was dropped by
+	// relabel_configs.
+	MetricRelabelConfigs *RelabelPhase `json:"metric_relabel_configs"`
+}
+
+// RelabelPhase describes the result of applying a list of relabel rules.
+type RelabelPhase struct {
+	Input   labels.Labels `json:"input"`
+	Steps   []RelabelStep `json:"steps"`
+	Output  labels.Labels `json:"output"`
+	Dropped bool          `json:"dropped"`
+}
+
+// RelabelStep is the result of applying a single relabel rule.
+type RelabelStep struct {
+	Index   int            `json:"index"`
+	Action  relabel.Action `json:"action"`
+	Labels  labels.Labels  `json:"labels"`
+	Dropped bool           `json:"dropped"`
+}
+
+// RelabelHandler applies the relabel_configs and metric_relabel_configs of a
+// scrape_config to a set of labels and returns the result of each rule.
+func (a *Agent) RelabelHandler(w http.ResponseWriter, r *http.Request) {
+	var req RelabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to parse request: %w", err))
+		return
+	}
+
+	cfg, ok := a.mm.ListConfigs()[req.Instance]
+	if !ok {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("instance %q not found", req.Instance))
+		return
+	}
+
+	var sc *config.ScrapeConfig
+	for _, c := range cfg.ScrapeConfigs {
+		if c.JobName == req.Job {
+			sc = c
+			break
+		}
+	}
+	if sc == nil {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("scrape config %q not found in instance %q", req.Job, req.Instance))
+		return
+	}
+
+	err := configapi.WriteResponse(w, http.StatusOK, debugRelabel(sc, req.Labels))
+	if err != nil {
+		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
+	}
+}
+
+func (a *Agent) writeError(w http.ResponseWriter, statusCode int, err error) {
+	if err := configapi.WriteError(w, statusCode, err); err != nil {
+		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
+	}
+}
+
+// debugRelabel runs lset through the relabel_configs of sc. If the labels
+// weren't dropped, the public labels of the result (along with __name__, if
+// set) are then run through the metric_relabel_configs of sc.
+func debugRelabel(sc *config.ScrapeConfig, lset labels.Labels) RelabelResponse {
+	var resp RelabelResponse
+
+	// Set the same default labels that Prometheus sets before relabeling
+	// targets.
+	lb := labels.NewBuilder(lset)
+	for name, value := range map[string]string{
+		model.JobLabel:         sc.JobName,
+		model.MetricsPathLabel: sc.MetricsPath,
+		model.SchemeLabel:      sc.Scheme,
+	} {
+		if lset.Get(name) == "" {
+			lb.Set(name, value)
+		}
+	}
+	for k, v := range sc.Params {
+		if len(v) > 0 {
+			lb.Set(model.ParamLabelPrefix+k, v[0])
+		}
+	}
+
+	resp.RelabelConfigs = relabelSteps(lb.Labels(), sc.RelabelConfigs)
+	if resp.RelabelConfigs.Dropped {
+		return resp
+	}
+
+	// Internal labels are removed from targets after relabeling. __name__
+	// is kept so metric relabel rules can be tested against a metric name.
+	target := resp.RelabelConfigs.Output
+	lb = labels.NewBuilder(target)
+	for _, l := range target {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			lb.Del(l.Name)
+		}
+	}
+	if target.Get(model.InstanceLabel) == "" {
+		lb.Set(model.InstanceLabel, target.Get(model.AddressLabel))
+	}
+	if name := lset.Get(model.MetricNameLabel); name != "" {
+		lb.Set(model.MetricNameLabel, name)
+	}
+
+	metricPhase := relabelSteps(lb.Labels(), sc.MetricRelabelConfigs)
+	resp.MetricRelabelConfigs = &metricPhase
+	return resp
+}
+
+// relabelSteps applies each rule in cfgs to lset in order, stopping once the
+// labels are dropped.
+func relabelSteps(lset labels.Labels, cfgs []*relabel.Config) RelabelPhase {
+	phase := RelabelPhase{
+		Input: lset,
+		Steps: make([]RelabelStep, 0, len(cfgs)),
+	}
+
+	current := lset
+	for i, cfg := range cfgs {
+		current = relabel.Process(current, cfg)
+		phase.Steps = append(phase.Steps, RelabelStep{
+			Index:   i,
+			Action:  cfg.Action,
+			Labels:  current,
+			Dropped: current == nil,
+		})
+		if current == nil {
+			break
+		}
+	}
+
+	phase.Output = current
+	phase.Dropped = current == nil
+	return phase
+}
@@ -0,0 +1,126 @@
+package prom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_RelabelHandler(t *testing.T) {
+	cfgText := `
+name: default
+scrape_configs:
+- job_name: test
+  static_configs:
+  - targets: ['localhost:12345']
+  relabel_configs:
+  - source_labels: [__meta_pod]
+    target_label: pod
+  - source_labels: [__meta_drop]
+    regex: yes
+    action: drop
+  metric_relabel_configs:
+  - source_labels: [__name__]
+    regex: go_.*
+    action: drop`
+
+	cfg, err := instance.UnmarshalConfig(strings.NewReader(cfgText))
+	require.NoError(t, err)
+	require.NoError(t, cfg.ApplyDefaults(instance.DefaultGlobalConfig))
+
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+	defer a.Stop()
+	require.NoError(t, a.mm.ApplyConfig(*cfg))
+
+	tt := []struct {
+		name   string
+		body   string
+		status int
+		expect string
+	}{
+		{
+			name:   "kept",
+			body:   `{"instance": "default", "job": "test", "labels": {"__address__": "localhost:12345", "__meta_pod": "a", "__name__": "up"}}`,
+			status: http.StatusOK,
+			expect: `{
+				"status": "success",
+				"data": {
+					"relabel_configs": {
+						"input": {"__address__": "localhost:12345", "__meta_pod": "a", "__metrics_path__": "/metrics", "__name__": "up", "__scheme__": "http", "job": "test"},
+						"steps": [
+							{"index": 0, "action": "replace", "labels": {"__address__": "localhost:12345", "__meta_pod": "a", "__metrics_path__": "/metrics", "__name__": "up", "__scheme__": "http", "job": "test", "pod": "a"}, "dropped": false},
+							{"index": 1, "action": "drop", "labels": {"__address__": "localhost:12345", "__meta_pod": "a", "__metrics_path__": "/metrics", "__name__": "up", "__scheme__": "http", "job": "test", "pod": "a"}, "dropped": false}
+						],
+						"output": {"__address__": "localhost:12345", "__meta_pod": "a", "__metrics_path__": "/metrics", "__name__": "up", "__scheme__": "http", "job": "test", "pod": "a"},
+						"dropped": false
+					},
+					"metric_relabel_configs": {
+						"input": {"__name__": "up", "instance": "localhost:12345", "job": "test", "pod": "a"},
+						"steps": [
+							{"index": 0, "action": "drop", "labels": {"__name__": "up", "instance": "localhost:12345", "job": "test", "pod": "a"}, "dropped": false}
+						],
+						"output": {"__name__": "up", "instance": "localhost:12345", "job": "test", "pod": "a"},
+						"dropped": false
+					}
+				}
+			}`,
+		},
+		{
+			name:   "target dropped",
+			body:   `{"instance": "default", "job": "test", "labels": {"__address__": "localhost:12345", "__meta_drop": "yes"}}`,
+			status: http.StatusOK,
+			expect: `{
+				"status": "success",
+				"data": {
+					"relabel_configs": {
+						"input": {"__address__": "localhost:12345", "__meta_drop": "yes", "__metrics_path__": "/metrics", "__scheme__": "http", "job": "test"},
+						"steps": [
+							{"index": 0, "action": "replace", "labels": {"__address__": "localhost:12345", "__meta_drop": "yes", "__metrics_path__": "/metrics", "__scheme__": "http", "job": "test"}, "dropped": false},
+							{"index": 1, "action": "drop", "labels": {}, "dropped": true}
+						],
+						"output": {},
+						"dropped": true
+					},
+					"metric_relabel_configs": null
+				}
+			}`,
+		},
+		{
+			name:   "unknown instance",
+			body:   `{"instance": "missing", "job": "test"}`,
+			status: http.StatusNotFound,
+			expect: `{"status": "error", "data": {"error": "instance \"missing\" not found"}}`,
+		},
+		{
+			name:   "unknown job",
+			body:   `{"instance": "default", "job": "missing"}`,
+			status: http.StatusNotFound,
+			expect: `{"status": "error", "data": {"error": "scrape config \"missing\" not found in instance \"default\""}}`,
+		},
+		{
+			name:   "invalid body",
+			body:   `{`,
+			status: http.StatusBadRequest,
+			expect: `{"status": "error", "data": {"error": "failed to parse request: unexpected EOF"}}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			a.RelabelHandler(rr, httptest.NewRequest("POST", "/agent/api/v1/metrics/relabel", strings.NewReader(tc.body)))
+			require.Equal(t, tc.status, rr.Result().StatusCode)
+			require.JSONEq(t, tc.expect, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package wal
+
+import (
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// ReplayStatus describes the progress of replaying an existing WAL when a
+// Storage is created.
+type ReplayStatus struct {
+	// Done is true once replaying has finished.
+	Done bool
+
+	// SegmentsTotal is the number of segments to replay, including the
+	// checkpoint. Set once replaying starts.
+	SegmentsTotal int
+	// SegmentsReplayed is the number of segments that have been replayed.
+	SegmentsReplayed int
+	// SamplesReplayed is the number of samples that have been read.
+	SamplesReplayed int64
+
+	// StartTime is when replaying started. Zero if it hasn't started.
+	StartTime time.Time
+	// EndTime is when replaying finished. Zero if it hasn't finished.
+	EndTime time.Time
+}
+
+// SegmentsRemaining returns the number of segments left to replay.
+func (s ReplayStatus) SegmentsRemaining() int {
+	return s.SegmentsTotal - s.SegmentsReplayed
+}
+
+// ReplayProgress tracks the progress of replaying the WAL. It is safe for
+// concurrent use, allowing progress to be checked while a Storage is being
+// created.
+type ReplayProgress struct {
+	done             atomic.Bool
+	segmentsTotal    atomic.Int64
+	segmentsReplayed atomic.Int64
+	samplesReplayed  atomic.Int64
+	startTime        atomic.Int64 // Unix nanoseconds
+	endTime          atomic.Int64 // Unix nanoseconds
+}
+
+// NewReplayProgress creates a new ReplayProgress.
+func NewReplayProgress() *ReplayProgress {
+	return &ReplayProgress{}
+}
+
+// Status returns the current status of replaying the WAL.
+func (p *ReplayProgress) Status() ReplayStatus {
+	return ReplayStatus{
+		Done:             p.done.Load(),
+		SegmentsTotal:    int(p.segmentsTotal.Load()),
+		SegmentsReplayed: int(p.segmentsReplayed.Load()),
+		SamplesReplayed:  p.samplesReplayed.Load(),
+		StartTime:        unixNanoTime(p.startTime.Load()),
+		EndTime:          unixNanoTime(p.endTime.Load()),
+	}
+}
+
+// reset clears progress so p can be reused for a new Storage.
+func (p *ReplayProgress) reset() {
+	p.done.Store(false)
+	p.segmentsTotal.Store(0)
+	p.segmentsReplayed.Store(0)
+	p.samplesReplayed.Store(0)
+	p.startTime.Store(time.Now().UnixNano())
+	p.endTime.Store(0)
+}
+
+func (p *ReplayProgress) finish() {
+	p.endTime.Store(time.Now().UnixNano())
+	p.done.Store(true)
+}
+
+func unixNanoTime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
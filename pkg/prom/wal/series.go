@@ -3,6 +3,7 @@ package wal
 import (
 	"sync"
 
+	"github.com/prometheus/prometheus/pkg/exemplar"
 	"github.com/prometheus/prometheus/pkg/intern"
 	"github.com/prometheus/prometheus/pkg/labels"
 )
@@ -14,6 +15,11 @@ type memSeries struct {
 	lset   labels.Labels
 	lastTs int64
 
+	// lastExemplar is the most recent exemplar committed for the series. It's
+	// used to drop duplicate and out of order exemplars, which are common
+	// since targets expose the same exemplar until a new one is recorded.
+	lastExemplar *exemplar.Exemplar
+
 	// TODO(rfratto): this solution below isn't perfect, and there's still
 	// the possibility for a series to be deleted before it's
 	// completely gone from the WAL. Rather, we should have gc return
@@ -33,7 +39,11 @@ type memSeries struct {
 }
 
 func (s *memSeries) updateTs(ts int64) {
-	s.lastTs = ts
+	// Out of order samples must not move lastTs backwards, otherwise a series
+	// receiving delayed samples could be garbage collected while active.
+	if ts > s.lastTs {
+		s.lastTs = ts
+	}
 	s.willDelete = false
 	s.pendingCommit = true
 }
@@ -184,6 +194,24 @@ func (s *stripeSeries) gc(mint int64) map[uint64]struct{} {
 	return deleted
 }
 
+// remove removes series from the stripeSeries. It's a no-op if series was
+// already removed.
+func (s *stripeSeries) remove(series *memSeries) {
+	// The two stripes are locked one after the other rather than together so
+	// remove can't deadlock with gc or an iterator, which lock them in
+	// ascending ID order.
+	i := series.ref & uint64(s.size-1)
+	s.locks[i].Lock()
+	delete(s.series[i], series.ref)
+	s.locks[i].Unlock()
+
+	hash := series.lset.Hash()
+	i = hash & uint64(s.size-1)
+	s.locks[i].Lock()
+	s.hashes[i].del(hash, series.ref)
+	s.locks[i].Unlock()
+}
+
 func (s *stripeSeries) getByID(id uint64) *memSeries {
 	i := id & uint64(s.size-1)
 
@@ -18,7 +18,9 @@ import (
 	"github.com/prometheus/prometheus/pkg/value"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/fileutil"
 	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
 	"github.com/prometheus/prometheus/tsdb/wal"
 	"go.uber.org/atomic"
 )
@@ -36,9 +38,14 @@ type storageMetrics struct {
 	totalRemovedSeries     prometheus.Counter
 	totalAppendedSamples   prometheus.Counter
 	totalAppendedExemplars prometheus.Counter
+	storageSize            prometheus.GaugeFunc
+
+	replaySegmentsRemaining prometheus.GaugeFunc
+	replaySamples           prometheus.CounterFunc
+	replayDuration          prometheus.GaugeFunc
 }
 
-func newStorageMetrics(r prometheus.Registerer) *storageMetrics {
+func newStorageMetrics(r prometheus.Registerer, dir string, replay *ReplayProgress) *storageMetrics {
 	m := storageMetrics{r: r}
 	m.numActiveSeries = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "agent_wal_storage_active_series",
@@ -70,6 +77,46 @@ func newStorageMetrics(r prometheus.Registerer) *storageMetrics {
 		Help: "Total number of exemplars appended to the WAL",
 	})
 
+	m.storageSize = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "agent_wal_storage_size_bytes",
+		Help: "Size of the WAL directory on disk, including checkpoints",
+	}, func() float64 {
+		size, err := fileutil.DirSize(dir)
+		if err != nil {
+			return 0
+		}
+		return float64(size)
+	})
+
+	m.replaySegmentsRemaining = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "agent_wal_replay_segments_remaining",
+		Help: "Number of WAL segments left to replay on startup, including the checkpoint",
+	}, func() float64 {
+		return float64(replay.Status().SegmentsRemaining())
+	})
+
+	m.replaySamples = prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "agent_wal_replay_samples_total",
+		Help: "Total number of samples read while replaying the WAL on startup",
+	}, func() float64 {
+		return float64(replay.Status().SamplesReplayed)
+	})
+
+	m.replayDuration = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "agent_wal_replay_duration_seconds",
+		Help: "Time spent replaying the WAL on startup so far",
+	}, func() float64 {
+		status := replay.Status()
+		if status.StartTime.IsZero() {
+			return 0
+		}
+		end := status.EndTime
+		if end.IsZero() {
+			end = time.Now()
+		}
+		return end.Sub(status.StartTime).Seconds()
+	})
+
 	if r != nil {
 		r.MustRegister(
 			m.numActiveSeries,
@@ -78,6 +125,10 @@ func newStorageMetrics(r prometheus.Registerer) *storageMetrics {
 			m.totalRemovedSeries,
 			m.totalAppendedSamples,
 			m.totalAppendedExemplars,
+			m.storageSize,
+			m.replaySegmentsRemaining,
+			m.replaySamples,
+			m.replayDuration,
 		)
 	}
 
@@ -95,6 +146,10 @@ func (m *storageMetrics) Unregister() {
 		m.totalRemovedSeries,
 		m.totalAppendedSamples,
 		m.totalAppendedExemplars,
+		m.storageSize,
+		m.replaySegmentsRemaining,
+		m.replaySamples,
+		m.replayDuration,
 	}
 	for _, c := range cs {
 		m.r.Unregister(c)
@@ -127,11 +182,24 @@ type Storage struct {
 	deletedMtx sync.Mutex
 	deleted    map[uint64]int // Deleted series, and what WAL segment they must be kept until.
 
+	replay  *ReplayProgress
 	metrics *storageMetrics
 }
 
 // NewStorage makes a new Storage.
 func NewStorage(logger log.Logger, registerer prometheus.Registerer, path string) (*Storage, error) {
+	return NewStorageWithProgress(logger, registerer, path, nil)
+}
+
+// NewStorageWithProgress makes a new Storage, reporting the progress of
+// replaying the existing WAL to progress. The progress can be checked while
+// NewStorageWithProgress is still running. progress may be nil.
+func NewStorageWithProgress(logger log.Logger, registerer prometheus.Registerer, path string, progress *ReplayProgress) (*Storage, error) {
+	if progress == nil {
+		progress = NewReplayProgress()
+	}
+	progress.reset()
+
 	w, err := wal.NewSize(logger, registerer, SubDirectory(path), wal.DefaultSegmentSize, true)
 	if err != nil {
 		return nil, err
@@ -143,7 +211,8 @@ func NewStorage(logger log.Logger, registerer prometheus.Registerer, path string
 		logger:  logger,
 		deleted: map[uint64]int{},
 		series:  newStripeSeries(),
-		metrics: newStorageMetrics(registerer),
+		replay:  progress,
+		metrics: newStorageMetrics(registerer, SubDirectory(path), progress),
 		ref:     atomic.NewUint64(0),
 	}
 
@@ -161,7 +230,9 @@ func NewStorage(logger log.Logger, registerer prometheus.Registerer, path string
 		}
 	}
 
-	if err := storage.replayWAL(); err != nil {
+	err = storage.replayWAL()
+	progress.finish()
+	if err != nil {
 		level.Warn(storage.logger).Log("msg", "encountered WAL read error, attempting repair", "err", err)
 		if err := w.Repair(err); err != nil {
 			return nil, errors.Wrap(err, "repair corrupted WAL")
@@ -184,8 +255,28 @@ func (w *Storage) replayWAL() error {
 	if err != nil && err != record.ErrNotFound {
 		return errors.Wrap(err, "find last checkpoint")
 	}
+	hasCheckpoint := err == nil
+
+	// Find the last segment.
+	_, last, err := wal.Segments(w.wal.Dir())
+	if err != nil {
+		return errors.Wrap(err, "finding WAL segments")
+	}
 
-	if err == nil {
+	// Segments are replayed starting after the checkpoint, which is counted
+	// as its own segment.
+	var segmentsTotal int
+	first := startFrom
+	if hasCheckpoint {
+		segmentsTotal++
+		first++
+	}
+	if last >= first {
+		segmentsTotal += last - first + 1
+	}
+	w.replay.segmentsTotal.Store(int64(segmentsTotal))
+
+	if hasCheckpoint {
 		sr, err := wal.NewSegmentsReader(dir)
 		if err != nil {
 			return errors.Wrap(err, "open checkpoint")
@@ -202,15 +293,10 @@ func (w *Storage) replayWAL() error {
 			return errors.Wrap(err, "backfill checkpoint")
 		}
 		startFrom++
+		w.replay.segmentsReplayed.Inc()
 		level.Info(w.logger).Log("msg", "WAL checkpoint loaded")
 	}
 
-	// Find the last segment.
-	_, last, err := wal.Segments(w.wal.Dir())
-	if err != nil {
-		return errors.Wrap(err, "finding WAL segments")
-	}
-
 	// Backfill segments from the most recent checkpoint onwards.
 	for i := startFrom; i <= last; i++ {
 		s, err := wal.OpenReadSegment(wal.SegmentName(w.wal.Dir(), i))
@@ -226,6 +312,7 @@ func (w *Storage) replayWAL() error {
 		if err != nil {
 			return err
 		}
+		w.replay.segmentsReplayed.Inc()
 		level.Info(w.logger).Log("msg", "WAL segment loaded", "segment", i, "maxSegment", last)
 	}
 
@@ -280,8 +367,19 @@ func (w *Storage) loadWAL(r *wal.Reader) (err error) {
 					}
 				}
 				decoded <- samples
-			case record.Tombstones, record.Exemplars:
-				// We don't care about decoding tombstones or exemplars
+			case record.Tombstones:
+				stones, err := dec.Tombstones(rec, nil)
+				if err != nil {
+					errCh <- &wal.CorruptionErr{
+						Err:     errors.Wrap(err, "decode tombstones"),
+						Segment: r.Segment(),
+						Offset:  r.Offset(),
+					}
+					return
+				}
+				decoded <- stones
+			case record.Exemplars:
+				// We don't care about decoding exemplars
 				continue
 			default:
 				errCh <- &wal.CorruptionErr{
@@ -320,6 +418,7 @@ func (w *Storage) loadWAL(r *wal.Reader) (err error) {
 			//nolint:staticcheck
 			seriesPool.Put(v)
 		case []record.RefSample:
+			w.replay.samplesReplayed.Add(int64(len(v)))
 			for _, s := range v {
 				// Update the lastTs for the series based
 				series := w.series.getByID(s.Ref)
@@ -337,6 +436,20 @@ func (w *Storage) loadWAL(r *wal.Reader) (err error) {
 
 			//nolint:staticcheck
 			samplesPool.Put(v)
+		case []tombstones.Stone:
+			// Tombstones are only written by DeleteSeries, which removes the
+			// series entirely.
+			deleted := make(map[uint64]struct{}, len(v))
+			for _, s := range v {
+				series := w.series.getByID(s.Ref)
+				if series == nil {
+					continue
+				}
+				w.series.remove(series)
+				deleted[s.Ref] = struct{}{}
+			}
+			w.metrics.numActiveSeries.Sub(float64(len(deleted)))
+			w.markDeleted(deleted)
 		default:
 			panic(fmt.Errorf("unexpected decoded type: %T", d))
 		}
@@ -373,6 +486,14 @@ func (*Storage) StartTime() (int64, error) {
 	return 0, nil
 }
 
+// ForEachSeries calls fn with the labels of every series in the WAL, including
+// series loaded when replaying an existing WAL.
+func (w *Storage) ForEachSeries(fn func(labels.Labels)) {
+	for series := range w.series.iterator().Channel() {
+		fn(series.lset)
+	}
+}
+
 // Truncate removes all data from the WAL prior to the timestamp specified by
 // mint.
 func (w *Storage) Truncate(mint int64) error {
@@ -457,11 +578,87 @@ func (w *Storage) Truncate(mint int64) error {
 	return nil
 }
 
+// DeleteSeries removes all series matching any of the selectors from the
+// WAL and returns the number of removed series. Removed series no longer get
+// staleness markers, and a tombstone is logged for them so they're not loaded
+// again when the WAL is replayed and are dropped from later checkpoints.
+// Samples already in the WAL may still be sent by remote_write.
+//
+// Series which receive new samples afterwards are created again.
+func (w *Storage) DeleteSeries(selectors ...[]*labels.Matcher) (int, error) {
+	w.walMtx.RLock()
+	defer w.walMtx.RUnlock()
+
+	if w.walClosed {
+		return 0, ErrWALClosed
+	}
+
+	var matched []*memSeries
+	for series := range w.series.iterator().Channel() {
+		for _, sel := range selectors {
+			if matchesAll(sel, series.lset) {
+				matched = append(matched, series)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return 0, nil
+	}
+
+	// The tombstones cover every sample written until now, so the checkpoint
+	// drops them once all of those samples are older than its mint.
+	var (
+		now     = timestamp.FromTime(time.Now())
+		stones  = make([]tombstones.Stone, 0, len(matched))
+		deleted = make(map[uint64]struct{}, len(matched))
+	)
+	for _, series := range matched {
+		stones = append(stones, tombstones.Stone{
+			Ref:       series.ref,
+			Intervals: tombstones.Intervals{{Mint: math.MinInt64, Maxt: now}},
+		})
+		deleted[series.ref] = struct{}{}
+	}
+
+	var encoder record.Encoder
+	buf := w.bufPool.Get().([]byte)
+	buf = encoder.Tombstones(stones, buf)
+	err := w.wal.Log(buf)
+	//nolint:staticcheck
+	w.bufPool.Put(buf[:0])
+	if err != nil {
+		return 0, errors.Wrap(err, "log tombstones")
+	}
+
+	for _, series := range matched {
+		w.series.remove(series)
+	}
+	w.metrics.numActiveSeries.Sub(float64(len(deleted)))
+	w.markDeleted(deleted)
+
+	return len(deleted), nil
+}
+
+// matchesAll returns true if lset matches all of the matchers.
+func matchesAll(matchers []*labels.Matcher, lset labels.Labels) bool {
+	for _, m := range matchers {
+		if !m.Matches(lset.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
 // gc removes data before the minimum timestamp from the head.
 func (w *Storage) gc(mint int64) {
 	deleted := w.series.gc(mint)
 	w.metrics.numActiveSeries.Sub(float64(len(deleted)))
+	w.markDeleted(deleted)
+}
 
+// markDeleted tracks series that were removed from the head.
+func (w *Storage) markDeleted(deleted map[uint64]struct{}) {
 	_, last, _ := wal.Segments(w.wal.Dir())
 	w.deletedMtx.Lock()
 	defer w.deletedMtx.Unlock()
@@ -646,6 +843,23 @@ func (a *appender) AppendExemplar(ref uint64, _ labels.Labels, e exemplar.Exempl
 		}
 	}
 
+	s.Lock()
+	defer s.Unlock()
+
+	// Exemplars are checked against the last committed exemplar here so out
+	// of order exemplars are reported to the caller. They're checked again in
+	// Commit, which also catches duplicates within the same batch.
+	if s.lastExemplar != nil {
+		switch {
+		case s.lastExemplar.Equals(e):
+			// Duplicate exemplars are dropped without an error, mirroring the
+			// behavior of the TSDB.
+			return s.ref, nil
+		case e.Ts < s.lastExemplar.Ts:
+			return 0, storage.ErrOutOfOrderExemplar
+		}
+	}
+
 	a.exemplars = append(a.exemplars, record.RefExemplar{
 		Ref:    ref,
 		T:      e.Ts,
@@ -656,6 +870,38 @@ func (a *appender) AppendExemplar(ref uint64, _ labels.Labels, e exemplar.Exempl
 	return s.ref, nil
 }
 
+// filterExemplars drops pending exemplars which duplicate or are older than
+// the last exemplar of their series, including exemplars earlier in the same
+// batch. It returns the newest remaining exemplar for each series.
+func (a *appender) filterExemplars() map[uint64]exemplar.Exemplar {
+	newest := make(map[uint64]exemplar.Exemplar)
+	kept := a.exemplars[:0]
+
+	for _, re := range a.exemplars {
+		e := exemplar.Exemplar{Labels: re.Labels, Value: re.V, Ts: re.T, HasTs: true}
+
+		last, ok := newest[re.Ref]
+		if !ok {
+			if s := a.w.series.getByID(re.Ref); s != nil {
+				s.Lock()
+				if s.lastExemplar != nil {
+					last, ok = *s.lastExemplar, true
+				}
+				s.Unlock()
+			}
+		}
+		if ok && (last.Equals(e) || e.Ts < last.Ts) {
+			continue
+		}
+
+		newest[re.Ref] = e
+		kept = append(kept, re)
+	}
+
+	a.exemplars = kept
+	return newest
+}
+
 // Commit submits the collected samples and purges the batch.
 func (a *appender) Commit() error {
 	a.w.walMtx.RLock()
@@ -684,6 +930,7 @@ func (a *appender) Commit() error {
 		buf = buf[:0]
 	}
 
+	newestExemplars := a.filterExemplars()
 	if len(a.exemplars) > 0 {
 		buf = encoder.Exemplars(a.exemplars, buf)
 		if err := a.w.wal.Log(buf); err != nil {
@@ -704,6 +951,17 @@ func (a *appender) Commit() error {
 		}
 	}
 
+	for ref, e := range newestExemplars {
+		series := a.w.series.getByID(ref)
+		if series != nil {
+			e := e
+			series.Lock()
+			series.lastExemplar = &e
+			series.Unlock()
+		}
+	}
+	a.w.metrics.totalAppendedExemplars.Add(float64(len(a.exemplars)))
+
 	return a.Rollback()
 }
 
@@ -11,11 +11,14 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/prometheus/pkg/exemplar"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/value"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/fileutil"
 	"github.com/prometheus/prometheus/tsdb/record"
 	"github.com/stretchr/testify/require"
 )
@@ -62,6 +65,111 @@ func TestStorage_InvalidSeries(t *testing.T) {
 	require.NoError(t, err, "should not reject valid exemplars")
 }
 
+func TestStorage_DuplicateExemplars(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+
+	app := s.Appender(context.Background())
+
+	sRef, err := app.Append(0, labels.Labels{{Name: "a", Value: "1"}}, 0, 0)
+	require.NoError(t, err)
+
+	e := exemplar.Exemplar{Labels: labels.Labels{{Name: "traceID", Value: "123"}}, Value: 20, Ts: 10, HasTs: true}
+	_, err = app.AppendExemplar(sRef, nil, e)
+	require.NoError(t, err)
+
+	// Duplicates within the same batch are dropped on commit.
+	_, err = app.AppendExemplar(sRef, nil, e)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	app = s.Appender(context.Background())
+	_, err = app.AppendExemplar(sRef, nil, e)
+	require.NoError(t, err, "duplicate exemplars should be dropped without an error")
+
+	e = exemplar.Exemplar{Labels: labels.Labels{{Name: "traceID", Value: "456"}}, Value: 30, Ts: 5, HasTs: true}
+	_, err = app.AppendExemplar(sRef, nil, e)
+	require.ErrorIs(t, err, storage.ErrOutOfOrderExemplar)
+
+	// A rolled back exemplar shouldn't be used to drop later exemplars.
+	rolledBack := exemplar.Exemplar{Labels: labels.Labels{{Name: "traceID", Value: "999"}}, Value: 50, Ts: 20, HasTs: true}
+	_, err = app.AppendExemplar(sRef, nil, rolledBack)
+	require.NoError(t, err)
+	require.NoError(t, app.Rollback())
+
+	app = s.Appender(context.Background())
+	e = exemplar.Exemplar{Labels: labels.Labels{{Name: "traceID", Value: "789"}}, Value: 40, Ts: 15, HasTs: true}
+	_, err = app.AppendExemplar(sRef, nil, e)
+	require.NoError(t, err)
+
+	require.NoError(t, app.Commit())
+
+	collector := walDataCollector{}
+	replayer := walReplayer{w: &collector}
+	require.NoError(t, replayer.Replay(s.wal.Dir()))
+
+	require.Len(t, collector.exemplars, 2, "only unique and in order exemplars should be written")
+	require.Equal(t, int64(10), collector.exemplars[0].T)
+	require.Equal(t, int64(15), collector.exemplars[1].T)
+}
+
+func TestStorage_OutOfOrderSamples(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+
+	lset := labels.Labels{{Name: "a", Value: "1"}}
+
+	// Out of order samples of any age are accepted.
+	app := s.Appender(context.Background())
+	ref, err := app.Append(0, lset, 100_000, 0)
+	require.NoError(t, err)
+	_, err = app.Append(ref, lset, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	// Delayed samples should not move the series timestamp backwards.
+	series := s.series.getByID(ref)
+	require.Equal(t, int64(100_000), series.lastTs)
+}
+
+func TestStorage_SizeMetric(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), prometheus.NewRegistry(), walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+
+	app := s.Appender(context.Background())
+	payload := buildSeries([]string{"foo", "bar", "baz"})
+	for _, metric := range payload {
+		metric.Write(t, app)
+	}
+	require.NoError(t, app.Commit())
+
+	expected, err := fileutil.DirSize(SubDirectory(walDir))
+	require.NoError(t, err)
+	require.NotZero(t, expected)
+	require.Equal(t, float64(expected), testutil.ToFloat64(s.metrics.storageSize))
+}
+
 func TestStorage(t *testing.T) {
 	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
 	require.NoError(t, err)
@@ -104,6 +212,45 @@ func TestStorage(t *testing.T) {
 	require.Equal(t, expectedExemplars, actualExemplars)
 }
 
+func TestStorage_ReplayProgress(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	progress := NewReplayProgress()
+	s, err := NewStorageWithProgress(log.NewNopLogger(), nil, walDir, progress)
+	require.NoError(t, err)
+
+	status := progress.Status()
+	require.True(t, status.Done)
+	require.Zero(t, status.SamplesReplayed)
+	require.Zero(t, status.SegmentsRemaining())
+
+	app := s.Appender(context.Background())
+	payload := buildSeries([]string{"foo", "bar", "baz"})
+	var expectSamples int64
+	for _, metric := range payload {
+		metric.Write(t, app)
+		expectSamples += int64(len(metric.samples))
+	}
+	require.NoError(t, app.Commit())
+	require.NoError(t, s.Close())
+
+	s, err = NewStorageWithProgress(log.NewNopLogger(), nil, walDir, progress)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+
+	status = progress.Status()
+	require.True(t, status.Done)
+	require.Equal(t, expectSamples, status.SamplesReplayed)
+	require.NotZero(t, status.SegmentsTotal)
+	require.Equal(t, status.SegmentsTotal, status.SegmentsReplayed)
+	require.False(t, status.StartTime.IsZero())
+	require.False(t, status.EndTime.Before(status.StartTime))
+}
+
 func TestStorage_ExistingWAL(t *testing.T) {
 	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
 	require.NoError(t, err)
@@ -140,6 +287,12 @@ func TestStorage_ExistingWAL(t *testing.T) {
 		require.Greater(t, series.lastTs, int64(0), "series timestamp not updated")
 	}
 
+	var replayed []string
+	s.ForEachSeries(func(lset labels.Labels) {
+		replayed = append(replayed, lset.Get("__name__"))
+	})
+	require.ElementsMatch(t, payload[0:len(payload)/2].SeriesNames(), replayed)
+
 	app = s.Appender(context.Background())
 
 	for _, metric := range payload[len(payload)/2:] {
@@ -319,6 +472,61 @@ func TestStorage_WriteStalenessMarkers(t *testing.T) {
 	}
 }
 
+func TestStorage_DeleteSeries(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+
+	app := s.Appender(context.Background())
+	payload := buildSeries([]string{"foo", "bar", "baz", "blerg"})
+	for _, metric := range payload {
+		metric.Write(t, app)
+	}
+	require.NoError(t, app.Commit())
+
+	deleted, err := s.DeleteSeries(
+		[]*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, "__name__", "ba.*")},
+		[]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "__name__", "foo")},
+		[]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "__name__", "unknown")},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 3, deleted)
+
+	seriesNames := func() []string {
+		var names []string
+		s.ForEachSeries(func(lset labels.Labels) {
+			names = append(names, lset.Get("__name__"))
+		})
+		return names
+	}
+	require.Equal(t, []string{"blerg"}, seriesNames())
+
+	// Deleting the same series again is a no-op.
+	deleted, err = s.DeleteSeries([]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "__name__", "foo")})
+	require.NoError(t, err)
+	require.Equal(t, 0, deleted)
+
+	// Deleted series are created again by new samples.
+	app = s.Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("__name__", "foo"), 100, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.ElementsMatch(t, []string{"foo", "blerg"}, seriesNames())
+
+	// Deleted series must not come back when replaying the WAL.
+	require.NoError(t, s.Close())
+	s, err = NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"foo", "blerg"}, seriesNames())
+
+	require.NoError(t, s.Close())
+	_, err = s.DeleteSeries([]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "__name__", "foo")})
+	require.Equal(t, ErrWALClosed, err)
+}
+
 func TestStorage_TruncateAfterClose(t *testing.T) {
 	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
 	require.NoError(t, err)
@@ -0,0 +1,226 @@
+package summary
+
+import (
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/version"
+)
+
+// Snapshot is the summary of the Agent at a point in time.
+type Snapshot struct {
+	Timestamp time.Time `json:"timestamp"`
+	// UptimeSeconds is how long the Agent has been running.
+	UptimeSeconds float64   `json:"uptime_seconds"`
+	Build         BuildInfo `json:"build"`
+	// Subsystems holds the running subsystems in the order prometheus, loki,
+	// tempo.
+	Subsystems []Subsystem `json:"subsystems"`
+}
+
+// BuildInfo describes the build of the Agent.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Subsystem is the throughput of a subsystem, counted in samples for
+// prometheus, log lines for loki, and spans for tempo. Rates are per second
+// and averaged over the interval the summary is calculated at.
+type Subsystem struct {
+	Name string `json:"name"`
+
+	// ReceivedPerSecond is the rate data is collected at. It's nil for loki,
+	// which doesn't count the log lines it reads.
+	ReceivedPerSecond *float64 `json:"received_per_second,omitempty"`
+	SentPerSecond     float64  `json:"sent_per_second"`
+	// FailedPerSecond is the rate data is dropped at because it couldn't be
+	// received or sent.
+	FailedPerSecond float64 `json:"failed_per_second"`
+	// ErrorRatio is the fraction of data that failed, between 0 and 1.
+	ErrorRatio float64 `json:"error_ratio"`
+
+	// Pending is the amount of data queued to be sent. It's nil for loki,
+	// which doesn't report the size of its queues.
+	Pending *float64 `json:"pending,omitempty"`
+}
+
+// source is the metrics the summary of a subsystem is calculated from.
+// Counters are summed over all of their series.
+type source struct {
+	received []string // Counters of data collected.
+	sent     []string // Counters of data sent.
+	failed   []string // Counters of data dropped.
+	pending  []string // Gauges of queued data.
+}
+
+// sources holds the metrics of every subsystem, in the order they're
+// reported.
+var sources = []struct {
+	subsystem string
+	source
+}{
+	{"prometheus", source{
+		received: []string{"agent_wal_samples_appended_total"},
+		sent:     []string{"prometheus_remote_storage_samples_total"},
+		failed:   []string{"prometheus_remote_storage_samples_failed_total", "prometheus_remote_storage_samples_dropped_total"},
+		pending:  []string{"prometheus_remote_storage_samples_pending"},
+	}},
+	{"loki", source{
+		sent:   []string{"promtail_sent_entries_total", "agent_logs_spool_sent_entries_total"},
+		failed: []string{"promtail_dropped_entries_total", "agent_logs_spool_dropped_entries_total"},
+	}},
+	{"tempo", source{
+		received: []string{"tempo_receiver_accepted_spans"},
+		sent:     []string{"tempo_exporter_sent_spans"},
+		failed:   []string{"tempo_receiver_refused_spans", "tempo_exporter_send_failed_spans"},
+		pending:  []string{"tempo_exporter_queue_size"},
+	}},
+}
+
+// calculator calculates rates from the counters of consecutive calculations.
+type calculator struct {
+	subsystems map[string]bool
+
+	prevTime     time.Time
+	prevCounters map[string]float64
+}
+
+func newCalculator(subsystems []string) *calculator {
+	c := &calculator{
+		subsystems:   make(map[string]bool, len(subsystems)),
+		prevCounters: make(map[string]float64),
+	}
+	for _, s := range subsystems {
+		c.subsystems[s] = true
+	}
+	return c
+}
+
+// calculate returns the summary of the running subsystems found in families.
+func (c *calculator) calculate(now time.Time, families []*dto.MetricFamily) *Snapshot {
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, mf := range families {
+		byName[mf.GetName()] = mf
+	}
+
+	var (
+		counters = make(map[string]float64, len(c.prevCounters))
+		elapsed  float64
+	)
+	// There's nothing to compare against on the first calculation.
+	if !c.prevTime.IsZero() {
+		elapsed = now.Sub(c.prevTime).Seconds()
+	}
+
+	// rate returns the per-second increase of the sum of the named counters
+	// since the previous calculation. Series created since then, such as the
+	// first failure of a remote_write queue, count from 0.
+	rate := func(names []string) float64 {
+		var increase float64
+		for _, name := range names {
+			mf, ok := byName[name]
+			if !ok {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				key, value := metricKey(mf, m), metricValue(m)
+				counters[key] = value
+
+				if prev, ok := c.prevCounters[key]; ok && value >= prev {
+					increase += value - prev
+				} else {
+					// The series is new or the counter was reset.
+					increase += value
+				}
+			}
+		}
+		if elapsed <= 0 {
+			return 0
+		}
+		return increase / elapsed
+	}
+
+	// sum returns the sum of the named gauges.
+	sum := func(names []string) float64 {
+		var total float64
+		for _, name := range names {
+			for _, m := range byName[name].GetMetric() {
+				total += metricValue(m)
+			}
+		}
+		return total
+	}
+
+	snapshot := &Snapshot{
+		Timestamp:  now,
+		Build:      buildInfo(),
+		Subsystems: []Subsystem{},
+	}
+	for _, s := range sources {
+		if !c.subsystems[s.subsystem] {
+			continue
+		}
+
+		sub := Subsystem{
+			Name:            s.subsystem,
+			SentPerSecond:   rate(s.sent),
+			FailedPerSecond: rate(s.failed),
+		}
+		if s.received != nil {
+			received := rate(s.received)
+			sub.ReceivedPerSecond = &received
+		}
+		if s.pending != nil {
+			pending := sum(s.pending)
+			sub.Pending = &pending
+		}
+		if total := sub.SentPerSecond + sub.FailedPerSecond; total > 0 {
+			sub.ErrorRatio = sub.FailedPerSecond / total
+		}
+		snapshot.Subsystems = append(snapshot.Subsystems, sub)
+	}
+
+	c.prevTime, c.prevCounters = now, counters
+	return snapshot
+}
+
+func buildInfo() BuildInfo {
+	return BuildInfo{
+		Version:   version.Version,
+		Revision:  version.Revision,
+		Branch:    version.Branch,
+		BuildDate: version.BuildDate,
+		GoVersion: version.GoVersion,
+	}
+}
+
+// metricValue returns the value of a counter, gauge, or untyped metric.
+// Metrics exported from OpenCensus views aren't always counters.
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.GetCounter().GetValue()
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue()
+	default:
+		return m.GetUntyped().GetValue()
+	}
+}
+
+// metricKey uniquely identifies a series of a family.
+func metricKey(mf *dto.MetricFamily, m *dto.Metric) string {
+	var sb strings.Builder
+	sb.WriteString(mf.GetName())
+	for _, l := range m.GetLabel() {
+		sb.WriteString("\xff")
+		sb.WriteString(l.GetName())
+		sb.WriteString("=")
+		sb.WriteString(l.GetValue())
+	}
+	return sb.String()
+}
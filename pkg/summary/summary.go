@@ -0,0 +1,163 @@
+// Package summary reports a compact snapshot of the health of the Agent:
+// the throughput, error rate, and queue depth of each subsystem along with
+// build info. It allows a central controller to poll a fleet of Agents
+// cheaply instead of scraping all of their metrics.
+package summary
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/prom/cluster/configapi"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Config controls calculating the summary.
+type Config struct {
+	// Interval is how often the summary is calculated. 0 disables it.
+	Interval time.Duration
+}
+
+// RegisterFlags registers flags for the Config.
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.DurationVar(&c.Interval, "summary.interval", 15*time.Second, "How often to calculate the summary of the health of the Agent. 0 disables the summary.")
+}
+
+// Tracker periodically calculates the summary of the Agent from its metrics.
+type Tracker struct {
+	log      log.Logger
+	gatherer prometheus.Gatherer
+	start    time.Time
+
+	mut     sync.Mutex
+	cfg     Config
+	stop    chan struct{}
+	done    chan struct{}
+	stopped bool
+
+	calc     *calculator
+	snapshot *Snapshot
+}
+
+// New creates a new Tracker. The summary is calculated from the metrics of
+// gatherer for each of subsystems, which are the names of the running
+// subsystems: prometheus, loki, or tempo. The Tracker does nothing until
+// ApplyConfig is called.
+func New(l log.Logger, gatherer prometheus.Gatherer, subsystems []string) *Tracker {
+	return &Tracker{
+		log:      log.With(l, "component", "summary"),
+		gatherer: gatherer,
+		start:    time.Now(),
+		calc:     newCalculator(subsystems),
+	}
+}
+
+// ApplyConfig updates the Tracker.
+func (t *Tracker) ApplyConfig(cfg Config) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.stopped {
+		return errors.New("tracker stopped")
+	}
+	if t.cfg == cfg {
+		return nil
+	}
+	t.cfg = cfg
+
+	t.stopLoop()
+	if cfg.Interval > 0 {
+		t.stop, t.done = make(chan struct{}), make(chan struct{})
+		go t.run(cfg.Interval, t.stop, t.done)
+	}
+	return nil
+}
+
+// stopLoop stops calculating the summary. mut must be held.
+func (t *Tracker) stopLoop() {
+	if t.stop == nil {
+		return
+	}
+	close(t.stop)
+	<-t.done
+	t.stop, t.done = nil, nil
+}
+
+func (t *Tracker) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Update(time.Now()); err != nil {
+				level.Error(t.log).Log("msg", "failed to calculate summary", "err", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Update calculates the current summary. Rates are calculated from the
+// previous call to Update.
+func (t *Tracker) Update(now time.Time) error {
+	// Gather without holding the lock; gathering may take a while.
+	families, err := t.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.snapshot = t.calc.calculate(now, families)
+	t.snapshot.UptimeSeconds = now.Sub(t.start).Seconds()
+	return nil
+}
+
+// Snapshot returns the most recently calculated summary. Returns nil if the
+// summary hasn't been calculated yet.
+func (t *Tracker) Snapshot() *Snapshot {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.snapshot
+}
+
+// WireAPI adds API routes to the provided mux router.
+func (t *Tracker) WireAPI(r *mux.Router) {
+	r.HandleFunc("/agent/api/v1/summary", t.SnapshotHandler).Methods("GET")
+}
+
+// SnapshotHandler writes the most recently calculated summary.
+func (t *Tracker) SnapshotHandler(w http.ResponseWriter, _ *http.Request) {
+	t.mut.Lock()
+	enabled, snapshot := t.cfg.Interval > 0, t.snapshot
+	t.mut.Unlock()
+
+	switch {
+	case !enabled:
+		_ = configapi.WriteError(w, http.StatusNotFound, errors.New("summary is disabled"))
+	case snapshot == nil:
+		_ = configapi.WriteError(w, http.StatusServiceUnavailable, errors.New("summary hasn't been calculated yet"))
+	default:
+		_ = configapi.WriteResponse(w, http.StatusOK, snapshot)
+	}
+}
+
+// Stop stops the Tracker.
+func (t *Tracker) Stop() {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.stopLoop()
+	t.stopped = true
+}
@@ -0,0 +1,109 @@
+package summary
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	// Metrics normally registered by the subsystems.
+	src := prometheus.NewRegistry()
+	samplesAppended := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "agent_wal_samples_appended_total"}, []string{"instance_name"})
+	samplesSent := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "prometheus_remote_storage_samples_total"}, []string{"instance_name", "remote_name"})
+	samplesFailed := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "prometheus_remote_storage_samples_failed_total"}, []string{"instance_name", "remote_name"})
+	samplesPending := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "prometheus_remote_storage_samples_pending"}, []string{"instance_name", "remote_name"})
+	logLinesSent := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "promtail_sent_entries_total"}, []string{"host"})
+	spansAccepted := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "tempo_receiver_accepted_spans"}, []string{"receiver"})
+	src.MustRegister(samplesAppended, samplesSent, samplesFailed, samplesPending, logLinesSent, spansAccepted)
+
+	// Only running subsystems are reported.
+	tr := New(log.NewNopLogger(), src, []string{"prometheus", "loki"})
+	defer tr.Stop()
+
+	// Calculate the summary manually instead of on an interval.
+	tr.cfg.Interval = time.Hour
+
+	samplesAppended.WithLabelValues("a").Add(100)
+	samplesSent.WithLabelValues("a", "cloud").Add(100)
+	samplesSent.WithLabelValues("a", "local").Add(100)
+	logLinesSent.WithLabelValues("loki:3100").Add(100)
+	spansAccepted.WithLabelValues("otlp").Add(100)
+
+	start := time.Now()
+	require.NoError(t, tr.Update(start))
+
+	// Rates are 0 until there's a previous calculation to compare against.
+	zero := 0.0
+	require.Equal(t, []Subsystem{
+		{Name: "prometheus", ReceivedPerSecond: &zero, Pending: &zero},
+		{Name: "loki"},
+	}, tr.Snapshot().Subsystems)
+
+	samplesAppended.WithLabelValues("a").Add(200)
+	samplesSent.WithLabelValues("a", "cloud").Add(100)
+	samplesSent.WithLabelValues("a", "local").Add(50)
+	samplesFailed.WithLabelValues("a", "cloud").Add(50)
+	samplesPending.WithLabelValues("a", "cloud").Set(30)
+	samplesPending.WithLabelValues("a", "local").Set(20)
+	logLinesSent.WithLabelValues("loki:3100").Add(10)
+
+	require.NoError(t, tr.Update(start.Add(10*time.Second)))
+
+	received, pending := 20.0, 50.0
+	snapshot := tr.Snapshot()
+	require.Equal(t, []Subsystem{
+		{Name: "prometheus", ReceivedPerSecond: &received, SentPerSecond: 15, FailedPerSecond: 5, ErrorRatio: 0.25, Pending: &pending},
+		{Name: "loki", SentPerSecond: 1},
+	}, snapshot.Subsystems)
+	require.Equal(t, version.Version, snapshot.Build.Version)
+	require.Equal(t, version.GoVersion, snapshot.Build.GoVersion)
+
+	// A reset counter counts from 0.
+	samplesSent.Reset()
+	samplesSent.WithLabelValues("a", "cloud").Add(30)
+	require.NoError(t, tr.Update(start.Add(20*time.Second)))
+	require.Equal(t, 3.0, tr.Snapshot().Subsystems[0].SentPerSecond)
+
+	rw := httptest.NewRecorder()
+	tr.SnapshotHandler(rw, httptest.NewRequest(http.MethodGet, "/agent/api/v1/summary", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	var resp struct {
+		Status string   `json:"status"`
+		Data   Snapshot `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rw.Body).Decode(&resp))
+	require.Equal(t, "success", resp.Status)
+	require.Len(t, resp.Data.Subsystems, 2)
+	require.Nil(t, resp.Data.Subsystems[1].Pending)
+}
+
+func TestTracker_NotCalculated(t *testing.T) {
+	tr := New(log.NewNopLogger(), prometheus.NewRegistry(), nil)
+	defer tr.Stop()
+
+	require.NoError(t, tr.ApplyConfig(Config{Interval: time.Hour}))
+
+	rw := httptest.NewRecorder()
+	tr.SnapshotHandler(rw, httptest.NewRequest(http.MethodGet, "/agent/api/v1/summary", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rw.Code)
+}
+
+func TestTracker_Disabled(t *testing.T) {
+	tr := New(log.NewNopLogger(), prometheus.NewRegistry(), nil)
+	defer tr.Stop()
+
+	require.NoError(t, tr.ApplyConfig(Config{}))
+
+	rw := httptest.NewRecorder()
+	tr.SnapshotHandler(rw, httptest.NewRequest(http.MethodGet, "/agent/api/v1/summary", nil))
+	require.Equal(t, http.StatusNotFound, rw.Code)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	util "github.com/cortexproject/cortex/pkg/util/log"
@@ -37,12 +39,17 @@ const (
 	typeSpan    = "span"
 	typeRoot    = "root"
 	typeProcess = "process"
+
+	// kindKey is the key holding the kind of log line in the data passed to
+	// the format template.
+	kindKey = "kind"
 )
 
 type automaticLoggingProcessor struct {
 	nextConsumer consumer.Traces
 
 	cfg          *AutomaticLoggingConfig
+	format       *template.Template
 	logToStdout  bool
 	lokiInstance *loki.Instance
 	done         atomic.Bool
@@ -85,9 +92,20 @@ func newTraceProcessor(nextConsumer consumer.Traces, cfg *AutomaticLoggingConfig
 	cfg.Overrides.DurationKey = override(cfg.Overrides.DurationKey, defaultDurationKey)
 	cfg.Overrides.TraceIDKey = override(cfg.Overrides.TraceIDKey, defaultTraceIDKey)
 
+	var format *template.Template
+	if cfg.Format != "" {
+		var err error
+		// Missing keys render as an empty string rather than "<no value>".
+		format, err = template.New("format").Option("missingkey=zero").Parse(cfg.Format)
+		if err != nil {
+			return nil, fmt.Errorf("automaticLoggingProcessor failed to parse format: %w", err)
+		}
+	}
+
 	return &automaticLoggingProcessor{
 		nextConsumer: nextConsumer,
 		cfg:          cfg,
+		format:       format,
 		logToStdout:  logToStdout,
 		logger:       logger,
 		done:         atomic.Bool{},
@@ -209,16 +227,20 @@ func (p *automaticLoggingProcessor) exportToLoki(kind string, traceID string, ke
 		return
 	}
 
+	line, err := p.formatLine(kind, traceID, keyvals)
 	keyvals = append(keyvals, []interface{}{p.cfg.Overrides.TraceIDKey, traceID}...)
-	line, err := logfmt.MarshalKeyvals(keyvals...)
 	if err != nil {
-		level.Warn(p.logger).Log("msg", "unable to marshal keyvals", "err", err)
+		level.Warn(p.logger).Log("msg", "unable to format log line", "err", err)
 		return
 	}
 
 	// if we're logging to stdout, log and bail
 	if p.logToStdout {
-		level.Info(p.logger).Log(keyvals...)
+		if p.format != nil {
+			level.Info(p.logger).Log("msg", line)
+		} else {
+			level.Info(p.logger).Log(keyvals...)
+		}
 		return
 	}
 
@@ -228,7 +250,7 @@ func (p *automaticLoggingProcessor) exportToLoki(kind string, traceID string, ke
 		},
 		Entry: logproto.Entry{
 			Timestamp: time.Now(),
-			Line:      string(line),
+			Line:      line,
 		},
 	}, p.cfg.Timeout)
 
@@ -237,6 +259,37 @@ func (p *automaticLoggingProcessor) exportToLoki(kind string, traceID string, ke
 	}
 }
 
+// formatLine renders keyvals and the trace ID as a log line. Lines are written
+// in logfmt unless a format template is configured, in which case the
+// template is executed with a map of keyvals, the trace ID, and the kind of the
+// line.
+func (p *automaticLoggingProcessor) formatLine(kind string, traceID string, keyvals []interface{}) (string, error) {
+	if p.format == nil {
+		line, err := logfmt.MarshalKeyvals(append(keyvals[:len(keyvals):len(keyvals)], p.cfg.Overrides.TraceIDKey, traceID)...)
+		return string(line), err
+	}
+
+	// keyvals starts with the keys set by the processor, followed by
+	// attributes. Only the first value of a key is kept so attributes can't
+	// replace the processor's keys, and the trace ID and kind are set last for
+	// the same reason.
+	data := make(map[string]string, len(keyvals)/2+2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		if _, ok := data[key]; !ok {
+			data[key] = fmt.Sprint(keyvals[i+1])
+		}
+	}
+	data[p.cfg.Overrides.TraceIDKey] = traceID
+	data[kindKey] = kind
+
+	var sb strings.Builder
+	if err := p.format.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
 func spanDuration(span pdata.Span) string {
 	dur := int64(span.EndTimestamp() - span.StartTimestamp())
 	return strconv.FormatInt(dur, 10) + "ns"
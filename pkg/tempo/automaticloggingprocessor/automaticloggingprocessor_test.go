@@ -186,6 +186,13 @@ func TestBadConfigs(t *testing.T) {
 				Backend: "stdout",
 			},
 		},
+		{
+			cfg: &AutomaticLoggingConfig{
+				Backend: "stdout",
+				Spans:   true,
+				Format:  "{{ .svc ",
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -215,6 +222,53 @@ func TestLogToStdoutSet(t *testing.T) {
 	require.False(t, p.(*automaticLoggingProcessor).logToStdout)
 }
 
+func TestFormatLine(t *testing.T) {
+	keyvals := []interface{}{
+		"svc", "frontend",
+		"span", "GET /",
+		"dur", "10ns",
+		"http.method", "GET",
+		// Attributes must not replace the keys set by the processor.
+		"kind", "attribute",
+		"span", "attribute",
+		"tid", "attribute",
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			expected: "svc=frontend span=\"GET /\" dur=10ns http.method=GET kind=attribute span=attribute tid=attribute tid=0102",
+		},
+		{
+			format:   `{{ .kind }} {{ .svc }}/{{ .span }} took {{ .dur }} traceID={{ .tid }}`,
+			expected: "span frontend/GET / took 10ns traceID=0102",
+		},
+		{
+			format:   `method={{ index . "http.method" }}{{ with .missing }} missing={{ . }}{{ end }}`,
+			expected: "method=GET",
+		},
+		{
+			format:   `missing="{{ .missing }}"`,
+			expected: `missing=""`,
+		},
+	}
+
+	for _, tc := range tests {
+		cfg := &AutomaticLoggingConfig{
+			Spans:  true,
+			Format: tc.format,
+		}
+		p, err := newTraceProcessor(&automaticLoggingProcessor{}, cfg)
+		require.NoError(t, err)
+
+		actual, err := p.(*automaticLoggingProcessor).formatLine(typeSpan, "0102", keyvals)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, actual)
+	}
+}
+
 func TestDefaults(t *testing.T) {
 	cfg := &AutomaticLoggingConfig{
 		Spans: true,
@@ -31,6 +31,9 @@ type AutomaticLoggingConfig struct {
 	ProcessAttributes []string       `mapstructure:"process_attributes" yaml:"process_attributes"`
 	Overrides         OverrideConfig `mapstructure:"overrides" yaml:"overrides"`
 	Timeout           time.Duration  `mapstructure:"timeout" yaml:"timeout"`
+	// Format is an optional Go template used to render log lines. When empty,
+	// log lines are written in logfmt.
+	Format string `mapstructure:"format" yaml:"format,omitempty"`
 }
 
 // OverrideConfig contains overrides for various strings
@@ -0,0 +1,51 @@
+// Package backpressureprocessor implements a processor which reports spans
+// that couldn't be accepted by the rest of the pipeline, such as when the
+// sending_queue of an exporter is full, as a retryable error to receivers.
+package backpressureprocessor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenterror"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type backpressureProcessor struct {
+	nextConsumer consumer.Traces
+}
+
+func newTraceProcessor(nextConsumer consumer.Traces) (component.TracesProcessor, error) {
+	if nextConsumer == nil {
+		return nil, componenterror.ErrNilNextConsumer
+	}
+	return &backpressureProcessor{nextConsumer: nextConsumer}, nil
+}
+
+// ConsumeTraces passes td to the next consumer. Errors are converted into a
+// RESOURCE_EXHAUSTED gRPC status so that receivers ask clients to retry
+// instead of failing with an unknown error.
+func (p *backpressureProcessor) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	err := p.nextConsumer.ConsumeTraces(ctx, td)
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		// The error already carries a status which receivers will use.
+		return err
+	}
+	return status.Error(codes.ResourceExhausted, err.Error())
+}
+
+func (p *backpressureProcessor) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+// Start is invoked during service startup.
+func (p *backpressureProcessor) Start(context.Context, component.Host) error { return nil }
+
+// Shutdown is invoked during service shutdown.
+func (p *backpressureProcessor) Shutdown(context.Context) error { return nil }
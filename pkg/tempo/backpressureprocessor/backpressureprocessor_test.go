@@ -0,0 +1,54 @@
+package backpressureprocessor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConsumeTraces(t *testing.T) {
+	tt := []struct {
+		name         string
+		nextErr      error
+		expectedCode codes.Code
+	}{
+		{
+			name:         "accepted",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "queue full",
+			nextErr:      errors.New("sending_queue is full"),
+			expectedCode: codes.ResourceExhausted,
+		},
+		{
+			name:         "existing status",
+			nextErr:      status.Error(codes.Unavailable, "backend unavailable"),
+			expectedCode: codes.Unavailable,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			next := consumertest.NewNop()
+			if tc.nextErr != nil {
+				next = consumertest.NewErr(tc.nextErr)
+			}
+
+			p, err := newTraceProcessor(next)
+			require.NoError(t, err)
+
+			err = p.ConsumeTraces(context.Background(), pdata.NewTraces())
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.nextErr != nil {
+				require.Contains(t, err.Error(), tc.nextErr.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package backpressureprocessor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+// TypeStr is the unique identifier for the Backpressure processor.
+const TypeStr = "backpressure"
+
+// Config holds the configuration for the Backpressure processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+}
+
+// NewFactory returns a new factory for the Backpressure processor.
+func NewFactory() component.ProcessorFactory {
+	return processorhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		processorhelper.WithTraces(createTraceProcessor),
+	)
+}
+
+func createDefaultConfig() config.Processor {
+	return &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(TypeStr, TypeStr)),
+	}
+}
+
+func createTraceProcessor(
+	_ context.Context,
+	_ component.ProcessorCreateSettings,
+	_ config.Processor,
+	nextConsumer consumer.Traces,
+) (component.TracesProcessor, error) {
+	return newTraceProcessor(nextConsumer)
+}
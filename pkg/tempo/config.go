@@ -7,27 +7,33 @@ import (
 	"io/ioutil"
 	"net"
 	"sort"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/grafana/agent/pkg/loki"
 	"github.com/grafana/agent/pkg/tempo/automaticloggingprocessor"
+	"github.com/grafana/agent/pkg/tempo/backpressureprocessor"
+	"github.com/grafana/agent/pkg/tempo/groupbytraceprocessor"
+	"github.com/grafana/agent/pkg/tempo/internal/otelcol"
+	"github.com/grafana/agent/pkg/tempo/loadbalancingexporter"
+	"github.com/grafana/agent/pkg/tempo/localroutingprocessor"
 	"github.com/grafana/agent/pkg/tempo/noopreceiver"
 	"github.com/grafana/agent/pkg/tempo/promsdprocessor"
 	"github.com/grafana/agent/pkg/tempo/remotewriteexporter"
-	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/loadbalancingexporter"
-	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/spanmetricsprocessor"
+	"github.com/grafana/agent/pkg/tempo/spanmetricsprocessor"
+	"github.com/grafana/agent/pkg/tempo/usageprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
 	"github.com/prometheus/client_golang/prometheus"
 	prom_config "github.com/prometheus/common/config"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
-	"go.opentelemetry.io/collector/config/configloader"
-	"go.opentelemetry.io/collector/config/configparser"
 	"go.opentelemetry.io/collector/exporter/otlpexporter"
 	"go.opentelemetry.io/collector/exporter/otlphttpexporter"
 	"go.opentelemetry.io/collector/exporter/prometheusexporter"
 	"go.opentelemetry.io/collector/processor/attributesprocessor"
 	"go.opentelemetry.io/collector/processor/batchprocessor"
+	"go.opentelemetry.io/collector/processor/resourceprocessor"
 	"go.opentelemetry.io/collector/receiver/jaegerreceiver"
 	"go.opentelemetry.io/collector/receiver/kafkareceiver"
 	"go.opentelemetry.io/collector/receiver/opencensusreceiver"
@@ -43,6 +49,9 @@ const (
 
 	// defaultLoadBalancingPort is the default port the agent uses for internal load balancing
 	defaultLoadBalancingPort = "4318"
+	// loadBalancingReceiverName is the name of the receiver of load balanced
+	// spans
+	loadBalancingReceiverName = "otlp/lb"
 	// agent's load balancing options
 	dnsTagName    = "dns"
 	staticTagName = "static"
@@ -57,6 +66,10 @@ const (
 // Config controls the configuration of Tempo trace pipelines.
 type Config struct {
 	Configs []InstanceConfig `yaml:"configs,omitempty"`
+
+	// GlobalAttributes are resource attributes added to every span received
+	// by any instance, such as the cluster or region the Agent runs in.
+	GlobalAttributes map[string]string `yaml:"global_attributes,omitempty"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -65,21 +78,32 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return unmarshal((*plain)(c))
 }
 
-// Validate ensures that the Config is valid.
+// Validate ensures that the Config is valid. Errors are prefixed with the path
+// of the offending field, e.g., tempo.configs[0].spanmetrics.
 func (c *Config) Validate(lokiConfig *loki.Config) error {
+	for key := range c.GlobalAttributes {
+		if key == "" {
+			return errors.New("tempo.global_attributes: keys must not be empty")
+		}
+	}
+
 	names := make(map[string]struct{}, len(c.Configs))
 	for idx, c := range c.Configs {
 		if c.Name == "" {
-			return fmt.Errorf("tempo config at index %d is missing a name", idx)
+			return fmt.Errorf("tempo.configs[%d].name: must not be empty", idx)
 		}
 		if _, exist := names[c.Name]; exist {
-			return fmt.Errorf("found multiple tempo configs with name %s", c.Name)
+			return fmt.Errorf("tempo.configs[%d].name: found multiple tempo configs with name %s", idx, c.Name)
 		}
 		names[c.Name] = struct{}{}
+
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("tempo.configs[%d].%w", idx, err)
+		}
 	}
 
 	// check to make sure that any referenced Loki configs exist.
-	for _, inst := range c.Configs {
+	for idx, inst := range c.Configs {
 		if inst.AutomaticLogging != nil {
 			if inst.AutomaticLogging.Backend != automaticloggingprocessor.BackendLoki { // we can ignore if we're not logging to loki
 				continue
@@ -96,7 +120,7 @@ func (c *Config) Validate(lokiConfig *loki.Config) error {
 			}
 
 			if !found {
-				return fmt.Errorf("specified loki config %s not found", lokiName)
+				return fmt.Errorf("tempo.configs[%d].automatic_logging.loki_name: specified loki config %s not found", idx, lokiName)
 			}
 		}
 	}
@@ -104,6 +128,21 @@ func (c *Config) Validate(lokiConfig *loki.Config) error {
 	return nil
 }
 
+// DryRun validates c and builds the OTel collector config of every instance
+// without starting any pipelines.
+func (c *Config) DryRun(lokiConfig *loki.Config) error {
+	if err := c.Validate(lokiConfig); err != nil {
+		return err
+	}
+	for idx, inst := range c.Configs {
+		inst.globalAttributes = c.GlobalAttributes
+		if _, err := inst.otelConfig(); err != nil {
+			return fmt.Errorf("tempo.configs[%d]: %w", idx, err)
+		}
+	}
+	return nil
+}
+
 // InstanceConfig configures an individual Tempo trace pipeline.
 type InstanceConfig struct {
 	Name string `yaml:"name"`
@@ -133,7 +172,92 @@ type InstanceConfig struct {
 	AutomaticLogging *automaticloggingprocessor.AutomaticLoggingConfig `yaml:"automatic_logging,omitempty"`
 
 	// TailSampling defines a sampling strategy for the pipeline
-	TailSampling *tailSamplingConfig `yaml:"tail_sampling"`
+	TailSampling *tailSamplingConfig `yaml:"tail_sampling,omitempty"`
+
+	// GroupByTrace holds spans until their trace is complete before passing
+	// them to the rest of the pipeline.
+	GroupByTrace *groupByTraceConfig `yaml:"group_by_trace,omitempty"`
+
+	// Backpressure makes receivers return a retryable error to clients when
+	// spans can't be accepted by an exporter, such as when its sending_queue
+	// is full, instead of dropping them.
+	Backpressure bool `yaml:"backpressure,omitempty"`
+
+	// globalAttributes are the GlobalAttributes of the Config the instance
+	// belongs to.
+	globalAttributes map[string]string
+}
+
+// Validate checks that c doesn't contain conflicting settings. Errors are
+// prefixed with the path of the offending field, relative to c. Validate
+// doesn't check for settings that are only rejected once the OTel config is
+// built, such as a missing receiver.
+func (c *InstanceConfig) Validate() error {
+	if len(c.RemoteWrite) != 0 && len(c.PushConfig.Endpoint) != 0 {
+		return errors.New("remote_write: must not configure push_config and remote_write. push_config is deprecated in favor of remote_write")
+	}
+	if c.Batch != nil && c.PushConfig.Batch != nil {
+		return errors.New("batch: must not configure push_config.batch and batch. push_config.batch is deprecated in favor of batch")
+	}
+
+	for i, rw := range c.RemoteWrite {
+		if rw.Protocol != protocolGRPC && rw.Protocol != protocolHTTP {
+			return fmt.Errorf("remote_write[%d].protocol: unsupported protocol '%s', expected 'grpc' or 'http'", i, rw.Protocol)
+		}
+		for name := range rw.HeadersFiles {
+			if _, ok := rw.Headers[name]; ok {
+				return fmt.Errorf("remote_write[%d].headers_files: header %s must not also be set in headers", i, name)
+			}
+		}
+	}
+
+	if c.Backpressure {
+		// Both processors consume spans asynchronously, so errors from
+		// exporters never reach the receivers.
+		if c.Batch != nil || c.PushConfig.Batch != nil {
+			return errors.New("backpressure: must not configure batch, which hides exporter errors from receivers")
+		}
+		if c.TailSampling != nil {
+			return errors.New("backpressure: must not configure tail_sampling, which hides exporter errors from receivers")
+		}
+		if c.GroupByTrace != nil {
+			return errors.New("backpressure: must not configure group_by_trace, which hides exporter errors from receivers")
+		}
+	}
+
+	if c.AutomaticLogging != nil && c.AutomaticLogging.Format != "" {
+		if _, err := template.New("format").Parse(c.AutomaticLogging.Format); err != nil {
+			return fmt.Errorf("automatic_logging.format: %w", err)
+		}
+	}
+
+	if c.SpanMetrics != nil {
+		if err := c.SpanMetrics.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.GroupByTrace != nil {
+		if c.GroupByTrace.WaitDuration < 0 {
+			return errors.New("group_by_trace.wait_duration: must not be negative")
+		}
+		if c.GroupByTrace.NumTraces < 0 {
+			return errors.New("group_by_trace.num_traces: must not be negative")
+		}
+	}
+
+	if c.TailSampling != nil {
+		if _, err := formatPolicies(c.TailSampling.Policies); err != nil {
+			return fmt.Errorf("tail_sampling.policies: %w", err)
+		}
+		if c.TailSampling.LoadBalancing != nil {
+			if _, err := resolver(c.TailSampling.LoadBalancing.Resolver); err != nil {
+				return fmt.Errorf("tail_sampling.load_balancing.resolver: %w", err)
+			}
+		}
+	}
+
+	return nil
 }
 
 const (
@@ -192,8 +316,11 @@ type RemoteWriteConfig struct {
 	TLSConfig          *prom_config.TLSConfig `yaml:"tls_config,omitempty"`
 	BasicAuth          *prom_config.BasicAuth `yaml:"basic_auth,omitempty"`
 	Headers            map[string]string      `yaml:"headers,omitempty"`
-	SendingQueue       map[string]interface{} `yaml:"sending_queue,omitempty"`    // https://github.com/open-telemetry/opentelemetry-collector/blob/7d7ae2eb34b5d387627875c498d7f43619f37ee3/exporter/exporterhelper/queued_retry.go#L30
-	RetryOnFailure     map[string]interface{} `yaml:"retry_on_failure,omitempty"` // https://github.com/open-telemetry/opentelemetry-collector/blob/7d7ae2eb34b5d387627875c498d7f43619f37ee3/exporter/exporterhelper/queued_retry.go#L54
+	// HeadersFiles are headers whose values are read from files. The files are
+	// checked for changes periodically.
+	HeadersFiles   map[string]string      `yaml:"headers_files,omitempty"`
+	SendingQueue   map[string]interface{} `yaml:"sending_queue,omitempty"`    // https://github.com/open-telemetry/opentelemetry-collector/blob/7d7ae2eb34b5d387627875c498d7f43619f37ee3/exporter/exporterhelper/queued_retry.go#L30
+	RetryOnFailure map[string]interface{} `yaml:"retry_on_failure,omitempty"` // https://github.com/open-telemetry/opentelemetry-collector/blob/7d7ae2eb34b5d387627875c498d7f43619f37ee3/exporter/exporterhelper/queued_retry.go#L54
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -223,6 +350,56 @@ type SpanMetricsConfig struct {
 	PromInstance string `yaml:"prom_instance"`
 	// HandlerEndpoint is the address where a prometheus exporter will be exposed
 	HandlerEndpoint string `yaml:"handler_endpoint"`
+	// MaxSeries is the maximum number of active series the remote_write exporter writes. Samples
+	// for new series are dropped once the limit is reached. Only used with PromInstance.
+	MaxSeries int `yaml:"max_series,omitempty"`
+	// SeriesIdleTimeout is how long a series may go without being written before it no longer
+	// counts towards MaxSeries. Only used with PromInstance.
+	SeriesIdleTimeout time.Duration `yaml:"series_idle_timeout,omitempty"`
+	// SpanKinds restricts the spans metrics are calculated from to the given kinds, such as
+	// SERVER. All spans are used when empty.
+	SpanKinds []string `yaml:"span_kinds,omitempty"`
+	// ExcludeDimensions removes default dimensions, such as span.kind, from the metrics.
+	ExcludeDimensions []string `yaml:"exclude_dimensions,omitempty"`
+}
+
+// validate checks the SpanMetricsConfig for conflicting settings.
+func (c *SpanMetricsConfig) validate() error {
+	switch {
+	case len(c.PromInstance) != 0 && len(c.HandlerEndpoint) != 0:
+		return errors.New("spanmetrics: must not configure both prom_instance and handler_endpoint")
+	case len(c.PromInstance) == 0 && len(c.HandlerEndpoint) == 0:
+		return errors.New("spanmetrics: must specify a prometheus instance or a metrics handler endpoint to export the metrics")
+	}
+
+	for i, kind := range c.SpanKinds {
+		if _, err := spanmetricsprocessor.ParseSpanKind(kind); err != nil {
+			return fmt.Errorf("spanmetrics.span_kinds[%d]: %w", i, err)
+		}
+	}
+	for i, dim := range c.ExcludeDimensions {
+		if err := spanmetricsprocessor.ValidateExcludedDimension(dim); err != nil {
+			return fmt.Errorf("spanmetrics.exclude_dimensions[%d]: %w", i, err)
+		}
+	}
+
+	if len(c.HandlerEndpoint) != 0 {
+		if c.MaxSeries != 0 {
+			return errors.New("spanmetrics.max_series: can only be used with prom_instance")
+		}
+		if c.SeriesIdleTimeout != 0 {
+			return errors.New("spanmetrics.series_idle_timeout: can only be used with prom_instance")
+		}
+		return nil
+	}
+
+	if c.MaxSeries < 0 {
+		return errors.New("spanmetrics.max_series: must not be negative")
+	}
+	if c.SeriesIdleTimeout < 0 {
+		return errors.New("spanmetrics.series_idle_timeout: must not be negative")
+	}
+	return nil
 }
 
 // tailSamplingConfig is the configuration for tail-based sampling
@@ -238,11 +415,45 @@ type tailSamplingConfig struct {
 	LoadBalancing *loadBalancingConfig `yaml:"load_balancing"`
 }
 
+// groupByTraceConfig is the configuration for grouping spans by trace
+type groupByTraceConfig struct {
+	// WaitDuration is how long to wait for spans of a trace after its first span was received
+	WaitDuration time.Duration `yaml:"wait_duration,omitempty"`
+	// NumTraces is the maximum number of traces held in memory
+	NumTraces int `yaml:"num_traces,omitempty"`
+}
+
 // loadBalancingConfig defines the configuration for load balancing spans between agent instances
 // loadBalancingConfig is an OTel exporter's config with extra resolver config
 type loadBalancingConfig struct {
 	Exporter exporterConfig         `yaml:"exporter"`
 	Resolver map[string]interface{} `yaml:"resolver"`
+	// Receiver configures the gRPC server of the receiver used to get load balanced spans
+	Receiver *grpcServerConfig `yaml:"receiver,omitempty"`
+}
+
+// grpcServerConfig defines the limits of a gRPC server created by the agent for a receiver.
+// The settings are the same as the ones in OTel's configgrpc.GRPCServerSettings.
+type grpcServerConfig struct {
+	MaxRecvMsgSizeMiB    uint64                 `yaml:"max_recv_msg_size_mib,omitempty"`
+	MaxConcurrentStreams uint32                 `yaml:"max_concurrent_streams,omitempty"`
+	Keepalive            map[string]interface{} `yaml:"keepalive,omitempty"` // https://github.com/open-telemetry/opentelemetry-collector/blob/v0.29.0/config/configgrpc/configgrpc.go#L104
+}
+
+// apply sets the gRPC server settings of c in a receiver's protocol config.
+func (c *grpcServerConfig) apply(protocol map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	if c.MaxRecvMsgSizeMiB != 0 {
+		protocol["max_recv_msg_size_mib"] = c.MaxRecvMsgSizeMiB
+	}
+	if c.MaxConcurrentStreams != 0 {
+		protocol["max_concurrent_streams"] = c.MaxConcurrentStreams
+	}
+	if c.Keepalive != nil {
+		protocol["keepalive"] = c.Keepalive
+	}
 }
 
 // exporterConfig defined the config for a otlp exporter for load balancing
@@ -259,9 +470,18 @@ func exporter(rwCfg RemoteWriteConfig) (map[string]interface{}, error) {
 		return nil, errors.New("must have a configured a backend endpoint")
 	}
 
-	headers := map[string]string{}
-	if rwCfg.Headers != nil {
-		headers = rwCfg.Headers
+	// Headers are copied so that the headers added below don't leak into
+	// rwCfg.
+	headers := make(map[string]string, len(rwCfg.Headers)+len(rwCfg.HeadersFiles))
+	for name, value := range rwCfg.Headers {
+		headers[name] = value
+	}
+	for name, path := range rwCfg.HeadersFiles {
+		value, err := readHeaderFile(path)
+		if err != nil {
+			return nil, err
+		}
+		headers[name] = value
 	}
 
 	if rwCfg.BasicAuth != nil {
@@ -321,6 +541,32 @@ func exporter(rwCfg RemoteWriteConfig) (map[string]interface{}, error) {
 	return otlpExporter, nil
 }
 
+// readHeaderFile reads the value of a header from a file. Trailing newlines
+// are removed.
+func readHeaderFile(path string) (string, error) {
+	buff, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to load headers file %s: %w", path, err)
+	}
+	return strings.TrimRight(string(buff), "\r\n"), nil
+}
+
+// readHeadersFiles returns the contents of every headers file of the
+// remote_write blocks of c, keyed by path.
+func (c *InstanceConfig) readHeadersFiles() (map[string]string, error) {
+	contents := make(map[string]string)
+	for _, rw := range c.RemoteWrite {
+		for _, path := range rw.HeadersFiles {
+			value, err := readHeaderFile(path)
+			if err != nil {
+				return nil, err
+			}
+			contents[path] = value
+		}
+	}
+	return contents, nil
+}
+
 // exporters builds one or multiple exporters from a remote_write block.
 // It also supports building an exporter from push_config.
 func (c *InstanceConfig) exporters() (map[string]interface{}, error) {
@@ -429,18 +675,42 @@ func formatPolicies(cfg []map[string]interface{}) ([]map[string]interface{}, err
 }
 
 func (c *InstanceConfig) otelConfig() (*config.Config, error) {
+	otelMapStructure, err := c.otelMapStructure()
+	if err != nil {
+		return nil, err
+	}
+
+	factories, err := tracingFactories()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create factories: %w", err)
+	}
+
+	otelCfg, err := otelcol.Load(otelMapStructure, factories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OTel config: %w", err)
+	}
+
+	return otelCfg, nil
+}
+
+// otelMapStructure builds the OTel collector config of c, before it's loaded
+// by the collector.
+func (c *InstanceConfig) otelMapStructure() (map[string]interface{}, error) {
 	otelMapStructure := map[string]interface{}{}
 
 	if len(c.Receivers) == 0 {
 		return nil, errors.New("must have at least one configured receiver")
 	}
 
-	if len(c.RemoteWrite) != 0 && len(c.PushConfig.Endpoint) != 0 {
-		return nil, errors.New("must not configure push_config and remote_write. push_config is deprecated in favor of remote_write")
+	// Receivers are copied so that the receivers added for internal pipelines
+	// don't leak into c.
+	receivers := make(map[string]interface{}, len(c.Receivers))
+	for name, receiver := range c.Receivers {
+		receivers[name] = receiver
 	}
 
-	if c.Batch != nil && c.PushConfig.Batch != nil {
-		return nil, errors.New("must not configure push_config.batch and batch. push_config.batch is deprecated in favor of batch")
+	if err := c.Validate(); err != nil {
+		return nil, err
 	}
 
 	exporters, err := c.exporters()
@@ -455,6 +725,11 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 	// processors
 	processors := map[string]interface{}{}
 	processorNames := []string{}
+	if c.Backpressure {
+		processorNames = append(processorNames, backpressureprocessor.TypeStr)
+		processors[backpressureprocessor.TypeStr] = nil
+	}
+
 	if c.ScrapeConfigs != nil {
 		processorNames = append(processorNames, promsdprocessor.TypeStr)
 		processors[promsdprocessor.TypeStr] = map[string]interface{}{
@@ -469,6 +744,13 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 		}
 	}
 
+	if len(c.globalAttributes) > 0 {
+		processors["resource"] = map[string]interface{}{
+			"attributes": globalAttributeActions(c.globalAttributes),
+		}
+		processorNames = append(processorNames, "resource")
+	}
+
 	if c.Attributes != nil {
 		processors["attributes"] = c.Attributes
 		processorNames = append(processorNames, "attributes")
@@ -482,6 +764,18 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 		processorNames = append(processorNames, "batch")
 	}
 
+	if c.GroupByTrace != nil {
+		groupByTrace := map[string]interface{}{}
+		if c.GroupByTrace.WaitDuration != 0 {
+			groupByTrace["wait_duration"] = c.GroupByTrace.WaitDuration
+		}
+		if c.GroupByTrace.NumTraces != 0 {
+			groupByTrace["num_traces"] = c.GroupByTrace.NumTraces
+		}
+		processors[groupbytraceprocessor.TypeStr] = groupByTrace
+		processorNames = append(processorNames, groupbytraceprocessor.TypeStr)
+	}
+
 	pipelines := make(map[string]interface{})
 	if c.SpanMetrics != nil {
 		// Configure the metrics exporter.
@@ -493,11 +787,18 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 		var exporterName string
 		if len(c.SpanMetrics.PromInstance) != 0 && len(c.SpanMetrics.HandlerEndpoint) == 0 {
 			exporterName = remotewriteexporter.TypeStr
-			exporters[remotewriteexporter.TypeStr] = map[string]interface{}{
+			exporter := map[string]interface{}{
 				"namespace":     namespace,
 				"const_labels":  c.SpanMetrics.ConstLabels,
 				"prom_instance": c.SpanMetrics.PromInstance,
 			}
+			if c.SpanMetrics.MaxSeries != 0 {
+				exporter["max_series"] = c.SpanMetrics.MaxSeries
+			}
+			if c.SpanMetrics.SeriesIdleTimeout != 0 {
+				exporter["series_idle_timeout"] = c.SpanMetrics.SeriesIdleTimeout
+			}
+			exporters[remotewriteexporter.TypeStr] = exporter
 		} else if len(c.SpanMetrics.PromInstance) == 0 && len(c.SpanMetrics.HandlerEndpoint) != 0 {
 			exporterName = "prometheus"
 			exporters[exporterName] = map[string]interface{}{
@@ -514,6 +815,8 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 			"metrics_exporter":          exporterName,
 			"latency_histogram_buckets": c.SpanMetrics.LatencyHistogramBuckets,
 			"dimensions":                c.SpanMetrics.Dimensions,
+			"span_kinds":                c.SpanMetrics.SpanKinds,
+			"exclude_dimensions":        c.SpanMetrics.ExcludeDimensions,
 		}
 
 		pipelines[spanMetricsPipelineName] = map[string]interface{}{
@@ -524,7 +827,7 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 
 	// receivers
 	receiverNames := []string{}
-	for name := range c.Receivers {
+	for name := range receivers {
 		receiverNames = append(receiverNames, name)
 	}
 
@@ -558,11 +861,13 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 			if c.TailSampling.Port != "" {
 				receiverPort = c.TailSampling.Port
 			}
-			c.Receivers["otlp/lb"] = map[string]interface{}{
+			grpcServer := map[string]interface{}{
+				"endpoint": net.JoinHostPort("0.0.0.0", receiverPort),
+			}
+			c.TailSampling.LoadBalancing.Receiver.apply(grpcServer)
+			receivers[loadBalancingReceiverName] = map[string]interface{}{
 				"protocols": map[string]interface{}{
-					"grpc": map[string]interface{}{
-						"endpoint": net.JoinHostPort("0.0.0.0", receiverPort),
-					},
+					"grpc": grpcServer,
 				},
 			}
 		}
@@ -582,7 +887,7 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 		pipelines["traces/1"] = map[string]interface{}{
 			"exporters":  exportersNames,
 			"processors": orderedSplitProcessors[1],
-			"receivers":  []string{"otlp/lb"},
+			"receivers":  []string{loadBalancingReceiverName},
 		}
 	} else {
 		pipelines["traces"] = map[string]interface{}{
@@ -595,30 +900,40 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 	if c.SpanMetrics != nil {
 		// Insert a noop receiver in the metrics pipeline.
 		// Added to pass validation requiring at least one receiver in a pipeline.
-		c.Receivers[noopreceiver.TypeStr] = nil
+		receivers[noopreceiver.TypeStr] = nil
 	}
 
 	otelMapStructure["exporters"] = exporters
 	otelMapStructure["processors"] = processors
-	otelMapStructure["receivers"] = c.Receivers
+	otelMapStructure["receivers"] = receivers
 
 	// pipelines
 	otelMapStructure["service"] = map[string]interface{}{
 		"pipelines": pipelines,
 	}
 
-	factories, err := tracingFactories()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create factories: %w", err)
-	}
+	return otelMapStructure, nil
+}
 
-	parser := configparser.NewParserFromStringMap(otelMapStructure)
-	otelCfg, err := configloader.Load(parser, factories)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load OTel config: %w", err)
+// globalAttributeActions returns the resource processor actions setting
+// attributes, sorted by key. Attributes sent by clients with the same keys
+// are overwritten.
+func globalAttributeActions(attributes map[string]string) []map[string]interface{} {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
 	}
-
-	return otelCfg, nil
+	sort.Strings(keys)
+
+	actions := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		actions = append(actions, map[string]interface{}{
+			"key":    key,
+			"value":  attributes[key],
+			"action": "upsert",
+		})
+	}
+	return actions
 }
 
 // tracingFactories() only creates the needed factories.  if we decide to add support for a new
@@ -655,10 +970,15 @@ func tracingFactories() (component.Factories, error) {
 	processors, err := component.MakeProcessorFactoryMap(
 		batchprocessor.NewFactory(),
 		attributesprocessor.NewFactory(),
+		resourceprocessor.NewFactory(),
 		promsdprocessor.NewFactory(),
 		spanmetricsprocessor.NewFactory(),
 		automaticloggingprocessor.NewFactory(),
 		tailsamplingprocessor.NewFactory(),
+		backpressureprocessor.NewFactory(),
+		groupbytraceprocessor.NewFactory(),
+		usageprocessor.NewFactory(),
+		localroutingprocessor.NewFactory(),
 	)
 	if err != nil {
 		return component.Factories{}, err
@@ -677,11 +997,14 @@ func tracingFactories() (component.Factories, error) {
 // sets: before and after load balancing
 func orderProcessors(processors []string, splitPipelines bool) [][]string {
 	order := map[string]int{
-		"attributes":        0,
-		"spanmetrics":       1,
-		"tail_sampling":     2,
-		"automatic_logging": 3,
-		"batch":             4,
+		"backpressure":      -1,
+		"resource":          0,
+		"attributes":        1,
+		"spanmetrics":       2,
+		"groupbytrace":      3,
+		"tail_sampling":     4,
+		"automatic_logging": 5,
+		"batch":             6,
 	}
 
 	sort.Slice(processors, func(i, j int) bool {
@@ -702,6 +1025,7 @@ func orderProcessors(processors []string, splitPipelines bool) [][]string {
 	foundAt := len(processors)
 	for i, processor := range processors {
 		if processor == "batch" ||
+			processor == "groupbytrace" ||
 			processor == "tail_sampling" {
 			foundAt = i
 			break
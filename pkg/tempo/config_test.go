@@ -6,6 +6,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/grafana/agent/pkg/loki"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/config"
@@ -289,6 +290,42 @@ remote_write:
 `,
 			expectedError: true,
 		},
+		{
+			name: "remote_write headers_files",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+    headers:
+      x-scope-orgid: tenant
+    headers_files:
+      x-api-key: ` + tmpfile.Name() + `
+`,
+			expectedConfig: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    headers:
+      x-scope-orgid: tenant
+      x-api-key: password_in_file
+    retry_on_failure:
+      max_elapsed_time: 60s
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: []
+      receivers: ["jaeger"]
+`,
+		},
 		{
 			name: "push_config.batch and batch",
 			cfg: `
@@ -481,6 +518,51 @@ service:
     metrics/spanmetrics:
       exporters: ["remote_write"]
       receivers: ["noop"]
+`,
+		},
+		{
+			name: "span metrics span kinds and excluded dimensions",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+spanmetrics:
+  span_kinds: [SERVER, CONSUMER]
+  exclude_dimensions: [operation]
+  prom_instance: tempo
+`,
+			expectedConfig: `
+receivers:
+  noop:
+  jaeger:
+    protocols:
+      grpc:
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    retry_on_failure:
+      max_elapsed_time: 60s
+  remote_write:
+    namespace: tempo_spanmetrics
+    prom_instance: tempo
+processors:
+  spanmetrics:
+    metrics_exporter: remote_write
+    span_kinds: [SERVER, CONSUMER]
+    exclude_dimensions: [operation]
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: ["spanmetrics"]
+      receivers: ["jaeger"]
+    metrics/spanmetrics:
+      exporters: ["remote_write"]
+      receivers: ["noop"]
 `,
 		},
 		{
@@ -536,6 +618,66 @@ remote_write:
 spanmetrics:
   handler_endpoint: "0.0.0.0:8889"
   prom_instance: tempo
+`,
+			expectedError: true,
+		},
+		{
+			name: "span metrics remote write exporter with series limits",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+spanmetrics:
+  prom_instance: tempo
+  max_series: 100
+  series_idle_timeout: 10m
+`,
+			expectedConfig: `
+receivers:
+  noop:
+  jaeger:
+    protocols:
+      grpc:
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    retry_on_failure:
+      max_elapsed_time: 60s
+  remote_write:
+    namespace: tempo_spanmetrics
+    prom_instance: tempo
+    max_series: 100
+    series_idle_timeout: 10m
+processors:
+  spanmetrics:
+    metrics_exporter: remote_write
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: ["spanmetrics"]
+      receivers: ["jaeger"]
+    metrics/spanmetrics:
+      exporters: ["remote_write"]
+      receivers: ["noop"]
+`,
+		},
+		{
+			name: "span metrics prometheus exporter with series limits fails",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+spanmetrics:
+  handler_endpoint: "0.0.0.0:8889"
+  max_series: 100
 `,
 			expectedError: true,
 		},
@@ -663,6 +805,130 @@ service:
       exporters: ["otlp/0"]
       processors: ["tail_sampling"]
       receivers: ["otlp/lb"]
+`,
+		},
+		{
+			name: "tail sampling config with load balancing receiver limits",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+tail_sampling:
+  policies:
+    - always_sample:
+  load_balancing:
+    exporter:
+      insecure: true
+    resolver:
+      dns:
+        hostname: agent
+        port: 4318
+    receiver:
+      max_recv_msg_size_mib: 16
+      max_concurrent_streams: 100
+      keepalive:
+        enforcement_policy:
+          min_time: 10s
+          permit_without_stream: true
+`,
+			expectedConfig: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+  otlp/lb:
+    protocols:
+      grpc:
+        endpoint: "0.0.0.0:4318"
+        max_recv_msg_size_mib: 16
+        max_concurrent_streams: 100
+        keepalive:
+          enforcement_policy:
+            min_time: 10s
+            permit_without_stream: true
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    retry_on_failure:
+      max_elapsed_time: 60s
+  loadbalancing:
+    protocol:
+      otlp:
+        insecure: true
+        endpoint: noop
+        retry_on_failure:
+          max_elapsed_time: 60s
+    resolver:
+      dns:
+        hostname: agent
+        port: 4318
+processors:
+  tail_sampling:
+    decision_wait: 5s
+    policies:
+      - name: always_sample/0
+        type: always_sample
+service:
+  pipelines:
+    traces/0:
+      exporters: ["loadbalancing"]
+      processors: []
+      receivers: ["jaeger"]
+    traces/1:
+      exporters: ["otlp/0"]
+      processors: ["tail_sampling"]
+      receivers: ["otlp/lb"]
+`,
+		},
+		{
+			name: "grpc receiver limits",
+			cfg: `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        max_recv_msg_size_mib: 32
+        max_concurrent_streams: 50
+        keepalive:
+          enforcement_policy:
+            min_time: 5s
+  jaeger:
+    protocols:
+      grpc:
+        max_recv_msg_size_mib: 32
+remote_write:
+  - endpoint: example.com:12345
+`,
+			expectedConfig: `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        max_recv_msg_size_mib: 32
+        max_concurrent_streams: 50
+        keepalive:
+          enforcement_policy:
+            min_time: 5s
+  jaeger:
+    protocols:
+      grpc:
+        max_recv_msg_size_mib: 32
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    retry_on_failure:
+      max_elapsed_time: 60s
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: []
+      receivers: ["otlp", "jaeger"]
 `,
 		},
 		{
@@ -698,6 +964,100 @@ service:
       exporters: ["otlp"]
       processors: ["automatic_logging"]
       receivers: ["jaeger"]
+      `,
+		},
+		{
+			name: "group by trace before tail sampling",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+batch:
+  timeout: 5s
+group_by_trace:
+  wait_duration: 10s
+  num_traces: 1000
+tail_sampling:
+  policies:
+    - always_sample:
+`,
+			expectedConfig: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+processors:
+  batch:
+    timeout: 5s
+  groupbytrace:
+    wait_duration: 10s
+    num_traces: 1000
+  tail_sampling:
+    decision_wait: 5s
+    policies:
+      - name: always_sample/0
+        type: always_sample
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    retry_on_failure:
+      max_elapsed_time: 60s
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: ["groupbytrace", "tail_sampling", "batch"]
+      receivers: ["jaeger"]
+`,
+		},
+		{
+			name: "backpressure",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+    sending_queue:
+      queue_size: 100
+attributes:
+  actions:
+  - key: montgomery
+    value: forever
+    action: update
+backpressure: true
+`,
+			expectedConfig: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+processors:
+  backpressure:
+  attributes:
+    actions:
+    - key: montgomery
+      value: forever
+      action: update
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    sending_queue:
+      queue_size: 100
+    retry_on_failure:
+      max_elapsed_time: 60s
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: ["backpressure", "attributes"]
+      receivers: ["jaeger"]
       `,
 		},
 		{
@@ -812,6 +1172,198 @@ service:
 	}
 }
 
+func TestConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name          string
+		cfg           string
+		expectedError string
+	}{
+		{
+			name: "valid",
+			cfg: `
+configs:
+- name: default
+  receivers:
+    jaeger:
+  remote_write:
+  - endpoint: example.com:12345
+  spanmetrics:
+    prom_instance: tempo
+`,
+		},
+		{
+			name: "spanmetrics prometheus and remote write exporters",
+			cfg: `
+configs:
+- name: default
+  spanmetrics:
+    handler_endpoint: "0.0.0.0:8889"
+    prom_instance: tempo
+`,
+			expectedError: "tempo.configs[0].spanmetrics: must not configure both prom_instance and handler_endpoint",
+		},
+		{
+			name: "spanmetrics negative series_idle_timeout",
+			cfg: `
+configs:
+- name: default
+- name: other
+  spanmetrics:
+    prom_instance: tempo
+    series_idle_timeout: -1m
+`,
+			expectedError: "tempo.configs[1].spanmetrics.series_idle_timeout: must not be negative",
+		},
+		{
+			name: "spanmetrics unsupported span kind",
+			cfg: `
+configs:
+- name: default
+  spanmetrics:
+    prom_instance: tempo
+    span_kinds: [SERVER, REMOTE]
+`,
+			expectedError: `tempo.configs[0].spanmetrics.span_kinds[1]: unsupported span kind "REMOTE", must be one of INTERNAL, SERVER, CLIENT, PRODUCER, CONSUMER, or UNSPECIFIED`,
+		},
+		{
+			name: "spanmetrics unsupported excluded dimension",
+			cfg: `
+configs:
+- name: default
+  spanmetrics:
+    prom_instance: tempo
+    exclude_dimensions: [http.method]
+`,
+			expectedError: `tempo.configs[0].spanmetrics.exclude_dimensions[0]: unsupported dimension "http.method", must be one of service.name, operation, span.kind, status.code`,
+		},
+		{
+			name: "push_config and remote_write",
+			cfg: `
+configs:
+- name: default
+  push_config:
+    endpoint: example:12345
+  remote_write:
+  - endpoint: anotherexample.com:12345
+`,
+			expectedError: "tempo.configs[0].remote_write: must not configure push_config and remote_write. push_config is deprecated in favor of remote_write",
+		},
+		{
+			name: "header in headers and headers_files",
+			cfg: `
+configs:
+- name: default
+  remote_write:
+  - endpoint: example.com:12345
+    headers:
+      x-api-key: key
+    headers_files:
+      x-api-key: /run/secrets/key
+`,
+			expectedError: "tempo.configs[0].remote_write[0].headers_files: header x-api-key must not also be set in headers",
+		},
+		{
+			name: "unsupported remote_write protocol",
+			cfg: `
+configs:
+- name: default
+  remote_write:
+  - endpoint: example.com:12345
+    protocol: thrift
+`,
+			expectedError: "tempo.configs[0].remote_write[0].protocol: unsupported protocol 'thrift', expected 'grpc' or 'http'",
+		},
+		{
+			name: "unsupported tail sampling policy",
+			cfg: `
+configs:
+- name: default
+  tail_sampling:
+    policies:
+    - probabilistic:
+`,
+			expectedError: "tempo.configs[0].tail_sampling.policies: unsupported policy type probabilistic",
+		},
+		{
+			name: "backpressure with batch",
+			cfg: `
+configs:
+- name: default
+  backpressure: true
+  batch:
+    timeout: 5s
+`,
+			expectedError: "tempo.configs[0].backpressure: must not configure batch, which hides exporter errors from receivers",
+		},
+		{
+			name: "backpressure with tail sampling",
+			cfg: `
+configs:
+- name: default
+  backpressure: true
+  tail_sampling:
+    policies:
+    - always_sample:
+`,
+			expectedError: "tempo.configs[0].backpressure: must not configure tail_sampling, which hides exporter errors from receivers",
+		},
+		{
+			name: "negative group by trace num_traces",
+			cfg: `
+configs:
+- name: default
+  group_by_trace:
+    num_traces: -1
+`,
+			expectedError: "tempo.configs[0].group_by_trace.num_traces: must not be negative",
+		},
+		{
+			name: "empty global attribute key",
+			cfg: `
+global_attributes:
+  "": value
+configs:
+- name: default
+`,
+			expectedError: "tempo.global_attributes: keys must not be empty",
+		},
+		{
+			name: "missing name",
+			cfg: `
+configs:
+- name: default
+- receivers:
+    jaeger:
+`,
+			expectedError: "tempo.configs[1].name: must not be empty",
+		},
+		{
+			name: "duplicate name",
+			cfg: `
+configs:
+- name: default
+- name: default
+`,
+			expectedError: "tempo.configs[1].name: found multiple tempo configs with name default",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			err := yaml.Unmarshal([]byte(tc.cfg), &cfg)
+			require.NoError(t, err)
+
+			err = cfg.Validate(&loki.Config{})
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
 func TestProcessorOrder(t *testing.T) {
 	// tests!
 	tt := []struct {
@@ -960,6 +1512,63 @@ tail_sampling:
 	}
 }
 
+func TestConfig_GlobalAttributes(t *testing.T) {
+	cfg := `
+global_attributes:
+  region: eu-west-1
+  cluster: prod
+configs:
+- name: default
+  receivers:
+    jaeger:
+      protocols:
+        grpc:
+  remote_write:
+  - endpoint: example.com:12345
+  attributes:
+    actions:
+    - key: montgomery
+      value: forever
+      action: update
+  tail_sampling:
+    policies:
+    - always_sample:
+    load_balancing:
+      exporter:
+        insecure: true
+      resolver:
+        dns:
+          hostname: agent
+`
+	var c Config
+	require.NoError(t, yaml.Unmarshal([]byte(cfg), &c))
+	require.NoError(t, c.DryRun(&loki.Config{}))
+
+	inst := c.Configs[0]
+	inst.globalAttributes = c.GlobalAttributes
+	otelCfg, err := inst.otelConfig()
+	require.NoError(t, err)
+
+	// Attributes are added by the instance which first receives a span,
+	// before any other processor.
+	require.Equal(t, []config.ComponentID{
+		config.NewID("resource"),
+		config.NewID("attributes"),
+	}, otelCfg.Pipelines["traces/0"].Processors)
+	require.Equal(t, []config.ComponentID{
+		config.NewID("tail_sampling"),
+	}, otelCfg.Pipelines["traces/1"].Processors)
+
+	mapStructure, err := inst.otelMapStructure()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"attributes": []map[string]interface{}{
+			{"key": "cluster", "value": "prod", "action": "upsert"},
+			{"key": "region", "value": "eu-west-1", "action": "upsert"},
+		},
+	}, mapStructure["processors"].(map[string]interface{})["resource"])
+}
+
 func TestOrderProcessors(t *testing.T) {
 	tests := []struct {
 		processors     []string
@@ -1055,6 +1664,25 @@ func TestOrderProcessors(t *testing.T) {
 				},
 			},
 		},
+		{
+			processors: []string{
+				"batch",
+				"tail_sampling",
+				"groupbytrace",
+				"attributes",
+			},
+			splitPipelines: true,
+			expected: [][]string{
+				{
+					"attributes",
+				},
+				{
+					"groupbytrace",
+					"tail_sampling",
+					"batch",
+				},
+			},
+		},
 		{
 			processors: []string{
 				"spanmetrics",
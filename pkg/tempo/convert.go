@@ -0,0 +1,299 @@
+package tempo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	prom_config "github.com/prometheus/common/config"
+	"gopkg.in/yaml.v2"
+)
+
+// redactedHeader replaces the value of authorization headers in generated
+// OTel configs.
+const redactedHeader = "<secret>"
+
+// OTelConfigYAML returns the OpenTelemetry collector config generated for c.
+// The authorization header of exporters, which holds the basic_auth
+// credentials, is redacted.
+func (c *InstanceConfig) OTelConfigYAML() ([]byte, error) {
+	// Loading the config validates it the same way as when starting the
+	// pipelines.
+	if _, err := c.otelConfig(); err != nil {
+		return nil, err
+	}
+
+	otelMapStructure, err := c.otelMapStructure()
+	if err != nil {
+		return nil, err
+	}
+
+	exporters := otelMapStructure["exporters"].(map[string]interface{})
+	for _, exporter := range exporters {
+		exporter, ok := exporter.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		headers, ok := exporter["headers"].(map[string]string)
+		if !ok {
+			continue
+		}
+		redacted := make(map[string]string, len(headers))
+		for k, v := range headers {
+			if strings.EqualFold(k, "authorization") {
+				v = redactedHeader
+			}
+			redacted[k] = v
+		}
+		exporter["headers"] = redacted
+	}
+
+	return yaml.Marshal(formatDurations(otelMapStructure))
+}
+
+// formatDurations replaces durations in v with their string representation,
+// which is readable and understood by the OTel collector.
+func formatDurations(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Duration:
+		return v.String()
+	case []time.Duration:
+		res := make([]string, 0, len(v))
+		for _, d := range v {
+			res = append(res, d.String())
+		}
+		return res
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			res[k] = formatDurations(e)
+		}
+		return res
+	case []map[string]interface{}:
+		res := make([]map[string]interface{}, 0, len(v))
+		for _, e := range v {
+			res = append(res, formatDurations(e).(map[string]interface{}))
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			res = append(res, formatDurations(e))
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// otelCollectorConfig is the subset of an OTel collector config which can be
+// converted into an InstanceConfig.
+type otelCollectorConfig struct {
+	Receivers  map[string]interface{}            `yaml:"receivers"`
+	Processors map[string]map[string]interface{} `yaml:"processors"`
+	Exporters  map[string]map[string]interface{} `yaml:"exporters"`
+	Service    struct {
+		Pipelines map[string]struct {
+			Receivers  []string `yaml:"receivers"`
+			Processors []string `yaml:"processors"`
+			Exporters  []string `yaml:"exporters"`
+		} `yaml:"pipelines"`
+	} `yaml:"service"`
+}
+
+// InstanceConfigFromOTel converts the traces pipeline of an OTel collector
+// config into an InstanceConfig with the given name. The collector config
+// must have exactly one traces pipeline, whose processors and exporters are
+// supported by the Agent.
+//
+// The Agent runs processors in its own order, which may differ from the order
+// of the collector pipeline.
+func InstanceConfigFromOTel(name string, buf []byte) (*InstanceConfig, error) {
+	var otelCfg otelCollectorConfig
+	if err := yaml.Unmarshal(buf, &otelCfg); err != nil {
+		return nil, err
+	}
+
+	var pipelineNames []string
+	for pipelineName := range otelCfg.Service.Pipelines {
+		if pipelineName == "traces" || strings.HasPrefix(pipelineName, "traces/") {
+			pipelineNames = append(pipelineNames, pipelineName)
+		}
+	}
+	if len(pipelineNames) != 1 {
+		sort.Strings(pipelineNames)
+		return nil, fmt.Errorf("collector config must have exactly one traces pipeline, found %d %v", len(pipelineNames), pipelineNames)
+	}
+	pipeline := otelCfg.Service.Pipelines[pipelineNames[0]]
+
+	cfg := &InstanceConfig{
+		Name:      name,
+		Receivers: make(map[string]interface{}, len(pipeline.Receivers)),
+	}
+
+	for _, receiverName := range pipeline.Receivers {
+		receiver, ok := otelCfg.Receivers[receiverName]
+		if !ok {
+			return nil, fmt.Errorf("receiver %q is not defined", receiverName)
+		}
+		cfg.Receivers[receiverName] = receiver
+	}
+
+	for _, processorName := range pipeline.Processors {
+		processor, ok := otelCfg.Processors[processorName]
+		if !ok {
+			return nil, fmt.Errorf("processor %q is not defined", processorName)
+		}
+		if err := cfg.importProcessor(componentType(processorName), processor); err != nil {
+			return nil, fmt.Errorf("processor %q: %w", processorName, err)
+		}
+	}
+
+	for _, exporterName := range pipeline.Exporters {
+		exporter, ok := otelCfg.Exporters[exporterName]
+		if !ok {
+			return nil, fmt.Errorf("exporter %q is not defined", exporterName)
+		}
+		rw, err := importExporter(componentType(exporterName), exporter)
+		if err != nil {
+			return nil, fmt.Errorf("exporter %q: %w", exporterName, err)
+		}
+		cfg.RemoteWrite = append(cfg.RemoteWrite, rw)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// componentType returns the type of a component from its name, which is
+// either the type or type/name.
+func componentType(name string) string {
+	return strings.SplitN(name, "/", 2)[0]
+}
+
+// importProcessor sets the field of c matching a processor of the given type.
+func (c *InstanceConfig) importProcessor(typ string, processor map[string]interface{}) error {
+	switch typ {
+	case "batch":
+		if c.Batch != nil {
+			return errors.New("only one batch processor is supported")
+		}
+		c.Batch = processor
+		if c.Batch == nil {
+			c.Batch = map[string]interface{}{}
+		}
+	case "attributes":
+		if c.Attributes != nil {
+			return errors.New("only one attributes processor is supported")
+		}
+		c.Attributes = processor
+	case "groupbytrace":
+		if c.GroupByTrace != nil {
+			return errors.New("only one groupbytrace processor is supported")
+		}
+		c.GroupByTrace = &groupByTraceConfig{}
+		return remarshal(processor, c.GroupByTrace)
+	case "tail_sampling":
+		if c.TailSampling != nil {
+			return errors.New("only one tail_sampling processor is supported")
+		}
+		var ts struct {
+			DecisionWait time.Duration            `yaml:"decision_wait"`
+			Policies     []map[string]interface{} `yaml:"policies"`
+		}
+		if err := remarshal(processor, &ts); err != nil {
+			return err
+		}
+		c.TailSampling = &tailSamplingConfig{DecisionWait: ts.DecisionWait}
+		for _, policy := range ts.Policies {
+			typ, _ := policy["type"].(string)
+			if typ == "" {
+				return errors.New("sampling policy must set a type")
+			}
+			c.TailSampling.Policies = append(c.TailSampling.Policies, map[string]interface{}{
+				typ: policy[typ],
+			})
+		}
+	default:
+		return fmt.Errorf("unsupported processor type %s", typ)
+	}
+	return nil
+}
+
+// importExporter converts an otlp or otlphttp exporter into a
+// RemoteWriteConfig.
+func importExporter(typ string, exporter map[string]interface{}) (RemoteWriteConfig, error) {
+	rw := DefaultRemoteWriteConfig
+	switch typ {
+	case "otlp":
+		rw.Protocol = protocolGRPC
+	case "otlphttp":
+		rw.Protocol = protocolHTTP
+	default:
+		return rw, fmt.Errorf("unsupported exporter type %s, expected otlp or otlphttp", typ)
+	}
+
+	var otlp struct {
+		Endpoint           string                 `yaml:"endpoint"`
+		Compression        *string                `yaml:"compression"`
+		Headers            map[string]string      `yaml:"headers"`
+		Insecure           bool                   `yaml:"insecure"`
+		InsecureSkipVerify bool                   `yaml:"insecure_skip_verify"`
+		CAFile             string                 `yaml:"ca_file"`
+		CertFile           string                 `yaml:"cert_file"`
+		KeyFile            string                 `yaml:"key_file"`
+		SendingQueue       map[string]interface{} `yaml:"sending_queue"`
+		RetryOnFailure     map[string]interface{} `yaml:"retry_on_failure"`
+	}
+	if err := remarshalStrict(exporter, &otlp); err != nil {
+		return rw, err
+	}
+
+	rw.Endpoint = otlp.Endpoint
+	if otlp.Compression != nil {
+		switch *otlp.Compression {
+		case "", compressionNone:
+			rw.Compression = compressionNone
+		case compressionGzip:
+			rw.Compression = compressionGzip
+		default:
+			return rw, fmt.Errorf("unsupported compression '%s', expected 'gzip' or 'none'", *otlp.Compression)
+		}
+	}
+	rw.Headers = otlp.Headers
+	rw.Insecure = otlp.Insecure
+	if otlp.InsecureSkipVerify || otlp.CAFile != "" || otlp.CertFile != "" || otlp.KeyFile != "" {
+		rw.TLSConfig = &prom_config.TLSConfig{
+			CAFile:             otlp.CAFile,
+			CertFile:           otlp.CertFile,
+			KeyFile:            otlp.KeyFile,
+			InsecureSkipVerify: otlp.InsecureSkipVerify,
+		}
+	}
+	rw.SendingQueue = otlp.SendingQueue
+	rw.RetryOnFailure = otlp.RetryOnFailure
+	return rw, nil
+}
+
+// remarshal converts in into out through YAML.
+func remarshal(in interface{}, out interface{}) error {
+	bb, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(bb, out)
+}
+
+// remarshalStrict converts in into out through YAML, failing on fields of in
+// which don't exist in out.
+func remarshalStrict(in interface{}, out interface{}) error {
+	bb, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalStrict(bb, out)
+}
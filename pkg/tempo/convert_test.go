@@ -0,0 +1,290 @@
+package tempo
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/pkg/tempo/internal/otelcol"
+	"github.com/grafana/agent/pkg/util"
+	prom_config "github.com/prometheus/common/config"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of OTel configs")
+
+func TestInstanceConfig_OTelConfigYAML(t *testing.T) {
+	cfgText := util.Untab(`
+name: default
+receivers:
+	jaeger:
+		protocols:
+			grpc:
+remote_write:
+- endpoint: example.com:12345
+	basic_auth:
+		username: user
+		password: secret
+	headers:
+		x-scope-orgid: tenant
+group_by_trace:
+	wait_duration: 10s
+tail_sampling:
+	policies:
+	- always_sample:
+	`)
+
+	var cfg InstanceConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfgText), &cfg))
+
+	bb, err := cfg.OTelConfigYAML()
+	require.NoError(t, err)
+
+	expect := util.Untab(`
+exporters:
+	otlp/0:
+		compression: gzip
+		endpoint: example.com:12345
+		headers:
+			authorization: <secret>
+			x-scope-orgid: tenant
+		insecure: false
+		insecure_skip_verify: false
+		retry_on_failure:
+			max_elapsed_time: 60s
+		sending_queue: {}
+processors:
+	groupbytrace:
+		wait_duration: 10s
+	tail_sampling:
+		decision_wait: 5s
+		policies:
+		- name: always_sample/0
+			type: always_sample
+receivers:
+	jaeger:
+		protocols:
+			grpc: null
+service:
+	pipelines:
+		traces:
+			exporters: [otlp/0]
+			processors: [groupbytrace, tail_sampling]
+			receivers: [jaeger]
+	`)
+	require.YAMLEq(t, expect, string(bb))
+}
+
+// TestInstanceConfig_OTelConfigYAML_Golden compares the OTel configs
+// generated for the instance configs in testdata/otelconfig with their golden
+// files, and checks that the golden files are still loaded by the collector.
+// Run with -update to regenerate the golden files after an intended change.
+func TestInstanceConfig_OTelConfigYAML_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/otelconfig/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+
+	factories, err := tracingFactories()
+	require.NoError(t, err)
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			buf, err := ioutil.ReadFile(input)
+			require.NoError(t, err)
+
+			var cfg InstanceConfig
+			require.NoError(t, yaml.UnmarshalStrict(buf, &cfg))
+
+			actual, err := cfg.OTelConfigYAML()
+			require.NoError(t, err)
+
+			golden := strings.TrimSuffix(input, ".yaml") + ".golden"
+			if *updateGolden {
+				require.NoError(t, ioutil.WriteFile(golden, actual, 0644))
+			}
+			expect, err := ioutil.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(expect), string(actual))
+
+			var mapStructure map[string]interface{}
+			require.NoError(t, yamlv3.Unmarshal(expect, &mapStructure))
+			_, err = otelcol.Load(mapStructure, factories)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestInstanceConfigFromOTel(t *testing.T) {
+	otelText := util.Untab(`
+receivers:
+	otlp:
+		protocols:
+			grpc:
+	zipkin:
+processors:
+	batch:
+		timeout: 5s
+	groupbytrace/complete:
+		wait_duration: 10s
+		num_traces: 1000
+	tail_sampling:
+		decision_wait: 15s
+		policies:
+		- name: keep-all
+			type: always_sample
+		- name: errors
+			type: string_attribute
+			string_attribute:
+				key: error
+				values: ["true"]
+exporters:
+	otlp:
+		endpoint: tempo:4317
+		insecure: true
+		sending_queue:
+			queue_size: 100
+	otlphttp/cloud:
+		endpoint: https://tempo.example.com
+		compression: none
+		ca_file: ca.pem
+		headers:
+			authorization: Basic dXNlcjpzZWNyZXQ=
+service:
+	pipelines:
+		traces:
+			receivers: [otlp]
+			processors: [groupbytrace/complete, tail_sampling, batch]
+			exporters: [otlp, otlphttp/cloud]
+	`)
+
+	cfg, err := InstanceConfigFromOTel("imported", []byte(otelText))
+	require.NoError(t, err)
+
+	expect := &InstanceConfig{
+		Name: "imported",
+		Receivers: map[string]interface{}{
+			"otlp": map[interface{}]interface{}{
+				"protocols": map[interface{}]interface{}{"grpc": nil},
+			},
+		},
+		Batch:        map[string]interface{}{"timeout": "5s"},
+		GroupByTrace: &groupByTraceConfig{WaitDuration: 10 * time.Second, NumTraces: 1000},
+		TailSampling: &tailSamplingConfig{
+			DecisionWait: 15 * time.Second,
+			Policies: []map[string]interface{}{
+				{"always_sample": nil},
+				{"string_attribute": map[interface{}]interface{}{
+					"key":    "error",
+					"values": []interface{}{"true"},
+				}},
+			},
+		},
+		RemoteWrite: []RemoteWriteConfig{
+			{
+				Endpoint:     "tempo:4317",
+				Compression:  compressionGzip,
+				Protocol:     protocolGRPC,
+				Insecure:     true,
+				SendingQueue: map[string]interface{}{"queue_size": 100},
+			},
+			{
+				Endpoint:    "https://tempo.example.com",
+				Compression: compressionNone,
+				Protocol:    protocolHTTP,
+				TLSConfig:   &prom_config.TLSConfig{CAFile: "ca.pem"},
+				Headers:     map[string]string{"authorization": "Basic dXNlcjpzZWNyZXQ="},
+			},
+		},
+	}
+	require.Equal(t, expect, cfg)
+
+	// The converted config must be usable by the Agent.
+	_, err = cfg.otelConfig()
+	require.NoError(t, err)
+}
+
+func TestInstanceConfigFromOTel_Errors(t *testing.T) {
+	tt := []struct {
+		name          string
+		cfg           string
+		expectedError string
+	}{
+		{
+			name: "no traces pipeline",
+			cfg: `
+service:
+  pipelines:
+    metrics:
+      receivers: [otlp]
+`,
+			expectedError: "collector config must have exactly one traces pipeline, found 0 []",
+		},
+		{
+			name: "multiple traces pipelines",
+			cfg: `
+service:
+  pipelines:
+    traces/a:
+      receivers: [otlp]
+    traces/b:
+      receivers: [otlp]
+`,
+			expectedError: "collector config must have exactly one traces pipeline, found 2 [traces/a traces/b]",
+		},
+		{
+			name: "undefined receiver",
+			cfg: `
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+`,
+			expectedError: `receiver "otlp" is not defined`,
+		},
+		{
+			name: "unsupported processor",
+			cfg: `
+receivers:
+  otlp:
+processors:
+  memory_limiter:
+    limit_mib: 100
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      processors: [memory_limiter]
+`,
+			expectedError: `processor "memory_limiter": unsupported processor type memory_limiter`,
+		},
+		{
+			name: "unsupported exporter",
+			cfg: `
+receivers:
+  otlp:
+exporters:
+  jaeger:
+    endpoint: jaeger:14250
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      exporters: [jaeger]
+`,
+			expectedError: `exporter "jaeger": unsupported exporter type jaeger, expected otlp or otlphttp`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := InstanceConfigFromOTel("default", []byte(tc.cfg))
+			require.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package groupbytraceprocessor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+// TypeStr is the unique identifier for the Group By Trace processor.
+const TypeStr = "groupbytrace"
+
+const (
+	defaultWaitDuration = time.Second
+	defaultNumTraces    = 1_000_000
+)
+
+// Config holds the configuration for the Group By Trace processor. It
+// matches the config of the groupbytrace processor of
+// opentelemetry-collector-contrib.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// WaitDuration is how long to wait for spans of a trace after its first
+	// span was received.
+	WaitDuration time.Duration `mapstructure:"wait_duration"`
+
+	// NumTraces is the maximum number of traces held in memory. The oldest
+	// trace is released early when a new trace would exceed it.
+	NumTraces int `mapstructure:"num_traces"`
+}
+
+// NewFactory returns a new factory for the Group By Trace processor.
+func NewFactory() component.ProcessorFactory {
+	return processorhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		processorhelper.WithTraces(createTraceProcessor),
+	)
+}
+
+func createDefaultConfig() config.Processor {
+	return &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(TypeStr, TypeStr)),
+		WaitDuration:      defaultWaitDuration,
+		NumTraces:         defaultNumTraces,
+	}
+}
+
+func createTraceProcessor(
+	_ context.Context,
+	_ component.ProcessorCreateSettings,
+	cfg config.Processor,
+	nextConsumer consumer.Traces,
+) (component.TracesProcessor, error) {
+	oCfg := cfg.(*Config)
+
+	return newTraceProcessor(nextConsumer, oCfg)
+}
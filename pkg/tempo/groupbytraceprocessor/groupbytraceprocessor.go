@@ -0,0 +1,149 @@
+// Package groupbytraceprocessor implements a processor which holds spans
+// until all spans of their trace are expected to have been received, and then
+// sends the whole trace to the next consumer at once.
+package groupbytraceprocessor
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	util "github.com/cortexproject/cortex/pkg/util/log"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/batchpersignal"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenterror"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+type groupByTraceProcessor struct {
+	nextConsumer consumer.Traces
+	cfg          *Config
+	logger       log.Logger
+
+	mtx    sync.Mutex
+	traces map[pdata.TraceID]*trace
+	// order holds the ID of every trace in traces, oldest first.
+	order   *list.List
+	stopped bool
+}
+
+// trace holds the spans received for a trace.
+type trace struct {
+	id    pdata.TraceID
+	spans pdata.Traces
+	elem  *list.Element
+	timer *time.Timer
+}
+
+func newTraceProcessor(nextConsumer consumer.Traces, cfg *Config) (component.TracesProcessor, error) {
+	if nextConsumer == nil {
+		return nil, componenterror.ErrNilNextConsumer
+	}
+	if cfg.WaitDuration <= 0 {
+		return nil, errors.New("groupbytrace requires a positive wait_duration")
+	}
+	if cfg.NumTraces <= 0 {
+		return nil, errors.New("groupbytrace requires a positive num_traces")
+	}
+
+	return &groupByTraceProcessor{
+		nextConsumer: nextConsumer,
+		cfg:          cfg,
+		logger:       log.With(util.Logger, "component", "tempo groupbytrace"),
+		traces:       make(map[pdata.TraceID]*trace),
+		order:        list.New(),
+	}, nil
+}
+
+// ConsumeTraces groups the spans of td by trace. Traces are sent to the next
+// consumer once wait_duration has passed since their first span was received.
+func (p *groupByTraceProcessor) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	var evicted []*trace
+
+	p.mtx.Lock()
+	if p.stopped {
+		p.mtx.Unlock()
+		return p.nextConsumer.ConsumeTraces(ctx, td)
+	}
+
+	for _, batch := range batchpersignal.SplitTraces(td) {
+		id := batch.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).TraceID()
+
+		if t, ok := p.traces[id]; ok {
+			batch.ResourceSpans().MoveAndAppendTo(t.spans.ResourceSpans())
+			continue
+		}
+
+		if p.order.Len() >= p.cfg.NumTraces {
+			evicted = append(evicted, p.remove(p.order.Front().Value.(*trace)))
+		}
+
+		t := &trace{id: id, spans: batch}
+		t.elem = p.order.PushBack(t)
+		t.timer = time.AfterFunc(p.cfg.WaitDuration, func() { p.release(t) })
+		p.traces[id] = t
+	}
+	p.mtx.Unlock()
+
+	for _, t := range evicted {
+		p.send(t)
+	}
+	return nil
+}
+
+// remove stops tracking t. p.mtx must be held.
+func (p *groupByTraceProcessor) remove(t *trace) *trace {
+	t.timer.Stop()
+	p.order.Remove(t.elem)
+	delete(p.traces, t.id)
+	return t
+}
+
+// release sends t to the next consumer if it hasn't been sent yet.
+func (p *groupByTraceProcessor) release(t *trace) {
+	p.mtx.Lock()
+	if p.traces[t.id] != t {
+		// t was already evicted or flushed.
+		p.mtx.Unlock()
+		return
+	}
+	p.remove(t)
+	p.mtx.Unlock()
+
+	p.send(t)
+}
+
+func (p *groupByTraceProcessor) send(t *trace) {
+	if err := p.nextConsumer.ConsumeTraces(context.Background(), t.spans); err != nil {
+		level.Error(p.logger).Log("msg", "failed to send trace", "trace_id", t.id.HexString(), "err", err)
+	}
+}
+
+func (p *groupByTraceProcessor) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+// Start is invoked during service startup.
+func (p *groupByTraceProcessor) Start(context.Context, component.Host) error { return nil }
+
+// Shutdown is invoked during service shutdown. Traces which are still being
+// held are sent to the next consumer.
+func (p *groupByTraceProcessor) Shutdown(context.Context) error {
+	p.mtx.Lock()
+	p.stopped = true
+	var pending []*trace
+	for p.order.Len() > 0 {
+		pending = append(pending, p.remove(p.order.Front().Value.(*trace)))
+	}
+	p.mtx.Unlock()
+
+	for _, t := range pending {
+		p.send(t)
+	}
+	return nil
+}
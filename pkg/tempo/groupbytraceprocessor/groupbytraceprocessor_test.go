@@ -0,0 +1,68 @@
+package groupbytraceprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+func TestGroupByTrace(t *testing.T) {
+	sink := new(consumertest.TracesSink)
+	p, err := newTraceProcessor(sink, &Config{WaitDuration: 100 * time.Millisecond, NumTraces: 10})
+	require.NoError(t, err)
+
+	// Spans of the same trace are sent in separate requests, interleaved with
+	// another trace.
+	require.NoError(t, p.ConsumeTraces(context.Background(), testTraces(1, 2)))
+	require.NoError(t, p.ConsumeTraces(context.Background(), testTraces(1)))
+	require.Empty(t, sink.AllTraces(), "spans should be held until wait_duration passes")
+
+	require.Eventually(t, func() bool {
+		return len(sink.AllTraces()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	spansPerTrace := map[byte]int{}
+	for _, td := range sink.AllTraces() {
+		rss := td.ResourceSpans()
+		id := rss.At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).TraceID().Bytes()
+		spansPerTrace[id[0]] += td.SpanCount()
+	}
+	require.Equal(t, map[byte]int{1: 2, 2: 1}, spansPerTrace)
+}
+
+func TestGroupByTrace_NumTraces(t *testing.T) {
+	sink := new(consumertest.TracesSink)
+	p, err := newTraceProcessor(sink, &Config{WaitDuration: time.Hour, NumTraces: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, p.ConsumeTraces(context.Background(), testTraces(1, 2)))
+	require.Empty(t, sink.AllTraces())
+
+	// A third trace releases the oldest one.
+	require.NoError(t, p.ConsumeTraces(context.Background(), testTraces(3)))
+	require.Len(t, sink.AllTraces(), 1)
+	id := sink.AllTraces()[0].ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).TraceID().Bytes()
+	require.Equal(t, byte(1), id[0])
+
+	// Shutting down flushes the remaining traces.
+	require.NoError(t, p.Shutdown(context.Background()))
+	require.Len(t, sink.AllTraces(), 3)
+}
+
+// testTraces returns a pdata.Traces with one span for each of the given
+// trace IDs.
+func testTraces(ids ...byte) pdata.Traces {
+	td := pdata.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().InstrumentationLibrarySpans().AppendEmpty().Spans()
+	for _, id := range ids {
+		span := spans.AppendEmpty()
+		span.SetTraceID(pdata.NewTraceID([16]byte{id}))
+		span.SetSpanID(pdata.NewSpanID([8]byte{id}))
+		span.SetName("test")
+	}
+	return td
+}
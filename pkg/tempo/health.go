@@ -0,0 +1,35 @@
+package tempo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/agent/pkg/health"
+)
+
+// Health returns the status of every running instance. Instances are
+// unhealthy when any of their exporters failed to send spans without ever
+// sending one successfully.
+func (t *Tempo) Health() health.Subsystem {
+	status := t.status()
+
+	components := make([]health.Component, 0, len(status))
+	for _, inst := range status {
+		c := health.Component{Name: inst.InstanceName, Healthy: true, Ready: true}
+
+		var failing []string
+		for _, e := range inst.Exporters {
+			if e.SentSpans == 0 && e.FailedSpans+e.EnqueueFailedSpans > 0 {
+				failing = append(failing, e.Name)
+			}
+		}
+		if len(failing) > 0 {
+			c.Healthy = false
+			c.Message = fmt.Sprintf("exporters failed to send any spans: %s", strings.Join(failing, ", "))
+		}
+
+		components = append(components, c)
+	}
+
+	return health.NewSubsystem("tempo", components)
+}
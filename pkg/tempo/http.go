@@ -0,0 +1,208 @@
+package tempo
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/prom/cluster/configapi"
+	"go.opencensus.io/metric/metricproducer"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/collector/external/obsreportconfig/obsmetrics"
+	"go.uber.org/zap"
+)
+
+// queueSizeMetric is the name of the gauge reporting the size of the
+// sending queue of each exporter.
+const queueSizeMetric = obsmetrics.ExporterKey + "/queue_size"
+
+// WireAPI adds API routes to the provided mux router.
+func (t *Tempo) WireAPI(r *mux.Router) {
+	r.HandleFunc("/agent/api/v1/traces/status", t.StatusHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/traces/otel_config/{instance}", t.OTelConfigHandler).Methods("GET")
+}
+
+// OTelConfigHandler returns the OpenTelemetry collector config generated for
+// a running instance.
+func (t *Tempo) OTelConfigHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["instance"]
+
+	t.mut.Lock()
+	inst, ok := t.instances[name]
+	t.mut.Unlock()
+	if !ok {
+		t.writeError(w, http.StatusNotFound, fmt.Errorf("instance %s not found", name))
+		return
+	}
+
+	inst.mut.Lock()
+	cfg := inst.cfg
+	inst.mut.Unlock()
+
+	bb, err := cfg.OTelConfigYAML()
+	if err != nil {
+		t.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = configapi.WriteResponse(w, http.StatusOK, &OTelConfigResponse{Value: string(bb)})
+	if err != nil {
+		t.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// OTelConfigResponse is returned by the OTelConfigHandler.
+type OTelConfigResponse struct {
+	// Value is the stringified YAML config of the OTel collector.
+	Value string `json:"value"`
+}
+
+func (t *Tempo) writeError(w http.ResponseWriter, statusCode int, err error) {
+	if err := configapi.WriteError(w, statusCode, err); err != nil {
+		t.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// StatusHandler reports the receivers and exporters of each running instance
+// along with the number of spans that went through them.
+func (t *Tempo) StatusHandler(w http.ResponseWriter, _ *http.Request) {
+	err := configapi.WriteResponse(w, http.StatusOK, t.status())
+	if err != nil {
+		t.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// status returns the status of every running instance, sorted by name.
+func (t *Tempo) status() StatusResponse {
+	t.mut.Lock()
+	resp := make(StatusResponse, 0, len(t.instances))
+	for name, inst := range t.instances {
+		status := inst.status()
+		status.InstanceName = name
+		resp = append(resp, status)
+	}
+	t.mut.Unlock()
+
+	sort.Slice(resp, func(i, j int) bool { return resp[i].InstanceName < resp[j].InstanceName })
+
+	// Stats are collected by the OTel collector for each component name
+	// rather than for each instance.
+	var (
+		accepted      = viewSums(obsmetrics.ReceiverAcceptedSpans.Name(), obsmetrics.TagKeyReceiver.Name())
+		refused       = viewSums(obsmetrics.ReceiverRefusedSpans.Name(), obsmetrics.TagKeyReceiver.Name())
+		sent          = viewSums(obsmetrics.ExporterSentSpans.Name(), obsmetrics.TagKeyExporter.Name())
+		failed        = viewSums(obsmetrics.ExporterFailedToSendSpans.Name(), obsmetrics.TagKeyExporter.Name())
+		enqueueFailed = viewSums(obsmetrics.ExporterFailedToEnqueueSpans.Name(), obsmetrics.TagKeyExporter.Name())
+		queueSizes    = exporterQueueSizes()
+	)
+	for _, status := range resp {
+		for i, r := range status.Receivers {
+			status.Receivers[i].AcceptedSpans = accepted[r.Name]
+			status.Receivers[i].RefusedSpans = refused[r.Name]
+		}
+		for i, e := range status.Exporters {
+			status.Exporters[i].SentSpans = sent[e.Name]
+			status.Exporters[i].FailedSpans = failed[e.Name]
+			status.Exporters[i].EnqueueFailedSpans = enqueueFailed[e.Name]
+			status.Exporters[i].QueueSize = queueSizes[e.Name]
+		}
+	}
+	return resp
+}
+
+// StatusResponse is returned by the StatusHandler.
+type StatusResponse []InstanceStatus
+
+// InstanceStatus describes the components of a running instance.
+type InstanceStatus struct {
+	InstanceName string           `json:"instance"`
+	Receivers    []ReceiverStatus `json:"receivers"`
+	Exporters    []ExporterStatus `json:"exporters"`
+}
+
+// ReceiverStatus describes a receiver. Span counts are shared by every
+// instance with a receiver of the same name.
+type ReceiverStatus struct {
+	Name          string `json:"name"`
+	AcceptedSpans int64  `json:"accepted_spans"`
+	RefusedSpans  int64  `json:"refused_spans"`
+}
+
+// ExporterStatus describes an exporter. Span counts and the queue size are
+// shared by every instance with an exporter of the same name.
+type ExporterStatus struct {
+	Name               string `json:"name"`
+	SentSpans          int64  `json:"sent_spans"`
+	FailedSpans        int64  `json:"failed_spans"`
+	EnqueueFailedSpans int64  `json:"enqueue_failed_spans"`
+	QueueSize          int64  `json:"queue_size"`
+}
+
+// status returns the receivers and exporters of i, sorted by name.
+func (i *Instance) status() InstanceStatus {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	var status InstanceStatus
+	if i.service == nil {
+		return status
+	}
+	for _, name := range i.service.ReceiverNames() {
+		status.Receivers = append(status.Receivers, ReceiverStatus{Name: name})
+	}
+	for _, name := range i.service.ExporterNames() {
+		status.Exporters = append(status.Exporters, ExporterStatus{Name: name})
+	}
+
+	sort.Slice(status.Receivers, func(i, j int) bool { return status.Receivers[i].Name < status.Receivers[j].Name })
+	sort.Slice(status.Exporters, func(i, j int) bool { return status.Exporters[i].Name < status.Exporters[j].Name })
+	return status
+}
+
+// viewSums returns the sum of every row of the view with the given name,
+// grouped by the value of the tag named key.
+func viewSums(name string, key string) map[string]int64 {
+	res := make(map[string]int64)
+
+	rows, err := view.RetrieveData(name)
+	if err != nil {
+		// The view isn't registered when no instances are running.
+		return res
+	}
+	for _, row := range rows {
+		sum, ok := row.Data.(*view.SumData)
+		if !ok {
+			continue
+		}
+		for _, t := range row.Tags {
+			if t.Key.Name() == key {
+				res[t.Value] += int64(sum.Value)
+			}
+		}
+	}
+	return res
+}
+
+// exporterQueueSizes returns the size of the sending queue of every exporter
+// by name.
+func exporterQueueSizes() map[string]int64 {
+	res := make(map[string]int64)
+
+	for _, producer := range metricproducer.GlobalManager().GetAll() {
+		for _, m := range producer.Read() {
+			if m.Descriptor.Name != queueSizeMetric {
+				continue
+			}
+			for _, ts := range m.TimeSeries {
+				if len(ts.LabelValues) == 0 || len(ts.Points) == 0 {
+					continue
+				}
+				if v, ok := ts.Points[len(ts.Points)-1].Value.(int64); ok {
+					res[ts.LabelValues[0].Value] = v
+				}
+			}
+		}
+	}
+	return res
+}
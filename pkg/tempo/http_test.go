@@ -0,0 +1,128 @@
+package tempo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/tempo/internal/tempoutils"
+	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTempo_StatusHandler(t *testing.T) {
+	tracesAddr := tempoutils.NewTestServer(t, func(pdata.Traces) {})
+
+	tempoCfgText := util.Untab(fmt.Sprintf(`
+configs:
+- name: default
+  receivers:
+		jaeger:
+			protocols:
+				thrift_compact:
+	remote_write:
+	- endpoint: %s
+		insecure: true
+	batch:
+		timeout: 100ms
+		send_batch_size: 1
+	`, tracesAddr))
+
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(tempoCfgText), &cfg))
+
+	tempo, err := New(nil, nil, prometheus.NewRegistry(), cfg, logrus.InfoLevel)
+	require.NoError(t, err)
+	t.Cleanup(tempo.Stop)
+
+	tr := testJaegerTracer(t)
+	span := tr.StartSpan("test-span")
+	span.Finish()
+
+	getStatus := func() StatusResponse {
+		rec := httptest.NewRecorder()
+		tempo.StatusHandler(rec, httptest.NewRequest(http.MethodGet, "/agent/api/v1/traces/status", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp struct {
+			Status string         `json:"status"`
+			Data   StatusResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(strings.NewReader(rec.Body.String())).Decode(&resp))
+		require.Equal(t, "success", resp.Status)
+		return resp.Data
+	}
+
+	require.Eventually(t, func() bool {
+		status := getStatus()
+		require.Len(t, status, 1)
+		require.Equal(t, "default", status[0].InstanceName)
+		require.Equal(t, []string{"jaeger"}, receiverNames(status[0].Receivers))
+		require.Len(t, status[0].Exporters, 1)
+
+		return status[0].Receivers[0].AcceptedSpans >= 1 && status[0].Exporters[0].SentSpans >= 1
+	}, 30*time.Second, 100*time.Millisecond)
+}
+
+func TestTempo_OTelConfigHandler(t *testing.T) {
+	tempoCfgText := util.Untab(`
+configs:
+- name: default
+  receivers:
+		jaeger:
+			protocols:
+				thrift_compact:
+	remote_write:
+	- endpoint: example.com:12345
+		insecure: true
+	`)
+
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(tempoCfgText), &cfg))
+
+	tempo, err := New(nil, nil, prometheus.NewRegistry(), cfg, logrus.InfoLevel)
+	require.NoError(t, err)
+	t.Cleanup(tempo.Stop)
+
+	r := mux.NewRouter()
+	tempo.WireAPI(r)
+
+	get := func(instance string) (int, string) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/agent/api/v1/traces/otel_config/"+instance, nil))
+
+		var resp struct {
+			Data OTelConfigResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return rec.Code, resp.Data.Value
+	}
+
+	code, value := get("default")
+	require.Equal(t, http.StatusOK, code)
+
+	var otelCfg otelCollectorConfig
+	require.NoError(t, yaml.Unmarshal([]byte(value), &otelCfg))
+	require.Contains(t, otelCfg.Receivers, "jaeger")
+	require.Contains(t, otelCfg.Exporters, "otlp/0")
+
+	code, _ = get("missing")
+	require.Equal(t, http.StatusNotFound, code)
+}
+
+func receiverNames(receivers []ReceiverStatus) []string {
+	names := make([]string, 0, len(receivers))
+	for _, r := range receivers {
+		names = append(names, r.Name)
+	}
+	return names
+}
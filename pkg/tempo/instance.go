@@ -3,6 +3,7 @@ package tempo
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
@@ -10,15 +11,22 @@ import (
 	"github.com/grafana/agent/pkg/loki"
 	"github.com/grafana/agent/pkg/prom/instance"
 	"github.com/grafana/agent/pkg/tempo/contextkeys"
+	"github.com/grafana/agent/pkg/tempo/internal/otelcol"
+	"github.com/grafana/agent/pkg/tempo/loadbalancingexporter"
+	"github.com/grafana/agent/pkg/tempo/localroutingprocessor"
+	"github.com/grafana/agent/pkg/tempo/usageprocessor"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opencensus.io/stats/view"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
-	"go.opentelemetry.io/collector/service/external/builder"
 	"go.uber.org/zap"
 )
 
+// headersFilesCheckInterval is how often headers files are checked for
+// changes.
+var headersFilesCheckInterval = 30 * time.Second
+
 // Instance wraps the OpenTelemetry collector to enable tracing pipelines
 type Instance struct {
 	mut         sync.Mutex
@@ -26,9 +34,19 @@ type Instance struct {
 	logger      *zap.Logger
 	metricViews []*view.View
 
-	exporter  builder.Exporters
-	pipelines builder.BuiltPipelines
-	receivers builder.Receivers
+	loki                *loki.Loki
+	promInstanceManager instance.Manager
+
+	// headersFiles are the contents of the headers files the pipeline was
+	// built with, keyed by path.
+	headersFiles map[string]string
+	stop         chan struct{}
+	stopped      bool
+
+	reg           prometheus.Registerer
+	receivedSpans prometheus.Counter
+
+	service *otelcol.Service
 }
 
 // NewInstance creates and starts an instance of tracing pipelines.
@@ -42,9 +60,22 @@ func NewInstance(loki *loki.Loki, reg prometheus.Registerer, cfg InstanceConfig,
 		return nil, fmt.Errorf("failed to create metric views: %w", err)
 	}
 
+	instance.reg = reg
+	instance.receivedSpans = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_traces_received_spans_total",
+		Help: "Total number of spans received by the instance.",
+	})
+	if err := reg.Register(instance.receivedSpans); err != nil {
+		return nil, fmt.Errorf("failed to register metrics: %w", err)
+	}
+
 	if err := instance.ApplyConfig(loki, promInstanceManager, cfg); err != nil {
+		reg.Unregister(instance.receivedSpans)
 		return nil, err
 	}
+
+	instance.stop = make(chan struct{})
+	go instance.watchHeadersFiles(instance.stop)
 	return instance, nil
 }
 
@@ -53,17 +84,32 @@ func (i *Instance) ApplyConfig(loki *loki.Loki, promInstanceManager instance.Man
 	i.mut.Lock()
 	defer i.mut.Unlock()
 
-	if util.CompareYAML(cfg, i.cfg) {
+	// globalAttributes isn't marshaled, so it's compared separately.
+	if util.CompareYAML(cfg, i.cfg) && reflect.DeepEqual(cfg.globalAttributes, i.cfg.globalAttributes) {
 		// No config change
 		return nil
 	}
 	i.cfg = cfg
+	i.loki, i.promInstanceManager = loki, promInstanceManager
+
+	return i.rebuild()
+}
 
-	// Shut down any existing pipeline
-	i.stop()
+// rebuild shuts down the existing pipeline and builds a new one from i.cfg.
+// The mutex must be held when calling rebuild.
+func (i *Instance) rebuild() error {
+	i.shutdown()
 
-	createCtx := context.WithValue(context.Background(), contextkeys.Loki, loki)
-	err := i.buildAndStartPipeline(createCtx, cfg, promInstanceManager)
+	// Headers files are read before building the pipeline, so a change made
+	// while it's built is found by the next check.
+	headersFiles, err := i.cfg.readHeadersFiles()
+	if err != nil {
+		return fmt.Errorf("failed to create pipeline: %w", err)
+	}
+	i.headersFiles = headersFiles
+
+	createCtx := context.WithValue(context.Background(), contextkeys.Loki, i.loki)
+	err = i.buildAndStartPipeline(createCtx, i.cfg, i.promInstanceManager)
 	if err != nil {
 		return fmt.Errorf("failed to create pipeline: %w", err)
 	}
@@ -71,62 +117,72 @@ func (i *Instance) ApplyConfig(loki *loki.Loki, promInstanceManager instance.Man
 	return nil
 }
 
+// watchHeadersFiles rebuilds the pipeline whenever the contents of its
+// headers files change, until stop is closed.
+func (i *Instance) watchHeadersFiles(stop chan struct{}) {
+	ticker := time.NewTicker(headersFilesCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			i.checkHeadersFiles()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// checkHeadersFiles rebuilds the pipeline if the contents of its headers
+// files changed. The pipeline is left untouched when a file can't be read.
+func (i *Instance) checkHeadersFiles() {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.stopped || len(i.headersFiles) == 0 {
+		return
+	}
+
+	headersFiles, err := i.cfg.readHeadersFiles()
+	if err != nil {
+		i.logger.Error("failed to check headers files for changes", zap.Error(err))
+		return
+	}
+	if reflect.DeepEqual(headersFiles, i.headersFiles) {
+		return
+	}
+
+	i.logger.Info("headers files changed, rebuilding pipeline")
+	if err := i.rebuild(); err != nil {
+		i.logger.Error("failed to rebuild pipeline after headers files changed", zap.Error(err))
+	}
+}
+
 // Stop stops the OpenTelemetry collector subsystem
 func (i *Instance) Stop() {
 	i.mut.Lock()
 	defer i.mut.Unlock()
 
-	i.stop()
+	if i.stop != nil && !i.stopped {
+		close(i.stop)
+	}
+	i.stopped = true
+
+	i.shutdown()
 	view.Unregister(i.metricViews...)
+	i.reg.Unregister(i.receivedSpans)
 }
 
-func (i *Instance) stop() {
+func (i *Instance) shutdown() {
+	if i.service == nil {
+		return
+	}
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	dependencies := []struct {
-		name     string
-		shutdown func() error
-	}{
-		{
-			name: "receiver",
-			shutdown: func() error {
-				if i.receivers == nil {
-					return nil
-				}
-				return i.receivers.ShutdownAll(shutdownCtx)
-			},
-		},
-		{
-			name: "processors",
-			shutdown: func() error {
-				if i.pipelines == nil {
-					return nil
-				}
-				return i.pipelines.ShutdownProcessors(shutdownCtx)
-			},
-		},
-		{
-			name: "exporters",
-			shutdown: func() error {
-				if i.exporter == nil {
-					return nil
-				}
-				return i.exporter.ShutdownAll(shutdownCtx)
-			},
-		},
-	}
-
-	for _, dep := range dependencies {
-		i.logger.Info(fmt.Sprintf("shutting down %s", dep.name))
-		if err := dep.shutdown(); err != nil {
-			i.logger.Error(fmt.Sprintf("failed to shutdown %s", dep.name), zap.Error(err))
-		}
-	}
-
-	i.receivers = nil
-	i.pipelines = nil
-	i.exporter = nil
+	_ = i.service.Shutdown(shutdownCtx, i.logger)
+	i.service = nil
 }
 
 func (i *Instance) buildAndStartPipeline(ctx context.Context, cfg InstanceConfig, promManager instance.Manager) error {
@@ -135,6 +191,15 @@ func (i *Instance) buildAndStartPipeline(ctx context.Context, cfg InstanceConfig
 	if err != nil {
 		return fmt.Errorf("failed to load otelConfig from agent tempo config: %w", err)
 	}
+	addUsageProcessor(otelConfig, i.receivedSpans)
+	if cfg.TailSampling != nil && cfg.TailSampling.LoadBalancing != nil {
+		receiverPort := defaultLoadBalancingPort
+		if cfg.TailSampling.Port != "" {
+			receiverPort = cfg.TailSampling.Port
+		}
+		addLocalRouting(otelConfig, receiverPort)
+	}
+
 	if cfg.PushConfig.Endpoint != "" {
 		i.logger.Warn("Configuring exporter with deprecated push_config. Use remote_write and batch instead")
 	}
@@ -162,40 +227,9 @@ func (i *Instance) buildAndStartPipeline(ctx context.Context, cfg InstanceConfig
 		Version:     build.Version,
 	}
 
-	// start exporter
-	i.exporter, err = builder.BuildExporters(i.logger, appinfo, otelConfig, factories.Exporters)
-	if err != nil {
-		return fmt.Errorf("failed to create exporters builder: %w", err)
-	}
-
-	err = i.exporter.StartAll(ctx, i)
-	if err != nil {
-		return fmt.Errorf("failed to start exporters: %w", err)
-	}
-
-	// start pipelines
-	i.pipelines, err = builder.BuildPipelines(i.logger, appinfo, otelConfig, i.exporter, factories.Processors)
-	if err != nil {
-		return fmt.Errorf("failed to create pipelines builder: %w", err)
-	}
-
-	err = i.pipelines.StartProcessors(ctx, i)
-	if err != nil {
-		return fmt.Errorf("failed to start processors: %w", err)
-	}
-
-	// start receivers
-	i.receivers, err = builder.BuildReceivers(i.logger, appinfo, otelConfig, i.pipelines, factories.Receivers)
-	if err != nil {
-		return fmt.Errorf("failed to create receivers builder: %w", err)
-	}
-
-	err = i.receivers.StartAll(ctx, i)
-	if err != nil {
-		return fmt.Errorf("failed to start receivers: %w", err)
-	}
-
-	return nil
+	// start exporters, pipelines and receivers
+	i.service = &otelcol.Service{}
+	return i.service.Start(ctx, i.logger, appinfo, otelConfig, factories, i)
 }
 
 // ReportFatalError implements component.Host
@@ -216,5 +250,51 @@ func (i *Instance) GetExtensions() map[config.ComponentID]component.Extension {
 // GetExporters implements component.Host
 func (i *Instance) GetExporters() map[config.DataType]map[config.ComponentID]component.Exporter {
 	// SpanMetricsProcessor needs to get the configured exporters.
-	return i.exporter.ToMapByDataType()
+	return i.service.Exporters()
+}
+
+// addUsageProcessor adds a processor counting received spans to the start of
+// every traces pipeline that receives spans from outside of the instance.
+func addUsageProcessor(otelConfig *config.Config, receivedSpans prometheus.Counter) {
+	processor := &usageprocessor.Config{
+		ProcessorSettings: otelcol.NewProcessorSettings(usageprocessor.TypeStr),
+		ReceivedSpans:     receivedSpans,
+	}
+
+	// Spans received by the load balancing receiver were already counted by
+	// the instance which received them first.
+	otelcol.PrependProcessor(otelConfig, processor, func(receivers []string) bool {
+		return !hasLoadBalancingReceiver(receivers)
+	})
+}
+
+// addLocalRouting lets the load balancing exporter pass spans to the
+// processing pipeline in-process while the instance is the only load
+// balancing backend, instead of sending them to its own load balancing
+// receiver on receiverPort.
+func addLocalRouting(otelConfig *config.Config, receiverPort string) {
+	exporterCfg, ok := otelcol.Exporter(otelConfig, loadbalancingexporter.TypeStr).(*loadbalancingexporter.Config)
+	if !ok {
+		return
+	}
+	router := loadbalancingexporter.NewRouter()
+	exporterCfg.Router = router
+	exporterCfg.ReceiverPort = receiverPort
+
+	processor := &localroutingprocessor.Config{
+		ProcessorSettings: otelcol.NewProcessorSettings(localroutingprocessor.TypeStr),
+		Router:            router,
+	}
+	otelcol.PrependProcessor(otelConfig, processor, hasLoadBalancingReceiver)
+}
+
+// hasLoadBalancingReceiver returns true if receivers include the receiver of
+// load balanced spans.
+func hasLoadBalancingReceiver(receivers []string) bool {
+	for _, r := range receivers {
+		if r == loadBalancingReceiverName {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,191 @@
+// Package otelcol isolates pkg/tempo from the config and service packages of
+// the OpenTelemetry Collector, whose APIs change between collector releases.
+// Instance configs are translated into the collector's config map structure,
+// which is stable, and only this package loads it and runs the components it
+// describes. Upgrading the collector should only require changes here.
+package otelcol
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/configloader"
+	"go.opentelemetry.io/collector/config/configparser"
+	"go.opentelemetry.io/collector/service/external/builder"
+	"go.uber.org/zap"
+)
+
+// Load loads a collector config from its map structure, the same way the
+// collector loads its config file, and validates it.
+func Load(mapStructure map[string]interface{}, factories component.Factories) (*config.Config, error) {
+	parser := configparser.NewParserFromStringMap(mapStructure)
+	cfg, err := configloader.Load(parser, factories)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// NewProcessorSettings returns the settings of a processor of the given type
+// added to a config after it was loaded.
+func NewProcessorSettings(typ string) config.ProcessorSettings {
+	return config.NewProcessorSettings(config.NewID(config.Type(typ)))
+}
+
+// Exporter returns the loaded config of the exporter with the given name,
+// such as "otlp/0", or nil if cfg has no such exporter.
+func Exporter(cfg *config.Config, name string) config.Exporter {
+	id, err := config.NewIDFromString(name)
+	if err != nil {
+		return nil
+	}
+	return cfg.Exporters[id]
+}
+
+// PrependProcessor adds processor to cfg and to the start of every traces
+// pipeline for which match returns true. match is called with the names of
+// the receivers of the pipeline.
+func PrependProcessor(cfg *config.Config, processor config.Processor, match func(receivers []string) bool) {
+	id := processor.ID()
+	cfg.Processors[id] = processor
+
+	for _, pipeline := range cfg.Pipelines {
+		if pipeline.InputType != config.TracesDataType {
+			continue
+		}
+		receivers := make([]string, 0, len(pipeline.Receivers))
+		for _, r := range pipeline.Receivers {
+			receivers = append(receivers, r.String())
+		}
+		if match(receivers) {
+			pipeline.Processors = append([]config.ComponentID{id}, pipeline.Processors...)
+		}
+	}
+}
+
+// Service runs the exporters, pipelines and receivers of a loaded config.
+// The zero value is ready to use.
+type Service struct {
+	exporters builder.Exporters
+	pipelines builder.BuiltPipelines
+	receivers builder.Receivers
+}
+
+// Start builds and starts the components of cfg. Exporters are started
+// first and receivers last, so components only receive spans once the
+// components they pass them to are running. Components started before an
+// error are stopped by Shutdown.
+func (s *Service) Start(ctx context.Context, logger *zap.Logger, buildInfo component.BuildInfo, cfg *config.Config, factories component.Factories, host component.Host) error {
+	var err error
+
+	s.exporters, err = builder.BuildExporters(logger, buildInfo, cfg, factories.Exporters)
+	if err != nil {
+		return fmt.Errorf("failed to create exporters builder: %w", err)
+	}
+	if err := s.exporters.StartAll(ctx, host); err != nil {
+		return fmt.Errorf("failed to start exporters: %w", err)
+	}
+
+	s.pipelines, err = builder.BuildPipelines(logger, buildInfo, cfg, s.exporters, factories.Processors)
+	if err != nil {
+		return fmt.Errorf("failed to create pipelines builder: %w", err)
+	}
+	if err := s.pipelines.StartProcessors(ctx, host); err != nil {
+		return fmt.Errorf("failed to start processors: %w", err)
+	}
+
+	s.receivers, err = builder.BuildReceivers(logger, buildInfo, cfg, s.pipelines, factories.Receivers)
+	if err != nil {
+		return fmt.Errorf("failed to create receivers builder: %w", err)
+	}
+	if err := s.receivers.StartAll(ctx, host); err != nil {
+		return fmt.Errorf("failed to start receivers: %w", err)
+	}
+
+	return nil
+}
+
+// Shutdown stops the receivers, pipelines and exporters of s, in that order.
+// Errors are logged and don't prevent stopping the remaining components.
+// The first error is returned.
+func (s *Service) Shutdown(ctx context.Context, logger *zap.Logger) error {
+	dependencies := []struct {
+		name     string
+		shutdown func() error
+	}{
+		{
+			name: "receiver",
+			shutdown: func() error {
+				if s.receivers == nil {
+					return nil
+				}
+				return s.receivers.ShutdownAll(ctx)
+			},
+		},
+		{
+			name: "processors",
+			shutdown: func() error {
+				if s.pipelines == nil {
+					return nil
+				}
+				return s.pipelines.ShutdownProcessors(ctx)
+			},
+		},
+		{
+			name: "exporters",
+			shutdown: func() error {
+				if s.exporters == nil {
+					return nil
+				}
+				return s.exporters.ShutdownAll(ctx)
+			},
+		},
+	}
+
+	var firstErr error
+	for _, dep := range dependencies {
+		logger.Info(fmt.Sprintf("shutting down %s", dep.name))
+		if err := dep.shutdown(); err != nil {
+			logger.Error(fmt.Sprintf("failed to shutdown %s", dep.name), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	s.receivers = nil
+	s.pipelines = nil
+	s.exporters = nil
+	return firstErr
+}
+
+// Exporters returns the running exporters by data type, as returned by
+// component.Host.
+func (s *Service) Exporters() map[config.DataType]map[config.ComponentID]component.Exporter {
+	if s.exporters == nil {
+		return nil
+	}
+	return s.exporters.ToMapByDataType()
+}
+
+// ReceiverNames returns the names of the running receivers, such as
+// "otlp/lb", in no particular order.
+func (s *Service) ReceiverNames() []string {
+	names := make([]string, 0, len(s.receivers))
+	for r := range s.receivers {
+		names = append(names, r.ID().String())
+	}
+	return names
+}
+
+// ExporterNames returns the names of the running exporters, such as
+// "otlp/0", in no particular order.
+func (s *Service) ExporterNames() []string {
+	names := make([]string, 0, len(s.exporters))
+	for e := range s.exporters {
+		names = append(names, e.ID().String())
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+package otelcol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/exporter/otlpexporter"
+	"go.opentelemetry.io/collector/processor/batchprocessor"
+	"go.opentelemetry.io/collector/receiver/otlpreceiver"
+)
+
+func testFactories(t *testing.T) component.Factories {
+	t.Helper()
+
+	receivers, err := component.MakeReceiverFactoryMap(otlpreceiver.NewFactory())
+	require.NoError(t, err)
+	processors, err := component.MakeProcessorFactoryMap(batchprocessor.NewFactory())
+	require.NoError(t, err)
+	exporters, err := component.MakeExporterFactoryMap(otlpexporter.NewFactory())
+	require.NoError(t, err)
+
+	return component.Factories{Receivers: receivers, Processors: processors, Exporters: exporters}
+}
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg, err := Load(map[string]interface{}{
+		"receivers": map[string]interface{}{
+			"otlp":    map[string]interface{}{"protocols": map[string]interface{}{"grpc": nil}},
+			"otlp/lb": map[string]interface{}{"protocols": map[string]interface{}{"grpc": nil}},
+		},
+		"processors": map[string]interface{}{"batch": nil},
+		"exporters": map[string]interface{}{
+			"otlp/0": map[string]interface{}{"endpoint": "example.com:4317"},
+		},
+		"service": map[string]interface{}{
+			"pipelines": map[string]interface{}{
+				"traces/0": map[string]interface{}{
+					"receivers":  []string{"otlp"},
+					"processors": []string{"batch"},
+					"exporters":  []string{"otlp/0"},
+				},
+				"traces/1": map[string]interface{}{
+					"receivers": []string{"otlp/lb"},
+					"exporters": []string{"otlp/0"},
+				},
+			},
+		},
+	}, testFactories(t))
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(map[string]interface{}{
+		"receivers": map[string]interface{}{"unknown": nil},
+	}, testFactories(t))
+	require.Error(t, err)
+}
+
+func TestNewProcessorSettings(t *testing.T) {
+	settings := NewProcessorSettings("batch")
+	require.Equal(t, config.NewID("batch"), settings.ID())
+}
+
+func TestExporter(t *testing.T) {
+	cfg := testConfig(t)
+
+	exporter, ok := Exporter(cfg, "otlp/0").(*otlpexporter.Config)
+	require.True(t, ok)
+	require.Equal(t, "example.com:4317", exporter.Endpoint)
+
+	require.Nil(t, Exporter(cfg, "otlp/1"))
+}
+
+func TestPrependProcessor(t *testing.T) {
+	cfg := testConfig(t)
+
+	settings := config.NewProcessorSettings(config.NewIDWithName("batch", "lb"))
+	PrependProcessor(cfg, &settings, func(receivers []string) bool {
+		return len(receivers) == 1 && receivers[0] == "otlp/lb"
+	})
+
+	pipelines := make(map[string][]string)
+	for name, pipeline := range cfg.Pipelines {
+		var processors []string
+		for _, id := range pipeline.Processors {
+			processors = append(processors, id.String())
+		}
+		pipelines[name] = processors
+	}
+	require.Equal(t, map[string][]string{
+		"traces/0": {"batch"},
+		"traces/1": {"batch/lb"},
+	}, pipelines)
+	require.Contains(t, cfg.Processors, config.NewIDWithName("batch", "lb"))
+}
@@ -8,18 +8,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/agent/pkg/tempo/internal/otelcol"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/spanmetricsprocessor/mocks"
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
-	"go.opentelemetry.io/collector/config/configloader"
-	"go.opentelemetry.io/collector/config/configparser"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/consumer/pdata"
 	"go.opentelemetry.io/collector/processor/processorhelper"
 	"go.opentelemetry.io/collector/receiver/otlpreceiver"
-	"go.opentelemetry.io/collector/service/external/builder"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
@@ -27,9 +25,7 @@ import (
 // Server is a Tempo testing server that invokes a function every time a span
 // is received.
 type Server struct {
-	receivers builder.Receivers
-	pipelines builder.BuiltPipelines
-	exporters builder.Exporters
+	service *otelcol.Service
 }
 
 // NewTestServer creates a new Server for testing, where received traces will
@@ -123,8 +119,7 @@ service:
 		Processors: processorsFactory,
 		Exporters:  exportersFactory,
 	}
-	p := configparser.NewParserFromStringMap(cfg)
-	otelCfg, err := configloader.Load(p, factories)
+	otelCfg, err := otelcol.Load(cfg, factories)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make otel config: %w", err)
 	}
@@ -134,37 +129,15 @@ service:
 		startInfo component.BuildInfo
 	)
 
-	exporters, err := builder.BuildExporters(logger, startInfo, otelCfg, factories.Exporters)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build exporters: %w", err)
-	}
-	if err := exporters.StartAll(context.Background(), nil); err != nil {
-		return nil, fmt.Errorf("failed to start exporters: %w", err)
-	}
-
-	pipelines, err := builder.BuildPipelines(logger, startInfo, otelCfg, exporters, factories.Processors)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build pipelines: %w", err)
-	}
-	if err := pipelines.StartProcessors(context.Background(), nil); err != nil {
-		return nil, fmt.Errorf("failed to start pipelines: %w", err)
-	}
-
-	receivers, err := builder.BuildReceivers(logger, startInfo, otelCfg, pipelines, factories.Receivers)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build receivers: %w", err)
-	}
 	h := &mocks.Host{}
 	h.On("GetExtensions").Return(nil)
-	if err := receivers.StartAll(context.Background(), h); err != nil {
-		return nil, fmt.Errorf("failed to start receivers: %w", err)
-	}
 
-	return &Server{
-		receivers: receivers,
-		pipelines: pipelines,
-		exporters: exporters,
-	}, nil
+	srv := &Server{service: &otelcol.Service{}}
+	if err := srv.service.Start(context.Background(), logger, startInfo, otelCfg, factories, h); err != nil {
+		_ = srv.Stop()
+		return nil, err
+	}
+	return srv, nil
 }
 
 // Stop stops the testing server.
@@ -172,21 +145,7 @@ func (s *Server) Stop() error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var firstErr error
-
-	deps := []func(context.Context) error{
-		s.receivers.ShutdownAll,
-		s.pipelines.ShutdownProcessors,
-		s.exporters.ShutdownAll,
-	}
-	for _, dep := range deps {
-		err := dep(shutdownCtx)
-		if err != nil && firstErr == nil {
-			firstErr = err
-		}
-	}
-
-	return firstErr
+	return s.service.Shutdown(shutdownCtx, zap.NewNop())
 }
 
 func newFuncProcessorFactory(callback func(pdata.Traces)) component.ProcessorFactory {
@@ -0,0 +1,56 @@
+package loadbalancingexporter
+
+import (
+	"context"
+
+	contrib "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/loadbalancingexporter"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+)
+
+// TypeStr is the unique identifier for the load balancing exporter.
+const TypeStr = "loadbalancing"
+
+// Config holds the configuration for the load balancing exporter. It extends
+// the config of the loadbalancing exporter of opentelemetry-collector-contrib.
+type Config struct {
+	contrib.Config `mapstructure:",squash"`
+
+	// ReceiverPort is the port the instance receives load balanced spans on.
+	// A backend listening on it on an address of this host is the instance
+	// itself.
+	ReceiverPort string `mapstructure:"-"`
+
+	// Router passes spans to the instance's own processing pipeline when
+	// it's the only backend. Spans are always sent over the network when
+	// Router is nil.
+	Router *Router `mapstructure:"-"`
+}
+
+var _ config.Exporter = (*Config)(nil)
+
+// NewFactory returns a new factory for the load balancing exporter.
+func NewFactory() component.ExporterFactory {
+	return exporterhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		exporterhelper.WithTraces(createTracesExporter),
+	)
+}
+
+func createDefaultConfig() config.Exporter {
+	return &Config{
+		Config: *contrib.NewFactory().CreateDefaultConfig().(*contrib.Config),
+	}
+}
+
+func createTracesExporter(ctx context.Context, params component.ExporterCreateSettings, cfg config.Exporter) (component.TracesExporter, error) {
+	eCfg := cfg.(*Config)
+
+	inner, err := contrib.NewFactory().CreateTracesExporter(ctx, params, &eCfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newTracesExporter(params.Logger, eCfg, inner), nil
+}
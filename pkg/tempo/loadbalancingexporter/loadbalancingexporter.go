@@ -0,0 +1,254 @@
+// Package loadbalancingexporter wraps the loadbalancing exporter of
+// opentelemetry-collector-contrib. When the only backend the resolver returns
+// is the instance itself, spans are passed to its processing pipeline
+// in-process instead of being sent to its own load balancing receiver over
+// the network, saving the cost of serializing them on single-replica
+// deployments.
+package loadbalancingexporter
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultPort is the port of backends which don't specify one, matching
+	// the contrib exporter.
+	defaultPort = "4317"
+
+	resolveTimeout = time.Second
+)
+
+// resolveInterval is how often backends are resolved to check whether the
+// instance is the only one. It matches the interval of the contrib DNS
+// resolver.
+var resolveInterval = 5 * time.Second
+
+// Router passes spans to the processing pipeline of an instance. The
+// pipeline registers itself with SetConsumer once it's started.
+type Router struct {
+	mut  sync.RWMutex
+	next consumer.Traces
+}
+
+// NewRouter creates a Router without a consumer.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// SetConsumer sets the consumer spans are routed to. A nil consumer
+// disables routing.
+func (r *Router) SetConsumer(next consumer.Traces) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.next = next
+}
+
+// Consumer returns the consumer spans are routed to, if any.
+func (r *Router) Consumer() consumer.Traces {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	return r.next
+}
+
+// netResolver resolves hostnames. It's implemented by *net.Resolver.
+type netResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+type tracesExporter struct {
+	inner  component.TracesExporter
+	logger *zap.Logger
+	cfg    *Config
+
+	resolver netResolver
+	// localAddrs returns the addresses of the network interfaces of this
+	// host.
+	localAddrs func() ([]net.Addr, error)
+
+	// local is 1 while the instance is the only backend.
+	local int32
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newTracesExporter(logger *zap.Logger, cfg *Config, inner component.TracesExporter) *tracesExporter {
+	return &tracesExporter{
+		inner:      inner,
+		logger:     logger,
+		cfg:        cfg,
+		resolver:   &net.Resolver{},
+		localAddrs: net.InterfaceAddrs,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start starts the contrib exporter and periodically checks whether the
+// instance is the only backend.
+func (e *tracesExporter) Start(ctx context.Context, host component.Host) error {
+	if err := e.inner.Start(ctx, host); err != nil {
+		return err
+	}
+	if e.cfg.Router == nil {
+		return nil
+	}
+
+	e.checkLocal()
+	e.wg.Add(1)
+	go e.run()
+	return nil
+}
+
+func (e *tracesExporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(resolveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.checkLocal()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// checkLocal updates whether the instance is the only backend. Backends
+// which fail to resolve are assumed to be remote.
+func (e *tracesExporter) checkLocal() {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	local := false
+	endpoints, err := e.endpoints(ctx)
+	if err != nil {
+		e.logger.Debug("failed to resolve load balancing backends", zap.Error(err))
+	} else if len(endpoints) == 1 {
+		local = e.isLocal(ctx, endpoints[0])
+	}
+
+	var val int32
+	if local {
+		val = 1
+	}
+	if prev := atomic.SwapInt32(&e.local, val); prev != val {
+		e.logger.Info("changed routing of load balanced spans", zap.Bool("in_process", local))
+	}
+}
+
+// endpoints returns the distinct backends of the resolver as host:port.
+func (e *tracesExporter) endpoints(ctx context.Context) ([]string, error) {
+	set := make(map[string]struct{})
+
+	switch res := e.cfg.Resolver; {
+	case res.Static != nil:
+		for _, hostname := range res.Static.Hostnames {
+			set[withPort(hostname, defaultPort)] = struct{}{}
+		}
+	case res.DNS != nil:
+		addrs, err := e.resolver.LookupIPAddr(ctx, res.DNS.Hostname)
+		if err != nil {
+			return nil, err
+		}
+		port := res.DNS.Port
+		if port == "" {
+			port = defaultPort
+		}
+		for _, addr := range addrs {
+			set[net.JoinHostPort(addr.IP.String(), port)] = struct{}{}
+		}
+	}
+
+	endpoints := make([]string, 0, len(set))
+	for endpoint := range set {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints, nil
+}
+
+// isLocal returns true if endpoint is the load balancing receiver of the
+// instance: its port is the receiver's and all of its addresses belong to
+// this host.
+func (e *tracesExporter) isLocal(ctx context.Context, endpoint string) bool {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil || port != e.cfg.ReceiverPort {
+		return false
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := e.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return false
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return false
+	}
+
+	localAddrs, err := e.localAddrs()
+	if err != nil {
+		return false
+	}
+
+NextIP:
+	for _, ip := range ips {
+		if ip.IsLoopback() {
+			continue
+		}
+		for _, addr := range localAddrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				continue NextIP
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// withPort returns endpoint with port added if it doesn't have one.
+func withPort(endpoint, port string) string {
+	if _, _, err := net.SplitHostPort(endpoint); err == nil {
+		return endpoint
+	}
+	return net.JoinHostPort(endpoint, port)
+}
+
+// ConsumeTraces passes td to the processing pipeline of the instance if it's
+// the only backend, and to the contrib exporter otherwise.
+func (e *tracesExporter) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	if atomic.LoadInt32(&e.local) == 1 {
+		if next := e.cfg.Router.Consumer(); next != nil {
+			return next.ConsumeTraces(ctx, td)
+		}
+	}
+	return e.inner.ConsumeTraces(ctx, td)
+}
+
+func (e *tracesExporter) Capabilities() consumer.Capabilities {
+	return e.inner.Capabilities()
+}
+
+// Shutdown stops checking the backends and shuts down the contrib exporter.
+func (e *tracesExporter) Shutdown(ctx context.Context) error {
+	close(e.stop)
+	e.wg.Wait()
+	return e.inner.Shutdown(ctx)
+}
@@ -0,0 +1,140 @@
+package loadbalancingexporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	contrib "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/loadbalancingexporter"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.uber.org/zap"
+)
+
+// sinkExporter is a traces exporter storing the spans it receives.
+type sinkExporter struct {
+	consumertest.TracesSink
+}
+
+func (e *sinkExporter) Start(context.Context, component.Host) error { return nil }
+func (e *sinkExporter) Shutdown(context.Context) error              { return nil }
+
+// fakeResolver resolves hostnames from a fixed map.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestTracesExporter_Local(t *testing.T) {
+	resolver := fakeResolver{
+		"localhost":   {"127.0.0.1", "::1"},
+		"agent":       {"10.0.0.1"},
+		"other-agent": {"10.0.0.2"},
+		"agents":      {"10.0.0.1", "10.0.0.2"},
+	}
+
+	tt := []struct {
+		name     string
+		resolver contrib.ResolverSettings
+		local    bool
+	}{
+		{
+			name:     "static localhost",
+			resolver: contrib.ResolverSettings{Static: &contrib.StaticResolver{Hostnames: []string{"localhost:4318"}}},
+			local:    true,
+		},
+		{
+			name:     "static interface address",
+			resolver: contrib.ResolverSettings{Static: &contrib.StaticResolver{Hostnames: []string{"agent:4318"}}},
+			local:    true,
+		},
+		{
+			name:     "static default port",
+			resolver: contrib.ResolverSettings{Static: &contrib.StaticResolver{Hostnames: []string{"localhost"}}},
+			local:    false,
+		},
+		{
+			name:     "static other host",
+			resolver: contrib.ResolverSettings{Static: &contrib.StaticResolver{Hostnames: []string{"other-agent:4318"}}},
+			local:    false,
+		},
+		{
+			name:     "static multiple backends",
+			resolver: contrib.ResolverSettings{Static: &contrib.StaticResolver{Hostnames: []string{"localhost:4318", "other-agent:4318"}}},
+			local:    false,
+		},
+		{
+			name:     "static unresolvable",
+			resolver: contrib.ResolverSettings{Static: &contrib.StaticResolver{Hostnames: []string{"unknown:4318"}}},
+			local:    false,
+		},
+		{
+			name:     "dns single backend",
+			resolver: contrib.ResolverSettings{DNS: &contrib.DNSResolver{Hostname: "agent", Port: "4318"}},
+			local:    true,
+		},
+		{
+			name:     "dns multiple backends",
+			resolver: contrib.ResolverSettings{DNS: &contrib.DNSResolver{Hostname: "agents", Port: "4318"}},
+			local:    false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{ReceiverPort: "4318", Router: NewRouter()}
+			cfg.Resolver = tc.resolver
+
+			e := newTracesExporter(zap.NewNop(), cfg, &sinkExporter{})
+			e.resolver = resolver
+			e.localAddrs = func() ([]net.Addr, error) {
+				return []net.Addr{&net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.CIDRMask(24, 32)}}, nil
+			}
+
+			e.checkLocal()
+			require.Equal(t, tc.local, e.local == 1)
+		})
+	}
+}
+
+func TestTracesExporter_ConsumeTraces(t *testing.T) {
+	cfg := &Config{ReceiverPort: "4318", Router: NewRouter()}
+	cfg.Resolver.Static = &contrib.StaticResolver{Hostnames: []string{"127.0.0.1:4318"}}
+
+	inner := &sinkExporter{}
+	e := newTracesExporter(zap.NewNop(), cfg, inner)
+	e.checkLocal()
+
+	td := pdata.NewTraces()
+	td.ResourceSpans().AppendEmpty().InstrumentationLibrarySpans().AppendEmpty().Spans().AppendEmpty()
+
+	// Spans are sent over the network until the processing pipeline is
+	// registered with the router.
+	require.NoError(t, e.ConsumeTraces(context.Background(), td))
+	require.Equal(t, 1, inner.SpansCount())
+
+	local := new(consumertest.TracesSink)
+	cfg.Router.SetConsumer(local)
+	require.NoError(t, e.ConsumeTraces(context.Background(), td))
+	require.Equal(t, 1, inner.SpansCount())
+	require.Equal(t, 1, local.SpansCount())
+
+	// Spans are sent over the network again once another backend is added.
+	cfg.Resolver.Static.Hostnames = append(cfg.Resolver.Static.Hostnames, "10.0.0.2:4318")
+	e.checkLocal()
+	require.NoError(t, e.ConsumeTraces(context.Background(), td))
+	require.Equal(t, 2, inner.SpansCount())
+	require.Equal(t, 1, local.SpansCount())
+}
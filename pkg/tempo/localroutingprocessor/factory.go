@@ -0,0 +1,52 @@
+package localroutingprocessor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/agent/pkg/tempo/loadbalancingexporter"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+// TypeStr is the unique identifier for the local routing processor.
+const TypeStr = "local_routing"
+
+// Config holds the configuration for the local routing processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// Router is shared with the load balancing exporter, which passes spans
+	// to the processor through it.
+	Router *loadbalancingexporter.Router `mapstructure:"-"`
+}
+
+// NewFactory returns a new factory for the local routing processor.
+func NewFactory() component.ProcessorFactory {
+	return processorhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		processorhelper.WithTraces(createTraceProcessor),
+	)
+}
+
+func createDefaultConfig() config.Processor {
+	return &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewID(TypeStr)),
+	}
+}
+
+func createTraceProcessor(
+	_ context.Context,
+	_ component.ProcessorCreateSettings,
+	cfg config.Processor,
+	nextConsumer consumer.Traces,
+) (component.TracesProcessor, error) {
+	routingCfg := cfg.(*Config)
+	if routingCfg.Router == nil {
+		return nil, fmt.Errorf("local routing processor requires a router")
+	}
+	return newTraceProcessor(nextConsumer, routingCfg.Router)
+}
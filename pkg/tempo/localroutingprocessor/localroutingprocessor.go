@@ -0,0 +1,49 @@
+// Package localroutingprocessor implements a processor which registers the
+// rest of its pipeline with the load balancing exporter, so spans can be
+// passed to it in-process when the instance is the only load balancing
+// backend.
+package localroutingprocessor
+
+import (
+	"context"
+
+	"github.com/grafana/agent/pkg/tempo/loadbalancingexporter"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenterror"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+type localRoutingProcessor struct {
+	nextConsumer consumer.Traces
+	router       *loadbalancingexporter.Router
+}
+
+func newTraceProcessor(nextConsumer consumer.Traces, router *loadbalancingexporter.Router) (component.TracesProcessor, error) {
+	if nextConsumer == nil {
+		return nil, componenterror.ErrNilNextConsumer
+	}
+	return &localRoutingProcessor{nextConsumer: nextConsumer, router: router}, nil
+}
+
+// ConsumeTraces passes td to the next consumer.
+func (p *localRoutingProcessor) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	return p.nextConsumer.ConsumeTraces(ctx, td)
+}
+
+func (p *localRoutingProcessor) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+// Start registers the next consumer with the router.
+func (p *localRoutingProcessor) Start(context.Context, component.Host) error {
+	p.router.SetConsumer(p.nextConsumer)
+	return nil
+}
+
+// Shutdown unregisters the next consumer from the router, so spans are sent
+// over the network again.
+func (p *localRoutingProcessor) Shutdown(context.Context) error {
+	p.router.SetConsumer(nil)
+	return nil
+}
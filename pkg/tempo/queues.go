@@ -0,0 +1,76 @@
+package tempo
+
+import (
+	"fmt"
+	"math"
+)
+
+// defaultQueueSize is the queue_size of an exporter's sending_queue when it
+// isn't set.
+const defaultQueueSize = 5000
+
+// SetQueueScale scales the queue_size of the sending_queue of every exporter
+// by scale, which must be in (0, 1]. Instances whose queue size changes are
+// rebuilt, dropping the spans in their queues.
+func (t *Tempo) SetQueueScale(scale float64) error {
+	if scale <= 0 || scale > 1 {
+		return fmt.Errorf("queue scale must be in (0, 1], got %v", scale)
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.queueScale == scale {
+		return nil
+	}
+	prev := t.queueScale
+	t.queueScale = scale
+	if err := t.applyConfig(t.loki, t.promInstanceManager, t.cfg); err != nil {
+		t.queueScale = prev
+		return err
+	}
+	return nil
+}
+
+// scaleQueues returns c with the queue_size of every sending_queue scaled by
+// scale. c is returned unmodified when scale is 1.
+func scaleQueues(c InstanceConfig, scale float64) InstanceConfig {
+	if scale == 1 {
+		return c
+	}
+
+	rws := make([]RemoteWriteConfig, len(c.RemoteWrite))
+	for i, rw := range c.RemoteWrite {
+		rw.SendingQueue = scaleSendingQueue(rw.SendingQueue, scale)
+		rws[i] = rw
+	}
+	c.RemoteWrite = rws
+
+	if c.PushConfig.Endpoint != "" {
+		c.PushConfig.SendingQueue = scaleSendingQueue(c.PushConfig.SendingQueue, scale)
+	}
+	return c
+}
+
+// scaleSendingQueue returns a copy of queue with its queue_size scaled. The
+// queue_size is never scaled below 1.
+func scaleSendingQueue(queue map[string]interface{}, scale float64) map[string]interface{} {
+	if enabled, ok := queue["enabled"].(bool); ok && !enabled {
+		return queue
+	}
+
+	size := float64(defaultQueueSize)
+	switch v := queue["queue_size"].(type) {
+	case int:
+		size = float64(v)
+	case float64:
+		size = v
+	}
+
+	res := make(map[string]interface{}, len(queue)+1)
+	for k, v := range queue {
+		res[k] = v
+	}
+	res["queue_size"] = int(math.Max(1, math.Floor(size*scale)))
+	return res
+}
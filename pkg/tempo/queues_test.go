@@ -0,0 +1,30 @@
+package tempo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaleQueues(t *testing.T) {
+	cfg := InstanceConfig{
+		RemoteWrite: []RemoteWriteConfig{
+			{Endpoint: "default:4317"},
+			{Endpoint: "sized:4317", SendingQueue: map[string]interface{}{"queue_size": 100, "num_consumers": 2}},
+			{Endpoint: "disabled:4317", SendingQueue: map[string]interface{}{"enabled": false}},
+			{Endpoint: "tiny:4317", SendingQueue: map[string]interface{}{"queue_size": 1}},
+		},
+	}
+
+	require.Equal(t, cfg, scaleQueues(cfg, 1))
+
+	scaled := scaleQueues(cfg, 0.1)
+	require.Equal(t, map[string]interface{}{"queue_size": 500}, scaled.RemoteWrite[0].SendingQueue)
+	require.Equal(t, map[string]interface{}{"queue_size": 10, "num_consumers": 2}, scaled.RemoteWrite[1].SendingQueue)
+	require.Equal(t, map[string]interface{}{"enabled": false}, scaled.RemoteWrite[2].SendingQueue)
+	require.Equal(t, map[string]interface{}{"queue_size": 1}, scaled.RemoteWrite[3].SendingQueue)
+
+	// The original config must not be modified.
+	require.Nil(t, cfg.RemoteWrite[0].SendingQueue)
+	require.Equal(t, 100, cfg.RemoteWrite[1].SendingQueue["queue_size"])
+}
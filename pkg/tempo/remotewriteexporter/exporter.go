@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	util "github.com/cortexproject/cortex/pkg/util/log"
@@ -15,6 +16,7 @@ import (
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/timestamp"
 	"github.com/prometheus/prometheus/storage"
+	"go.opencensus.io/stats"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/consumer/pdata"
@@ -44,17 +46,31 @@ type remoteWriteExporter struct {
 	constLabels labels.Labels
 	namespace   string
 
+	// series enforces the maximum number of active series. nil if there is
+	// no limit.
+	series *seriesLimiter
+
 	logger log.Logger
 }
 
 func newRemoteWriteExporter(cfg *Config) (component.MetricsExporter, error) {
 	logger := log.With(util.Logger, "component", "tempo remote write exporter")
 
+	var series *seriesLimiter
+	if cfg.MaxSeries > 0 {
+		idleTimeout := cfg.SeriesIdleTimeout
+		if idleTimeout <= 0 {
+			idleTimeout = DefaultSeriesIdleTimeout
+		}
+		series = newSeriesLimiter(cfg.MaxSeries, idleTimeout)
+	}
+
 	return &remoteWriteExporter{
 		done:         atomic.Bool{},
 		constLabels:  cfg.ConstLabels,
 		namespace:    cfg.Namespace,
 		promInstance: cfg.PromInstance,
+		series:       series,
 		logger:       logger,
 	}, nil
 }
@@ -214,6 +230,10 @@ func (e *remoteWriteExporter) appendDataPoint(app storage.Appender, name, suffix
 
 func (e *remoteWriteExporter) appendDataPointWithLabels(app storage.Appender, name, suffix string, dp dataPoint, v float64, customLabels labels.Labels) error {
 	ls := e.createLabelSet(name, suffix, dp.LabelsMap(), customLabels)
+	if !e.admitSeries(ls) {
+		stats.Record(context.Background(), statDroppedSeries.M(1))
+		return nil
+	}
 	// TODO(mario.rodriguez): Use timestamp from metric
 	// time.Now() is used to avoid out-of-order metrics
 	ts := timestamp.FromTime(time.Now())
@@ -223,6 +243,67 @@ func (e *remoteWriteExporter) appendDataPointWithLabels(app storage.Appender, na
 	return nil
 }
 
+// admitSeries reports whether a sample for ls may be written.
+func (e *remoteWriteExporter) admitSeries(ls labels.Labels) bool {
+	if e.series == nil {
+		return true
+	}
+	return e.series.Admit(labels.New(ls...).Hash(), time.Now())
+}
+
+// seriesLimiter limits the number of active series. A series is active until
+// it hasn't been written for idleTimeout.
+type seriesLimiter struct {
+	maxSeries   int
+	idleTimeout time.Duration
+
+	mut        sync.Mutex
+	lastWrite  map[uint64]time.Time
+	lastExpire time.Time
+}
+
+func newSeriesLimiter(maxSeries int, idleTimeout time.Duration) *seriesLimiter {
+	return &seriesLimiter{
+		maxSeries:   maxSeries,
+		idleTimeout: idleTimeout,
+		lastWrite:   make(map[uint64]time.Time, maxSeries),
+	}
+}
+
+// Admit reports whether the series identified by hash may be written at now.
+// Series that are already active are always admitted. New series are only
+// admitted if there are fewer than maxSeries active series, after idle series
+// are expired.
+func (l *seriesLimiter) Admit(hash uint64, now time.Time) bool {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if _, ok := l.lastWrite[hash]; !ok && len(l.lastWrite) >= l.maxSeries {
+		l.expire(now)
+		if len(l.lastWrite) >= l.maxSeries {
+			return false
+		}
+	}
+	l.lastWrite[hash] = now
+	return true
+}
+
+// expire removes idle series. Expiring walks every series, so it's done at
+// most once per second to bound the cost of many new series being rejected.
+// The mutex must be held when calling expire.
+func (l *seriesLimiter) expire(now time.Time) {
+	if now.Sub(l.lastExpire) < time.Second {
+		return
+	}
+	l.lastExpire = now
+
+	for hash, lastWrite := range l.lastWrite {
+		if now.Sub(lastWrite) > l.idleTimeout {
+			delete(l.lastWrite, hash)
+		}
+	}
+}
+
 func (e *remoteWriteExporter) createLabelSet(name, suffix string, labelMap pdata.StringMap, customLabels labels.Labels) labels.Labels {
 	ls := make(labels.Labels, 0, labelMap.Len()+1+len(e.constLabels)+len(customLabels))
 	// Labels from spanmetrics processor
@@ -82,6 +82,60 @@ func TestRemoteWriteExporter_handleHistogramIntDataPoints(t *testing.T) {
 	}
 }
 
+func TestRemoteWriteExporter_MaxSeries(t *testing.T) {
+	exp, err := newRemoteWriteExporter(&Config{
+		Namespace:    "tempo_spanmetrics",
+		PromInstance: "tempo",
+		MaxSeries:    2,
+	})
+	require.NoError(t, err)
+
+	rwExp := exp.(*remoteWriteExporter)
+	manager := &mockManager{}
+	rwExp.manager = manager
+	instance, _ := manager.GetInstance("tempo")
+	app := instance.Appender(context.TODO())
+
+	for _, method := range []string{"GET", "POST", "GET", "PUT", "POST"} {
+		dp := pdata.NewIntDataPoint()
+		dp.LabelsMap().Insert("http.method", method)
+		dp.SetValue(1)
+		dps := pdata.NewIntDataPointSlice()
+		dps.Append(dp)
+
+		err := rwExp.handleScalarIntDataPoints(app, "calls", counterSuffix, dps)
+		require.NoError(t, err)
+	}
+
+	// The PUT series should have been dropped after reaching max_series.
+	calls := manager.instance.GetAppended("tempo_spanmetrics_calls_total")
+	require.Len(t, calls, 4)
+	for _, c := range calls {
+		require.NotEqual(t, "PUT", c.l.Get("http_method"))
+	}
+}
+
+func TestRemoteWriteExporter_NoMaxSeries(t *testing.T) {
+	exp, err := newRemoteWriteExporter(&Config{})
+	require.NoError(t, err)
+	require.Nil(t, exp.(*remoteWriteExporter).series, "series should not be tracked without max_series")
+}
+
+func TestSeriesLimiter(t *testing.T) {
+	l := newSeriesLimiter(2, time.Minute)
+	now := time.Now()
+
+	require.True(t, l.Admit(1, now))
+	require.True(t, l.Admit(2, now))
+	require.False(t, l.Admit(3, now), "new series over the limit should be rejected")
+
+	// Keep series 2 active. Series 1 becomes idle, making room for series 3.
+	require.True(t, l.Admit(2, now.Add(45*time.Second)))
+	require.True(t, l.Admit(3, now.Add(90*time.Second)))
+	require.True(t, l.Admit(2, now.Add(90*time.Second)))
+	require.False(t, l.Admit(1, now.Add(90*time.Second)))
+}
+
 type mockManager struct {
 	instance *mockInstance
 }
@@ -2,6 +2,7 @@ package remotewriteexporter
 
 import (
 	"context"
+	"time"
 
 	"github.com/prometheus/prometheus/pkg/labels"
 	"go.opentelemetry.io/collector/component"
@@ -12,15 +13,21 @@ import (
 const (
 	// TypeStr is the unique identifier for the Prometheus remote write exporter.
 	TypeStr = "remote_write"
+
+	// DefaultSeriesIdleTimeout is the default time a series may go without
+	// being written before it no longer counts towards MaxSeries.
+	DefaultSeriesIdleTimeout = 5 * time.Minute
 )
 
 // Config holds the configuration for the Prometheus SD processor.
 type Config struct {
 	config.ProcessorSettings `mapstructure:",squash"`
 
-	ConstLabels  labels.Labels `mapstructure:"const_labels"`
-	Namespace    string        `mapstructure:"namespace"`
-	PromInstance string        `mapstructure:"prom_instance"`
+	ConstLabels       labels.Labels `mapstructure:"const_labels"`
+	Namespace         string        `mapstructure:"namespace"`
+	PromInstance      string        `mapstructure:"prom_instance"`
+	MaxSeries         int           `mapstructure:"max_series"`
+	SeriesIdleTimeout time.Duration `mapstructure:"series_idle_timeout"`
 }
 
 // NewFactory returns a new factory for the Attributes processor.
@@ -35,6 +42,7 @@ func NewFactory() component.ExporterFactory {
 func createDefaultConfig() config.Exporter {
 	return &Config{
 		ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(TypeStr, TypeStr)),
+		SeriesIdleTimeout: DefaultSeriesIdleTimeout,
 	}
 }
 
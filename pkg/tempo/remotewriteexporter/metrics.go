@@ -0,0 +1,24 @@
+package remotewriteexporter
+
+import (
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+var (
+	statDroppedSeries = stats.Int64("dropped_series", "Number of series samples dropped because the max_series limit was reached", stats.UnitDimensionless)
+)
+
+// MetricViews returns the metrics views related to the remote write exporter.
+func MetricViews() []*view.View {
+	droppedSeriesView := &view.View{
+		Name:        "exporter/" + TypeStr + "/" + statDroppedSeries.Name(),
+		Measure:     statDroppedSeries,
+		Description: statDroppedSeries.Description(),
+		Aggregation: view.Sum(),
+	}
+
+	return []*view.View{
+		droppedSeriesView,
+	}
+}
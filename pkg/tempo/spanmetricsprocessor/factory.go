@@ -0,0 +1,126 @@
+package spanmetricsprocessor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	contrib "github.com/open-telemetry/opentelemetry-collector-contrib/processor/spanmetricsprocessor"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+// TypeStr is the unique identifier for the spanmetrics processor.
+const TypeStr = "spanmetrics"
+
+// Dimension is an additional dimension of the metrics, read from a span
+// attribute.
+type Dimension = contrib.Dimension
+
+// Default dimensions of the metrics, which can be excluded.
+const (
+	DimensionServiceName = "service.name"
+	DimensionOperation   = "operation"
+	DimensionSpanKind    = "span.kind"
+	DimensionStatusCode  = "status.code"
+)
+
+var defaultDimensions = []string{DimensionServiceName, DimensionOperation, DimensionSpanKind, DimensionStatusCode}
+
+var spanKinds = map[string]pdata.SpanKind{
+	"UNSPECIFIED": pdata.SpanKindUnspecified,
+	"INTERNAL":    pdata.SpanKindInternal,
+	"SERVER":      pdata.SpanKindServer,
+	"CLIENT":      pdata.SpanKindClient,
+	"PRODUCER":    pdata.SpanKindProducer,
+	"CONSUMER":    pdata.SpanKindConsumer,
+}
+
+// Config holds the configuration for the spanmetrics processor. It extends
+// the config of the spanmetrics processor of opentelemetry-collector-contrib.
+type Config struct {
+	contrib.Config `mapstructure:",squash"`
+
+	// SpanKinds restricts the spans metrics are calculated from to the
+	// given kinds, such as SERVER. Spans of all kinds are used when empty.
+	SpanKinds []string `mapstructure:"span_kinds"`
+
+	// ExcludeDimensions removes default dimensions from the metrics.
+	ExcludeDimensions []string `mapstructure:"exclude_dimensions"`
+}
+
+var _ config.Processor = (*Config)(nil)
+
+// Validate checks that the span kinds and excluded dimensions are supported.
+func (c *Config) Validate() error {
+	for _, kind := range c.SpanKinds {
+		if _, err := ParseSpanKind(kind); err != nil {
+			return err
+		}
+	}
+	for _, dim := range c.ExcludeDimensions {
+		if err := ValidateExcludedDimension(dim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSpanKind parses the name of a span kind, such as SERVER. Names are
+// case insensitive and may include the SPAN_KIND_ prefix.
+func ParseSpanKind(s string) (pdata.SpanKind, error) {
+	kind, ok := spanKinds[strings.TrimPrefix(strings.ToUpper(s), "SPAN_KIND_")]
+	if !ok {
+		return 0, fmt.Errorf("unsupported span kind %q, must be one of INTERNAL, SERVER, CLIENT, PRODUCER, CONSUMER, or UNSPECIFIED", s)
+	}
+	return kind, nil
+}
+
+// ValidateExcludedDimension returns an error if dim isn't a default
+// dimension.
+func ValidateExcludedDimension(dim string) error {
+	for _, d := range defaultDimensions {
+		if dim == d {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported dimension %q, must be one of %s", dim, strings.Join(defaultDimensions, ", "))
+}
+
+// NewFactory returns a new factory for the spanmetrics processor.
+func NewFactory() component.ProcessorFactory {
+	return processorhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		processorhelper.WithTraces(createTracesProcessor),
+	)
+}
+
+func createDefaultConfig() config.Processor {
+	return &Config{
+		Config: contrib.Config{
+			ProcessorSettings: config.NewProcessorSettings(config.NewID(TypeStr)),
+		},
+	}
+}
+
+func createTracesProcessor(
+	ctx context.Context,
+	params component.ProcessorCreateSettings,
+	cfg config.Processor,
+	nextConsumer consumer.Traces,
+) (component.TracesProcessor, error) {
+	pCfg := cfg.(*Config)
+
+	// Spans are passed to the next consumer by this processor, so the spans
+	// passed to the contrib processor are discarded after metrics were
+	// calculated.
+	inner, err := contrib.NewFactory().CreateTracesProcessor(ctx, params, &pCfg.Config, discard{})
+	if err != nil {
+		return nil, err
+	}
+	return newProcessor(pCfg, inner, nextConsumer)
+}
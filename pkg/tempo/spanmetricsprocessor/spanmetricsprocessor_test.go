@@ -0,0 +1,119 @@
+package spanmetricsprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+// metricsExporter is a metrics exporter storing the metrics it receives.
+type metricsExporter struct {
+	consumertest.MetricsSink
+}
+
+func (e *metricsExporter) Start(context.Context, component.Host) error { return nil }
+func (e *metricsExporter) Shutdown(context.Context) error              { return nil }
+
+// host is a component.Host with a single metrics exporter.
+type host struct {
+	component.Host
+	exporter *metricsExporter
+}
+
+func (h *host) GetExporters() map[config.DataType]map[config.ComponentID]component.Exporter {
+	return map[config.DataType]map[config.ComponentID]component.Exporter{
+		config.MetricsDataType: {config.NewID("sink"): h.exporter},
+	}
+}
+
+func TestSpanMetrics(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.MetricsExporter = "sink"
+	cfg.SpanKinds = []string{"SERVER", "consumer"}
+	cfg.ExcludeDimensions = []string{DimensionOperation, DimensionStatusCode}
+
+	sink := new(consumertest.TracesSink)
+	p, err := createTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, sink)
+	require.NoError(t, err)
+
+	exporter := &metricsExporter{}
+	require.NoError(t, p.Start(context.Background(), &host{Host: componenttest.NewNopHost(), exporter: exporter}))
+	defer p.Shutdown(context.Background())
+
+	td := testTraces(
+		testSpan{"GET /a", pdata.SpanKindServer, pdata.StatusCodeOk},
+		testSpan{"GET /b", pdata.SpanKindServer, pdata.StatusCodeError},
+		testSpan{"SELECT", pdata.SpanKindClient, pdata.StatusCodeOk},
+		testSpan{"process", pdata.SpanKindConsumer, pdata.StatusCodeOk},
+	)
+	require.NoError(t, p.ConsumeTraces(context.Background(), td))
+
+	// All spans are passed on unmodified.
+	require.Equal(t, 4, sink.SpansCount())
+	require.Equal(t, "GET /a", sink.AllTraces()[0].ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).Name())
+
+	// Calls are only counted for the selected kinds, without the excluded
+	// dimensions.
+	calls := make(map[string]int64)
+	md := exporter.AllMetrics()[0]
+	metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	for i := 0; i < metrics.Len(); i++ {
+		m := metrics.At(i)
+		if m.Name() != "calls_total" {
+			continue
+		}
+		dp := m.IntSum().DataPoints().At(0)
+		labels := dp.LabelsMap()
+		require.Equal(t, 2, labels.Len())
+		service, _ := labels.Get(DimensionServiceName)
+		require.Equal(t, "svc", service)
+		kind, _ := labels.Get(DimensionSpanKind)
+		calls[kind] = dp.Value()
+	}
+	require.Equal(t, map[string]int64{"SPAN_KIND_SERVER": 2, "SPAN_KIND_CONSUMER": 1}, calls)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.SpanKinds = []string{"span_kind_server", "CLIENT"}
+	cfg.ExcludeDimensions = []string{DimensionServiceName}
+	require.NoError(t, cfg.Validate())
+
+	cfg.SpanKinds = []string{"REMOTE"}
+	require.EqualError(t, cfg.Validate(), `unsupported span kind "REMOTE", must be one of INTERNAL, SERVER, CLIENT, PRODUCER, CONSUMER, or UNSPECIFIED`)
+
+	cfg.SpanKinds = nil
+	cfg.ExcludeDimensions = []string{"http.method"}
+	require.EqualError(t, cfg.Validate(), `unsupported dimension "http.method", must be one of service.name, operation, span.kind, status.code`)
+}
+
+type testSpan struct {
+	name   string
+	kind   pdata.SpanKind
+	status pdata.StatusCode
+}
+
+func testTraces(spans ...testSpan) pdata.Traces {
+	td := pdata.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().UpsertString(DimensionServiceName, "svc")
+	ils := rs.InstrumentationLibrarySpans().AppendEmpty()
+
+	now := time.Now()
+	for _, s := range spans {
+		span := ils.Spans().AppendEmpty()
+		span.SetName(s.name)
+		span.SetKind(s.kind)
+		span.Status().SetCode(s.status)
+		span.SetStartTimestamp(pdata.TimestampFromTime(now))
+		span.SetEndTimestamp(pdata.TimestampFromTime(now.Add(time.Millisecond)))
+	}
+	return td
+}
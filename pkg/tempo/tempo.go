@@ -9,6 +9,7 @@ import (
 	"contrib.go.opencensus.io/exporter/prometheus"
 	"github.com/grafana/agent/pkg/loki"
 	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/tempo/remotewriteexporter"
 	zaplogfmt "github.com/jsternberg/zap-logfmt"
 	prom_client "github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -30,6 +31,12 @@ type Tempo struct {
 	reg      prom_client.Registerer
 
 	promInstanceManager instance.Manager
+
+	// The last applied config is kept to rebuild instances when queueScale
+	// changes.
+	loki       *loki.Loki
+	cfg        Config
+	queueScale float64
 }
 
 // New creates and starts Loki log collection.
@@ -42,6 +49,7 @@ func New(loki *loki.Loki, promInstanceManager instance.Manager, reg prom_client.
 		logger:              newLogger(&leveller),
 		reg:                 reg,
 		promInstanceManager: promInstanceManager,
+		queueScale:          1,
 	}
 	if err := tempo.ApplyConfig(loki, promInstanceManager, cfg, level); err != nil {
 		return nil, err
@@ -57,9 +65,22 @@ func (t *Tempo) ApplyConfig(loki *loki.Loki, promInstanceManager instance.Manage
 	// Update the log level, if it has changed.
 	t.leveller.SetLevel(level)
 
+	if err := t.applyConfig(loki, promInstanceManager, cfg); err != nil {
+		return err
+	}
+	t.loki, t.cfg = loki, cfg
+	return nil
+}
+
+// applyConfig creates, updates, and stops instances for cfg. The mutex must
+// be held when calling applyConfig.
+func (t *Tempo) applyConfig(loki *loki.Loki, promInstanceManager instance.Manager, cfg Config) error {
 	newInstances := make(map[string]*Instance, len(cfg.Configs))
 
 	for _, c := range cfg.Configs {
+		c = scaleQueues(c, t.queueScale)
+		c.globalAttributes = cfg.GlobalAttributes
+
 		// If an old instance exists, update it and move it to the new map.
 		if old, ok := t.instances[c.Name]; ok {
 			err := old.ApplyConfig(loki, promInstanceManager, c)
@@ -162,7 +183,8 @@ func (l *logLeveller) Enabled(target zapcore.Level) bool {
 
 func newMetricViews(reg prom_client.Registerer) ([]*view.View, error) {
 	obsMetrics := obsreportconfig.Configure(configtelemetry.LevelBasic)
-	err := view.Register(obsMetrics.Views...)
+	views := append(obsMetrics.Views, remotewriteexporter.MetricViews()...)
+	err := view.Register(views...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to register views: %w", err)
 	}
@@ -177,5 +199,5 @@ func newMetricViews(reg prom_client.Registerer) ([]*view.View, error) {
 
 	view.RegisterExporter(pe)
 
-	return obsMetrics.Views, nil
+	return views, nil
 }
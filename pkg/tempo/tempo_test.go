@@ -2,6 +2,10 @@ package tempo
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -134,6 +138,133 @@ configs:
 	}
 }
 
+func TestTempo_HeadersFiles(t *testing.T) {
+	defer func(prev time.Duration) { headersFilesCheckInterval = prev }(headersFilesCheckInterval)
+	headersFilesCheckInterval = 10 * time.Millisecond
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("old-key\n"), 0600))
+
+	tempoCfgText := util.Untab(fmt.Sprintf(`
+configs:
+- name: default
+  receivers:
+		otlp:
+			protocols:
+				grpc:
+					endpoint: %s
+	remote_write:
+	- endpoint: 127.0.0.1:80
+		insecure: true
+		headers_files:
+			x-api-key: %s
+	`, freeAddr(t), keyFile))
+
+	var cfg Config
+	dec := yaml.NewDecoder(strings.NewReader(tempoCfgText))
+	dec.SetStrict(true)
+	require.NoError(t, dec.Decode(&cfg))
+
+	tempo, err := New(nil, nil, prometheus.NewRegistry(), cfg, logrus.InfoLevel)
+	require.NoError(t, err)
+	t.Cleanup(tempo.Stop)
+
+	inst := tempo.instances["default"]
+	headersFiles := func() map[string]string {
+		inst.mut.Lock()
+		defer inst.mut.Unlock()
+		return inst.headersFiles
+	}
+	require.Equal(t, map[string]string{keyFile: "old-key"}, headersFiles())
+
+	// The pipeline is rebuilt once the file changes.
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("new-key\n"), 0600))
+	require.Eventually(t, func() bool {
+		return headersFiles()[keyFile] == "new-key"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTempo_ZipkinV1(t *testing.T) {
+	tracesCh := make(chan pdata.Traces)
+	tracesAddr := tempoutils.NewTestServer(t, func(t pdata.Traces) {
+		tracesCh <- t
+	})
+
+	zipkinAddr := freeAddr(t)
+
+	tempoCfgText := util.Untab(fmt.Sprintf(`
+configs:
+- name: default
+  receivers:
+		zipkin:
+			endpoint: %s
+			parse_string_tags: true
+	push_config:
+		endpoint: %s
+		insecure: true
+		batch:
+			timeout: 100ms
+			send_batch_size: 1
+	`, zipkinAddr, tracesAddr))
+
+	var cfg Config
+	dec := yaml.NewDecoder(strings.NewReader(tempoCfgText))
+	dec.SetStrict(true)
+	err := dec.Decode(&cfg)
+	require.NoError(t, err)
+
+	tempo, err := New(nil, nil, prometheus.NewRegistry(), cfg, logrus.InfoLevel)
+	require.NoError(t, err)
+	t.Cleanup(tempo.Stop)
+
+	v1Spans := `[{
+		"traceId": "4d1e00c0db9010db",
+		"id": "4d1e00c0db9010db",
+		"name": "get",
+		"timestamp": 1472470996199000,
+		"duration": 207000,
+		"binaryAnnotations": [{
+			"key": "http.status_code",
+			"value": "200",
+			"endpoint": {"serviceName": "frontend", "ipv4": "127.0.0.1"}
+		}]
+	}]`
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Post(fmt.Sprintf("http://%s/api/v1/spans", zipkinAddr), "application/json", strings.NewReader(v1Spans))
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusAccepted
+	}, 10*time.Second, 100*time.Millisecond)
+
+	select {
+	case <-time.After(30 * time.Second):
+		require.Fail(t, "failed to receive a span after 30 seconds")
+	case tr := <-tracesCh:
+		require.Equal(t, 1, tr.SpanCount())
+
+		span := tr.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0)
+		require.Equal(t, "get", span.Name())
+
+		// parse_string_tags converts the string tag into an int attribute.
+		status, ok := span.Attributes().Get("http.status_code")
+		require.True(t, ok)
+		require.Equal(t, pdata.AttributeValueTypeInt, status.Type())
+		require.Equal(t, int64(200), status.IntVal())
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
 func testJaegerTracer(t *testing.T) opentracing.Tracer {
 	t.Helper()
 
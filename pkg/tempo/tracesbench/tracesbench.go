@@ -0,0 +1,292 @@
+// Package tracesbench generates synthetic traces and sends them to an OTLP
+// gRPC endpoint, such as a receiver of a running traces instance, measuring
+// how many spans are accepted and how long requests take. It's used to tune
+// batch and queue settings of traces pipelines.
+package tracesbench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.opentelemetry.io/collector/exporter/otlpexporter"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// Config controls the load generated by Run.
+type Config struct {
+	// Endpoint is the host:port of the OTLP gRPC receiver to send traces to.
+	Endpoint string
+	// Insecure disables TLS.
+	Insecure bool
+	// Headers are added to every request.
+	Headers map[string]string
+
+	// Duration is how long traces are sent for.
+	Duration time.Duration
+	// Rate is the number of traces sent per second. Traces are sent as fast
+	// as possible when zero.
+	Rate int
+	// Workers is the number of concurrent requests.
+	Workers int
+	// TracesPerRequest is the number of traces sent in each request.
+	TracesPerRequest int
+	// SpansPerTrace is the number of spans of each trace.
+	SpansPerTrace int
+	// Timeout is the timeout of each request.
+	Timeout time.Duration
+}
+
+// DefaultConfig holds the default settings for Run.
+var DefaultConfig = Config{
+	Endpoint:         "localhost:4317",
+	Insecure:         true,
+	Duration:         30 * time.Second,
+	Workers:          4,
+	TracesPerRequest: 10,
+	SpansPerTrace:    10,
+	Timeout:          5 * time.Second,
+}
+
+// Validate returns an error if c can't be used to generate load.
+func (c *Config) Validate() error {
+	switch {
+	case c.Endpoint == "":
+		return errors.New("endpoint must not be empty")
+	case c.Duration <= 0:
+		return errors.New("duration must be greater than 0")
+	case c.Rate < 0:
+		return errors.New("rate must not be negative")
+	case c.Workers <= 0:
+		return errors.New("workers must be greater than 0")
+	case c.TracesPerRequest <= 0:
+		return errors.New("traces per request must be greater than 0")
+	case c.SpansPerTrace <= 0:
+		return errors.New("spans per trace must be greater than 0")
+	case c.Timeout <= 0:
+		return errors.New("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Result holds the measurements of a run.
+type Result struct {
+	// Duration is how long the run took.
+	Duration time.Duration
+
+	Requests       int
+	FailedRequests int
+	SentSpans      int
+	FailedSpans    int
+
+	// Latency percentiles of successful and failed requests.
+	LatencyP50 time.Duration
+	LatencyP90 time.Duration
+	LatencyP99 time.Duration
+	LatencyMax time.Duration
+}
+
+// SpansPerSecond returns the rate of spans accepted by the endpoint.
+func (r Result) SpansPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.SentSpans) / r.Duration.Seconds()
+}
+
+// Run sends traces to the endpoint of cfg until its duration elapsed or ctx
+// is canceled. Failed requests are counted and not retried.
+func Run(ctx context.Context, logger *zap.Logger, cfg Config) (Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	exporter, err := newExporter(ctx, logger, cfg)
+	if err != nil {
+		return Result{}, err
+	}
+	defer func() {
+		_ = exporter.Shutdown(context.Background())
+	}()
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if cfg.Rate > 0 {
+		// Requests are limited rather than traces, so the rate is divided by
+		// the number of traces per request.
+		requestRate := float64(cfg.Rate) / float64(cfg.TracesPerRequest)
+		limiter = rate.NewLimiter(rate.Limit(requestRate), 1)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	var (
+		mut       sync.Mutex
+		res       Result
+		latencies []time.Duration
+		wg        sync.WaitGroup
+	)
+
+	start := time.Now()
+	for w := 0; w < cfg.Workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+
+			gen := newGenerator(seed, cfg.SpansPerTrace)
+			for {
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+				td := gen.traces(cfg.TracesPerRequest)
+
+				reqStart := time.Now()
+				err := exporter.ConsumeTraces(ctx, td)
+				latency := time.Since(reqStart)
+
+				// Requests canceled by the end of the run aren't counted.
+				if ctx.Err() != nil {
+					return
+				}
+
+				mut.Lock()
+				res.Requests++
+				latencies = append(latencies, latency)
+				if err != nil {
+					res.FailedRequests++
+					res.FailedSpans += td.SpanCount()
+				} else {
+					res.SentSpans += td.SpanCount()
+				}
+				mut.Unlock()
+
+				if err != nil {
+					logger.Debug("failed to send traces", zap.Error(err))
+				}
+			}
+		}(rand.Int63())
+	}
+	wg.Wait()
+	res.Duration = time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	res.LatencyP50 = percentile(latencies, 0.5)
+	res.LatencyP90 = percentile(latencies, 0.9)
+	res.LatencyP99 = percentile(latencies, 0.99)
+	res.LatencyMax = percentile(latencies, 1)
+	return res, nil
+}
+
+// newExporter creates an OTLP exporter sending every request synchronously,
+// without a queue or retries, so latencies are the latencies of requests.
+func newExporter(ctx context.Context, logger *zap.Logger, cfg Config) (component.TracesExporter, error) {
+	factory := otlpexporter.NewFactory()
+
+	eCfg := factory.CreateDefaultConfig().(*otlpexporter.Config)
+	eCfg.Endpoint = cfg.Endpoint
+	eCfg.TLSSetting.Insecure = cfg.Insecure
+	for k, v := range cfg.Headers {
+		eCfg.Headers[k] = v
+	}
+	eCfg.Timeout = cfg.Timeout
+	eCfg.QueueSettings.Enabled = false
+	eCfg.RetrySettings.Enabled = false
+
+	exporter, err := factory.CreateTracesExporter(ctx, component.ExporterCreateSettings{Logger: logger}, eCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	if err := exporter.Start(ctx, host{}); err != nil {
+		return nil, fmt.Errorf("failed to start OTLP exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// percentile returns the q-th quantile of sorted latencies.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(q*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// generator generates synthetic traces. Each trace has a root span and
+// children of the root span, spread over a few services and operations.
+type generator struct {
+	rnd           *rand.Rand
+	spansPerTrace int
+}
+
+func newGenerator(seed int64, spansPerTrace int) *generator {
+	return &generator{rnd: rand.New(rand.NewSource(seed)), spansPerTrace: spansPerTrace}
+}
+
+const numServices = 5
+
+func (g *generator) traces(n int) pdata.Traces {
+	td := pdata.NewTraces()
+	now := time.Now()
+
+	for i := 0; i < n; i++ {
+		var traceID [16]byte
+		g.rnd.Read(traceID[:])
+
+		rs := td.ResourceSpans().AppendEmpty()
+		service := "tracesbench-" + strconv.Itoa(g.rnd.Intn(numServices))
+		rs.Resource().Attributes().UpsertString("service.name", service)
+		spans := rs.InstrumentationLibrarySpans().AppendEmpty().Spans()
+
+		var rootID [8]byte
+		for j := 0; j < g.spansPerTrace; j++ {
+			var spanID [8]byte
+			g.rnd.Read(spanID[:])
+
+			span := spans.AppendEmpty()
+			span.SetTraceID(pdata.NewTraceID(traceID))
+			span.SetSpanID(pdata.NewSpanID(spanID))
+			span.SetStartTimestamp(pdata.TimestampFromTime(now))
+			span.SetEndTimestamp(pdata.TimestampFromTime(now.Add(time.Duration(g.rnd.Intn(1000)) * time.Millisecond)))
+			span.Status().SetCode(pdata.StatusCodeOk)
+
+			if j == 0 {
+				rootID = spanID
+				span.SetName("GET /api")
+				span.SetKind(pdata.SpanKindServer)
+				continue
+			}
+			span.SetParentSpanID(pdata.NewSpanID(rootID))
+			span.SetName("query-" + strconv.Itoa(j%numServices))
+			span.SetKind(pdata.SpanKindClient)
+			span.Attributes().UpsertInt("bench.index", int64(j))
+		}
+	}
+	return td
+}
+
+// host is a component.Host without extensions or exporters.
+type host struct{}
+
+func (host) ReportFatalError(error) {}
+
+func (host) GetFactory(component.Kind, config.Type) component.Factory { return nil }
+
+func (host) GetExtensions() map[config.ComponentID]component.Extension { return nil }
+
+func (host) GetExporters() map[config.DataType]map[config.ComponentID]component.Exporter {
+	return nil
+}
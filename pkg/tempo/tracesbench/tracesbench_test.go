@@ -0,0 +1,70 @@
+package tracesbench
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/pkg/tempo/internal/tempoutils"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+func TestRun(t *testing.T) {
+	var received atomic.Int64
+	addr := tempoutils.NewTestServer(t, func(td pdata.Traces) {
+		received.Add(int64(td.SpanCount()))
+	})
+
+	cfg := DefaultConfig
+	cfg.Endpoint = addr
+	cfg.Duration = time.Second
+	cfg.Rate = 100
+	cfg.Workers = 2
+	cfg.TracesPerRequest = 5
+	cfg.SpansPerTrace = 3
+
+	res, err := Run(context.Background(), zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	require.Zero(t, res.FailedRequests)
+	require.Greater(t, res.Requests, 0)
+	require.Equal(t, res.Requests*5*3, res.SentSpans)
+	require.Equal(t, int64(res.SentSpans), received.Load())
+	// The limiter allows about 20 requests per second.
+	require.LessOrEqual(t, res.Requests, 25)
+	require.LessOrEqual(t, res.LatencyP50, res.LatencyMax)
+	require.Greater(t, res.SpansPerSecond(), 0.0)
+}
+
+func TestRun_Unreachable(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Endpoint = "127.0.0.1:1"
+	cfg.Duration = 500 * time.Millisecond
+	cfg.Rate = 200
+	cfg.Timeout = 100 * time.Millisecond
+
+	res, err := Run(context.Background(), zap.NewNop(), cfg)
+	require.NoError(t, err)
+	require.Greater(t, res.Requests, 1)
+	require.Zero(t, res.SentSpans)
+	require.Equal(t, res.Requests, res.FailedRequests)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := DefaultConfig
+	require.NoError(t, cfg.Validate())
+
+	cfg.Workers = 0
+	require.EqualError(t, cfg.Validate(), "workers must be greater than 0")
+}
+
+func BenchmarkGenerator(b *testing.B) {
+	gen := newGenerator(0, 10)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		gen.traces(10)
+	}
+}
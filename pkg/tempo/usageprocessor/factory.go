@@ -0,0 +1,52 @@
+package usageprocessor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+// TypeStr is the unique identifier for the Usage processor.
+const TypeStr = "usage"
+
+// Config holds the configuration for the Usage processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// ReceivedSpans is incremented by the number of spans passing through
+	// the processor.
+	ReceivedSpans prometheus.Counter `mapstructure:"-"`
+}
+
+// NewFactory returns a new factory for the Usage processor.
+func NewFactory() component.ProcessorFactory {
+	return processorhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		processorhelper.WithTraces(createTraceProcessor),
+	)
+}
+
+func createDefaultConfig() config.Processor {
+	return &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(TypeStr, TypeStr)),
+	}
+}
+
+func createTraceProcessor(
+	_ context.Context,
+	_ component.ProcessorCreateSettings,
+	cfg config.Processor,
+	nextConsumer consumer.Traces,
+) (component.TracesProcessor, error) {
+	usageCfg := cfg.(*Config)
+	if usageCfg.ReceivedSpans == nil {
+		return nil, fmt.Errorf("usage processor requires a counter for received spans")
+	}
+	return newTraceProcessor(nextConsumer, usageCfg.ReceivedSpans)
+}
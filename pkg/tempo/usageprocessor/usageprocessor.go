@@ -0,0 +1,41 @@
+// Package usageprocessor implements a processor which counts the spans
+// received by a traces instance, used to report the usage of the instance.
+package usageprocessor
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenterror"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+type usageProcessor struct {
+	nextConsumer  consumer.Traces
+	receivedSpans prometheus.Counter
+}
+
+func newTraceProcessor(nextConsumer consumer.Traces, receivedSpans prometheus.Counter) (component.TracesProcessor, error) {
+	if nextConsumer == nil {
+		return nil, componenterror.ErrNilNextConsumer
+	}
+	return &usageProcessor{nextConsumer: nextConsumer, receivedSpans: receivedSpans}, nil
+}
+
+// ConsumeTraces counts the spans of td and passes it to the next consumer.
+func (p *usageProcessor) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	p.receivedSpans.Add(float64(td.SpanCount()))
+	return p.nextConsumer.ConsumeTraces(ctx, td)
+}
+
+func (p *usageProcessor) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+// Start is invoked during service startup.
+func (p *usageProcessor) Start(context.Context, component.Host) error { return nil }
+
+// Shutdown is invoked during service shutdown.
+func (p *usageProcessor) Shutdown(context.Context) error { return nil }
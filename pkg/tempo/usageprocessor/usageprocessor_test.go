@@ -0,0 +1,31 @@
+package usageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+func TestConsumeTraces(t *testing.T) {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "received_spans_total"})
+	next := new(consumertest.TracesSink)
+
+	p, err := newTraceProcessor(next, counter)
+	require.NoError(t, err)
+
+	td := pdata.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().InstrumentationLibrarySpans().AppendEmpty().Spans()
+	spans.AppendEmpty()
+	spans.AppendEmpty()
+
+	require.NoError(t, p.ConsumeTraces(context.Background(), td))
+	require.NoError(t, p.ConsumeTraces(context.Background(), pdata.NewTraces()))
+
+	require.Equal(t, 2.0, testutil.ToFloat64(counter))
+	require.Equal(t, 2, next.SpansCount())
+}
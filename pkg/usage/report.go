@@ -0,0 +1,471 @@
+package usage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/agent/pkg/loki"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/tempo"
+	dto "github.com/prometheus/client_model/go"
+	prom_config "github.com/prometheus/common/config"
+)
+
+// AnonymousTenant is used for data sent without a tenant.
+const AnonymousTenant = "anonymous"
+
+// tenantHeader is the header used by Cortex, Loki, and Tempo to identify the
+// tenant that data is written for.
+const tenantHeader = "X-Scope-OrgID"
+
+// Names of the metrics usage is calculated from.
+const (
+	activeSeriesMetric    = "agent_wal_storage_active_series"
+	samplesAppendedMetric = "agent_wal_samples_appended_total"
+	samplesSentMetric     = "prometheus_remote_storage_samples_total"
+	spansReceivedMetric   = "agent_traces_received_spans_total"
+	logLinesSentMetric    = "promtail_sent_entries_total"
+	logBytesSentMetric    = "promtail_sent_bytes_total"
+	logLinesSpooledMetric = "agent_logs_spool_sent_entries_total"
+	logBytesSpooledMetric = "agent_logs_spool_sent_bytes_total"
+)
+
+// Snapshot is the usage of every instance and tenant at a point in time.
+type Snapshot struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Instances []InstanceUsage `json:"instances"`
+	Tenants   []TenantUsage   `json:"tenants"`
+}
+
+// Usage is the amount of data collected. Rates are per second and averaged
+// over the interval usage is calculated at.
+type Usage struct {
+	ActiveSeries      float64 `json:"active_series,omitempty"`
+	SamplesPerSecond  float64 `json:"samples_per_second,omitempty"`
+	SpansPerSecond    float64 `json:"spans_per_second,omitempty"`
+	LogLinesPerSecond float64 `json:"log_lines_per_second,omitempty"`
+	LogBytesPerSecond float64 `json:"log_bytes_per_second,omitempty"`
+}
+
+// InstanceUsage is the usage of an instance of a subsystem.
+type InstanceUsage struct {
+	// Subsystem is one of prometheus, loki, or tempo.
+	Subsystem string `json:"subsystem"`
+	Name      string `json:"name"`
+	// Tenants the instance sends data to.
+	Tenants []string `json:"tenants"`
+	Usage
+}
+
+// TenantUsage is the usage of a tenant summed over all instances.
+type TenantUsage struct {
+	Tenant string `json:"tenant"`
+	Usage
+}
+
+// tenantDelta is how much a tenant's counters increased since the previous
+// calculation.
+type tenantDelta struct {
+	samples, spans, logLines, logBytes float64
+}
+
+// tenantResolver finds the tenants of instances from their configs.
+type tenantResolver struct {
+	// remoteWrites maps the names of Prometheus remote_write configs to their
+	// tenant. splitRemoteWrites maps the names of configs with a tenant_label
+	// to their name prefix; the tenant is the rest of the queue name.
+	remoteWrites      map[string]string
+	splitRemoteWrites map[string]string
+
+	// lokiHosts maps the hosts of Loki clients to the instances and tenant
+	// using them. lokiSpools maps the names of Loki instances with a spool to
+	// the tenant of each client's host, since spools report metrics per
+	// instance.
+	lokiHosts  map[string]*lokiHost
+	lokiSpools map[string]map[string]string
+
+	// tempoInstances maps Tempo instance names to their tenants.
+	tempoInstances map[string][]string
+}
+
+func newTenantResolver(promConfigs map[string]instance.Config, lokiCfg loki.Config, tempoCfg tempo.Config) *tenantResolver {
+	r := &tenantResolver{
+		remoteWrites:      make(map[string]string),
+		splitRemoteWrites: make(map[string]string),
+		lokiHosts:         make(map[string]*lokiHost),
+		lokiSpools:        make(map[string]map[string]string),
+		tempoInstances:    make(map[string][]string),
+	}
+
+	for _, c := range promConfigs {
+		for _, rw := range c.RemoteWrite {
+			if rw == nil {
+				continue
+			}
+			r.remoteWrites[rw.Name] = tenantOf(rw.Headers, rw.HTTPClientConfig.BasicAuth)
+			if rw.TenantLabel != "" {
+				r.splitRemoteWrites[rw.Name] = rw.Name + "-"
+			}
+		}
+	}
+
+	for _, c := range lokiCfg.Configs {
+		if c == nil {
+			continue
+		}
+		for _, cc := range c.ClientConfigs {
+			if cc.URL.URL == nil {
+				continue
+			}
+			tenant := cc.TenantID
+			if tenant == "" {
+				tenant = tenantOf(nil, cc.Client.BasicAuth)
+			}
+
+			if c.Spool != nil {
+				if r.lokiSpools[c.Name] == nil {
+					r.lokiSpools[c.Name] = make(map[string]string)
+				}
+				r.lokiSpools[c.Name][cc.URL.Host] = tenant
+				continue
+			}
+
+			h, ok := r.lokiHosts[cc.URL.Host]
+			if !ok {
+				h = &lokiHost{tenant: tenant}
+				r.lokiHosts[cc.URL.Host] = h
+			} else if h.tenant != tenant {
+				// Instances send to the same host as different tenants, so the
+				// tenant of the host's data can't be known.
+				h.tenant = AnonymousTenant
+			}
+			h.instances = append(h.instances, c.Name)
+		}
+	}
+
+	for _, c := range tempoCfg.Configs {
+		var tenants []string
+		for _, rw := range c.RemoteWrite {
+			tenants = append(tenants, tenantOf(rw.Headers, rw.BasicAuth))
+		}
+		if c.PushConfig.Endpoint != "" {
+			tenants = append(tenants, tenantOf(nil, c.PushConfig.BasicAuth))
+		}
+		r.tempoInstances[c.Name] = uniqueSorted(tenants)
+	}
+
+	return r
+}
+
+// tenantOf returns the tenant of a client sending data with the given headers
+// and basic auth. The tenant header takes precedence over the basic auth
+// username, which is used as the tenant by hosted services.
+func tenantOf(headers map[string]string, basicAuth *prom_config.BasicAuth) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, tenantHeader) && v != "" {
+			return v
+		}
+	}
+	if basicAuth != nil && basicAuth.Username != "" {
+		return basicAuth.Username
+	}
+	return AnonymousTenant
+}
+
+// remoteWriteTenant returns the tenant of a Prometheus remote_write queue.
+// split is true when the queue only sends some of the series of its instance
+// because of a tenant_label.
+func (r *tenantResolver) remoteWriteTenant(queue string) (tenant string, split bool) {
+	if tenant, ok := r.remoteWrites[queue]; ok {
+		// The queue of a config with a tenant_label only sends series without
+		// the label.
+		_, split := r.splitRemoteWrites[queue]
+		return tenant, split
+	}
+	for _, prefix := range r.splitRemoteWrites {
+		if strings.HasPrefix(queue, prefix) {
+			return strings.TrimPrefix(queue, prefix), true
+		}
+	}
+	return AnonymousTenant, false
+}
+
+// lokiHost is a host that Loki clients send to.
+type lokiHost struct {
+	instances []string
+	tenant    string
+}
+
+// lokiHost returns the name to report usage of a Loki client host under and
+// the tenant of the host. Clients of different instances share metrics when
+// they send to the same host, so their usage is reported together under the
+// name of the host.
+func (r *tenantResolver) lokiHost(host string) (name, tenant string) {
+	h, ok := r.lokiHosts[host]
+	switch {
+	case !ok:
+		return host, AnonymousTenant
+	case len(h.instances) == 1:
+		return h.instances[0], h.tenant
+	default:
+		return host, h.tenant
+	}
+}
+
+// lokiSpoolTenant returns the tenant of the client of a Loki instance with a
+// spool sending to host.
+func (r *tenantResolver) lokiSpoolTenant(instance, host string) string {
+	if tenant, ok := r.lokiSpools[instance][host]; ok {
+		return tenant
+	}
+	return AnonymousTenant
+}
+
+func (r *tenantResolver) tempoTenants(instance string) []string {
+	if tenants, ok := r.tempoInstances[instance]; ok {
+		return tenants
+	}
+	return []string{AnonymousTenant}
+}
+
+// calculator calculates rates from the counters of consecutive calculations.
+type calculator struct {
+	prevTime     time.Time
+	prevCounters map[string]float64
+}
+
+func newCalculator() *calculator {
+	return &calculator{prevCounters: make(map[string]float64)}
+}
+
+// calculate returns the usage found in families and how much the counters of
+// every tenant increased since the previous call.
+func (c *calculator) calculate(now time.Time, families []*dto.MetricFamily, r *tenantResolver) (*Snapshot, map[string]*tenantDelta) {
+	var (
+		instances = make(map[instanceKey]*InstanceUsage)
+		deltas    = make(map[string]*tenantDelta)
+		counters  = make(map[string]float64, len(c.prevCounters))
+	)
+
+	getInstance := func(subsystem, name string) *InstanceUsage {
+		key := instanceKey{subsystem, name}
+		iu, ok := instances[key]
+		if !ok {
+			iu = &InstanceUsage{Subsystem: subsystem, Name: name}
+			instances[key] = iu
+		}
+		return iu
+	}
+	getDelta := func(tenant string) *tenantDelta {
+		d, ok := deltas[tenant]
+		if !ok {
+			d = &tenantDelta{}
+			deltas[tenant] = d
+		}
+		return d
+	}
+
+	// increase returns how much a counter increased since the previous
+	// calculation. Counters seen for the first time haven't increased.
+	increase := func(key string, value float64) float64 {
+		counters[key] = value
+		prev, ok := c.prevCounters[key]
+		switch {
+		case !ok:
+			return 0
+		case value < prev:
+			// The counter was reset.
+			return value
+		default:
+			return value - prev
+		}
+	}
+
+	// Active series are attributed to every tenant the series are sent to,
+	// which are only known once the remote_write queues are found.
+	var (
+		activeSeries  = make(map[string]float64)
+		seriesTenants = make(map[string]map[string]struct{})
+		promTenants   = make(map[string][]string)
+	)
+
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			switch mf.GetName() {
+			case activeSeriesMetric:
+				name := promInstanceName(m)
+				getInstance("prometheus", name).ActiveSeries += m.GetGauge().GetValue()
+				activeSeries[name] += m.GetGauge().GetValue()
+
+			case samplesAppendedMetric:
+				name := promInstanceName(m)
+				getInstance("prometheus", name).SamplesPerSecond += increase(metricKey(mf, m), m.GetCounter().GetValue())
+
+			case samplesSentMetric:
+				name := promInstanceName(m)
+				tenant, split := r.remoteWriteTenant(labelValue(m, "remote_name"))
+				getDelta(tenant).samples += increase(metricKey(mf, m), m.GetCounter().GetValue())
+				getInstance("prometheus", name)
+				promTenants[name] = append(promTenants[name], tenant)
+				if !split {
+					// Series of queues split by tenant_label are a subset of the
+					// instance's series, so only whole queues are attributed
+					// active series.
+					if seriesTenants[name] == nil {
+						seriesTenants[name] = make(map[string]struct{})
+					}
+					seriesTenants[name][tenant] = struct{}{}
+				}
+
+			case spansReceivedMetric:
+				name := labelValue(m, "tempo_config")
+				spans := increase(metricKey(mf, m), m.GetCounter().GetValue())
+				iu := getInstance("tempo", name)
+				iu.SpansPerSecond += spans
+				iu.Tenants = r.tempoTenants(name)
+				for _, tenant := range iu.Tenants {
+					getDelta(tenant).spans += spans
+				}
+
+			case logLinesSentMetric, logBytesSentMetric, logLinesSpooledMetric, logBytesSpooledMetric:
+				value := increase(metricKey(mf, m), m.GetCounter().GetValue())
+				name, tenant := r.lokiHost(labelValue(m, "host"))
+				if spooled := labelValue(m, "loki_config"); spooled != "" {
+					name, tenant = spooled, r.lokiSpoolTenant(spooled, labelValue(m, "host"))
+				}
+				iu := getInstance("loki", name)
+				iu.Tenants = append(iu.Tenants, tenant)
+				if mf.GetName() == logLinesSentMetric || mf.GetName() == logLinesSpooledMetric {
+					iu.LogLinesPerSecond += value
+					getDelta(tenant).logLines += value
+				} else {
+					iu.LogBytesPerSecond += value
+					getDelta(tenant).logBytes += value
+				}
+			}
+		}
+	}
+
+	tenantUsage := make(map[string]*TenantUsage)
+	getTenant := func(tenant string) *TenantUsage {
+		tu, ok := tenantUsage[tenant]
+		if !ok {
+			tu = &TenantUsage{Tenant: tenant}
+			tenantUsage[tenant] = tu
+		}
+		return tu
+	}
+
+	for name, tenants := range promTenants {
+		instances[instanceKey{"prometheus", name}].Tenants = tenants
+	}
+	for name, tenants := range seriesTenants {
+		for tenant := range tenants {
+			getTenant(tenant).ActiveSeries += activeSeries[name]
+		}
+	}
+
+	// Convert increases to rates. There's nothing to compare against on the
+	// first calculation.
+	var elapsed float64
+	if !c.prevTime.IsZero() {
+		elapsed = now.Sub(c.prevTime).Seconds()
+	}
+	perSecond := func(v float64) float64 {
+		if elapsed <= 0 {
+			return 0
+		}
+		return v / elapsed
+	}
+
+	snapshot := &Snapshot{
+		Timestamp: now,
+		Instances: make([]InstanceUsage, 0, len(instances)),
+	}
+	for _, iu := range instances {
+		iu.Tenants = uniqueSorted(iu.Tenants)
+		iu.SamplesPerSecond = perSecond(iu.SamplesPerSecond)
+		iu.SpansPerSecond = perSecond(iu.SpansPerSecond)
+		iu.LogLinesPerSecond = perSecond(iu.LogLinesPerSecond)
+		iu.LogBytesPerSecond = perSecond(iu.LogBytesPerSecond)
+		snapshot.Instances = append(snapshot.Instances, *iu)
+	}
+	sort.Slice(snapshot.Instances, func(i, j int) bool {
+		a, b := snapshot.Instances[i], snapshot.Instances[j]
+		if a.Subsystem != b.Subsystem {
+			return a.Subsystem < b.Subsystem
+		}
+		return a.Name < b.Name
+	})
+
+	for tenant, d := range deltas {
+		tu := getTenant(tenant)
+		tu.SamplesPerSecond = perSecond(d.samples)
+		tu.SpansPerSecond = perSecond(d.spans)
+		tu.LogLinesPerSecond = perSecond(d.logLines)
+		tu.LogBytesPerSecond = perSecond(d.logBytes)
+	}
+	snapshot.Tenants = make([]TenantUsage, 0, len(tenantUsage))
+	for _, tu := range tenantUsage {
+		snapshot.Tenants = append(snapshot.Tenants, *tu)
+	}
+	sort.Slice(snapshot.Tenants, func(i, j int) bool {
+		return snapshot.Tenants[i].Tenant < snapshot.Tenants[j].Tenant
+	})
+
+	c.prevTime, c.prevCounters = now, counters
+	return snapshot, deltas
+}
+
+type instanceKey struct {
+	subsystem, name string
+}
+
+// promInstanceName returns the name of the Prometheus instance a metric is
+// for. Instances are labeled by their group when running in shared mode.
+func promInstanceName(m *dto.Metric) string {
+	if name := labelValue(m, "instance_name"); name != "" {
+		return name
+	}
+	return labelValue(m, "instance_group_name")
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+// metricKey uniquely identifies a series of a family.
+func metricKey(mf *dto.MetricFamily, m *dto.Metric) string {
+	var sb strings.Builder
+	sb.WriteString(mf.GetName())
+	for _, l := range m.GetLabel() {
+		sb.WriteString("\xff")
+		sb.WriteString(l.GetName())
+		sb.WriteString("=")
+		sb.WriteString(l.GetValue())
+	}
+	return sb.String()
+}
+
+func uniqueSorted(ss []string) []string {
+	if len(ss) == 0 {
+		return ss
+	}
+	set := make(map[string]struct{}, len(ss))
+	res := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if _, ok := set[s]; ok {
+			continue
+		}
+		set[s] = struct{}{}
+		res = append(res, s)
+	}
+	sort.Strings(res)
+	return res
+}
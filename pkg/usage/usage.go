@@ -0,0 +1,224 @@
+// Package usage reports how much data the Agent collects per instance and per
+// tenant. It allows platform teams to attribute the cost of the data sent by
+// a fleet of Agents to the tenants receiving it.
+package usage
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/loki"
+	"github.com/grafana/agent/pkg/prom/cluster/configapi"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/tempo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Config controls tracking usage.
+type Config struct {
+	// Interval is how often usage is calculated. 0 disables tracking usage.
+	Interval time.Duration
+}
+
+// RegisterFlags registers flags for the Config.
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.DurationVar(&c.Interval, "usage.interval", time.Minute, "How often to calculate usage per instance and tenant. 0 disables tracking usage.")
+}
+
+// Tracker periodically calculates the usage of every instance from the
+// metrics of the Agent and attributes it to tenants.
+type Tracker struct {
+	log      log.Logger
+	im       instance.Manager
+	gatherer prometheus.Gatherer
+
+	mut      sync.Mutex
+	cfg      Config
+	lokiCfg  loki.Config
+	tempoCfg tempo.Config
+	stop     chan struct{}
+	done     chan struct{}
+	stopped  bool
+
+	calc     *calculator
+	snapshot *Snapshot
+
+	tenantActiveSeries *prometheus.GaugeVec
+	tenantSamples      *prometheus.CounterVec
+	tenantSpans        *prometheus.CounterVec
+	tenantLogLines     *prometheus.CounterVec
+	tenantLogBytes     *prometheus.CounterVec
+}
+
+// New creates a new Tracker. Usage is calculated from the metrics of
+// gatherer, and the remote_write configs of the instances of im are used to
+// find their tenants; im may be nil when metrics aren't collected. Metrics
+// with the usage of every tenant are registered to reg. The Tracker does
+// nothing until ApplyConfig is called.
+func New(l log.Logger, im instance.Manager, gatherer prometheus.Gatherer, reg prometheus.Registerer) (*Tracker, error) {
+	t := &Tracker{
+		log:      log.With(l, "component", "usage"),
+		im:       im,
+		gatherer: gatherer,
+		calc:     newCalculator(),
+
+		tenantActiveSeries: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "agent_usage_tenant_active_series",
+			Help: "Active series sent to a tenant.",
+		}, []string{"tenant"}),
+		tenantSamples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_usage_tenant_samples_total",
+			Help: "Total number of samples sent to a tenant.",
+		}, []string{"tenant"}),
+		tenantSpans: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_usage_tenant_spans_total",
+			Help: "Total number of spans received for a tenant.",
+		}, []string{"tenant"}),
+		tenantLogLines: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_usage_tenant_log_lines_total",
+			Help: "Total number of log lines sent to a tenant.",
+		}, []string{"tenant"}),
+		tenantLogBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_usage_tenant_log_bytes_total",
+			Help: "Total number of bytes of log lines sent to a tenant.",
+		}, []string{"tenant"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		t.tenantActiveSeries, t.tenantSamples, t.tenantSpans, t.tenantLogLines, t.tenantLogBytes,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// ApplyConfig updates the Tracker. The Loki and Tempo configs are used to
+// find the tenants that logs and traces are sent to.
+func (t *Tracker) ApplyConfig(cfg Config, lokiCfg loki.Config, tempoCfg tempo.Config) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.stopped {
+		return errors.New("tracker stopped")
+	}
+
+	t.lokiCfg, t.tempoCfg = lokiCfg, tempoCfg
+	if t.cfg == cfg {
+		return nil
+	}
+	t.cfg = cfg
+
+	t.stopLoop()
+	if cfg.Interval > 0 {
+		t.stop, t.done = make(chan struct{}), make(chan struct{})
+		go t.run(cfg.Interval, t.stop, t.done)
+	}
+	return nil
+}
+
+// stopLoop stops calculating usage. mut must be held.
+func (t *Tracker) stopLoop() {
+	if t.stop == nil {
+		return
+	}
+	close(t.stop)
+	<-t.done
+	t.stop, t.done = nil, nil
+}
+
+func (t *Tracker) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Update(time.Now()); err != nil {
+				level.Error(t.log).Log("msg", "failed to calculate usage", "err", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Update calculates the current usage. Rates are calculated from the
+// previous call to Update.
+func (t *Tracker) Update(now time.Time) error {
+	// Gather without holding the lock; gathering may take a while.
+	families, err := t.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	var promConfigs map[string]instance.Config
+	if t.im != nil {
+		promConfigs = t.im.ListConfigs()
+	}
+	tenants := newTenantResolver(promConfigs, t.lokiCfg, t.tempoCfg)
+	snapshot, deltas := t.calc.calculate(now, families, tenants)
+	t.snapshot = snapshot
+
+	t.tenantActiveSeries.Reset()
+	for _, tu := range snapshot.Tenants {
+		t.tenantActiveSeries.WithLabelValues(tu.Tenant).Set(tu.ActiveSeries)
+	}
+	for tenant, d := range deltas {
+		t.tenantSamples.WithLabelValues(tenant).Add(d.samples)
+		t.tenantSpans.WithLabelValues(tenant).Add(d.spans)
+		t.tenantLogLines.WithLabelValues(tenant).Add(d.logLines)
+		t.tenantLogBytes.WithLabelValues(tenant).Add(d.logBytes)
+	}
+	return nil
+}
+
+// Snapshot returns the most recently calculated usage. Returns nil if usage
+// hasn't been calculated yet.
+func (t *Tracker) Snapshot() *Snapshot {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.snapshot
+}
+
+// WireAPI adds API routes to the provided mux router.
+func (t *Tracker) WireAPI(r *mux.Router) {
+	r.HandleFunc("/agent/api/v1/usage", t.SnapshotHandler).Methods("GET")
+}
+
+// SnapshotHandler writes the most recently calculated usage.
+func (t *Tracker) SnapshotHandler(w http.ResponseWriter, _ *http.Request) {
+	t.mut.Lock()
+	enabled, snapshot := t.cfg.Interval > 0, t.snapshot
+	t.mut.Unlock()
+
+	switch {
+	case !enabled:
+		_ = configapi.WriteError(w, http.StatusNotFound, errors.New("tracking usage is disabled"))
+	case snapshot == nil:
+		_ = configapi.WriteError(w, http.StatusServiceUnavailable, errors.New("usage hasn't been calculated yet"))
+	default:
+		_ = configapi.WriteResponse(w, http.StatusOK, snapshot)
+	}
+}
+
+// Stop stops the Tracker.
+func (t *Tracker) Stop() {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.stopLoop()
+	t.stopped = true
+}
@@ -0,0 +1,149 @@
+package usage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/util/flagext"
+	"github.com/go-kit/kit/log"
+	"github.com/grafana/agent/pkg/loki"
+	"github.com/grafana/agent/pkg/loki/spool"
+	"github.com/grafana/agent/pkg/prom/instance"
+	"github.com/grafana/agent/pkg/tempo"
+	"github.com/grafana/loki/clients/pkg/promtail/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	prom_config "github.com/prometheus/common/config"
+	"github.com/prometheus/prometheus/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	// Metrics normally registered by the subsystems.
+	src := prometheus.NewRegistry()
+	activeSeries := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: activeSeriesMetric}, []string{"instance_name"})
+	samplesSent := prometheus.NewCounterVec(prometheus.CounterOpts{Name: samplesSentMetric}, []string{"instance_name", "remote_name", "url"})
+	spansReceived := prometheus.NewCounterVec(prometheus.CounterOpts{Name: spansReceivedMetric}, []string{"tempo_config"})
+	logLinesSent := prometheus.NewCounterVec(prometheus.CounterOpts{Name: logLinesSentMetric}, []string{"host"})
+	logLinesSpooled := prometheus.NewCounterVec(prometheus.CounterOpts{Name: logLinesSpooledMetric}, []string{"loki_config", "host"})
+	src.MustRegister(activeSeries, samplesSent, spansReceived, logLinesSent, logLinesSpooled)
+
+	im := instance.MockManager{
+		ListConfigsFunc: func() map[string]instance.Config {
+			return map[string]instance.Config{
+				"metrics": {
+					Name: "metrics",
+					RemoteWrite: []*instance.RemoteWriteConfig{
+						{RemoteWriteConfig: config.RemoteWriteConfig{
+							Name:             "metrics-abc",
+							HTTPClientConfig: prom_config.HTTPClientConfig{BasicAuth: &prom_config.BasicAuth{Username: "team-a"}},
+						}},
+						{RemoteWriteConfig: config.RemoteWriteConfig{Name: "split"}, TenantLabel: "team"},
+					},
+				},
+			}
+		},
+	}
+
+	lokiURL, err := url.Parse("http://loki:3100/loki/api/v1/push")
+	require.NoError(t, err)
+	lokiCfg := loki.Config{Configs: []*loki.InstanceConfig{
+		{
+			Name:          "logs",
+			ClientConfigs: []client.Config{{URL: flagext.URLValue{URL: lokiURL}, TenantID: "team-a"}},
+		},
+		{
+			Name:          "spooled-logs",
+			ClientConfigs: []client.Config{{URL: flagext.URLValue{URL: lokiURL}, TenantID: "team-b"}},
+			Spool:         &spool.Config{},
+		},
+	}}
+	tempoCfg := tempo.Config{Configs: []tempo.InstanceConfig{{
+		Name: "traces",
+		RemoteWrite: []tempo.RemoteWriteConfig{
+			{Endpoint: "tempo-a:55680", Headers: map[string]string{"X-Scope-OrgID": "team-a"}},
+			{Endpoint: "tempo-b:55680"},
+		},
+	}}}
+
+	reg := prometheus.NewRegistry()
+	tr, err := New(log.NewNopLogger(), im, src, reg)
+	require.NoError(t, err)
+	defer tr.Stop()
+
+	// Calculate usage manually instead of on an interval.
+	require.NoError(t, tr.ApplyConfig(Config{}, lokiCfg, tempoCfg))
+	tr.cfg.Interval = time.Hour
+
+	activeSeries.WithLabelValues("metrics").Set(1000)
+	samplesSent.WithLabelValues("metrics", "metrics-abc", "").Add(100)
+	samplesSent.WithLabelValues("metrics", "split-team-b", "").Add(100)
+	spansReceived.WithLabelValues("traces").Add(100)
+	logLinesSent.WithLabelValues("loki:3100").Add(100)
+	logLinesSpooled.WithLabelValues("spooled-logs", "loki:3100").Add(100)
+
+	start := time.Now()
+	require.NoError(t, tr.Update(start))
+
+	samplesSent.WithLabelValues("metrics", "metrics-abc", "").Add(100)
+	samplesSent.WithLabelValues("metrics", "split-team-b", "").Add(50)
+	spansReceived.WithLabelValues("traces").Add(20)
+	logLinesSent.WithLabelValues("loki:3100").Add(10)
+	logLinesSpooled.WithLabelValues("spooled-logs", "loki:3100").Add(20)
+
+	require.NoError(t, tr.Update(start.Add(10*time.Second)))
+
+	snapshot := tr.Snapshot()
+	require.Equal(t, []InstanceUsage{
+		{Subsystem: "loki", Name: "logs", Tenants: []string{"team-a"}, Usage: Usage{LogLinesPerSecond: 1}},
+		{Subsystem: "loki", Name: "spooled-logs", Tenants: []string{"team-b"}, Usage: Usage{LogLinesPerSecond: 2}},
+		{Subsystem: "prometheus", Name: "metrics", Tenants: []string{"team-a", "team-b"}, Usage: Usage{ActiveSeries: 1000}},
+		{Subsystem: "tempo", Name: "traces", Tenants: []string{AnonymousTenant, "team-a"}, Usage: Usage{SpansPerSecond: 2}},
+	}, snapshot.Instances)
+	require.Equal(t, []TenantUsage{
+		{Tenant: AnonymousTenant, Usage: Usage{SpansPerSecond: 2}},
+		{Tenant: "team-a", Usage: Usage{ActiveSeries: 1000, SamplesPerSecond: 10, SpansPerSecond: 2, LogLinesPerSecond: 1}},
+		{Tenant: "team-b", Usage: Usage{SamplesPerSecond: 5, LogLinesPerSecond: 2}},
+	}, snapshot.Tenants)
+
+	require.Equal(t, 1000.0, testutil.ToFloat64(tr.tenantActiveSeries.WithLabelValues("team-a")))
+	require.Equal(t, 100.0, testutil.ToFloat64(tr.tenantSamples.WithLabelValues("team-a")))
+	require.Equal(t, 50.0, testutil.ToFloat64(tr.tenantSamples.WithLabelValues("team-b")))
+	require.Equal(t, 20.0, testutil.ToFloat64(tr.tenantSpans.WithLabelValues(AnonymousTenant)))
+	require.Equal(t, 10.0, testutil.ToFloat64(tr.tenantLogLines.WithLabelValues("team-a")))
+
+	// A reset counter counts from 0.
+	samplesSent.Reset()
+	samplesSent.WithLabelValues("metrics", "metrics-abc", "").Add(30)
+	require.NoError(t, tr.Update(start.Add(20*time.Second)))
+	require.Equal(t, 130.0, testutil.ToFloat64(tr.tenantSamples.WithLabelValues("team-a")))
+
+	rw := httptest.NewRecorder()
+	tr.SnapshotHandler(rw, httptest.NewRequest(http.MethodGet, "/agent/api/v1/usage", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	var resp struct {
+		Status string   `json:"status"`
+		Data   Snapshot `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rw.Body).Decode(&resp))
+	require.Equal(t, "success", resp.Status)
+	require.Len(t, resp.Data.Tenants, 3)
+	require.Equal(t, 3.0, resp.Data.Tenants[1].SamplesPerSecond)
+}
+
+func TestTracker_Disabled(t *testing.T) {
+	tr, err := New(log.NewNopLogger(), instance.MockManager{}, prometheus.NewRegistry(), prometheus.NewRegistry())
+	require.NoError(t, err)
+	defer tr.Stop()
+
+	require.NoError(t, tr.ApplyConfig(Config{}, loki.Config{}, tempo.Config{}))
+
+	rw := httptest.NewRecorder()
+	tr.SnapshotHandler(rw, httptest.NewRequest(http.MethodGet, "/agent/api/v1/usage", nil))
+	require.Equal(t, http.StatusNotFound, rw.Code)
+}
@@ -0,0 +1,65 @@
+// Package kafka holds settings shared by components which connect to Kafka
+// brokers.
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/common/config"
+)
+
+// Authentication configures how to connect to the Kafka brokers.
+type Authentication struct {
+	// TLSConfig enables connecting to the brokers over TLS when set.
+	TLSConfig *config.TLSConfig `yaml:"tls_config,omitempty"`
+	// SASLConfig enables SASL authentication when set.
+	SASLConfig *SASLConfig `yaml:"sasl_config,omitempty"`
+}
+
+// SASLConfig configures SASL authentication.
+type SASLConfig struct {
+	// Mechanism is one of PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512.
+	Mechanism string        `yaml:"mechanism"`
+	User      string        `yaml:"user"`
+	Password  config.Secret `yaml:"password"`
+}
+
+// Apply sets the TLS and SASL settings of cfg.
+func (a *Authentication) Apply(cfg *sarama.Config) error {
+	if tc := a.TLSConfig; tc != nil {
+		tlsConfig, err := config.NewTLSConfig(tc)
+		if err != nil {
+			return fmt.Errorf("invalid tls_config: %w", err)
+		}
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = tlsConfig
+	}
+
+	if sc := a.SASLConfig; sc != nil {
+		if sc.User == "" || sc.Password == "" {
+			return errors.New("sasl_config must set user and password")
+		}
+		cfg.Net.SASL.Enable = true
+		cfg.Net.SASL.User = sc.User
+		cfg.Net.SASL.Password = string(sc.Password)
+
+		switch sc.Mechanism {
+		case sarama.SASLTypePlaintext:
+			cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case sarama.SASLTypeSCRAMSHA256:
+			cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hashGenerator: sha256.New} }
+		case sarama.SASLTypeSCRAMSHA512:
+			cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hashGenerator: sha512.New} }
+		default:
+			return fmt.Errorf("invalid SASL mechanism %q: must be PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512", sc.Mechanism)
+		}
+	}
+
+	return nil
+}
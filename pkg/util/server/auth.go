@@ -0,0 +1,194 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+)
+
+// unauthenticatedPaths can be requested without credentials so health checks
+// keep working. Paths ending in a slash match all paths with that prefix.
+var unauthenticatedPaths = []string{
+	"/-/healthy",
+	"/-/ready",
+	// Endpoints of integrations are protected by the basic_auth of the
+	// integrations listener, which is also used when scraping them.
+	"/integrations/",
+}
+
+// AuthConfig configures authentication for the HTTP server.
+type AuthConfig struct {
+	// UsersFile is a YAML file mapping usernames to bcrypt hashes of their
+	// passwords. Users may authenticate with basic auth.
+	UsersFile string
+
+	// BearerTokenFile holds a token which may be used to authenticate with
+	// the Authorization header.
+	BearerTokenFile string
+}
+
+// RegisterFlags registers flags for the AuthConfig.
+func (c *AuthConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&c.UsersFile, "server.http-auth.users-file", "", "YAML file mapping usernames to bcrypt password hashes. When set, HTTP requests must use basic auth with one of the users.")
+	f.StringVar(&c.BearerTokenFile, "server.http-auth.bearer-token-file", "", "File containing a bearer token. When set, HTTP requests may authenticate with the token.")
+}
+
+// Enabled returns true if requests must authenticate.
+func (c *AuthConfig) Enabled() bool {
+	return c.UsersFile != "" || c.BearerTokenFile != ""
+}
+
+// Authenticator is an HTTP middleware that rejects requests which don't
+// authenticate with the credentials of the current AuthConfig. Requests are
+// allowed when no credentials are configured.
+type Authenticator struct {
+	mut   sync.RWMutex
+	users map[string][]byte
+	token string
+
+	// verified caches hashes of credentials that passed the bcrypt check,
+	// which is intentionally expensive.
+	verified map[[sha256.Size]byte]struct{}
+}
+
+// NewAuthenticator creates a new Authenticator which allows all requests
+// until ApplyConfig is called.
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{}
+}
+
+// ApplyConfig reads the credentials of c. The previous credentials are kept
+// if reading them fails.
+func (a *Authenticator) ApplyConfig(c AuthConfig) error {
+	var (
+		users map[string][]byte
+		token string
+	)
+
+	if c.UsersFile != "" {
+		bb, err := ioutil.ReadFile(c.UsersFile)
+		if err != nil {
+			return fmt.Errorf("failed to read users file: %w", err)
+		}
+		var hashes map[string]string
+		if err := yaml.UnmarshalStrict(bb, &hashes); err != nil {
+			return fmt.Errorf("failed to parse users file: %w", err)
+		}
+		if len(hashes) == 0 {
+			return fmt.Errorf("users file %s has no users", c.UsersFile)
+		}
+
+		users = make(map[string][]byte, len(hashes))
+		for user, hash := range hashes {
+			if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+				return fmt.Errorf("invalid password hash for user %q: %w", user, err)
+			}
+			users[user] = []byte(hash)
+		}
+	}
+
+	if c.BearerTokenFile != "" {
+		bb, err := ioutil.ReadFile(c.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token file: %w", err)
+		}
+		token = strings.TrimSpace(string(bb))
+		if token == "" {
+			return fmt.Errorf("bearer token file %s is empty", c.BearerTokenFile)
+		}
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.users, a.token = users, token
+	a.verified = make(map[[sha256.Size]byte]struct{})
+	return nil
+}
+
+// Wrap implements middleware.Interface.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if isUnauthenticatedPath(r.URL.Path) || a.authenticate(r) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		a.mut.RLock()
+		usingBasicAuth := a.users != nil
+		a.mut.RUnlock()
+
+		if usingBasicAuth {
+			rw.Header().Set("WWW-Authenticate", `Basic realm="agent"`)
+		} else {
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="agent"`)
+		}
+		http.Error(rw, "401 Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+func isUnauthenticatedPath(path string) bool {
+	for _, p := range unauthenticatedPaths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate returns true if r uses valid credentials or if no credentials
+// are configured.
+func (a *Authenticator) authenticate(r *http.Request) bool {
+	a.mut.RLock()
+	users, token := a.users, a.token
+	a.mut.RUnlock()
+
+	if users == nil && token == "" {
+		return true
+	}
+
+	if token != "" {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			given := strings.TrimPrefix(auth, "Bearer ")
+			return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+		}
+	}
+
+	if users != nil {
+		user, pass, ok := r.BasicAuth()
+		if !ok {
+			return false
+		}
+		hash, ok := users[user]
+		if !ok {
+			return false
+		}
+
+		key := sha256.Sum256([]byte(user + ":" + pass + ":" + string(hash)))
+		a.mut.RLock()
+		_, verified := a.verified[key]
+		a.mut.RUnlock()
+		if verified {
+			return true
+		}
+
+		if bcrypt.CompareHashAndPassword(hash, []byte(pass)) != nil {
+			return false
+		}
+		a.mut.Lock()
+		if a.verified != nil {
+			a.verified[key] = struct{}{}
+		}
+		a.mut.Unlock()
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthenticator(t *testing.T) {
+	dir := t.TempDir()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	usersFile := filepath.Join(dir, "users.yml")
+	require.NoError(t, ioutil.WriteFile(usersFile, []byte("admin: "+string(hash)+"\n"), 0600))
+
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("secret-token\n"), 0600))
+
+	a := NewAuthenticator()
+	handler := a.Wrap(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(path string, modify func(r *http.Request)) int {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if modify != nil {
+			modify(r)
+		}
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, r)
+		return rw.Code
+	}
+	basicAuth := func(user, pass string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, pass) }
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	// All requests are allowed until credentials are configured.
+	require.Equal(t, http.StatusOK, request("/-/reload", nil))
+
+	require.NoError(t, a.ApplyConfig(AuthConfig{UsersFile: usersFile, BearerTokenFile: tokenFile}))
+
+	tt := []struct {
+		name   string
+		path   string
+		modify func(r *http.Request)
+		expect int
+	}{
+		{"no credentials", "/-/reload", nil, http.StatusUnauthorized},
+		{"valid user", "/-/reload", basicAuth("admin", "password"), http.StatusOK},
+		{"valid user again", "/-/config", basicAuth("admin", "password"), http.StatusOK},
+		{"wrong password", "/-/reload", basicAuth("admin", "wrong"), http.StatusUnauthorized},
+		{"unknown user", "/-/reload", basicAuth("nobody", "password"), http.StatusUnauthorized},
+		{"valid token", "/metrics", bearer("secret-token"), http.StatusOK},
+		{"wrong token", "/metrics", bearer("wrong"), http.StatusUnauthorized},
+		{"healthy", "/-/healthy", nil, http.StatusOK},
+		{"ready", "/-/ready", nil, http.StatusOK},
+		{"integrations", "/integrations/agent/metrics", nil, http.StatusOK},
+		{"not exempt by prefix", "/-/healthyish", nil, http.StatusUnauthorized},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, request(tc.path, tc.modify))
+		})
+	}
+
+	// Invalid configs are rejected and the previous credentials are kept.
+	badUsersFile := filepath.Join(dir, "bad-users.yml")
+	require.NoError(t, ioutil.WriteFile(badUsersFile, []byte("admin: password\n"), 0600))
+	err = a.ApplyConfig(AuthConfig{UsersFile: badUsersFile})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid password hash for user "admin"`)
+	require.Equal(t, http.StatusUnauthorized, request("/-/reload", nil))
+
+	// Removing the credentials allows all requests again.
+	require.NoError(t, a.ApplyConfig(AuthConfig{}))
+	require.Equal(t, http.StatusOK, request("/-/reload", nil))
+}
@@ -0,0 +1,243 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tags of the BER encoded values used by SNMP.
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+)
+
+var errTruncated = errors.New("truncated BER value")
+
+// readTLV reads the tag, content, and remaining bytes of the first BER
+// encoded value in b. Only single byte tags are supported, which is all
+// SNMP uses.
+func readTLV(b []byte) (tag byte, content, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errTruncated
+	}
+	tag, b = b[0], b[1:]
+
+	length := int(b[0])
+	b = b[1:]
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(b) < n {
+			return 0, nil, nil, fmt.Errorf("invalid BER length")
+		}
+		length = 0
+		for _, c := range b[:n] {
+			length = length<<8 | int(c)
+		}
+		b = b[n:]
+	}
+	if length < 0 || len(b) < length {
+		return 0, nil, nil, errTruncated
+	}
+	return tag, b[:length], b[length:], nil
+}
+
+// appendTLV appends a BER encoded value with the given tag and content to b.
+func appendTLV(b []byte, tag byte, content []byte) []byte {
+	b = append(b, tag)
+	switch n := len(content); {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n <= 0xff:
+		b = append(b, 0x81, byte(n))
+	case n <= 0xffff:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, content...)
+}
+
+// encodeInt encodes v as a minimal two's complement integer.
+func encodeInt(v int64) []byte {
+	n := 1
+	for i := v; i > 127 || i < -128; i >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// encodeUint encodes v as a minimal two's complement integer, adding a
+// leading zero byte when the high bit would otherwise be set.
+func encodeUint(v uint64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		v >>= 8
+		if v == 0 {
+			break
+		}
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func decodeInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, fmt.Errorf("invalid integer length %d", len(b))
+	}
+	v := int64(int8(b[0]))
+	for _, c := range b[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, nil
+}
+
+func decodeUint(b []byte) (uint64, error) {
+	if len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+	if len(b) > 8 {
+		return 0, fmt.Errorf("invalid unsigned integer length %d", len(b))
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// OID is an object identifier, such as 1.3.6.1.2.1.1.3.0.
+type OID []uint32
+
+// ParseOID parses an OID in dotted notation. A leading dot is allowed.
+func ParseOID(s string) (OID, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q: must have at least two parts", s)
+	}
+
+	oid := make(OID, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", s)
+		}
+		oid[i] = uint32(v)
+	}
+	return oid, nil
+}
+
+// String returns o in dotted notation.
+func (o OID) String() string {
+	parts := make([]string, len(o))
+	for i, v := range o {
+		parts[i] = strconv.FormatUint(uint64(v), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// HasPrefix returns true if prefix is a prefix of o.
+func (o OID) HasPrefix(prefix OID) bool {
+	if len(prefix) > len(o) {
+		return false
+	}
+	for i := range prefix {
+		if o[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare returns -1, 0, or 1 depending on whether o sorts before, equal
+// to, or after other in lexicographical order.
+func (o OID) Compare(other OID) int {
+	for i := 0; i < len(o) && i < len(other); i++ {
+		switch {
+		case o[i] < other[i]:
+			return -1
+		case o[i] > other[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(o) < len(other):
+		return -1
+	case len(o) > len(other):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func encodeOID(o OID) ([]byte, error) {
+	if len(o) < 2 || o[0] > 2 || (o[0] < 2 && o[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID %s", o)
+	}
+
+	b := appendBase128(nil, o[0]*40+o[1])
+	for _, v := range o[2:] {
+		b = appendBase128(b, v)
+	}
+	return b, nil
+}
+
+func appendBase128(b []byte, v uint32) []byte {
+	var tmp [5]byte
+	i := len(tmp) - 1
+	tmp[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		tmp[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, tmp[i:]...)
+}
+
+func decodeOID(b []byte) (OID, error) {
+	var (
+		oid OID
+		v   uint64
+	)
+	for i, c := range b {
+		v = v<<7 | uint64(c&0x7f)
+		if v > 0xffffffff {
+			return nil, fmt.Errorf("OID component out of range")
+		}
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return nil, errTruncated
+			}
+			continue
+		}
+
+		if len(oid) == 0 {
+			switch {
+			case v < 40:
+				oid = append(oid, 0, uint32(v))
+			case v < 80:
+				oid = append(oid, 1, uint32(v-40))
+			default:
+				oid = append(oid, 2, uint32(v-80))
+			}
+		} else {
+			oid = append(oid, uint32(v))
+		}
+		v = 0
+	}
+	if len(oid) == 0 {
+		return nil, fmt.Errorf("empty OID")
+	}
+	return oid, nil
+}
@@ -0,0 +1,118 @@
+// Package snmp implements the parts of SNMPv2c needed to walk devices and
+// receive traps.
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// maxPacketSize is the largest SNMP packet which can be received.
+const maxPacketSize = 65535
+
+// Client walks SNMP agents using SNMPv2c.
+type Client struct {
+	// Address of the agent in host:port form.
+	Address   string
+	Community string
+
+	// Timeout of each request and how many times to retry requests which
+	// time out.
+	Timeout time.Duration
+	Retries int
+
+	// MaxRepetitions is the number of variables requested at once while
+	// walking.
+	MaxRepetitions int
+}
+
+// Walk calls fn with every variable under root, in order.
+func (c *Client) Walk(ctx context.Context, root OID, fn func(Variable)) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", c.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	next := root
+	for {
+		resp, err := c.request(ctx, conn, &Packet{
+			Version:    Version2c,
+			Community:  c.Community,
+			PDUType:    GetBulkRequest,
+			ErrorIndex: c.MaxRepetitions,
+			Variables:  []Variable{{OID: next, Type: Null}},
+		})
+		if err != nil {
+			return err
+		}
+		if resp.ErrorStatus != 0 {
+			return fmt.Errorf("agent returned error status %d for %s", resp.ErrorStatus, next)
+		}
+		if len(resp.Variables) == 0 {
+			return nil
+		}
+
+		for _, v := range resp.Variables {
+			if v.Type == EndOfMibView || !v.OID.HasPrefix(root) {
+				return nil
+			}
+			if v.OID.Compare(next) <= 0 {
+				return fmt.Errorf("agent returned OID %s which doesn't increase after %s", v.OID, next)
+			}
+			fn(v)
+			next = v.OID
+		}
+	}
+}
+
+// request sends req and waits for its response, retrying when no response
+// is received in time.
+func (c *Client) request(ctx context.Context, conn net.Conn, req *Packet) (*Packet, error) {
+	buf := make([]byte, maxPacketSize)
+
+	var lastErr error
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		req.RequestID = rand.Int31()
+		b, err := req.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(c.Timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+		if _, err := conn.Write(b); err != nil {
+			return nil, err
+		}
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				lastErr = err
+				break
+			}
+
+			var resp Packet
+			if err := resp.UnmarshalBinary(buf[:n]); err != nil || resp.RequestID != req.RequestID {
+				// Ignore invalid packets and late responses to earlier
+				// attempts.
+				continue
+			}
+			return &resp, nil
+		}
+	}
+	return nil, fmt.Errorf("no response from %s: %w", c.Address, lastErr)
+}
@@ -0,0 +1,289 @@
+package snmp
+
+import (
+	"fmt"
+	"net"
+)
+
+// Version is an SNMP protocol version.
+type Version int
+
+// Supported versions.
+const (
+	Version1  Version = 0
+	Version2c Version = 1
+)
+
+// PDUType is the type of the PDU in a Packet.
+type PDUType byte
+
+// PDU types used by SNMPv2c.
+const (
+	GetRequest     PDUType = 0xa0
+	GetNextRequest PDUType = 0xa1
+	GetResponse    PDUType = 0xa2
+	SetRequest     PDUType = 0xa3
+	TrapV1         PDUType = 0xa4
+	GetBulkRequest PDUType = 0xa5
+	InformRequest  PDUType = 0xa6
+	TrapV2         PDUType = 0xa7
+)
+
+// Type is the type of a Variable.
+type Type byte
+
+// Types of variables.
+const (
+	Integer          Type = tagInteger
+	OctetString      Type = tagOctetString
+	Null             Type = tagNull
+	ObjectIdentifier Type = tagOID
+	IPAddress        Type = 0x40
+	Counter32        Type = 0x41
+	Gauge32          Type = 0x42
+	TimeTicks        Type = 0x43
+	Opaque           Type = 0x44
+	Counter64        Type = 0x46
+	NoSuchObject     Type = 0x80
+	NoSuchInstance   Type = 0x81
+	EndOfMibView     Type = 0x82
+)
+
+// Variable is a variable binding of a PDU. The Go type of Value depends on
+// Type:
+//
+//	Integer                           int64
+//	OctetString, Opaque               []byte
+//	ObjectIdentifier                  OID
+//	IPAddress                         net.IP
+//	Counter32, Gauge32, TimeTicks,
+//	Counter64                         uint64
+//
+// Value is nil for every other type.
+type Variable struct {
+	OID   OID
+	Type  Type
+	Value interface{}
+}
+
+// Float returns the value of v as a float64. Returns false if v doesn't
+// hold a number.
+func (v Variable) Float() (float64, bool) {
+	switch val := v.Value.(type) {
+	case int64:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	default:
+		return 0, false
+	}
+}
+
+// Packet is an SNMP message. Only the community based SNMPv1 and SNMPv2c
+// messages are supported. SNMPv1 traps use a different PDU layout and
+// can't be represented by Packet; use Trap to decode them.
+type Packet struct {
+	Version   Version
+	Community string
+	PDUType   PDUType
+	RequestID int32
+
+	// ErrorStatus and ErrorIndex hold the non-repeaters and max-repetitions
+	// of GetBulkRequest PDUs.
+	ErrorStatus int
+	ErrorIndex  int
+
+	Variables []Variable
+}
+
+// MarshalBinary encodes p.
+func (p *Packet) MarshalBinary() ([]byte, error) {
+	var vars []byte
+	for _, v := range p.Variables {
+		enc, err := encodeVariable(v)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, enc...)
+	}
+
+	var pdu []byte
+	pdu = appendTLV(pdu, tagInteger, encodeInt(int64(p.RequestID)))
+	pdu = appendTLV(pdu, tagInteger, encodeInt(int64(p.ErrorStatus)))
+	pdu = appendTLV(pdu, tagInteger, encodeInt(int64(p.ErrorIndex)))
+	pdu = appendTLV(pdu, tagSequence, vars)
+
+	var msg []byte
+	msg = appendTLV(msg, tagInteger, encodeInt(int64(p.Version)))
+	msg = appendTLV(msg, tagOctetString, []byte(p.Community))
+	msg = appendTLV(msg, byte(p.PDUType), pdu)
+	return appendTLV(nil, tagSequence, msg), nil
+}
+
+func encodeVariable(v Variable) ([]byte, error) {
+	oid, err := encodeOID(v.OID)
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	switch v.Type {
+	case Integer:
+		i, ok := v.Value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("%s: Integer value must be int64", v.OID)
+		}
+		value = encodeInt(i)
+	case OctetString, Opaque:
+		b, ok := v.Value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%s: OctetString value must be []byte", v.OID)
+		}
+		value = b
+	case ObjectIdentifier:
+		o, ok := v.Value.(OID)
+		if !ok {
+			return nil, fmt.Errorf("%s: ObjectIdentifier value must be OID", v.OID)
+		}
+		if value, err = encodeOID(o); err != nil {
+			return nil, err
+		}
+	case IPAddress:
+		ip, ok := v.Value.(net.IP)
+		if !ok || ip.To4() == nil {
+			return nil, fmt.Errorf("%s: IPAddress value must be an IPv4 net.IP", v.OID)
+		}
+		value = ip.To4()
+	case Counter32, Gauge32, TimeTicks, Counter64:
+		u, ok := v.Value.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsigned value must be uint64", v.OID)
+		}
+		value = encodeUint(u)
+	}
+
+	var b []byte
+	b = appendTLV(b, tagOID, oid)
+	b = appendTLV(b, byte(v.Type), value)
+	return appendTLV(nil, tagSequence, b), nil
+}
+
+// UnmarshalBinary decodes b into p.
+func (p *Packet) UnmarshalBinary(b []byte) error {
+	tag, msg, _, err := readTLV(b)
+	if err != nil {
+		return err
+	} else if tag != tagSequence {
+		return fmt.Errorf("message is not a sequence")
+	}
+
+	version, msg, err := readInt(msg)
+	if err != nil {
+		return fmt.Errorf("invalid version: %w", err)
+	}
+	p.Version = Version(version)
+	if p.Version != Version1 && p.Version != Version2c {
+		return fmt.Errorf("unsupported SNMP version %d", version)
+	}
+
+	tag, community, msg, err := readTLV(msg)
+	if err != nil || tag != tagOctetString {
+		return fmt.Errorf("invalid community")
+	}
+	p.Community = string(community)
+
+	tag, pdu, _, err := readTLV(msg)
+	if err != nil {
+		return fmt.Errorf("invalid PDU: %w", err)
+	}
+	p.PDUType = PDUType(tag)
+	if p.PDUType == TrapV1 {
+		return fmt.Errorf("SNMPv1 traps are not supported")
+	}
+
+	var fields [3]int64
+	for i := range fields {
+		if fields[i], pdu, err = readInt(pdu); err != nil {
+			return fmt.Errorf("invalid PDU: %w", err)
+		}
+	}
+	p.RequestID, p.ErrorStatus, p.ErrorIndex = int32(fields[0]), int(fields[1]), int(fields[2])
+
+	p.Variables, err = decodeVariables(pdu)
+	return err
+}
+
+func readInt(b []byte) (int64, []byte, error) {
+	tag, content, rest, err := readTLV(b)
+	if err != nil {
+		return 0, nil, err
+	} else if tag != tagInteger {
+		return 0, nil, fmt.Errorf("expected integer, got tag %#x", tag)
+	}
+	v, err := decodeInt(content)
+	return v, rest, err
+}
+
+// decodeVariables decodes the variable bindings sequence at the start of b.
+func decodeVariables(b []byte) ([]Variable, error) {
+	tag, list, _, err := readTLV(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid variable bindings: %w", err)
+	} else if tag != tagSequence {
+		return nil, fmt.Errorf("variable bindings are not a sequence")
+	}
+
+	var vars []Variable
+	for len(list) > 0 {
+		var binding []byte
+		tag, binding, list, err = readTLV(list)
+		if err != nil || tag != tagSequence {
+			return nil, fmt.Errorf("invalid variable binding")
+		}
+
+		v, err := decodeVariable(binding)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+func decodeVariable(b []byte) (Variable, error) {
+	var v Variable
+
+	tag, oid, b, err := readTLV(b)
+	if err != nil || tag != tagOID {
+		return v, fmt.Errorf("invalid variable name")
+	}
+	if v.OID, err = decodeOID(oid); err != nil {
+		return v, err
+	}
+
+	tag, value, _, err := readTLV(b)
+	if err != nil {
+		return v, fmt.Errorf("%s: invalid value: %w", v.OID, err)
+	}
+	v.Type = Type(tag)
+
+	switch v.Type {
+	case Integer:
+		v.Value, err = decodeInt(value)
+	case OctetString, Opaque:
+		v.Value = append([]byte(nil), value...)
+	case ObjectIdentifier:
+		v.Value, err = decodeOID(value)
+	case IPAddress:
+		if len(value) != 4 {
+			err = fmt.Errorf("invalid IP address length %d", len(value))
+		}
+		v.Value = net.IP(append([]byte(nil), value...))
+	case Counter32, Gauge32, TimeTicks, Counter64:
+		v.Value, err = decodeUint(value)
+	}
+	if err != nil {
+		return v, fmt.Errorf("%s: %w", v.OID, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,218 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseOID(t *testing.T, s string) OID {
+	t.Helper()
+	oid, err := ParseOID(s)
+	require.NoError(t, err)
+	return oid
+}
+
+func TestOID(t *testing.T) {
+	oid := mustParseOID(t, ".1.3.6.1.2.1.1.3.0")
+	require.Equal(t, "1.3.6.1.2.1.1.3.0", oid.String())
+	require.True(t, oid.HasPrefix(mustParseOID(t, "1.3.6.1.2.1")))
+	require.False(t, oid.HasPrefix(mustParseOID(t, "1.3.6.1.2.2")))
+
+	require.Equal(t, -1, mustParseOID(t, "1.3.6.1.2").Compare(mustParseOID(t, "1.3.6.1.10")))
+	require.Equal(t, -1, mustParseOID(t, "1.3.6").Compare(mustParseOID(t, "1.3.6.1")))
+	require.Equal(t, 0, oid.Compare(mustParseOID(t, "1.3.6.1.2.1.1.3.0")))
+
+	_, err := ParseOID("1.3.a")
+	require.EqualError(t, err, `invalid OID "1.3.a"`)
+}
+
+func TestPacket_Encoding(t *testing.T) {
+	// GetRequest for sysUpTime.0 with community public, as sent by snmpget.
+	expect := []byte{
+		0x30, 0x29, 0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x1c, 0x02, 0x04, 0x12, 0x34, 0x56, 0x78, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x03, 0x00, 0x05, 0x00,
+	}
+
+	p := Packet{
+		Version:   Version2c,
+		Community: "public",
+		PDUType:   GetRequest,
+		RequestID: 0x12345678,
+		Variables: []Variable{{OID: mustParseOID(t, "1.3.6.1.2.1.1.3.0"), Type: Null}},
+	}
+	b, err := p.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, expect, b)
+
+	var decoded Packet
+	require.NoError(t, decoded.UnmarshalBinary(b))
+	require.Equal(t, p, decoded)
+}
+
+func TestPacket_RoundTrip(t *testing.T) {
+	p := Packet{
+		Version:   Version2c,
+		Community: "private",
+		PDUType:   GetResponse,
+		RequestID: -5,
+		Variables: []Variable{
+			{OID: mustParseOID(t, "1.3.6.1.2.1.1.1.0"), Type: OctetString, Value: []byte("router")},
+			{OID: mustParseOID(t, "1.3.6.1.2.1.1.2.0"), Type: ObjectIdentifier, Value: mustParseOID(t, "1.3.6.1.4.1.9.1.1")},
+			{OID: mustParseOID(t, "1.3.6.1.2.1.2.2.1.8.1"), Type: Integer, Value: int64(-300)},
+			{OID: mustParseOID(t, "1.3.6.1.2.1.4.20.1.1.10"), Type: IPAddress, Value: net.IP{10, 0, 0, 1}},
+			{OID: mustParseOID(t, "1.3.6.1.2.1.2.2.1.10.1"), Type: Counter32, Value: uint64(0xffffffff)},
+			{OID: mustParseOID(t, "1.3.6.1.2.1.31.1.1.1.6.1"), Type: Counter64, Value: uint64(1 << 63)},
+			{OID: mustParseOID(t, "1.3.6.1.2.1.1.3.0"), Type: TimeTicks, Value: uint64(0)},
+			{OID: mustParseOID(t, "1.3.6.1.2.1.1.9.0"), Type: NoSuchObject},
+		},
+	}
+
+	b, err := p.MarshalBinary()
+	require.NoError(t, err)
+
+	var decoded Packet
+	require.NoError(t, decoded.UnmarshalBinary(b))
+	require.Equal(t, p, decoded)
+
+	f, ok := decoded.Variables[2].Float()
+	require.True(t, ok)
+	require.Equal(t, -300.0, f)
+	_, ok = decoded.Variables[0].Float()
+	require.False(t, ok)
+}
+
+// runAgent runs a fake SNMP agent serving vars until the test ends.
+func runAgent(t *testing.T, community string, vars []Variable) string {
+	t.Helper()
+
+	sort.Slice(vars, func(i, j int) bool { return vars[i].OID.Compare(vars[j].OID) < 0 })
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, maxPacketSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var req Packet
+			if err := req.UnmarshalBinary(buf[:n]); err != nil || req.Community != community || req.PDUType != GetBulkRequest {
+				continue
+			}
+
+			resp := Packet{Version: req.Version, Community: req.Community, PDUType: GetResponse, RequestID: req.RequestID}
+			start := sort.Search(len(vars), func(i int) bool { return vars[i].OID.Compare(req.Variables[0].OID) > 0 })
+			for i := start; i < len(vars) && len(resp.Variables) < req.ErrorIndex; i++ {
+				resp.Variables = append(resp.Variables, vars[i])
+			}
+			if len(resp.Variables) < req.ErrorIndex {
+				resp.Variables = append(resp.Variables, Variable{OID: req.Variables[0].OID, Type: EndOfMibView})
+			}
+
+			b, err := resp.MarshalBinary()
+			if err != nil {
+				panic(err)
+			}
+			_, _ = conn.WriteTo(b, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestClient_Walk(t *testing.T) {
+	var vars []Variable
+	for i := 1; i <= 30; i++ {
+		vars = append(vars, Variable{OID: OID{1, 3, 6, 1, 2, 1, 2, 2, 1, 10, uint32(i)}, Type: Counter32, Value: uint64(i * 100)})
+	}
+	vars = append(vars, Variable{OID: OID{1, 3, 6, 1, 2, 1, 2, 2, 1, 11, 1}, Type: Counter32, Value: uint64(1)})
+
+	c := Client{
+		Address:        runAgent(t, "public", vars),
+		Community:      "public",
+		Timeout:        time.Second,
+		MaxRepetitions: 10,
+	}
+
+	var walked []Variable
+	err := c.Walk(context.Background(), OID{1, 3, 6, 1, 2, 1, 2, 2, 1, 10}, func(v Variable) {
+		walked = append(walked, v)
+	})
+	require.NoError(t, err)
+	require.Equal(t, vars[:30], walked)
+
+	// Requests with the wrong community are ignored by the agent.
+	c.Community = "private"
+	c.Timeout = 50 * time.Millisecond
+	c.Retries = 1
+	err = c.Walk(context.Background(), OID{1, 3, 6, 1, 2, 1, 2, 2, 1, 10}, func(Variable) {})
+	require.Error(t, err)
+}
+
+func TestTrap_V1(t *testing.T) {
+	// linkDown trap for ifIndex 2 from agent 10.0.0.1, as sent by snmptrap -v 1.
+	pdu := []byte{
+		0x06, 0x06, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x08, // enterprise 1.3.6.1.4.1.8
+		0x40, 0x04, 10, 0, 0, 1, // agent address
+		0x02, 0x01, 0x02, // generic trap linkDown
+		0x02, 0x01, 0x00, // specific trap
+		0x43, 0x02, 0x04, 0xd2, // timestamp 1234
+		0x30, 0x11, 0x30, 0x0f, 0x06, 0x0a, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x01, 0x02, 0x02, 0x01, 0x02,
+	}
+	msg := []byte{0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', 0xa4, byte(len(pdu))}
+	b := append([]byte{0x30, byte(len(msg) + len(pdu))}, append(msg, pdu...)...)
+
+	var trap Trap
+	require.NoError(t, trap.UnmarshalBinary(b))
+	require.Equal(t, Trap{
+		Version:      Version1,
+		Community:    "public",
+		PDUType:      TrapV1,
+		TrapOID:      mustParseOID(t, "1.3.6.1.6.3.1.1.5.3"),
+		Uptime:       1234,
+		AgentAddress: net.IP{10, 0, 0, 1},
+		Variables:    []Variable{{OID: mustParseOID(t, "1.3.6.1.2.1.2.2.1.1.2"), Type: Integer, Value: int64(2)}},
+	}, trap)
+}
+
+func TestTrap_V2(t *testing.T) {
+	p := Packet{
+		Version:   Version2c,
+		Community: "public",
+		PDUType:   InformRequest,
+		RequestID: 7,
+		Variables: []Variable{
+			{OID: SysUpTimeOID, Type: TimeTicks, Value: uint64(1234)},
+			{OID: SnmpTrapOID, Type: ObjectIdentifier, Value: mustParseOID(t, "1.3.6.1.4.1.8.0.1")},
+			{OID: mustParseOID(t, "1.3.6.1.4.1.8.1"), Type: OctetString, Value: []byte("disk full")},
+		},
+	}
+	b, err := p.MarshalBinary()
+	require.NoError(t, err)
+
+	var trap Trap
+	require.NoError(t, trap.UnmarshalBinary(b))
+	require.Equal(t, mustParseOID(t, "1.3.6.1.4.1.8.0.1"), trap.TrapOID)
+	require.Equal(t, uint64(1234), trap.Uptime)
+	require.Equal(t, p.Variables, trap.Variables)
+
+	resp := trap.Response()
+	require.Equal(t, GetResponse, resp.PDUType)
+	require.Equal(t, int32(7), resp.RequestID)
+
+	// Other PDUs aren't traps.
+	p.PDUType = GetRequest
+	b, err = p.MarshalBinary()
+	require.NoError(t, err)
+	require.EqualError(t, trap.UnmarshalBinary(b), "PDU type 0xa0 is not a trap")
+}
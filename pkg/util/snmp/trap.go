@@ -0,0 +1,157 @@
+package snmp
+
+import (
+	"fmt"
+	"net"
+)
+
+// OIDs of the variables identifying SNMPv2c traps.
+var (
+	SysUpTimeOID = OID{1, 3, 6, 1, 2, 1, 1, 3, 0}
+	SnmpTrapOID  = OID{1, 3, 6, 1, 6, 3, 1, 1, 4, 1, 0}
+)
+
+// snmpTrapsOID is the prefix of the OIDs of the generic SNMPv1 traps.
+var snmpTrapsOID = OID{1, 3, 6, 1, 6, 3, 1, 1, 5}
+
+// enterpriseSpecific is the generic trap type of SNMPv1 traps defined by
+// an enterprise.
+const enterpriseSpecific = 6
+
+// Trap is a notification sent by an agent, either as an SNMPv1 trap, an
+// SNMPv2c trap, or an SNMPv2c inform request.
+type Trap struct {
+	Version   Version
+	Community string
+	PDUType   PDUType
+	RequestID int32
+
+	// TrapOID identifies the trap. SNMPv1 traps are converted to an OID
+	// following RFC 3584.
+	TrapOID OID
+	// Uptime of the agent when it sent the trap, in hundredths of a second.
+	Uptime uint64
+	// AgentAddress is the address of the agent set in SNMPv1 traps.
+	AgentAddress net.IP
+
+	// Variables holds the variable bindings of the trap. For SNMPv2c, it
+	// includes sysUpTime.0 and snmpTrapOID.0.
+	Variables []Variable
+}
+
+// UnmarshalBinary decodes b into t. Returns an error if b isn't a trap or
+// an inform request.
+func (t *Trap) UnmarshalBinary(b []byte) error {
+	tag, msg, _, err := readTLV(b)
+	if err != nil {
+		return err
+	} else if tag != tagSequence {
+		return fmt.Errorf("message is not a sequence")
+	}
+
+	version, msg, err := readInt(msg)
+	if err != nil {
+		return fmt.Errorf("invalid version: %w", err)
+	}
+	tag, community, msg, err := readTLV(msg)
+	if err != nil || tag != tagOctetString {
+		return fmt.Errorf("invalid community")
+	}
+	tag, pdu, _, err := readTLV(msg)
+	if err != nil {
+		return fmt.Errorf("invalid PDU: %w", err)
+	}
+
+	switch PDUType(tag) {
+	case TrapV1:
+		if Version(version) != Version1 {
+			return fmt.Errorf("unexpected SNMPv1 trap in SNMP version %d message", version)
+		}
+		*t = Trap{Version: Version1, Community: string(community), PDUType: TrapV1}
+		return t.decodeV1(pdu)
+	case TrapV2, InformRequest:
+		var p Packet
+		if err := p.UnmarshalBinary(b); err != nil {
+			return err
+		}
+		*t = Trap{
+			Version:   p.Version,
+			Community: p.Community,
+			PDUType:   p.PDUType,
+			RequestID: p.RequestID,
+			Variables: p.Variables,
+		}
+		for _, v := range p.Variables {
+			switch {
+			case v.OID.Compare(SysUpTimeOID) == 0:
+				t.Uptime, _ = v.Value.(uint64)
+			case v.OID.Compare(SnmpTrapOID) == 0:
+				t.TrapOID, _ = v.Value.(OID)
+			}
+		}
+		if t.TrapOID == nil {
+			return fmt.Errorf("trap has no snmpTrapOID.0 variable")
+		}
+		return nil
+	default:
+		return fmt.Errorf("PDU type %#x is not a trap", tag)
+	}
+}
+
+// decodeV1 decodes the content of an SNMPv1 Trap-PDU.
+func (t *Trap) decodeV1(pdu []byte) error {
+	tag, value, pdu, err := readTLV(pdu)
+	if err != nil || tag != tagOID {
+		return fmt.Errorf("invalid enterprise")
+	}
+	enterprise, err := decodeOID(value)
+	if err != nil {
+		return fmt.Errorf("invalid enterprise: %w", err)
+	}
+
+	tag, value, pdu, err = readTLV(pdu)
+	if err != nil || Type(tag) != IPAddress || len(value) != 4 {
+		return fmt.Errorf("invalid agent address")
+	}
+	t.AgentAddress = net.IP(append([]byte(nil), value...))
+
+	generic, pdu, err := readInt(pdu)
+	if err != nil {
+		return fmt.Errorf("invalid generic trap: %w", err)
+	}
+	specific, pdu, err := readInt(pdu)
+	if err != nil {
+		return fmt.Errorf("invalid specific trap: %w", err)
+	}
+
+	tag, value, pdu, err = readTLV(pdu)
+	if err != nil || Type(tag) != TimeTicks {
+		return fmt.Errorf("invalid timestamp")
+	}
+	if t.Uptime, err = decodeUint(value); err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	if generic < 0 || specific < 0 {
+		return fmt.Errorf("invalid trap type %d/%d", generic, specific)
+	}
+	if generic == enterpriseSpecific {
+		t.TrapOID = append(append(OID{}, enterprise...), 0, uint32(specific))
+	} else {
+		t.TrapOID = append(append(OID{}, snmpTrapsOID...), uint32(generic)+1)
+	}
+
+	t.Variables, err = decodeVariables(pdu)
+	return err
+}
+
+// Response returns the response acknowledging an inform request.
+func (t *Trap) Response() *Packet {
+	return &Packet{
+		Version:   t.Version,
+		Community: t.Community,
+		PDUType:   GetResponse,
+		RequestID: t.RequestID,
+		Variables: t.Variables,
+	}
+}